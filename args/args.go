@@ -3,6 +3,8 @@ package args
 import (
 	"strings"
 
+	"github.com/nuvolaris/sh/v3/syntax"
+
 	"github.com/nuvolaris/task/v3/taskfile"
 )
 
@@ -50,6 +52,25 @@ func ParseV2(args ...string) ([]taskfile.Call, *taskfile.Vars) {
 	return calls, globals
 }
 
+// SetCLIArgs sets the variables of the arguments given after "--":
+// CLI_ARGS has all of them quoted for the shell, and CLI_ARGS_LIST has each of
+// them as they are, so it can be iterated without splitting CLI_ARGS again.
+func SetCLIArgs(vars *taskfile.Vars, cliArgs []string) error {
+	quoted := make([]string, 0, len(cliArgs))
+	list := make([]any, 0, len(cliArgs))
+	for _, arg := range cliArgs {
+		quotedArg, err := syntax.Quote(arg, syntax.LangBash)
+		if err != nil {
+			return err
+		}
+		quoted = append(quoted, quotedArg)
+		list = append(list, arg)
+	}
+	vars.Set("CLI_ARGS", taskfile.Var{Static: strings.Join(quoted, " ")})
+	vars.Set("CLI_ARGS_LIST", taskfile.Var{Live: list})
+	return nil
+}
+
 func splitVar(s string) (string, string) {
 	pair := strings.SplitN(s, "=", 2)
 	return pair[0], pair[1]
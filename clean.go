@@ -0,0 +1,94 @@
+package task
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/nuvolaris/task/v3/internal/filepathext"
+	"github.com/nuvolaris/task/v3/internal/fingerprint"
+	"github.com/nuvolaris/task/v3/internal/logger"
+	"github.com/nuvolaris/task/v3/taskfile"
+)
+
+// Clean removes the files generated by the given tasks, the ones matched by
+// their `generates:`, along with the state kept to tell whether they're
+// up-to-date, so they run again next time. Without tasks, every task of the
+// Taskfile is cleaned. Generated files that are also sources of the task are
+// never removed. In dry mode, the files are only listed.
+func (e *Executor) Clean(ctx context.Context, calls ...taskfile.Call) error {
+	if err := e.setupIfNeeded(ctx); err != nil {
+		return err
+	}
+	if len(calls) == 0 {
+		for _, name := range e.Taskfile.Tasks.Keys() {
+			// Wildcard tasks can only be compiled with the name they're called with
+			if !strings.Contains(name, "*") {
+				calls = append(calls, taskfile.Call{Task: name})
+			}
+		}
+	}
+
+	var failed int
+	for _, call := range calls {
+		t, err := e.CompiledTask(ctx, call)
+		if err != nil {
+			return err
+		}
+		files, err := e.cleanFiles(t)
+		if err != nil {
+			return err
+		}
+		for _, f := range files {
+			if e.Dry {
+				e.Logger.Outf(logger.Yellow, "task: Would remove %s\n", filepathext.TryAbsToRel(f))
+				continue
+			}
+			if err := os.Remove(f); err != nil && !os.IsNotExist(err) {
+				e.Logger.Errf(logger.Red, "task: Unable to remove %s: %v\n", filepathext.TryAbsToRel(f), err)
+				failed++
+				continue
+			}
+			e.Logger.VerboseOutf(logger.Green, "task: Removed %s\n", filepathext.TryAbsToRel(f))
+		}
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("task: Unable to remove %d file(s)", failed)
+	}
+	return nil
+}
+
+// cleanFiles returns the existing files removed when cleaning a task.
+func (e *Executor) cleanFiles(t *taskfile.Task) ([]string, error) {
+	var files []string
+	if len(t.Generates) > 0 {
+		generates, err := fingerprint.Globs(t.Dir, t.Generates)
+		if err != nil {
+			return nil, err
+		}
+		sources, err := fingerprint.Sources(t)
+		if err != nil {
+			return nil, err
+		}
+		isSource := make(map[string]bool, len(sources))
+		for _, f := range sources {
+			isSource[filepathext.Key(f)] = true
+		}
+		for _, f := range generates {
+			if isSource[filepathext.Key(f)] {
+				e.Logger.VerboseErrf(logger.Yellow, "task: Not removing %s, which is also a source of %q\n", filepathext.TryAbsToRel(f), t.Name())
+				continue
+			}
+			if info, err := os.Stat(f); err == nil && !info.IsDir() {
+				files = append(files, f)
+			}
+		}
+	}
+	stateFiles, err := fingerprint.StateFiles(t, e.TempDir)
+	if err != nil {
+		return nil, err
+	}
+	return append(files, stateFiles...), nil
+}
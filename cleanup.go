@@ -0,0 +1,377 @@
+package task
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/nuvolaris/task/v3/internal/execext"
+	"github.com/nuvolaris/task/v3/internal/filepathext"
+	"github.com/nuvolaris/task/v3/internal/logger"
+)
+
+// ResourceKind is the kind of a temporary resource tracked in a run manifest.
+type ResourceKind string
+
+const (
+	// ResourceTempDir is a temporary directory, identified by its path
+	ResourceTempDir ResourceKind = "temp_dir"
+	// ResourceProcess is a background process, identified by its PID
+	ResourceProcess ResourceKind = "process"
+	// ResourceContainer is a container, identified by its ID or name
+	ResourceContainer ResourceKind = "container"
+)
+
+// Resource is a temporary resource created during a run that has to be
+// removed when the run ends.
+type Resource struct {
+	Kind ResourceKind `json:"kind"`
+	ID   string       `json:"id"`
+	// Runtime is the command used to remove a container. Defaults to "docker".
+	Runtime string `json:"runtime,omitempty"`
+	// Started is when a process started, as told by the OS. The process is
+	// only stopped if it still matches, so a PID reused by another process
+	// is left alone.
+	Started string `json:"started,omitempty"`
+	// Group is set when the process leads its own process group, which is
+	// stopped along with it.
+	Group bool `json:"group,omitempty"`
+}
+
+// runManifest lists the resources of a run that weren't removed yet. It's
+// stored in the temp dir while the run has resources, so the leftovers of a
+// run that crashed can be found later by --cleanup.
+type runManifest struct {
+	PID       int        `json:"pid"`
+	Started   time.Time  `json:"started"`
+	Resources []Resource `json:"resources"`
+
+	path  string
+	mutex sync.Mutex
+}
+
+func (e *Executor) runsDir() string {
+	return filepathext.SmartJoin(e.TempDir, "runs")
+}
+
+// runsDirs returns the dirs the manifests can be in: the one of the temp dir,
+// and the one of the writable fallback of a read-only project, which is used
+// when the first can't be written.
+func (e *Executor) runsDirs() []string {
+	dirs := []string{e.runsDir()}
+	if fallback, err := fallbackTempDir(e.Dir); err == nil && fallback != e.TempDir {
+		dirs = append(dirs, filepathext.SmartJoin(fallback, "runs"))
+	}
+	return dirs
+}
+
+// createManifest creates the file of the manifest in the first of the runs
+// dirs that can be written.
+func (e *Executor) createManifest(m *runManifest) error {
+	var err error
+	for _, dir := range e.runsDirs() {
+		// The dir is removed along with the last manifest in it
+		if err = os.MkdirAll(dir, 0o755); err != nil {
+			continue
+		}
+		var f *os.File
+		if f, err = os.CreateTemp(dir, fmt.Sprintf("%d-*.json", m.PID)); err != nil {
+			continue
+		}
+		f.Close()
+		m.path = f.Name()
+		return nil
+	}
+	return err
+}
+
+// TrackResource records a temporary resource in the manifest of the current
+// run. The returned function must be called once the resource is removed.
+// Resources still tracked when the run ends are removed automatically.
+func (e *Executor) TrackResource(r Resource) (release func(), err error) {
+	e.manifestMutex.Lock()
+	if e.manifest == nil {
+		e.manifest = &runManifest{PID: os.Getpid(), Started: time.Now()}
+	}
+	m := e.manifest
+	e.manifestMutex.Unlock()
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if m.path == "" {
+		if err := e.createManifest(m); err != nil {
+			return nil, err
+		}
+	}
+	m.Resources = append(m.Resources, r)
+	if err := m.write(); err != nil {
+		return nil, err
+	}
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			m.mutex.Lock()
+			defer m.mutex.Unlock()
+			m.remove(r)
+			if err := m.write(); err != nil {
+				e.Logger.VerboseErrf(logger.Yellow, "task: unable to update the run manifest: %v\n", err)
+			}
+		})
+	}, nil
+}
+
+// trackProcess records a process started by a command in the manifest of the
+// run, so it's stopped if it's still running when the run ends, or by
+// --cleanup if Task crashes before. The returned function releases it once it
+// exits.
+func (e *Executor) trackProcess(p *os.Process, group bool) func() {
+	started, err := processStartTime(p.Pid)
+	if err != nil {
+		// It may have exited already
+		e.Logger.VerboseErrf(logger.Yellow, "task: unable to track process %d: %v\n", p.Pid, err)
+		return func() {}
+	}
+	release, err := e.TrackResource(Resource{Kind: ResourceProcess, ID: strconv.Itoa(p.Pid), Started: started, Group: group})
+	if err != nil {
+		e.Logger.VerboseErrf(logger.Yellow, "task: unable to track process %d: %v\n", p.Pid, err)
+		return func() {}
+	}
+	return release
+}
+
+// trackTempDir records a temp dir created by Task in the manifest of the run,
+// so it's removed by --cleanup if Task crashes before removing it. The
+// returned function releases it once it's removed.
+func (e *Executor) trackTempDir(dir string) func() {
+	release, err := e.TrackResource(Resource{Kind: ResourceTempDir, ID: dir})
+	if err != nil {
+		e.Logger.VerboseErrf(logger.Yellow, "task: unable to track temp dir %q: %v\n", dir, err)
+		return func() {}
+	}
+	return release
+}
+
+// commandProcesses are the processes started by a command. Most of them exit
+// before the command does, so they're only tracked in the manifest of the run
+// once they outlive it, like the ones left in the background, unless they're
+// meant to run for long from the start, like the ones restarted by watch.
+type commandProcesses struct {
+	e     *Executor
+	group bool
+	eager bool
+
+	mutex sync.Mutex
+	// running are the processes that didn't exit yet, with the function that
+	// releases them once tracked
+	running  map[*os.Process]func()
+	finished bool
+}
+
+func (e *Executor) newCommandProcesses(group, eager bool) *commandProcesses {
+	return &commandProcesses{e: e, group: group, eager: eager, running: map[*os.Process]func(){}}
+}
+
+// add records a process just started, and returns the function to call once
+// it exits.
+func (cp *commandProcesses) add(p *os.Process) func() {
+	cp.mutex.Lock()
+	defer cp.mutex.Unlock()
+
+	var release func()
+	if cp.eager || cp.finished {
+		release = cp.e.trackProcess(p, cp.group)
+	}
+	cp.running[p] = release
+	return func() {
+		cp.mutex.Lock()
+		release := cp.running[p]
+		delete(cp.running, p)
+		cp.mutex.Unlock()
+		if release != nil {
+			release()
+		}
+	}
+}
+
+// finish tracks the processes still running once the command returned.
+func (cp *commandProcesses) finish() {
+	cp.mutex.Lock()
+	defer cp.mutex.Unlock()
+
+	cp.finished = true
+	for p, release := range cp.running {
+		if release == nil {
+			cp.running[p] = cp.e.trackProcess(p, cp.group)
+		}
+	}
+}
+
+// finishManifest removes the resources left by the current run, along with
+// its manifest.
+func (e *Executor) finishManifest() {
+	e.manifestMutex.Lock()
+	m := e.manifest
+	e.manifest = nil
+	e.manifestMutex.Unlock()
+
+	if m == nil {
+		return
+	}
+	e.cleanupManifest(m)
+}
+
+// Cleanup removes the resources left by runs that didn't end properly, like
+// the ones that crashed or were killed. Runs that are still going on are
+// left untouched.
+func (e *Executor) Cleanup() error {
+	if err := e.setupIfNeeded(context.Background()); err != nil {
+		return err
+	}
+	var paths []string
+	for _, dir := range e.runsDirs() {
+		matches, err := filepath.Glob(filepathext.SmartJoin(dir, "*.json"))
+		if err != nil {
+			return err
+		}
+		paths = append(paths, matches...)
+	}
+
+	var failed int
+	for _, path := range paths {
+		b, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		m := &runManifest{path: path}
+		if err := json.Unmarshal(b, m); err != nil {
+			e.Logger.Errf(logger.Red, "task: %s: invalid run manifest: %v\n", filepathext.TryAbsToRel(path), err)
+			failed++
+			continue
+		}
+		if m.PID != os.Getpid() && processExists(m.PID) {
+			e.Logger.VerboseOutf(logger.Yellow, "task: Skipping the run with PID %d, which is still running\n", m.PID)
+			continue
+		}
+		failed += e.cleanupManifest(m)
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("task: Unable to clean up %d resource(s)", failed)
+	}
+	e.Logger.VerboseOutf(logger.Green, "task: Clean up finished\n")
+	return nil
+}
+
+// cleanupManifest removes every resource of the manifest and returns how
+// many of them couldn't be removed. The manifest is deleted once empty.
+func (e *Executor) cleanupManifest(m *runManifest) int {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	for _, r := range append([]Resource(nil), m.Resources...) {
+		if err := removeResource(r); err != nil {
+			e.Logger.Errf(logger.Red, "task: Unable to remove %s %q: %v\n", r.Kind, r.ID, err)
+			continue
+		}
+		e.Logger.VerboseOutf(logger.Green, "task: Removed %s %q\n", r.Kind, r.ID)
+		m.remove(r)
+	}
+	if err := m.write(); err != nil {
+		e.Logger.Errf(logger.Red, "task: Unable to update the run manifest: %v\n", err)
+	}
+	return len(m.Resources)
+}
+
+func (m *runManifest) remove(r Resource) {
+	for i, resource := range m.Resources {
+		if resource == r {
+			m.Resources = append(m.Resources[:i], m.Resources[i+1:]...)
+			return
+		}
+	}
+}
+
+// write saves the manifest, or deletes it when there are no resources left.
+func (m *runManifest) write() error {
+	if m.path == "" {
+		return nil
+	}
+	if len(m.Resources) == 0 {
+		if err := os.Remove(m.path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		// Only removed when no other run has a manifest in it
+		_ = os.Remove(filepath.Dir(m.path))
+		return nil
+	}
+	b, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(m.path, b, 0o644)
+}
+
+func removeResource(r Resource) error {
+	switch r.Kind {
+	case ResourceTempDir:
+		return os.RemoveAll(r.ID)
+	case ResourceProcess:
+		var pid int
+		if _, err := fmt.Sscan(r.ID, &pid); err != nil {
+			return fmt.Errorf("invalid PID: %w", err)
+		}
+		if !processExists(pid) {
+			return nil
+		}
+		if r.Started == "" {
+			return fmt.Errorf("unknown start time of process %d, so it may not be the one started by the run", pid)
+		}
+		// The PID was reused by another process once the tracked one exited
+		if started, err := processStartTime(pid); err != nil || started != r.Started {
+			return nil
+		}
+		p, err := os.FindProcess(pid)
+		if err != nil {
+			return err
+		}
+		if r.Group {
+			return execext.SignalProcessGroup(p, os.Kill)
+		}
+		return p.Kill()
+	case ResourceContainer:
+		engine := r.Runtime
+		if engine == "" {
+			engine = "docker"
+		}
+		if out, err := exec.Command(engine, "rm", "--force", r.ID).CombinedOutput(); err != nil {
+			return fmt.Errorf("%w: %s", err, out)
+		}
+		return nil
+	default:
+		return fmt.Errorf("unknown resource kind %q", r.Kind)
+	}
+}
+
+// processExists reports whether a process with the given PID is running.
+func processExists(pid int) bool {
+	p, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+	// On Windows, finding the process already fails if it doesn't exist
+	if runtime.GOOS == "windows" {
+		return true
+	}
+	err = p.Signal(syscall.Signal(0))
+	return err == nil || err == syscall.EPERM
+}
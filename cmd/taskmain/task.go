@@ -2,14 +2,16 @@ package taskmain
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"log"
 	"os"
+	"os/signal"
 	"path/filepath"
 	"strings"
+	"syscall"
 	"time"
 
-	"github.com/nuvolaris/sh/v3/syntax"
 	"github.com/spf13/pflag"
 
 	"github.com/nuvolaris/task/v3"
@@ -17,10 +19,13 @@ import (
 	"github.com/nuvolaris/task/v3/errors"
 
 	"github.com/nuvolaris/task/v3/internal/experiments"
+	"github.com/nuvolaris/task/v3/internal/flagenv"
 	"github.com/nuvolaris/task/v3/internal/logger"
+	"github.com/nuvolaris/task/v3/internal/lsp"
 	"github.com/nuvolaris/task/v3/internal/sort"
 	ver "github.com/nuvolaris/task/v3/internal/version"
 	"github.com/nuvolaris/task/v3/taskfile"
+	"github.com/nuvolaris/task/v3/taskfile/read"
 )
 
 const usage = `Usage: task [flags...] [task...]
@@ -49,9 +54,13 @@ var flags struct {
 	version     bool
 	help        bool
 	init        bool
+	listTmpls   bool
 	list        bool
 	listAll     bool
 	listJson    bool
+	listVars    bool
+	which       bool
+	graph       bool
 	taskSort    string
 	status      bool
 	insecure    bool
@@ -61,20 +70,50 @@ var flags struct {
 	verbose     bool
 	silent      bool
 	assumeYes   bool
+	noInput     bool
+	hermetic    bool
+	slashPaths  bool
+	strict      bool
+	from        string
+	until       string
 	dry         bool
 	summary     bool
 	exitCode    bool
 	parallel    bool
+	failFast    bool
 	concurrency int
 	dir         string
 	entrypoint  string
 	output      taskfile.Output
 	color       bool
 	interval    time.Duration
+	timeout     time.Duration
+	dirs        []string
+	updateIncs  bool
+	watchMax    int
+	watchDelta  bool
+	watchListen string
+	serve       string
+	serveToken  string
+	stdioProto  bool
+	scheduler   bool
+	watchStatus bool
+	stripANSI   bool
 	global      bool
 	experiments bool
 	download    bool
 	offline     bool
+	rename      bool
+	cleanup     bool
+	clean       bool
+	genEnv      bool
+	lint        bool
+	lsp         bool
+	schema      bool
+	report      string
+	profile     bool
+	profileFile string
+	format      string
 }
 
 var plagsInitialized = false
@@ -86,15 +125,17 @@ func Task(_args []string) (int, error) {
 			Stdout:  os.Stdout,
 			Stderr:  os.Stderr,
 			Verbose: flags.verbose,
-			Color:   flags.color,
+			Color:   logger.UseColor(flags.color),
 		}
-		if err, ok := err.(*errors.TaskRunError); ok && flags.exitCode {
+		var runErr *errors.TaskRunError
+		if errors.As(err, &runErr) && flags.exitCode {
 			l.Errf(logger.Red, "%v\n", err)
-			return err.TaskExitCode(), err
+			return runErr.TaskExitCode(), err
 		}
-		if err, ok := err.(errors.TaskError); ok {
+		var taskErr errors.TaskError
+		if errors.As(err, &taskErr) {
 			l.Errf(logger.Red, "%v\n", err)
-			return err.Code(), err
+			return taskErr.Code(), err
 		}
 		l.Errf(logger.Red, "%v\n", err)
 		return errors.CodeUnknown, err
@@ -115,32 +156,69 @@ func run() error {
 
 		pflag.BoolVar(&flags.version, "version", false, "Show Task version.")
 		pflag.BoolVarP(&flags.help, "help", "h", false, "Shows Task usage.")
-		pflag.BoolVarP(&flags.init, "init", "i", false, "Creates a new Taskfile.yml in the current folder.")
+		pflag.BoolVarP(&flags.init, "init", "i", false, "Creates a new Taskfile.yml in the current folder, from the template given as argument if any.")
+		pflag.BoolVar(&flags.listTmpls, "list-templates", false, "Lists the templates of --init.")
 		pflag.BoolVarP(&flags.list, "list", "l", false, "Lists tasks with description of current Taskfile.")
 		pflag.BoolVarP(&flags.listAll, "list-all", "a", false, "Lists tasks with or without a description.")
-		pflag.BoolVarP(&flags.listJson, "json", "j", false, "Formats task list as JSON.")
+		pflag.BoolVarP(&flags.listJson, "json", "j", false, "Formats task list, the status with --status, the plan with --dry, or the version with --version, as JSON.")
+		pflag.BoolVar(&flags.listVars, "list-vars", false, "Lists the variables required by the given tasks.")
+		pflag.BoolVar(&flags.which, "which", false, "Prints the file and line where the given tasks are defined, and the includes that brought them in.")
+		pflag.BoolVar(&flags.graph, "graph", false, "Prints the given tasks, their deps and pipelines as a Graphviz DOT graph.")
 		pflag.StringVar(&flags.taskSort, "sort", "", "Changes the order of the tasks when listed. [default|alphanumeric|none].")
-		pflag.BoolVar(&flags.status, "status", false, "Exits with non-zero exit code if any of the given tasks is not up-to-date.")
+		pflag.BoolVar(&flags.status, "status", false, "Exits with non-zero exit code if any of the given tasks is not up-to-date. Tells why with --verbose.")
 		pflag.BoolVar(&flags.insecure, "insecure", false, "Forces Task to download Taskfiles over insecure connections.")
 		pflag.BoolVarP(&flags.watch, "watch", "w", false, "Enables watch of the given task.")
 		pflag.BoolVarP(&flags.verbose, "verbose", "v", false, "Enables verbose mode.")
 		pflag.BoolVarP(&flags.silent, "silent", "s", false, "Disables echoing.")
 		pflag.BoolVarP(&flags.assumeYes, "yes", "y", false, "Assume \"yes\" as answer to all prompts.")
+		pflag.BoolVar(&flags.noInput, "no-input", false, "Never ask for the values of variables, using their defaults instead.")
+		pflag.BoolVar(&flags.hermetic, "hermetic", false, "Runs the commands with only the environment variables allowed by the env_policy of the Taskfile, or the usual ones (PATH, HOME...) without one.")
+		pflag.BoolVar(&flags.slashPaths, "slash-paths", false, "Writes ROOT_DIR, TASKFILE_DIR and USER_WORKING_DIR with forward slashes, even on Windows.")
+		pflag.BoolVar(&flags.strict, "strict", false, "Turns the deprecation warnings into errors.")
 		pflag.BoolVarP(&flags.parallel, "parallel", "p", false, "Executes tasks provided on command line in parallel.")
+		pflag.BoolVar(&flags.failFast, "fail-fast", true, "Stops at the first failure of the tasks provided on command line. Set to false to run all of them and report every failure.")
+		pflag.StringVar(&flags.from, "from", "", "Runs only the tasks that run after the given one, including it, e.g. to resume a pipeline.")
+		pflag.StringVar(&flags.until, "until", "", "Runs only the tasks that run before the given one, including it.")
 		pflag.BoolVarP(&flags.dry, "dry", "n", false, "Compiles and prints tasks in the order that they would be run, without executing them.")
+		pflag.StringVar(&flags.format, "format", "", `Format of the --dry output. "sh" prints a shell script with the commands that would be run.`)
 		pflag.BoolVar(&flags.summary, "summary", false, "Show summary about a task.")
 		pflag.BoolVarP(&flags.exitCode, "exit-code", "x", false, "Pass-through the exit code of the task command.")
 		pflag.StringVarP(&flags.dir, "dir", "d", "", "Sets directory of execution.")
 		pflag.StringVarP(&flags.entrypoint, "taskfile", "t", "", `Choose which Taskfile to run. Defaults to "Taskfile.yml".`)
-		pflag.StringVarP(&flags.output.Name, "output", "o", "", "Sets output style: [interleaved|group|prefixed].")
+		pflag.StringSliceVar(&flags.dirs, "dirs", nil, `Runs the tasks in every directory matching the given patterns (e.g. "services/*") that has a Taskfile, in parallel.`)
+		pflag.StringVarP(&flags.output.Name, "output", "o", "", "Sets output style: [interleaved|group|prefixed|progress].")
 		pflag.StringVar(&flags.output.Group.Begin, "output-group-begin", "", "Message template to print before a task's grouped output.")
 		pflag.StringVar(&flags.output.Group.End, "output-group-end", "", "Message template to print after a task's grouped output.")
 		pflag.BoolVar(&flags.output.Group.ErrorOnly, "output-group-error-only", false, "Swallow output from successful tasks.")
+		pflag.BoolVar(&flags.output.Group.Nested, "output-group-nested", false, "Nest the grouped output of the tasks called by a task inside its own.")
+		pflag.StringVar(&flags.output.Timestamps, "output-timestamps", "", "Prefixes every line of output with a timestamp: [rfc3339|relative].")
+		pflag.Lookup("output-timestamps").NoOptDefVal = taskfile.OutputTimestampsRFC3339
+		pflag.BoolVar(&flags.stripANSI, "strip-ansi", false, "Removes the ANSI escape sequences, like colors, from the output of the commands when it isn't a terminal.")
 		pflag.BoolVarP(&flags.color, "color", "c", true, "Colored output. Enabled by default. Set flag to false or use NO_COLOR=1 to disable.")
 		pflag.IntVarP(&flags.concurrency, "concurrency", "C", 0, "Limit number tasks to run concurrently.")
-		pflag.DurationVarP(&flags.interval, "interval", "I", 0, "Interval to watch for changes.")
-		pflag.BoolVarP(&flags.global, "global", "g", false, "Runs global Taskfile, from $HOME/{T,t}askfile.{yml,yaml}.")
+		pflag.DurationVarP(&flags.interval, "interval", "I", 0, "Polls the sources for changes on this interval, instead of using the events of the file system.")
+		pflag.DurationVar(&flags.timeout, "timeout", 0, "Stops everything after the given time (e.g. 10m), including reading the Taskfiles.")
+		pflag.IntVar(&flags.watchMax, "watch-max-files", 0, "Maximum number of files to watch (default 10000). Set to -1 to disable the limit.")
+		pflag.BoolVar(&flags.watchDelta, "watch-delta", false, "Shows only the output that changed since the previous successful run when watching.")
+		pflag.StringVar(&flags.watchListen, "watch-listen", "", "Listens on the given address (e.g. localhost:8765) for POST requests that rerun the watched tasks.")
+		pflag.BoolVar(&flags.watchStatus, "watch-status", true, "Shows a status line and reads the keys r (rerun), c (clear) and q (quit) when watching in a terminal. Set to false to disable.")
+		pflag.StringVar(&flags.report, "report", "", "Writes a JSON report with the state of each task that ran to the given file.")
+		pflag.BoolVar(&flags.profile, "profile", false, "Prints how long each task and command took at the end of the run, slowest first.")
+		pflag.StringVar(&flags.profileFile, "profile-file", "", "Writes the timings of each task and command to the given file, in the Chrome trace event format.")
+		pflag.BoolVarP(&flags.global, "global", "g", false, "Runs global Taskfile, from $HOME/{T,t}askfile.{yml,yaml} or $XDG_CONFIG_HOME/task/{T,t}askfile.{yml,yaml}.")
 		pflag.BoolVar(&flags.experiments, "experiments", false, "Lists all the available experiments and whether or not they are enabled.")
+		pflag.BoolVar(&flags.rename, "rename", false, "Renames a task and updates all references to it. Usage: task --rename old:name new:name.")
+		pflag.BoolVar(&flags.updateIncs, "update-includes", false, "Updates the pinned versioned includes to the newest tag of their repository and locks them in Taskfile.lock.")
+		pflag.BoolVar(&flags.cleanup, "cleanup", false, "Removes the temporary resources left by runs that crashed or were killed.")
+		pflag.BoolVar(&flags.clean, "clean", false, "Removes the files generated by the given tasks, or by all of them, and their fingerprint state. Lists them with --dry.")
+		pflag.BoolVar(&flags.genEnv, "gen-env-example", false, "Writes a .env.example file with the environment variables used by the tasks.")
+		pflag.BoolVar(&flags.lint, "lint", false, "Reports the likely mistakes of the Taskfile, like unused vars or internal tasks nobody calls.")
+		pflag.BoolVar(&flags.lsp, "lsp", false, "Starts an experimental language server for editors, speaking the Language Server Protocol over stdin and stdout.")
+		pflag.StringVar(&flags.serve, "serve", "", "Serves an experimental HTTP API on the given address (e.g. localhost:8080) to list the tasks, run them and follow their output.")
+		pflag.StringVar(&flags.serveToken, "serve-token", os.Getenv("TASK_SERVE_TOKEN"), "Token the requests to --serve must carry as \"Authorization: Bearer <token>\". Needed to serve on addresses other than the loopback interface.")
+		pflag.BoolVar(&flags.stdioProto, "stdio-protocol", false, "Answers the experimental JSON-RPC requests of editors over stdin and stdout, to list the tasks, run them and follow their output.")
+		pflag.BoolVar(&flags.scheduler, "scheduler", false, "Runs the tasks with a schedule every time they're due, until interrupted. Logs the events as JSON lines with --json.")
+		pflag.BoolVar(&flags.schema, "schema", false, "Prints the JSON Schema of the Taskfile format, so editors can validate Taskfiles.")
 	}
 	// Gentle force experiment will override the force flag and add a new force-all flag
 	if experiments.GentleForce {
@@ -157,8 +235,16 @@ func run() error {
 	}
 
 	pflag.Parse()
+	if err := flagenv.Apply(pflag.CommandLine); err != nil {
+		return err
+	}
 
 	if flags.version {
+		if flags.listJson {
+			encoder := json.NewEncoder(os.Stdout)
+			encoder.SetIndent("", "  ")
+			return encoder.Encode(task.Version())
+		}
 		fmt.Printf("Task version: %s\n", ver.GetVersion())
 		return nil
 	}
@@ -173,17 +259,41 @@ func run() error {
 			Stdout:  os.Stdout,
 			Stderr:  os.Stderr,
 			Verbose: flags.verbose,
-			Color:   flags.color,
+			Color:   logger.UseColor(flags.color),
 		}
 		return experiments.List(l)
 	}
 
+	if flags.lsp {
+		return lsp.NewServer(os.Stdin, os.Stdout).Serve()
+	}
+
+	if flags.schema {
+		b, err := json.MarshalIndent(taskfile.Schema(), "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(b))
+		return nil
+	}
+
+	if flags.listTmpls {
+		return task.ListInitTemplates(os.Stdout)
+	}
+
 	if flags.init {
 		wd, err := os.Getwd()
 		if err != nil {
 			log.Fatal(err)
 		}
-		if err := task.InitTaskfile(os.Stdout, wd); err != nil {
+		if pflag.NArg() > 1 {
+			return errors.New("task: --init takes at most one template")
+		}
+		opts := []task.InitOption{
+			task.WithInitTemplate(pflag.Arg(0)),
+			task.WithInitForce(flags.force || flags.forceAll),
+		}
+		if err := task.InitTaskfile(os.Stdout, wd, opts...); err != nil {
 			log.Fatal(err)
 		}
 		return nil
@@ -198,17 +308,17 @@ func run() error {
 		return nil
 	}
 	if flags.global {
-		home, err := os.UserHomeDir()
+		dir, err := read.GlobalDir()
 		if err != nil {
-			return fmt.Errorf("task: Failed to get user home directory: %w", err)
+			return err
 		}
-		flags.dir = home
+		flags.dir = dir
 	}
 
 	if flags.dir != "" && flags.entrypoint != "" {
 		return errors.New("task: You can't set both --dir and --taskfile")
 	}
-	if flags.entrypoint != "" {
+	if flags.entrypoint != "" && !read.IsRemoteURI(flags.entrypoint) {
 		flags.dir = filepath.Dir(flags.entrypoint)
 		flags.entrypoint = filepath.Base(flags.entrypoint)
 	}
@@ -223,6 +333,9 @@ func run() error {
 		if flags.output.Group.ErrorOnly {
 			return errors.New("task: You can't set --output-group-error-only without --output=group")
 		}
+		if flags.output.Group.Nested {
+			return errors.New("task: You can't set --output-group-nested without --output=group")
+		}
 	}
 
 	var taskSorter sort.TaskSorter
@@ -234,23 +347,40 @@ func run() error {
 	}
 
 	e := task.Executor{
-		Force:       flags.force,
-		ForceAll:    flags.forceAll,
-		Insecure:    flags.insecure,
-		Download:    flags.download,
-		Offline:     flags.offline,
-		Watch:       flags.watch,
-		Verbose:     flags.verbose,
-		Silent:      flags.silent,
-		AssumeYes:   flags.assumeYes,
-		Dir:         flags.dir,
-		Dry:         flags.dry || flags.status,
-		Entrypoint:  flags.entrypoint,
-		Summary:     flags.summary,
-		Parallel:    flags.parallel,
-		Color:       flags.color,
-		Concurrency: flags.concurrency,
-		Interval:    flags.interval,
+		Force:         flags.force,
+		ForceAll:      flags.forceAll,
+		Insecure:      flags.insecure,
+		Download:      flags.download,
+		Offline:       flags.offline,
+		Watch:         flags.watch,
+		Verbose:       flags.verbose,
+		Silent:        flags.silent,
+		AssumeYes:     flags.assumeYes,
+		NoInput:       flags.noInput,
+		Hermetic:      flags.hermetic,
+		SlashPaths:    flags.slashPaths,
+		Strict:        flags.strict,
+		From:          flags.from,
+		Until:         flags.until,
+		Dir:           flags.dir,
+		Dry:           flags.dry || flags.status,
+		DryFormat:     flags.format,
+		Entrypoint:    flags.entrypoint,
+		Summary:       flags.summary,
+		Parallel:      flags.parallel,
+		NoFailFast:    !flags.failFast,
+		Color:         flags.color,
+		Concurrency:   flags.concurrency,
+		Interval:      flags.interval,
+		WatchMaxFiles: flags.watchMax,
+		WatchDelta:    flags.watchDelta,
+		WatchListen:   flags.watchListen,
+		NoWatchStatus: !flags.watchStatus,
+		StripANSI:     flags.stripANSI,
+		ReportFile:    flags.report,
+		Profile:       flags.profile,
+		ProfileFile:   flags.profileFile,
+		OTelExporter:  os.Getenv("TASK_OTEL_EXPORTER"),
 
 		Stdin:  os.Stdin,
 		Stdout: os.Stdout,
@@ -260,7 +390,58 @@ func run() error {
 		TaskSorter:  taskSorter,
 	}
 
-	listOptions := task.NewListOptions(flags.list, flags.listAll, flags.listJson)
+	if flags.format != "" && !flags.dry {
+		return errors.New("task: --format only applies to --dry")
+	}
+
+	// With --dry, --json prints the plan of the run instead of the commands
+	jsonPlan := flags.dry && flags.listJson && !flags.status && !flags.list && !flags.listAll
+	if jsonPlan && flags.format != "" {
+		return errors.New("task: --format doesn't apply to --dry --json")
+	}
+
+	if flags.watchDelta && !flags.watch {
+		return errors.New("task: --watch-delta only applies to --watch")
+	}
+
+	if flags.watchListen != "" && !flags.watch {
+		return errors.New("task: --watch-listen only applies to --watch")
+	}
+
+	if flags.serve != "" && (flags.watch || pflag.NArg() > 0) {
+		return errors.New("task: --serve doesn't take tasks to run nor --watch")
+	}
+
+	if flags.stdioProto {
+		if flags.serve != "" || flags.watch || pflag.NArg() > 0 {
+			return errors.New("task: --stdio-protocol doesn't take tasks to run nor --serve or --watch")
+		}
+		// The standard input and output are the ones of the protocol
+		e.Stdin, e.Stdout, e.NoInput = strings.NewReader(""), os.Stderr, true
+	}
+
+	if flags.scheduler && (flags.serve != "" || flags.stdioProto || flags.watch || pflag.NArg() > 0) {
+		return errors.New("task: --scheduler doesn't take tasks to run nor --serve, --stdio-protocol or --watch")
+	}
+
+	if flags.timeout < 0 {
+		return fmt.Errorf("task: The timeout can't be negative, got %v", flags.timeout)
+	}
+
+	if flags.timeout != 0 && flags.watch {
+		return errors.New("task: --timeout doesn't apply to --watch")
+	}
+
+	ctx := context.Background()
+	if flags.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, flags.timeout)
+		defer cancel()
+	}
+
+	// With --status, --json formats the status instead of the list, and with
+	// --scheduler the events
+	listOptions := task.NewListOptions(flags.list, flags.listAll, flags.listJson && !flags.status && !jsonPlan && !flags.scheduler)
 	if err := listOptions.Validate(); err != nil {
 		return err
 	}
@@ -270,8 +451,56 @@ func run() error {
 		return nil
 	}
 
-	if err := e.Setup(); err != nil {
-		return err
+	if flags.updateIncs {
+		return timedOut(ctx, e.UpdateIncludes(ctx))
+	}
+
+	if len(flags.dirs) > 0 {
+		if flags.entrypoint != "" || flags.watch || flags.status || flags.rename || flags.cleanup || flags.clean ||
+			flags.genEnv || flags.lint || flags.listVars || flags.which || flags.graph || listOptions.ShouldListTasks() {
+			return errors.New("task: --dirs only applies to running tasks")
+		}
+		tasksAndVars, cliArgs := getArgs()
+		calls, globals := args.ParseV3(tasksAndVars...)
+		if len(calls) == 0 {
+			calls = append(calls, taskfile.Call{Task: "default", Direct: true})
+		}
+		if err := args.SetCLIArgs(globals, cliArgs); err != nil {
+			return err
+		}
+		return timedOut(ctx, e.RunInDirs(ctx, flags.dirs, globals, calls...))
+	}
+
+	if err := e.SetupWithContext(ctx); err != nil {
+		return timedOut(ctx, err)
+	}
+	if flags.forceAll && !experiments.GentleForce {
+		if err := e.ReportForceAll(); err != nil {
+			return err
+		}
+	}
+
+	if flags.serve != "" {
+		ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+		defer stop()
+		return e.Serve(ctx, flags.serve, flags.serveToken)
+	}
+
+	if flags.stdioProto {
+		ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+		defer stop()
+		return e.ServeStdioProtocol(ctx, os.Stdin, os.Stdout)
+	}
+
+	if flags.scheduler {
+		ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+		defer stop()
+		var onEvent func(task.SchedulerEvent)
+		if flags.listJson {
+			encoder := json.NewEncoder(os.Stdout)
+			onEvent = func(event task.SchedulerEvent) { _ = encoder.Encode(event) }
+		}
+		return e.RunScheduler(ctx, onEvent)
 	}
 
 	if listOptions.ShouldListTasks() {
@@ -285,15 +514,43 @@ func run() error {
 		return nil
 	}
 
+	if flags.rename {
+		names := pflag.Args()
+		if len(names) != 2 {
+			return errors.New("task: --rename requires the current and the new task name")
+		}
+		return e.RenameTask(names[0], names[1])
+	}
+
+	if flags.cleanup {
+		return e.Cleanup()
+	}
+
+	if flags.genEnv {
+		return e.GenEnvExample()
+	}
+
+	if flags.lint {
+		issues, err := e.Lint()
+		if err != nil {
+			return err
+		}
+		for _, issue := range issues {
+			e.Logger.Outf(logger.Yellow, "%s\n", issue)
+		}
+		if len(issues) > 0 {
+			return &errors.TaskfileLintFailedError{Issues: len(issues)}
+		}
+		e.Logger.Outf(logger.Green, "task: No issues found\n")
+		return nil
+	}
+
 	var (
 		calls   []taskfile.Call
 		globals *taskfile.Vars
 	)
 
-	tasksAndVars, cliArgs, err := getArgs()
-	if err != nil {
-		return err
-	}
+	tasksAndVars, cliArgs := getArgs()
 
 	if e.Taskfile.Version.Compare(taskfile.V3) >= 0 {
 		calls, globals = args.ParseV3(tasksAndVars...)
@@ -301,6 +558,15 @@ func run() error {
 		calls, globals = args.ParseV2(tasksAndVars...)
 	}
 
+	// Without tasks, --clean cleans all of them instead of the default one
+	if flags.clean {
+		if err := args.SetCLIArgs(globals, cliArgs); err != nil {
+			return err
+		}
+		e.Taskfile.Vars.Merge(globals)
+		return timedOut(ctx, e.Clean(ctx, calls...))
+	}
+
 	// If there are no calls, run the default task instead
 	// Unless the download flag is specified, in which case we want to download
 	// the Taskfile and do nothing else
@@ -308,39 +574,64 @@ func run() error {
 		calls = append(calls, taskfile.Call{Task: "default", Direct: true})
 	}
 
-	globals.Set("CLI_ARGS", taskfile.Var{Static: cliArgs})
+	if flags.which {
+		return e.Which(calls...)
+	}
+
+	calls, err := e.BindArgs(calls)
+	if err != nil {
+		return err
+	}
+
+	if flags.listVars {
+		return e.ListTaskVars(calls...)
+	}
+
+	if err := args.SetCLIArgs(globals, cliArgs); err != nil {
+		return err
+	}
 	e.Taskfile.Vars.Merge(globals)
 
+	if flags.graph {
+		return e.Graph(calls...)
+	}
+
 	if !flags.watch {
 		e.InterceptInterruptSignals()
 	}
 
-	ctx := context.Background()
-
 	if flags.status {
-		return e.Status(ctx, calls...)
+		if flags.listJson {
+			return timedOut(ctx, e.StatusJSON(ctx, calls...))
+		}
+		return timedOut(ctx, e.Status(ctx, calls...))
 	}
 
-	return e.Run(ctx, calls...)
+	if jsonPlan {
+		return timedOut(ctx, e.PlanJSON(ctx, calls...))
+	}
+
+	return timedOut(ctx, e.Run(ctx, calls...))
 }
 
-func getArgs() ([]string, string, error) {
+// timedOut tells the errors caused by the --timeout apart.
+func timedOut(ctx context.Context, err error) error {
+	if err != nil && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return &errors.TaskTimeoutError{Timeout: flags.timeout, Err: err}
+	}
+	return err
+}
+
+// getArgs splits the arguments into the tasks and variables, and the CLI_ARGS
+// given after "--".
+func getArgs() ([]string, []string) {
 	var (
 		args          = pflag.Args()
 		doubleDashPos = pflag.CommandLine.ArgsLenAtDash()
 	)
 
 	if doubleDashPos == -1 {
-		return args, "", nil
-	}
-
-	var quotedCliArgs []string
-	for _, arg := range args[doubleDashPos:] {
-		quotedCliArg, err := syntax.Quote(arg, syntax.LangBash)
-		if err != nil {
-			return nil, "", err
-		}
-		quotedCliArgs = append(quotedCliArgs, quotedCliArg)
+		return args, nil
 	}
-	return args[:doubleDashPos], strings.Join(quotedCliArgs, " "), nil
+	return args[:doubleDashPos], args[doubleDashPos:]
 }
@@ -48,6 +48,11 @@ function _task()
   local tasks=( $( "${words[@]}" --silent $_GO_TASK_COMPLETION_LIST_OPTION 2> /dev/null ) )
   COMPREPLY=( $( compgen -W "${tasks[*]}" -- "$cur" ) )
 
+  # Prepare completions of the variables required by the given tasks.
+  local vars=( $( "${words[@]:0:$cword}" --silent --list-vars 2> /dev/null ) )
+  COMPREPLY+=( $( compgen -S = -W "${vars[*]}" -- "$cur" ) )
+  [[ ${#COMPREPLY[@]} -eq 1 && $COMPREPLY == *= ]] && compopt -o nospace
+
   # Post-process because task names might contain colons.
   __ltrim_colon_completions "$cur"
 }
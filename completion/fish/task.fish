@@ -10,14 +10,24 @@ function __task_get_tasks --description "Prints all available tasks with their d
   end
 
   # Grab names and descriptions (if any) of the tasks
-  set -l output (echo $rawOutput | sed -e '1d; s/\* \(.*\):\s*\(.*\)\s*(aliases.*/\1\t\2/' -e 's/\* \(.*\):\s*\(.*\)/\1\t\2/'| string split0)
+  set -l output (echo $rawOutput | sed -e '/^\* /!d; s/\* \(.*\):\s*\(.*\)\s*(aliases.*/\1\t\2/' -e 's/\* \(.*\):\s*\(.*\)/\1\t\2/'| string split0)
   if test $output
     echo $output
   end
 end
 
-complete -c $GO_TASK_PROGNAME -d 'Runs the specified task(s). Falls back to the "default" task if no task name was specified, or lists all tasks if an unknown task name was
+function __task_get_vars --description "Prints the variables required by the tasks given so far"
+  set -l args (commandline -opc)
+  $args --silent --list-vars 2>/dev/null | string replace -r '$' '='
+end
+
+function __task_no_cli_args --description "Checks that `--` hasn't been given yet"
+  not contains -- -- (commandline -opc)
+end
+
+complete -c $GO_TASK_PROGNAME -n __task_no_cli_args -d 'Runs the specified task(s). Falls back to the "default" task if no task name was specified, or lists all tasks if an unknown task name was
 specified.' -xa "(__task_get_tasks)"
+complete -c $GO_TASK_PROGNAME -n __task_no_cli_args -d 'Sets a variable required by the task' -xa "(__task_get_vars)"
 
 complete -c $GO_TASK_PROGNAME -s c -l color     -d 'colored output (default true)'
 complete -c $GO_TASK_PROGNAME -s d -l dir       -d 'sets directory of execution'
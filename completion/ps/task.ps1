@@ -3,6 +3,13 @@ using namespace System.Management.Automation
 Register-ArgumentCompleter -CommandName task -ScriptBlock {
 	param($commandName, $parameterName, $wordToComplete, $commandAst, $fakeBoundParameters)
 
+	$elements = $commandAst.CommandElements | Select-Object -Skip 1 | ForEach-Object { $_.Extent.Text } | Where-Object { $_ -ne $commandName }
+
+	# Do not complete words following `--` passed to CLI_ARGS
+	if ($elements -contains '--') {
+		return
+	}
+
 	if ($commandName.StartsWith('-')) {
 		$completions = @(
 			[CompletionResult]::new('--list-all ', '--list-all ', [CompletionResultType]::ParameterName, 'list all tasks'),
@@ -24,5 +31,8 @@ Register-ArgumentCompleter -CommandName task -ScriptBlock {
 		return $completions.Where{ $_.CompletionText.StartsWith($commandName) }
 	}
 
-	return 	$(task --list-all --silent) | Where-Object { $_.StartsWith($commandName) } | ForEach-Object { return $_ + " " }
+	$tasks = $(task --list-all --silent) | Where-Object { $_.StartsWith($commandName) } | ForEach-Object { return $_ + " " }
+	$vars = $(task @elements --silent --list-vars 2>$null) | ForEach-Object { return $_ + "=" } | Where-Object { $_.StartsWith($commandName) }
+
+	return @($tasks) + @($vars)
 }
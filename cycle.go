@@ -0,0 +1,70 @@
+package task
+
+import (
+	"strings"
+
+	"golang.org/x/exp/slices"
+
+	"github.com/nuvolaris/task/v3/errors"
+	"github.com/nuvolaris/task/v3/taskfile"
+)
+
+// checkDepCycles returns an error if a task depends on itself, directly or not,
+// through its deps and pipeline stages, which would otherwise be called until
+// MaximumTaskCall is reached. The deps with a templated name and the ones that
+// don't exist are skipped, since they can only be resolved when running.
+// The calls from commands aren't checked: they can stop the recursion with
+// their vars.
+func (e *Executor) checkDepCycles() error {
+	checked := make(map[string]bool, e.Taskfile.Tasks.Len())
+	var check func(t *taskfile.Task, chain []string) error
+	check = func(t *taskfile.Task, chain []string) error {
+		if checked[t.Task] {
+			return nil
+		}
+		if i := slices.Index(chain, t.Task); i >= 0 {
+			return &errors.TaskDependencyCycleError{Cycle: append(slices.Clone(chain[i:]), t.Task)}
+		}
+		chain = append(chain, t.Task)
+		for _, name := range depNames(t) {
+			dep, err := e.GetTask(taskfile.Call{Task: name})
+			if err != nil {
+				continue
+			}
+			if err := check(dep, chain); err != nil {
+				return err
+			}
+		}
+		checked[t.Task] = true
+		return nil
+	}
+	for _, t := range e.Taskfile.Tasks.Values() {
+		if err := check(t, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// depNames returns the names of the tasks that must run before the given one.
+func depNames(t *taskfile.Task) []string {
+	var names []string
+	for _, d := range t.Deps {
+		if d != nil {
+			names = append(names, d.Task)
+		}
+	}
+	for _, stage := range t.Pipeline {
+		if stage == nil {
+			continue
+		}
+		for _, d := range stage.Tasks {
+			if d != nil {
+				names = append(names, d.Task)
+			}
+		}
+	}
+	return slices.DeleteFunc(names, func(name string) bool {
+		return name == "" || strings.Contains(name, "{{")
+	})
+}
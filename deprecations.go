@@ -0,0 +1,103 @@
+package task
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/nuvolaris/task/v3/internal/deprecations"
+	"github.com/nuvolaris/task/v3/internal/templater"
+)
+
+// ReportForceAll reports the use of --force to force the deps of the tasks
+// too, which won't be forced by it in a future release. It fails when Strict,
+// like the other deprecations.
+func (e *Executor) ReportForceAll() error {
+	if err := e.setupIfNeeded(context.Background()); err != nil {
+		return err
+	}
+	return e.deprecations.Report(deprecations.ForceAll, "--force will only force the called tasks, not their dependencies, in a future release. Use --force-all with TASK_X_FORCE=1 to keep forcing them")
+}
+
+// checkDeprecatedFuncs reports the deprecated template functions used by the
+// vars and env of the Taskfile and by its tasks.
+func (e *Executor) checkDeprecatedFuncs() error {
+	if err := e.checkDeprecatedFuncsIn("the Taskfile", e.Taskfile.Vars, e.Taskfile.Env); err != nil {
+		return err
+	}
+	for _, t := range e.Taskfile.Tasks.Values() {
+		if err := e.checkDeprecatedFuncsIn(fmt.Sprintf("task %q", t.Task), t); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkDeprecatedFuncsIn reports the deprecated template functions used by
+// the templates found anywhere in the values.
+func (e *Executor) checkDeprecatedFuncsIn(where string, values ...any) error {
+	var templates []string
+	visited := map[uintptr]bool{}
+	for _, v := range values {
+		findTemplates(reflect.ValueOf(v), visited, &templates)
+	}
+
+	for _, str := range templates {
+		tmpl, err := templater.Parse(str)
+		if err != nil {
+			// Reported when the template is used
+			continue
+		}
+		for _, name := range templater.FuncNames(tmpl) {
+			replacement, ok := templater.DeprecatedFuncs[name]
+			if !ok {
+				continue
+			}
+			message := fmt.Sprintf("%s uses the %q template function, which is deprecated", where, name)
+			if replacement != "" {
+				message += fmt.Sprintf(", use %q instead", replacement)
+			}
+			if err := e.deprecations.Report(deprecations.TemplateFunctions, message); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// findTemplates appends the strings that hold a template anywhere in v, in a
+// deterministic order. The pointers already visited are skipped.
+func findTemplates(v reflect.Value, visited map[uintptr]bool, templates *[]string) {
+	switch v.Kind() {
+	case reflect.Pointer:
+		if v.IsNil() || visited[v.Pointer()] {
+			return
+		}
+		visited[v.Pointer()] = true
+		findTemplates(v.Elem(), visited, templates)
+	case reflect.Interface:
+		findTemplates(v.Elem(), visited, templates)
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			findTemplates(v.Field(i), visited, templates)
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			findTemplates(v.Index(i), visited, templates)
+		}
+	case reflect.Map:
+		keys := v.MapKeys()
+		sort.Slice(keys, func(i, j int) bool {
+			return fmt.Sprint(keys[i]) < fmt.Sprint(keys[j])
+		})
+		for _, k := range keys {
+			findTemplates(v.MapIndex(k), visited, templates)
+		}
+	case reflect.String:
+		if str := v.String(); strings.Contains(str, "{{") {
+			*templates = append(*templates, str)
+		}
+	}
+}
@@ -0,0 +1,197 @@
+package task
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+
+	"golang.org/x/sync/errgroup"
+
+	"github.com/nuvolaris/task/v3/errors"
+	"github.com/nuvolaris/task/v3/internal/filepathext"
+	"github.com/nuvolaris/task/v3/internal/output"
+	"github.com/nuvolaris/task/v3/taskfile"
+	"github.com/nuvolaris/task/v3/taskfile/read"
+)
+
+// RunInDirs runs the given tasks in every directory matching the glob
+// patterns (e.g. "services/*") that has a Taskfile, with the given global
+// variables. The patterns are relative to Dir.
+//
+// Every directory gets its own Executor, configured like this one, and the
+// lines they write are prefixed with the directory. At most Concurrency
+// directories run at the same time, or as many as the CPUs when it's zero.
+// Like Run, the first failure stops the other directories, unless NoFailFast
+// is set.
+func (e *Executor) RunInDirs(ctx context.Context, patterns []string, globals *taskfile.Vars, calls ...taskfile.Call) error {
+	if err := e.validate(); err != nil {
+		return err
+	}
+	root := e.Dir
+	if root == "" {
+		wd, err := os.Getwd()
+		if err != nil {
+			return err
+		}
+		root = wd
+	}
+	dirs, err := findTaskfileDirs(root, patterns)
+	if err != nil {
+		return err
+	}
+	if len(dirs) == 0 {
+		return fmt.Errorf("task: No directory matching %s has a Taskfile", strings.Join(patterns, ", "))
+	}
+
+	limit := e.Concurrency
+	if limit <= 0 {
+		limit = runtime.NumCPU()
+	}
+
+	var g *errgroup.Group
+	if e.NoFailFast {
+		g = &errgroup.Group{}
+	} else {
+		g, ctx = errgroup.WithContext(ctx)
+	}
+	g.SetLimit(limit)
+
+	// The directories write whole lines, so their output is never mixed up
+	var mutex sync.Mutex
+	out := &lockedWriter{w: e.Stdout, mutex: &mutex}
+	style := output.Prefixed{Color: e.Color}
+
+	errs := make([]error, len(dirs))
+	for i, dir := range dirs {
+		i, dir := i, dir
+		rel, err := filepath.Rel(root, dir)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		g.Go(func() error {
+			w, _, close := style.WrapWriter(out, out, rel, nil)
+			// The commands and Task itself write to w at the same time
+			err := e.runInDir(ctx, dir, &lockedWriter{w: w, mutex: &sync.Mutex{}}, globals, calls)
+			if closeErr := close(err); err == nil {
+				err = closeErr
+			}
+			if err != nil {
+				errs[i] = fmt.Errorf("%s: %w", rel, err)
+			}
+			return errs[i]
+		})
+	}
+	if err := g.Wait(); err != nil && !e.NoFailFast {
+		return err
+	}
+
+	var failed []error
+	for _, err := range errs {
+		if err != nil {
+			failed = append(failed, err)
+		}
+	}
+	switch len(failed) {
+	case 0:
+		return nil
+	case 1:
+		return failed[0]
+	default:
+		return &errors.TasksFailedError{Errs: failed}
+	}
+}
+
+// runInDir runs the given tasks with the Taskfile of the given directory,
+// writing everything to w.
+func (e *Executor) runInDir(ctx context.Context, dir string, w io.Writer, globals *taskfile.Vars, calls []taskfile.Call) error {
+	entrypoint, err := read.Exists(dir)
+	if err != nil {
+		return err
+	}
+	sub := e.subExecutor()
+	sub.Dir = dir
+	sub.Entrypoint = filepath.Base(entrypoint)
+	sub.Stdout = w
+	sub.Stderr = w
+	if err := sub.SetupWithContext(ctx); err != nil {
+		return err
+	}
+	sub.Taskfile.Vars.Merge(globals)
+	return sub.Run(ctx, calls...)
+}
+
+// subExecutorFields are the exported fields of an Executor that every
+// directory sets up for its own Taskfile, instead of getting them from the
+// Executor running them all.
+var subExecutorFields = map[string]bool{
+	"Taskfile":   true,
+	"Dir":        true,
+	"Entrypoint": true,
+	"Stdout":     true,
+	"Stderr":     true,
+	"Logger":     true,
+	"Compiler":   true,
+	"Output":     true,
+}
+
+// subExecutor returns a new Executor with all the options of this one, so
+// that the ones added later are never forgotten. The unexported fields, the
+// state of a run, are left to its setup.
+func (e *Executor) subExecutor() *Executor {
+	sub := &Executor{}
+	src, dst := reflect.ValueOf(e).Elem(), reflect.ValueOf(sub).Elem()
+	for i := 0; i < src.NumField(); i++ {
+		if field := src.Type().Field(i); field.IsExported() && !subExecutorFields[field.Name] {
+			dst.Field(i).Set(src.Field(i))
+		}
+	}
+	return sub
+}
+
+// findTaskfileDirs returns the directories matching the glob patterns that
+// have a Taskfile, sorted and without duplicates.
+func findTaskfileDirs(root string, patterns []string) ([]string, error) {
+	seen := make(map[string]bool)
+	var dirs []string
+	for _, pattern := range patterns {
+		matches, err := filepath.Glob(filepathext.SmartJoin(root, pattern))
+		if err != nil {
+			return nil, fmt.Errorf("task: Invalid pattern %q given to --dirs: %w", pattern, err)
+		}
+		for _, match := range matches {
+			if seen[match] {
+				continue
+			}
+			if info, err := os.Stat(match); err != nil || !info.IsDir() {
+				continue
+			}
+			if _, err := read.Exists(match); err != nil {
+				continue
+			}
+			seen[match] = true
+			dirs = append(dirs, match)
+		}
+	}
+	sort.Strings(dirs)
+	return dirs, nil
+}
+
+// lockedWriter serializes the writes of many goroutines.
+type lockedWriter struct {
+	w     io.Writer
+	mutex *sync.Mutex
+}
+
+func (lw *lockedWriter) Write(p []byte) (int, error) {
+	lw.mutex.Lock()
+	defer lw.mutex.Unlock()
+	return lw.w.Write(p)
+}
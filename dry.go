@@ -0,0 +1,87 @@
+package task
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+
+	"github.com/nuvolaris/sh/v3/syntax"
+
+	"github.com/nuvolaris/task/v3/internal/slicesext"
+	"github.com/nuvolaris/task/v3/taskfile"
+)
+
+// DryFormatSh prints the commands of a dry run as a standalone POSIX shell
+// script instead of logging them.
+const DryFormatSh = "sh"
+
+// dryScript writes the commands of a dry run as a shell script. Consecutive
+// commands of the same task are grouped in a subshell that sets the task
+// directory and environment, so they don't leak into other tasks.
+type dryScript struct {
+	w       io.Writer
+	mutex   sync.Mutex
+	started bool
+	current *taskfile.Task
+}
+
+func (s *dryScript) writeCmd(e *Executor, t *taskfile.Task, cmd *taskfile.Cmd) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	var b strings.Builder
+	if !s.started {
+		b.WriteString("#!/bin/sh\nset -e\n")
+		s.started = true
+	}
+	if s.current != t {
+		if s.current != nil {
+			b.WriteString(")\n")
+		}
+		s.current = t
+		fmt.Fprintf(&b, "\n# task: %s\n(\n", t.Name())
+		if t.Dir != "" {
+			fmt.Fprintf(&b, "cd %s\n", shQuote(t.Dir))
+		}
+		_ = t.Env.Range(func(k string, v taskfile.Var) error {
+			fmt.Fprintf(&b, "export %s=%s\n", k, shQuote(v.Static))
+			return nil
+		})
+	}
+	for _, opt := range slicesext.UniqueJoin(e.Taskfile.Set, t.Set, cmd.Set) {
+		if len(opt) == 1 {
+			fmt.Fprintf(&b, "set -%s\n", opt)
+		} else {
+			fmt.Fprintf(&b, "set -o %s\n", opt)
+		}
+	}
+	b.WriteString(strings.TrimRight(cmd.Cmd, "\n"))
+	b.WriteString("\n")
+
+	_, err := io.WriteString(s.w, b.String())
+	return err
+}
+
+// close ends the subshell of the last task, if any.
+func (s *dryScript) close() error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.current == nil {
+		return nil
+	}
+	s.current = nil
+	_, err := io.WriteString(s.w, ")\n")
+	return err
+}
+
+func shQuote(s string) string {
+	quoted, err := syntax.Quote(s, syntax.LangPOSIX)
+	if err != nil {
+		// Values that can't be quoted (e.g. containing null bytes) are
+		// written as they are instead.
+		return s
+	}
+	return quoted
+}
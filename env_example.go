@@ -0,0 +1,223 @@
+package task
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/joho/godotenv"
+	"github.com/nuvolaris/sh/v3/syntax"
+	"golang.org/x/exp/slices"
+
+	"github.com/nuvolaris/task/v3/internal/filepathext"
+	"github.com/nuvolaris/task/v3/internal/logger"
+	"github.com/nuvolaris/task/v3/taskfile"
+	"github.com/nuvolaris/task/v3/taskfile/read"
+)
+
+// EnvExampleFile is the name of the file written by GenEnvExample.
+const EnvExampleFile = ".env.example"
+
+// wellKnownEnv are variables set by the system or the shell itself, which
+// never have to be documented.
+var wellKnownEnv = []string{
+	"HOME", "IFS", "OLDPWD", "OPTARG", "OPTIND", "PATH", "PPID", "PWD",
+	"RANDOM", "SHELL", "TERM", "TMPDIR", "UID", "USER",
+}
+
+type envExampleVar struct {
+	dotenv     bool
+	requiredBy []string
+	usedBy     []string
+}
+
+// GenEnvExample writes a .env.example file next to the Taskfile. It lists the
+// variables declared in `requires:`, the ones loaded from dotenv files and
+// the ones the commands read from the environment without being set by an
+// `env:` declaration, along with the tasks that need them. Values are always
+// left empty, so secrets in dotenv files are never copied.
+func (e *Executor) GenEnvExample() error {
+	ctx := context.Background()
+	if err := e.setupIfNeeded(ctx); err != nil {
+		return err
+	}
+	vars := map[string]*envExampleVar{}
+	get := func(name string) *envExampleVar {
+		if vars[name] == nil {
+			vars[name] = &envExampleVar{}
+		}
+		return vars[name]
+	}
+
+	dotenv, err := read.Dotenv(ctx, e.Compiler, e.Taskfile, e.Dir)
+	if err != nil {
+		return err
+	}
+	_ = dotenv.Range(func(k string, _ taskfile.Var) error {
+		get(k).dotenv = true
+		return nil
+	})
+
+	// Variables set by the Taskfile itself don't have to come from outside
+	defined := map[string]bool{}
+	_ = e.Taskfile.Env.Range(func(k string, _ taskfile.Var) error {
+		if !dotenv.Exists(k) {
+			defined[k] = true
+		}
+		return nil
+	})
+	globalScripts := shellScripts(e.Taskfile.Vars, e.Taskfile.Env)
+
+	for _, name := range e.Taskfile.Tasks.Keys() {
+		t := e.Taskfile.Tasks.Get(name)
+		if t.Requires != nil {
+			for _, v := range t.Requires.Vars {
+				get(v).requiredBy = appendUnique(get(v).requiredBy, name)
+			}
+		}
+
+		if len(t.Dotenv) > 0 {
+			compiled, err := e.FastCompiledTask(ctx, taskfile.Call{Task: name})
+			if err != nil {
+				return err
+			}
+			for _, dotEnvPath := range compiled.Dotenv {
+				dotEnvPath = filepathext.SmartJoin(compiled.Dir, dotEnvPath)
+				if _, err := os.Stat(dotEnvPath); os.IsNotExist(err) {
+					continue
+				}
+				envs, err := godotenv.Read(dotEnvPath)
+				if err != nil {
+					return err
+				}
+				for key := range envs {
+					get(key).dotenv = true
+				}
+			}
+		}
+
+		scripts := shellScripts(t.Vars, t.Env)
+		for _, c := range t.Cmds {
+			if c.Cmd != "" {
+				scripts = append(scripts, c.Cmd)
+			}
+		}
+		for _, p := range t.Preconditions {
+			scripts = append(scripts, p.Sh)
+		}
+		scripts = append(scripts, t.Status...)
+
+		for _, script := range scripts {
+			for _, v := range envReferences(script) {
+				if defined[v] || t.Env.Exists(v) {
+					continue
+				}
+				get(v).usedBy = appendUnique(get(v).usedBy, name)
+			}
+		}
+	}
+
+	// Scripts of global vars and env run for every task
+	for _, script := range globalScripts {
+		for _, v := range envReferences(script) {
+			if !defined[v] {
+				get(v)
+			}
+		}
+	}
+
+	names := make([]string, 0, len(vars))
+	for name := range vars {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "# Environment variables used by the tasks of %s.\n", e.Entrypoint)
+	b.WriteString("# Generated by `task --gen-env-example`.\n")
+	for _, name := range names {
+		v := vars[name]
+		b.WriteString("\n")
+		if len(v.requiredBy) > 0 {
+			fmt.Fprintf(&b, "# Required by: %s\n", strings.Join(v.requiredBy, ", "))
+		}
+		if len(v.usedBy) > 0 {
+			fmt.Fprintf(&b, "# Used by: %s\n", strings.Join(v.usedBy, ", "))
+		}
+		if v.dotenv {
+			b.WriteString("# Loaded from a dotenv file\n")
+		}
+		if len(v.requiredBy) == 0 && len(v.usedBy) == 0 && !v.dotenv {
+			b.WriteString("# Used by the Taskfile variables\n")
+		}
+		fmt.Fprintf(&b, "%s=\n", name)
+	}
+
+	path := filepathext.SmartJoin(e.Dir, EnvExampleFile)
+	if err := os.WriteFile(path, []byte(b.String()), 0o644); err != nil {
+		return err
+	}
+	e.Logger.Outf(logger.Green, "task: Wrote %d variable(s) to %s\n", len(names), filepathext.TryAbsToRel(path))
+	return nil
+}
+
+// shellScripts returns the scripts of the dynamic variables.
+func shellScripts(vars ...*taskfile.Vars) []string {
+	var scripts []string
+	for _, vs := range vars {
+		_ = vs.Range(func(_ string, v taskfile.Var) error {
+			if v.Sh != "" {
+				scripts = append(scripts, v.Sh)
+			}
+			return nil
+		})
+	}
+	return scripts
+}
+
+// envReferences returns the variables a script reads, leaving out the ones
+// it assigns itself (e.g. loop counters) and the special parameters.
+func envReferences(script string) []string {
+	file, err := syntax.NewParser().Parse(strings.NewReader(script), "")
+	if err != nil {
+		// Scripts generated by templates may not parse before being compiled
+		return nil
+	}
+
+	var refs []string
+	assigned := map[string]bool{}
+	syntax.Walk(file, func(node syntax.Node) bool {
+		switch n := node.(type) {
+		case *syntax.Assign:
+			if n.Name != nil {
+				assigned[n.Name.Value] = true
+			}
+		case *syntax.ForClause:
+			if iter, ok := n.Loop.(*syntax.WordIter); ok {
+				assigned[iter.Name.Value] = true
+			}
+		case *syntax.ParamExp:
+			if syntax.ValidName(n.Param.Value) {
+				refs = appendUnique(refs, n.Param.Value)
+			}
+		}
+		return true
+	})
+
+	result := refs[:0]
+	for _, ref := range refs {
+		if !assigned[ref] && !slices.Contains(wellKnownEnv, ref) {
+			result = append(result, ref)
+		}
+	}
+	return result
+}
+
+func appendUnique(s []string, v string) []string {
+	if slices.Contains(s, v) {
+		return s
+	}
+	return append(s, v)
+}
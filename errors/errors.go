@@ -17,6 +17,10 @@ const (
 	CodeTaskfileNotTrusted
 	CodeTaskfileNotSecure
 	CodeTaskfileCacheNotFound
+	CodeTaskfileLockMismatch
+	CodeTaskfileVerificationFailed
+	CodeTaskfileDeprecated
+	CodeTaskfileLintFailed
 )
 
 // Task related exit codes
@@ -28,6 +32,8 @@ const (
 	CodeTaskCalledTooManyTimes
 	CodeTaskCancelled
 	CodeTaskMissingRequiredVars
+	CodeTaskPreconditionFailed
+	CodeTaskTimeout
 )
 
 // TaskError extends the standard error interface with a Code method. This code will
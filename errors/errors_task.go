@@ -3,6 +3,7 @@ package errors
 import (
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/nuvolaris/sh/v3/interp"
 )
@@ -45,6 +46,10 @@ func (err *TaskRunError) Code() int {
 	return CodeTaskRunError
 }
 
+func (err *TaskRunError) Unwrap() error {
+	return err.Err
+}
+
 func (err *TaskRunError) TaskExitCode() int {
 	if c, ok := interp.IsExitStatus(err.Err); ok {
 		return int(c)
@@ -52,6 +57,28 @@ func (err *TaskRunError) TaskExitCode() int {
 	return err.Code()
 }
 
+// TasksFailedError is returned when many of the given tasks fail, because the
+// run didn't stop at the first failure.
+type TasksFailedError struct {
+	Errs []error
+}
+
+func (err *TasksFailedError) Error() string {
+	msgs := make([]string, len(err.Errs))
+	for i, e := range err.Errs {
+		msgs[i] = e.Error()
+	}
+	return fmt.Sprintf("task: %d tasks failed:\n%s", len(err.Errs), strings.Join(msgs, "\n"))
+}
+
+func (err *TasksFailedError) Code() int {
+	return CodeTaskRunError
+}
+
+func (err *TasksFailedError) Unwrap() []error {
+	return err.Errs
+}
+
 // TaskInternalError when the user attempts to invoke a task that is internal.
 type TaskInternalError struct {
 	TaskName string
@@ -99,6 +126,21 @@ func (err *TaskCalledTooManyTimesError) Code() int {
 	return CodeTaskCalledTooManyTimes
 }
 
+// TaskDependencyCycleError is returned when a task depends on itself, through
+// its deps or its pipeline. It has the same exit code as
+// TaskCalledTooManyTimesError, which is what the cycle would end with.
+type TaskDependencyCycleError struct {
+	Cycle []string
+}
+
+func (err *TaskDependencyCycleError) Error() string {
+	return fmt.Sprintf(`task: Task %q depends on itself: %s`, err.Cycle[0], strings.Join(err.Cycle, " -> "))
+}
+
+func (err *TaskDependencyCycleError) Code() int {
+	return CodeTaskCalledTooManyTimes
+}
+
 // TaskCancelledByUserError is returned when the user does not accept an optional prompt to continue.
 type TaskCancelledByUserError struct {
 	TaskName string
@@ -145,3 +187,97 @@ func (err *TaskMissingRequiredVars) Error() string {
 func (err *TaskMissingRequiredVars) Code() int {
 	return CodeTaskMissingRequiredVars
 }
+
+// TaskMissingRequiredArgs is returned when a task is called without its
+// required positional arguments.
+type TaskMissingRequiredArgs struct {
+	TaskName    string
+	MissingArgs []string
+	Usage       string
+}
+
+func (err *TaskMissingRequiredArgs) Error() string {
+	return fmt.Sprintf(
+		`task: Task %q cancelled because it is missing required arguments: %s. Usage: task %s %s`,
+		err.TaskName,
+		strings.Join(err.MissingArgs, ", "),
+		err.TaskName,
+		err.Usage,
+	)
+}
+
+func (err *TaskMissingRequiredArgs) Code() int {
+	return CodeTaskMissingRequiredVars
+}
+
+// VarPromptUnavailableError is returned when a variable with a prompt isn't
+// set and has no default, but the user can't be asked for its value.
+type VarPromptUnavailableError struct {
+	VarName string
+	NoInput bool
+}
+
+func (err *VarPromptUnavailableError) Error() string {
+	reason := "the environment is not a terminal"
+	if err.NoInput {
+		reason = "--no-input is set"
+	}
+	return fmt.Sprintf(
+		`task: Variable %q has no value or default and can't be asked for, because %s. Set it with %s=value`,
+		err.VarName,
+		reason,
+		err.VarName,
+	)
+}
+
+func (err *VarPromptUnavailableError) Code() int {
+	return CodeTaskMissingRequiredVars
+}
+
+// ErrPreconditionFailed is matched by every TaskPreconditionError, for
+// callers that only need to know that a precondition failed.
+var ErrPreconditionFailed = New("task: precondition not met")
+
+// TaskPreconditionError is returned when a precondition of a task is not met.
+type TaskPreconditionError struct {
+	TaskName string
+	Msg      string
+}
+
+func (err *TaskPreconditionError) Error() string {
+	if err.Msg == "" {
+		return ErrPreconditionFailed.Error()
+	}
+	return fmt.Sprintf("%s: %s", ErrPreconditionFailed, err.Msg)
+}
+
+func (err *TaskPreconditionError) Code() int {
+	return CodeTaskPreconditionFailed
+}
+
+func (err *TaskPreconditionError) Unwrap() error {
+	return ErrPreconditionFailed
+}
+
+// TaskTimeoutError is returned when a task doesn't finish within its timeout,
+// or Task within the --timeout, when TaskName is empty.
+type TaskTimeoutError struct {
+	TaskName string
+	Timeout  time.Duration
+	Err      error
+}
+
+func (err *TaskTimeoutError) Error() string {
+	if err.TaskName == "" {
+		return fmt.Sprintf(`task: Timed out after %s: %v`, err.Timeout, err.Err)
+	}
+	return fmt.Sprintf(`task: Task %q timed out after %s`, err.TaskName, err.Timeout)
+}
+
+func (err *TaskTimeoutError) Code() int {
+	return CodeTaskTimeout
+}
+
+func (err *TaskTimeoutError) Unwrap() error {
+	return err.Err
+}
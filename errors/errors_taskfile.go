@@ -56,6 +56,8 @@ func (err TaskfileInvalidError) Code() int {
 type TaskfileFetchFailedError struct {
 	URI            string
 	HTTPStatusCode int
+	// Reason tells why the download failed, when it's not an HTTP status
+	Reason string
 }
 
 func (err TaskfileFetchFailedError) Error() string {
@@ -63,6 +65,9 @@ func (err TaskfileFetchFailedError) Error() string {
 	if err.HTTPStatusCode != 0 {
 		statusText = fmt.Sprintf(" with status code %d (%s)", err.HTTPStatusCode, http.StatusText(err.HTTPStatusCode))
 	}
+	if err.Reason != "" {
+		statusText += ": " + err.Reason
+	}
 	return fmt.Sprintf(`task: Download of %q failed%s`, err.URI, statusText)
 }
 
@@ -120,3 +125,66 @@ func (err *TaskfileCacheNotFound) Error() string {
 func (err *TaskfileCacheNotFound) Code() int {
 	return CodeTaskfileCacheNotFound
 }
+
+// TaskfileLockMismatchError is returned when a pinned remote Taskfile doesn't
+// match the checksum in the lock file, meaning it changed since it was locked.
+type TaskfileLockMismatchError struct {
+	URI      string
+	LockFile string
+}
+
+func (err *TaskfileLockMismatchError) Error() string {
+	return fmt.Sprintf(
+		`task: Taskfile %q doesn't match its checksum in %s. Remove it from the lock file only if you trust the new content`,
+		err.URI,
+		err.LockFile,
+	)
+}
+
+func (err *TaskfileLockMismatchError) Code() int {
+	return CodeTaskfileLockMismatch
+}
+
+// TaskfileVerificationFailedError is returned when an included Taskfile
+// doesn't match the checksum or the signature of its include.
+type TaskfileVerificationFailedError struct {
+	URI    string
+	Reason string
+}
+
+func (err *TaskfileVerificationFailedError) Error() string {
+	return fmt.Sprintf(`task: Taskfile %q failed verification: %s`, err.URI, err.Reason)
+}
+
+func (err *TaskfileVerificationFailedError) Code() int {
+	return CodeTaskfileVerificationFailed
+}
+
+// TaskfileDeprecatedError is returned in strict mode when a Taskfile, or the
+// CLI, uses something deprecated.
+type TaskfileDeprecatedError struct {
+	Message string
+	Docs    string
+}
+
+func (err *TaskfileDeprecatedError) Error() string {
+	return fmt.Sprintf(`task: %s, which is an error with --strict. See %s for more details`, err.Message, err.Docs)
+}
+
+func (err *TaskfileDeprecatedError) Code() int {
+	return CodeTaskfileDeprecated
+}
+
+// TaskfileLintFailedError is returned by task --lint when it found issues in
+// the Taskfile.
+type TaskfileLintFailedError struct {
+	Issues int
+}
+
+func (err *TaskfileLintFailedError) Error() string {
+	return fmt.Sprintf(`task: Found %d issue(s) in the Taskfile`, err.Issues)
+}
+
+func (err *TaskfileLintFailedError) Code() int {
+	return CodeTaskfileLintFailed
+}
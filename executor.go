@@ -0,0 +1,355 @@
+package task
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"sync/atomic"
+	"time"
+
+	"github.com/nuvolaris/task/v3/internal/sort"
+	"github.com/nuvolaris/task/v3/taskfile"
+)
+
+// ExecutorOption configures an Executor created by NewExecutor.
+type ExecutorOption func(*Executor)
+
+// NewExecutor creates an Executor with the given options. Unlike an Executor
+// created as a struct literal, it uses the standard input and outputs and
+// colors by default, and doesn't need Setup to be called: it's called on the
+// first use and its errors are returned from there.
+//
+// An Executor can run tasks many times, but only one run at a time.
+func NewExecutor(opts ...ExecutorOption) *Executor {
+	e := &Executor{
+		Stdin:  os.Stdin,
+		Stdout: os.Stdout,
+		Stderr: os.Stderr,
+		Color:  true,
+	}
+	for _, opt := range opts {
+		opt(e)
+	}
+	return e
+}
+
+// WithDir sets the directory the Taskfile is searched from.
+func WithDir(dir string) ExecutorOption {
+	return func(e *Executor) {
+		e.Dir = dir
+	}
+}
+
+// WithEntrypoint sets the Taskfile to read, instead of searching for one.
+func WithEntrypoint(entrypoint string) ExecutorOption {
+	return func(e *Executor) {
+		e.Entrypoint = entrypoint
+	}
+}
+
+// WithTempDir sets the directory of the checksums and other state of Task.
+// Defaults to the .task directory next to the Taskfile.
+func WithTempDir(tempDir string) ExecutorOption {
+	return func(e *Executor) {
+		e.TempDir = tempDir
+	}
+}
+
+// WithStdin sets the standard input of the commands.
+func WithStdin(stdin io.Reader) ExecutorOption {
+	return func(e *Executor) {
+		e.Stdin = stdin
+	}
+}
+
+// WithStdout sets the standard output of the commands and of Task.
+func WithStdout(stdout io.Writer) ExecutorOption {
+	return func(e *Executor) {
+		e.Stdout = stdout
+	}
+}
+
+// WithStderr sets the standard error of the commands and of Task.
+func WithStderr(stderr io.Writer) ExecutorOption {
+	return func(e *Executor) {
+		e.Stderr = stderr
+	}
+}
+
+// WithTaskWriters sets the function giving the writers of the commands of
+// each task run, instead of the standard output and error. See
+// Executor.TaskWriters.
+func WithTaskWriters(taskWriters func(taskName string) (stdout, stderr io.Writer)) ExecutorOption {
+	return func(e *Executor) {
+		e.TaskWriters = taskWriters
+	}
+}
+
+// WithForce runs the given tasks even when they are up to date.
+func WithForce(force bool) ExecutorOption {
+	return func(e *Executor) {
+		e.Force = force
+	}
+}
+
+// WithForceAll runs all the tasks, including their deps, even when they are
+// up to date.
+func WithForceAll(forceAll bool) ExecutorOption {
+	return func(e *Executor) {
+		e.ForceAll = forceAll
+	}
+}
+
+// WithInsecure allows remote Taskfiles to be fetched over HTTP.
+func WithInsecure(insecure bool) ExecutorOption {
+	return func(e *Executor) {
+		e.Insecure = insecure
+	}
+}
+
+// WithDownload fetches the remote Taskfiles again instead of using the cache.
+func WithDownload(download bool) ExecutorOption {
+	return func(e *Executor) {
+		e.Download = download
+	}
+}
+
+// WithOffline only uses the cached copies of the remote Taskfiles.
+func WithOffline(offline bool) ExecutorOption {
+	return func(e *Executor) {
+		e.Offline = offline
+	}
+}
+
+// WithWatch runs the tasks again whenever their sources change.
+func WithWatch(watch bool) ExecutorOption {
+	return func(e *Executor) {
+		e.Watch = watch
+	}
+}
+
+// WithInterval polls the sources for changes on the given interval when
+// watching, instead of using the events of the file system.
+func WithInterval(interval time.Duration) ExecutorOption {
+	return func(e *Executor) {
+		e.Interval = interval
+	}
+}
+
+// WithWatchListen reruns the watched tasks when the HTTP endpoint at the
+// given address receives a POST request.
+func WithWatchListen(addr string) ExecutorOption {
+	return func(e *Executor) {
+		e.WatchListen = addr
+	}
+}
+
+// WithVerbose prints the extra messages of Task.
+func WithVerbose(verbose bool) ExecutorOption {
+	return func(e *Executor) {
+		e.Verbose = verbose
+	}
+}
+
+// WithSilent disables the echoing of the commands.
+func WithSilent(silent bool) ExecutorOption {
+	return func(e *Executor) {
+		e.Silent = silent
+	}
+}
+
+// WithColor enables or disables the colors of the messages of Task.
+func WithColor(color bool) ExecutorOption {
+	return func(e *Executor) {
+		e.Color = color
+	}
+}
+
+// WithAssumeYes answers yes to the prompts of the tasks.
+func WithAssumeYes(assumeYes bool) ExecutorOption {
+	return func(e *Executor) {
+		e.AssumeYes = assumeYes
+	}
+}
+
+// WithAssumeTerm asks prompts even when the input and the output aren't a
+// terminal.
+func WithAssumeTerm(assumeTerm bool) ExecutorOption {
+	return func(e *Executor) {
+		e.AssumesTerm = assumeTerm
+	}
+}
+
+// WithNoInput never asks for the values of variables with a prompt, using
+// their defaults instead.
+func WithNoInput(noInput bool) ExecutorOption {
+	return func(e *Executor) {
+		e.NoInput = noInput
+	}
+}
+
+// WithDry prints the commands instead of running them.
+func WithDry(dry bool) ExecutorOption {
+	return func(e *Executor) {
+		e.Dry = dry
+	}
+}
+
+// WithSummary prints the summary of the tasks instead of running them.
+func WithSummary(summary bool) ExecutorOption {
+	return func(e *Executor) {
+		e.Summary = summary
+	}
+}
+
+// WithParallel runs the given tasks in parallel.
+func WithParallel(parallel bool) ExecutorOption {
+	return func(e *Executor) {
+		e.Parallel = parallel
+	}
+}
+
+// WithFailFast stops the run at the first failure of the given tasks, which is
+// the default. When false, all of them run and all the failures are returned.
+func WithFailFast(failFast bool) ExecutorOption {
+	return func(e *Executor) {
+		e.NoFailFast = !failFast
+	}
+}
+
+// WithFrom runs only the tasks that run after the given one, including it.
+func WithFrom(from string) ExecutorOption {
+	return func(e *Executor) {
+		e.From = from
+	}
+}
+
+// WithUntil runs only the tasks that run before the given one, including it.
+func WithUntil(until string) ExecutorOption {
+	return func(e *Executor) {
+		e.Until = until
+	}
+}
+
+// WithConcurrency limits the number of tasks running at once. Zero means no
+// limit.
+func WithConcurrency(concurrency int) ExecutorOption {
+	return func(e *Executor) {
+		e.Concurrency = concurrency
+	}
+}
+
+// WithOutputStyle sets the output style, instead of the one of the Taskfile.
+func WithOutputStyle(style taskfile.Output) ExecutorOption {
+	return func(e *Executor) {
+		e.OutputStyle = style
+	}
+}
+
+// WithTaskSorter sets the order of the tasks when they are listed.
+func WithTaskSorter(sorter sort.TaskSorter) ExecutorOption {
+	return func(e *Executor) {
+		e.TaskSorter = sorter
+	}
+}
+
+// WithReportFile writes a JSON report of every run to the given file.
+func WithReportFile(reportFile string) ExecutorOption {
+	return func(e *Executor) {
+		e.ReportFile = reportFile
+	}
+}
+
+// WithProfile prints how long each task and command took, see
+// Executor.Profile.
+func WithProfile(profile bool) ExecutorOption {
+	return func(e *Executor) {
+		e.Profile = profile
+	}
+}
+
+// WithProfileFile writes the timings of every run to the given file, see
+// Executor.ProfileFile.
+func WithProfileFile(profileFile string) ExecutorOption {
+	return func(e *Executor) {
+		e.ProfileFile = profileFile
+	}
+}
+
+// WithOTelExporter emits OpenTelemetry spans to the given exporter, see
+// Executor.OTelExporter.
+func WithOTelExporter(exporter string) ExecutorOption {
+	return func(e *Executor) {
+		e.OTelExporter = exporter
+	}
+}
+
+// WithHermetic inherits only the allowed environment variables, see
+// Executor.Hermetic.
+func WithHermetic(hermetic bool) ExecutorOption {
+	return func(e *Executor) {
+		e.Hermetic = hermetic
+	}
+}
+
+// WithSlashPaths writes the directories in the special variables with
+// forward slashes, even on Windows.
+func WithSlashPaths(slashPaths bool) ExecutorOption {
+	return func(e *Executor) {
+		e.SlashPaths = slashPaths
+	}
+}
+
+// WithStrict turns the deprecation warnings into errors.
+func WithStrict(strict bool) ExecutorOption {
+	return func(e *Executor) {
+		e.Strict = strict
+	}
+}
+
+// WithMiddlewares adds middlewares around the execution of every task. The
+// first one is the outermost.
+func WithMiddlewares(middlewares ...Middleware) ExecutorOption {
+	return func(e *Executor) {
+		e.Middlewares = append(e.Middlewares, middlewares...)
+	}
+}
+
+// validate checks the options of the Executor.
+func (e *Executor) validate() error {
+	if e.Concurrency < 0 {
+		return fmt.Errorf("task: The concurrency can't be negative, got %d", e.Concurrency)
+	}
+	if e.Interval < 0 {
+		return fmt.Errorf("task: The watch interval can't be negative, got %v", e.Interval)
+	}
+	if e.Offline && e.Download {
+		return fmt.Errorf("task: You can't set both Download and Offline")
+	}
+	return nil
+}
+
+// setupIfNeeded calls SetupWithContext, unless it already succeeded.
+func (e *Executor) setupIfNeeded(ctx context.Context) error {
+	if e.setupDone {
+		return nil
+	}
+	return e.SetupWithContext(ctx)
+}
+
+// resetRunState forgets the state of the previous run, so an Executor can be
+// used for many runs: the calls of every task are counted again, the tasks
+// that run once run again, the deprecations are reported again, and a run
+// interrupted before doesn't make the tasks of this one interrupted.
+func (e *Executor) resetRunState() {
+	for _, count := range e.taskCallCount {
+		atomic.StoreInt32(count, 0)
+	}
+	e.executionHashesMutex.Lock()
+	e.executionHashes = make(map[string]context.Context)
+	e.executionHashesMutex.Unlock()
+	e.deprecations.Reset()
+	e.interrupted.Store(false)
+	e.report = &RunReport{}
+}
@@ -0,0 +1,17 @@
+//go:build !windows
+
+package task
+
+import (
+	"os"
+	"syscall"
+)
+
+// forwardableSignals are the signals that can be forwarded to the commands,
+// other than the ones intercepted by InterceptInterruptSignals.
+var forwardableSignals = map[string]os.Signal{
+	"SIGHUP":  syscall.SIGHUP,
+	"SIGQUIT": syscall.SIGQUIT,
+	"SIGUSR1": syscall.SIGUSR1,
+	"SIGUSR2": syscall.SIGUSR2,
+}
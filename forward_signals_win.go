@@ -0,0 +1,9 @@
+//go:build windows
+
+package task
+
+import "os"
+
+// NOTE: Signals other than interrupts can't be sent to processes on Windows,
+// so they're never forwarded.
+var forwardableSignals = map[string]os.Signal{}
@@ -0,0 +1,119 @@
+package task
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"golang.org/x/exp/slices"
+
+	"github.com/nuvolaris/task/v3/taskfile"
+)
+
+// Graph prints the given tasks and everything they run as a Graphviz DOT
+// graph. Deps are drawn as solid edges, calls from commands as dotted edges
+// and every pipeline stage as a cluster, chained to the next stage with
+// dashed edges.
+func (e *Executor) Graph(calls ...taskfile.Call) error {
+	if err := e.setupIfNeeded(context.Background()); err != nil {
+		return err
+	}
+	g := &taskGraph{e: e, visited: map[string]bool{}, edges: map[string]bool{}}
+	g.b.WriteString("digraph tasks {\n")
+	for _, call := range calls {
+		if err := g.add(call); err != nil {
+			return err
+		}
+	}
+	g.b.WriteString("}\n")
+
+	_, err := io.WriteString(e.Stdout, g.b.String())
+	return err
+}
+
+type taskGraph struct {
+	e       *Executor
+	b       strings.Builder
+	visited map[string]bool
+	edges   map[string]bool
+}
+
+// edge writes an edge between two quoted task names, unless the same edge was
+// already written (e.g. for a task called many times with different vars).
+func (g *taskGraph) edge(from, to, attrs string) {
+	edge := fmt.Sprintf("  %s -> %s%s;\n", from, to, attrs)
+	if g.edges[edge] {
+		return
+	}
+	g.edges[edge] = true
+	g.b.WriteString(edge)
+}
+
+func (g *taskGraph) add(call taskfile.Call) error {
+	t, err := g.e.FastCompiledTask(context.Background(), call)
+	if err != nil {
+		return err
+	}
+	if g.visited[t.Task] {
+		return nil
+	}
+	g.visited[t.Task] = true
+
+	name := strconv.Quote(t.Task)
+	fmt.Fprintf(&g.b, "  %s;\n", name)
+
+	var next []taskfile.Call
+	for _, d := range t.Deps {
+		g.edge(name, strconv.Quote(d.Task), "")
+		next = append(next, taskfile.Call{Task: d.Task, Vars: d.Vars})
+	}
+
+	previous := []string{name}
+	for i, stage := range t.Pipeline {
+		label := fmt.Sprintf("%s: stage %d", t.Task, i+1)
+		if stage.Name != "" {
+			label = fmt.Sprintf("%s: %s", t.Task, stage.Name)
+		}
+		fmt.Fprintf(&g.b, "  subgraph %s {\n", strconv.Quote(fmt.Sprintf("cluster_%s_%d", t.Task, i+1)))
+		fmt.Fprintf(&g.b, "    label = %s;\n", strconv.Quote(label))
+		current := make([]string, 0, len(stage.Tasks))
+		for _, d := range stage.Tasks {
+			next = append(next, taskfile.Call{Task: d.Task, Vars: d.Vars})
+			if slices.Contains(current, strconv.Quote(d.Task)) {
+				continue
+			}
+			fmt.Fprintf(&g.b, "    %s;\n", strconv.Quote(d.Task))
+			current = append(current, strconv.Quote(d.Task))
+		}
+		g.b.WriteString("  }\n")
+
+		// Every task of a stage waits for all the tasks of the previous one
+		for _, from := range previous {
+			for _, to := range current {
+				if i == 0 {
+					g.edge(from, to, "")
+				} else {
+					g.edge(from, to, " [style=dashed]")
+				}
+			}
+		}
+		previous = current
+	}
+
+	for _, c := range t.Cmds {
+		if c.Task == "" {
+			continue
+		}
+		g.edge(name, strconv.Quote(c.Task), " [style=dotted]")
+		next = append(next, taskfile.Call{Task: c.Task, Vars: c.Vars})
+	}
+
+	for _, call := range next {
+		if err := g.add(call); err != nil {
+			return err
+		}
+	}
+	return nil
+}
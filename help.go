@@ -13,6 +13,7 @@ import (
 	"golang.org/x/sync/errgroup"
 
 	"github.com/nuvolaris/task/v3/internal/editors"
+	"github.com/nuvolaris/task/v3/internal/filepathext"
 	"github.com/nuvolaris/task/v3/internal/fingerprint"
 	"github.com/nuvolaris/task/v3/internal/logger"
 	"github.com/nuvolaris/task/v3/internal/sort"
@@ -68,6 +69,9 @@ func (o ListOptions) Filters() []FilterFunc {
 // The function returns a boolean indicating whether tasks were found
 // and an error if one was encountered while preparing the output.
 func (e *Executor) ListTasks(o ListOptions) (bool, error) {
+	if err := e.setupIfNeeded(context.Background()); err != nil {
+		return false, err
+	}
 	tasks, err := e.GetTaskList(o.Filters()...)
 	if err != nil {
 		return false, err
@@ -96,11 +100,20 @@ func (e *Executor) ListTasks(o ListOptions) (bool, error) {
 	}
 	e.Logger.Outf(logger.Default, "nuv: available subcommands:\n")
 
+	// When listing all tasks, group them by the Taskfile they're defined in
+	var groups []string
+	if o.ListAllTasks {
+		tasks, groups = groupTasksByTaskfile(e.Taskfile.Location, tasks)
+	}
+
 	// Format in tab-separated columns with a tab stop of 8.
 	w := tabwriter.NewWriter(e.Stdout, 0, 8, 6, ' ', 0)
-	for _, task := range tasks {
+	for i, task := range tasks {
+		if groups != nil && (i == 0 || groups[i] != groups[i-1]) {
+			e.Logger.FOutf(w, logger.Cyan, "%s:\n", filepathext.TryAbsToRel(groups[i]))
+		}
 		e.Logger.FOutf(w, logger.Yellow, "* ")
-		e.Logger.FOutf(w, logger.Green, task.Task)
+		e.Logger.FOutf(w, logger.TaskName, task.Task)
 		e.Logger.FOutf(w, logger.Default, ": \t%s", task.Desc)
 		if len(task.Aliases) > 0 {
 			e.Logger.FOutf(w, logger.Cyan, "\t(aliases: %s)", strings.Join(task.Aliases, ", "))
@@ -113,13 +126,49 @@ func (e *Executor) ListTasks(o ListOptions) (bool, error) {
 	return true, nil
 }
 
+// groupTasksByTaskfile reorders the tasks so the ones defined in the root
+// Taskfile come first, followed by the ones of each included Taskfile, keeping
+// their order otherwise. It also returns the Taskfile of every task, or nil
+// when all the tasks are defined in the same Taskfile.
+func groupTasksByTaskfile(root string, tasks []*taskfile.Task) ([]*taskfile.Task, []string) {
+	taskfileOf := func(t *taskfile.Task) string {
+		if t.Location == nil || t.Location.Taskfile == "" {
+			return root
+		}
+		return t.Location.Taskfile
+	}
+
+	order := []string{root}
+	byTaskfile := map[string][]*taskfile.Task{}
+	for _, t := range tasks {
+		location := taskfileOf(t)
+		if _, ok := byTaskfile[location]; !ok && location != root {
+			order = append(order, location)
+		}
+		byTaskfile[location] = append(byTaskfile[location], t)
+	}
+	if len(byTaskfile) <= 1 {
+		return tasks, nil
+	}
+
+	grouped := make([]*taskfile.Task, 0, len(tasks))
+	groups := make([]string, 0, len(tasks))
+	for _, location := range order {
+		for _, t := range byTaskfile[location] {
+			grouped = append(grouped, t)
+			groups = append(groups, location)
+		}
+	}
+	return grouped, groups
+}
+
 // ListTaskNames prints only the task names in a Taskfile.
 // Only tasks with a non-empty description are printed if allTasks is false.
 // Otherwise, all task names are printed.
 func (e *Executor) ListTaskNames(allTasks bool) {
 	// if called from cmd/task.go, e.Taskfile has not yet been parsed
 	if e.Taskfile == nil {
-		if err := e.readTaskfile(); err != nil {
+		if err := e.readTaskfile(context.Background()); err != nil {
 			log.Fatal(err)
 			return
 		}
@@ -194,3 +243,64 @@ func (e *Executor) ToEditorOutput(tasks []*taskfile.Task) (*editors.Taskfile, er
 	}
 	return o, g.Wait()
 }
+
+// Which prints where the given tasks are defined, as "file:line:column", and
+// the includes that brought them into the root Taskfile.
+func (e *Executor) Which(calls ...taskfile.Call) error {
+	if err := e.setupIfNeeded(context.Background()); err != nil {
+		return err
+	}
+	for _, call := range calls {
+		t, err := e.GetTask(call)
+		if err != nil {
+			return err
+		}
+		if t.Location == nil {
+			continue
+		}
+		if _, err := fmt.Fprintf(e.Stdout, "%s:%d:%d\n", filepathext.TryAbsToRel(t.Location.Taskfile), t.Location.Line, t.Location.Column); err != nil {
+			return err
+		}
+		for _, include := range t.Location.Includes {
+			if _, err := fmt.Fprintf(e.Stdout, "  included as %q by %s:%d:%d\n", include.Namespace, filepathext.TryAbsToRel(include.Taskfile), include.Line, include.Column); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// ListTaskVars prints the names of the variables required by the given tasks,
+// or set by their positional args, one per line. It's used by the shell
+// completions to complete "VAR=" arguments.
+func (e *Executor) ListTaskVars(calls ...taskfile.Call) error {
+	if err := e.setupIfNeeded(context.Background()); err != nil {
+		return err
+	}
+	seen := make(map[string]bool)
+	for _, call := range calls {
+		t, err := e.GetTask(call)
+		if err != nil {
+			return err
+		}
+		var names []string
+		if t.Requires != nil {
+			names = append(names, t.Requires.Vars...)
+		}
+		for _, arg := range t.Args {
+			if arg != nil {
+				names = append(names, arg.Name)
+			}
+		}
+		for _, name := range names {
+			if seen[name] {
+				continue
+			}
+			seen[name] = true
+			if _, err := fmt.Fprintln(e.Stdout, name); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
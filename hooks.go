@@ -0,0 +1,104 @@
+package task
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/nuvolaris/task/v3/internal/logger"
+	"github.com/nuvolaris/task/v3/taskfile"
+)
+
+// runWithAllHooks runs the before_all hooks of the Taskfile, then the calls
+// with run, unless a hook failed, and then the after_all hooks. Like the
+// deferred commands, the after_all hooks run even when something failed or
+// the run was canceled, so they can tear down what the before_all ones set
+// up.
+func (e *Executor) runWithAllHooks(ctx context.Context, run func(ctx context.Context) error) error {
+	err := e.runHooks(ctx, e.Taskfile.BeforeAll, nil)
+	if err == nil {
+		err = run(ctx)
+	}
+	return e.runAfterHooks(ctx, e.Taskfile.AfterAll, &taskfile.Vars{}, err)
+}
+
+// runWithEachHooks runs the before_each hooks of the Taskfile, then the
+// called task with run, unless a hook failed, and then the after_each hooks,
+// even when something failed. The hook tasks themselves aren't wrapped.
+func (e *Executor) runWithEachHooks(ctx context.Context, call taskfile.Call, run func() error) error {
+	if len(e.Taskfile.BeforeEach) == 0 && len(e.Taskfile.AfterEach) == 0 {
+		return run()
+	}
+	t, err := e.GetTask(call)
+	if err != nil || e.isHook(t) {
+		return run()
+	}
+
+	vars := &taskfile.Vars{}
+	vars.Set("HOOK_TASK", taskfile.Var{Static: t.Task})
+	err = e.runHooks(ctx, e.Taskfile.BeforeEach, vars)
+	if err == nil {
+		err = run()
+	}
+	return e.runAfterHooks(ctx, e.Taskfile.AfterEach, vars, err)
+}
+
+// runAfterHooks runs the after hooks with the exit code of what they follow,
+// and returns its error first, since the one of a hook is only logged then.
+func (e *Executor) runAfterHooks(parent context.Context, hooks []*taskfile.Dep, vars *taskfile.Vars, err error) error {
+	if len(hooks) == 0 {
+		return err
+	}
+	ctx, cancel := context.WithCancel(detachedContext{parent})
+	defer cancel()
+
+	vars = vars.DeepCopy()
+	vars.Set("HOOK_EXIT_CODE", taskfile.Var{Static: strconv.Itoa(exitCode(err))})
+	hookErr := e.runHooks(ctx, hooks, vars)
+	if err != nil {
+		if hookErr != nil {
+			e.Logger.VerboseErrf(logger.Yellow, "task: ignored error in hook: %v\n", hookErr)
+		}
+		return err
+	}
+	return hookErr
+}
+
+// runHooks runs the hook tasks one after the other, with the given vars on
+// top of their own.
+func (e *Executor) runHooks(ctx context.Context, hooks []*taskfile.Dep, vars *taskfile.Vars) error {
+	for _, d := range hooks {
+		if d == nil || d.Task == "" {
+			continue
+		}
+		callVars := forwardCLIArgs(d.ForwardCLIArgs, d.Vars.DeepCopy(), e.Taskfile.Vars)
+		if callVars == nil {
+			callVars = &taskfile.Vars{}
+		}
+		callVars.Merge(vars)
+		err := e.RunTask(ctx, taskfile.Call{Task: d.Task, Vars: callVars, Silent: d.Silent})
+		if err != nil && d.IgnoreError && ctx.Err() == nil {
+			e.Logger.VerboseErrf(logger.Yellow, "task: hook %q error ignored: %v\n", d.Task, err)
+			e.report.ignoreError(d.Task, err)
+			continue
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// isHook tells whether the task is one of the hooks of the Taskfile.
+func (e *Executor) isHook(t *taskfile.Task) bool {
+	for _, hooks := range [][]*taskfile.Dep{e.Taskfile.BeforeAll, e.Taskfile.AfterAll, e.Taskfile.BeforeEach, e.Taskfile.AfterEach} {
+		for _, d := range hooks {
+			if d == nil {
+				continue
+			}
+			if hook, err := e.GetTask(taskfile.Call{Task: d.Task}); err == nil && hook.Task == t.Task {
+				return true
+			}
+		}
+	}
+	return false
+}
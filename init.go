@@ -4,8 +4,10 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"text/tabwriter"
 
 	"github.com/nuvolaris/task/v3/errors"
+	"github.com/nuvolaris/task/v3/internal/experiments"
 	"github.com/nuvolaris/task/v3/internal/filepathext"
 )
 
@@ -23,19 +25,211 @@ tasks:
     silent: true
 `
 
+const goTaskfile = `# https://taskfile.dev
+
+version: '3'
+
+vars:
+  BINARY: bin/app{{exeExt}}
+
+tasks:
+  default:
+    cmds:
+      - task: build
+
+  build:
+    desc: Builds the binary
+    sources:
+      - '**/*.go'
+      - go.mod
+      - go.sum
+    generates:
+      - '{{.BINARY}}'
+    cmds:
+      - go build -o {{.BINARY}} .
+
+  test:
+    desc: Runs the tests
+    cmds:
+      - go test ./...
+
+  lint:
+    desc: Checks the formatting and runs go vet
+    cmds:
+      - test -z "$(gofmt -l .)"
+      - go vet ./...
+
+  clean:
+    desc: Removes the binary
+    cmds:
+      - rm -rf bin
+`
+
+const nodeTaskfile = `# https://taskfile.dev
+
+version: '3'
+
+tasks:
+  default:
+    cmds:
+      - task: build
+
+  install:
+    desc: Installs the dependencies
+    sources:
+      - package.json
+      - package-lock.json
+    generates:
+      - node_modules/.package-lock.json
+    cmds:
+      - npm install
+
+  build:
+    desc: Builds the project
+    deps: [install]
+    cmds:
+      - npm run build
+
+  test:
+    desc: Runs the tests
+    deps: [install]
+    cmds:
+      - npm test
+
+  lint:
+    desc: Runs the linters
+    deps: [install]
+    cmds:
+      - npm run lint
+`
+
+const dockerTaskfile = `# https://taskfile.dev
+
+version: '3'
+
+vars:
+  IMAGE: app
+  TAG: latest
+
+tasks:
+  default:
+    cmds:
+      - task: build
+
+  build:
+    desc: Builds the image
+    sources:
+      - Dockerfile
+      - '**/*'
+    cmds:
+      - docker build -t {{.IMAGE}}:{{.TAG}} .
+
+  run:
+    desc: Runs a container of the image
+    deps: [build]
+    interactive: true
+    cmds:
+      - docker run --rm -it {{.IMAGE}}:{{.TAG}} {{.CLI_ARGS}}
+
+  lint:
+    desc: Checks the Dockerfile with hadolint
+    cmds:
+      - docker run --rm -i hadolint/hadolint < Dockerfile
+
+  push:
+    desc: Pushes the image
+    deps: [build]
+    cmds:
+      - docker push {{.IMAGE}}:{{.TAG}}
+`
+
 const defaultTaskfileName = "Taskfile.yml"
 
+type initTemplate struct {
+	name     string
+	desc     string
+	taskfile string
+}
+
+// initTemplates are the Taskfiles that can be created by InitTaskfile, in the
+// order they're listed.
+var initTemplates = []initTemplate{
+	{"default", "A Taskfile with a single task printing a greeting", defaultTaskfile},
+	{"go", "Build, test, lint and clean tasks for a Go module", goTaskfile},
+	{"node", "Install, build, test and lint tasks using npm", nodeTaskfile},
+	{"docker", "Build, run, lint and push tasks for a Docker image", dockerTaskfile},
+}
+
+// InitOption configures InitTaskfile.
+type InitOption func(*initOptions)
+
+type initOptions struct {
+	template string
+	force    bool
+}
+
+// WithInitTemplate sets the template of the new Taskfile. The names of the
+// templates are printed by ListInitTemplates.
+func WithInitTemplate(name string) InitOption {
+	return func(o *initOptions) {
+		o.template = name
+	}
+}
+
+// WithInitForce overwrites the Taskfile when it already exists.
+func WithInitForce(force bool) InitOption {
+	return func(o *initOptions) {
+		o.force = force
+	}
+}
+
 // InitTaskfile Taskfile creates a new Taskfile
-func InitTaskfile(w io.Writer, dir string) error {
-	f := filepathext.SmartJoin(dir, defaultTaskfileName)
+func InitTaskfile(w io.Writer, dir string, opts ...InitOption) error {
+	var o initOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
 
-	if _, err := os.Stat(f); err == nil {
+	content := defaultTaskfile
+	if o.template != "" {
+		t := findInitTemplate(o.template)
+		if t == nil {
+			return fmt.Errorf("task: Unknown template %q. Run task --init --list-templates to see the available ones", o.template)
+		}
+		content = t.taskfile
+	} else if experiments.ZeroConfig {
+		if p := detectProject(dir); p != nil {
+			content = p.taskfile
+		}
+	}
+
+	f := filepathext.SmartJoin(dir, defaultTaskfileName)
+	if _, err := os.Stat(f); err == nil && !o.force {
 		return errors.TaskfileAlreadyExistsError{}
 	}
 
-	if err := os.WriteFile(f, []byte(defaultTaskfile), 0o644); err != nil {
+	if err := os.WriteFile(f, []byte(content), 0o644); err != nil {
 		return err
 	}
-	fmt.Fprintf(w, "%s created in the current directory\n", defaultTaskfile)
+	fmt.Fprintf(w, "%s created in the current directory\n", content)
+	return nil
+}
+
+// ListInitTemplates prints the templates that InitTaskfile can use, with
+// their descriptions.
+func ListInitTemplates(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 8, 6, ' ', 0)
+	for _, t := range initTemplates {
+		fmt.Fprintf(tw, "* %s:\t%s\n", t.name, t.desc)
+	}
+	return tw.Flush()
+}
+
+func findInitTemplate(name string) *initTemplate {
+	for i := range initTemplates {
+		if initTemplates[i].name == name {
+			return &initTemplates[i]
+		}
+	}
 	return nil
 }
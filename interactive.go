@@ -0,0 +1,43 @@
+package task
+
+import (
+	"io"
+	"os"
+
+	"github.com/nuvolaris/task/v3/internal/term"
+)
+
+// startInteractive gives the terminal to an interactive command, and returns
+// the files it's connected to along with the function that takes the
+// terminal back. Only one interactive command or prompt has the terminal at
+// a time, and the progress dashboard isn't drawn meanwhile.
+func (e *Executor) startInteractive() (io.Reader, io.Writer, io.Writer, func()) {
+	e.promptMutex.Lock()
+	resume := func() {}
+	if e.tracker != nil {
+		resume = e.tracker.Pause()
+	}
+	stdin, stdout, stderr := e.terminalFiles()
+	return stdin, stdout, stderr, func() {
+		resume()
+		e.promptMutex.Unlock()
+	}
+}
+
+// terminalFiles returns the standard files of the Executor. When the input
+// is a terminal but the outputs are not files, like when they're prefixed by
+// --dirs, the outputs of the process are used instead if they're a terminal
+// too, so an interactive command is always connected to the TTY itself.
+func (e *Executor) terminalFiles() (io.Reader, io.Writer, io.Writer) {
+	stdout, stderr := e.Stdout, e.Stderr
+	if !term.IsTerminalReader(e.Stdin) {
+		return e.Stdin, stdout, stderr
+	}
+	if _, ok := stdout.(*os.File); !ok && term.IsTerminalWriter(os.Stdout) {
+		stdout = os.Stdout
+	}
+	if _, ok := stderr.(*os.File); !ok && term.IsTerminalWriter(os.Stderr) {
+		stderr = os.Stderr
+	}
+	return e.Stdin, stdout, stderr
+}
@@ -1,15 +1,18 @@
 package compiler
 
 import (
+	"context"
+
 	"github.com/nuvolaris/task/v3/taskfile"
 )
 
 // Compiler handles compilation of a task before its execution.
-// E.g. variable merger, template processing, etc.
+// E.g. variable merger, template processing, etc. The context cancels the
+// commands of the dynamic variables.
 type Compiler interface {
-	GetTaskfileVariables() (*taskfile.Vars, error)
-	GetVariables(t *taskfile.Task, call taskfile.Call) (*taskfile.Vars, error)
-	FastGetVariables(t *taskfile.Task, call taskfile.Call) (*taskfile.Vars, error)
-	HandleDynamicVar(v taskfile.Var, dir string) (string, error)
+	GetTaskfileVariables(ctx context.Context) (*taskfile.Vars, error)
+	GetVariables(ctx context.Context, t *taskfile.Task, call taskfile.Call) (*taskfile.Vars, error)
+	FastGetVariables(ctx context.Context, t *taskfile.Task, call taskfile.Call) (*taskfile.Vars, error)
+	HandleDynamicVar(ctx context.Context, v taskfile.Var, dir string) (string, error)
 	ResetCache()
 }
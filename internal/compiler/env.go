@@ -8,10 +8,10 @@ import (
 )
 
 // GetEnviron the all return all environment variables encapsulated on a
-// taskfile.Vars
-func GetEnviron() *taskfile.Vars {
+// taskfile.Vars, except the ones the given env policy doesn't inherit
+func GetEnviron(policy *taskfile.EnvPolicy) *taskfile.Vars {
 	m := &taskfile.Vars{}
-	for _, e := range os.Environ() {
+	for _, e := range policy.Filter(os.Environ()) {
 		keyVal := strings.SplitN(e, "=", 2)
 		key, val := keyVal[0], keyVal[1]
 		m.Set(key, taskfile.Var{Static: val})
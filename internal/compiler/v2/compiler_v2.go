@@ -30,13 +30,13 @@ type CompilerV2 struct {
 	muDynamicCache sync.Mutex
 }
 
-func (c *CompilerV2) GetTaskfileVariables() (*taskfile.Vars, error) {
+func (c *CompilerV2) GetTaskfileVariables(_ context.Context) (*taskfile.Vars, error) {
 	return &taskfile.Vars{}, nil
 }
 
 // FastGetVariables is a no-op on v2
-func (c *CompilerV2) FastGetVariables(t *taskfile.Task, call taskfile.Call) (*taskfile.Vars, error) {
-	return c.GetVariables(t, call)
+func (c *CompilerV2) FastGetVariables(ctx context.Context, t *taskfile.Task, call taskfile.Call) (*taskfile.Vars, error) {
+	return c.GetVariables(ctx, t, call)
 }
 
 // GetVariables returns fully resolved variables following the priority order:
@@ -45,10 +45,11 @@ func (c *CompilerV2) FastGetVariables(t *taskfile.Task, call taskfile.Call) (*ta
 // 3. Taskfile variables
 // 4. Taskvars file variables
 // 5. Environment variables
-func (c *CompilerV2) GetVariables(t *taskfile.Task, call taskfile.Call) (*taskfile.Vars, error) {
+func (c *CompilerV2) GetVariables(ctx context.Context, t *taskfile.Task, call taskfile.Call) (*taskfile.Vars, error) {
 	vr := varResolver{
+		ctx:  ctx,
 		c:    c,
-		vars: compiler.GetEnviron(),
+		vars: compiler.GetEnviron(nil),
 	}
 	vr.vars.Set("TASK", taskfile.Var{Static: t.Task})
 
@@ -61,6 +62,7 @@ func (c *CompilerV2) GetVariables(t *taskfile.Task, call taskfile.Call) (*taskfi
 }
 
 type varResolver struct {
+	ctx  context.Context
 	c    *CompilerV2
 	vars *taskfile.Vars
 	err  error
@@ -76,7 +78,7 @@ func (vr *varResolver) merge(vars *taskfile.Vars) {
 			Static: tr.Replace(v.Static),
 			Sh:     tr.Replace(v.Sh),
 		}
-		static, err := vr.c.HandleDynamicVar(v, "")
+		static, err := vr.c.HandleDynamicVar(vr.ctx, v, "")
 		if err != nil {
 			vr.err = err
 			return err
@@ -87,7 +89,7 @@ func (vr *varResolver) merge(vars *taskfile.Vars) {
 	vr.err = tr.Err()
 }
 
-func (c *CompilerV2) HandleDynamicVar(v taskfile.Var, _ string) (string, error) {
+func (c *CompilerV2) HandleDynamicVar(ctx context.Context, v taskfile.Var, _ string) (string, error) {
 	if v.Static != "" || v.Sh == "" {
 		return v.Static, nil
 	}
@@ -108,8 +110,8 @@ func (c *CompilerV2) HandleDynamicVar(v taskfile.Var, _ string) (string, error)
 		Stdout:  &stdout,
 		Stderr:  c.Logger.Stderr,
 	}
-	if err := execext.RunCommand(context.Background(), opts); err != nil {
-		return "", fmt.Errorf(`task: Command "%s" failed: %s`, opts.Command, err)
+	if err := execext.RunCommand(ctx, opts); err != nil {
+		return "", fmt.Errorf(`task: Command "%s" failed: %w`, opts.Command, err)
 	}
 
 	// Trim a single trailing newline from the result to make most command
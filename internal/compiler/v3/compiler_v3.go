@@ -4,6 +4,8 @@ import (
 	"bytes"
 	"context"
 	"fmt"
+	"path/filepath"
+	"runtime"
 	"strings"
 	"sync"
 
@@ -24,27 +26,55 @@ type CompilerV3 struct {
 
 	TaskfileEnv  *taskfile.Vars
 	TaskfileVars *taskfile.Vars
+	// EnvPolicy limits the environment available to the templates and the
+	// dynamic variables
+	EnvPolicy *taskfile.EnvPolicy
+	// SlashPaths writes the directories in the special variables (like
+	// TASKFILE_DIR) with forward slashes, which the embedded shell prefers on
+	// Windows
+	SlashPaths bool
+	// Builtins runs the dynamic variables of the Taskfile with the portable
+	// built-in commands. The ones of the tasks use the setting of their own
+	// Taskfile.
+	Builtins bool
 
 	Logger *logger.Logger
 
-	dynamicCache   map[string]string
+	// PromptVar asks the user for the value of a variable with a prompt. When
+	// nil, the default of the variable is used.
+	PromptVar func(name string, v taskfile.Var) (string, error)
+
+	dynamicCache   map[dynamicKey]*dynamicResult
 	muDynamicCache sync.Mutex
 }
 
-func (c *CompilerV3) GetTaskfileVariables() (*taskfile.Vars, error) {
-	return c.getVariables(nil, nil, true)
+// The dynamic variables run once per run for each command, directory and
+// environment, even when many tasks compile them at the same time.
+type dynamicKey struct {
+	sh, dir, env string
+	builtins     bool
+}
+
+type dynamicResult struct {
+	once  sync.Once
+	value string
+	err   error
+}
+
+func (c *CompilerV3) GetTaskfileVariables(ctx context.Context) (*taskfile.Vars, error) {
+	return c.getVariables(ctx, nil, nil, true)
 }
 
-func (c *CompilerV3) GetVariables(t *taskfile.Task, call taskfile.Call) (*taskfile.Vars, error) {
-	return c.getVariables(t, &call, true)
+func (c *CompilerV3) GetVariables(ctx context.Context, t *taskfile.Task, call taskfile.Call) (*taskfile.Vars, error) {
+	return c.getVariables(ctx, t, &call, true)
 }
 
-func (c *CompilerV3) FastGetVariables(t *taskfile.Task, call taskfile.Call) (*taskfile.Vars, error) {
-	return c.getVariables(t, &call, false)
+func (c *CompilerV3) FastGetVariables(ctx context.Context, t *taskfile.Task, call taskfile.Call) (*taskfile.Vars, error) {
+	return c.getVariables(ctx, t, &call, false)
 }
 
-func (c *CompilerV3) getVariables(t *taskfile.Task, call *taskfile.Call, evaluateShVars bool) (*taskfile.Vars, error) {
-	result := compiler.GetEnviron()
+func (c *CompilerV3) getVariables(ctx context.Context, t *taskfile.Task, call *taskfile.Call, evaluateShVars bool) (*taskfile.Vars, error) {
+	result := compiler.GetEnviron(c.EnvPolicy)
 	if t != nil {
 		specialVars, err := c.getSpecialVars(t)
 		if err != nil {
@@ -53,17 +83,74 @@ func (c *CompilerV3) getVariables(t *taskfile.Task, call *taskfile.Call, evaluat
 		for k, v := range specialVars {
 			result.Set(k, taskfile.Var{Static: v})
 		}
+		if len(t.Wildcards) > 0 {
+			result.Set("MATCH", matchVar(t.Wildcards))
+		}
 	}
 
-	getRangeFunc := func(dir string) func(k string, v taskfile.Var) error {
+	getRangeFunc := func(dir string, builtins bool) func(k string, v taskfile.Var) error {
 		return func(k string, v taskfile.Var) error {
-			tr := templater.Templater{Vars: result, RemoveNoValue: true}
+			// Structured values are used as they are
+			if v.Live != nil {
+				result.Set(k, taskfile.Var{Live: v.Live})
+				return nil
+			}
+
+			tr := templater.Templater{
+				Vars:          result,
+				RemoveNoValue: true,
+				Dir:           c.Dir,
+				ResolveLazy: func(v taskfile.Var) (string, error) {
+					return c.handleDynamicVar(ctx, v, dir, builtins, false)
+				},
+			}
+
+			// Prompts are only asked when the variable isn't set yet
+			if v.Prompt != "" {
+				if result.Exists(k) || (call != nil && call.Vars.Exists(k) && call.Vars.Get(k).Prompt == "") {
+					return nil
+				}
+				v = taskfile.Var{
+					Prompt:  tr.Replace(v.Prompt),
+					Default: tr.Replace(v.Default),
+					Secret:  v.Secret,
+				}
+				if err := tr.Err(); err != nil {
+					return err
+				}
+				// The user is only asked when a task is about to run
+				value := v.Default
+				if evaluateShVars && t != nil && c.PromptVar != nil {
+					var err error
+					if value, err = c.PromptVar(k, v); err != nil {
+						return err
+					}
+				}
+				result.Set(k, taskfile.Var{Static: value})
+				return nil
+			}
+
+			// References are resolved without templating the value again
+			if v.Ref != "" {
+				result.Set(k, tr.ResolveRef(v.Ref))
+				return nil
+			}
 
 			if !evaluateShVars {
 				result.Set(k, taskfile.Var{Static: tr.Replace(v.Static)})
 				return nil
 			}
 
+			// Lazy variables run when a template uses them, in the directory
+			// they're defined in
+			if v.Lazy && v.Sh != "" {
+				if v.Dir == "" {
+					v.Dir = dir
+				}
+				result.Set(k, taskfile.Var{Sh: tr.Replace(v.Sh), Dir: v.Dir, Lazy: true})
+				return tr.Err()
+			}
+
 			v = taskfile.Var{
 				Static: tr.Replace(v.Static),
 				Sh:     tr.Replace(v.Sh),
@@ -72,7 +159,7 @@ func (c *CompilerV3) getVariables(t *taskfile.Task, call *taskfile.Call, evaluat
 			if err := tr.Err(); err != nil {
 				return err
 			}
-			static, err := c.HandleDynamicVar(v, dir)
+			static, err := c.handleDynamicVar(ctx, v, dir, builtins, false)
 			if err != nil {
 				return err
 			}
@@ -80,32 +167,63 @@ func (c *CompilerV3) getVariables(t *taskfile.Task, call *taskfile.Call, evaluat
 			return nil
 		}
 	}
-	rangeFunc := getRangeFunc(c.Dir)
+	rangeFunc := getRangeFunc(c.Dir, c.Builtins)
 
 	var taskRangeFunc func(k string, v taskfile.Var) error
+	var taskDir string
 	if t != nil {
 		// NOTE(@andreynering): We're manually joining these paths here because
 		// this is the raw task, not the compiled one.
-		tr := templater.Templater{Vars: result, RemoveNoValue: true}
+		tr := templater.Templater{Vars: result, RemoveNoValue: true, Dir: c.Dir}
 		dir := tr.Replace(t.Dir)
 		if err := tr.Err(); err != nil {
 			return nil, err
 		}
-		dir = filepathext.SmartJoin(c.Dir, dir)
-		taskRangeFunc = getRangeFunc(dir)
+		taskDir = filepathext.SmartJoin(c.Dir, dir)
+		taskRangeFunc = getRangeFunc(taskDir, t.Builtins)
 	}
 
-	if err := c.TaskfileEnv.Range(rangeFunc); err != nil {
+	if evaluateShVars {
+		c.prefetchDynamicVars(ctx, t, call, taskDir)
+	}
+
+	// With --verbose, the origin of the vars of the tasks is printed
+	var origins map[string]string
+	trace := func(origin string, f func(k string, v taskfile.Var) error) func(k string, v taskfile.Var) error {
+		if c.Logger == nil || !c.Logger.Verbose || !evaluateShVars || t == nil || call == nil {
+			return f
+		}
+		if origins == nil {
+			origins = make(map[string]string)
+		}
+		return func(k string, v taskfile.Var) error {
+			if err := f(k, v); err != nil {
+				return err
+			}
+			origins[k] = origin
+			return nil
+		}
+	}
+
+	if err := c.TaskfileEnv.Range(trace("the global env", rangeFunc)); err != nil {
 		return nil, err
 	}
-	if err := c.TaskfileVars.Range(rangeFunc); err != nil {
+	if err := c.TaskfileVars.Range(trace("the global vars", rangeFunc)); err != nil {
 		return nil, err
 	}
 	if t != nil {
-		if err := t.IncludedTaskfileVars.Range(taskRangeFunc); err != nil {
+		// The vars given by the include come first, so the vars of the
+		// included Taskfile can use them, but they can't override them
+		if err := t.IncludeVars.Range(trace("the include", rangeFunc)); err != nil {
 			return nil, err
 		}
-		if err := t.IncludeVars.Range(rangeFunc); err != nil {
+		err := t.IncludedTaskfileVars.Range(func(k string, v taskfile.Var) error {
+			if t.IncludeVars.Exists(k) {
+				return nil
+			}
+			return trace("the included Taskfile", taskRangeFunc)(k, v)
+		})
+		if err != nil {
 			return nil, err
 		}
 	}
@@ -114,45 +232,76 @@ func (c *CompilerV3) getVariables(t *taskfile.Task, call *taskfile.Call, evaluat
 		return result, nil
 	}
 
-	if err := call.Vars.Range(rangeFunc); err != nil {
+	if err := call.Vars.Range(trace("the call", rangeFunc)); err != nil {
 		return nil, err
 	}
-	if err := t.Vars.Range(taskRangeFunc); err != nil {
+	if err := t.Vars.Range(trace("the task", taskRangeFunc)); err != nil {
 		return nil, err
 	}
 
+	for _, k := range result.Keys() {
+		if origin, ok := origins[k]; ok {
+			c.Logger.VerboseErrf(logger.Magenta, "task: %q var %q set by %s\n", t.Task, k, origin)
+		}
+	}
 	return result, nil
 }
 
-func (c *CompilerV3) HandleDynamicVar(v taskfile.Var, dir string) (string, error) {
+func (c *CompilerV3) HandleDynamicVar(ctx context.Context, v taskfile.Var, dir string) (string, error) {
+	return c.handleDynamicVar(ctx, v, dir, c.Builtins, false)
+}
+
+// handleDynamicVar returns the cached result of the command. The failures
+// are removed from the cache once returned, so the command runs again the
+// next time, unless keepErr is set.
+func (c *CompilerV3) handleDynamicVar(ctx context.Context, v taskfile.Var, dir string, builtins, keepErr bool) (string, error) {
 	if v.Static != "" || v.Sh == "" {
 		return v.Static, nil
 	}
 
-	c.muDynamicCache.Lock()
-	defer c.muDynamicCache.Unlock()
+	// NOTE(@andreynering): If a var have a specific dir, use this instead
+	if v.Dir != "" {
+		dir = v.Dir
+	}
+	env := c.EnvPolicy.Environ()
+	key := dynamicKey{sh: v.Sh, dir: dir, env: strings.Join(env, "\x00"), builtins: builtins}
 
+	c.muDynamicCache.Lock()
 	if c.dynamicCache == nil {
-		c.dynamicCache = make(map[string]string, 30)
+		c.dynamicCache = make(map[dynamicKey]*dynamicResult, 30)
 	}
-	if result, ok := c.dynamicCache[v.Sh]; ok {
-		return result, nil
+	result, ok := c.dynamicCache[key]
+	if !ok {
+		result = &dynamicResult{}
+		c.dynamicCache[key] = result
 	}
-
-	// NOTE(@andreynering): If a var have a specific dir, use this instead
-	if v.Dir != "" {
-		dir = v.Dir
+	c.muDynamicCache.Unlock()
+
+	result.once.Do(func() {
+		result.value, result.err = c.runDynamicVar(ctx, v.Sh, dir, env, builtins)
+	})
+	if result.err != nil && !keepErr {
+		c.muDynamicCache.Lock()
+		if c.dynamicCache[key] == result {
+			delete(c.dynamicCache, key)
+		}
+		c.muDynamicCache.Unlock()
 	}
+	return result.value, result.err
+}
 
+func (c *CompilerV3) runDynamicVar(ctx context.Context, sh, dir string, env []string, builtins bool) (string, error) {
 	var stdout bytes.Buffer
 	opts := &execext.RunCommandOptions{
-		Command: v.Sh,
-		Dir:     dir,
-		Stdout:  &stdout,
-		Stderr:  c.Logger.Stderr,
+		Command:  sh,
+		Dir:      dir,
+		Env:      env,
+		Builtins: builtins,
+		Stdout:   &stdout,
+		Stderr:   c.Logger.Stderr,
 	}
-	if err := execext.RunCommand(context.Background(), opts); err != nil {
-		return "", fmt.Errorf(`task: Command "%s" failed: %s`, opts.Command, err)
+	if err := execext.RunCommand(ctx, opts); err != nil {
+		return "", fmt.Errorf(`task: Command "%s" failed: %w`, opts.Command, err)
 	}
 
 	// Trim a single trailing newline from the result to make most command
@@ -160,18 +309,71 @@ func (c *CompilerV3) HandleDynamicVar(v taskfile.Var, dir string) (string, error
 	result := strings.TrimSuffix(stdout.String(), "\r\n")
 	result = strings.TrimSuffix(result, "\n")
 
-	c.dynamicCache[v.Sh] = result
-	c.Logger.VerboseErrf(logger.Magenta, "task: dynamic variable: %q result: %q\n", v.Sh, result)
+	c.Logger.VerboseErrf(logger.Magenta, "task: dynamic variable: %q result: %q\n", sh, result)
 
 	return result, nil
 }
 
+// prefetchDynamicVars runs the dynamic variables that don't use other
+// variables at the same time, before they're compiled one after the other, so
+// their results are already in the cache. Their errors are returned later,
+// when compiling the variable that failed.
+func (c *CompilerV3) prefetchDynamicVars(ctx context.Context, t *taskfile.Task, call *taskfile.Call, taskDir string) {
+	type dynamicVar struct {
+		v        taskfile.Var
+		dir      string
+		builtins bool
+	}
+	var vars []dynamicVar
+	add := func(dir string, builtins bool, skip func(k string) bool) func(k string, v taskfile.Var) error {
+		return func(k string, v taskfile.Var) error {
+			if v.Sh == "" || v.Lazy || v.Static != "" || v.Prompt != "" || v.Ref != "" || v.Live != nil || strings.Contains(v.Sh, "{{") {
+				return nil
+			}
+			if skip == nil || !skip(k) {
+				vars = append(vars, dynamicVar{v: v, dir: dir, builtins: builtins})
+			}
+			return nil
+		}
+	}
+	_ = c.TaskfileEnv.Range(add(c.Dir, c.Builtins, nil))
+	_ = c.TaskfileVars.Range(add(c.Dir, c.Builtins, nil))
+	if t != nil {
+		_ = t.IncludeVars.Range(add(c.Dir, c.Builtins, nil))
+		_ = t.IncludedTaskfileVars.Range(add(taskDir, t.Builtins, t.IncludeVars.Exists))
+		if call != nil {
+			_ = call.Vars.Range(add(c.Dir, c.Builtins, nil))
+			_ = t.Vars.Range(add(taskDir, t.Builtins, nil))
+		}
+	}
+	if len(vars) < 2 {
+		return
+	}
+
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, runtime.NumCPU())
+	for _, dv := range vars {
+		dv := dv
+		wg.Add(1)
+		sem <- struct{}{}
+		go func() {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			_, _ = c.handleDynamicVar(ctx, dv.v, dv.dir, dv.builtins, true)
+		}()
+	}
+	wg.Wait()
+}
+
 // ResetCache clear the dymanic variables cache
 func (c *CompilerV3) ResetCache() {
 	c.muDynamicCache.Lock()
 	defer c.muDynamicCache.Unlock()
 
 	c.dynamicCache = nil
+	templater.ClearGitCache()
 }
 
 func (c *CompilerV3) getSpecialVars(t *taskfile.Task) (map[string]string, error) {
@@ -180,15 +382,35 @@ func (c *CompilerV3) getSpecialVars(t *taskfile.Task) (map[string]string, error)
 		return nil, err
 	}
 
+	rootDir, userWorkingDir := c.Dir, c.UserWorkingDir
+	if c.SlashPaths {
+		rootDir = filepath.ToSlash(rootDir)
+		taskfileDir = filepath.ToSlash(taskfileDir)
+		userWorkingDir = filepath.ToSlash(userWorkingDir)
+	}
+
 	return map[string]string{
 		"TASK":             t.Task,
-		"ROOT_DIR":         c.Dir,
+		"ROOT_DIR":         rootDir,
 		"TASKFILE_DIR":     taskfileDir,
-		"USER_WORKING_DIR": c.UserWorkingDir,
+		"USER_WORKING_DIR": userWorkingDir,
 		"TASK_VERSION":     version.GetVersion(),
 	}, nil
 }
 
+// matchVar returns the MATCH variable of a task called through a wildcard
+// name: the matched part when there's a single wildcard, or the list of them.
+func matchVar(wildcards []string) taskfile.Var {
+	if len(wildcards) == 1 {
+		return taskfile.Var{Static: wildcards[0]}
+	}
+	list := make([]any, len(wildcards))
+	for i, w := range wildcards {
+		list[i] = w
+	}
+	return taskfile.Var{Live: list}
+}
+
 func (c *CompilerV3) getTaskfileDir(t *taskfile.Task) (string, error) {
 	if t.IncludedTaskfile != nil {
 		return t.IncludedTaskfile.FullDirPath()
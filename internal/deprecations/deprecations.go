@@ -0,0 +1,73 @@
+package deprecations
+
+import (
+	"sync"
+
+	"github.com/nuvolaris/task/v3/errors"
+	"github.com/nuvolaris/task/v3/internal/logger"
+)
+
+// A Deprecation is something of the Taskfiles or of the CLI that still works,
+// but will be removed or change in a future release.
+type Deprecation struct {
+	Name string
+	// Docs is the page telling why and how to migrate
+	Docs string
+}
+
+// The known deprecations.
+var (
+	Version2Schema = &Deprecation{
+		Name: "version-2-schema",
+		Docs: "https://github.com/go-task/task/issues/1197",
+	}
+	TemplateFunctions = &Deprecation{
+		Name: "template-functions",
+		Docs: "https://taskfile.dev/deprecations/template-functions/",
+	}
+	// ForceAll is the --force flag forcing the deps of the tasks called too,
+	// which the gentle force experiment changes
+	ForceAll = &Deprecation{
+		Name: "force-all",
+		Docs: "https://taskfile.dev/experiments/gentle-force/",
+	}
+)
+
+// A Reporter warns about the deprecations used, once each, or fails on them
+// when strict.
+type Reporter struct {
+	Logger *logger.Logger
+	Strict bool
+
+	mutex    sync.Mutex
+	reported map[string]bool
+}
+
+// Report warns about a use of the deprecation, with a message telling what is
+// deprecated, unless the same was already reported. When strict, it returns
+// it as an error instead.
+func (r *Reporter) Report(d *Deprecation, message string) error {
+	if r.Strict {
+		return &errors.TaskfileDeprecatedError{Message: message, Docs: d.Docs}
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	key := d.Name + "\x00" + message
+	if r.reported[key] {
+		return nil
+	}
+	if r.reported == nil {
+		r.reported = map[string]bool{}
+	}
+	r.reported[key] = true
+	r.Logger.Errf(logger.Yellow, "task: %s\nSee %s for more details\n", message, d.Docs)
+	return nil
+}
+
+// Reset forgets the deprecations reported, so they're reported again.
+func (r *Reporter) Reset() {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.reported = nil
+}
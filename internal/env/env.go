@@ -3,16 +3,24 @@ package env
 import (
 	"fmt"
 	"os"
+	"sort"
+	"strings"
 
 	"github.com/nuvolaris/task/v3/taskfile"
 )
 
+// Get returns the environment of the commands of a task: the inherited one,
+// limited by the env policy of the task, and the env of the task. It's nil
+// when both are the ones of the process.
 func Get(t *taskfile.Task) []string {
-	if t.Env == nil {
+	if t.Env == nil && t.EnvPolicy == nil {
 		return nil
 	}
 
 	environ := os.Environ()
+	if t.EnvPolicy != nil {
+		environ = t.EnvPolicy.Filter(environ)
+	}
 
 	for k, v := range t.Env.ToCacheMap() {
 		str, isString := v.(string)
@@ -20,7 +28,7 @@ func Get(t *taskfile.Task) []string {
 			continue
 		}
 
-		if _, alreadySet := os.LookupEnv(k); alreadySet {
+		if _, alreadySet := os.LookupEnv(k); alreadySet && t.EnvPolicy.Allowed(k) {
 			continue
 		}
 
@@ -29,3 +37,37 @@ func Get(t *taskfile.Task) []string {
 
 	return environ
 }
+
+// Diff returns the variables of environ that the environment of the process
+// doesn't have or has another value of, as KEY=value, and the names of the ones
+// of the process environ doesn't have, both sorted. A nil environ is the one
+// of the process.
+func Diff(environ []string) (set, unset []string) {
+	if environ == nil {
+		return nil, nil
+	}
+	process := toMap(os.Environ())
+	env := toMap(environ)
+	for k, v := range env {
+		if processValue, ok := process[k]; !ok || processValue != v {
+			set = append(set, k+"="+v)
+		}
+	}
+	for k := range process {
+		if _, ok := env[k]; !ok {
+			unset = append(unset, k)
+		}
+	}
+	sort.Strings(set)
+	sort.Strings(unset)
+	return set, unset
+}
+
+func toMap(environ []string) map[string]string {
+	m := make(map[string]string, len(environ))
+	for _, kv := range environ {
+		k, v, _ := strings.Cut(kv, "=")
+		m[k] = v
+	}
+	return m
+}
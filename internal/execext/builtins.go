@@ -0,0 +1,269 @@
+package execext
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/nuvolaris/sh/v3/interp"
+)
+
+type builtinFunc func(ctx context.Context, hc interp.HandlerContext, flags string, args []string) error
+
+// builtins are portable implementations of common commands. They're only
+// used when the host doesn't provide the command, so simple Taskfiles also
+// work on bare Windows installs or scratch containers.
+var builtins = map[string]struct {
+	flags string
+	run   builtinFunc
+}{
+	"cat":   {"", builtinCat},
+	"cp":    {"rRf", builtinCp},
+	"mkdir": {"p", builtinMkdir},
+	"mv":    {"f", builtinMv},
+	"rm":    {"rRf", builtinRm},
+	"sleep": {"", builtinSleep},
+}
+
+func builtinsExecHandler(next interp.ExecHandlerFunc) interp.ExecHandlerFunc {
+	return func(ctx context.Context, args []string) error {
+		builtin, ok := builtins[args[0]]
+		if !ok {
+			return next(ctx, args)
+		}
+		hc := interp.HandlerCtx(ctx)
+		if _, err := interp.LookPathDir(hc.Dir, hc.Env, args[0]); err == nil {
+			return next(ctx, args)
+		}
+
+		flags, operands, err := parseBuiltinFlags(builtin.flags, args[1:])
+		if err == nil {
+			err = builtin.run(ctx, hc, flags, operands)
+		}
+		if err != nil {
+			fmt.Fprintf(hc.Stderr, "%s: %v\n", args[0], err)
+			return interp.NewExitStatus(1)
+		}
+		return nil
+	}
+}
+
+// parseBuiltinFlags splits the leading single letter flags (e.g. "-rf") from
+// the operands, failing on unknown flags.
+func parseBuiltinFlags(allowed string, args []string) (string, []string, error) {
+	var flags strings.Builder
+	for len(args) > 0 {
+		arg := args[0]
+		if arg == "--" {
+			args = args[1:]
+			break
+		}
+		if len(arg) < 2 || arg[0] != '-' {
+			break
+		}
+		for _, flag := range arg[1:] {
+			if !strings.ContainsRune(allowed, flag) {
+				return "", nil, fmt.Errorf("unknown flag -%c", flag)
+			}
+			flags.WriteRune(flag)
+		}
+		args = args[1:]
+	}
+	return flags.String(), args, nil
+}
+
+func builtinPath(hc interp.HandlerContext, path string) string {
+	if filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(hc.Dir, path)
+}
+
+func builtinCat(ctx context.Context, hc interp.HandlerContext, flags string, args []string) error {
+	if len(args) == 0 {
+		args = []string{"-"}
+	}
+	for _, arg := range args {
+		if arg == "-" {
+			if hc.Stdin == nil {
+				continue
+			}
+			if _, err := io.Copy(hc.Stdout, hc.Stdin); err != nil {
+				return err
+			}
+			continue
+		}
+		f, err := os.Open(builtinPath(hc, arg))
+		if err != nil {
+			return err
+		}
+		_, err = io.Copy(hc.Stdout, f)
+		f.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func builtinMkdir(ctx context.Context, hc interp.HandlerContext, flags string, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("missing operand")
+	}
+	for _, arg := range args {
+		var err error
+		if strings.Contains(flags, "p") {
+			err = os.MkdirAll(builtinPath(hc, arg), 0o755)
+		} else {
+			err = os.Mkdir(builtinPath(hc, arg), 0o755)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func builtinRm(ctx context.Context, hc interp.HandlerContext, flags string, args []string) error {
+	recursive := strings.ContainsAny(flags, "rR")
+	force := strings.Contains(flags, "f")
+	if len(args) == 0 && !force {
+		return fmt.Errorf("missing operand")
+	}
+	for _, arg := range args {
+		path := builtinPath(hc, arg)
+		info, err := os.Lstat(path)
+		if err != nil {
+			if force && os.IsNotExist(err) {
+				continue
+			}
+			return err
+		}
+		if info.IsDir() && !recursive {
+			return fmt.Errorf("%s: is a directory", arg)
+		}
+		if err := os.RemoveAll(path); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// builtinTarget returns the destination of each source for cp and mv, which
+// is inside the last operand when it's a directory.
+func builtinTarget(hc interp.HandlerContext, args []string) ([][2]string, error) {
+	if len(args) < 2 {
+		return nil, fmt.Errorf("missing destination operand")
+	}
+	sources, dest := args[:len(args)-1], builtinPath(hc, args[len(args)-1])
+	info, err := os.Stat(dest)
+	isDir := err == nil && info.IsDir()
+	if len(sources) > 1 && !isDir {
+		return nil, fmt.Errorf("%s: not a directory", args[len(args)-1])
+	}
+	targets := make([][2]string, 0, len(sources))
+	for _, source := range sources {
+		target := dest
+		if isDir {
+			target = filepath.Join(dest, filepath.Base(source))
+		}
+		targets = append(targets, [2]string{builtinPath(hc, source), target})
+	}
+	return targets, nil
+}
+
+func builtinMv(ctx context.Context, hc interp.HandlerContext, flags string, args []string) error {
+	targets, err := builtinTarget(hc, args)
+	if err != nil {
+		return err
+	}
+	for _, t := range targets {
+		if err := os.Rename(t[0], t[1]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func builtinCp(ctx context.Context, hc interp.HandlerContext, flags string, args []string) error {
+	targets, err := builtinTarget(hc, args)
+	if err != nil {
+		return err
+	}
+	recursive := strings.ContainsAny(flags, "rR")
+	for _, t := range targets {
+		info, err := os.Stat(t[0])
+		if err != nil {
+			return err
+		}
+		if info.IsDir() && !recursive {
+			return fmt.Errorf("-r not specified; omitting directory %s", t[0])
+		}
+		if err := copyPath(t[0], t[1], info); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func copyPath(source, target string, info os.FileInfo) error {
+	if !info.IsDir() {
+		return copyFile(source, target, info.Mode())
+	}
+	return filepath.Walk(source, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(source, path)
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			return os.MkdirAll(filepath.Join(target, rel), info.Mode().Perm())
+		}
+		return copyFile(path, filepath.Join(target, rel), info.Mode())
+	})
+}
+
+func copyFile(source, target string, mode os.FileMode) error {
+	in, err := os.Open(source)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(target, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, mode.Perm())
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+func builtinSleep(ctx context.Context, hc interp.HandlerContext, flags string, args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("expected a single duration")
+	}
+	d, err := time.ParseDuration(args[0])
+	if err != nil {
+		seconds, err := strconv.ParseFloat(args[0], 64)
+		if err != nil {
+			return fmt.Errorf("invalid time interval %q", args[0])
+		}
+		d = time.Duration(seconds * float64(time.Second))
+	}
+	select {
+	case <-time.After(d):
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
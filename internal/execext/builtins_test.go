@@ -0,0 +1,67 @@
+package execext_test
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/nuvolaris/sh/v3/interp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nuvolaris/task/v3/internal/execext"
+)
+
+func TestBuiltins(t *testing.T) {
+	// The nuv integration handles these commands itself when enabled
+	nuvIntegrationEnabled := interp.NuvIntegrationEnabled
+	interp.NuvIntegrationEnabled = false
+	t.Cleanup(func() { interp.NuvIntegrationEnabled = nuvIntegrationEnabled })
+
+	dir := t.TempDir()
+	run := func(command string, builtins bool) (string, error) {
+		var buff bytes.Buffer
+		err := execext.RunCommand(context.Background(), &execext.RunCommandOptions{
+			Command:  command,
+			Dir:      dir,
+			Env:      []string{"PATH="}, // Make sure the host commands aren't found
+			Builtins: builtins,
+			Stdout:   &buff,
+			Stderr:   &buff,
+		})
+		return buff.String(), err
+	}
+
+	_, err := run("mkdir out", false)
+	require.Error(t, err)
+
+	_, err = run("mkdir -p out/sub && echo foo > out/a.txt", true)
+	require.NoError(t, err)
+	_, err = run("cp out/a.txt out/b.txt && mv out/b.txt out/sub && cp -r out/sub out/copy", true)
+	require.NoError(t, err)
+
+	out, err := run("cat out/a.txt out/sub/b.txt out/copy/b.txt", true)
+	require.NoError(t, err)
+	assert.Equal(t, "foo\nfoo\nfoo\n", out)
+
+	out, err = run("rm out/sub", true)
+	require.Error(t, err)
+	assert.Contains(t, out, "rm: out/sub: is a directory")
+
+	_, err = run("rm -rf out missing", true)
+	require.NoError(t, err)
+	_, err = os.Stat(filepath.Join(dir, "out"))
+	assert.True(t, os.IsNotExist(err))
+
+	start := time.Now()
+	_, err = run("sleep 0.05", true)
+	require.NoError(t, err)
+	assert.GreaterOrEqual(t, time.Since(start), 50*time.Millisecond)
+
+	out, err = run("mkdir -x foo", true)
+	require.Error(t, err)
+	assert.Equal(t, "mkdir: unknown flag -x\n", out)
+}
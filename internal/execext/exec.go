@@ -23,9 +23,18 @@ type RunCommandOptions struct {
 	Env       []string
 	PosixOpts []string
 	BashOpts  []string
+	Builtins  bool
 	Stdin     io.Reader
 	Stdout    io.Writer
 	Stderr    io.Writer
+	// OnProcess, when set, is called with every process started by the
+	// command, e.g. to forward signals to it. The function it returns is
+	// called once the process exits.
+	OnProcess func(p *os.Process) func()
+	// ProcessGroup starts every process in its own process group, and stops
+	// the whole group when the context is cancelled, so the processes it
+	// started are stopped too, like the ones of a dev server.
+	ProcessGroup bool
 }
 
 // ErrNilOptions is returned when a nil options is given
@@ -51,15 +60,24 @@ func RunCommand(ctx context.Context, opts *RunCommandOptions) error {
 		}
 	}
 
+	// An empty but not nil environment is a hermetic one
 	environ := opts.Env
-	if len(environ) == 0 {
+	if environ == nil {
 		environ = os.Environ()
 	}
 
+	execHandlers := []func(next interp.ExecHandlerFunc) interp.ExecHandlerFunc{execHandler}
+	if opts.OnProcess != nil || opts.ProcessGroup {
+		execHandlers = []func(next interp.ExecHandlerFunc) interp.ExecHandlerFunc{processExecHandler(opts.OnProcess, opts.ProcessGroup)}
+	}
+	if opts.Builtins {
+		execHandlers = append([]func(next interp.ExecHandlerFunc) interp.ExecHandlerFunc{builtinsExecHandler}, execHandlers...)
+	}
+
 	r, err := interp.New(
 		interp.Params(params...),
 		interp.Env(expand.ListEnviron(environ...)),
-		interp.ExecHandlers(execHandler),
+		interp.ExecHandlers(execHandlers...),
 		interp.OpenHandler(openHandler),
 		interp.StdIO(opts.Stdin, opts.Stdout, opts.Stderr),
 		dirOption(opts.Dir),
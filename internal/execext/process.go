@@ -0,0 +1,120 @@
+package execext
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/nuvolaris/sh/v3/expand"
+	"github.com/nuvolaris/sh/v3/interp"
+)
+
+// killTimeout is how long a process has to exit after being interrupted
+// because its context was cancelled, before being killed.
+const killTimeout = 15 * time.Second
+
+// processExecHandler works like interp.DefaultExecHandler, but tells about
+// the processes it starts, which the default handler keeps to itself, and can
+// start them in their own process group.
+func processExecHandler(onProcess func(p *os.Process) func(), group bool) func(next interp.ExecHandlerFunc) interp.ExecHandlerFunc {
+	return func(next interp.ExecHandlerFunc) interp.ExecHandlerFunc {
+		return func(ctx context.Context, args []string) error {
+			hc := interp.HandlerCtx(ctx)
+			path, err := interp.LookPathDir(hc.Dir, hc.Env, args[0])
+			if err != nil {
+				fmt.Fprintln(hc.Stderr, err)
+				return interp.NewExitStatus(127)
+			}
+			cmd := exec.Cmd{
+				Path:   path,
+				Args:   args,
+				Env:    execEnv(hc.Env),
+				Dir:    hc.Dir,
+				Stdin:  hc.Stdin,
+				Stdout: hc.Stdout,
+				Stderr: hc.Stderr,
+			}
+
+			if group {
+				setProcessGroup(&cmd)
+			}
+			err = cmd.Start()
+			if err == nil {
+				exited := func() {}
+				if onProcess != nil {
+					exited = onProcess(cmd.Process)
+				}
+				stop := make(chan struct{})
+				go func() {
+					select {
+					case <-ctx.Done():
+					case <-stop:
+						return
+					}
+					if group {
+						time.AfterFunc(killTimeout, func() { killProcessGroup(cmd.Process) })
+						terminateProcessGroup(cmd.Process)
+						return
+					}
+					if runtime.GOOS == "windows" {
+						_ = cmd.Process.Signal(os.Kill)
+						return
+					}
+					time.AfterFunc(killTimeout, func() { _ = cmd.Process.Signal(os.Kill) })
+					_ = cmd.Process.Signal(os.Interrupt)
+				}()
+				err = cmd.Wait()
+				close(stop)
+				exited()
+			}
+
+			switch x := err.(type) {
+			case *exec.ExitError:
+				// started, but errored - default to 1 if OS
+				// doesn't have exit statuses
+				if status, ok := x.Sys().(syscall.WaitStatus); ok {
+					if status.Signaled() {
+						if ctx.Err() != nil {
+							return ctx.Err()
+						}
+						return interp.NewExitStatus(uint8(128 + status.Signal()))
+					}
+					return interp.NewExitStatus(uint8(status.ExitStatus()))
+				}
+				return interp.NewExitStatus(1)
+			case *exec.Error:
+				// did not start
+				fmt.Fprintf(hc.Stderr, "%v\n", err)
+				return interp.NewExitStatus(127)
+			default:
+				return err
+			}
+		}
+	}
+}
+
+// execEnv returns the exported variables of the environment, as "key=value"
+// pairs, like the unexported function of interp.
+func execEnv(env expand.Environ) []string {
+	list := make([]string, 0, 64)
+	env.Each(func(name string, vr expand.Variable) bool {
+		if !vr.IsSet() {
+			// Variables unset by the command aren't inherited
+			for i, kv := range list {
+				if strings.HasPrefix(kv, name+"=") {
+					list[i] = ""
+				}
+			}
+		}
+		if vr.Exported && vr.Kind == expand.String {
+			list = append(list, name+"="+vr.String())
+		}
+		return true
+	})
+	return list
+}
@@ -0,0 +1,24 @@
+//go:build windows
+
+package execext
+
+import (
+	"os"
+	"os/exec"
+)
+
+// NOTE: Windows has no process groups that can be signaled, so only the
+// process itself is stopped.
+func setProcessGroup(cmd *exec.Cmd) {}
+
+func SignalProcessGroup(p *os.Process, sig os.Signal) error {
+	return p.Signal(sig)
+}
+
+func terminateProcessGroup(p *os.Process) {
+	_ = p.Kill()
+}
+
+func killProcessGroup(p *os.Process) {
+	_ = p.Kill()
+}
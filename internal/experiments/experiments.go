@@ -18,12 +18,27 @@ const envPrefix = "TASK_X_"
 var (
 	GentleForce     bool
 	RemoteTaskfiles bool
+	AnyVariables    bool
+	ZeroConfig      bool
 )
 
+// all is the name of each experiment, after envPrefix, and where it's
+// enabled, in the order they're listed.
+var all = []struct {
+	name  string
+	value *bool
+}{
+	{"GENTLE_FORCE", &GentleForce},
+	{"REMOTE_TASKFILES", &RemoteTaskfiles},
+	{"ANY_VARIABLES", &AnyVariables},
+	{"ZERO_CONFIG", &ZeroConfig},
+}
+
 func init() {
 	readDotEnv()
-	GentleForce = parseEnv("GENTLE_FORCE")
-	RemoteTaskfiles = parseEnv("REMOTE_TASKFILES")
+	for _, x := range all {
+		*x.value = parseEnv(x.name)
+	}
 }
 
 func parseEnv(xName string) bool {
@@ -47,9 +62,22 @@ func printExperiment(w io.Writer, l *logger.Logger, name string, value bool) {
 	l.FOutf(w, logger.Default, ": \t%t\n", value)
 }
 
+// Enabled returns the names of the enabled experiments.
+func Enabled() []string {
+	var enabled []string
+	for _, x := range all {
+		if *x.value {
+			enabled = append(enabled, x.name)
+		}
+	}
+	return enabled
+}
+
+// List prints all the experiments and whether they are enabled.
 func List(l *logger.Logger) error {
-	w := tabwriter.NewWriter(os.Stdout, 0, 8, 0, ' ', 0)
-	printExperiment(w, l, "GENTLE_FORCE", GentleForce)
-	printExperiment(w, l, "REMOTE_TASKFILES", RemoteTaskfiles)
+	w := tabwriter.NewWriter(l.Stdout, 0, 8, 0, ' ', 0)
+	for _, x := range all {
+		printExperiment(w, l, x.name, *x.value)
+	}
 	return w.Flush()
 }
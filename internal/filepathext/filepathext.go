@@ -3,18 +3,71 @@ package filepathext
 import (
 	"os"
 	"path/filepath"
+	"runtime"
 	"strings"
 )
 
+// caseInsensitive is true where the file systems ignore the case of the
+// names. Only Windows is assumed to: on macOS, APFS can be case-sensitive, and
+// folding the case there would take different files for the same one.
+var caseInsensitive = runtime.GOOS == "windows"
+
 // SmartJoin joins two paths, but only if the second is not already an
 // absolute path.
 func SmartJoin(a, b string) string {
+	return smartJoin(a, b, runtime.GOOS == "windows")
+}
+
+func smartJoin(a, b string, windows bool) string {
+	if windows && !isSpecialDir(b) {
+		switch {
+		case volumeName(b) != "":
+			// "C:\foo" is absolute, and "C:foo" is relative to the current
+			// directory of that drive, which only the OS knows
+			return b
+		case isRooted(b, `\`) || isRooted(b, "/"):
+			// "\foo" and "/foo" are on the drive of the first path
+			return filepath.Join(volumeName(a), b)
+		}
+	}
 	if IsAbs(b) {
 		return b
 	}
 	return filepath.Join(a, b)
 }
 
+// isRooted tells whether a Windows path starts at the root of the current
+// drive. Paths starting with two separators are network shares instead.
+func isRooted(path, separator string) bool {
+	return strings.HasPrefix(path, separator) && !strings.HasPrefix(path, separator+separator)
+}
+
+// volumeName returns the drive letter of a Windows path, like "C:". Unlike
+// filepath.VolumeName, it works the same on every OS.
+func volumeName(path string) string {
+	if len(path) >= 2 && path[1] == ':' {
+		if c := path[0]; ('a' <= c && c <= 'z') || ('A' <= c && c <= 'Z') {
+			return path[:2]
+		}
+	}
+	return ""
+}
+
+// Key returns a key that is the same for all the ways to write the same path:
+// cleaned, with forward slashes and, where the file systems ignore case, in
+// lower case. Use it to compare or deduplicate paths.
+func Key(path string) string {
+	return key(path, caseInsensitive)
+}
+
+func key(path string, caseInsensitive bool) string {
+	path = filepath.ToSlash(filepath.Clean(path))
+	if caseInsensitive {
+		path = strings.ToLower(path)
+	}
+	return path
+}
+
 func IsAbs(path string) bool {
 	// NOTE(@andreynering): If the path contains any if the special
 	// variables that we know are absolute, return true.
@@ -0,0 +1,34 @@
+package filepathext
+
+import (
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSmartJoinWindows(t *testing.T) {
+	tests := []struct {
+		a, b, want string
+	}{
+		{"/project", "src", filepath.Join("/project", "src")},
+		{"C:/project", "src", filepath.Join("C:/project", "src")},
+		{"C:/project", "D:src", "D:src"},
+		{"C:/project", "/src", filepath.Join("C:", "/src")},
+		{"C:/project", "{{.TASKFILE_DIR}}/src", "{{.TASKFILE_DIR}}/src"},
+	}
+	for _, test := range tests {
+		assert.Equal(t, test.want, smartJoin(test.a, test.b, true), "%s + %s", test.a, test.b)
+	}
+	// Only Windows has drives
+	assert.Equal(t, filepath.Join("/project", "D:src"), smartJoin("/project", "D:src", false))
+}
+
+func TestKey(t *testing.T) {
+	assert.Equal(t, "src/main.go", key(filepath.FromSlash("./src/../src/main.go"), false))
+	assert.Equal(t, "src/Main.go", key("src/Main.go", false))
+	assert.Equal(t, "src/main.go", key("SRC/Main.go", true))
+	// The file systems of macOS can be case-sensitive
+	assert.Equal(t, runtime.GOOS == "windows", caseInsensitive)
+}
@@ -18,3 +18,15 @@ type SourcesCheckable interface {
 	OnError(t *taskfile.Task) error
 	Kind() string
 }
+
+// StatusExplainable defines any type that can tell why the status of a task is
+// not up-to-date.
+type StatusExplainable interface {
+	Explain(ctx context.Context, t *taskfile.Task) ([]StaleReason, error)
+}
+
+// SourcesExplainable defines any type that can tell why the sources of a task
+// are not up-to-date.
+type SourcesExplainable interface {
+	Explain(t *taskfile.Task) ([]StaleReason, error)
+}
@@ -0,0 +1,116 @@
+package fingerprint
+
+import (
+	"context"
+	"path/filepath"
+
+	"github.com/nuvolaris/task/v3/taskfile"
+)
+
+// Kinds of StaleReason.
+const (
+	// StaleAlways means the task always runs, because it has no sources or
+	// status or its method is "none".
+	StaleAlways = "always"
+	// StaleNeverRun means there's no record of a previous run of the task.
+	StaleNeverRun = "never_run"
+	// StaleSourcesChanged means some sources changed since the last run.
+	StaleSourcesChanged = "sources_changed"
+	// StaleGeneratesMissing means some generated files don't exist.
+	StaleGeneratesMissing = "generates_missing"
+	// StaleGeneratesChanged means the generated files changed since the last
+	// run, with the "checksum" generates method.
+	StaleGeneratesChanged = "generates_changed"
+	// StaleStatusFailed means a status command exited non-zero.
+	StaleStatusFailed = "status_failed"
+)
+
+// StaleReason is a reason why a task is not up-to-date.
+type StaleReason struct {
+	Kind    string `json:"kind"`
+	Message string `json:"message"`
+	// Files are the changed sources or the missing generated files, relative
+	// to the directory of the task, when they are known.
+	Files []string `json:"files,omitempty"`
+	// Command is the status command that failed.
+	Command string `json:"command,omitempty"`
+}
+
+// ExplainTask returns the reasons why a task is not up-to-date, or none if it
+// is. Unlike IsTaskUpToDate, it never writes the state of the task.
+func ExplainTask(
+	ctx context.Context,
+	t *taskfile.Task,
+	opts ...CheckerOption,
+) ([]StaleReason, error) {
+	config, err := newCheckerConfig(opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	statusIsSet := len(t.Status) != 0
+	sourcesIsSet := len(t.Sources) != 0
+
+	if !statusIsSet && !sourcesIsSet {
+		return []StaleReason{{
+			Kind:    StaleAlways,
+			Message: "the task has no sources or status, so it always runs",
+		}}, nil
+	}
+
+	var reasons []StaleReason
+	if statusIsSet {
+		r, err := explainStatus(ctx, config.statusChecker, t)
+		if err != nil {
+			return nil, err
+		}
+		reasons = append(reasons, r...)
+	}
+	if sourcesIsSet {
+		r, err := explainSources(config.sourcesChecker, t)
+		if err != nil {
+			return nil, err
+		}
+		reasons = append(reasons, r...)
+		r, err = explainGenerates(t, config.tempDir)
+		if err != nil {
+			return nil, err
+		}
+		reasons = append(reasons, r...)
+	}
+	return reasons, nil
+}
+
+func explainStatus(ctx context.Context, checker StatusCheckable, t *taskfile.Task) ([]StaleReason, error) {
+	if explainer, ok := checker.(StatusExplainable); ok {
+		return explainer.Explain(ctx, t)
+	}
+	isUpToDate, err := checker.IsUpToDate(ctx, t)
+	if err != nil || isUpToDate {
+		return nil, err
+	}
+	return []StaleReason{{Kind: StaleStatusFailed, Message: "the status is not up-to-date"}}, nil
+}
+
+func explainSources(checker SourcesCheckable, t *taskfile.Task) ([]StaleReason, error) {
+	if explainer, ok := checker.(SourcesExplainable); ok {
+		return explainer.Explain(t)
+	}
+	isUpToDate, err := checker.IsUpToDate(t)
+	if err != nil || isUpToDate {
+		return nil, err
+	}
+	return []StaleReason{{Kind: StaleSourcesChanged, Message: "the sources are not up-to-date"}}, nil
+}
+
+// relativeTo makes the given paths relative to dir, when possible.
+func relativeTo(dir string, paths []string) []string {
+	rel := make([]string, len(paths))
+	for i, p := range paths {
+		if r, err := filepath.Rel(dir, p); err == nil {
+			p = filepath.ToSlash(r)
+		}
+		rel[i] = p
+	}
+	return rel
+}
@@ -0,0 +1,117 @@
+package fingerprint
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/nuvolaris/task/v3/internal/filepathext"
+	"github.com/nuvolaris/task/v3/taskfile"
+)
+
+// Generates methods. With "exists", the default, the generated files only
+// have to exist. With "checksum", their content must also be the same as
+// at the end of the last run, so editing them by hand runs the task again.
+const (
+	GeneratesMethodExists   = "exists"
+	GeneratesMethodChecksum = "checksum"
+)
+
+func checksumsGenerates(t *taskfile.Task) (bool, error) {
+	switch t.GeneratesMethod {
+	case "", GeneratesMethodExists:
+		return false, nil
+	case GeneratesMethodChecksum:
+		return len(t.Generates) > 0, nil
+	default:
+		return false, fmt.Errorf(`task: invalid generates method "%s"`, t.GeneratesMethod)
+	}
+}
+
+// isGeneratesUpToDate tells whether the generated files of a task are the
+// same as at the end of its last run.
+func isGeneratesUpToDate(t *taskfile.Task, tempDir string) (bool, error) {
+	ok, err := checksumsGenerates(t)
+	if err != nil || !ok {
+		return true, err
+	}
+	data, err := os.ReadFile(generatesChecksumFilePath(t, tempDir))
+	if err != nil {
+		return false, nil
+	}
+	newHash, err := generatesChecksum(t)
+	if err != nil {
+		return false, nil
+	}
+	return strings.TrimSpace(string(data)) == newHash, nil
+}
+
+func explainGenerates(t *taskfile.Task, tempDir string) ([]StaleReason, error) {
+	ok, err := checksumsGenerates(t)
+	if err != nil || !ok {
+		return nil, err
+	}
+	data, _ := os.ReadFile(generatesChecksumFilePath(t, tempDir))
+	oldHash := strings.TrimSpace(string(data))
+	if oldHash == "" {
+		return []StaleReason{{
+			Kind:    StaleGeneratesChanged,
+			Message: "there's no checksum of the generated files",
+		}}, nil
+	}
+	newHash, err := generatesChecksum(t)
+	if err != nil {
+		return []StaleReason{{
+			Kind:    StaleGeneratesChanged,
+			Message: fmt.Sprintf("the generated files can't be read: %v", err),
+		}}, nil
+	}
+	if oldHash != newHash {
+		return []StaleReason{{
+			Kind:    StaleGeneratesChanged,
+			Message: "the generated files changed since the last run",
+		}}, nil
+	}
+	return nil, nil
+}
+
+// RecordGenerates writes the checksum of the generated files of a task that
+// just ran, if its generates method is "checksum".
+func RecordGenerates(t *taskfile.Task, tempDir string, dry bool) error {
+	ok, err := checksumsGenerates(t)
+	if err != nil || !ok || dry {
+		return err
+	}
+	hash, err := generatesChecksum(t)
+	if err != nil {
+		return err
+	}
+	_ = os.MkdirAll(filepathext.SmartJoin(tempDir, "checksum"), 0o755)
+	return os.WriteFile(generatesChecksumFilePath(t, tempDir), []byte(hash+"\n"), 0o644)
+}
+
+// ForgetGenerates removes the checksum of the generated files of a task, so
+// it runs again after failing.
+func ForgetGenerates(t *taskfile.Task, tempDir string) error {
+	err := os.Remove(generatesChecksumFilePath(t, tempDir))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	return err
+}
+
+func generatesChecksum(t *taskfile.Task) (string, error) {
+	generates, err := Globs(t.Dir, t.Generates)
+	if err != nil {
+		return "", err
+	}
+	hash, _, err := checksumFiles(generates, nil)
+	return hash, err
+}
+
+// generatesChecksumFilePath can't clash with the checksum file of another
+// task, because dots are replaced in the names of the tasks.
+func generatesChecksumFilePath(t *taskfile.Task, tempDir string) string {
+	return filepath.Join(tempDir, "checksum", stateName(t, t.Name())) + ".generates"
+}
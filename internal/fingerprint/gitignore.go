@@ -0,0 +1,199 @@
+package fingerprint
+
+import (
+	"bufio"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/nuvolaris/task/v3/internal/filepathext"
+	"github.com/nuvolaris/task/v3/taskfile"
+)
+
+// Sources returns the source files of the task. With "ignore_gitignored", the
+// ones ignored by Git are left out, like the build outputs or the vendored
+// dependencies matched by a broad glob.
+func Sources(t *taskfile.Task) ([]string, error) {
+	sources, err := Globs(t.Dir, t.Sources)
+	if err != nil || !t.IgnoreGitignored {
+		return sources, err
+	}
+	ignore := newGitignore(t.Dir)
+	kept := sources[:0]
+	for _, f := range sources {
+		if !ignore.ignored(f) {
+			kept = append(kept, f)
+		}
+	}
+	return kept, nil
+}
+
+// gitignore tells whether files are ignored by the .gitignore files of the
+// repository they're in, and by its .git/info/exclude.
+type gitignore struct {
+	// root is the top directory of the repository, or the directory of the
+	// task when it isn't in one
+	root string
+	// exclude are the rules of .git/info/exclude, which apply before the
+	// ones of the .gitignore files
+	exclude []gitignoreRule
+	// rules are the rules of each directory, read only when needed
+	rules map[string][]gitignoreRule
+}
+
+type gitignoreRule struct {
+	pattern *regexp.Regexp
+	negate  bool
+	dirOnly bool
+}
+
+func newGitignore(dir string) *gitignore {
+	g := &gitignore{root: dir, rules: map[string][]gitignoreRule{}}
+	for d := dir; ; d = filepath.Dir(d) {
+		if _, err := os.Stat(filepath.Join(d, ".git")); err == nil {
+			g.root = d
+			g.exclude = readGitignore(filepath.Join(d, ".git", "info", "exclude"))
+			break
+		}
+		if filepath.Dir(d) == d {
+			break
+		}
+	}
+	return g
+}
+
+// ignored tells whether the file is ignored. Like with Git, a file in an
+// ignored directory is ignored, even if a later rule would include it again.
+func (g *gitignore) ignored(file string) bool {
+	rel, err := filepath.Rel(g.root, file)
+	if err != nil || strings.HasPrefix(rel, "..") {
+		return false
+	}
+	parts := strings.Split(filepath.ToSlash(rel), "/")
+	for i := range parts {
+		if g.match(parts[:i+1], i < len(parts)-1) {
+			return true
+		}
+	}
+	return false
+}
+
+// match applies the rules of the directories above the path, from the top
+// one, so the last rule matching the path decides.
+func (g *gitignore) match(parts []string, isDir bool) bool {
+	ignored := false
+	for i := 0; i < len(parts); i++ {
+		dir := filepathext.SmartJoin(g.root, filepath.FromSlash(path.Join(parts[:i]...)))
+		rel := path.Join(parts[i:]...)
+		rules := g.dirRules(dir)
+		if i == 0 {
+			rules = append(g.exclude[:len(g.exclude):len(g.exclude)], rules...)
+		}
+		for _, rule := range rules {
+			if rule.dirOnly && !isDir {
+				continue
+			}
+			if rule.pattern.MatchString(rel) {
+				ignored = !rule.negate
+			}
+		}
+	}
+	return ignored
+}
+
+func (g *gitignore) dirRules(dir string) []gitignoreRule {
+	rules, ok := g.rules[dir]
+	if !ok {
+		rules = readGitignore(filepath.Join(dir, ".gitignore"))
+		g.rules[dir] = rules
+	}
+	return rules
+}
+
+func readGitignore(name string) []gitignoreRule {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+
+	var rules []gitignoreRule
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if rule, ok := parseGitignoreRule(scanner.Text()); ok {
+			rules = append(rules, rule)
+		}
+	}
+	return rules
+}
+
+func parseGitignoreRule(line string) (gitignoreRule, bool) {
+	line = strings.TrimRight(line, " \t\r")
+	if line == "" || strings.HasPrefix(line, "#") {
+		return gitignoreRule{}, false
+	}
+	var rule gitignoreRule
+	if strings.HasPrefix(line, "!") {
+		rule.negate = true
+		line = line[1:]
+	} else if strings.HasPrefix(line, `\`) {
+		line = line[1:]
+	}
+	if strings.HasSuffix(line, "/") {
+		rule.dirOnly = true
+		line = strings.TrimRight(line, "/")
+	}
+	// A pattern with a slash before its end is relative to the directory of
+	// the .gitignore, otherwise it matches at any depth
+	anchored := strings.Contains(line, "/")
+	line = strings.TrimPrefix(line, "/")
+	if line == "" {
+		return gitignoreRule{}, false
+	}
+
+	var b strings.Builder
+	b.WriteString("^")
+	if !anchored {
+		b.WriteString("(?:.*/)?")
+	}
+	for i := 0; i < len(line); i++ {
+		switch c := line[i]; {
+		case strings.HasPrefix(line[i:], "**/"):
+			b.WriteString("(?:.*/)?")
+			i += 2
+		case strings.HasPrefix(line[i:], "**") && i+2 == len(line):
+			b.WriteString(".*")
+			i++
+		case c == '*':
+			b.WriteString("[^/]*")
+		case c == '?':
+			b.WriteString("[^/]")
+		case c == '[':
+			end := strings.IndexByte(line[i+1:], ']')
+			if end < 0 {
+				b.WriteString(`\[`)
+				continue
+			}
+			class := line[i+1 : i+1+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			b.WriteString("[" + strings.ReplaceAll(class, `\`, `\\`) + "]")
+			i += end + 1
+		case c == '\\' && i+1 < len(line):
+			i++
+			b.WriteString(regexp.QuoteMeta(line[i : i+1]))
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	b.WriteString("$")
+	pattern, err := regexp.Compile(b.String())
+	if err != nil {
+		return gitignoreRule{}, false
+	}
+	rule.pattern = pattern
+	return rule, true
+}
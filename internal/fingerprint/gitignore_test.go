@@ -0,0 +1,42 @@
+package fingerprint
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nuvolaris/task/v3/taskfile"
+)
+
+func TestSourcesIgnoreGitignored(t *testing.T) {
+	repo := t.TempDir()
+	write := func(name, content string) {
+		path := filepath.Join(repo, filepath.FromSlash(name))
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+		require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+	}
+	write(".git/info/exclude", "*.local\n")
+	write(".gitignore", "# build outputs\n/dist/\n*.log\n!keep.log\nvendor/\n")
+	write("app/.gitignore", "gen_*.go\n!gen_keep.go\n")
+	for _, name := range []string{
+		"app/main.go", "app/gen_a.go", "app/gen_keep.go", "app/debug.log", "app/keep.log",
+		"app/settings.local", "app/vendor/lib.go", "app/dist/out.go", "dist/out.go",
+	} {
+		write(name, "")
+	}
+
+	task := &taskfile.Task{Dir: filepath.Join(repo, "app"), Sources: []string{"**/*"}}
+	sources := func() []string {
+		files, err := Sources(task)
+		require.NoError(t, err)
+		return relativeTo(task.Dir, files)
+	}
+
+	assert.Contains(t, sources(), "gen_a.go")
+	task.IgnoreGitignored = true
+	// "/dist/" is anchored to the top of the repository, so app/dist is kept
+	assert.Equal(t, []string{".gitignore", "dist/out.go", "gen_keep.go", "keep.log", "main.go"}, sources())
+}
@@ -2,7 +2,9 @@ package fingerprint
 
 import (
 	"os"
+	"path/filepath"
 	"sort"
+	"strings"
 
 	"github.com/mattn/go-zglob"
 
@@ -10,19 +12,45 @@ import (
 	"github.com/nuvolaris/task/v3/internal/filepathext"
 )
 
+// Globs returns the files matching the globs, without duplicates, even where
+// the same file can be written in different ways (like with a different case
+// on Windows). They're sorted the same on every OS.
+//
+// Globs starting with "!" exclude the files they match from the ones matched
+// by the previous globs, so later globs can include them again.
 func Globs(dir string, globs []string) ([]string, error) {
-	files := make([]string, 0)
+	matched := make(map[string]string)
 	for _, g := range globs {
-		f, err := Glob(dir, g)
+		exclude := IsExclusion(g)
+		f, err := Glob(dir, strings.TrimPrefix(g, "!"))
 		if err != nil {
 			continue
 		}
-		files = append(files, f...)
+		for _, file := range f {
+			key := filepathext.Key(file)
+			if exclude {
+				delete(matched, key)
+			} else if _, ok := matched[key]; !ok {
+				matched[key] = file
+			}
+		}
+	}
+
+	files := make([]string, 0, len(matched))
+	for _, file := range matched {
+		files = append(files, file)
 	}
-	sort.Strings(files)
+	sort.Slice(files, func(i, j int) bool {
+		return filepath.ToSlash(files[i]) < filepath.ToSlash(files[j])
+	})
 	return files, nil
 }
 
+// IsExclusion tells whether the glob excludes files, like "!**/*_test.go".
+func IsExclusion(g string) bool {
+	return strings.HasPrefix(g, "!")
+}
+
 func Glob(dir string, g string) ([]string, error) {
 	files := make([]string, 0)
 	g = filepathext.SmartJoin(dir, g)
@@ -38,6 +66,8 @@ func Glob(dir string, g string) ([]string, error) {
 	}
 
 	for _, f := range fs {
+		// The embedded shell expands the globs with forward slashes
+		f = filepath.Clean(f)
 		info, err := os.Stat(f)
 		if err != nil {
 			return nil, err
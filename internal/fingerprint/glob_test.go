@@ -0,0 +1,30 @@
+package fingerprint
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGlobs(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"main.go", "main_test.go", "util.go", "util_test.go", "sub/sub.go", "sub/sub_test.go"} {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+		require.NoError(t, os.WriteFile(path, nil, 0o644))
+	}
+	globs := func(globs ...string) []string {
+		files, err := Globs(dir, globs)
+		require.NoError(t, err)
+		return relativeTo(dir, files)
+	}
+
+	assert.Equal(t, []string{"main.go", "main_test.go", "util.go", "util_test.go"}, globs("*.go", "main.go"))
+	assert.Equal(t, []string{"main.go", "sub/sub.go", "util.go"}, globs("**/*.go", "!**/*_test.go"))
+	// The globs apply in order, so a file can be excluded and included again
+	assert.Equal(t, []string{"main.go", "util.go", "util_test.go"}, globs("*.go", "!*_test.go", "util_test.go"))
+	assert.Equal(t, []string{"util.go"}, globs("!main.go", "*.go", "!*_test.go", "!main.go"))
+}
@@ -6,9 +6,14 @@ import (
 	"os"
 	"path/filepath"
 	"regexp"
+	"runtime"
+	"sort"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/zeebo/xxh3"
+	"golang.org/x/sync/errgroup"
 
 	"github.com/nuvolaris/task/v3/internal/filepathext"
 	"github.com/nuvolaris/task/v3/taskfile"
@@ -38,7 +43,8 @@ func (checker *ChecksumChecker) IsUpToDate(t *taskfile.Task) (bool, error) {
 	data, _ := os.ReadFile(checksumFile)
 	oldHash := strings.TrimSpace(string(data))
 
-	newHash, err := checker.checksum(t)
+	previous := checker.readSourceChecksums(t)
+	newHash, sums, err := checker.checksums(t)
 	if err != nil {
 		return false, nil
 	}
@@ -49,10 +55,18 @@ func (checker *ChecksumChecker) IsUpToDate(t *taskfile.Task) (bool, error) {
 			return false, err
 		}
 	}
+	if !checker.dry && (oldHash != newHash || sourceChecksumsChanged(previous, sums)) {
+		if err = checker.writeSourceChecksums(t, sums); err != nil {
+			return false, err
+		}
+	}
 
 	if len(t.Generates) > 0 {
 		// For each specified 'generates' field, check whether the files actually exist
 		for _, g := range t.Generates {
+			if IsExclusion(g) {
+				continue
+			}
 			generates, err := Glob(t.Dir, g)
 			if os.IsNotExist(err) {
 				return false, nil
@@ -73,10 +87,68 @@ func (checker *ChecksumChecker) Value(t *taskfile.Task) (any, error) {
 	return checker.checksum(t)
 }
 
+// Explain implements the SourcesExplainable interface. The changed files are
+// found by comparing the checksum of each source with the ones of the last
+// run.
+func (checker *ChecksumChecker) Explain(t *taskfile.Task) ([]StaleReason, error) {
+	data, _ := os.ReadFile(checker.checksumFilePath(t))
+	oldHash := strings.TrimSpace(string(data))
+
+	var reasons []StaleReason
+	newHash, sums, err := checker.checksums(t)
+	switch {
+	case err != nil:
+		reasons = append(reasons, StaleReason{
+			Kind:    StaleSourcesChanged,
+			Message: fmt.Sprintf("the sources can't be read: %v", err),
+		})
+	case oldHash == "":
+		reasons = append(reasons, StaleReason{
+			Kind:    StaleNeverRun,
+			Message: "there's no checksum of the sources, so the task never ran",
+		})
+	case oldHash != newHash:
+		files := checker.changedSources(t, sums)
+		message := "the sources changed"
+		if len(files) > 0 {
+			message = fmt.Sprintf("%d source file(s) changed", len(files))
+		}
+		reasons = append(reasons, StaleReason{
+			Kind:    StaleSourcesChanged,
+			Message: message,
+			Files:   files,
+		})
+	}
+
+	var missing []string
+	for _, g := range t.Generates {
+		if IsExclusion(g) {
+			continue
+		}
+		generates, err := Glob(t.Dir, g)
+		if err != nil && !os.IsNotExist(err) {
+			return nil, err
+		}
+		if len(generates) == 0 {
+			missing = append(missing, g)
+		}
+	}
+	if len(missing) > 0 {
+		reasons = append(reasons, StaleReason{
+			Kind:    StaleGeneratesMissing,
+			Message: fmt.Sprintf("%d generated file(s) are missing", len(missing)),
+			Files:   missing,
+		})
+	}
+
+	return reasons, nil
+}
+
 func (checker *ChecksumChecker) OnError(t *taskfile.Task) error {
 	if len(t.Sources) == 0 {
 		return nil
 	}
+	_ = os.Remove(checker.sourceChecksumsFilePath(t))
 	return os.Remove(checker.checksumFilePath(t))
 }
 
@@ -85,34 +157,185 @@ func (*ChecksumChecker) Kind() string {
 }
 
 func (c *ChecksumChecker) checksum(t *taskfile.Task) (string, error) {
-	sources, err := Globs(t.Dir, t.Sources)
+	hash, _, err := c.checksums(t)
+	return hash, err
+}
+
+// checksums returns the checksum of all the sources, along with what's known
+// of each of them. The sources whose size and modification time didn't change
+// since the last run aren't read again.
+func (c *ChecksumChecker) checksums(t *taskfile.Task) (string, map[string]fileMeta, error) {
+	sources, err := Sources(t)
 	if err != nil {
-		return "", err
+		return "", nil, err
+	}
+	return checksumFiles(sources, c.readSourceChecksums(t))
+}
+
+// A fileMeta is the checksum of a file, with the size and the modification
+// time it had when it was computed. The modification time is zero when it
+// can't be trusted, so the file is always read again.
+type fileMeta struct {
+	sum     string
+	size    int64
+	modTime int64
+}
+
+// recentModTime is how old a modification time must be for a checksum to be
+// kept with it. A file changed again within the resolution of the filesystem
+// clock could keep the same size and modification time.
+const recentModTime = 2 * time.Second
+
+// checksumFiles returns the checksum of the names and the checksums of the
+// given files, along with what's known of each of them. The files are hashed
+// concurrently, unless the previous checksum of a file is still valid.
+func checksumFiles(files []string, previous map[string]fileMeta) (string, map[string]fileMeta, error) {
+	metas := make([]fileMeta, len(files))
+	g := new(errgroup.Group)
+	g.SetLimit(runtime.NumCPU())
+	for i, f := range files {
+		i, f := i, f
+		g.Go(func() error {
+			meta, err := checksumFile(f, previous[f])
+			metas[i] = meta
+			return err
+		})
+	}
+	if err := g.Wait(); err != nil {
+		return "", nil, err
 	}
 
 	h := xxh3.New()
-	buf := make([]byte, 128*1024)
-	for _, f := range sources {
+	sums := make(map[string]fileMeta, len(files))
+	for i, f := range files {
 		// also sum the filename, so checksum changes for renaming a file
-		if _, err := io.CopyBuffer(h, strings.NewReader(filepath.Base(f)), buf); err != nil {
-			return "", err
+		_, _ = h.WriteString(filepath.Base(f))
+		_, _ = h.WriteString(metas[i].sum)
+		sums[f] = metas[i]
+	}
+	return formatHash(h.Sum128()), sums, nil
+}
+
+func checksumFile(path string, previous fileMeta) (fileMeta, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return fileMeta{}, err
+	}
+	meta := fileMeta{size: info.Size(), modTime: info.ModTime().UnixNano()}
+	if previous.sum != "" && previous.modTime != 0 && previous.size == meta.size && previous.modTime == meta.modTime {
+		meta.sum = previous.sum
+		return meta, nil
+	}
+	if time.Since(info.ModTime()) < recentModTime {
+		meta.modTime = 0
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return fileMeta{}, err
+	}
+	defer file.Close()
+	fh := xxh3.New()
+	if _, err := io.Copy(fh, file); err != nil {
+		return fileMeta{}, err
+	}
+	meta.sum = formatHash(fh.Sum128())
+	return meta, nil
+}
+
+func formatHash(hash xxh3.Uint128) string {
+	return fmt.Sprintf("%x%x", hash.Hi, hash.Lo)
+}
+
+// writeSourceChecksums records what's known of each source, so Explain can
+// tell which ones changed and the next run doesn't read the unchanged ones.
+// Each line has the checksum, the size and the modification time of a source,
+// separated by colons, then its path relative to the directory of the task.
+func (checker *ChecksumChecker) writeSourceChecksums(t *taskfile.Task, sums map[string]fileMeta) error {
+	files := make([]string, 0, len(sums))
+	for f := range sums {
+		files = append(files, f)
+	}
+	sort.Strings(files)
+
+	var b strings.Builder
+	for _, f := range files {
+		meta := sums[f]
+		fmt.Fprintf(&b, "%s:%d:%d %s\n", meta.sum, meta.size, meta.modTime, relativeTo(t.Dir, []string{f})[0])
+	}
+	return os.WriteFile(checker.sourceChecksumsFilePath(t), []byte(b.String()), 0o644)
+}
+
+// readSourceChecksums returns what was known of each source at the last run,
+// by their full path, or nothing if it's unknown. The files written by older
+// versions only have the checksums.
+func (checker *ChecksumChecker) readSourceChecksums(t *taskfile.Task) map[string]fileMeta {
+	data, err := os.ReadFile(checker.sourceChecksumsFilePath(t))
+	if err != nil {
+		return nil
+	}
+	previous := make(map[string]fileMeta)
+	for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+		fields, path, ok := strings.Cut(line, " ")
+		if !ok {
+			continue
 		}
-		f, err := os.Open(f)
-		if err != nil {
-			return "", err
+		var meta fileMeta
+		sum, rest, _ := strings.Cut(fields, ":")
+		meta.sum = sum
+		if size, modTime, ok := strings.Cut(rest, ":"); ok {
+			meta.size, _ = strconv.ParseInt(size, 10, 64)
+			meta.modTime, _ = strconv.ParseInt(modTime, 10, 64)
 		}
-		if _, err = io.CopyBuffer(h, f, buf); err != nil {
-			return "", err
+		previous[filepathext.SmartJoin(t.Dir, path)] = meta
+	}
+	return previous
+}
+
+// changedSources returns the sources that were added, removed or modified
+// since the last run, or nothing if the checksums of that run are unknown.
+func (checker *ChecksumChecker) changedSources(t *taskfile.Task, sums map[string]fileMeta) []string {
+	previous := checker.readSourceChecksums(t)
+	if previous == nil {
+		return nil
+	}
+
+	var changed []string
+	for f, meta := range sums {
+		if previous[f].sum != meta.sum {
+			changed = append(changed, relativeTo(t.Dir, []string{f})[0])
 		}
-		f.Close()
+		delete(previous, f)
 	}
+	for f := range previous {
+		changed = append(changed, relativeTo(t.Dir, []string{f})[0])
+	}
+	sort.Strings(changed)
+	return changed
+}
 
-	hash := h.Sum128()
-	return fmt.Sprintf("%x%x", hash.Hi, hash.Lo), nil
+// sourceChecksumsChanged tells whether what's known of the sources changed,
+// even if their checksums didn't, like when a file was only touched.
+func sourceChecksumsChanged(previous, sums map[string]fileMeta) bool {
+	if len(previous) != len(sums) {
+		return true
+	}
+	for f, meta := range sums {
+		if previous[f] != meta {
+			return true
+		}
+	}
+	return false
 }
 
 func (checker *ChecksumChecker) checksumFilePath(t *taskfile.Task) string {
-	return filepath.Join(checker.tempDir, "checksum", normalizeFilename(t.Name()))
+	return filepath.Join(checker.tempDir, "checksum", stateName(t, t.Name()))
+}
+
+// sourceChecksumsFilePath can't clash with the checksum file of another task,
+// because dots are replaced in the names of the tasks.
+func (checker *ChecksumChecker) sourceChecksumsFilePath(t *taskfile.Task) string {
+	return checker.checksumFilePath(t) + ".sources"
 }
 
 var checksumFilenameRegexp = regexp.MustCompile("[^A-z0-9]")
@@ -121,3 +344,13 @@ var checksumFilenameRegexp = regexp.MustCompile("[^A-z0-9]")
 func normalizeFilename(f string) string {
 	return checksumFilenameRegexp.ReplaceAllString(f, "-")
 }
+
+// stateName is the name of the files keeping the state of a task, which ends
+// with the hash of its variables when it uses some.
+func stateName(t *taskfile.Task, name string) string {
+	name = normalizeFilename(name)
+	if t.VarsHash != "" {
+		name += "-" + t.VarsHash
+	}
+	return name
+}
@@ -1,9 +1,16 @@
 package fingerprint
 
 import (
+	"fmt"
+	"os"
+	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nuvolaris/task/v3/taskfile"
 )
 
 func TestNormalizeFilename(t *testing.T) {
@@ -19,3 +26,81 @@ func TestNormalizeFilename(t *testing.T) {
 		assert.Equal(t, test.Out, normalizeFilename(test.In))
 	}
 }
+
+func TestChecksumCheckerExplain(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644))
+	}
+	write("a.txt", "a")
+	write("b.txt", "b")
+
+	task := &taskfile.Task{
+		Task:      "build",
+		Dir:       dir,
+		Sources:   []string{"*.txt"},
+		Generates: []string{"out.bin"},
+	}
+	checker := NewChecksumChecker(filepath.Join(dir, ".task"), false)
+
+	reasons, err := checker.Explain(task)
+	require.NoError(t, err)
+	assert.Equal(t, []StaleReason{
+		{Kind: StaleNeverRun, Message: "there's no checksum of the sources, so the task never ran"},
+		{Kind: StaleGeneratesMissing, Message: "1 generated file(s) are missing", Files: []string{"out.bin"}},
+	}, reasons)
+
+	_, err = checker.IsUpToDate(task)
+	require.NoError(t, err)
+	write("out.bin", "")
+	reasons, err = checker.Explain(task)
+	require.NoError(t, err)
+	assert.Empty(t, reasons)
+
+	write("b.txt", "changed")
+	write("c.txt", "c")
+	require.NoError(t, os.Remove(filepath.Join(dir, "a.txt")))
+	reasons, err = checker.Explain(task)
+	require.NoError(t, err)
+	assert.Equal(t, []StaleReason{
+		{Kind: StaleSourcesChanged, Message: "3 source file(s) changed", Files: []string{"a.txt", "b.txt", "c.txt"}},
+	}, reasons)
+}
+
+func TestChecksumFilesReusesUnchanged(t *testing.T) {
+	dir := t.TempDir()
+	var files []string
+	for i := 0; i < 50; i++ {
+		f := filepath.Join(dir, fmt.Sprintf("%02d.txt", i))
+		require.NoError(t, os.WriteFile(f, []byte(f), 0o644))
+		// Old enough for the modification time to be trusted
+		old := time.Now().Add(-time.Hour)
+		require.NoError(t, os.Chtimes(f, old, old))
+		files = append(files, f)
+	}
+
+	hash, sums, err := checksumFiles(files, nil)
+	require.NoError(t, err)
+	again, _, err := checksumFiles(files, nil)
+	require.NoError(t, err)
+	assert.Equal(t, hash, again, "hashing concurrently must not change the order")
+
+	// A checksum is kept only while the size and modification time match
+	previous := map[string]fileMeta{}
+	for f, meta := range sums {
+		assert.NotZero(t, meta.modTime)
+		meta.sum = "cached"
+		previous[f] = meta
+	}
+	previous[files[1]] = fileMeta{sum: "stale", size: sums[files[1]].size + 1, modTime: sums[files[1]].modTime}
+	_, cached, err := checksumFiles(files, previous)
+	require.NoError(t, err)
+	assert.Equal(t, "cached", cached[files[0]].sum)
+	assert.Equal(t, sums[files[1]].sum, cached[files[1]].sum)
+
+	// A file just modified is always read again
+	require.NoError(t, os.WriteFile(files[2], []byte("changed"), 0o644))
+	_, fresh, err := checksumFiles(files, nil)
+	require.NoError(t, err)
+	assert.Zero(t, fresh[files[2]].modTime)
+}
@@ -21,3 +21,7 @@ func (NoneChecker) OnError(t *taskfile.Task) error {
 func (NoneChecker) Kind() string {
 	return "none"
 }
+
+func (NoneChecker) Explain(t *taskfile.Task) ([]StaleReason, error) {
+	return []StaleReason{{Kind: StaleAlways, Message: `the method is "none", so the task always runs`}}, nil
+}
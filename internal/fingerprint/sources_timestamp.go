@@ -1,6 +1,7 @@
 package fingerprint
 
 import (
+	"fmt"
 	"os"
 	"path/filepath"
 	"time"
@@ -28,7 +29,7 @@ func (checker *TimestampChecker) IsUpToDate(t *taskfile.Task) (bool, error) {
 		return false, nil
 	}
 
-	sources, err := Globs(t.Dir, t.Sources)
+	sources, err := Sources(t)
 	if err != nil {
 		return false, nil
 	}
@@ -84,13 +85,55 @@ func (checker *TimestampChecker) IsUpToDate(t *taskfile.Task) (bool, error) {
 	return !shouldUpdate, nil
 }
 
+// Explain implements the SourcesExplainable interface.
+func (checker *TimestampChecker) Explain(t *taskfile.Task) ([]StaleReason, error) {
+	sources, err := Sources(t)
+	if err != nil {
+		return []StaleReason{{Kind: StaleSourcesChanged, Message: fmt.Sprintf("the sources can't be read: %v", err)}}, nil
+	}
+	generates, err := Globs(t.Dir, t.Generates)
+	if err != nil {
+		return []StaleReason{{Kind: StaleGeneratesMissing, Message: fmt.Sprintf("the generated files can't be read: %v", err)}}, nil
+	}
+	if _, err := os.Stat(checker.timestampFilePath(t)); err == nil {
+		generates = append(generates, checker.timestampFilePath(t))
+	}
+
+	generateMaxTime, err := getMaxTime(generates...)
+	if err != nil {
+		return []StaleReason{{Kind: StaleGeneratesMissing, Message: fmt.Sprintf("the generated files can't be read: %v", err)}}, nil
+	}
+	if generateMaxTime.IsZero() {
+		return []StaleReason{{Kind: StaleNeverRun, Message: "there are no generated files or timestamp, so the task never ran"}}, nil
+	}
+
+	var newer []string
+	for _, f := range sources {
+		info, err := os.Stat(f)
+		if err != nil {
+			return []StaleReason{{Kind: StaleSourcesChanged, Message: fmt.Sprintf("the sources can't be read: %v", err)}}, nil
+		}
+		if info.ModTime().After(generateMaxTime) {
+			newer = append(newer, f)
+		}
+	}
+	if len(newer) > 0 {
+		return []StaleReason{{
+			Kind:    StaleSourcesChanged,
+			Message: fmt.Sprintf("%d source file(s) are newer than the generated files", len(newer)),
+			Files:   relativeTo(t.Dir, newer),
+		}}, nil
+	}
+	return nil, nil
+}
+
 func (checker *TimestampChecker) Kind() string {
 	return "timestamp"
 }
 
 // Value implements the Checker Interface
 func (checker *TimestampChecker) Value(t *taskfile.Task) (any, error) {
-	sources, err := Globs(t.Dir, t.Sources)
+	sources, err := Sources(t)
 	if err != nil {
 		return time.Now(), err
 	}
@@ -147,5 +190,5 @@ func (*TimestampChecker) OnError(t *taskfile.Task) error {
 }
 
 func (checker *TimestampChecker) timestampFilePath(t *taskfile.Task) string {
-	return filepath.Join(checker.tempDir, "timestamp", normalizeFilename(t.Task))
+	return filepath.Join(checker.tempDir, "timestamp", stateName(t, t.Task))
 }
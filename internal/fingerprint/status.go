@@ -2,6 +2,7 @@ package fingerprint
 
 import (
 	"context"
+	"fmt"
 
 	"github.com/nuvolaris/task/v3/internal/env"
 	"github.com/nuvolaris/task/v3/internal/execext"
@@ -20,17 +21,29 @@ func NewStatusChecker(logger *logger.Logger) StatusCheckable {
 }
 
 func (checker *StatusChecker) IsUpToDate(ctx context.Context, t *taskfile.Task) (bool, error) {
+	reasons, err := checker.Explain(ctx, t)
+	return len(reasons) == 0, err
+}
+
+// Explain implements the StatusExplainable interface. It stops at the first
+// status command that fails.
+func (checker *StatusChecker) Explain(ctx context.Context, t *taskfile.Task) ([]StaleReason, error) {
 	for _, s := range t.Status {
 		err := execext.RunCommand(ctx, &execext.RunCommandOptions{
-			Command: s,
-			Dir:     t.Dir,
-			Env:     env.Get(t),
+			Command:  s,
+			Dir:      t.Dir,
+			Env:      env.Get(t),
+			Builtins: t.Builtins,
 		})
 		if err != nil {
 			checker.logger.VerboseOutf(logger.Yellow, "task: status command %s exited non-zero: %s\n", s, err)
-			return false, nil
+			return []StaleReason{{
+				Kind:    StaleStatusFailed,
+				Message: fmt.Sprintf("the status command %q exited non-zero: %s", s, err),
+				Command: s,
+			}}, nil
 		}
 		checker.logger.VerboseOutf(logger.Yellow, "task: status command %s exited zero\n", s)
 	}
-	return true, nil
+	return nil, nil
 }
@@ -2,6 +2,9 @@ package fingerprint
 
 import (
 	"context"
+	"os"
+	"path/filepath"
+	"regexp"
 
 	"github.com/nuvolaris/task/v3/internal/logger"
 	"github.com/nuvolaris/task/v3/taskfile"
@@ -55,15 +58,56 @@ func WithSourcesChecker(checker SourcesCheckable) CheckerOption {
 	}
 }
 
+// IsTaskUpToDate tells whether a task can be skipped. A task with neither
+// status nor sources always runs. Otherwise, every check it sets must say it
+// is up-to-date: all of its status commands must exit zero, and its sources,
+// with their generates, must be unchanged. So a failing status command makes
+// the task run even when the checksum of its sources matches.
 func IsTaskUpToDate(
 	ctx context.Context,
 	t *taskfile.Task,
 	opts ...CheckerOption,
 ) (bool, error) {
-	var statusUpToDate bool
-	var sourcesUpToDate bool
-	var err error
+	config, err := newCheckerConfig(opts...)
+	if err != nil {
+		return false, err
+	}
+
+	statusIsSet := len(t.Status) != 0
+	sourcesIsSet := len(t.Sources) != 0
+	if !statusIsSet && !sourcesIsSet {
+		return false, nil
+	}
+
+	upToDate := true
+	if statusIsSet {
+		statusUpToDate, err := config.statusChecker.IsUpToDate(ctx, t)
+		if err != nil {
+			return false, err
+		}
+		upToDate = statusUpToDate
+	}
 
+	// The sources are checked even when the status already failed, since the
+	// sources checker records the fingerprint they have for the coming run
+	if sourcesIsSet {
+		sourcesUpToDate, err := config.sourcesChecker.IsUpToDate(t)
+		if err != nil {
+			return false, err
+		}
+		if sourcesUpToDate {
+			sourcesUpToDate, err = isGeneratesUpToDate(t, config.tempDir)
+			if err != nil {
+				return false, err
+			}
+		}
+		upToDate = upToDate && sourcesUpToDate
+	}
+
+	return upToDate, nil
+}
+
+func newCheckerConfig(opts ...CheckerOption) (*CheckerConfig, error) {
 	// Default config
 	config := &CheckerConfig{
 		method:         "none",
@@ -86,47 +130,41 @@ func IsTaskUpToDate(
 
 	// If no sources checker was given, set up the default one
 	if config.sourcesChecker == nil {
+		var err error
 		config.sourcesChecker, err = NewSourcesChecker(config.method, config.tempDir, config.dry)
 		if err != nil {
-			return false, err
+			return nil, err
 		}
 	}
 
-	statusIsSet := len(t.Status) != 0
-	sourcesIsSet := len(t.Sources) != 0
+	return config, nil
+}
 
-	// If status is set, check if it is up-to-date
-	if statusIsSet {
-		statusUpToDate, err = config.statusChecker.IsUpToDate(ctx, t)
-		if err != nil {
-			return false, err
+// StateFiles returns the paths of the files where the state of a task, like
+// the checksum of its sources, is kept between runs. They are the ones of
+// every value of its variables, not only the current one, from the runs with
+// each of them.
+func StateFiles(t *taskfile.Task, tempDir string) ([]string, error) {
+	var files []string
+	for _, dir := range []struct{ name, task string }{
+		{"checksum", t.Name()},
+		{"timestamp", t.Task},
+	} {
+		entries, err := os.ReadDir(filepath.Join(tempDir, dir.name))
+		if os.IsNotExist(err) {
+			continue
 		}
-	}
-
-	// If sources is set, check if they are up-to-date
-	if sourcesIsSet {
-		sourcesUpToDate, err = config.sourcesChecker.IsUpToDate(t)
 		if err != nil {
-			return false, err
+			return nil, err
+		}
+		// The files are named by stateName, with the hash of the variables
+		// when the task uses some, and the suffix of the kind of state
+		re := regexp.MustCompile("^" + regexp.QuoteMeta(normalizeFilename(dir.task)) + `(-[0-9a-f]{16})?(\.sources|\.generates)?$`)
+		for _, entry := range entries {
+			if !entry.IsDir() && re.MatchString(entry.Name()) {
+				files = append(files, filepath.Join(tempDir, dir.name, entry.Name()))
+			}
 		}
 	}
-
-	// If both status and sources are set, the task is up-to-date if both are up-to-date
-	if statusIsSet && sourcesIsSet {
-		return statusUpToDate && sourcesUpToDate, nil
-	}
-
-	// If only status is set, the task is up-to-date if the status is up-to-date
-	if statusIsSet {
-		return statusUpToDate, nil
-	}
-
-	// If only sources is set, the task is up-to-date if the sources are up-to-date
-	if sourcesIsSet {
-		return sourcesUpToDate, nil
-	}
-
-	// If no status or sources are set, the task should always run
-	// i.e. it is never considered "up-to-date"
-	return false, nil
+	return files, nil
 }
@@ -0,0 +1,121 @@
+// Package flagenv sets the flags of the command line from environment
+// variables, so they can be given once for every invocation of Task, like in
+// CI pipelines.
+package flagenv
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/pflag"
+)
+
+// Prefix is the prefix of the environment variables of the flags.
+const Prefix = "TASK_"
+
+// Allowed are the flags that change how Task runs the tasks, which are the
+// only ones that can be set from the environment.
+var Allowed = map[string]bool{
+	"concurrency":             true,
+	"color":                   true,
+	"dir":                     true,
+	"download":                true,
+	"exit-code":               true,
+	"fail-fast":               true,
+	"format":                  true,
+	"hermetic":                true,
+	"insecure":                true,
+	"interval":                true,
+	"json":                    true,
+	"no-input":                true,
+	"offline":                 true,
+	"output":                  true,
+	"output-group-begin":      true,
+	"output-group-end":        true,
+	"output-group-error-only": true,
+	"output-group-nested":     true,
+	"output-timestamps":       true,
+	"parallel":                true,
+	"profile":                 true,
+	"profile-file":            true,
+	"report":                  true,
+	"serve-token":             true,
+	"silent":                  true,
+	"slash-paths":             true,
+	"sort":                    true,
+	"strict":                  true,
+	"strip-ansi":              true,
+	"taskfile":                true,
+	"timeout":                 true,
+	"verbose":                 true,
+	"watch-delta":             true,
+	"watch-listen":            true,
+	"watch-max-files":         true,
+	"watch-status":            true,
+	"yes":                     true,
+}
+
+// Ignored are the flags that make Task do something else than running the
+// given tasks, or change which ones run, which would break every invocation
+// if set by a leftover variable.
+var Ignored = map[string]bool{
+	"clean":           true,
+	"cleanup":         true,
+	"dirs":            true,
+	"dry":             true,
+	"experiments":     true,
+	"force":           true,
+	"force-all":       true,
+	"from":            true,
+	"gen-env-example": true,
+	"global":          true,
+	"graph":           true,
+	"help":            true,
+	"init":            true,
+	"lint":            true,
+	"list":            true,
+	"list-all":        true,
+	"list-templates":  true,
+	"list-vars":       true,
+	"lsp":             true,
+	"rename":          true,
+	"scheduler":       true,
+	"schema":          true,
+	"serve":           true,
+	"status":          true,
+	"stdio-protocol":  true,
+	"summary":         true,
+	"until":           true,
+	"update-includes": true,
+	"version":         true,
+	"watch":           true,
+	"which":           true,
+}
+
+// Name returns the environment variable of a flag, like TASK_OUTPUT_GROUP_BEGIN
+// for --output-group-begin.
+func Name(flag string) string {
+	return Prefix + strings.ToUpper(strings.ReplaceAll(flag, "-", "_"))
+}
+
+// Apply sets the Allowed flags that weren't given on the command line to the
+// value of their environment variable, if it's set and not empty. It must be called
+// after parsing the flags, so the ones given on the command line win.
+func Apply(fs *pflag.FlagSet) error {
+	var err error
+	fs.VisitAll(func(f *pflag.Flag) {
+		if err != nil || f.Changed || !Allowed[f.Name] {
+			return
+		}
+		name := Name(f.Name)
+		value := os.Getenv(name)
+		if value == "" {
+			return
+		}
+		if setErr := f.Value.Set(value); setErr != nil {
+			err = fmt.Errorf("task: Invalid value %q of %s for --%s: %w", value, name, f.Name, setErr)
+		}
+	})
+	return err
+}
@@ -0,0 +1,96 @@
+package flagenv_test
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/spf13/pflag"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nuvolaris/task/v3/internal/flagenv"
+)
+
+func TestApply(t *testing.T) {
+	t.Setenv("TASK_CONCURRENCY", "4")
+	t.Setenv("TASK_OUTPUT", "group")
+	t.Setenv("TASK_OUTPUT_GROUP_ERROR_ONLY", "true")
+	t.Setenv("TASK_INTERVAL", "2s")
+	t.Setenv("TASK_VERBOSE", "")
+	t.Setenv("TASK_VERSION", "1")
+	t.Setenv("TASK_LIST_ALL", "1")
+	t.Setenv("TASK_SUMMARY", "true")
+
+	fs := pflag.NewFlagSet("task", pflag.ContinueOnError)
+	concurrency := fs.Int("concurrency", 0, "")
+	output := fs.String("output", "", "")
+	errorOnly := fs.Bool("output-group-error-only", false, "")
+	interval := fs.Duration("interval", 0, "")
+	verbose := fs.Bool("verbose", false, "")
+	version := fs.Bool("version", false, "")
+	listAll := fs.Bool("list-all", false, "")
+	summary := fs.Bool("summary", false, "")
+	require.NoError(t, fs.Parse([]string{"--output", "prefixed"}))
+	require.NoError(t, flagenv.Apply(fs))
+
+	assert.Equal(t, 4, *concurrency)
+	// The flags given on the command line win
+	assert.Equal(t, "prefixed", *output)
+	assert.True(t, *errorOnly)
+	assert.Equal(t, 2*time.Second, *interval)
+	assert.False(t, *verbose)
+	assert.False(t, *version)
+	assert.False(t, *listAll)
+	assert.False(t, *summary)
+}
+
+// TestFlagsClassified fails when a flag of the CLI is neither Allowed nor
+// Ignored, so every new flag is given a thought.
+func TestFlagsClassified(t *testing.T) {
+	for _, file := range []string{"../../cmd/task/task.go", "../../cmd/taskmain/task.go"} {
+		f, err := parser.ParseFile(token.NewFileSet(), file, nil, 0)
+		require.NoError(t, err)
+
+		var names []string
+		ast.Inspect(f, func(n ast.Node) bool {
+			call, ok := n.(*ast.CallExpr)
+			if !ok || len(call.Args) < 2 {
+				return true
+			}
+			sel, ok := call.Fun.(*ast.SelectorExpr)
+			if !ok || !strings.HasSuffix(sel.Sel.Name, "Var") && !strings.HasSuffix(sel.Sel.Name, "VarP") {
+				return true
+			}
+			if x, ok := sel.X.(*ast.Ident); !ok || x.Name != "pflag" {
+				return true
+			}
+			if lit, ok := call.Args[1].(*ast.BasicLit); ok && lit.Kind == token.STRING {
+				name, err := strconv.Unquote(lit.Value)
+				require.NoError(t, err)
+				names = append(names, name)
+			}
+			return true
+		})
+		require.NotEmpty(t, names, file)
+
+		for _, name := range names {
+			assert.True(t, flagenv.Allowed[name] != flagenv.Ignored[name], "--%s of %s must be either Allowed or Ignored", name, file)
+		}
+	}
+}
+
+func TestApplyInvalid(t *testing.T) {
+	t.Setenv("TASK_CONCURRENCY", "many")
+
+	fs := pflag.NewFlagSet("task", pflag.ContinueOnError)
+	fs.Int("concurrency", 0, "")
+	require.NoError(t, fs.Parse(nil))
+	err := flagenv.Apply(fs)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `task: Invalid value "many" of TASK_CONCURRENCY for --concurrency`)
+}
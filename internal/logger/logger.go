@@ -2,6 +2,7 @@ package logger
 
 import (
 	"bufio"
+	"fmt"
 	"io"
 	"os"
 	"strconv"
@@ -11,44 +12,7 @@ import (
 	"golang.org/x/exp/slices"
 )
 
-type (
-	Color     func() PrintFunc
-	PrintFunc func(io.Writer, string, ...any)
-)
-
-func Default() PrintFunc {
-	return color.New(envColor("TASK_COLOR_RESET", color.Reset)).FprintfFunc()
-}
-
-func Blue() PrintFunc {
-	return color.New(envColor("TASK_COLOR_BLUE", color.FgBlue)).FprintfFunc()
-}
-
-func Green() PrintFunc {
-	return color.New(envColor("TASK_COLOR_GREEN", color.FgGreen)).FprintfFunc()
-}
-
-func Cyan() PrintFunc {
-	return color.New(envColor("TASK_COLOR_CYAN", color.FgCyan)).FprintfFunc()
-}
-
-func Yellow() PrintFunc {
-	return color.New(envColor("TASK_COLOR_YELLOW", color.FgYellow)).FprintfFunc()
-}
-
-func Magenta() PrintFunc {
-	return color.New(envColor("TASK_COLOR_MAGENTA", color.FgMagenta)).FprintfFunc()
-}
-
-func Red() PrintFunc {
-	return color.New(envColor("TASK_COLOR_RED", color.FgRed)).FprintfFunc()
-}
-
 func envColor(env string, defaultColor color.Attribute) color.Attribute {
-	if os.Getenv("FORCE_COLOR") != "" {
-		color.NoColor = false
-	}
-
 	override, err := strconv.Atoi(os.Getenv(env))
 	if err == nil {
 		return color.Attribute(override)
@@ -63,6 +27,8 @@ type Logger struct {
 	Stderr  io.Writer
 	Verbose bool
 	Color   bool
+	// Theme is the colors of the elements, DefaultTheme() if nil.
+	Theme Theme
 }
 
 // Outf prints stuff to STDOUT.
@@ -72,14 +38,7 @@ func (l *Logger) Outf(color Color, s string, args ...any) {
 
 // FOutf prints stuff to the given writer.
 func (l *Logger) FOutf(w io.Writer, color Color, s string, args ...any) {
-	if len(args) == 0 {
-		s, args = "%s", []any{s}
-	}
-	if !l.Color {
-		color = Default
-	}
-	print := color()
-	print(w, s, args...)
+	l.print(w, color, s, args...)
 }
 
 // VerboseOutf prints stuff to STDOUT if verbose mode is enabled.
@@ -91,14 +50,7 @@ func (l *Logger) VerboseOutf(color Color, s string, args ...any) {
 
 // Errf prints stuff to STDERR.
 func (l *Logger) Errf(color Color, s string, args ...any) {
-	if len(args) == 0 {
-		s, args = "%s", []any{s}
-	}
-	if !l.Color {
-		color = Default
-	}
-	print := color()
-	print(l.Stderr, s, args...)
+	l.print(l.Stderr, color, s, args...)
 }
 
 // VerboseErrf prints stuff to STDERR if verbose mode is enabled.
@@ -108,6 +60,21 @@ func (l *Logger) VerboseErrf(color Color, s string, args ...any) {
 	}
 }
 
+func (l *Logger) print(w io.Writer, c Color, s string, args ...any) {
+	if len(args) == 0 {
+		s, args = "%s", []any{s}
+	}
+	if !l.Color {
+		_, _ = fmt.Fprintf(w, s, args...)
+		return
+	}
+	theme := l.Theme
+	if theme == nil {
+		theme = DefaultTheme()
+	}
+	color.New(theme[c]...).Fprintf(w, s, args...)
+}
+
 func (l *Logger) Prompt(color Color, s string, defaultValue string, continueValues ...string) (bool, error) {
 	if len(continueValues) == 0 {
 		return false, nil
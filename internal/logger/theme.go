@@ -0,0 +1,185 @@
+package logger
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/fatih/color"
+)
+
+// A Color is an element of the output of Task, whose color is given by the
+// Theme of the Logger.
+type Color int
+
+// The elements of the output of Task.
+const (
+	// Text is the plain text.
+	Text Color = iota
+	// Command is the echo of a command before it runs.
+	Command
+	// TaskName is the name of a task in the lists and summaries.
+	TaskName
+	Success
+	// Info is for the details, like the verbose messages.
+	Info
+	// Highlight sets some parts apart, like the aliases in the lists.
+	Highlight
+	Warning
+	Error
+)
+
+// The elements named after their default color, as they're used across Task.
+const (
+	Default = Text
+	Green   = Success
+	Magenta = Info
+	Cyan    = Highlight
+	Blue    = Highlight
+	Yellow  = Warning
+	Red     = Error
+)
+
+// themeElements are the names of the elements in the "style" of a Taskfile.
+var themeElements = map[string]Color{
+	"text":      Text,
+	"command":   Command,
+	"task_name": TaskName,
+	"success":   Success,
+	"info":      Info,
+	"highlight": Highlight,
+	"warning":   Warning,
+	"error":     Error,
+}
+
+// Theme is the colors of the elements of the output.
+type Theme map[Color][]color.Attribute
+
+// DefaultTheme returns the usual colors of Task. The ones of each element can
+// be changed with the TASK_COLOR_* environment variables, named after the
+// default color of the element, like TASK_COLOR_RED for the errors.
+func DefaultTheme() Theme {
+	return Theme{
+		Text:      {envColor("TASK_COLOR_RESET", color.Reset)},
+		Command:   {envColor("TASK_COLOR_GREEN", color.FgGreen)},
+		TaskName:  {envColor("TASK_COLOR_GREEN", color.FgGreen)},
+		Success:   {envColor("TASK_COLOR_GREEN", color.FgGreen)},
+		Info:      {envColor("TASK_COLOR_MAGENTA", color.FgMagenta)},
+		Highlight: {envColor("TASK_COLOR_CYAN", color.FgCyan)},
+		Warning:   {envColor("TASK_COLOR_YELLOW", color.FgYellow)},
+		Error:     {envColor("TASK_COLOR_RED", color.FgRed)},
+	}
+}
+
+// WithStyle returns the theme with the colors of the given elements replaced,
+// like {"error": "bold red"}.
+func (t Theme) WithStyle(style map[string]string) (Theme, error) {
+	theme := make(Theme, len(t))
+	for c, attrs := range t {
+		theme[c] = attrs
+	}
+	for name, spec := range style {
+		c, ok := themeElements[name]
+		if !ok {
+			return nil, fmt.Errorf("task: unknown element %q in style", name)
+		}
+		attrs, err := ParseColor(spec)
+		if err != nil {
+			return nil, fmt.Errorf("task: invalid style of %s: %w", name, err)
+		}
+		theme[c] = attrs
+	}
+	return theme, nil
+}
+
+var colorNames = map[string]color.Attribute{
+	"black":   color.FgBlack,
+	"red":     color.FgRed,
+	"green":   color.FgGreen,
+	"yellow":  color.FgYellow,
+	"blue":    color.FgBlue,
+	"magenta": color.FgMagenta,
+	"cyan":    color.FgCyan,
+	"white":   color.FgWhite,
+}
+
+var colorModifiers = map[string]color.Attribute{
+	"reset":     color.Reset,
+	"bold":      color.Bold,
+	"faint":     color.Faint,
+	"italic":    color.Italic,
+	"underline": color.Underline,
+}
+
+// ParseColor parses words separated by spaces, like "bold red": the colors,
+// their "bright-" and "on-" (background) variants, the modifiers like "bold"
+// or "underline", and the raw SGR codes, like "38;5;208".
+func ParseColor(spec string) ([]color.Attribute, error) {
+	var attrs []color.Attribute
+	for _, word := range strings.Fields(spec) {
+		name := strings.ToLower(word)
+		var offset color.Attribute
+		if strings.HasPrefix(name, "on-") {
+			name, offset = strings.TrimPrefix(name, "on-"), color.BgBlack-color.FgBlack
+		}
+		bright := strings.HasPrefix(name, "bright-")
+		if bright {
+			name, offset = strings.TrimPrefix(name, "bright-"), offset+color.FgHiBlack-color.FgBlack
+		}
+		if attr, ok := colorNames[name]; ok {
+			attrs = append(attrs, attr+offset)
+			continue
+		}
+		if attr, ok := colorModifiers[name]; ok && offset == 0 {
+			attrs = append(attrs, attr)
+			continue
+		}
+		codes, ok := parseSGR(word)
+		if !ok {
+			return nil, fmt.Errorf("unknown color %q", word)
+		}
+		attrs = append(attrs, codes...)
+	}
+	return attrs, nil
+}
+
+func parseSGR(s string) ([]color.Attribute, bool) {
+	var attrs []color.Attribute
+	for _, part := range strings.Split(s, ";") {
+		n, err := strconv.Atoi(part)
+		if err != nil || n < 0 {
+			return nil, false
+		}
+		attrs = append(attrs, color.Attribute(n))
+	}
+	return attrs, true
+}
+
+// autoNoColor is whether colors are disabled by default, because the output
+// isn't a terminal or NO_COLOR is set.
+var autoNoColor = color.NoColor
+
+// UseColor tells whether the output is colored, given the --color flag, and
+// sets it for everything printed with github.com/fatih/color. Following
+// https://no-color.org and https://bixense.com/clicolors, NO_COLOR disables
+// the colors, CLICOLOR_FORCE (or FORCE_COLOR) enables them even when the
+// output isn't a terminal, and CLICOLOR=0 disables them otherwise.
+func UseColor(flag bool) bool {
+	switch {
+	case !flag || os.Getenv("NO_COLOR") != "":
+		color.NoColor = true
+	case isEnabled("CLICOLOR_FORCE") || os.Getenv("FORCE_COLOR") != "":
+		color.NoColor = false
+	case os.Getenv("CLICOLOR") == "0":
+		color.NoColor = true
+	default:
+		color.NoColor = autoNoColor
+	}
+	return !color.NoColor
+}
+
+func isEnabled(env string) bool {
+	v, ok := os.LookupEnv(env)
+	return ok && v != "0"
+}
@@ -0,0 +1,55 @@
+package logger_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/fatih/color"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nuvolaris/task/v3/internal/logger"
+)
+
+func TestParseColor(t *testing.T) {
+	attrs, err := logger.ParseColor("bold bright-red on-blue 38;5;208")
+	require.NoError(t, err)
+	assert.Equal(t, []color.Attribute{color.Bold, color.FgHiRed, color.BgBlue, 38, 5, 208}, attrs)
+
+	_, err = logger.ParseColor("on-bold")
+	assert.EqualError(t, err, `unknown color "on-bold"`)
+}
+
+func TestUseColor(t *testing.T) {
+	t.Cleanup(func() { logger.UseColor(true) })
+	t.Setenv("NO_COLOR", "")
+	t.Setenv("CLICOLOR", "0")
+
+	t.Setenv("CLICOLOR_FORCE", "1")
+	assert.True(t, logger.UseColor(true))
+	assert.False(t, logger.UseColor(false))
+
+	t.Setenv("CLICOLOR_FORCE", "0")
+	assert.False(t, logger.UseColor(true))
+
+	t.Setenv("NO_COLOR", "1")
+	t.Setenv("CLICOLOR_FORCE", "1")
+	assert.False(t, logger.UseColor(true))
+}
+
+func TestTheme(t *testing.T) {
+	t.Cleanup(func() { logger.UseColor(true) })
+	t.Setenv("NO_COLOR", "")
+	t.Setenv("CLICOLOR_FORCE", "1")
+
+	theme, err := logger.DefaultTheme().WithStyle(map[string]string{"error": "bold red"})
+	require.NoError(t, err)
+	var b bytes.Buffer
+	l := &logger.Logger{Stderr: &b, Color: logger.UseColor(true), Theme: theme}
+	l.Errf(logger.Red, "failed\n")
+	l.Errf(logger.Yellow, "careful\n")
+	assert.Equal(t, "\x1b[1;31mfailed\n\x1b[0m\x1b[33mcareful\n\x1b[0m", b.String())
+
+	_, err = logger.DefaultTheme().WithStyle(map[string]string{"errors": "red"})
+	assert.EqualError(t, err, `task: unknown element "errors" in style`)
+}
@@ -0,0 +1,399 @@
+package lsp
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/nuvolaris/task/v3/internal/templater"
+	"github.com/nuvolaris/task/v3/taskfile"
+	"github.com/nuvolaris/task/v3/taskfile/read"
+)
+
+// maxIncludeDepth limits how deep the includes are followed, in case they
+// include each other.
+const maxIncludeDepth = 16
+
+// specialVars are the variables Task sets for every task, see getSpecialVars
+// in the compiler.
+var specialVars = []string{
+	"TASK",
+	"ROOT_DIR",
+	"TASKFILE_DIR",
+	"USER_WORKING_DIR",
+	"TASK_VERSION",
+	"CLI_ARGS",
+	"CLI_ARGS_LIST",
+	"MATCH",
+	"CHECKSUM",
+	"TIMESTAMP",
+	"ITEM",
+}
+
+var (
+	errorLineRegex = regexp.MustCompile(`line (\d+)`)
+	varPrefixRegex = regexp.MustCompile(`\.(\w*)$`)
+	taskValueRegex = regexp.MustCompile(`^\s*(-\s*)?task:\s*[^\s{]*$`)
+	inlineDepRegex = regexp.MustCompile(`^\s*deps:\s*\[[^\]]*$`)
+	depItemRegex   = regexp.MustCompile(`^\s*-\s*[^\s:{]*$`)
+)
+
+// diagnostics returns the problems of a document: the errors Task would give
+// when reading it, the includes without a Taskfile, the calls of tasks that
+// don't exist and the variables that aren't defined anywhere.
+func (s *Server) diagnostics(d *document) []diagnostic {
+	diags := []diagnostic{}
+	if d.err != nil {
+		var line int
+		if m := errorLineRegex.FindStringSubmatch(d.err.Error()); m != nil {
+			line, _ = strconv.Atoi(m[1])
+			line--
+		}
+		diags = append(diags, diagnostic{
+			Range:    d.lineRange(line),
+			Severity: severityError,
+			Source:   "task",
+			Message:  d.err.Error(),
+		})
+	}
+
+	for _, inc := range d.includes {
+		path := d.includePath(inc)
+		if path == "" || inc.include.Optional || inc.include.If != "" {
+			continue
+		}
+		if _, err := read.Exists(path); err != nil {
+			diags = append(diags, diagnostic{
+				Range:    d.nodeRange(inc.taskfile),
+				Severity: severityError,
+				Source:   "task",
+				Message:  fmt.Sprintf("The included Taskfile %q doesn't exist", inc.taskfile.Value),
+			})
+		}
+	}
+
+	for _, t := range d.tasks {
+		for _, ref := range t.refs {
+			if !isPlainTaskName(ref.Value) {
+				continue
+			}
+			if _, target, certain := s.resolveTask(d, ref.Value, 0); target == nil && certain {
+				diags = append(diags, diagnostic{
+					Range:    d.nodeRange(ref),
+					Severity: severityError,
+					Source:   "task",
+					Message:  fmt.Sprintf("Task %q does not exist", ref.Value),
+				})
+			}
+		}
+		diags = append(diags, s.undefinedVars(d, t)...)
+	}
+	return diags
+}
+
+// undefinedVars warns about the variables used by the templates of a task
+// that aren't defined by the Taskfile, the task, its callers or the
+// environment. They could still be given on the command line, or by the
+// Taskfile including this one.
+func (s *Server) undefinedVars(d *document, t *taskNode) []diagnostic {
+	if t.task == nil || len(t.task.Dotenv) > 0 || len(d.taskfile.Dotenv) > 0 {
+		return nil
+	}
+	known := knownVars(d, t)
+	var diags []diagnostic
+	walkScalars(t.value, func(node *yaml.Node) {
+		if !strings.Contains(node.Value, "{{") {
+			return
+		}
+		tmpl, err := templater.Parse(node.Value)
+		if err != nil {
+			diags = append(diags, diagnostic{
+				Range:    d.nodeRange(node),
+				Severity: severityError,
+				Source:   "task",
+				Message:  fmt.Sprintf("Invalid template: %v", err),
+			})
+			return
+		}
+		for _, name := range templater.VarNames(tmpl) {
+			if _, ok := known[name]; ok {
+				continue
+			}
+			if _, ok := os.LookupEnv(name); ok {
+				continue
+			}
+			diags = append(diags, diagnostic{
+				Range:    d.varRange(node, name),
+				Severity: severityWarning,
+				Source:   "task",
+				Message:  fmt.Sprintf("Variable %q is not defined", name),
+			})
+		}
+	})
+	return diags
+}
+
+// knownVars returns the variables a task can use, other than the
+// environment, with what defines them.
+func knownVars(d *document, t *taskNode) map[string]string {
+	known := make(map[string]string)
+	add := func(detail string, names ...string) {
+		for _, name := range names {
+			if _, ok := known[name]; !ok {
+				known[name] = detail
+			}
+		}
+	}
+	add("Special variable", specialVars...)
+	if t != nil && t.task != nil {
+		add("Task variable", varNames(t.task.Vars)...)
+		add("Task environment variable", varNames(t.task.Env)...)
+		if t.task.Requires != nil {
+			add("Required variable", t.task.Requires.Vars...)
+		}
+		for _, arg := range t.task.Args {
+			add("Argument", arg.Name)
+		}
+		for _, cmd := range t.task.Cmds {
+			if cmd != nil && cmd.For != nil && cmd.For.As != "" {
+				add("Loop variable", cmd.For.As)
+			}
+		}
+		// A task has the vars of the one it extends, and a template the vars
+		// of the tasks extending it
+		if base := d.task(t.task.Extends); base != nil && base.task != nil {
+			add("Inherited variable", varNames(base.task.Vars)...)
+		}
+		for _, other := range d.tasks {
+			if other.task != nil && other.task.Extends == t.name {
+				add("Variable of an extending task", varNames(other.task.Vars)...)
+			}
+		}
+	}
+	if d.taskfile != nil {
+		add("Taskfile variable", varNames(d.taskfile.Vars)...)
+		add("Taskfile environment variable", varNames(d.taskfile.Env)...)
+	}
+	// The variables given by the calls of any task of the document
+	for _, other := range d.tasks {
+		if other.task == nil {
+			continue
+		}
+		for _, cmd := range other.task.Cmds {
+			if cmd != nil && cmd.Task != "" {
+				add("Call variable", varNames(cmd.Vars)...)
+			}
+		}
+		for _, dep := range other.task.Deps {
+			if dep != nil {
+				add("Call variable", varNames(dep.Vars)...)
+			}
+		}
+	}
+	return known
+}
+
+// walkScalars calls fn with every scalar value under the node.
+func walkScalars(node *yaml.Node, fn func(*yaml.Node)) {
+	switch node.Kind {
+	case yaml.ScalarNode:
+		fn(node)
+	case yaml.MappingNode:
+		for i := 1; i < len(node.Content); i += 2 {
+			walkScalars(node.Content[i], fn)
+		}
+	case yaml.SequenceNode:
+		for _, item := range node.Content {
+			walkScalars(item, fn)
+		}
+	}
+}
+
+// varRange returns the range of the first use of a variable in a scalar, or
+// the one of the scalar if it can't be found.
+func (d *document) varRange(node *yaml.Node, name string) textRange {
+	re := regexp.MustCompile(`\.` + regexp.QuoteMeta(name) + `\b`)
+	first := node.Line - 1
+	last := first + strings.Count(node.Value, "\n") + 1
+	for line := first; line <= last; line++ {
+		text := d.line(line)
+		loc := re.FindStringIndex(text)
+		if loc == nil {
+			continue
+		}
+		// The range is the name, without the dot
+		start := utf8.RuneCountInString(text[:loc[0]]) + 1
+		return textRange{
+			Start: d.position(line, start),
+			End:   d.position(line, start+utf8.RuneCountInString(name)),
+		}
+	}
+	return d.nodeRange(node)
+}
+
+// resolveTask returns the task with the given name, following the includes.
+// When it can't be found, certain tells whether that's because it doesn't
+// exist, and not because some Taskfile couldn't be read.
+func (s *Server) resolveTask(d *document, name string, depth int) (target *document, t *taskNode, certain bool) {
+	if depth > maxIncludeDepth {
+		return nil, nil, false
+	}
+	if t := d.task(name); t != nil {
+		return d, t, true
+	}
+	if d.taskfile != nil {
+		if wildcard, _ := d.taskfile.Tasks.FindWildcard(name); wildcard != nil {
+			return d, d.task(wildcard.Task), true
+		}
+	}
+	for _, inc := range d.includes {
+		for _, namespace := range append([]string{inc.namespace}, inc.include.Aliases...) {
+			rest, ok := strings.CutPrefix(name, namespace+":")
+			if !ok {
+				continue
+			}
+			included := s.loadInclude(d, inc)
+			if included == nil {
+				return nil, nil, false
+			}
+			return s.resolveTask(included, rest, depth+1)
+		}
+	}
+	return nil, nil, d.taskfile != nil
+}
+
+// loadInclude returns the document with the Taskfile of an include, or nil if
+// it can't be read.
+func (s *Server) loadInclude(d *document, inc *includeNode) *document {
+	path := d.includePath(inc)
+	if path == "" {
+		return nil
+	}
+	file, err := read.Exists(path)
+	if err != nil {
+		return nil
+	}
+	return s.load(file)
+}
+
+// definition returns where the task called at the given position is defined,
+// or where the included Taskfile is.
+func (s *Server) definition(d *document, pos position) *location {
+	for _, t := range d.tasks {
+		for _, ref := range t.refs {
+			if !d.contains(ref, pos) || !isPlainTaskName(ref.Value) {
+				continue
+			}
+			target, found, _ := s.resolveTask(d, ref.Value, 0)
+			if found == nil {
+				return nil
+			}
+			return &location{URI: target.uri, Range: target.nodeRange(found.key)}
+		}
+	}
+	for _, inc := range d.includes {
+		if inc.taskfile == nil || !d.contains(inc.taskfile, pos) {
+			continue
+		}
+		path := d.includePath(inc)
+		if path == "" {
+			return nil
+		}
+		file, err := read.Exists(path)
+		if err != nil {
+			return nil
+		}
+		return &location{URI: pathToURI(file)}
+	}
+	return nil
+}
+
+// completion returns the variables that can be used when the position is
+// in a template, or the tasks that can be called when it's in the value of
+// "task:" or in "deps".
+func (s *Server) completion(d *document, pos position) []completionItem {
+	items := []completionItem{}
+	before := string([]rune(d.line(pos.Line))[:d.column(pos)])
+
+	if open := strings.LastIndex(before, "{{"); open >= 0 && !strings.Contains(before[open:], "}}") {
+		if !varPrefixRegex.MatchString(before[open:]) {
+			return items
+		}
+		known := knownVars(d, d.taskAt(pos.Line))
+		names := make([]string, 0, len(known))
+		for name := range known {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			items = append(items, completionItem{Label: name, Kind: completionKindVariable, Detail: known[name]})
+		}
+		return items
+	}
+
+	if taskValueRegex.MatchString(before) || inlineDepRegex.MatchString(before) || d.inDeps(pos.Line, before) {
+		items = append(items, s.taskCompletions(d, "", 0)...)
+	}
+	return items
+}
+
+// inDeps tells whether the line is an item of a "deps" list.
+func (d *document) inDeps(line int, before string) bool {
+	if !depItemRegex.MatchString(before) {
+		return false
+	}
+	indent := len(before) - len(strings.TrimLeft(before, " "))
+	for i := line - 1; i >= 0; i-- {
+		text := d.line(i)
+		trimmed := strings.TrimLeft(text, " ")
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+		if len(text)-len(trimmed) < indent || !strings.HasPrefix(trimmed, "-") {
+			return strings.TrimSpace(trimmed) == "deps:"
+		}
+	}
+	return false
+}
+
+// taskCompletions returns the tasks of a document and of its includes, with
+// the given prefix.
+func (s *Server) taskCompletions(d *document, prefix string, depth int) []completionItem {
+	if depth > maxIncludeDepth {
+		return nil
+	}
+	var items []completionItem
+	for _, t := range d.tasks {
+		item := completionItem{Label: prefix + t.name, Kind: completionKindFunction}
+		if t.task != nil {
+			item.Detail = t.task.Desc
+		}
+		items = append(items, item)
+	}
+	for _, inc := range d.includes {
+		if included := s.loadInclude(d, inc); included != nil {
+			items = append(items, s.taskCompletions(included, prefix+inc.namespace+":", depth+1)...)
+		}
+	}
+	return items
+}
+
+// isPlainTaskName tells whether a task name can be resolved without running
+// Task: it's not templated and it's not a call to the root Taskfile from an
+// included one.
+func isPlainTaskName(name string) bool {
+	return name != "" && !strings.Contains(name, "{{") && !strings.HasPrefix(name, ":")
+}
+
+func varNames(vars *taskfile.Vars) []string {
+	if vars == nil {
+		return nil
+	}
+	return vars.Keys()
+}
@@ -0,0 +1,290 @@
+package lsp
+
+import (
+	"net/url"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+
+	"golang.org/x/exp/slices"
+	"gopkg.in/yaml.v3"
+
+	"github.com/nuvolaris/task/v3/taskfile"
+	"github.com/nuvolaris/task/v3/taskfile/read"
+)
+
+// A document is a Taskfile, parsed again each time it changes.
+type document struct {
+	uri   string
+	path  string
+	lines []string
+	// taskfile is nil when the document isn't a valid Taskfile, and err
+	// tells why
+	taskfile *taskfile.Taskfile
+	err      error
+	tasks    []*taskNode
+	includes []*includeNode
+}
+
+// A taskNode is a task of a document.
+type taskNode struct {
+	name  string
+	key   *yaml.Node
+	value *yaml.Node
+	task  *taskfile.Task
+	refs  []*yaml.Node
+}
+
+// An includeNode is an include of a document. The taskfile node is nil when
+// the include has no Taskfile, which is an error of the document.
+type includeNode struct {
+	namespace string
+	taskfile  *yaml.Node
+	include   taskfile.IncludedTaskfile
+}
+
+func parseDocument(uri, text string) *document {
+	d := &document{
+		uri:   uri,
+		path:  uriToPath(uri),
+		lines: strings.Split(text, "\n"),
+	}
+	var node yaml.Node
+	if err := yaml.Unmarshal([]byte(text), &node); err != nil {
+		d.err = err
+		return d
+	}
+	if len(node.Content) == 0 || node.Content[0].Kind != yaml.MappingNode {
+		return d
+	}
+	root := node.Content[0]
+
+	// The Taskfile is decoded like Task does when reading it, so the errors
+	// are the same
+	var tf taskfile.Taskfile
+	if err := node.Decode(&tf); err != nil {
+		d.err = err
+	} else {
+		d.taskfile = &tf
+	}
+
+	if tasks := mappingValue(root, "tasks"); tasks != nil && tasks.Kind == yaml.MappingNode {
+		for i := 0; i+1 < len(tasks.Content); i += 2 {
+			t := &taskNode{
+				name:  tasks.Content[i].Value,
+				key:   tasks.Content[i],
+				value: tasks.Content[i+1],
+			}
+			if d.taskfile != nil {
+				t.task = d.taskfile.Tasks.Get(t.name)
+			}
+			findTaskReferences(t.value, &t.refs)
+			d.tasks = append(d.tasks, t)
+		}
+	}
+
+	if includes := mappingValue(root, "includes"); includes != nil && includes.Kind == yaml.MappingNode {
+		for i := 0; i+1 < len(includes.Content); i += 2 {
+			inc := &includeNode{namespace: includes.Content[i].Value}
+			value := includes.Content[i+1]
+			if value.Kind == yaml.MappingNode {
+				value = mappingValue(value, "taskfile")
+			}
+			if value != nil && value.Kind == yaml.ScalarNode {
+				inc.taskfile = value
+			}
+			if d.taskfile != nil && d.taskfile.Includes != nil {
+				inc.include = d.taskfile.Includes.Mapping[inc.namespace]
+			}
+			d.includes = append(d.includes, inc)
+		}
+	}
+	return d
+}
+
+// findTaskReferences adds the scalars naming a task, like the value of
+// "task:" in the commands, the names in "deps" and the task in "extends", to
+// refs.
+func findTaskReferences(node *yaml.Node, refs *[]*yaml.Node) {
+	switch node.Kind {
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key, value := node.Content[i], node.Content[i+1]
+			switch key.Value {
+			case "vars", "env":
+				continue
+			case "task", "extends":
+				if value.Kind == yaml.ScalarNode {
+					*refs = append(*refs, value)
+				}
+			case "deps":
+				if value.Kind == yaml.SequenceNode {
+					for _, dep := range value.Content {
+						if dep.Kind == yaml.ScalarNode {
+							*refs = append(*refs, dep)
+						}
+					}
+				}
+			}
+			findTaskReferences(value, refs)
+		}
+	case yaml.SequenceNode:
+		for _, item := range node.Content {
+			findTaskReferences(item, refs)
+		}
+	}
+}
+
+// task returns the task with the given name or alias.
+func (d *document) task(name string) *taskNode {
+	for _, t := range d.tasks {
+		if t.name == name {
+			return t
+		}
+	}
+	for _, t := range d.tasks {
+		if t.task != nil && slices.Contains(t.task.Aliases, name) {
+			return t
+		}
+	}
+	return nil
+}
+
+// taskAt returns the task whose definition has the given line, if any.
+func (d *document) taskAt(line int) *taskNode {
+	var found *taskNode
+	for _, t := range d.tasks {
+		if t.key.Line-1 > line {
+			break
+		}
+		found = t
+	}
+	if found == nil {
+		return nil
+	}
+	// The line must be indented under the name of the task
+	if line > found.key.Line-1 && line < len(d.lines) {
+		text := d.lines[line]
+		trimmed := strings.TrimLeft(text, " \t")
+		if trimmed != "" && !strings.HasPrefix(trimmed, "#") && len(text)-len(trimmed) < found.key.Column {
+			return nil
+		}
+	}
+	return found
+}
+
+// includePath returns the path to the Taskfile of an include, resolved like
+// Task does, or an empty string if it isn't a local file known before running
+// Task, like the remote and the templated ones.
+func (d *document) includePath(inc *includeNode) string {
+	if inc.taskfile == nil || d.path == "" {
+		return ""
+	}
+	it := inc.include
+	it.Taskfile = inc.taskfile.Value
+	it.BaseDir = filepath.Dir(d.path)
+	if it.Taskfile == "" || strings.Contains(it.Taskfile, "{{") || strings.Contains(it.Taskfile, "://") || read.IsVersionedURI(it.Taskfile) || read.IsFetcherURI(it.Taskfile) {
+		return ""
+	}
+	path, err := it.FullTaskfilePath()
+	if err != nil {
+		return ""
+	}
+	return path
+}
+
+// nodeRange returns the range of a scalar node, or of its first line when it
+// spans many.
+func (d *document) nodeRange(node *yaml.Node) textRange {
+	line, column := node.Line-1, node.Column-1
+	length := utf8.RuneCountInString(node.Value)
+	switch {
+	case strings.Contains(node.Value, "\n") || node.Style&(yaml.LiteralStyle|yaml.FoldedStyle) != 0:
+		length = utf8.RuneCountInString(d.line(line)) - column
+	case node.Style&(yaml.DoubleQuotedStyle|yaml.SingleQuotedStyle) != 0:
+		length += 2
+	}
+	return textRange{
+		Start: d.position(line, column),
+		End:   d.position(line, column+length),
+	}
+}
+
+// lineRange returns the range of the whole line.
+func (d *document) lineRange(line int) textRange {
+	return textRange{
+		Start: position{Line: line},
+		End:   d.position(line, utf8.RuneCountInString(d.line(line))),
+	}
+}
+
+func (d *document) line(line int) string {
+	if line < 0 || line >= len(d.lines) {
+		return ""
+	}
+	return strings.TrimSuffix(d.lines[line], "\r")
+}
+
+// position converts a column counted in characters, like the ones of YAML,
+// to a position counted in UTF-16 code units.
+func (d *document) position(line, column int) position {
+	var character int
+	for i, r := range []rune(d.line(line)) {
+		if i >= column {
+			break
+		}
+		character += utf16.RuneLen(r)
+	}
+	return position{Line: line, Character: character}
+}
+
+// column converts a position to a column counted in characters.
+func (d *document) column(pos position) int {
+	var character, column int
+	for _, r := range d.line(pos.Line) {
+		if character >= pos.Character {
+			break
+		}
+		character += utf16.RuneLen(r)
+		column++
+	}
+	return column
+}
+
+// contains tells whether the range of the node has the position.
+func (d *document) contains(node *yaml.Node, pos position) bool {
+	r := d.nodeRange(node)
+	return pos.Line == r.Start.Line && pos.Character >= r.Start.Character && pos.Character <= r.End.Character
+}
+
+func mappingValue(node *yaml.Node, key string) *yaml.Node {
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i+1]
+		}
+	}
+	return nil
+}
+
+func uriToPath(uri string) string {
+	u, err := url.Parse(uri)
+	if err != nil || u.Scheme != "file" {
+		return ""
+	}
+	p := u.Path
+	// file:///C:/dir has the drive after the slash
+	if runtime.GOOS == "windows" {
+		p = strings.TrimPrefix(p, "/")
+	}
+	return filepath.FromSlash(p)
+}
+
+func pathToURI(path string) string {
+	p := filepath.ToSlash(path)
+	if !strings.HasPrefix(p, "/") {
+		p = "/" + p
+	}
+	return (&url.URL{Scheme: "file", Path: p}).String()
+}
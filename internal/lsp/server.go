@@ -0,0 +1,210 @@
+// Package lsp implements a language server for Taskfiles, used by editors
+// through the Language Server Protocol.
+package lsp
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/nuvolaris/task/v3/internal/jsonrpc"
+	"github.com/nuvolaris/task/v3/internal/version"
+)
+
+// A Server answers the requests of an editor. It keeps the open Taskfiles in
+// memory, and reads the included ones from disk when they aren't open.
+type Server struct {
+	r        *bufio.Reader
+	w        io.Writer
+	docs     map[string]*document
+	shutdown bool
+}
+
+// NewServer returns a Server that reads the messages of the editor from in
+// and writes its own to out. They're usually the standard input and output.
+func NewServer(in io.Reader, out io.Writer) *Server {
+	return &Server{
+		r:    bufio.NewReader(in),
+		w:    out,
+		docs: make(map[string]*document),
+	}
+}
+
+// Serve handles the messages until the editor asks the server to exit or
+// closes the input.
+func (s *Server) Serve() error {
+	for {
+		b, err := jsonrpc.ReadMessage(s.r)
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		var req jsonrpc.Request
+		if err := json.Unmarshal(b, &req); err != nil {
+			if err := s.replyError(nil, &jsonrpc.Error{Code: jsonrpc.CodeParseError, Message: err.Error()}); err != nil {
+				return err
+			}
+			continue
+		}
+		if req.Method == "exit" {
+			if !s.shutdown {
+				return fmt.Errorf("task: The editor asked the language server to exit before shutting it down")
+			}
+			return nil
+		}
+
+		result, err := s.handle(req)
+		// Notifications have no ID and never get a response
+		if req.ID == nil {
+			if _, ok := err.(*jsonrpc.Error); err != nil && !ok {
+				return err
+			}
+			continue
+		}
+		if respErr, ok := err.(*jsonrpc.Error); ok {
+			err = s.replyError(req.ID, respErr)
+		} else if err != nil {
+			return err
+		} else {
+			err = jsonrpc.WriteMessage(s.w, jsonrpc.Response{JSONRPC: "2.0", ID: req.ID, Result: result})
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func (s *Server) handle(req jsonrpc.Request) (any, error) {
+	switch req.Method {
+	case "initialize":
+		return map[string]any{
+			"capabilities": map[string]any{
+				// The editor sends the whole document on every change
+				"textDocumentSync":   1,
+				"definitionProvider": true,
+				"completionProvider": map[string]any{
+					"triggerCharacters": []string{".", ":"},
+				},
+			},
+			"serverInfo": map[string]any{
+				"name":    "task",
+				"version": version.GetVersion(),
+			},
+		}, nil
+
+	case "shutdown":
+		s.shutdown = true
+		return nil, nil
+
+	case "textDocument/didOpen":
+		var params didOpenParams
+		if err := jsonrpc.DecodeParams(req, &params); err != nil {
+			return nil, err
+		}
+		s.docs[params.TextDocument.URI] = parseDocument(params.TextDocument.URI, params.TextDocument.Text)
+		return nil, s.publishDiagnostics(params.TextDocument.URI)
+
+	case "textDocument/didChange":
+		var params didChangeParams
+		if err := jsonrpc.DecodeParams(req, &params); err != nil {
+			return nil, err
+		}
+		if len(params.ContentChanges) == 0 {
+			return nil, nil
+		}
+		text := params.ContentChanges[len(params.ContentChanges)-1].Text
+		s.docs[params.TextDocument.URI] = parseDocument(params.TextDocument.URI, text)
+		return nil, s.publishDiagnostics(params.TextDocument.URI)
+
+	case "textDocument/didSave":
+		// The included Taskfiles may have changed too
+		var params textDocumentParams
+		if err := jsonrpc.DecodeParams(req, &params); err != nil {
+			return nil, err
+		}
+		return nil, s.publishDiagnostics(params.TextDocument.URI)
+
+	case "textDocument/didClose":
+		var params textDocumentParams
+		if err := jsonrpc.DecodeParams(req, &params); err != nil {
+			return nil, err
+		}
+		delete(s.docs, params.TextDocument.URI)
+		return nil, jsonrpc.WriteMessage(s.w, jsonrpc.Notification{
+			JSONRPC: "2.0",
+			Method:  "textDocument/publishDiagnostics",
+			Params:  publishDiagnosticsParams{URI: params.TextDocument.URI, Diagnostics: []diagnostic{}},
+		})
+
+	case "textDocument/definition":
+		var params textDocumentPositionParams
+		if err := jsonrpc.DecodeParams(req, &params); err != nil {
+			return nil, err
+		}
+		d := s.docs[params.TextDocument.URI]
+		if d == nil {
+			return nil, nil
+		}
+		if loc := s.definition(d, params.Position); loc != nil {
+			return []location{*loc}, nil
+		}
+		return nil, nil
+
+	case "textDocument/completion":
+		var params textDocumentPositionParams
+		if err := jsonrpc.DecodeParams(req, &params); err != nil {
+			return nil, err
+		}
+		d := s.docs[params.TextDocument.URI]
+		if d == nil {
+			return []completionItem{}, nil
+		}
+		return s.completion(d, params.Position), nil
+
+	case "initialized", "$/cancelRequest", "$/setTrace":
+		return nil, nil
+	}
+
+	if req.ID == nil {
+		return nil, nil
+	}
+	return nil, &jsonrpc.Error{Code: jsonrpc.CodeMethodNotFound, Message: fmt.Sprintf("task: The %q method isn't supported", req.Method)}
+}
+
+func (s *Server) publishDiagnostics(uri string) error {
+	d := s.docs[uri]
+	if d == nil {
+		return nil
+	}
+	return jsonrpc.WriteMessage(s.w, jsonrpc.Notification{
+		JSONRPC: "2.0",
+		Method:  "textDocument/publishDiagnostics",
+		Params:  publishDiagnosticsParams{URI: uri, Diagnostics: s.diagnostics(d)},
+	})
+}
+
+func (s *Server) replyError(id json.RawMessage, respErr *jsonrpc.Error) error {
+	if id == nil {
+		id = json.RawMessage("null")
+	}
+	return jsonrpc.WriteMessage(s.w, jsonrpc.ErrorResponse{JSONRPC: "2.0", ID: id, Error: respErr})
+}
+
+// load returns the document of the Taskfile at the given path, which is the
+// open one if the editor has it.
+func (s *Server) load(path string) *document {
+	uri := pathToURI(path)
+	if d := s.docs[uri]; d != nil {
+		return d
+	}
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+	return parseDocument(uri, string(b))
+}
@@ -0,0 +1,215 @@
+package lsp_test
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nuvolaris/task/v3/internal/lsp"
+)
+
+const rootTaskfile = `version: '3'
+
+includes:
+  docs: ./docs
+  missing: ./missing
+
+vars:
+  NAME: World
+
+tasks:
+  default:
+    deps: [docs:build]
+    cmds:
+      - task: greet
+      - echo {{.NAME}} {{.UNKNOWN}}
+
+  greet:
+    cmds:
+      - task: nope
+`
+
+const docsTaskfile = `version: '3'
+
+tasks:
+  build:
+    desc: Builds the docs
+    cmds:
+      - echo docs
+`
+
+type message struct {
+	ID     int             `json:"id"`
+	Method string          `json:"method"`
+	Params json.RawMessage `json:"params"`
+	Result json.RawMessage `json:"result"`
+	Error  *struct {
+		Code int `json:"code"`
+	} `json:"error"`
+}
+
+func frame(t *testing.T, v any) string {
+	t.Helper()
+	b, err := json.Marshal(v)
+	require.NoError(t, err)
+	return fmt.Sprintf("Content-Length: %d\r\n\r\n%s", len(b), b)
+}
+
+func readMessages(t *testing.T, r io.Reader) []message {
+	t.Helper()
+	br := bufio.NewReader(r)
+	var messages []message
+	for {
+		header, err := br.ReadString('\n')
+		if err == io.EOF {
+			return messages
+		}
+		require.NoError(t, err)
+		length, err := strconv.Atoi(strings.TrimSpace(strings.TrimPrefix(header, "Content-Length:")))
+		require.NoError(t, err)
+		_, err = br.ReadString('\n')
+		require.NoError(t, err)
+		b := make([]byte, length)
+		_, err = io.ReadFull(br, b)
+		require.NoError(t, err)
+		var m message
+		require.NoError(t, json.Unmarshal(b, &m))
+		messages = append(messages, m)
+	}
+}
+
+func fileURI(path string) string {
+	return (&url.URL{Scheme: "file", Path: filepath.ToSlash(path)}).String()
+}
+
+func TestServer(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "docs"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "docs", "Taskfile.yml"), []byte(docsTaskfile), 0o644))
+	rootURI := fileURI(filepath.Join(dir, "Taskfile.yml"))
+	doc := map[string]string{"uri": rootURI}
+
+	var in strings.Builder
+	in.WriteString(frame(t, map[string]any{"jsonrpc": "2.0", "id": 1, "method": "initialize", "params": map[string]any{}}))
+	in.WriteString(frame(t, map[string]any{"jsonrpc": "2.0", "method": "initialized", "params": map[string]any{}}))
+	in.WriteString(frame(t, map[string]any{"jsonrpc": "2.0", "method": "textDocument/didOpen", "params": map[string]any{
+		"textDocument": map[string]any{"uri": rootURI, "languageId": "yaml", "version": 1, "text": rootTaskfile},
+	}}))
+	// "docs:build" in the deps
+	in.WriteString(frame(t, map[string]any{"jsonrpc": "2.0", "id": 2, "method": "textDocument/definition", "params": map[string]any{
+		"textDocument": doc, "position": map[string]int{"line": 11, "character": 15},
+	}}))
+	// After "task: " in the cmds of default
+	in.WriteString(frame(t, map[string]any{"jsonrpc": "2.0", "id": 3, "method": "textDocument/completion", "params": map[string]any{
+		"textDocument": doc, "position": map[string]int{"line": 13, "character": 14},
+	}}))
+	// After the "{{." of NAME
+	in.WriteString(frame(t, map[string]any{"jsonrpc": "2.0", "id": 4, "method": "textDocument/completion", "params": map[string]any{
+		"textDocument": doc, "position": map[string]int{"line": 14, "character": 16},
+	}}))
+	in.WriteString(frame(t, map[string]any{"jsonrpc": "2.0", "id": 5, "method": "shutdown"}))
+	in.WriteString(frame(t, map[string]any{"jsonrpc": "2.0", "method": "exit"}))
+
+	var out bytes.Buffer
+	require.NoError(t, lsp.NewServer(strings.NewReader(in.String()), &out).Serve())
+	messages := readMessages(t, &out)
+	require.Len(t, messages, 6)
+
+	assert.Equal(t, 1, messages[0].ID)
+	assert.Contains(t, string(messages[0].Result), `"definitionProvider":true`)
+
+	assert.Equal(t, "textDocument/publishDiagnostics", messages[1].Method)
+	var diagnostics struct {
+		Diagnostics []struct {
+			Range struct {
+				Start struct{ Line, Character int }
+			}
+			Message string
+		}
+	}
+	require.NoError(t, json.Unmarshal(messages[1].Params, &diagnostics))
+	var got []string
+	for _, d := range diagnostics.Diagnostics {
+		got = append(got, fmt.Sprintf("%d:%d %s", d.Range.Start.Line, d.Range.Start.Character, d.Message))
+	}
+	assert.Equal(t, []string{
+		`4:11 The included Taskfile "./missing" doesn't exist`,
+		`14:26 Variable "UNKNOWN" is not defined`,
+		`18:14 Task "nope" does not exist`,
+	}, got)
+
+	var locations []struct {
+		URI   string
+		Range struct {
+			Start struct{ Line, Character int }
+		}
+	}
+	require.NoError(t, json.Unmarshal(messages[2].Result, &locations))
+	require.Len(t, locations, 1)
+	assert.Equal(t, fileURI(filepath.Join(dir, "docs", "Taskfile.yml")), locations[0].URI)
+	assert.Equal(t, 3, locations[0].Range.Start.Line)
+	assert.Equal(t, 2, locations[0].Range.Start.Character)
+
+	var items []struct{ Label, Detail string }
+	require.NoError(t, json.Unmarshal(messages[3].Result, &items))
+	var labels []string
+	for _, item := range items {
+		labels = append(labels, item.Label)
+	}
+	assert.Equal(t, []string{"default", "greet", "docs:build"}, labels)
+	assert.Equal(t, "Builds the docs", items[2].Detail)
+
+	require.NoError(t, json.Unmarshal(messages[4].Result, &items))
+	labels = nil
+	for _, item := range items {
+		labels = append(labels, item.Label)
+	}
+	assert.Contains(t, labels, "NAME")
+	assert.Contains(t, labels, "TASK")
+
+	assert.Equal(t, 5, messages[5].ID)
+}
+
+func TestServerSchemaError(t *testing.T) {
+	const taskfile = "version: '3'\n\ntasks:\n  default:\n    cmds: 42\n"
+	uri := fileURI(filepath.Join(t.TempDir(), "Taskfile.yml"))
+
+	var in strings.Builder
+	in.WriteString(frame(t, map[string]any{"jsonrpc": "2.0", "method": "textDocument/didOpen", "params": map[string]any{
+		"textDocument": map[string]any{"uri": uri, "text": taskfile},
+	}}))
+	in.WriteString(frame(t, map[string]any{"jsonrpc": "2.0", "id": 1, "method": "textDocument/hover", "params": map[string]any{}}))
+
+	var out bytes.Buffer
+	// The input ends without an exit, like when the editor is killed
+	require.NoError(t, lsp.NewServer(strings.NewReader(in.String()), &out).Serve())
+	messages := readMessages(t, &out)
+	require.Len(t, messages, 2)
+
+	var diagnostics struct {
+		Diagnostics []struct {
+			Range struct {
+				Start struct{ Line int }
+			}
+			Severity int
+		}
+	}
+	require.NoError(t, json.Unmarshal(messages[0].Params, &diagnostics))
+	require.Len(t, diagnostics.Diagnostics, 1)
+	assert.Equal(t, 4, diagnostics.Diagnostics[0].Range.Start.Line)
+	assert.Equal(t, 1, diagnostics.Diagnostics[0].Severity)
+
+	require.NotNil(t, messages[1].Error)
+	assert.Equal(t, -32601, messages[1].Error.Code)
+}
@@ -0,0 +1,125 @@
+package otel
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// DefaultEndpoint is where the OTLP exporter sends the spans when neither
+// OTEL_EXPORTER_OTLP_TRACES_ENDPOINT nor OTEL_EXPORTER_OTLP_ENDPOINT is set.
+const DefaultEndpoint = "http://localhost:4318/v1/traces"
+
+// An Exporter sends the spans of a run somewhere.
+type Exporter interface {
+	Export(ctx context.Context, spans []*Span) error
+}
+
+// NewExporter returns the exporter of the given spec, or nil for an empty
+// one or "none":
+//
+//   - "otlp" sends the spans with OTLP over HTTP to the endpoint of the
+//     standard OTEL_EXPORTER_OTLP_* variables, and "otlp:URL" to the given URL
+//   - "file:PATH" writes them to a file, as OTLP JSON
+//   - "console" writes them to the given writer, as OTLP JSON
+func NewExporter(spec string, console io.Writer) (Exporter, error) {
+	kind, arg, _ := strings.Cut(spec, ":")
+	switch kind {
+	case "", "none":
+		return nil, nil
+	case "otlp":
+		if arg == "" {
+			return &httpExporter{url: endpointFromEnv(), headers: headersFromEnv()}, nil
+		}
+		return &httpExporter{url: arg, headers: headersFromEnv()}, nil
+	case "file":
+		if arg == "" {
+			return nil, fmt.Errorf(`task: The OpenTelemetry exporter "file" needs a path, like "file:trace.json"`)
+		}
+		return &fileExporter{path: arg}, nil
+	case "console":
+		return &writerExporter{w: console}, nil
+	default:
+		return nil, fmt.Errorf(`task: Invalid OpenTelemetry exporter %q, it should be "otlp", "file:PATH", "console" or "none"`, spec)
+	}
+}
+
+type httpExporter struct {
+	url     string
+	headers map[string]string
+}
+
+func (e *httpExporter) Export(ctx context.Context, spans []*Span) error {
+	b, err := json.Marshal(encode(spans))
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, "POST", e.url, bytes.NewReader(b))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range e.headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("task: %s answered %s to the spans", e.url, resp.Status)
+	}
+	return nil
+}
+
+type fileExporter struct {
+	path string
+}
+
+func (e *fileExporter) Export(_ context.Context, spans []*Span) error {
+	b, err := json.MarshalIndent(encode(spans), "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(e.path, append(b, '\n'), 0o644)
+}
+
+type writerExporter struct {
+	w io.Writer
+}
+
+func (e *writerExporter) Export(_ context.Context, spans []*Span) error {
+	encoder := json.NewEncoder(e.w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(encode(spans))
+}
+
+func endpointFromEnv() string {
+	if endpoint := os.Getenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT"); endpoint != "" {
+		return endpoint
+	}
+	if endpoint := os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT"); endpoint != "" {
+		return strings.TrimSuffix(endpoint, "/") + "/v1/traces"
+	}
+	return DefaultEndpoint
+}
+
+// headersFromEnv parses the "key1=value1,key2=value2" headers of the OTLP
+// requests, e.g. for authentication.
+func headersFromEnv() map[string]string {
+	headers := map[string]string{}
+	for _, name := range []string{"OTEL_EXPORTER_OTLP_HEADERS", "OTEL_EXPORTER_OTLP_TRACES_HEADERS"} {
+		for _, pair := range strings.Split(os.Getenv(name), ",") {
+			if k, v, ok := strings.Cut(pair, "="); ok && strings.TrimSpace(k) != "" {
+				headers[strings.TrimSpace(k)] = strings.TrimSpace(v)
+			}
+		}
+	}
+	return headers
+}
@@ -0,0 +1,157 @@
+// Package otel emits OpenTelemetry traces without depending on the
+// OpenTelemetry SDK. The spans of a run are kept in memory and sent at the
+// end of it, with the OTLP JSON encoding, to one of the exporters of
+// NewExporter.
+package otel
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Status codes of a span.
+const (
+	StatusUnset = 0
+	StatusOK    = 1
+	StatusError = 2
+)
+
+// A Span is an operation of a trace, like a task or a command.
+type Span struct {
+	TraceID       string
+	SpanID        string
+	ParentSpanID  string
+	Name          string
+	Start         time.Time
+	End           time.Time
+	Attributes    map[string]any
+	StatusCode    int
+	StatusMessage string
+
+	tracer *Tracer
+	mutex  sync.Mutex
+}
+
+// SetAttribute sets an attribute of the span. The value should be a string,
+// a bool, an int or a float64. A nil span does nothing.
+func (s *Span) SetAttribute(key string, value any) {
+	if s == nil {
+		return
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.Attributes[key] = value
+}
+
+// Finish ends the span, with an error status if err isn't nil. A nil span
+// does nothing.
+func (s *Span) Finish(err error) {
+	if s == nil {
+		return
+	}
+	s.mutex.Lock()
+	s.End = time.Now()
+	if err != nil {
+		s.StatusCode = StatusError
+		s.StatusMessage = err.Error()
+	} else {
+		s.StatusCode = StatusOK
+	}
+	s.mutex.Unlock()
+	s.tracer.finish(s)
+}
+
+// A Tracer records the spans of a trace. A nil Tracer records nothing.
+type Tracer struct {
+	traceID  string
+	parentID string
+	spans    []*Span
+	mutex    sync.Mutex
+}
+
+// NewTracer starts a new trace. If traceparent is a W3C trace context, like
+// the TRACEPARENT variable set by some CI systems, the spans are part of its
+// trace instead.
+func NewTracer(traceparent string) *Tracer {
+	t := &Tracer{traceID: randomID(16)}
+	if traceID, parentID, ok := parseTraceparent(traceparent); ok {
+		t.traceID = traceID
+		t.parentID = parentID
+	}
+	return t
+}
+
+type spanKey struct{}
+
+// Start starts a span, child of the span of the context, if any, and returns
+// a context with it.
+func (t *Tracer) Start(ctx context.Context, name string) (context.Context, *Span) {
+	if t == nil {
+		return ctx, nil
+	}
+	span := &Span{
+		TraceID:      t.traceID,
+		SpanID:       randomID(8),
+		ParentSpanID: t.parentID,
+		Name:         name,
+		Start:        time.Now(),
+		Attributes:   map[string]any{},
+		tracer:       t,
+	}
+	if parent := SpanFromContext(ctx); parent != nil {
+		span.ParentSpanID = parent.SpanID
+	}
+	return context.WithValue(ctx, spanKey{}, span), span
+}
+
+// SpanFromContext returns the span of the context, or nil.
+func SpanFromContext(ctx context.Context) *Span {
+	span, _ := ctx.Value(spanKey{}).(*Span)
+	return span
+}
+
+func (t *Tracer) finish(s *Span) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	t.spans = append(t.spans, s)
+}
+
+// Spans returns the spans that finished, in the order they did.
+func (t *Tracer) Spans() []*Span {
+	if t == nil {
+		return nil
+	}
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	spans := make([]*Span, len(t.spans))
+	copy(spans, t.spans)
+	return spans
+}
+
+func randomID(n int) string {
+	b := make([]byte, n)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// parseTraceparent parses a traceparent header, like
+// 00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01.
+func parseTraceparent(traceparent string) (traceID, parentID string, ok bool) {
+	parts := strings.Split(strings.TrimSpace(traceparent), "-")
+	if len(parts) < 4 || len(parts[1]) != 32 || len(parts[2]) != 16 {
+		return "", "", false
+	}
+	if !isHex(parts[1]) || !isHex(parts[2]) || strings.Trim(parts[1], "0") == "" || strings.Trim(parts[2], "0") == "" {
+		return "", "", false
+	}
+	return strings.ToLower(parts[1]), strings.ToLower(parts[2]), true
+}
+
+func isHex(s string) bool {
+	_, err := hex.DecodeString(s)
+	return err == nil
+}
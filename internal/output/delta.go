@@ -0,0 +1,194 @@
+package output
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"sync"
+)
+
+// maxDeltaCells is the largest output (lines of the previous run multiplied
+// by lines of the current one) compared line by line. Larger outputs are
+// compared as sets of lines instead, which is much cheaper.
+const maxDeltaCells = 4_000_000
+
+// Delta shows only the lines of output that changed since the previous
+// successful run of the same command, on top of another output style. Added
+// lines start with "+ " and removed ones with "- ". The whole output is shown
+// on the first run and whenever the command fails.
+type Delta struct {
+	Output Output
+
+	mutex    sync.Mutex
+	previous map[string][]deltaLine
+}
+
+type deltaLine struct {
+	stderr bool
+	text   string
+}
+
+// WrapWriter uses the prefix to tell the commands apart. Use Command to give
+// each command its own key instead.
+func (d *Delta) WrapWriter(stdOut, stdErr io.Writer, prefix string, tmpl Templater) (io.Writer, io.Writer, CloseFunc) {
+	return d.Command(prefix).WrapWriter(stdOut, stdErr, prefix, tmpl)
+}
+
+// Command returns the Output for a single command, identified by the given
+// key across runs.
+func (d *Delta) Command(key string) Output {
+	return deltaCommand{delta: d, key: key}
+}
+
+type deltaCommand struct {
+	delta *Delta
+	key   string
+}
+
+func (c deltaCommand) WrapWriter(stdOut, stdErr io.Writer, prefix string, tmpl Templater) (io.Writer, io.Writer, CloseFunc) {
+	stdOut, stdErr, close := c.delta.Output.WrapWriter(stdOut, stdErr, prefix, tmpl)
+	rec := &deltaRecorder{}
+	return rec.writer(false), rec.writer(true), func(err error) error {
+		lines := rec.lines()
+		if err == nil {
+			c.delta.mutex.Lock()
+			previous, ok := c.delta.previous[c.key]
+			if c.delta.previous == nil {
+				c.delta.previous = make(map[string][]deltaLine)
+			}
+			c.delta.previous[c.key] = lines
+			c.delta.mutex.Unlock()
+			if ok {
+				lines = diffLines(previous, lines)
+			}
+		}
+		for _, l := range lines {
+			w := stdOut
+			if l.stderr {
+				w = stdErr
+			}
+			if _, err := io.WriteString(w, l.text+"\n"); err != nil {
+				return err
+			}
+		}
+		return close(err)
+	}
+}
+
+// deltaRecorder keeps the lines written to stdout and stderr in the order
+// they were written.
+type deltaRecorder struct {
+	mutex  sync.Mutex
+	done   []deltaLine
+	stdOut bytes.Buffer
+	stdErr bytes.Buffer
+}
+
+func (r *deltaRecorder) writer(stderr bool) io.Writer {
+	return deltaWriter{r: r, stderr: stderr}
+}
+
+func (r *deltaRecorder) lines() []deltaLine {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	for _, buff := range []*bytes.Buffer{&r.stdOut, &r.stdErr} {
+		if buff.Len() > 0 {
+			r.done = append(r.done, deltaLine{stderr: buff == &r.stdErr, text: buff.String()})
+			buff.Reset()
+		}
+	}
+	return r.done
+}
+
+type deltaWriter struct {
+	r      *deltaRecorder
+	stderr bool
+}
+
+func (w deltaWriter) Write(p []byte) (int, error) {
+	w.r.mutex.Lock()
+	defer w.r.mutex.Unlock()
+
+	buff := &w.r.stdOut
+	if w.stderr {
+		buff = &w.r.stdErr
+	}
+	buff.Write(p)
+	for {
+		line, err := buff.ReadString('\n')
+		if err != nil {
+			// Keep the incomplete line until the rest of it is written
+			buff.WriteString(line)
+			break
+		}
+		w.r.done = append(w.r.done, deltaLine{stderr: w.stderr, text: strings.TrimSuffix(line, "\n")})
+	}
+	return len(p), nil
+}
+
+// diffLines returns the lines removed from and added to the previous output.
+func diffLines(previous, current []deltaLine) []deltaLine {
+	if len(previous)*len(current) > maxDeltaCells {
+		return diffLineSets(previous, current)
+	}
+
+	// lcs[i][j] is the length of the longest common subsequence of
+	// previous[i:] and current[j:]
+	lcs := make([][]int, len(previous)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(current)+1)
+	}
+	for i := len(previous) - 1; i >= 0; i-- {
+		for j := len(current) - 1; j >= 0; j-- {
+			switch {
+			case previous[i] == current[j]:
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			case lcs[i+1][j] >= lcs[i][j+1]:
+				lcs[i][j] = lcs[i+1][j]
+			default:
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	var diff []deltaLine
+	i, j := 0, 0
+	for i < len(previous) || j < len(current) {
+		switch {
+		case i < len(previous) && j < len(current) && previous[i] == current[j]:
+			i++
+			j++
+		// Like diff, removals come before the additions that replace them
+		case i < len(previous) && (j == len(current) || lcs[i+1][j] >= lcs[i][j+1]):
+			diff = append(diff, deltaLine{stderr: previous[i].stderr, text: "- " + previous[i].text})
+			i++
+		default:
+			diff = append(diff, deltaLine{stderr: current[j].stderr, text: "+ " + current[j].text})
+			j++
+		}
+	}
+	return diff
+}
+
+func diffLineSets(previous, current []deltaLine) []deltaLine {
+	count := make(map[deltaLine]int, len(previous))
+	for _, l := range previous {
+		count[l]++
+	}
+	var diff []deltaLine
+	for _, l := range current {
+		if count[l] > 0 {
+			count[l]--
+			continue
+		}
+		diff = append(diff, deltaLine{stderr: l.stderr, text: "+ " + l.text})
+	}
+	for _, l := range previous {
+		if count[l] > 0 {
+			count[l]--
+			diff = append(diff, deltaLine{stderr: l.stderr, text: "- " + l.text})
+		}
+	}
+	return diff
+}
@@ -3,14 +3,22 @@ package output
 import (
 	"bytes"
 	"io"
+	"sync"
 )
 
 type Group struct {
 	Begin, End string
 	ErrorOnly  bool
+	// Nested groups the output of each task, with the groups of the tasks it
+	// calls indented inside it, instead of grouping each command.
+	Nested bool
 }
 
 func (g Group) WrapWriter(stdOut, _ io.Writer, _ string, tmpl Templater) (io.Writer, io.Writer, CloseFunc) {
+	// The commands of a nested group write to the group of their task
+	if node, ok := stdOut.(*GroupNode); ok {
+		return node, node, func(error) error { return nil }
+	}
 	gw := &groupWriter{writer: stdOut}
 	if g.Begin != "" {
 		gw.begin = tmpl.Replace(g.Begin) + "\n"
@@ -49,3 +57,83 @@ func (gw *groupWriter) close() error {
 	_, err := io.Copy(gw.writer, &gw.buff)
 	return err
 }
+
+// groupIndent is how much the groups of the tasks called by a task are
+// indented inside its own.
+const groupIndent = "  "
+
+// GroupNode is the nested group of a task. It holds the output of the task,
+// and the groups of the tasks it calls once they finish.
+type GroupNode struct {
+	parent     *GroupNode
+	name       string
+	writer     io.Writer
+	begin, end string
+	errorOnly  bool
+
+	mutex sync.Mutex
+	buff  bytes.Buffer
+}
+
+// Nest starts the group of the named task. It's written to the group of the
+// parent task when it finishes, or to w when there's no parent. The begin and
+// end templates get the name of the parent task as {{.PARENT}}.
+func (g Group) Nest(parent *GroupNode, w io.Writer, name string, tmpl Templater) *GroupNode {
+	node := &GroupNode{parent: parent, name: name, writer: w, errorOnly: g.ErrorOnly}
+	extra := map[string]any{"PARENT": ""}
+	if parent != nil {
+		extra["PARENT"] = parent.name
+	}
+	if g.Begin != "" {
+		node.begin = tmpl.ReplaceWithExtra(g.Begin, extra) + "\n"
+	}
+	if g.End != "" {
+		node.end = tmpl.ReplaceWithExtra(g.End, extra) + "\n"
+	}
+	return node
+}
+
+func (n *GroupNode) Write(p []byte) (int, error) {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+	return n.buff.Write(p)
+}
+
+// Close writes the group, unless it's empty or only the failed groups are
+// shown.
+func (n *GroupNode) Close(err error) error {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+	if n.buff.Len() == 0 || (n.errorOnly && err == nil) {
+		return nil
+	}
+	content := n.buff.Bytes()
+	if !bytes.HasSuffix(content, []byte("\n")) {
+		content = append(content, '\n')
+	}
+	group := append(append([]byte(n.begin), content...), n.end...)
+	n.buff.Reset()
+	if n.parent == nil {
+		_, err := n.writer.Write(group)
+		return err
+	}
+	n.parent.mutex.Lock()
+	defer n.parent.mutex.Unlock()
+	// The group starts on its own line, even after a partial one
+	if b := n.parent.buff.Bytes(); len(b) > 0 && b[len(b)-1] != '\n' {
+		n.parent.buff.WriteByte('\n')
+	}
+	_, err = n.parent.buff.Write(indentLines(group, groupIndent))
+	return err
+}
+
+func indentLines(b []byte, indent string) []byte {
+	var out []byte
+	for _, line := range bytes.SplitAfter(b, []byte("\n")) {
+		if len(line) > 0 && line[0] != '\n' {
+			out = append(out, indent...)
+		}
+		out = append(out, line...)
+	}
+	return out
+}
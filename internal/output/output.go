@@ -4,6 +4,8 @@ import (
 	"fmt"
 	"io"
 
+	"github.com/fatih/color"
+
 	"github.com/nuvolaris/task/v3/taskfile"
 )
 
@@ -12,6 +14,8 @@ import (
 type Templater interface {
 	// Replace replaces the provided template string with a rendered string.
 	Replace(tmpl string) string
+	// ReplaceWithExtra does the same as Replace, with additional variables.
+	ReplaceWithExtra(tmpl string, extra map[string]any) string
 }
 
 type Output interface {
@@ -22,6 +26,9 @@ type CloseFunc func(err error) error
 
 // Build the Output for the requested taskfile.Output.
 func BuildFor(o *taskfile.Output) (Output, error) {
+	if err := o.ValidateTimestamps(); err != nil {
+		return nil, err
+	}
 	switch o.Name {
 	case "interleaved", "":
 		if err := checkOutputGroupUnset(o); err != nil {
@@ -33,17 +40,41 @@ func BuildFor(o *taskfile.Output) (Output, error) {
 			Begin:     o.Group.Begin,
 			End:       o.Group.End,
 			ErrorOnly: o.Group.ErrorOnly,
+			Nested:    o.Group.Nested,
 		}, nil
 	case "prefixed":
 		if err := checkOutputGroupUnset(o); err != nil {
 			return nil, err
 		}
-		return Prefixed{}, nil
+		if !o.Prefixed.IsSet() {
+			return Prefixed{}, nil
+		}
+		return Prefixed{
+			Template: o.Prefixed.Template,
+			Color:    prefixedColor(o.Prefixed.Color),
+			width:    &prefixWidth{},
+		}, nil
+	case "progress":
+		if err := checkOutputGroupUnset(o); err != nil {
+			return nil, err
+		}
+		return &Progress{}, nil
 	default:
 		return nil, fmt.Errorf(`task: output style %q not recognized`, o.Name)
 	}
 }
 
+func prefixedColor(mode string) bool {
+	switch mode {
+	case taskfile.OutputColorAlways:
+		return true
+	case taskfile.OutputColorAuto:
+		return !color.NoColor
+	default:
+		return false
+	}
+}
+
 func checkOutputGroupUnset(o *taskfile.Output) error {
 	if o.Group.IsSet() {
 		return fmt.Errorf("task: output style %q does not support the group begin/end parameter", o.Name)
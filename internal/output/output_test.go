@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"io"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -77,6 +78,31 @@ func TestGroupWithBeginEnd(t *testing.T) {
 	})
 }
 
+func TestGroupNested(t *testing.T) {
+	var tmpl templater.Templater
+	g := output.Group{
+		Begin:  "begin {{.PARENT}}",
+		End:    "end",
+		Nested: true,
+	}
+
+	var b bytes.Buffer
+	root := g.Nest(nil, &b, "root", &tmpl)
+	child := g.Nest(root, &b, "child", &tmpl)
+
+	// The commands write to the group of their task
+	w, _, cleanup := g.WrapWriter(child, io.Discard, "", &tmpl)
+	fmt.Fprintln(w, "foo")
+	require.NoError(t, cleanup(nil))
+	fmt.Fprint(root, "bar")
+	assert.Equal(t, "", b.String())
+
+	require.NoError(t, child.Close(nil))
+	assert.Equal(t, "", b.String())
+	require.NoError(t, root.Close(nil))
+	assert.Equal(t, "begin \nbar\n  begin root\n  foo\n  end\nend\n", b.String())
+}
+
 func TestGroupErrorOnlySwallowsOutputOnNoError(t *testing.T) {
 	var b bytes.Buffer
 	var o output.Output = output.Group{
@@ -132,3 +158,213 @@ func TestPrefixed(t *testing.T) {
 		assert.Equal(t, "[prefix] Test!\n", b.String())
 	})
 }
+
+func TestPrefixedWithTemplate(t *testing.T) {
+	tmpl := templater.Templater{
+		Vars: &taskfile.Vars{
+			OrderedMap: orderedmap.FromMap(map[string]taskfile.Var{
+				"TASK": {Static: "build"},
+			}),
+		},
+	}
+
+	o, err := output.BuildFor(&taskfile.Output{
+		Name:     "prefixed",
+		Prefixed: taskfile.OutputPrefixed{Template: "[{{.TASK}}:{{.PREFIX}}]"},
+	})
+	require.NoError(t, err)
+
+	var b bytes.Buffer
+	long, _, closeLong := o.WrapWriter(&b, io.Discard, "linux", &tmpl)
+	short, _, closeShort := o.WrapWriter(&b, io.Discard, "a", &tmpl)
+
+	// Prefixes are padded to the longest one written so far
+	fmt.Fprintln(long, "foo")
+	fmt.Fprintln(short, "bar")
+	require.NoError(t, closeLong(nil))
+	require.NoError(t, closeShort(nil))
+	assert.Equal(t, "[build:linux] foo\n[build:a]     bar\n", b.String())
+}
+
+func TestPrefixedWithColor(t *testing.T) {
+	o, err := output.BuildFor(&taskfile.Output{
+		Name:     "prefixed",
+		Prefixed: taskfile.OutputPrefixed{Color: taskfile.OutputColorAlways},
+	})
+	require.NoError(t, err)
+
+	render := func(prefix string) string {
+		var b bytes.Buffer
+		w, _, cleanup := o.WrapWriter(&b, io.Discard, prefix, nil)
+		fmt.Fprintln(w, "foo")
+		require.NoError(t, cleanup(nil))
+		return b.String()
+	}
+
+	out := render("build")
+	assert.Contains(t, out, "\x1b[")
+	assert.Contains(t, out, "[build]")
+	assert.Equal(t, out, render("build"), "a prefix always gets the same color")
+}
+
+func TestProgress(t *testing.T) {
+	var b bytes.Buffer
+	p := &output.Progress{Writer: &b}
+
+	finishBuild := p.TrackTask("build")
+	assert.Regexp(t, ` build \d+\.\ds\n`, b.String())
+
+	finishTest := p.TrackTask("test")
+	stdOut, _, cleanup := p.WrapWriter(io.Discard, io.Discard, "", nil)
+	fmt.Fprint(stdOut, "compil")
+	assert.NotContains(t, b.String(), "compil")
+	fmt.Fprintln(stdOut, "ing")
+	require.NoError(t, cleanup(nil))
+	assert.Contains(t, b.String(), "\x1b[2F\x1b[Jcompiling\n")
+
+	b.Reset()
+	finishBuild(nil)
+	finishTest(errors.New("failed"))
+	p.Stop()
+	assert.Regexp(t, `✓ build \d+\.\ds\n`, b.String())
+	assert.Regexp(t, `✗ test \d+\.\ds\n`, b.String())
+
+	// The dashboard is gone once stopped
+	b.Reset()
+	fmt.Fprintln(p.Printer(), "task: done")
+	assert.Equal(t, "task: done\n", b.String())
+}
+
+func TestProgressPrinterShowsPrompts(t *testing.T) {
+	var b bytes.Buffer
+	p := &output.Progress{Writer: &b}
+	finish := p.TrackTask("build")
+	defer p.Stop()
+	defer finish(nil)
+
+	b.Reset()
+	fmt.Fprint(p.Printer(), "task: Continue? [y/N]: ")
+	assert.Equal(t, "\x1b[1F\x1b[Jtask: Continue? [y/N]: ", b.String())
+}
+
+func TestProgressPause(t *testing.T) {
+	var b bytes.Buffer
+	p := &output.Progress{Writer: &b}
+	finish := p.TrackTask("build")
+	defer p.Stop()
+	defer finish(nil)
+
+	// The dashboard is erased and not drawn again until resumed
+	b.Reset()
+	resume := p.Pause()
+	assert.Equal(t, "\x1b[1F\x1b[J", b.String())
+	b.Reset()
+	fmt.Fprintln(p.Printer(), "task: [build] vim")
+	assert.Equal(t, "task: [build] vim\n", b.String())
+
+	b.Reset()
+	resume()
+	assert.Regexp(t, `^\S+ build \d+\.\ds\n$`, b.String())
+}
+
+func TestStatusLine(t *testing.T) {
+	var b bytes.Buffer
+	s := &output.StatusLine{Writer: &b}
+	w := s.Wrap(&b)
+
+	s.Set("watching")
+	assert.Equal(t, "\r\x1b[Kwatching", b.String())
+
+	// The line is erased, then drawn again below the output
+	b.Reset()
+	fmt.Fprintln(w, "built")
+	assert.Equal(t, "\r\x1b[Kbuilt\n\r\x1b[Kwatching", b.String())
+
+	// Not after an incomplete line, until it ends
+	b.Reset()
+	fmt.Fprint(w, "answer: ")
+	s.Set("running")
+	assert.Equal(t, "\r\x1b[Kanswer: ", b.String())
+	b.Reset()
+	fmt.Fprintln(w, "yes")
+	assert.Equal(t, "yes\n\r\x1b[Krunning", b.String())
+
+	b.Reset()
+	s.Stop()
+	fmt.Fprintln(w, "done")
+	assert.Equal(t, "\r\x1b[Kdone\n", b.String())
+}
+
+func TestTimestamped(t *testing.T) {
+	var b bytes.Buffer
+	var o output.Output = output.Timestamped{
+		Output: output.Interleaved{},
+		Format: taskfile.OutputTimestampsRelative,
+		Start:  time.Now(),
+	}
+	w, _, cleanup := o.WrapWriter(&b, io.Discard, "", nil)
+
+	fmt.Fprint(w, "foo\nba")
+	fmt.Fprintln(w, "r")
+	require.NoError(t, cleanup(nil))
+	assert.Regexp(t, `^\+\d+\.\d{3}s foo\n\+\d+\.\d{3}s bar\n$`, b.String())
+}
+
+func TestTimestampedGroup(t *testing.T) {
+	var b bytes.Buffer
+	var o output.Output = output.Timestamped{
+		Output: output.Group{},
+		Format: taskfile.OutputTimestampsRFC3339,
+	}
+	stdOut, stdErr, cleanup := o.WrapWriter(&b, io.Discard, "", nil)
+
+	fmt.Fprintln(stdOut, "out")
+	fmt.Fprintln(stdErr, "err")
+	assert.Equal(t, "", b.String())
+	require.NoError(t, cleanup(nil))
+	assert.Regexp(t, `^\d{4}-\d\d-\d\dT\d\d:\d\d:\d\d\S* out\n\d{4}-\d\d-\d\dT\d\d:\d\d:\d\d\S* err\n$`, b.String())
+}
+
+func TestStripANSI(t *testing.T) {
+	var b bytes.Buffer
+	var o output.Output = output.StripANSI{Output: output.Interleaved{}}
+	w, _, cleanup := o.WrapWriter(&b, io.Discard, "", nil)
+
+	fmt.Fprint(w, "\x1b[1;31mred\x1b[0m \x1b]8;;https://taskfile.dev\x07link\x1b]8;;\x1b\\\n")
+	// A sequence split between writes
+	fmt.Fprint(w, "\x1b[3")
+	fmt.Fprint(w, "2mgreen\x1b(B\n")
+	require.NoError(t, cleanup(nil))
+	assert.Equal(t, "red link\ngreen\n", b.String())
+}
+
+func TestDelta(t *testing.T) {
+	o := &output.Delta{Output: output.Interleaved{}}
+	run := func(out string, runErr error) string {
+		var b bytes.Buffer
+		stdOut, _, cleanup := o.Command("test").WrapWriter(&b, &b, "", nil)
+		fmt.Fprint(stdOut, out)
+		require.NoError(t, cleanup(runErr))
+		return b.String()
+	}
+
+	// The first run shows everything
+	assert.Equal(t, "ok a\nok b\nok c\n", run("ok a\nok b\nok c\n", nil))
+	assert.Equal(t, "", run("ok a\nok b\nok c\n", nil))
+	assert.Equal(t, "- ok b\n+ FAIL b\n+ ok d\n", run("ok a\nFAIL b\nok c\nok d\n", nil))
+
+	// Failures show everything, and don't replace the previous output
+	assert.Equal(t, "ok a\npanic\n", run("ok a\npanic", errors.New("exit status 1")))
+	assert.Equal(t, "+ ok e\n", run("ok a\nFAIL b\nok c\nok d\nok e\n", nil))
+}
+
+func TestDeltaKeepsCommandsApart(t *testing.T) {
+	var b bytes.Buffer
+	o := &output.Delta{Output: output.Interleaved{}}
+	for _, key := range []string{"build", "test"} {
+		stdOut, _, cleanup := o.Command(key).WrapWriter(&b, io.Discard, "", nil)
+		fmt.Fprintln(stdOut, key)
+		require.NoError(t, cleanup(nil))
+	}
+	assert.Equal(t, "build\ntest\n", b.String())
+}
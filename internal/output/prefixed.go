@@ -3,20 +3,85 @@ package output
 import (
 	"bytes"
 	"fmt"
+	"hash/fnv"
 	"io"
 	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/fatih/color"
 )
 
-type Prefixed struct{}
+// prefixColors are the colors given to the prefixes. Each prefix always gets
+// the same color, so a task is easy to follow across runs.
+var prefixColors = []color.Attribute{
+	color.FgBlue,
+	color.FgGreen,
+	color.FgCyan,
+	color.FgYellow,
+	color.FgMagenta,
+	color.FgHiBlue,
+	color.FgHiGreen,
+	color.FgHiCyan,
+	color.FgHiYellow,
+	color.FgHiMagenta,
+}
+
+type Prefixed struct {
+	// Template of the prefix, rendered for every line. Defaults to
+	// "[{{.PREFIX}}]".
+	Template string
+	// Color gives every prefix its own color.
+	Color bool
+
+	// width is the width of the longest prefix written so far, so the output
+	// of concurrent tasks stays aligned.
+	width *prefixWidth
+}
+
+type prefixWidth struct {
+	mutex sync.Mutex
+	max   int
+}
 
-func (Prefixed) WrapWriter(stdOut, _ io.Writer, prefix string, _ Templater) (io.Writer, io.Writer, CloseFunc) {
-	pw := &prefixWriter{writer: stdOut, prefix: prefix}
+// pad returns the padding needed to align the given prefix with the longest
+// one written so far.
+func (w *prefixWidth) pad(prefix string) string {
+	if w == nil {
+		return ""
+	}
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	n := utf8.RuneCountInString(prefix)
+	if n > w.max {
+		w.max = n
+	}
+	return strings.Repeat(" ", w.max-n)
+}
+
+func (p Prefixed) WrapWriter(stdOut, _ io.Writer, prefix string, tmpl Templater) (io.Writer, io.Writer, CloseFunc) {
+	pw := &prefixWriter{writer: stdOut, prefix: prefix, style: p, tmpl: tmpl}
+	if p.Color {
+		pw.color = color.New(prefixColor(prefix))
+		pw.color.EnableColor()
+	}
 	return pw, pw, func(error) error { return pw.close() }
 }
 
+func prefixColor(prefix string) color.Attribute {
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(prefix))
+	return prefixColors[h.Sum32()%uint32(len(prefixColors))]
+}
+
 type prefixWriter struct {
 	writer io.Writer
 	prefix string
+	style  Prefixed
+	tmpl   Templater
+	color  *color.Color
 	buff   bytes.Buffer
 }
 
@@ -61,6 +126,21 @@ func (pw *prefixWriter) writeLine(line string) error {
 	if !strings.HasSuffix(line, "\n") {
 		line += "\n"
 	}
-	_, err := fmt.Fprintf(pw.writer, "[%s] %s", pw.prefix, line)
+	prefix := pw.renderPrefix()
+	padding := pw.style.width.pad(prefix)
+	if pw.color != nil {
+		prefix = pw.color.Sprint(prefix)
+	}
+	_, err := fmt.Fprintf(pw.writer, "%s%s %s", prefix, padding, line)
 	return err
 }
+
+func (pw *prefixWriter) renderPrefix() string {
+	if pw.style.Template == "" || pw.tmpl == nil {
+		return "[" + pw.prefix + "]"
+	}
+	return pw.tmpl.ReplaceWithExtra(pw.style.Template, map[string]any{
+		"PREFIX": pw.prefix,
+		"TIME":   time.Now().Format("15:04:05"),
+	})
+}
@@ -0,0 +1,234 @@
+package output
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fatih/color"
+)
+
+var spinnerFrames = []string{"⠋", "⠙", "⠹", "⠸", "⠼", "⠴", "⠦", "⠧", "⠇", "⠏"}
+
+// progressInterval is how often the spinners and elapsed times are updated.
+const progressInterval = 100 * time.Millisecond
+
+// Tracker is implemented by the outputs that follow the tasks as they run.
+type Tracker interface {
+	// TrackTask records that a task started running its commands. The
+	// returned function must be called once the task finishes.
+	TrackTask(name string) (finish func(err error))
+	// Stop renders the final state of the tasks and stops updating it.
+	Stop()
+	// Pause stops rendering until the returned function is called, so an
+	// interactive command can have the terminal for itself.
+	Pause() (resume func())
+}
+
+// Progress renders an in-place dashboard on a terminal, with one line per
+// running task showing a spinner and the elapsed time. Once a task finishes,
+// its final line (with ✓ or ✗) is printed above the dashboard, along with
+// everything else written while tasks run, like the output of the commands.
+type Progress struct {
+	// Writer is the terminal the dashboard is drawn on.
+	Writer io.Writer
+
+	mutex   sync.Mutex
+	running []*progressTask
+	drawn   int
+	frame   int
+	stop    chan struct{}
+	// partial is set while the cursor is at the end of an incomplete line,
+	// like a prompt waiting for an answer, which must not be erased
+	partial bool
+	// paused is how many interactive commands have the terminal
+	paused int
+}
+
+type progressTask struct {
+	name    string
+	started time.Time
+}
+
+func (p *Progress) WrapWriter(_, _ io.Writer, _ string, _ Templater) (io.Writer, io.Writer, CloseFunc) {
+	stdOut, stdErr := &progressWriter{p: p}, &progressWriter{p: p}
+	return stdOut, stdErr, func(error) error {
+		stdOut.flush()
+		stdErr.flush()
+		return nil
+	}
+}
+
+// Printer returns a writer whose lines are printed above the dashboard. It's
+// meant for the messages that are not written by the commands, like the logs.
+// Unlike the output of the commands, an incomplete line is printed right away,
+// so prompts are visible.
+func (p *Progress) Printer() io.Writer {
+	return &progressWriter{p: p, prompts: true}
+}
+
+func (p *Progress) TrackTask(name string) func(err error) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	task := &progressTask{name: name, started: time.Now()}
+	p.running = append(p.running, task)
+	if p.stop == nil {
+		p.stop = make(chan struct{})
+		go p.tick(p.stop)
+	}
+	p.redraw("")
+
+	var once sync.Once
+	return func(err error) {
+		once.Do(func() { p.finish(task, err) })
+	}
+}
+
+func (p *Progress) finish(task *progressTask, err error) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	for i, t := range p.running {
+		if t == task {
+			p.running = append(p.running[:i], p.running[i+1:]...)
+			break
+		}
+	}
+	mark := color.GreenString("✓")
+	if err != nil {
+		mark = color.RedString("✗")
+	}
+	p.redraw(fmt.Sprintf("%s %s %s\n", mark, task.name, elapsed(task.started)))
+}
+
+func (p *Progress) Stop() {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if p.stop != nil {
+		close(p.stop)
+		p.stop = nil
+	}
+	p.redraw("")
+	// Whatever is left stays on the screen as it is
+	p.drawn = 0
+	p.running = nil
+}
+
+func (p *Progress) Pause() func() {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if p.paused == 0 && p.drawn > 0 && !p.partial {
+		_, _ = fmt.Fprintf(p.Writer, "\x1b[%dF\x1b[J", p.drawn)
+	}
+	p.drawn = 0
+	p.paused++
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			p.mutex.Lock()
+			defer p.mutex.Unlock()
+			p.paused--
+			p.redraw("")
+		})
+	}
+}
+
+func (p *Progress) tick(stop chan struct{}) {
+	ticker := time.NewTicker(progressInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			p.mutex.Lock()
+			p.frame++
+			p.redraw("")
+			p.mutex.Unlock()
+		}
+	}
+}
+
+// redraw erases the dashboard, prints the given text in its place and draws
+// the dashboard again below it. It must be called with the mutex held.
+func (p *Progress) redraw(above string) {
+	if p.partial && above == "" {
+		return
+	}
+	// While paused, the dashboard is not drawn, but the rest is printed
+	if p.paused > 0 {
+		_, _ = io.WriteString(p.Writer, above)
+		p.partial = above != "" && !strings.HasSuffix(above, "\n")
+		return
+	}
+
+	var b strings.Builder
+	if p.drawn > 0 {
+		// Move the cursor to the first line of the dashboard and clear the
+		// screen from there
+		fmt.Fprintf(&b, "\x1b[%dF\x1b[J", p.drawn)
+	}
+	b.WriteString(above)
+	p.drawn = 0
+	p.partial = above != "" && !strings.HasSuffix(above, "\n")
+	if !p.partial {
+		frame := spinnerFrames[p.frame%len(spinnerFrames)]
+		for _, t := range p.running {
+			fmt.Fprintf(&b, "%s %s %s\n", color.CyanString(frame), t.name, elapsed(t.started))
+		}
+		p.drawn = len(p.running)
+	}
+	_, _ = io.WriteString(p.Writer, b.String())
+}
+
+func elapsed(started time.Time) string {
+	return fmt.Sprintf("%.1fs", time.Since(started).Seconds())
+}
+
+var ansiEscape = regexp.MustCompile(`\x1b\[[0-9;]*[A-Za-z]`)
+
+// progressWriter prints complete lines above the dashboard, so the lines of
+// concurrent tasks are never mixed up.
+type progressWriter struct {
+	p    *Progress
+	buff bytes.Buffer
+	// prompts prints incomplete lines right away, unless they only contain
+	// escape sequences (e.g. the color reset written after every message)
+	prompts bool
+}
+
+func (w *progressWriter) Write(b []byte) (int, error) {
+	n, _ := w.buff.Write(b)
+	if i := bytes.LastIndexByte(w.buff.Bytes(), '\n'); i >= 0 {
+		w.print(string(w.buff.Next(i + 1)))
+	}
+	if w.prompts && ansiEscape.ReplaceAllString(w.buff.String(), "") != "" {
+		w.print(w.buff.String())
+		w.buff.Reset()
+	}
+	return n, nil
+}
+
+func (w *progressWriter) flush() {
+	if w.buff.Len() == 0 {
+		return
+	}
+	w.print(w.buff.String() + "\n")
+	w.buff.Reset()
+}
+
+func (w *progressWriter) print(s string) {
+	w.p.mutex.Lock()
+	defer w.p.mutex.Unlock()
+
+	w.p.redraw(s)
+}
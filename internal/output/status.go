@@ -0,0 +1,94 @@
+package output
+
+import (
+	"bytes"
+	"io"
+	"sync"
+)
+
+// StatusLine keeps a line at the bottom of a terminal, below everything
+// written through the writers it wraps, like the state of the watched tasks.
+type StatusLine struct {
+	// Writer is the terminal the line is drawn on.
+	Writer io.Writer
+
+	mutex sync.Mutex
+	text  string
+	drawn bool
+	// partial is set while the cursor is at the end of an incomplete line,
+	// which must not be erased, so the line is drawn again after it ends
+	partial bool
+	stopped bool
+}
+
+// Set replaces the text of the line.
+func (s *StatusLine) Set(text string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.text = text
+	s.draw()
+}
+
+// Wrap returns a writer whose output is printed above the line.
+func (s *StatusLine) Wrap(w io.Writer) io.Writer {
+	return &statusWriter{s: s, w: w}
+}
+
+// ClearScreen clears the terminal, leaving only the line.
+func (s *StatusLine) ClearScreen() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	_, _ = io.WriteString(s.Writer, "\x1b[H\x1b[2J")
+	s.drawn, s.partial = false, false
+	s.draw()
+}
+
+// Stop erases the line and stops drawing it.
+func (s *StatusLine) Stop() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.erase()
+	s.stopped = true
+}
+
+// draw draws the line again. It must be called with the mutex held.
+func (s *StatusLine) draw() {
+	if s.stopped || s.partial || s.text == "" {
+		return
+	}
+	// Erase the previous line, without moving to the next one
+	_, _ = io.WriteString(s.Writer, "\r\x1b[K"+s.text)
+	s.drawn = true
+}
+
+// erase erases the line, leaving the cursor at its start. It must be called
+// with the mutex held.
+func (s *StatusLine) erase() {
+	if s.drawn {
+		_, _ = io.WriteString(s.Writer, "\r\x1b[K")
+		s.drawn = false
+	}
+}
+
+type statusWriter struct {
+	s *StatusLine
+	w io.Writer
+}
+
+func (sw *statusWriter) Write(p []byte) (int, error) {
+	s := sw.s
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.stopped {
+		return sw.w.Write(p)
+	}
+
+	s.erase()
+	n, err := sw.w.Write(p)
+	// Like the colors reset after a line
+	if len(ansiEscape.ReplaceAll(p, nil)) > 0 {
+		s.partial = !bytes.HasSuffix(p, []byte("\n"))
+	}
+	s.draw()
+	return n, err
+}
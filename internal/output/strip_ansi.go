@@ -0,0 +1,82 @@
+package output
+
+import "io"
+
+// StripANSI removes the ANSI escape sequences, like colors or cursor moves,
+// from the output of the commands, on top of any other output style. It's
+// meant for logs that aren't read in a terminal, like the ones of CI.
+type StripANSI struct {
+	Output Output
+}
+
+func (s StripANSI) WrapWriter(stdOut, stdErr io.Writer, prefix string, tmpl Templater) (io.Writer, io.Writer, CloseFunc) {
+	stdOut, stdErr, close := s.Output.WrapWriter(stdOut, stdErr, prefix, tmpl)
+	return &stripANSIWriter{writer: stdOut}, &stripANSIWriter{writer: stdErr}, close
+}
+
+// States of stripANSIWriter, which are kept between writes since a sequence
+// can be split across them.
+const (
+	stripText = iota
+	// stripEscape is after an ESC
+	stripEscape
+	// stripCSI is inside a control sequence, like the colors
+	stripCSI
+	// stripOSC is inside an operating system command, like a title or a
+	// hyperlink, which ends with BEL or ESC \
+	stripOSC
+	stripOSCEscape
+)
+
+type stripANSIWriter struct {
+	writer io.Writer
+	state  int
+}
+
+func (w *stripANSIWriter) Write(p []byte) (int, error) {
+	b := make([]byte, 0, len(p))
+	for _, c := range p {
+		switch w.state {
+		case stripText:
+			if c == 0x1b {
+				w.state = stripEscape
+			} else {
+				b = append(b, c)
+			}
+		case stripEscape:
+			switch {
+			case c == '[':
+				w.state = stripCSI
+			case c == ']':
+				w.state = stripOSC
+			case c >= 0x20 && c <= 0x2f:
+				// An intermediate byte, like in ESC ( B
+			default:
+				w.state = stripText
+			}
+		case stripCSI:
+			if c >= 0x40 && c <= 0x7e {
+				w.state = stripText
+			}
+		case stripOSC:
+			switch c {
+			case 0x07:
+				w.state = stripText
+			case 0x1b:
+				w.state = stripOSCEscape
+			}
+		case stripOSCEscape:
+			if c == '\\' {
+				w.state = stripText
+			} else {
+				w.state = stripOSC
+			}
+		}
+	}
+	if len(b) > 0 {
+		if _, err := w.writer.Write(b); err != nil {
+			return 0, err
+		}
+	}
+	return len(p), nil
+}
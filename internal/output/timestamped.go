@@ -0,0 +1,62 @@
+package output
+
+import (
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/nuvolaris/task/v3/taskfile"
+)
+
+// Timestamped prefixes every line written by the commands with the time it
+// was written at, on top of any other output style.
+type Timestamped struct {
+	Output Output
+	// Format is either taskfile.OutputTimestampsRFC3339 or
+	// taskfile.OutputTimestampsRelative.
+	Format string
+	// Start is the time relative timestamps are counted from.
+	Start time.Time
+}
+
+func (t Timestamped) WrapWriter(stdOut, stdErr io.Writer, prefix string, tmpl Templater) (io.Writer, io.Writer, CloseFunc) {
+	stdOut, stdErr, close := t.Output.WrapWriter(stdOut, stdErr, prefix, tmpl)
+	// The lines are stamped before reaching the wrapped style, so styles
+	// that buffer the output (like group) still show when each line was
+	// written
+	return &timestampWriter{writer: stdOut, style: t, atLineStart: true},
+		&timestampWriter{writer: stdErr, style: t, atLineStart: true},
+		close
+}
+
+func (t Timestamped) timestamp(now time.Time) string {
+	if t.Format == taskfile.OutputTimestampsRelative {
+		return fmt.Sprintf("+%.3fs", now.Sub(t.Start).Seconds())
+	}
+	return now.Format(time.RFC3339)
+}
+
+type timestampWriter struct {
+	writer      io.Writer
+	style       Timestamped
+	atLineStart bool
+}
+
+func (w *timestampWriter) Write(p []byte) (int, error) {
+	var b []byte
+	for _, c := range p {
+		if w.atLineStart {
+			b = append(b, w.style.timestamp(time.Now())...)
+			b = append(b, ' ')
+			w.atLineStart = false
+		}
+		b = append(b, c)
+		if c == '\n' {
+			w.atLineStart = true
+		}
+	}
+	if _, err := w.writer.Write(b); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
@@ -27,7 +27,9 @@ func PrintSpaceBetweenSummaries(l *logger.Logger, i int) {
 func PrintTask(l *logger.Logger, t *taskfile.Task) {
 	printTaskName(l, t)
 	printTaskDescribingText(t, l)
+	printTaskArgs(l, t)
 	printTaskDependencies(l, t)
+	printTaskPipeline(l, t)
 	printTaskAliases(l, t)
 	printTaskCommands(l, t)
 }
@@ -58,7 +60,7 @@ func printTaskSummary(l *logger.Logger, t *taskfile.Task) {
 
 func printTaskName(l *logger.Logger, t *taskfile.Task) {
 	l.Outf(logger.Default, "task: ")
-	l.Outf(logger.Green, "%s\n", t.Name())
+	l.Outf(logger.TaskName, "%s\n", t.Name())
 	l.Outf(logger.Default, "\n")
 }
 
@@ -86,6 +88,23 @@ func printNoDescriptionOrSummary(l *logger.Logger) {
 	l.Outf(logger.Default, "(task does not have description or summary)\n")
 }
 
+func printTaskArgs(l *logger.Logger, t *taskfile.Task) {
+	if len(t.Args) == 0 {
+		return
+	}
+
+	l.Outf(logger.Default, "\n")
+	l.Outf(logger.Default, "usage: task %s %s\n", t.Task, taskfile.ArgsUsage(t.Args))
+
+	for _, a := range t.Args {
+		if a.Desc != "" {
+			l.Outf(logger.Default, " - %s: %s\n", a.Name, a.Desc)
+		} else {
+			l.Outf(logger.Default, " - %s\n", a.Name)
+		}
+	}
+}
+
 func printTaskDependencies(l *logger.Logger, t *taskfile.Task) {
 	if len(t.Deps) == 0 {
 		return
@@ -99,6 +118,26 @@ func printTaskDependencies(l *logger.Logger, t *taskfile.Task) {
 	}
 }
 
+func printTaskPipeline(l *logger.Logger, t *taskfile.Task) {
+	if len(t.Pipeline) == 0 {
+		return
+	}
+
+	l.Outf(logger.Default, "\n")
+	l.Outf(logger.Default, "pipeline:\n")
+
+	for i, stage := range t.Pipeline {
+		if stage.Name != "" {
+			l.Outf(logger.Default, " %d. %s:\n", i+1, stage.Name)
+		} else {
+			l.Outf(logger.Default, " %d.\n", i+1)
+		}
+		for _, d := range stage.Tasks {
+			l.Outf(logger.Default, "   - %s\n", d.Task)
+		}
+	}
+}
+
 func printTaskCommands(l *logger.Logger, t *taskfile.Task) {
 	if len(t.Cmds) == 0 {
 		return
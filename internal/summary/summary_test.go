@@ -48,6 +48,21 @@ func TestDoesNotPrintDependenciesIfMissing(t *testing.T) {
 	assert.NotContains(t, buffer.String(), "dependencies:")
 }
 
+func TestPrintsArgsIfPresent(t *testing.T) {
+	buffer, l := createDummyLogger()
+	task := &taskfile.Task{
+		Task: "deploy",
+		Args: []*taskfile.Arg{
+			{Name: "target", Desc: "Where to deploy", Required: true},
+			{Name: "flags", Variadic: true},
+		},
+	}
+
+	summary.PrintTask(&l, task)
+
+	assert.Contains(t, buffer.String(), "\nusage: task deploy <target> [flags...]\n - target: Where to deploy\n - flags\n")
+}
+
 func TestPrintTaskName(t *testing.T) {
 	buffer, l := createDummyLogger()
 	task := &taskfile.Task{
@@ -0,0 +1,94 @@
+package templater
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+
+	"github.com/nuvolaris/task/v3/internal/filepathext"
+	"github.com/nuvolaris/task/v3/internal/fingerprint"
+)
+
+// fileFuncs returns the functions reading the files of the given directory.
+// Relative paths are resolved from it, and the paths outside of it can't be
+// read, so a Taskfile only reads the files of its own project. The current
+// directory is used when dir is empty.
+func fileFuncs(dir string) template.FuncMap {
+	root := func() (string, error) {
+		if dir != "" {
+			return filepath.Abs(dir)
+		}
+		return os.Getwd()
+	}
+	resolve := func(funcName, path string) (string, string, error) {
+		root, err := root()
+		if err != nil {
+			return "", "", err
+		}
+		resolved := filepathext.SmartJoin(root, path)
+		rel, err := filepath.Rel(root, resolved)
+		if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return "", "", fmt.Errorf("task: %s can't use %q, which is outside of %s", funcName, path, root)
+		}
+		return root, resolved, nil
+	}
+
+	return template.FuncMap{
+		"readFile": func(path string) (string, error) {
+			_, resolved, err := resolve("readFile", path)
+			if err != nil {
+				return "", err
+			}
+			b, err := os.ReadFile(resolved)
+			if err != nil {
+				return "", err
+			}
+			return string(b), nil
+		},
+		"fileExists": func(path string) (bool, error) {
+			_, resolved, err := resolve("fileExists", path)
+			if err != nil {
+				return false, err
+			}
+			_, err = os.Stat(resolved)
+			return err == nil, nil
+		},
+		// glob returns the matching files relative to the directory, sorted
+		// the same on every OS. The patterns starting with "!" exclude files,
+		// like in sources.
+		"glob": func(patterns ...string) ([]string, error) {
+			root := ""
+			for _, pattern := range patterns {
+				r, _, err := resolve("glob", strings.TrimPrefix(pattern, "!"))
+				if err != nil {
+					return nil, err
+				}
+				root = r
+			}
+			files, err := fingerprint.Globs(root, patterns)
+			if err != nil {
+				return nil, err
+			}
+			for i, file := range files {
+				if files[i], err = filepath.Rel(root, file); err != nil {
+					return nil, err
+				}
+			}
+			return files, nil
+		},
+	}
+}
+
+// dir returns the directory of the file functions: the Dir of the templater,
+// or the ROOT_DIR of its variables.
+func (r *Templater) dir() string {
+	if r.Dir != "" {
+		return r.Dir
+	}
+	if r.Vars != nil && r.Vars.Exists("ROOT_DIR") {
+		return r.Vars.Get("ROOT_DIR").Static
+	}
+	return ""
+}
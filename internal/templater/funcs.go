@@ -1,18 +1,31 @@
 package templater
 
 import (
+	"fmt"
 	"path/filepath"
 	"runtime"
 	"strings"
 	"text/template"
 
+	"github.com/Masterminds/semver/v3"
 	sprig "github.com/go-task/slim-sprig"
 	"github.com/nuvolaris/sh/v3/shell"
 	"github.com/nuvolaris/sh/v3/syntax"
+	"github.com/pelletier/go-toml"
+	"gopkg.in/yaml.v3"
 )
 
 var templateFuncs template.FuncMap
 
+// DeprecatedFuncs are the functions that still work but will be removed, and
+// the ones to use instead, if any.
+var DeprecatedFuncs = map[string]string{
+	"IsSH":      "",
+	"FromSlash": "fromSlash",
+	"ToSlash":   "toSlash",
+	"ExeExt":    "exeExt",
+}
+
 func init() {
 	taskFuncs := template.FuncMap{
 		"OS":   func() string { return runtime.GOOS },
@@ -51,14 +64,114 @@ func init() {
 		"relPath": func(basePath, targetPath string) (string, error) {
 			return filepath.Rel(basePath, targetPath)
 		},
+		"uuid":          newUUID,
+		"randomInt":     randomInt,
+		"randAlphaNum":  randAlphaNum,
+		"now":           now,
+		"dateInZone":    dateInZone,
+		"semverCompare": semverCompare,
+		"semverBump":    semverBump,
+		"trimV": func(version string) string {
+			return strings.TrimPrefix(version, "v")
+		},
+		"toYaml":   toYaml,
+		"fromYaml": fromYaml,
+		"toToml":   toToml,
+		"fromToml": fromToml,
 	}
 	// Deprecated aliases for renamed functions.
-	taskFuncs["FromSlash"] = taskFuncs["fromSlash"]
-	taskFuncs["ToSlash"] = taskFuncs["toSlash"]
-	taskFuncs["ExeExt"] = taskFuncs["exeExt"]
+	for deprecated, replacement := range DeprecatedFuncs {
+		if replacement != "" {
+			taskFuncs[deprecated] = taskFuncs[replacement]
+		}
+	}
 
 	templateFuncs = sprig.TxtFuncMap()
 	for k, v := range taskFuncs {
 		templateFuncs[k] = v
 	}
+	// Templaters replace them with the ones of their directory
+	for k, v := range fileFuncs("") {
+		templateFuncs[k] = v
+	}
+	for k, v := range gitFuncs("") {
+		templateFuncs[k] = v
+	}
+}
+
+// semverCompare tells whether the version meets the constraint, like ">= 1.2"
+// or "^2.0.0".
+func semverCompare(constraint, version string) (bool, error) {
+	c, err := semver.NewConstraint(constraint)
+	if err != nil {
+		return false, err
+	}
+	v, err := semver.NewVersion(version)
+	if err != nil {
+		return false, err
+	}
+	return c.Check(v), nil
+}
+
+// semverBump increments the major, minor or patch part of the version,
+// keeping its "v" prefix if it has one.
+func semverBump(part, version string) (string, error) {
+	v, err := semver.NewVersion(version)
+	if err != nil {
+		return "", err
+	}
+	var bumped semver.Version
+	switch part {
+	case "major":
+		bumped = v.IncMajor()
+	case "minor":
+		bumped = v.IncMinor()
+	case "patch":
+		bumped = v.IncPatch()
+	default:
+		return "", fmt.Errorf(`task: semverBump can't bump %q, only "major", "minor" or "patch"`, part)
+	}
+	if strings.HasPrefix(version, "v") {
+		return "v" + bumped.String(), nil
+	}
+	return bumped.String(), nil
+}
+
+// toYaml encodes the value as YAML, without the trailing newline so it can be
+// used inline.
+func toYaml(v any) (string, error) {
+	b, err := yaml.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSuffix(string(b), "\n"), nil
+}
+
+func fromYaml(s string) (any, error) {
+	var v any
+	if err := yaml.Unmarshal([]byte(s), &v); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// toToml encodes a map as TOML, since a TOML document is always a table.
+func toToml(v any) (string, error) {
+	m, ok := v.(map[string]any)
+	if !ok {
+		return "", fmt.Errorf("task: toToml can only encode maps, not %T", v)
+	}
+	tree, err := toml.TreeFromMap(m)
+	if err != nil {
+		return "", err
+	}
+	return tree.ToTomlString()
+}
+
+func fromToml(s string) (map[string]any, error) {
+	tree, err := toml.Load(s)
+	if err != nil {
+		return nil, err
+	}
+	return tree.ToMap(), nil
 }
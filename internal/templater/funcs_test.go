@@ -0,0 +1,161 @@
+package templater_test
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nuvolaris/task/v3/internal/templater"
+	"github.com/nuvolaris/task/v3/taskfile"
+)
+
+func TestVersionAndConfigFuncs(t *testing.T) {
+	vars := &taskfile.Vars{}
+	vars.Set("VERSION", taskfile.Var{Static: "v1.4.2"})
+	vars.Set("CONFIG", taskfile.Var{Static: "name: app\nports: [80, 443]\n"})
+	vars.Set("TOML", taskfile.Var{Static: "[server]\nport = 8080\n"})
+
+	tests := []struct {
+		template string
+		expected string
+	}{
+		{`{{semverCompare ">= 1.4" .VERSION}}`, "true"},
+		{`{{semverCompare "^2" .VERSION}}`, "false"},
+		{`{{semverBump "minor" .VERSION}}`, "v1.5.0"},
+		{`{{semverBump "major" "2.3.4"}}`, "3.0.0"},
+		{`{{semverBump "patch" .VERSION | trimV}}`, "1.4.3"},
+		{`{{(fromYaml .CONFIG).name}}`, "app"},
+		{`{{(fromYaml .CONFIG).ports | toYaml}}`, "- 80\n- 443"},
+		{`{{(fromToml .TOML).server.port}}`, "8080"},
+		{`{{fromYaml .CONFIG | toToml}}`, "name = \"app\"\nports = [80, 443]\n"},
+	}
+	for _, test := range tests {
+		r := templater.Templater{Vars: vars}
+		assert.Equal(t, test.expected, r.Replace(test.template), test.template)
+		require.NoError(t, r.Err(), test.template)
+	}
+
+	r := templater.Templater{Vars: vars}
+	r.Replace(`{{semverBump "build" .VERSION}}`)
+	assert.ErrorContains(t, r.Err(), `semverBump can't bump "build"`)
+}
+
+func TestFileFuncs(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "src", "pkg"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "VERSION"), []byte("1.2.3\n"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "src", "b.go"), nil, 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "src", "a.go"), nil, 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "src", "a_test.go"), nil, 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "src", "pkg", "c.go"), nil, 0o644))
+
+	tests := []struct {
+		template string
+		expected string
+	}{
+		{`{{readFile "VERSION" | trim}}`, "1.2.3"},
+		{`{{fileExists "src/a.go"}}`, "true"},
+		{`{{fileExists "src/nope.go"}}`, "false"},
+		{`{{glob "src/*.go" | join " "}}`, filepath.Join("src", "a.go") + " " + filepath.Join("src", "a_test.go") + " " + filepath.Join("src", "b.go")},
+		{`{{glob "src/**/*.go" "!src/*_test.go" | len}}`, "3"},
+	}
+	for _, test := range tests {
+		r := templater.Templater{Vars: &taskfile.Vars{}, Dir: dir}
+		assert.Equal(t, test.expected, r.Replace(test.template), test.template)
+		require.NoError(t, r.Err(), test.template)
+	}
+
+	// The directory defaults to ROOT_DIR
+	vars := &taskfile.Vars{}
+	vars.Set("ROOT_DIR", taskfile.Var{Static: dir})
+	r := templater.Templater{Vars: vars}
+	assert.Equal(t, "1.2.3", r.Replace(`{{readFile "VERSION" | trim}}`))
+	require.NoError(t, r.Err())
+
+	for _, template := range []string{
+		`{{readFile "../secret"}}`,
+		`{{fileExists "/etc/passwd"}}`,
+		`{{glob "../*"}}`,
+	} {
+		r := templater.Templater{Vars: &taskfile.Vars{}, Dir: dir}
+		r.Replace(template)
+		assert.ErrorContains(t, r.Err(), "which is outside of", template)
+	}
+}
+
+func TestRandomFuncs(t *testing.T) {
+	render := func(template string) string {
+		r := templater.Templater{Vars: &taskfile.Vars{}}
+		s := r.Replace(template)
+		require.NoError(t, r.Err())
+		return s
+	}
+	const template = `{{uuid}} {{randomInt 1 100}} {{randAlphaNum 8}}`
+
+	seed := int64(42)
+	templater.SetSeed(&seed)
+	first := render(template)
+	assert.Regexp(t, `^[0-9a-f-]{36} [0-9]+ [a-zA-Z0-9]{8}$`, first)
+	assert.Equal(t, render(`{{now.UnixNano}}`), render(`{{now.UnixNano}}`))
+	templater.SetSeed(&seed)
+	assert.Equal(t, first, render(template))
+
+	templater.SetSeed(nil)
+	assert.NotEqual(t, render(template), render(template))
+
+	t.Setenv("SOURCE_DATE_EPOCH", "1700000000")
+	r := templater.Templater{Vars: &taskfile.Vars{}}
+	assert.Equal(t, "2023-11-14 22:13", r.Replace(`{{dateInZone "2006-01-02 15:04" now "UTC"}}`))
+	assert.Equal(t, "2023-11-15 07:13", r.Replace(`{{dateInZone "2006-01-02 15:04" 1700000000 "Asia/Tokyo"}}`))
+	require.NoError(t, r.Err())
+
+	r.Replace(`{{randomInt 5 5}}`)
+	assert.ErrorContains(t, r.Err(), "needs a min lower than max")
+}
+
+func TestGitFuncs(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	dir := t.TempDir()
+	git := func(args ...string) {
+		cmd := exec.Command("git", append([]string{"-c", "user.name=Task", "-c", "user.email=task@example.com"}, args...)...)
+		cmd.Dir = dir
+		out, err := cmd.CombinedOutput()
+		require.NoError(t, err, string(out))
+	}
+	git("init", "-q", "-b", "main")
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "README"), []byte("1"), 0o644))
+	git("add", "README")
+	git("commit", "-q", "-m", "First")
+	git("tag", "v1.0.0")
+
+	render := func(template string) string {
+		templater.ClearGitCache()
+		r := templater.Templater{Vars: &taskfile.Vars{}, Dir: dir}
+		s := r.Replace(template)
+		require.NoError(t, r.Err(), template)
+		return s
+	}
+	assert.Regexp(t, `^[0-9a-f]{7,}$`, render(`{{gitCommit}}`))
+	assert.Equal(t, "main v1.0.0 false", render(`{{gitBranch}} {{gitTag}} {{gitDirty}}`))
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "README"), []byte("2"), 0o644))
+	git("checkout", "-q", "--detach")
+	assert.Equal(t, " v1.0.0 true", render(`{{gitBranch}} {{gitTag}} {{gitDirty}}`))
+
+	templater.ClearGitCache()
+	r := templater.Templater{Vars: &taskfile.Vars{}, Dir: t.TempDir()}
+	r.Replace(`{{gitCommit}}`)
+	assert.ErrorContains(t, r.Err(), "gitCommit failed")
+}
+
+func TestFuncNames(t *testing.T) {
+	tmpl, err := templater.Parse(`{{if IsSH}}{{range .LIST}}{{FromSlash .}}{{end}}{{end}}{{.DIR | joinPath "a"}}`)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"IsSH", "FromSlash", "joinPath"}, templater.FuncNames(tmpl))
+}
@@ -0,0 +1,92 @@
+package templater
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"text/template"
+)
+
+// gitCache keeps the output of the git commands, by directory and command,
+// so a git function is run once per run instead of once per variable.
+var gitCache sync.Map
+
+type gitResult struct {
+	once sync.Once
+	out  string
+	err  error
+}
+
+// gitFuncs returns the functions giving the git metadata of the repository
+// of the given directory. The current directory is used when dir is empty.
+func gitFuncs(dir string) template.FuncMap {
+	return template.FuncMap{
+		"gitCommit": func() (string, error) {
+			return git(dir, "gitCommit", "rev-parse", "--short", "HEAD")
+		},
+		// gitBranch is empty when HEAD is detached, like in most CIs
+		"gitBranch": func() (string, error) {
+			branch, err := git(dir, "gitBranch", "rev-parse", "--abbrev-ref", "HEAD")
+			if branch == "HEAD" {
+				return "", err
+			}
+			return branch, err
+		},
+		// gitTag is empty when no tag points to HEAD
+		"gitTag": func() (string, error) {
+			tags, err := git(dir, "gitTag", "tag", "--points-at", "HEAD", "--sort=-version:refname")
+			tag, _, _ := strings.Cut(tags, "\n")
+			return tag, err
+		},
+		"gitDirty": func() (bool, error) {
+			status, err := git(dir, "gitDirty", "status", "--porcelain")
+			return status != "", err
+		},
+	}
+}
+
+func git(dir, funcName string, args ...string) (string, error) {
+	if dir == "" {
+		var err error
+		if dir, err = os.Getwd(); err != nil {
+			return "", err
+		}
+	}
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", err
+	}
+	key := dir + "\x00" + strings.Join(args, "\x00")
+	v, _ := gitCache.LoadOrStore(key, &gitResult{})
+	result := v.(*gitResult)
+	result.once.Do(func() {
+		var stdout, stderr bytes.Buffer
+		cmd := exec.Command("git", args...)
+		cmd.Dir = dir
+		cmd.Stdout = &stdout
+		cmd.Stderr = &stderr
+		if err := cmd.Run(); err != nil {
+			msg := strings.TrimSpace(stderr.String())
+			if msg == "" {
+				msg = err.Error()
+			}
+			result.err = fmt.Errorf("task: %s failed in %s: %s", funcName, dir, msg)
+			return
+		}
+		result.out = strings.TrimSpace(stdout.String())
+	})
+	return result.out, result.err
+}
+
+// ClearGitCache makes the git functions run git again, like when the watcher
+// runs the tasks again after a change.
+func ClearGitCache() {
+	gitCache.Range(func(key, _ any) bool {
+		gitCache.Delete(key)
+		return true
+	})
+}
@@ -0,0 +1,126 @@
+package templater
+
+import (
+	"fmt"
+	"math/rand"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// The functions returning random values and the time use a seeded source
+// when the Taskfile sets a seed, so the same Taskfile renders the same vars,
+// labels and checksums on every run.
+var random = struct {
+	mu     sync.Mutex
+	rand   *rand.Rand
+	seeded bool
+	now    time.Time
+}{rand: rand.New(rand.NewSource(time.Now().UnixNano()))}
+
+// SetSeed makes uuid, randomInt, randAlphaNum and now deterministic. A nil
+// seed makes them random again.
+func SetSeed(seed *int64) {
+	random.mu.Lock()
+	defer random.mu.Unlock()
+	random.seeded = seed != nil
+	random.now = time.Time{}
+	if seed != nil {
+		random.rand = rand.New(rand.NewSource(*seed))
+	} else {
+		random.rand = rand.New(rand.NewSource(time.Now().UnixNano()))
+	}
+}
+
+func withRand[T any](f func(r *rand.Rand) T) T {
+	random.mu.Lock()
+	defer random.mu.Unlock()
+	return f(random.rand)
+}
+
+func newUUID() (string, error) {
+	random.mu.Lock()
+	defer random.mu.Unlock()
+	if !random.seeded {
+		return uuid.NewString(), nil
+	}
+	id, err := uuid.NewRandomFromReader(random.rand)
+	return id.String(), err
+}
+
+// randomInt returns a number in [min, max).
+func randomInt(min, max int) (int, error) {
+	if min >= max {
+		return 0, fmt.Errorf("task: randomInt needs a min lower than max, got %d and %d", min, max)
+	}
+	return withRand(func(r *rand.Rand) int {
+		return min + r.Intn(max-min)
+	}), nil
+}
+
+const alphaNum = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
+
+func randAlphaNum(n int) string {
+	return withRand(func(r *rand.Rand) string {
+		b := make([]byte, n)
+		for i := range b {
+			b[i] = alphaNum[r.Intn(len(alphaNum))]
+		}
+		return string(b)
+	})
+}
+
+// now follows SOURCE_DATE_EPOCH, like the reproducible builds, and is frozen
+// at its first call when the Taskfile sets a seed.
+func now() (time.Time, error) {
+	if epoch := os.Getenv("SOURCE_DATE_EPOCH"); epoch != "" {
+		seconds, err := strconv.ParseInt(epoch, 10, 64)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("task: SOURCE_DATE_EPOCH must be a number of seconds, got %q", epoch)
+		}
+		return time.Unix(seconds, 0).UTC(), nil
+	}
+	random.mu.Lock()
+	defer random.mu.Unlock()
+	if !random.seeded {
+		return time.Now(), nil
+	}
+	if random.now.IsZero() {
+		random.now = time.Now()
+	}
+	return random.now, nil
+}
+
+// dateInZone formats a time, a Unix timestamp or an RFC 3339 string in the
+// given time zone. Other values are the current time, like in sprig.
+func dateInZone(format string, date any, zone string) (string, error) {
+	var t time.Time
+	switch date := date.(type) {
+	case time.Time:
+		t = date
+	case *time.Time:
+		t = *date
+	case int:
+		t = time.Unix(int64(date), 0)
+	case int64:
+		t = time.Unix(date, 0)
+	case string:
+		var err error
+		if t, err = time.Parse(time.RFC3339, date); err != nil {
+			return "", fmt.Errorf("task: dateInZone needs an RFC 3339 date, got %q", date)
+		}
+	default:
+		var err error
+		if t, err = now(); err != nil {
+			return "", err
+		}
+	}
+	loc, err := time.LoadLocation(zone)
+	if err != nil {
+		return "", fmt.Errorf("task: Unknown time zone %q", zone)
+	}
+	return t.In(loc).Format(format), nil
+}
@@ -6,6 +6,7 @@ import (
 	"text/template"
 
 	"golang.org/x/exp/maps"
+	"golang.org/x/exp/slices"
 
 	"github.com/nuvolaris/task/v3/taskfile"
 )
@@ -17,11 +18,23 @@ import (
 type Templater struct {
 	Vars          *taskfile.Vars
 	RemoveNoValue bool
+	// Dir is the directory read by the file functions, like readFile.
+	// Defaults to the ROOT_DIR variable, or the current directory.
+	Dir string
+	// ResolveLazy runs the lazy dynamic variables used by the templates.
+	// When nil, they have no value.
+	ResolveLazy func(v taskfile.Var) (string, error)
 
 	cacheMap map[string]any
 	err      error
 }
 
+// Parse parses a template with the functions available to the Taskfiles,
+// without executing it.
+func Parse(str string) (*template.Template, error) {
+	return template.New("").Funcs(templateFuncs).Parse(str)
+}
+
 func (r *Templater) ResetCache() {
 	r.cacheMap = r.Vars.ToCacheMap()
 }
@@ -39,11 +52,14 @@ func (r *Templater) replace(str string, extra map[string]any) string {
 		return ""
 	}
 
-	templ, err := template.New("").Funcs(templateFuncs).Parse(str)
+	templ, err := template.New("").Funcs(templateFuncs).Funcs(fileFuncs(r.dir())).Funcs(gitFuncs(r.dir())).Parse(str)
 	if err != nil {
 		r.err = err
 		return ""
 	}
+	if r.resolveLazyVars(VarNames(templ)); r.err != nil {
+		return ""
+	}
 
 	if r.cacheMap == nil {
 		r.cacheMap = r.Vars.ToCacheMap()
@@ -95,10 +111,17 @@ func (r *Templater) replaceVars(vars *taskfile.Vars, extra map[string]any) *task
 
 	var new taskfile.Vars
 	_ = vars.Range(func(k string, v taskfile.Var) error {
+		if v.Ref != "" {
+			new.Set(k, r.resolveRef(v.Ref, extra))
+			return nil
+		}
 		new.Set(k, taskfile.Var{
-			Static: r.ReplaceWithExtra(v.Static, extra),
-			Live:   v.Live,
-			Sh:     r.ReplaceWithExtra(v.Sh, extra),
+			Static:  r.ReplaceWithExtra(v.Static, extra),
+			Live:    v.Live,
+			Sh:      r.ReplaceWithExtra(v.Sh, extra),
+			Prompt:  r.ReplaceWithExtra(v.Prompt, extra),
+			Default: r.ReplaceWithExtra(v.Default, extra),
+			Secret:  v.Secret,
 		})
 		return nil
 	})
@@ -106,6 +129,56 @@ func (r *Templater) replaceVars(vars *taskfile.Vars, extra map[string]any) *task
 	return &new
 }
 
+// ResolveRef returns the value of the referenced variable. The value is
+// returned as a live value, so it's never templated again.
+func (r *Templater) ResolveRef(ref string) taskfile.Var {
+	return r.resolveRef(ref, nil)
+}
+
+func (r *Templater) resolveRef(ref string, extra map[string]any) taskfile.Var {
+	if r.err != nil {
+		return taskfile.Var{}
+	}
+	if r.cacheMap == nil {
+		r.cacheMap = r.Vars.ToCacheMap()
+	}
+	if v, ok := extra[ref]; ok {
+		return taskfile.Var{Live: v}
+	}
+	if r.resolveLazyVars([]string{ref}); r.err != nil {
+		return taskfile.Var{}
+	}
+	if v, ok := r.cacheMap[ref]; ok {
+		return taskfile.Var{Live: v}
+	}
+	return taskfile.Var{}
+}
+
+// resolveLazyVars runs the lazy dynamic variables among the ones used by a
+// template, so they have a value when it's executed.
+func (r *Templater) resolveLazyVars(used []string) {
+	if r.ResolveLazy == nil || r.Vars == nil {
+		return
+	}
+	var names []string
+	for _, k := range used {
+		if v := r.Vars.Get(k); v.Lazy && v.Sh != "" && !slices.Contains(names, k) {
+			names = append(names, k)
+		}
+	}
+	for _, k := range names {
+		value, err := r.ResolveLazy(r.Vars.Get(k))
+		if err != nil {
+			r.err = err
+			return
+		}
+		r.Vars.Set(k, taskfile.Var{Static: value})
+		if r.cacheMap != nil {
+			r.cacheMap[k] = value
+		}
+	}
+}
+
 func (r *Templater) Err() error {
 	return r.err
 }
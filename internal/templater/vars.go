@@ -0,0 +1,114 @@
+package templater
+
+import (
+	"text/template"
+	"text/template/parse"
+)
+
+// VarNames returns the variables used by a template, like NAME in {{.NAME}},
+// in the order they are used and possibly repeated. The fields used inside
+// "range" and "with" are skipped, since the dot isn't the variables there.
+func VarNames(tmpl *template.Template) []string {
+	if tmpl == nil || tmpl.Tree == nil {
+		return nil
+	}
+	var names []string
+	varNames(tmpl.Tree.Root, &names)
+	return names
+}
+
+func varNames(node parse.Node, names *[]string) {
+	switch node := node.(type) {
+	case *parse.ListNode:
+		if node == nil {
+			return
+		}
+		for _, n := range node.Nodes {
+			varNames(n, names)
+		}
+	case *parse.ActionNode:
+		varNames(node.Pipe, names)
+	case *parse.PipeNode:
+		if node == nil {
+			return
+		}
+		for _, cmd := range node.Cmds {
+			varNames(cmd, names)
+		}
+	case *parse.CommandNode:
+		for _, arg := range node.Args {
+			varNames(arg, names)
+		}
+	case *parse.ChainNode:
+		varNames(node.Node, names)
+	case *parse.FieldNode:
+		*names = append(*names, node.Ident[0])
+	case *parse.IfNode:
+		varNames(node.Pipe, names)
+		varNames(node.List, names)
+		varNames(node.ElseList, names)
+	case *parse.RangeNode:
+		varNames(node.Pipe, names)
+		varNames(node.ElseList, names)
+	case *parse.WithNode:
+		varNames(node.Pipe, names)
+		varNames(node.ElseList, names)
+	case *parse.TemplateNode:
+		varNames(node.Pipe, names)
+	}
+}
+
+// FuncNames returns the functions called by a template, like joinPath in
+// {{joinPath .DIR "file"}}, in the order they are called and possibly
+// repeated.
+func FuncNames(tmpl *template.Template) []string {
+	if tmpl == nil || tmpl.Tree == nil {
+		return nil
+	}
+	var names []string
+	funcNames(tmpl.Tree.Root, &names)
+	return names
+}
+
+func funcNames(node parse.Node, names *[]string) {
+	switch node := node.(type) {
+	case *parse.ListNode:
+		if node == nil {
+			return
+		}
+		for _, n := range node.Nodes {
+			funcNames(n, names)
+		}
+	case *parse.ActionNode:
+		funcNames(node.Pipe, names)
+	case *parse.PipeNode:
+		if node == nil {
+			return
+		}
+		for _, cmd := range node.Cmds {
+			funcNames(cmd, names)
+		}
+	case *parse.CommandNode:
+		for _, arg := range node.Args {
+			funcNames(arg, names)
+		}
+	case *parse.ChainNode:
+		funcNames(node.Node, names)
+	case *parse.IdentifierNode:
+		*names = append(*names, node.Ident)
+	case *parse.IfNode:
+		funcNames(node.Pipe, names)
+		funcNames(node.List, names)
+		funcNames(node.ElseList, names)
+	case *parse.RangeNode:
+		funcNames(node.Pipe, names)
+		funcNames(node.List, names)
+		funcNames(node.ElseList, names)
+	case *parse.WithNode:
+		funcNames(node.Pipe, names)
+		funcNames(node.List, names)
+		funcNames(node.ElseList, names)
+	case *parse.TemplateNode:
+		funcNames(node.Pipe, names)
+	}
+}
@@ -0,0 +1,35 @@
+//go:build darwin || dragonfly || freebsd || linux || netbsd || openbsd
+
+package term
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+// Cbreak makes the given terminal send every key as soon as it's pressed,
+// without echoing it. Unlike the raw mode, the output is still processed and
+// keys like Ctrl+C still send signals. The returned function restores the
+// terminal.
+func Cbreak(r io.Reader) (func(), error) {
+	f, ok := r.(*os.File)
+	if !ok {
+		return nil, fmt.Errorf("task: can't read keys from a %T", r)
+	}
+	fd := int(f.Fd())
+	termios, err := unix.IoctlGetTermios(fd, ioctlReadTermios)
+	if err != nil {
+		return nil, err
+	}
+	old := *termios
+	termios.Lflag &^= unix.ICANON | unix.ECHO
+	termios.Cc[unix.VMIN] = 1
+	termios.Cc[unix.VTIME] = 0
+	if err := unix.IoctlSetTermios(fd, ioctlWriteTermios, termios); err != nil {
+		return nil, err
+	}
+	return func() { _ = unix.IoctlSetTermios(fd, ioctlWriteTermios, &old) }, nil
+}
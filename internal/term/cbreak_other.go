@@ -0,0 +1,14 @@
+//go:build !darwin && !dragonfly && !freebsd && !linux && !netbsd && !openbsd
+
+package term
+
+import (
+	"errors"
+	"io"
+)
+
+// Cbreak is not supported on this OS, so the keys are only sent once Enter is
+// pressed.
+func Cbreak(r io.Reader) (func(), error) {
+	return nil, errors.New("task: keys can't be read as they're pressed on this OS")
+}
@@ -1,6 +1,8 @@
 package term
 
 import (
+	"fmt"
+	"io"
 	"os"
 
 	"golang.org/x/term"
@@ -9,3 +11,25 @@ import (
 func IsTerminal() bool {
 	return term.IsTerminal(int(os.Stdin.Fd())) && term.IsTerminal(int(os.Stdout.Fd()))
 }
+
+// IsTerminalWriter returns true if the given writer is a terminal.
+func IsTerminalWriter(w io.Writer) bool {
+	f, ok := w.(*os.File)
+	return ok && term.IsTerminal(int(f.Fd()))
+}
+
+// IsTerminalReader returns true if the given reader is a terminal.
+func IsTerminalReader(r io.Reader) bool {
+	f, ok := r.(*os.File)
+	return ok && term.IsTerminal(int(f.Fd()))
+}
+
+// ReadPassword reads a line from the given terminal without echoing it.
+func ReadPassword(r io.Reader) (string, error) {
+	f, ok := r.(*os.File)
+	if !ok {
+		return "", fmt.Errorf("task: can't read a password from a %T", r)
+	}
+	b, err := term.ReadPassword(int(f.Fd()))
+	return string(b), err
+}
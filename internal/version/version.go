@@ -5,7 +5,13 @@ import (
 	"runtime/debug"
 )
 
-var version = ""
+// These are set at build time with -ldflags "-X ...". Without them, the
+// commit and the build date are read from the VCS information embedded by Go.
+var (
+	version = ""
+	commit  = ""
+	date    = ""
+)
 
 func GetVersion() string {
 	if version != "" {
@@ -23,3 +29,34 @@ func GetVersion() string {
 	}
 	return ver
 }
+
+// GetCommit returns the commit Task was built from, or an empty string if
+// it's unknown.
+func GetCommit() string {
+	if commit != "" {
+		return commit
+	}
+	return buildSetting("vcs.revision")
+}
+
+// GetDate returns when Task was built, or when its commit was made if the
+// build date wasn't set, or an empty string if it's unknown.
+func GetDate() string {
+	if date != "" {
+		return date
+	}
+	return buildSetting("vcs.time")
+}
+
+func buildSetting(key string) string {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return ""
+	}
+	for _, s := range info.Settings {
+		if s.Key == key {
+			return s.Value
+		}
+	}
+	return ""
+}
@@ -0,0 +1,338 @@
+package task
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+
+	"github.com/nuvolaris/sh/v3/syntax"
+
+	"github.com/nuvolaris/task/v3/internal/filepathext"
+	"github.com/nuvolaris/task/v3/internal/templater"
+	"github.com/nuvolaris/task/v3/taskfile"
+)
+
+// The rules checked by Lint.
+const (
+	LintUnusedVar               = "unused-var"
+	LintGeneratesWithoutSources = "generates-without-sources"
+	LintUnreachableTask         = "unreachable-task"
+	LintShadowedEnv             = "shadowed-env"
+	LintInternalDep             = "internal-dep"
+	LintNonPortableShell        = "non-portable-shell"
+)
+
+// A LintIssue is a likely mistake found in a Taskfile by Lint.
+type LintIssue struct {
+	Rule string
+	// Task is the task the issue is about, if any
+	Task    string
+	Message string
+	// Location is where the issue is, or the Taskfile it's in when the exact
+	// line isn't known
+	Location *taskfile.Location
+}
+
+func (issue LintIssue) String() string {
+	var where string
+	if loc := issue.Location; loc != nil && loc.Taskfile != "" {
+		where = filepathext.TryAbsToRel(loc.Taskfile)
+		if loc.Line > 0 {
+			where += fmt.Sprintf(":%d:%d", loc.Line, loc.Column)
+		}
+		where += ": "
+	}
+	return fmt.Sprintf("%s%s (%s)", where, issue.Message, issue.Rule)
+}
+
+// Lint looks for the common mistakes of the Taskfile and its includes, like
+// vars nobody uses or internal tasks nobody calls, and returns them in the
+// order of the tasks. They aren't errors: the Taskfile still runs.
+func (e *Executor) Lint() ([]LintIssue, error) {
+	if err := e.setupIfNeeded(context.Background()); err != nil {
+		return nil, err
+	}
+	root := &taskfile.Location{Taskfile: e.Taskfile.Location}
+
+	var issues []LintIssue
+	issues = append(issues, e.lintUnusedVars(root)...)
+	issues = append(issues, e.lintShadowedEnv("", root, e.Taskfile.Env)...)
+	called, ok := e.calledTasks()
+	for _, t := range e.Taskfile.Tasks.Values() {
+		loc := t.Location
+		if loc == nil {
+			loc = root
+		}
+
+		issues = append(issues, e.lintUnusedTaskVars(t, loc)...)
+		if len(t.Generates) > 0 && len(t.Sources) == 0 {
+			issues = append(issues, LintIssue{
+				Rule:     LintGeneratesWithoutSources,
+				Task:     t.Task,
+				Message:  fmt.Sprintf("task %q has generates but no sources, which are needed for them to be checked", t.Task),
+				Location: loc,
+			})
+		}
+		if ok && t.Internal && !called[t.Task] {
+			issues = append(issues, LintIssue{
+				Rule:     LintUnreachableTask,
+				Task:     t.Task,
+				Message:  fmt.Sprintf("task %q is internal, but no task calls it", t.Task),
+				Location: loc,
+			})
+		}
+		issues = append(issues, e.lintShadowedEnv(t.Task, loc, t.Env)...)
+		if !t.Internal {
+			issues = append(issues, e.lintInternalDeps(t, loc)...)
+		}
+		issues = append(issues, lintNonPortableCmds(t, loc)...)
+	}
+	return issues, nil
+}
+
+// lintUnusedVars finds the vars of the Taskfile that no template uses, in its
+// tasks or in its includes and dotenv files.
+func (e *Executor) lintUnusedVars(loc *taskfile.Location) []LintIssue {
+	if e.Taskfile.Vars == nil {
+		return nil
+	}
+	values := []any{e.Taskfile.Includes, e.Taskfile.Dotenv}
+	used := usedVarNames(e.Taskfile.Tasks.Values(), values, e.Taskfile.Vars, e.Taskfile.Env)
+	var issues []LintIssue
+	for _, name := range e.Taskfile.Vars.Keys() {
+		if !used[name] {
+			issues = append(issues, LintIssue{
+				Rule:     LintUnusedVar,
+				Message:  fmt.Sprintf("var %q of the Taskfile is never used", name),
+				Location: loc,
+			})
+		}
+	}
+	return issues
+}
+
+// lintUnusedTaskVars finds the vars of the task that no template of the task,
+// or of the tasks extending it, uses. They can't be used by the tasks it
+// calls, which only get the vars of their call.
+func (e *Executor) lintUnusedTaskVars(t *taskfile.Task, loc *taskfile.Location) []LintIssue {
+	if t.Vars == nil {
+		return nil
+	}
+	used := usedVarNames(append([]*taskfile.Task{t}, e.extendingTasks(t.Task)...), nil)
+	var issues []LintIssue
+	for _, name := range t.Vars.Keys() {
+		if !used[name] {
+			issues = append(issues, LintIssue{
+				Rule:     LintUnusedVar,
+				Task:     t.Task,
+				Message:  fmt.Sprintf("var %q of task %q is never used", name, t.Task),
+				Location: loc,
+			})
+		}
+	}
+	return issues
+}
+
+// usedVarNames returns the vars used by the templates and references found
+// anywhere in the tasks, the values and the vars, and the ones required by
+// the tasks.
+func usedVarNames(tasks []*taskfile.Task, values []any, vars ...*taskfile.Vars) map[string]bool {
+	used := map[string]bool{}
+	var templates []string
+	visited := map[uintptr]bool{}
+	for _, v := range values {
+		findTemplates(reflect.ValueOf(v), visited, &templates)
+	}
+	for _, t := range tasks {
+		findTemplates(reflect.ValueOf(t), visited, &templates)
+		vars = append(vars, t.Vars, t.Env)
+		if t.Requires != nil {
+			for _, name := range t.Requires.Vars {
+				used[name] = true
+			}
+		}
+	}
+	for _, vs := range vars {
+		findTemplates(reflect.ValueOf(vs), visited, &templates)
+		_ = vs.Range(func(_ string, v taskfile.Var) error {
+			if v.Ref != "" {
+				templates = append(templates, "{{"+v.Ref+"}}")
+			}
+			return nil
+		})
+	}
+
+	for _, str := range templates {
+		tmpl, err := templater.Parse(str)
+		if err != nil {
+			continue
+		}
+		for _, name := range templater.VarNames(tmpl) {
+			used[name] = true
+		}
+	}
+	return used
+}
+
+// extendingTasks returns the tasks that extend the given one, directly or
+// through another task.
+func (e *Executor) extendingTasks(name string) []*taskfile.Task {
+	var tasks []*taskfile.Task
+	for _, t := range e.Taskfile.Tasks.Values() {
+		if t.Extends == name {
+			tasks = append(tasks, t)
+			tasks = append(tasks, e.extendingTasks(t.Task)...)
+		}
+	}
+	return tasks
+}
+
+// calledTasks returns the tasks called by the others, or by the hooks of the
+// Taskfile. It isn't ok when a call has a templated name, since it could be
+// any task.
+func (e *Executor) calledTasks() (called map[string]bool, ok bool) {
+	var names []string
+	for _, hooks := range [][]*taskfile.Dep{e.Taskfile.BeforeAll, e.Taskfile.AfterAll, e.Taskfile.BeforeEach, e.Taskfile.AfterEach} {
+		for _, d := range hooks {
+			if d != nil {
+				names = append(names, d.Task)
+			}
+		}
+	}
+	for _, t := range e.Taskfile.Tasks.Values() {
+		for _, d := range t.Deps {
+			if d != nil {
+				names = append(names, d.Task)
+			}
+		}
+		for _, stage := range t.Pipeline {
+			if stage == nil {
+				continue
+			}
+			for _, d := range stage.Tasks {
+				if d != nil {
+					names = append(names, d.Task)
+				}
+			}
+		}
+		for _, c := range t.Cmds {
+			if c != nil {
+				names = append(names, c.Task)
+			}
+		}
+		names = append(names, t.Extends)
+	}
+
+	called = map[string]bool{}
+	for _, name := range names {
+		if strings.Contains(name, "{{") {
+			return nil, false
+		}
+		if name == "" {
+			continue
+		}
+		if t, err := e.GetTask(taskfile.Call{Task: name}); err == nil {
+			called[t.Task] = true
+		}
+	}
+	return called, true
+}
+
+// lintShadowedEnv finds the env vars that are already set in the environment
+// of Task, which wins over them.
+func (e *Executor) lintShadowedEnv(task string, loc *taskfile.Location, vars *taskfile.Vars) []LintIssue {
+	if vars == nil {
+		return nil
+	}
+	var issues []LintIssue
+	for _, name := range vars.Keys() {
+		if _, ok := os.LookupEnv(name); !ok || !e.envPolicy.Allowed(name) {
+			continue
+		}
+		message := fmt.Sprintf("env var %q of the Taskfile is ignored, since it's already set in the environment", name)
+		if task != "" {
+			message = fmt.Sprintf("env var %q of task %q is ignored, since it's already set in the environment", name, task)
+		}
+		issues = append(issues, LintIssue{Rule: LintShadowedEnv, Task: task, Message: message, Location: loc})
+	}
+	return issues
+}
+
+// lintInternalDeps finds the deps of the task on tasks marked as internal in
+// another Taskfile, which are meant to be private to it. The tasks of a
+// Taskfile included as internal are meant to be called by the including one.
+func (e *Executor) lintInternalDeps(t *taskfile.Task, loc *taskfile.Location) []LintIssue {
+	var issues []LintIssue
+	for _, name := range depNames(t) {
+		dep, err := e.GetTask(taskfile.Call{Task: name})
+		if err != nil || !dep.Internal || dep.Location == nil || t.Location == nil {
+			continue
+		}
+		if dep.Location.Taskfile == t.Location.Taskfile || (dep.IncludedTaskfile != nil && dep.IncludedTaskfile.Internal) {
+			continue
+		}
+		issues = append(issues, LintIssue{
+			Rule:     LintInternalDep,
+			Task:     t.Task,
+			Message:  fmt.Sprintf("task %q depends on %q, which is internal to %s", t.Task, dep.Task, filepathext.TryAbsToRel(dep.Location.Taskfile)),
+			Location: loc,
+		})
+	}
+	return issues
+}
+
+// lintNonPortableCmds finds the commands of the task that use features of
+// Bash that POSIX shells don't have. Task runs them anyway, but they don't
+// work once copied to a script run by sh.
+func lintNonPortableCmds(t *taskfile.Task, loc *taskfile.Location) []LintIssue {
+	var issues []LintIssue
+	for _, c := range t.Cmds {
+		if c == nil || c.Cmd == "" {
+			continue
+		}
+		feature := nonPortableFeature(c.Cmd)
+		if feature == "" {
+			continue
+		}
+		cmdLoc := loc
+		if c.Location != nil && c.Location.Line > 0 {
+			cmdLoc = c.Location
+		}
+		issues = append(issues, LintIssue{
+			Rule:     LintNonPortableShell,
+			Task:     t.Task,
+			Message:  fmt.Sprintf("a command of task %q isn't portable: %s", t.Task, feature),
+			Location: cmdLoc,
+		})
+	}
+	return issues
+}
+
+// nonPortableFeature returns the first feature of the script that POSIX
+// shells don't have, if any. The scripts that don't parse at all, like the
+// ones only valid once their templates are compiled, are skipped.
+func nonPortableFeature(script string) string {
+	file, err := syntax.NewParser().Parse(strings.NewReader(script), "")
+	if err != nil {
+		return ""
+	}
+	if _, err := syntax.NewParser(syntax.Variant(syntax.LangPOSIX)).Parse(strings.NewReader(script), ""); err != nil {
+		// Strip the position of the error
+		msg := err.Error()
+		if _, feature, ok := strings.Cut(msg, ": "); ok {
+			return feature
+		}
+		return msg
+	}
+
+	var feature string
+	syntax.Walk(file, func(node syntax.Node) bool {
+		if _, ok := node.(*syntax.TestClause); ok && feature == "" {
+			feature = "[[ ]] tests are a bash/mksh feature"
+		}
+		return feature == ""
+	})
+	return feature
+}
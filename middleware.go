@@ -0,0 +1,44 @@
+package task
+
+import (
+	"context"
+
+	"github.com/nuvolaris/task/v3/taskfile"
+)
+
+// TaskRunner runs a single compiled task: its dependencies and its commands.
+type TaskRunner func(ctx context.Context, t *taskfile.Task, call taskfile.Call) error
+
+// Middleware wraps a TaskRunner to add behavior around the execution of every
+// task, like retries, locking, tracing or notifications. A middleware can run
+// logic before or after calling next, change its arguments or not call it at
+// all to skip the task.
+type Middleware func(next TaskRunner) TaskRunner
+
+// taskRunner returns the TaskRunner with the middlewares of Task itself, which
+// profile and trace the tasks, then the ones of the Executor applied. The
+// first middleware is the outermost one.
+func (e *Executor) taskRunner() TaskRunner {
+	middlewares := append([]Middleware{e.profileTask, e.traceTask}, e.Middlewares...)
+	runner := TaskRunner(e.executeTask)
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		runner = middlewares[i](runner)
+	}
+	return runner
+}
+
+// commandRunner runs a single command of a task.
+type commandRunner func(ctx context.Context, t *taskfile.Task, cmd *taskfile.Cmd) error
+
+// commandMiddleware wraps a commandRunner, like a Middleware does for tasks.
+type commandMiddleware func(next commandRunner) commandRunner
+
+// commandRunner returns the given commandRunner with the middlewares of Task
+// itself applied, which profile and trace the commands.
+func (e *Executor) commandRunner(runner commandRunner) commandRunner {
+	middlewares := []commandMiddleware{e.profileCommand, e.traceCommand}
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		runner = middlewares[i](runner)
+	}
+	return runner
+}
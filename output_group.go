@@ -0,0 +1,39 @@
+package task
+
+import (
+	"context"
+
+	"github.com/nuvolaris/task/v3/internal/logger"
+	"github.com/nuvolaris/task/v3/internal/output"
+	"github.com/nuvolaris/task/v3/internal/templater"
+	"github.com/nuvolaris/task/v3/taskfile"
+)
+
+type outputGroupKey struct{}
+
+// startOutputGroup starts the group of the task when the groups are nested,
+// inside the group of the task that called it. The returned function writes
+// the group once the task finished.
+func (e *Executor) startOutputGroup(ctx context.Context, t *taskfile.Task, call taskfile.Call) (context.Context, func(error)) {
+	if e.nestedGroup == nil {
+		return ctx, func(error) {}
+	}
+	vars, err := e.Compiler.FastGetVariables(ctx, t, call)
+	if err != nil {
+		e.Logger.VerboseErrf(logger.Yellow, "task: failed to get variables of the output group: %v\n", err)
+	}
+	tmpl := &templater.Templater{Vars: vars, RemoveNoValue: true}
+	node := e.nestedGroup.Nest(outputGroupFromContext(ctx), e.Stdout, t.Name(), tmpl)
+	return context.WithValue(ctx, outputGroupKey{}, node), func(err error) {
+		if closeErr := node.Close(err); closeErr != nil {
+			e.Logger.Errf(logger.Red, "task: unable to close writer: %v\n", closeErr)
+		}
+	}
+}
+
+// outputGroupFromContext returns the nested group the output of the task is
+// written to, if any.
+func outputGroupFromContext(ctx context.Context) *output.GroupNode {
+	node, _ := ctx.Value(outputGroupKey{}).(*output.GroupNode)
+	return node
+}
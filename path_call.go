@@ -0,0 +1,82 @@
+package task
+
+import (
+	"context"
+	"path"
+	"strings"
+	"sync"
+
+	"github.com/nuvolaris/task/v3/internal/filepathext"
+	"github.com/nuvolaris/task/v3/taskfile"
+	"github.com/nuvolaris/task/v3/taskfile/read"
+)
+
+// splitPathCall splits the name of a task called by the path of its
+// Taskfile, like "./services/api:deploy", into the namespace its Taskfile is
+// loaded under, which is the clean path, and the name of the task in it.
+func splitPathCall(name string) (namespace, task string, ok bool) {
+	if !strings.HasPrefix(name, "./") && !strings.HasPrefix(name, "../") {
+		return "", "", false
+	}
+	dir, task, _ := strings.Cut(name, taskfile.NamespaceSeparator)
+	switch dir = path.Clean(dir); {
+	case dir == ".":
+		dir = "./"
+	case dir != ".." && !strings.HasPrefix(dir, "../"):
+		dir = "./" + dir
+	}
+	return dir, task, true
+}
+
+// loadPathCalls loads the Taskfiles of the tasks called by path, and returns
+// the calls with the names of the tasks once loaded.
+func (e *Executor) loadPathCalls(ctx context.Context, calls []taskfile.Call) ([]taskfile.Call, error) {
+	loaded := make([]taskfile.Call, len(calls))
+	for i, call := range calls {
+		var err error
+		if loaded[i], err = e.loadPathCall(ctx, call); err != nil {
+			return nil, err
+		}
+	}
+	return loaded, nil
+}
+
+// loadPathCall loads the Taskfile of a task called by path, relative to the
+// directory Task was called from, unless it's already loaded. Its tasks are
+// merged into the Taskfile like the ones of an include, with the path as
+// namespace, and run in its directory.
+func (e *Executor) loadPathCall(ctx context.Context, call taskfile.Call) (taskfile.Call, error) {
+	namespace, name, ok := splitPathCall(call.Task)
+	if !ok {
+		return call, nil
+	}
+	call.Task = namespace
+	if name != "" {
+		call.Task += taskfile.NamespaceSeparator + name
+	}
+
+	prefix := namespace + taskfile.NamespaceSeparator
+	for _, k := range e.Taskfile.Tasks.Keys() {
+		if strings.HasPrefix(k, prefix) {
+			return call, nil
+		}
+	}
+	dir := filepathext.SmartJoin(e.UserWorkingDir, namespace)
+	if err := read.Include(ctx, e.Taskfile, namespace, dir, e.Insecure, e.Download, e.Offline, e.TempDir, e.Logger); err != nil {
+		return call, err
+	}
+	if err := e.checkDepCycles(); err != nil {
+		return call, err
+	}
+	if err := e.checkDeprecatedFuncs(); err != nil {
+		return call, err
+	}
+	for _, k := range e.Taskfile.Tasks.Keys() {
+		if _, ok := e.taskCallCount[k]; !ok {
+			e.taskCallCount[k] = new(int32)
+			e.mkdirMutexMap[k] = &sync.Mutex{}
+		}
+	}
+	e.setupFuzzyModel()
+	return call, nil
+}
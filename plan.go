@@ -0,0 +1,218 @@
+package task
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/nuvolaris/task/v3/errors"
+	"github.com/nuvolaris/task/v3/internal/fingerprint"
+	"github.com/nuvolaris/task/v3/taskfile"
+)
+
+// Reasons why a planned task or command doesn't run.
+const (
+	PlanSkippedUpToDate     = "up to date"
+	PlanSkippedPrecondition = "precondition not met"
+	PlanSkippedPlatform     = "not for the current platform"
+	PlanSkippedRunOnce      = "already run"
+)
+
+// Plan is what running some tasks would do, without running anything.
+type Plan struct {
+	// Tasks are in the order they would start. The deps of a task, which run
+	// in parallel, come before it in the order they are declared, and the
+	// tasks called by its commands after it.
+	Tasks []*PlannedTask `json:"tasks"`
+}
+
+// PlannedTask is a task of a Plan.
+type PlannedTask struct {
+	Task string `json:"task"`
+	// Label is the label of the task, when it has one.
+	Label string            `json:"label,omitempty"`
+	Dir   string            `json:"dir,omitempty"`
+	Env   map[string]string `json:"env,omitempty"`
+	Deps  []string          `json:"deps,omitempty"`
+	Cmds  []*PlannedCmd     `json:"cmds,omitempty"`
+	// Skipped is why the task doesn't run, if it doesn't. Its deps still do.
+	Skipped string `json:"skipped,omitempty"`
+	// Message is the message of the precondition that skips the task.
+	Message string `json:"message,omitempty"`
+}
+
+// PlannedCmd is a command of a PlannedTask: either a shell command or a call
+// of another task, which is planned too.
+type PlannedCmd struct {
+	Cmd      string `json:"cmd,omitempty"`
+	Task     string `json:"task,omitempty"`
+	Deferred bool   `json:"deferred,omitempty"`
+	Skipped  string `json:"skipped,omitempty"`
+}
+
+// Plan returns what running the given tasks would do, like --dry, but as a
+// structure instead of by printing the commands. The dynamic variables, the
+// fingerprints and the preconditions are still evaluated, but the state of the
+// tasks is never written.
+func (e *Executor) Plan(ctx context.Context, calls ...taskfile.Call) (*Plan, error) {
+	if err := e.setupIfNeeded(ctx); err != nil {
+		return nil, err
+	}
+	for _, call := range calls {
+		task, err := e.GetTask(call)
+		if err != nil {
+			return nil, err
+		}
+		if task.Internal {
+			return nil, &errors.TaskInternalError{TaskName: call.Task}
+		}
+	}
+
+	p := &planner{e: e, plan: &Plan{}, calls: map[string]int{}, hashes: map[string]bool{}}
+	for _, call := range calls {
+		if _, err := p.task(ctx, call); err != nil {
+			return nil, err
+		}
+	}
+	return p.plan, nil
+}
+
+// PlanJSON prints the plan of the given tasks as JSON.
+func (e *Executor) PlanJSON(ctx context.Context, calls ...taskfile.Call) error {
+	plan, err := e.Plan(ctx, calls...)
+	if err != nil {
+		return err
+	}
+	encoder := json.NewEncoder(e.Stdout)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(plan)
+}
+
+type planner struct {
+	e      *Executor
+	plan   *Plan
+	calls  map[string]int
+	hashes map[string]bool
+}
+
+func (p *planner) task(ctx context.Context, call taskfile.Call) (*PlannedTask, error) {
+	e := p.e
+	t, err := e.CompiledTask(ctx, call)
+	if err != nil {
+		return nil, err
+	}
+	p.calls[t.Task]++
+	if p.calls[t.Task] >= MaximumTaskCall {
+		return nil, &errors.TaskCalledTooManyTimesError{TaskName: t.Task, MaximumTaskCall: MaximumTaskCall}
+	}
+
+	planned := &PlannedTask{Task: t.Task, Label: t.Label, Dir: t.Dir}
+	// Like startExecution, the tasks with "run: once" or "run: when_changed"
+	// run once per hash
+	h, err := e.GetHash(t)
+	if err != nil {
+		return nil, err
+	}
+	if h != "" && p.hashes[h] {
+		planned.Skipped = PlanSkippedRunOnce
+		p.plan.Tasks = append(p.plan.Tasks, planned)
+		return planned, nil
+	}
+	if h != "" {
+		p.hashes[h] = true
+	}
+
+	for _, d := range t.Deps {
+		dep, err := p.task(ctx, taskfile.Call{Task: d.Task, Vars: d.Vars, Silent: d.Silent})
+		if err != nil {
+			return nil, err
+		}
+		planned.Deps = append(planned.Deps, dep.Task)
+	}
+	for _, stage := range t.Pipeline {
+		for _, d := range stage.Tasks {
+			dep, err := p.task(ctx, taskfile.Call{Task: d.Task, Vars: d.Vars, Silent: d.Silent})
+			if err != nil {
+				return nil, err
+			}
+			planned.Deps = append(planned.Deps, dep.Task)
+		}
+	}
+
+	p.plan.Tasks = append(p.plan.Tasks, planned)
+	_ = t.Env.Range(func(k string, v taskfile.Var) error {
+		if planned.Env == nil {
+			planned.Env = map[string]string{}
+		}
+		planned.Env[k] = v.Static
+		return nil
+	})
+
+	if skipped, err := p.skipped(ctx, t, call, planned); err != nil || skipped {
+		return planned, err
+	}
+
+	for _, cmd := range t.Cmds {
+		plannedCmd := &PlannedCmd{Cmd: cmd.Cmd, Task: cmd.Task, Deferred: cmd.Defer}
+		planned.Cmds = append(planned.Cmds, plannedCmd)
+		if cmd.Task != "" {
+			if _, err := p.task(ctx, taskfile.Call{Task: cmd.Task, Vars: cmd.Vars, Silent: cmd.Silent}); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		if !shouldRunOnCurrentPlatform(cmd.Platforms) {
+			plannedCmd.Skipped = PlanSkippedPlatform
+		}
+	}
+	return planned, nil
+}
+
+// skipped sets why the task doesn't run, if it doesn't, like executeTask
+// decides it.
+func (p *planner) skipped(ctx context.Context, t *taskfile.Task, call taskfile.Call, planned *PlannedTask) (bool, error) {
+	e := p.e
+	if !shouldRunOnCurrentPlatform(t.Platforms) {
+		planned.Skipped = PlanSkippedPlatform
+		return true, nil
+	}
+	if e.ForceAll || (call.Direct && e.Force) {
+		return false, nil
+	}
+	if err := e.areTaskRequiredVarsSet(ctx, t, call); err != nil {
+		return false, err
+	}
+	for _, precondition := range t.Preconditions {
+		if e.isPreconditionMet(ctx, t, precondition) {
+			continue
+		}
+		switch precondition.OnFailure {
+		case taskfile.PreconditionWarn:
+			continue
+		case taskfile.PreconditionSkip:
+			planned.Skipped = PlanSkippedPrecondition
+			planned.Message = precondition.Msg
+			return true, nil
+		default:
+			return false, &errors.TaskPreconditionError{TaskName: t.Task, Msg: precondition.Msg}
+		}
+	}
+
+	method := e.Taskfile.Method
+	if t.Method != "" {
+		method = t.Method
+	}
+	upToDate, err := fingerprint.IsTaskUpToDate(ctx, t,
+		fingerprint.WithMethod(method),
+		fingerprint.WithTempDir(e.TempDir),
+		fingerprint.WithDry(true),
+		fingerprint.WithLogger(e.Logger),
+	)
+	if err != nil {
+		return false, err
+	}
+	if upToDate {
+		planned.Skipped = PlanSkippedUpToDate
+		return true, nil
+	}
+	return false, nil
+}
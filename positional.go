@@ -0,0 +1,52 @@
+package task
+
+import (
+	"context"
+
+	"github.com/nuvolaris/task/v3/taskfile"
+)
+
+// BindArgs sets the positional args of the given calls, like the ones parsed
+// from the command line. The calls that follow a task with args are the words
+// given after its name, so they're set to the vars named after its args, in
+// order, instead of being called. A variadic arg takes all the remaining
+// words as a list. The calls of tasks without args are kept as they are.
+func (e *Executor) BindArgs(calls []taskfile.Call) ([]taskfile.Call, error) {
+	if err := e.setupIfNeeded(context.Background()); err != nil {
+		return nil, err
+	}
+
+	bound := make([]taskfile.Call, 0, len(calls))
+	for i := 0; i < len(calls); i++ {
+		call, err := e.loadPathCall(context.Background(), calls[i])
+		if err != nil {
+			return nil, err
+		}
+		t, err := e.GetTask(call)
+		if err != nil || len(t.Args) == 0 {
+			bound = append(bound, call)
+			continue
+		}
+		if call.Vars == nil {
+			call.Vars = &taskfile.Vars{}
+		}
+		for _, arg := range t.Args {
+			if arg.Variadic {
+				words := make([]any, 0, len(calls)-i-1)
+				for _, word := range calls[i+1:] {
+					words = append(words, word.Task)
+				}
+				call.Vars.Set(arg.Name, taskfile.Var{Live: words})
+				i = len(calls) - 1
+				break
+			}
+			if i+1 >= len(calls) {
+				break
+			}
+			i++
+			call.Vars.Set(arg.Name, taskfile.Var{Static: calls[i].Task})
+		}
+		bound = append(bound, call)
+	}
+	return bound, nil
+}
@@ -2,29 +2,63 @@ package task
 
 import (
 	"context"
-	"errors"
+	"os"
+	"os/exec"
 
+	"github.com/nuvolaris/task/v3/errors"
 	"github.com/nuvolaris/task/v3/internal/env"
 	"github.com/nuvolaris/task/v3/internal/execext"
+	"github.com/nuvolaris/task/v3/internal/filepathext"
 	"github.com/nuvolaris/task/v3/internal/logger"
 	"github.com/nuvolaris/task/v3/taskfile"
 )
 
-// ErrPreconditionFailed is returned when a precondition fails
-var ErrPreconditionFailed = errors.New("task: precondition not met")
+// ErrPreconditionFailed is matched by the errors returned when a precondition
+// fails. See errors.TaskPreconditionError for the details of the failure.
+var ErrPreconditionFailed = errors.ErrPreconditionFailed
 
+// areTaskPreconditionsMet returns false without an error when the task should
+// be skipped because a precondition with "on_failure: skip" wasn't met.
 func (e *Executor) areTaskPreconditionsMet(ctx context.Context, t *taskfile.Task) (bool, error) {
 	for _, p := range t.Preconditions {
-		err := execext.RunCommand(ctx, &execext.RunCommandOptions{
-			Command: p.Sh,
-			Dir:     t.Dir,
-			Env:     env.Get(t),
-		})
-		if err != nil {
-			e.Logger.Errf(logger.Magenta, "task: %s\n", p.Msg)
-			return false, ErrPreconditionFailed
+		if e.isPreconditionMet(ctx, t, p) {
+			continue
+		}
+
+		switch p.OnFailure {
+		case taskfile.PreconditionWarn:
+			e.Logger.Errf(logger.Yellow, "task: %s\n", p.Msg)
+		case taskfile.PreconditionSkip:
+			e.Logger.VerboseErrf(logger.Yellow, "task: Task %q skipped: %s\n", t.Name(), p.Msg)
+			return false, nil
+		default:
+			return false, &errors.TaskPreconditionError{TaskName: t.Task, Msg: p.Msg}
 		}
 	}
 
 	return true, nil
 }
+
+func (e *Executor) isPreconditionMet(ctx context.Context, t *taskfile.Task, p *taskfile.Precondition) bool {
+	switch {
+	case p.FileExists != "":
+		_, err := os.Stat(filepathext.SmartJoin(t.Dir, p.FileExists))
+		return err == nil
+	case p.EnvSet != "":
+		if _, ok := os.LookupEnv(p.EnvSet); ok {
+			return true
+		}
+		return t.Env != nil && t.Env.Exists(p.EnvSet)
+	case p.CommandExists != "":
+		_, err := exec.LookPath(p.CommandExists)
+		return err == nil
+	default:
+		err := execext.RunCommand(ctx, &execext.RunCommandOptions{
+			Command:  p.Sh,
+			Dir:      t.Dir,
+			Env:      env.Get(t),
+			Builtins: t.Builtins,
+		})
+		return err == nil
+	}
+}
@@ -0,0 +1,22 @@
+//go:build darwin
+
+package task
+
+import (
+	"fmt"
+
+	"golang.org/x/sys/unix"
+)
+
+// processStartTime returns when the process started, as told by sysctl.
+func processStartTime(pid int) (string, error) {
+	info, err := unix.SysctlKinfoProc("kern.proc.pid", pid)
+	if err != nil {
+		return "", err
+	}
+	if int(info.Proc.P_pid) != pid {
+		return "", fmt.Errorf("process %d not found", pid)
+	}
+	t := info.Proc.P_starttime
+	return fmt.Sprintf("%d.%06d", t.Sec, t.Usec), nil
+}
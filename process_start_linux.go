@@ -0,0 +1,30 @@
+//go:build linux
+
+package task
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// processStartTime returns when the process started, in clock ticks since
+// the boot, as told by /proc.
+func processStartTime(pid int) (string, error) {
+	b, err := os.ReadFile(fmt.Sprintf("/proc/%d/stat", pid))
+	if err != nil {
+		return "", err
+	}
+	// The name of the command, in parentheses, can have spaces
+	i := bytes.LastIndexByte(b, ')')
+	if i < 0 {
+		return "", fmt.Errorf("invalid stat of process %d", pid)
+	}
+	// The start time is the 22nd field, and the 20th after the name
+	fields := strings.Fields(string(b[i+1:]))
+	if len(fields) < 20 {
+		return "", fmt.Errorf("invalid stat of process %d", pid)
+	}
+	return fields[19], nil
+}
@@ -0,0 +1,23 @@
+//go:build !linux && !darwin && !windows
+
+package task
+
+import (
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// processStartTime returns when the process started, as told by ps.
+func processStartTime(pid int) (string, error) {
+	out, err := exec.Command("ps", "-o", "lstart=", "-p", strconv.Itoa(pid)).Output()
+	if err != nil {
+		return "", err
+	}
+	started := strings.TrimSpace(string(out))
+	if started == "" {
+		return "", fmt.Errorf("process %d not found", pid)
+	}
+	return started, nil
+}
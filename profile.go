@@ -0,0 +1,201 @@
+package task
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/nuvolaris/task/v3/internal/logger"
+	"github.com/nuvolaris/task/v3/taskfile"
+)
+
+// Kinds of ProfileSpan.
+const (
+	ProfileSpanTask    = "task"
+	ProfileSpanCommand = "cmd"
+)
+
+// A ProfileSpan is the wall-clock duration of a task, including its deps, or
+// of one of its commands.
+type ProfileSpan struct {
+	Kind     string
+	Task     string
+	Command  string
+	Start    time.Time
+	Duration time.Duration
+	Failed   bool
+
+	lane int
+}
+
+// Name is the task of the span and, for commands, the command itself.
+func (s *ProfileSpan) Name() string {
+	if s.Kind == ProfileSpanCommand {
+		return fmt.Sprintf("%s: %s", s.Task, s.Command)
+	}
+	return s.Task
+}
+
+// profiler records the spans of a run. Every span gets the first lane that
+// is free when it starts, so spans running at the same time, like parallel
+// deps, never share one in the trace.
+type profiler struct {
+	start time.Time
+	spans []*ProfileSpan
+	lanes []bool
+	mutex sync.Mutex
+}
+
+// profileTask is the middleware recording the span of every task, including
+// its deps.
+func (e *Executor) profileTask(next TaskRunner) TaskRunner {
+	return func(ctx context.Context, t *taskfile.Task, call taskfile.Call) (err error) {
+		end := e.profiler.begin(ProfileSpanTask, t.Name(), "")
+		defer func() { end(err) }()
+		return next(ctx, t, call)
+	}
+}
+
+// profileCommand is the middleware recording the span of every command.
+func (e *Executor) profileCommand(next commandRunner) commandRunner {
+	return func(ctx context.Context, t *taskfile.Task, cmd *taskfile.Cmd) (err error) {
+		end := e.profiler.begin(ProfileSpanCommand, t.Name(), cmd.Cmd)
+		defer func() { end(err) }()
+		return next(ctx, t, cmd)
+	}
+}
+
+func newProfiler() *profiler {
+	return &profiler{start: time.Now()}
+}
+
+// begin starts a span and returns the function that ends it.
+func (p *profiler) begin(kind, task, command string) func(err error) {
+	if p == nil {
+		return func(error) {}
+	}
+	p.mutex.Lock()
+	span := &ProfileSpan{Kind: kind, Task: task, Command: command, Start: time.Now(), lane: -1}
+	for i, busy := range p.lanes {
+		if !busy {
+			span.lane = i
+			break
+		}
+	}
+	if span.lane == -1 {
+		span.lane = len(p.lanes)
+		p.lanes = append(p.lanes, false)
+	}
+	p.lanes[span.lane] = true
+	p.mutex.Unlock()
+
+	return func(err error) {
+		p.mutex.Lock()
+		defer p.mutex.Unlock()
+		span.Duration = time.Since(span.Start)
+		span.Failed = err != nil
+		p.lanes[span.lane] = false
+		p.spans = append(p.spans, span)
+	}
+}
+
+// LastRunProfile returns the spans of the last call to Run, slowest first,
+// or nil if it wasn't profiled.
+func (e *Executor) LastRunProfile() []*ProfileSpan {
+	if e.profiler == nil {
+		return nil
+	}
+	return e.profiler.sorted()
+}
+
+func (p *profiler) sorted() []*ProfileSpan {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	spans := make([]*ProfileSpan, len(p.spans))
+	copy(spans, p.spans)
+	sort.SliceStable(spans, func(i, j int) bool {
+		return spans[i].Duration > spans[j].Duration
+	})
+	return spans
+}
+
+// printProfile prints the timing table of the run, if it was requested, and
+// writes the trace file.
+func (e *Executor) printProfile() error {
+	p := e.profiler
+	if p == nil {
+		return nil
+	}
+	spans := p.sorted()
+
+	if e.Profile {
+		e.Logger.Errf(logger.Magenta, "task: Profile of the run, which took %s:\n", formatProfileDuration(time.Since(p.start)))
+		for _, s := range spans {
+			color := logger.Default
+			if s.Failed {
+				color = logger.Red
+			}
+			e.Logger.Errf(color, "task: %10s  %-4s %s\n", formatProfileDuration(s.Duration), s.Kind, s.Name())
+		}
+	}
+
+	if e.ProfileFile == "" {
+		return nil
+	}
+	b, err := json.MarshalIndent(chromeTrace(p.start, spans), "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(e.ProfileFile, append(b, '\n'), 0o644)
+}
+
+func formatProfileDuration(d time.Duration) string {
+	return d.Round(time.Millisecond).String()
+}
+
+// traceEvent is a complete event of the Chrome trace event format, which is
+// understood by chrome://tracing, Perfetto and speedscope. Times are in
+// microseconds.
+type traceEvent struct {
+	Name     string         `json:"name"`
+	Category string         `json:"cat"`
+	Phase    string         `json:"ph"`
+	Time     int64          `json:"ts"`
+	Duration int64          `json:"dur"`
+	PID      int            `json:"pid"`
+	TID      int            `json:"tid"`
+	Args     map[string]any `json:"args,omitempty"`
+}
+
+type trace struct {
+	TraceEvents     []traceEvent `json:"traceEvents"`
+	DisplayTimeUnit string       `json:"displayTimeUnit"`
+}
+
+func chromeTrace(start time.Time, spans []*ProfileSpan) trace {
+	events := make([]traceEvent, 0, len(spans))
+	for _, s := range spans {
+		event := traceEvent{
+			Name:     s.Name(),
+			Category: s.Kind,
+			Phase:    "X",
+			Time:     s.Start.Sub(start).Microseconds(),
+			Duration: s.Duration.Microseconds(),
+			PID:      1,
+			TID:      s.lane + 1,
+			Args:     map[string]any{"task": s.Task},
+		}
+		if s.Failed {
+			event.Args["failed"] = true
+		}
+		events = append(events, event)
+	}
+	sort.SliceStable(events, func(i, j int) bool {
+		return events[i].Time < events[j].Time
+	})
+	return trace{TraceEvents: events, DisplayTimeUnit: "ms"}
+}
@@ -0,0 +1,72 @@
+package task
+
+import (
+	"bufio"
+	"io"
+	"strings"
+
+	"github.com/nuvolaris/task/v3/errors"
+	"github.com/nuvolaris/task/v3/internal/logger"
+	"github.com/nuvolaris/task/v3/internal/term"
+	"github.com/nuvolaris/task/v3/taskfile"
+)
+
+// promptVar asks the user for the value of a variable that has a prompt and
+// isn't set. Each variable is asked only once, even if many tasks use it, and
+// the default is used when the user can't be asked.
+func (e *Executor) promptVar(name string, v taskfile.Var) (string, error) {
+	e.promptMutex.Lock()
+	defer e.promptMutex.Unlock()
+
+	if answer, ok := e.promptAnswers[name]; ok {
+		return answer, nil
+	}
+
+	if e.NoInput || (!e.AssumesTerm && !term.IsTerminal()) {
+		if v.Default == "" {
+			return "", &errors.VarPromptUnavailableError{VarName: name, NoInput: e.NoInput}
+		}
+		return v.Default, nil
+	}
+
+	if v.Default != "" && !v.Secret {
+		e.Logger.Outf(logger.Yellow, "task: %s [%s]: ", v.Prompt, v.Default)
+	} else {
+		e.Logger.Outf(logger.Yellow, "task: %s: ", v.Prompt)
+	}
+
+	answer, err := e.readAnswer(v.Secret)
+	if err != nil {
+		return "", err
+	}
+	if answer == "" {
+		answer = v.Default
+	}
+
+	if e.promptAnswers == nil {
+		e.promptAnswers = make(map[string]string)
+	}
+	e.promptAnswers[name] = answer
+	return answer, nil
+}
+
+// readAnswer reads a line from the standard input. Secret answers aren't
+// echoed when the input is a terminal.
+func (e *Executor) readAnswer(secret bool) (string, error) {
+	if secret && term.IsTerminalReader(e.Stdin) {
+		answer, err := term.ReadPassword(e.Stdin)
+		// The newline typed by the user isn't echoed either
+		e.Logger.Outf(logger.Default, "\n")
+		return strings.TrimSpace(answer), err
+	}
+
+	// The reader is kept between prompts, so the input it buffered isn't lost
+	if e.stdinReader == nil {
+		e.stdinReader = bufio.NewReader(e.Stdin)
+	}
+	answer, err := e.stdinReader.ReadString('\n')
+	if err != nil && err != io.EOF {
+		return "", err
+	}
+	return strings.TrimSpace(answer), nil
+}
@@ -0,0 +1,40 @@
+package task
+
+import (
+	"strings"
+
+	"github.com/nuvolaris/task/v3/internal/filepathext"
+	"github.com/nuvolaris/task/v3/internal/logger"
+	"github.com/nuvolaris/task/v3/taskfile"
+)
+
+// logCmdProvenance prints, in verbose mode, where a command comes from: the
+// Taskfile and the line it's written at, which can be the ones of the task it
+// extends, then the dir it runs in and how its environment differs from the
+// one of Task. The values of the secret env of the task aren't printed.
+func (e *Executor) logCmdProvenance(t *taskfile.Task, cmd *taskfile.Cmd, set, unset []string) {
+	if !e.Verbose {
+		return
+	}
+	if loc := cmd.Location; loc != nil && loc.Taskfile != "" {
+		e.Logger.VerboseErrf(logger.Magenta, "task: [%s]   from %s:%d:%d\n", t.Name(), filepathext.TryAbsToRel(loc.Taskfile), loc.Line, loc.Column)
+	}
+	if t.Dir != "" {
+		e.Logger.VerboseErrf(logger.Magenta, "task: [%s]   dir: %s\n", t.Name(), t.Dir)
+	}
+	if len(set) == 0 && len(unset) == 0 {
+		return
+	}
+	diff := make([]string, 0, len(set)+len(unset))
+	for _, kv := range set {
+		k, _, _ := strings.Cut(kv, "=")
+		if t.Env.Exists(k) && t.Env.Get(k).Secret {
+			kv = k + "=***"
+		}
+		diff = append(diff, "+"+kv)
+	}
+	for _, k := range unset {
+		diff = append(diff, "-"+k)
+	}
+	e.Logger.VerboseErrf(logger.Magenta, "task: [%s]   env: %s\n", t.Name(), strings.Join(diff, " "))
+}
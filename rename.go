@@ -0,0 +1,400 @@
+package task
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"golang.org/x/exp/slices"
+	"gopkg.in/yaml.v3"
+
+	"github.com/nuvolaris/task/v3/errors"
+	"github.com/nuvolaris/task/v3/internal/filepathext"
+	"github.com/nuvolaris/task/v3/internal/logger"
+	"github.com/nuvolaris/task/v3/internal/templater"
+	"github.com/nuvolaris/task/v3/taskfile"
+	"github.com/nuvolaris/task/v3/taskfile/read"
+)
+
+// renameFile is a local Taskfile that takes part in a rename, along with the
+// namespace its tasks are included under.
+type renameFile struct {
+	path      string
+	namespace string
+	content   []byte
+	root      *yaml.Node
+	edits     []renameEdit
+}
+
+// renameEdit replaces a single scalar in the original file content. Editing
+// the source in place (instead of re-encoding the YAML) keeps comments, blank
+// lines and quoting exactly as the user wrote them.
+type renameEdit struct {
+	line, column int
+	old, new     string
+}
+
+// RenameTask renames a task and rewrites every reference to it (deps, task
+// calls, deferred task calls, pipeline stages, extends and hooks) across the
+// root Taskfile and its local includes. Remote includes are never modified.
+func (e *Executor) RenameTask(oldName, newName string) error {
+	if err := e.setupIfNeeded(context.Background()); err != nil {
+		return err
+	}
+	if e.Taskfile.Tasks.Get(oldName) == nil {
+		return &errors.TaskNotFoundError{TaskName: oldName}
+	}
+	if e.Taskfile.Tasks.Get(newName) != nil {
+		return fmt.Errorf("task: Task %q already exists", newName)
+	}
+
+	files, err := loadRenameFiles(filepathext.SmartJoin(e.Dir, e.Entrypoint), "", map[[2]string]bool{})
+	if err != nil {
+		return err
+	}
+
+	// Find the file that defines the task and rename its key
+	var defining *renameFile
+	var localName, newLocalName string
+	for _, f := range files {
+		var ok bool
+		localName, ok = localTaskName(f.namespace, oldName)
+		if !ok {
+			continue
+		}
+		keyNode := f.taskKeyNode(localName)
+		if keyNode == nil {
+			continue
+		}
+		newLocalName, ok = localTaskName(f.namespace, newName)
+		if !ok {
+			return fmt.Errorf("task: Task %q can't be moved out of the %q namespace", oldName, f.namespace)
+		}
+		f.addEdit(keyNode, newLocalName)
+		defining = f
+		break
+	}
+	if defining == nil {
+		return fmt.Errorf("task: Task %q is not defined in a local Taskfile", oldName)
+	}
+
+	// A Taskfile included more than once defines the task under each of its
+	// namespaces, which are all renamed along with it
+	renames := map[string]string{}
+	for _, f := range files {
+		if f.path != defining.path {
+			continue
+		}
+		from, to := fullTaskName(f.namespace, localName), fullTaskName(f.namespace, newLocalName)
+		if from != oldName && e.Taskfile.Tasks.Get(to) != nil {
+			return fmt.Errorf("task: Task %q already exists", to)
+		}
+		renames[from] = to
+	}
+
+	// Rewrite the references in every file, and in the hooks, which only the
+	// root Taskfile has
+	for _, f := range files {
+		f.rewriteReferences(renames)
+	}
+	files[0].rewriteHooks(renames)
+	files = mergeRenameFiles(files)
+
+	for _, f := range files {
+		if len(f.edits) == 0 {
+			continue
+		}
+		if e.Dry {
+			e.Logger.Outf(logger.Green, "task: %s: %d reference(s) would be renamed\n", filepathext.TryAbsToRel(f.path), len(f.edits))
+			continue
+		}
+		content, err := f.apply()
+		if err != nil {
+			return err
+		}
+		info, err := os.Stat(f.path)
+		if err != nil {
+			return err
+		}
+		if err := os.WriteFile(f.path, content, info.Mode()); err != nil {
+			return err
+		}
+		e.Logger.VerboseOutf(logger.Green, "task: %s: renamed %d reference(s)\n", filepathext.TryAbsToRel(f.path), len(f.edits))
+	}
+
+	e.Logger.Outf(logger.Green, "task: Task %q renamed to %q\n", oldName, newName)
+	return nil
+}
+
+// loadRenameFiles loads the Taskfile and its local includes, once for each
+// namespace they're included under.
+func loadRenameFiles(path, namespace string, seen map[[2]string]bool) ([]*renameFile, error) {
+	key := [2]string{path, namespace}
+	if seen[key] {
+		return nil, nil
+	}
+	seen[key] = true
+
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var doc yaml.Node
+	if err := yaml.Unmarshal(b, &doc); err != nil {
+		return nil, &errors.TaskfileInvalidError{URI: filepathext.TryAbsToRel(path), Err: err}
+	}
+	var tf taskfile.Taskfile
+	if err := doc.Decode(&tf); err != nil {
+		return nil, &errors.TaskfileInvalidError{URI: filepathext.TryAbsToRel(path), Err: err}
+	}
+
+	f := &renameFile{path: path, namespace: namespace, content: b}
+	if len(doc.Content) > 0 {
+		f.root = doc.Content[0]
+	}
+	files := []*renameFile{f}
+
+	err = tf.Includes.Range(func(key string, includedTask taskfile.IncludedTaskfile) error {
+		tr := templater.Templater{Vars: tf.Vars, RemoveNoValue: true}
+		includedTask.Taskfile = tr.Replace(includedTask.Taskfile)
+		if err := tr.Err(); err != nil {
+			return err
+		}
+		// Remote Taskfiles can't be rewritten
+		if strings.Contains(includedTask.Taskfile, "://") || read.IsVersionedURI(includedTask.Taskfile) || read.IsFetcherURI(includedTask.Taskfile) {
+			return nil
+		}
+		includedTask.BaseDir = filepath.Dir(path)
+		uri, err := includedTask.FullTaskfilePath()
+		if err != nil {
+			return err
+		}
+		includedPath, err := read.Exists(uri)
+		if err != nil {
+			if includedTask.Optional {
+				return nil
+			}
+			return err
+		}
+		included, err := loadRenameFiles(includedPath, fullTaskName(namespace, key), seen)
+		if err != nil {
+			return err
+		}
+		files = append(files, included...)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return files, nil
+}
+
+// taskKeyNode returns the key node of the given task in the "tasks" mapping.
+func (f *renameFile) taskKeyNode(name string) *yaml.Node {
+	tasks := mappingValue(f.root, "tasks")
+	if tasks == nil || tasks.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i < len(tasks.Content); i += 2 {
+		if tasks.Content[i].Value == name {
+			return tasks.Content[i]
+		}
+	}
+	return nil
+}
+
+// mergeRenameFiles merges the edits of the files loaded under several
+// namespaces, so that each file is written once.
+func mergeRenameFiles(files []*renameFile) []*renameFile {
+	var merged []*renameFile
+	byPath := map[string]*renameFile{}
+	for _, f := range files {
+		m, ok := byPath[f.path]
+		if !ok {
+			byPath[f.path] = f
+			merged = append(merged, f)
+			continue
+		}
+		for _, edit := range f.edits {
+			if !slices.Contains(m.edits, edit) {
+				m.edits = append(m.edits, edit)
+			}
+		}
+	}
+	return merged
+}
+
+// rewriteReferences rewrites the references to the renamed tasks, from their
+// old full name to the new one.
+func (f *renameFile) rewriteReferences(renames map[string]string) {
+	tasks := mappingValue(f.root, "tasks")
+	if tasks == nil || tasks.Kind != yaml.MappingNode {
+		return
+	}
+	for i := 1; i < len(tasks.Content); i += 2 {
+		task := tasks.Content[i]
+		var cmds []*yaml.Node
+		switch task.Kind {
+		case yaml.MappingNode:
+			f.rewriteReference(mappingValue(task, "extends"), renames)
+			if deps := mappingValue(task, "deps"); deps != nil && deps.Kind == yaml.SequenceNode {
+				for _, dep := range deps.Content {
+					f.rewriteDep(dep, renames)
+				}
+			}
+			if pipeline := mappingValue(task, "pipeline"); pipeline != nil && pipeline.Kind == yaml.SequenceNode {
+				for _, stage := range pipeline.Content {
+					f.rewriteStage(stage, renames)
+				}
+			}
+			cmds = append(cmds, mappingValue(task, "cmd"))
+			if seq := mappingValue(task, "cmds"); seq != nil && seq.Kind == yaml.SequenceNode {
+				cmds = append(cmds, seq.Content...)
+			}
+		// The short notation of a task is the list of its commands
+		case yaml.SequenceNode:
+			cmds = task.Content
+		}
+		for _, cmd := range cmds {
+			if cmd == nil || cmd.Kind != yaml.MappingNode {
+				continue
+			}
+			f.rewriteReference(mappingValue(cmd, "task"), renames)
+			f.rewriteReference(mappingValue(mappingValue(cmd, "defer"), "task"), renames)
+		}
+	}
+}
+
+// rewriteHooks rewrites the references of the hooks of the Taskfile.
+func (f *renameFile) rewriteHooks(renames map[string]string) {
+	for _, key := range []string{"before_all", "after_all", "before_each", "after_each"} {
+		if hooks := mappingValue(f.root, key); hooks != nil && hooks.Kind == yaml.SequenceNode {
+			for _, hook := range hooks.Content {
+				f.rewriteDep(hook, renames)
+			}
+		}
+	}
+}
+
+// rewriteDep rewrites a dependency, in any of its forms.
+func (f *renameFile) rewriteDep(dep *yaml.Node, renames map[string]string) {
+	if dep.Kind == yaml.ScalarNode {
+		f.rewriteReference(dep, renames)
+	} else {
+		f.rewriteReference(mappingValue(dep, "task"), renames)
+	}
+}
+
+// rewriteStage rewrites a stage of a pipeline: a single dependency, a list of
+// them, or a named stage with its list of tasks.
+func (f *renameFile) rewriteStage(stage *yaml.Node, renames map[string]string) {
+	deps := []*yaml.Node{stage}
+	switch {
+	case stage.Kind == yaml.SequenceNode:
+		deps = stage.Content
+	case mappingValue(stage, "tasks") != nil:
+		deps = nil
+		if tasks := mappingValue(stage, "tasks"); tasks.Kind == yaml.SequenceNode {
+			deps = tasks.Content
+		}
+	}
+	for _, dep := range deps {
+		f.rewriteDep(dep, renames)
+	}
+}
+
+func (f *renameFile) rewriteReference(node *yaml.Node, renames map[string]string) {
+	if node == nil || node.Kind != yaml.ScalarNode {
+		return
+	}
+	ref := node.Value
+	absolute := strings.HasPrefix(ref, ":")
+	newName, ok := renames[resolveTaskReference(f.namespace, ref)]
+	if !ok {
+		return
+	}
+	newRef, ok := localTaskName(f.namespace, newName)
+	if absolute || !ok {
+		newRef = ":" + newName
+	}
+	f.addEdit(node, newRef)
+}
+
+func (f *renameFile) addEdit(node *yaml.Node, value string) {
+	old := node.Value
+	switch node.Style {
+	case yaml.SingleQuotedStyle:
+		old, value = "'"+old+"'", "'"+value+"'"
+	case yaml.DoubleQuotedStyle:
+		old, value = `"`+old+`"`, `"`+value+`"`
+	}
+	f.edits = append(f.edits, renameEdit{line: node.Line, column: node.Column, old: old, new: value})
+}
+
+// apply returns the file content with all the edits applied.
+func (f *renameFile) apply() ([]byte, error) {
+	lines := strings.SplitAfter(string(f.content), "\n")
+	// Apply the edits from the end of the file to keep positions valid
+	sort.Slice(f.edits, func(i, j int) bool {
+		if f.edits[i].line != f.edits[j].line {
+			return f.edits[i].line > f.edits[j].line
+		}
+		return f.edits[i].column > f.edits[j].column
+	})
+	for _, edit := range f.edits {
+		if edit.line < 1 || edit.line > len(lines) {
+			return nil, fmt.Errorf("task: %s: unexpected position of %q", f.path, edit.old)
+		}
+		line := []rune(lines[edit.line-1])
+		start, end := edit.column-1, edit.column-1+len([]rune(edit.old))
+		if start < 0 || end > len(line) || string(line[start:end]) != edit.old {
+			return nil, fmt.Errorf("task: %s:%d: unable to rename %q in place", f.path, edit.line, edit.old)
+		}
+		lines[edit.line-1] = string(line[:start]) + edit.new + string(line[end:])
+	}
+	return []byte(strings.Join(lines, "")), nil
+}
+
+func mappingValue(node *yaml.Node, key string) *yaml.Node {
+	if node == nil || node.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i+1]
+		}
+	}
+	return nil
+}
+
+// resolveTaskReference returns the full name of a task referenced from a
+// Taskfile included under the given namespace.
+func resolveTaskReference(namespace, ref string) string {
+	if strings.HasPrefix(ref, ":") {
+		return strings.TrimPrefix(ref, ":")
+	}
+	return fullTaskName(namespace, ref)
+}
+
+func fullTaskName(namespace, name string) string {
+	if namespace == "" {
+		return name
+	}
+	return namespace + taskfile.NamespaceSeparator + name
+}
+
+// localTaskName returns the name of a task relative to the given namespace.
+func localTaskName(namespace, name string) (string, bool) {
+	if namespace == "" {
+		return name, true
+	}
+	prefix := namespace + taskfile.NamespaceSeparator
+	if !strings.HasPrefix(name, prefix) {
+		return "", false
+	}
+	return strings.TrimPrefix(name, prefix), true
+}
@@ -0,0 +1,181 @@
+package task
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"sync"
+
+	"github.com/nuvolaris/task/v3/internal/logger"
+	"github.com/nuvolaris/task/v3/taskfile"
+)
+
+// TaskState is the state a task ended up in after a run.
+type TaskState string
+
+const (
+	// TaskStateCompleted means that the task ran all its commands successfully
+	TaskStateCompleted TaskState = "completed"
+	// TaskStateFailed means that the task failed
+	TaskStateFailed TaskState = "failed"
+	// TaskStateInterrupted means that the task was running its commands when
+	// the run was cancelled
+	TaskStateInterrupted TaskState = "interrupted"
+	// TaskStateSkipped means that the task didn't run any command, because it
+	// was up-to-date, a precondition told to skip it or the run was cancelled
+	// before it could start
+	TaskStateSkipped TaskState = "skipped"
+)
+
+// RunReport describes what happened to the tasks executed by a run.
+type RunReport struct {
+	Cancelled     bool            `json:"cancelled"`
+	Tasks         []*TaskReport   `json:"tasks"`
+	IgnoredErrors []*IgnoredError `json:"ignored_errors,omitempty"`
+
+	mutex sync.Mutex
+}
+
+// TaskReport describes what happened to a single task execution.
+type TaskReport struct {
+	Task  string    `json:"task"`
+	State TaskState `json:"state"`
+	Error string    `json:"error,omitempty"`
+
+	started  bool
+	upToDate bool
+}
+
+// IgnoredError is an error that didn't stop the run because of ignore_error,
+// either of a command, of a task or of a dep.
+type IgnoredError struct {
+	Task  string `json:"task"`
+	Error string `json:"error"`
+}
+
+type taskReportKey struct{}
+
+func withTaskReport(ctx context.Context, tr *TaskReport) context.Context {
+	return context.WithValue(ctx, taskReportKey{}, tr)
+}
+
+func taskReportFromContext(ctx context.Context) *TaskReport {
+	tr, _ := ctx.Value(taskReportKey{}).(*TaskReport)
+	return tr
+}
+
+// LastRunReport returns the report of the last call to Run, or nil if Run
+// was never called.
+func (e *Executor) LastRunReport() *RunReport {
+	return e.report
+}
+
+func (r *RunReport) startTask(t *taskfile.Task) *TaskReport {
+	if r == nil {
+		return nil
+	}
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	tr := &TaskReport{Task: t.Name()}
+	r.Tasks = append(r.Tasks, tr)
+	return tr
+}
+
+// markStarted records that the task began running its commands.
+func (r *RunReport) markStarted(tr *TaskReport) {
+	if r == nil || tr == nil {
+		return
+	}
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	tr.started = true
+}
+
+// markUpToDate records that the task was skipped because it's up to date.
+func (r *RunReport) markUpToDate(tr *TaskReport) {
+	if r == nil || tr == nil {
+		return
+	}
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	tr.upToDate = true
+}
+
+func (r *RunReport) finishTask(ctx context.Context, tr *TaskReport, err error, interrupted bool) {
+	if r == nil {
+		return
+	}
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	cancelled := err != nil && (ctx.Err() != nil || interrupted)
+	switch {
+	case cancelled && tr.started:
+		tr.State = TaskStateInterrupted
+	case cancelled:
+		tr.State = TaskStateSkipped
+	case err != nil:
+		tr.State = TaskStateFailed
+	case tr.started:
+		tr.State = TaskStateCompleted
+	default:
+		tr.State = TaskStateSkipped
+	}
+	if err != nil {
+		tr.Error = err.Error()
+	}
+	if cancelled {
+		r.Cancelled = true
+	}
+}
+
+// ignoreError records an error that didn't stop the run.
+func (r *RunReport) ignoreError(task string, err error) {
+	if r == nil {
+		return
+	}
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.IgnoredErrors = append(r.IgnoredErrors, &IgnoredError{Task: task, Error: err.Error()})
+}
+
+// printRunReport prints the state of every task when the run was cancelled
+// and the errors ignored along the way, and writes the JSON report if one was
+// requested.
+func (e *Executor) printRunReport() error {
+	r := e.report
+
+	if len(r.IgnoredErrors) > 0 {
+		e.Logger.Errf(logger.Yellow, "task: Ignored %d error(s):\n", len(r.IgnoredErrors))
+		for _, ie := range r.IgnoredErrors {
+			e.Logger.Errf(logger.Yellow, "task: - %q: %s\n", ie.Task, ie.Error)
+		}
+	}
+
+	if r.Cancelled {
+		e.Logger.Errf(logger.Yellow, "task: Run cancelled\n")
+		for _, tr := range r.Tasks {
+			color := logger.Yellow
+			switch tr.State {
+			case TaskStateCompleted:
+				color = logger.Green
+			case TaskStateFailed:
+				color = logger.Red
+			}
+			e.Logger.Errf(color, "task: Task %q %s\n", tr.Task, tr.State)
+		}
+	}
+
+	if e.ReportFile == "" {
+		return nil
+	}
+	b, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(e.ReportFile, append(b, '\n'), 0o644)
+}
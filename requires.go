@@ -8,15 +8,32 @@ import (
 )
 
 func (e *Executor) areTaskRequiredVarsSet(ctx context.Context, t *taskfile.Task, call taskfile.Call) error {
-	if t.Requires == nil || len(t.Requires.Vars) == 0 {
+	if (t.Requires == nil || len(t.Requires.Vars) == 0) && !hasRequiredArgs(t) {
 		return nil
 	}
 
-	vars, err := e.Compiler.GetVariables(t, call)
+	vars, err := e.Compiler.GetVariables(ctx, t, call)
 	if err != nil {
 		return err
 	}
 
+	var missingArgs []string
+	for _, arg := range t.Args {
+		if arg.Required && !vars.Exists(arg.Name) {
+			missingArgs = append(missingArgs, arg.Name)
+		}
+	}
+	if len(missingArgs) > 0 {
+		return &errors.TaskMissingRequiredArgs{
+			TaskName:    t.Name(),
+			MissingArgs: missingArgs,
+			Usage:       taskfile.ArgsUsage(t.Args),
+		}
+	}
+
+	if t.Requires == nil {
+		return nil
+	}
 	var missingVars []string
 	for _, requiredVar := range t.Requires.Vars {
 		if !vars.Exists(requiredVar) {
@@ -33,3 +50,12 @@ func (e *Executor) areTaskRequiredVarsSet(ctx context.Context, t *taskfile.Task,
 
 	return nil
 }
+
+func hasRequiredArgs(t *taskfile.Task) bool {
+	for _, arg := range t.Args {
+		if arg.Required {
+			return true
+		}
+	}
+	return false
+}
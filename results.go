@@ -0,0 +1,135 @@
+package task
+
+import (
+	"context"
+	"time"
+
+	"github.com/nuvolaris/task/v3/errors"
+	"github.com/nuvolaris/task/v3/internal/execext"
+	"github.com/nuvolaris/task/v3/taskfile"
+)
+
+// Results are the results of the calls given to RunWithResults, in the same
+// order.
+type Results []*CallResult
+
+// CallResult is what happened to one of the calls of a run. The tasks it ran
+// along the way, like its deps, are in the RunReport instead.
+type CallResult struct {
+	Call taskfile.Call
+	// Task is the name of the task called, once found
+	Task  string
+	State TaskState
+	// UpToDate is set when the task was skipped because it's up to date
+	UpToDate bool
+	Duration time.Duration
+	// ExitCode is the exit code of the failed command, or the one Task exits
+	// with for the other errors, or 0 when the call didn't fail
+	ExitCode int
+	Err      error
+
+	report *TaskReport
+}
+
+// Failed returns the results of the calls that failed.
+func (r Results) Failed() Results {
+	var failed Results
+	for _, cr := range r {
+		if cr.State == TaskStateFailed {
+			failed = append(failed, cr)
+		}
+	}
+	return failed
+}
+
+// RunWithResults runs the calls like Run, and returns what happened to each of
+// them, so the programs embedding Task know which ones failed without reading
+// its output. The error is the one Run returns.
+func (e *Executor) RunWithResults(ctx context.Context, calls ...taskfile.Call) (Results, error) {
+	results := make(Results, len(calls))
+	for i, c := range calls {
+		results[i] = &CallResult{Call: c, Task: c.Task, State: TaskStateSkipped}
+	}
+	e.callResults = results
+	defer func() { e.callResults = nil }()
+	err := e.Run(ctx, calls...)
+	return results, err
+}
+
+type callResultKey struct{}
+
+// runCall runs the i-th call of a run between the each hooks, recording its
+// result when asked to.
+func (e *Executor) runCall(ctx context.Context, i int, c taskfile.Call) error {
+	return e.runWithEachHooks(ctx, c, func() error {
+		if e.callResults == nil {
+			return e.RunTask(ctx, c)
+		}
+		cr := e.callResults[i]
+		start := time.Now()
+		err := e.RunTask(context.WithValue(ctx, callResultKey{}, cr), c)
+		cr.Duration = time.Since(start)
+		e.report.finishCall(ctx, cr, err)
+		return err
+	})
+}
+
+// withCallReport links the report of a task to the result of the call, if
+// the task is the one called, and returns the context of its deps and
+// commands, which aren't.
+func withCallReport(ctx context.Context, tr *TaskReport) context.Context {
+	cr, _ := ctx.Value(callResultKey{}).(*CallResult)
+	if cr == nil {
+		return ctx
+	}
+	cr.report = tr
+	return context.WithValue(ctx, callResultKey{}, (*CallResult)(nil))
+}
+
+// finishCall fills the result of a call from the report of its task, or from
+// its error when the task didn't even start.
+func (r *RunReport) finishCall(ctx context.Context, cr *CallResult, err error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	cr.Err = err
+	switch tr := cr.report; {
+	case tr != nil:
+		cr.Task, cr.State, cr.UpToDate = tr.Task, tr.State, tr.upToDate
+	case err != nil && ctx.Err() == nil:
+		cr.State = TaskStateFailed
+	}
+
+	cr.ExitCode = exitCode(err)
+}
+
+// failCall records that the i-th call failed before running, like when its
+// task doesn't exist.
+func (e *Executor) failCall(i int, err error) {
+	if e.callResults == nil {
+		return
+	}
+	cr := e.callResults[i]
+	cr.State, cr.Err, cr.ExitCode = TaskStateFailed, err, exitCode(err)
+}
+
+// exitCode returns the exit code of the failed command, or the one Task exits
+// with for the other errors.
+func exitCode(err error) int {
+	var taskRunErr *errors.TaskRunError
+	var taskErr errors.TaskError
+	switch {
+	case err == nil:
+		return 0
+	case errors.As(err, &taskRunErr):
+		return taskRunErr.TaskExitCode()
+	case execext.IsExitError(err):
+		// The errors of the commands aren't wrapped unless the calls are
+		// direct, like the ones of the CLI
+		return (&errors.TaskRunError{Err: err}).TaskExitCode()
+	case errors.As(err, &taskErr):
+		return taskErr.Code()
+	default:
+		return errors.CodeUnknown
+	}
+}
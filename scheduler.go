@@ -0,0 +1,198 @@
+package task
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nuvolaris/task/v3/errors"
+	"github.com/nuvolaris/task/v3/internal/cron"
+	"github.com/nuvolaris/task/v3/internal/logger"
+	"github.com/nuvolaris/task/v3/taskfile"
+)
+
+// The kinds of SchedulerEvent.
+const (
+	// SchedulerEventScheduled is logged with the next time a task is due
+	SchedulerEventScheduled = "scheduled"
+	SchedulerEventStarted   = "started"
+	SchedulerEventFinished  = "finished"
+	SchedulerEventFailed    = "failed"
+	// SchedulerEventInterrupted is logged when a run is stopped by the end of
+	// the scheduler
+	SchedulerEventInterrupted = "interrupted"
+	// SchedulerEventSkipped is logged when a task is due while its previous
+	// run is still running, with the skip overlap policy
+	SchedulerEventSkipped = "skipped"
+	// SchedulerEventQueued is logged when a task is due while its previous
+	// run is still running, with the queue overlap policy
+	SchedulerEventQueued = "queued"
+)
+
+// SchedulerEvent is something that happened to a scheduled task.
+type SchedulerEvent struct {
+	Time  time.Time `json:"time"`
+	Task  string    `json:"task"`
+	Event string    `json:"event"`
+	// Next is when the task is due next, for the scheduled events
+	Next *time.Time `json:"next,omitempty"`
+	// Duration is how long the run took, in seconds, once it's over
+	Duration float64 `json:"duration,omitempty"`
+	ExitCode int     `json:"exit_code,omitempty"`
+	Error    string  `json:"error,omitempty"`
+}
+
+// A scheduledTask is a task with a schedule, and its runs in progress.
+type scheduledTask struct {
+	name     string
+	schedule *cron.Schedule
+	overlap  string
+
+	mutex   sync.Mutex
+	running int
+	queued  int
+}
+
+// RunScheduler runs the tasks with a schedule every time they're due, until
+// the context is done, and then waits for their runs to finish. Each event of
+// the scheduler is given to onEvent, one at a time, or logged when it's nil.
+func (e *Executor) RunScheduler(ctx context.Context, onEvent func(SchedulerEvent)) error {
+	if err := e.setupIfNeeded(ctx); err != nil {
+		return err
+	}
+
+	var (
+		scheduled []*scheduledTask
+		names     []string
+	)
+	for _, t := range e.Taskfile.Tasks.Values() {
+		if t.Schedule == nil {
+			continue
+		}
+		schedule, err := cron.Parse(t.Schedule.Cron)
+		if err != nil {
+			return err
+		}
+		overlap := t.Schedule.Overlap
+		if overlap == "" {
+			overlap = taskfile.OverlapSkip
+		}
+		scheduled = append(scheduled, &scheduledTask{name: t.Task, schedule: schedule, overlap: overlap})
+		names = append(names, t.Task)
+	}
+	if len(scheduled) == 0 {
+		return errors.New("task: No task has a schedule")
+	}
+
+	var emitMutex sync.Mutex
+	emit := func(event SchedulerEvent) {
+		event.Time = time.Now()
+		emitMutex.Lock()
+		defer emitMutex.Unlock()
+		if onEvent != nil {
+			onEvent(event)
+		} else {
+			e.logSchedulerEvent(event)
+		}
+	}
+
+	e.scheduling = true
+	defer func() { e.scheduling = false }()
+	e.Logger.Errf(logger.Green, "task: Started the scheduler for tasks: %s\n", strings.Join(names, ", "))
+
+	var wg sync.WaitGroup
+	for _, st := range scheduled {
+		st := st
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				next := st.schedule.Next(time.Now())
+				if next.IsZero() {
+					e.Logger.Errf(logger.Yellow, "task: [%s] Its schedule is never due\n", st.name)
+					return
+				}
+				emit(SchedulerEvent{Task: st.name, Event: SchedulerEventScheduled, Next: &next})
+
+				timer := time.NewTimer(time.Until(next))
+				select {
+				case <-timer.C:
+				case <-ctx.Done():
+					timer.Stop()
+					return
+				}
+				e.runScheduledTask(ctx, st, &wg, emit)
+			}
+		}()
+	}
+	wg.Wait()
+	return nil
+}
+
+// runScheduledTask runs the task now that it's due, according to its overlap
+// policy.
+func (e *Executor) runScheduledTask(ctx context.Context, st *scheduledTask, wg *sync.WaitGroup, emit func(SchedulerEvent)) {
+	st.mutex.Lock()
+	defer st.mutex.Unlock()
+	if st.running > 0 {
+		switch st.overlap {
+		case taskfile.OverlapSkip:
+			emit(SchedulerEvent{Task: st.name, Event: SchedulerEventSkipped})
+			return
+		case taskfile.OverlapQueue:
+			st.queued++
+			emit(SchedulerEvent{Task: st.name, Event: SchedulerEventQueued})
+			return
+		}
+	}
+	st.running++
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for {
+			emit(SchedulerEvent{Task: st.name, Event: SchedulerEventStarted})
+			start := time.Now()
+			err := e.RunTask(withRunHashes(ctx), taskfile.Call{Task: st.name, Direct: true})
+			event := SchedulerEvent{Task: st.name, Event: SchedulerEventFinished, Duration: time.Since(start).Seconds()}
+			switch {
+			case err != nil && ctx.Err() != nil:
+				event.Event, event.Error = SchedulerEventInterrupted, err.Error()
+			case err != nil:
+				event.Event, event.ExitCode, event.Error = SchedulerEventFailed, exitCode(err), err.Error()
+			}
+			emit(event)
+
+			// The queued runs follow this one
+			st.mutex.Lock()
+			if st.queued > 0 && ctx.Err() == nil {
+				st.queued--
+				st.mutex.Unlock()
+				continue
+			}
+			st.running--
+			st.mutex.Unlock()
+			return
+		}
+	}()
+}
+
+func (e *Executor) logSchedulerEvent(event SchedulerEvent) {
+	switch event.Event {
+	case SchedulerEventScheduled:
+		e.Logger.VerboseErrf(logger.Magenta, "task: [%s] Next run at %s\n", event.Task, event.Next.Format(time.RFC3339))
+	case SchedulerEventStarted:
+		e.Logger.VerboseErrf(logger.Magenta, "task: [%s] Started by its schedule\n", event.Task)
+	case SchedulerEventFinished:
+		e.Logger.Errf(logger.Green, "task: [%s] Finished in %s\n", event.Task, time.Duration(event.Duration*float64(time.Second)).Round(time.Millisecond))
+	case SchedulerEventFailed:
+		e.Logger.Errf(logger.Red, "task: [%s] Failed in %s: %s\n", event.Task, time.Duration(event.Duration*float64(time.Second)).Round(time.Millisecond), event.Error)
+	case SchedulerEventInterrupted:
+		e.Logger.Errf(logger.Yellow, "task: [%s] Interrupted by the end of the scheduler\n", event.Task)
+	case SchedulerEventSkipped:
+		e.Logger.Errf(logger.Yellow, "task: [%s] Skipped, as its previous run is still running\n", event.Task)
+	case SchedulerEventQueued:
+		e.Logger.Errf(logger.Yellow, "task: [%s] Queued after its previous run\n", event.Task)
+	}
+}
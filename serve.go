@@ -0,0 +1,640 @@
+package task
+
+import (
+	"bytes"
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nuvolaris/task/v3/internal/logger"
+	"github.com/nuvolaris/task/v3/taskfile"
+)
+
+// RunStatus is the status of a run started through a Server.
+type RunStatus string
+
+const (
+	// RunStatusQueued means that the run waits for the ones before it
+	RunStatusQueued  RunStatus = "queued"
+	RunStatusRunning RunStatus = "running"
+	// RunStatusCompleted means that all the tasks called ran successfully or
+	// were up to date
+	RunStatusCompleted RunStatus = "completed"
+	RunStatusFailed    RunStatus = "failed"
+	// RunStatusCancelled means that the run was cancelled, before or while
+	// running
+	RunStatusCancelled RunStatus = "cancelled"
+)
+
+// A Server is the experimental HTTP API of an Executor, for dashboards and
+// editors to list the tasks, run them and follow their output:
+//
+//	GET    /tasks           the tasks, like --list-all --json
+//	POST   /runs            starts a run of {"calls": [{"task": "...", "vars": {...}}]}
+//	GET    /runs            the runs started
+//	GET    /runs/<id>       the status of a run and the result of each call
+//	GET    /runs/<id>/logs  the output of the run, as server-sent events
+//	DELETE /runs/<id>       cancels a run
+//
+// As an Executor runs one run at a time, the runs are queued. The output of
+// their commands is kept by the Server, through Executor.TaskWriters, which
+// it replaces.
+//
+// Since a run executes commands, the requests are checked before anything
+// else: the ones coming from a web page of another origin, and the ones for a
+// host that isn't the loopback interface (as sent after DNS rebinding), are
+// refused, and runs must be started with a JSON body, which pages of other
+// origins can't send without CORS. When the Token is set, the requests must
+// carry it as "Authorization: Bearer <token>" and may be for any host.
+//
+// As a Server usually lives as long as a daemon, it only keeps the last
+// MaxRuns finished runs, and the last MaxRunLogs lines of output of each run.
+type Server struct {
+	// Token is the token the requests must carry, if any
+	Token string
+	// MaxRuns is the number of finished runs kept, 100 by default. The oldest
+	// ones are forgotten.
+	MaxRuns int
+	// MaxRunLogs is the number of lines of output kept for each run, 10000 by
+	// default. The first ones are dropped, also for the logs streams that
+	// didn't send them yet.
+	MaxRunLogs int
+
+	e      *Executor
+	ctx    context.Context
+	cancel context.CancelFunc
+	queue  chan *serverRun
+
+	mutex   sync.Mutex
+	runs    []*serverRun
+	lastID  int
+	current *serverRun
+}
+
+type serverRun struct {
+	ID       int            `json:"id"`
+	Status   RunStatus      `json:"status"`
+	Calls    []serverCall   `json:"calls"`
+	Results  []serverResult `json:"results,omitempty"`
+	Error    string         `json:"error,omitempty"`
+	Queued   time.Time      `json:"queued"`
+	Started  *time.Time     `json:"started,omitempty"`
+	Finished *time.Time     `json:"finished,omitempty"`
+	// DroppedLogs is the number of lines of output dropped, past MaxRunLogs
+	DroppedLogs int `json:"dropped_logs,omitempty"`
+	cancel      context.CancelFunc
+	logs        []serverLog
+	// changed is closed, and replaced, whenever there's new output or the
+	// status changes
+	changed chan struct{}
+}
+
+type serverCall struct {
+	Task string            `json:"task"`
+	Vars map[string]string `json:"vars,omitempty"`
+}
+
+func (c serverCall) call() taskfile.Call {
+	vars := &taskfile.Vars{}
+	for k, v := range c.Vars {
+		vars.Set(k, taskfile.Var{Static: v})
+	}
+	return taskfile.Call{Task: c.Task, Vars: vars, Direct: true}
+}
+
+type serverResult struct {
+	Task     string    `json:"task"`
+	State    TaskState `json:"state"`
+	UpToDate bool      `json:"up_to_date"`
+	Duration float64   `json:"duration"`
+	ExitCode int       `json:"exit_code"`
+	Error    string    `json:"error,omitempty"`
+}
+
+type serverLog struct {
+	Task   string `json:"task"`
+	Stream string `json:"stream"`
+	Line   string `json:"line"`
+}
+
+// NewServer creates the Server of the Executor and starts running the queued
+// runs, until it's closed.
+func NewServer(e *Executor) *Server {
+	ctx, cancel := context.WithCancel(context.Background())
+	s := &Server{
+		e:      e,
+		ctx:    ctx,
+		cancel: cancel,
+		queue:  make(chan *serverRun, 100),
+		runs:   []*serverRun{},
+
+		MaxRuns:    100,
+		MaxRunLogs: 10000,
+	}
+	e.TaskWriters = s.taskWriters
+	go s.work()
+	return s
+}
+
+// Close cancels the runs and stops running the queued ones.
+func (s *Server) Close() {
+	s.cancel()
+}
+
+// Serve serves the API of a Server on the given address (e.g. localhost:8080)
+// until the context is done. Addresses other than the loopback interface are
+// refused without a token, which the requests must then carry.
+func (e *Executor) Serve(ctx context.Context, addr string, token string) error {
+	if token == "" && !isLoopback(addr) {
+		return fmt.Errorf("task: Refusing to serve the API on %q without a token, since anyone reaching it could run commands. Use a loopback address, like localhost:8080, or set a token", addr)
+	}
+	if err := e.setupIfNeeded(ctx); err != nil {
+		return err
+	}
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("task: Failed to listen: %w", err)
+	}
+
+	s := NewServer(e)
+	s.Token = token
+	defer s.Close()
+	server := &http.Server{Handler: s}
+	go func() {
+		<-ctx.Done()
+		_ = server.Close()
+	}()
+
+	e.Logger.Errf(logger.Green, "task: Serving the API on http://%s\n", listener.Addr())
+	if err := server.Serve(listener); err != nil && err != http.ErrServerClosed {
+		return fmt.Errorf("task: Failed to serve: %w", err)
+	}
+	return nil
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if status, err := s.check(r); err != nil {
+		http.Error(w, err.Error(), status)
+		return
+	}
+
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	switch {
+	case len(parts) == 1 && parts[0] == "tasks":
+		s.allow(w, r, http.MethodGet, s.listTasks)
+	case len(parts) == 1 && parts[0] == "runs":
+		switch r.Method {
+		case http.MethodGet:
+			s.listRuns(w, r)
+		case http.MethodPost:
+			s.startRun(w, r)
+		default:
+			w.Header().Set("Allow", "GET, POST")
+			http.Error(w, "task: Method not allowed", http.StatusMethodNotAllowed)
+		}
+	case len(parts) == 2 && parts[0] == "runs":
+		run := s.run(w, parts[1])
+		if run == nil {
+			return
+		}
+		switch r.Method {
+		case http.MethodGet:
+			s.writeRun(w, http.StatusOK, run)
+		case http.MethodDelete:
+			s.deleteRun(w, run)
+		default:
+			w.Header().Set("Allow", "GET, DELETE")
+			http.Error(w, "task: Method not allowed", http.StatusMethodNotAllowed)
+		}
+	case len(parts) == 3 && parts[0] == "runs" && parts[2] == "logs":
+		if run := s.run(w, parts[1]); run != nil {
+			s.allow(w, r, http.MethodGet, func(w http.ResponseWriter, r *http.Request) {
+				s.streamLogs(w, r, run)
+			})
+		}
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// check returns the error, and its status, of the requests that must be
+// refused.
+func (s *Server) check(r *http.Request) (int, error) {
+	if s.Token != "" {
+		auth := r.Header.Get("Authorization")
+		token, ok := strings.CutPrefix(auth, "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(token), []byte(s.Token)) != 1 {
+			return http.StatusUnauthorized, errors.New("task: Missing or invalid token")
+		}
+	} else if !isLoopback(r.Host) {
+		return http.StatusForbidden, fmt.Errorf("task: Host %q is not allowed", r.Host)
+	}
+	if origin := r.Header.Get("Origin"); origin != "" {
+		if u, err := url.Parse(origin); err != nil || u.Host != r.Host {
+			return http.StatusForbidden, fmt.Errorf("task: Origin %q is not allowed", origin)
+		}
+	}
+	return 0, nil
+}
+
+// isLoopback returns whether the host, with or without a port, is the
+// loopback interface.
+func isLoopback(host string) bool {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	if strings.EqualFold(host, "localhost") {
+		return true
+	}
+	ip := net.ParseIP(strings.Trim(host, "[]"))
+	return ip != nil && ip.IsLoopback()
+}
+
+func (s *Server) allow(w http.ResponseWriter, r *http.Request, method string, handler http.HandlerFunc) {
+	if r.Method != method {
+		w.Header().Set("Allow", method)
+		http.Error(w, "task: Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	handler(w, r)
+}
+
+func (s *Server) listTasks(w http.ResponseWriter, r *http.Request) {
+	if err := s.e.setupIfNeeded(r.Context()); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	tasks, err := s.e.GetTaskList(FilterOutInternal)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	output, err := s.e.ToEditorOutput(tasks)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, output)
+}
+
+func (s *Server) listRuns(w http.ResponseWriter, r *http.Request) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	writeJSON(w, http.StatusOK, s.runs)
+}
+
+func (s *Server) startRun(w http.ResponseWriter, r *http.Request) {
+	if mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); mediaType != "application/json" {
+		http.Error(w, "task: Runs must be started with an application/json body", http.StatusUnsupportedMediaType)
+		return
+	}
+	if err := s.e.setupIfNeeded(r.Context()); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	var body struct {
+		Calls []serverCall `json:"calls"`
+	}
+	if err := json.NewDecoder(io.LimitReader(r.Body, 1<<20)).Decode(&body); err != nil {
+		http.Error(w, fmt.Sprintf("task: Invalid run: %v", err), http.StatusBadRequest)
+		return
+	}
+	run, err := s.enqueue(body.Calls)
+	if err == errTooManyRuns {
+		s.writeRun(w, http.StatusServiceUnavailable, run)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	w.Header().Set("Location", fmt.Sprintf("/runs/%d", run.ID))
+	s.writeRun(w, http.StatusAccepted, run)
+}
+
+var errTooManyRuns = errors.New("task: Too many runs are queued")
+
+// enqueue queues a run of the calls. When too many runs are queued already,
+// the run is cancelled right away and errTooManyRuns is returned with it.
+func (s *Server) enqueue(calls []serverCall) (*serverRun, error) {
+	if len(calls) == 0 {
+		return nil, errors.New("task: The run has no calls")
+	}
+	for _, c := range calls {
+		if c.Task == "" {
+			return nil, errors.New("task: A call has no task")
+		}
+	}
+
+	s.mutex.Lock()
+	s.lastID++
+	run := &serverRun{
+		ID:      s.lastID,
+		Status:  RunStatusQueued,
+		Calls:   calls,
+		Queued:  time.Now(),
+		changed: make(chan struct{}),
+	}
+	s.runs = append(s.runs, run)
+	s.mutex.Unlock()
+
+	select {
+	case s.queue <- run:
+	default:
+		s.finish(run, RunStatusCancelled, nil, errTooManyRuns)
+		return run, errTooManyRuns
+	}
+	s.e.Logger.VerboseErrf(logger.Magenta, "task: queued run %d\n", run.ID)
+	return run, nil
+}
+
+// run returns the run of the ID, or writes that there's none.
+func (s *Server) run(w http.ResponseWriter, id string) *serverRun {
+	i, err := strconv.Atoi(id)
+	run := s.runByID(i)
+	if err != nil || run == nil {
+		http.Error(w, fmt.Sprintf("task: Run %q not found", id), http.StatusNotFound)
+		return nil
+	}
+	return run
+}
+
+func (s *Server) runByID(id int) *serverRun {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	for _, run := range s.runs {
+		if run.ID == id {
+			return run
+		}
+	}
+	return nil
+}
+
+func (s *Server) writeRun(w http.ResponseWriter, status int, run *serverRun) {
+	writeJSON(w, status, s.snapshot(run))
+}
+
+// snapshot returns the run as JSON, as it's changed by the runs.
+func (s *Server) snapshot(run *serverRun) json.RawMessage {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	b, _ := json.Marshal(run)
+	return b
+}
+
+func (s *Server) deleteRun(w http.ResponseWriter, run *serverRun) {
+	s.cancelRun(run)
+	s.writeRun(w, http.StatusAccepted, run)
+}
+
+// cancelRun cancels the run if it's queued or running.
+func (s *Server) cancelRun(run *serverRun) {
+	s.mutex.Lock()
+	switch run.Status {
+	case RunStatusQueued:
+		s.mutex.Unlock()
+		s.finish(run, RunStatusCancelled, nil, nil)
+	case RunStatusRunning:
+		run.cancel()
+		s.mutex.Unlock()
+	default:
+		s.mutex.Unlock()
+	}
+}
+
+// streamLogs sends the output of the run as it's written, after the one
+// written before, as "log" events. An "end" event with the run is sent once
+// the run finished.
+func (s *Server) streamLogs(w http.ResponseWriter, r *http.Request, run *serverRun) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "task: Streaming is not supported", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+
+	s.follow(r.Context(), run, func(logs []serverLog, status json.RawMessage, finished bool) {
+		for _, l := range logs {
+			b, _ := json.Marshal(l)
+			fmt.Fprintf(w, "event: log\ndata: %s\n\n", b)
+		}
+		if finished {
+			fmt.Fprintf(w, "event: end\ndata: %s\n\n", status)
+		}
+		flusher.Flush()
+	})
+}
+
+// follow calls fn with the output of the run written since the last call,
+// and the run once its status changed, until the run finished or the context
+// is done. The first call gets the output written before, and the run.
+func (s *Server) follow(ctx context.Context, run *serverRun, fn func(logs []serverLog, status json.RawMessage, finished bool)) {
+	// sent counts the lines dropped too, which are skipped
+	sent := 0
+	var lastStatus RunStatus
+	for {
+		s.mutex.Lock()
+		logs := run.logs
+		if i := sent - run.DroppedLogs; i > 0 {
+			logs = logs[i:]
+		}
+		sent = run.DroppedLogs + len(run.logs)
+		finished := run.Finished != nil
+		changed := run.changed
+		var status json.RawMessage
+		if run.Status != lastStatus {
+			lastStatus = run.Status
+			status, _ = json.Marshal(run)
+		}
+		s.mutex.Unlock()
+
+		fn(logs, status, finished)
+		if finished {
+			return
+		}
+
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// work runs the queued runs, one at a time.
+func (s *Server) work() {
+	for {
+		select {
+		case run := <-s.queue:
+			s.start(run)
+		case <-s.ctx.Done():
+			return
+		}
+	}
+}
+
+func (s *Server) start(run *serverRun) {
+	ctx, cancel := context.WithCancel(s.ctx)
+	defer cancel()
+
+	s.mutex.Lock()
+	if run.Status != RunStatusQueued {
+		s.mutex.Unlock()
+		return
+	}
+	now := time.Now()
+	run.Status, run.Started, run.cancel = RunStatusRunning, &now, cancel
+	s.current = run
+	s.notify(run)
+	s.mutex.Unlock()
+
+	calls := make([]taskfile.Call, len(run.Calls))
+	for i, c := range run.Calls {
+		calls[i] = c.call()
+	}
+	s.e.Logger.VerboseErrf(logger.Magenta, "task: started run %d\n", run.ID)
+	results, err := s.e.RunWithResults(ctx, calls...)
+
+	status := RunStatusCompleted
+	switch {
+	case ctx.Err() != nil:
+		status = RunStatusCancelled
+	case err != nil:
+		status = RunStatusFailed
+	}
+	s.finish(run, status, results, err)
+}
+
+// finish records the end of the run and tells the ones following it.
+func (s *Server) finish(run *serverRun, status RunStatus, results Results, err error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.current == run {
+		s.current = nil
+	}
+	now := time.Now()
+	run.Status, run.Finished = status, &now
+	if err != nil {
+		run.Error = err.Error()
+	}
+	for _, cr := range results {
+		result := serverResult{
+			Task:     cr.Task,
+			State:    cr.State,
+			UpToDate: cr.UpToDate,
+			Duration: cr.Duration.Seconds(),
+			ExitCode: cr.ExitCode,
+		}
+		if cr.Err != nil {
+			result.Error = cr.Err.Error()
+		}
+		run.Results = append(run.Results, result)
+	}
+	s.notify(run)
+	s.forgetRuns()
+}
+
+// forgetRuns forgets the oldest finished runs, past MaxRuns. It must be called
+// with the mutex locked.
+func (s *Server) forgetRuns() {
+	finished := 0
+	for _, run := range s.runs {
+		if run.Finished != nil {
+			finished++
+		}
+	}
+	runs := s.runs[:0]
+	for _, run := range s.runs {
+		if run.Finished != nil && finished > s.MaxRuns {
+			finished--
+			continue
+		}
+		runs = append(runs, run)
+	}
+	for i := len(runs); i < len(s.runs); i++ {
+		s.runs[i] = nil
+	}
+	s.runs = runs
+}
+
+// notify wakes up the ones following the run. It must be called with the
+// mutex locked.
+func (s *Server) notify(run *serverRun) {
+	close(run.changed)
+	run.changed = make(chan struct{})
+}
+
+// taskWriters gives the writers keeping the output of the tasks of the
+// current run.
+func (s *Server) taskWriters(taskName string) (io.Writer, io.Writer) {
+	s.mutex.Lock()
+	run := s.current
+	s.mutex.Unlock()
+	if run == nil {
+		return nil, nil
+	}
+	return &serverLogWriter{s: s, run: run, task: taskName, stream: "stdout"},
+		&serverLogWriter{s: s, run: run, task: taskName, stream: "stderr"}
+}
+
+// serverLogWriter adds each line written to the output of a run.
+type serverLogWriter struct {
+	s      *Server
+	run    *serverRun
+	task   string
+	stream string
+	buf    bytes.Buffer
+}
+
+func (w *serverLogWriter) Write(p []byte) (int, error) {
+	w.buf.Write(p)
+	for {
+		i := bytes.IndexByte(w.buf.Bytes(), '\n')
+		if i == -1 {
+			return len(p), nil
+		}
+		line := strings.TrimSuffix(string(w.buf.Next(i + 1)[:i]), "\r")
+		w.add(line)
+	}
+}
+
+// Close adds the last line, if it didn't end with a newline.
+func (w *serverLogWriter) Close() error {
+	if w.buf.Len() > 0 {
+		w.add(w.buf.String())
+		w.buf.Reset()
+	}
+	return nil
+}
+
+func (w *serverLogWriter) add(line string) {
+	w.s.mutex.Lock()
+	defer w.s.mutex.Unlock()
+	w.run.logs = append(w.run.logs, serverLog{Task: w.task, Stream: w.stream, Line: line})
+	if dropped := len(w.run.logs) - w.s.MaxRunLogs; dropped > 0 {
+		w.run.logs = w.run.logs[dropped:]
+		w.run.DroppedLogs += dropped
+	}
+	w.s.notify(w.run)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	_ = encoder.Encode(v)
+}
@@ -2,38 +2,64 @@ package task
 
 import (
 	"context"
-	"errors"
+	"crypto/sha256"
 	"fmt"
 	"os"
 	"path/filepath"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/Masterminds/semver/v3"
 	"github.com/sajari/fuzzy"
 
+	"github.com/nuvolaris/task/v3/errors"
 	compilerv2 "github.com/nuvolaris/task/v3/internal/compiler/v2"
 	compilerv3 "github.com/nuvolaris/task/v3/internal/compiler/v3"
+	"github.com/nuvolaris/task/v3/internal/deprecations"
 	"github.com/nuvolaris/task/v3/internal/execext"
+	"github.com/nuvolaris/task/v3/internal/experiments"
 	"github.com/nuvolaris/task/v3/internal/filepathext"
 	"github.com/nuvolaris/task/v3/internal/logger"
 	"github.com/nuvolaris/task/v3/internal/output"
+	"github.com/nuvolaris/task/v3/internal/templater"
+	"github.com/nuvolaris/task/v3/internal/term"
 	"github.com/nuvolaris/task/v3/taskfile"
 	"github.com/nuvolaris/task/v3/taskfile/read"
 )
 
+// Setup reads the Taskfile and prepares the Executor to run its tasks.
 func (e *Executor) Setup() error {
+	return e.SetupWithContext(context.Background())
+}
+
+// SetupWithContext is like Setup, but the context cancels reading the
+// Taskfiles, like downloading the remote ones, and the commands of the dynamic
+// variables.
+func (e *Executor) SetupWithContext(ctx context.Context) error {
 	e.setupLogger()
+	e.deprecations = &deprecations.Reporter{Logger: e.Logger, Strict: e.Strict}
+	if err := e.validate(); err != nil {
+		return err
+	}
 	if err := e.setCurrentDir(); err != nil {
 		return err
 	}
 	if err := e.setupTempDir(); err != nil {
 		return err
 	}
-	if err := e.readTaskfile(); err != nil {
+	if err := e.readTaskfile(ctx); err != nil {
+		return err
+	}
+	if err := e.checkDepCycles(); err != nil {
+		return err
+	}
+	if err := e.setupTheme(); err != nil {
 		return err
 	}
+	templater.SetSeed(e.Taskfile.Seed)
 	e.setupFuzzyModel()
+	e.setupEnvPolicy()
 	e.setupStdFiles()
 	if err := e.setupOutput(); err != nil {
 		return err
@@ -41,22 +67,27 @@ func (e *Executor) Setup() error {
 	if err := e.setupCompiler(); err != nil {
 		return err
 	}
-	if err := e.readDotEnvFiles(); err != nil {
+	if err := e.readDotEnvFiles(ctx); err != nil {
 		return err
 	}
 
 	if err := e.doVersionChecks(); err != nil {
 		return err
 	}
+	if err := e.checkDeprecatedFuncs(); err != nil {
+		return err
+	}
 	e.setupDefaults()
 	e.setupConcurrencyState()
 
+	e.setupDone = true
 	return nil
 }
 
 func (e *Executor) setCurrentDir() error {
-	// If the entrypoint is already set, we don't need to do anything
-	if e.Entrypoint != "" {
+	// If the entrypoint is already set, we don't need to do anything, but a
+	// remote one still runs in the current directory
+	if e.Entrypoint != "" && (!read.IsRemoteURI(e.Entrypoint) || e.Dir != "") {
 		return nil
 	}
 
@@ -68,10 +99,21 @@ func (e *Executor) setCurrentDir() error {
 		}
 		e.Dir = wd
 	}
+	if e.Entrypoint != "" {
+		return nil
+	}
 
 	// Search for a taskfile
 	root, err := read.ExistsWalk(e.Dir)
 	if err != nil {
+		// Without a Taskfile, known kinds of projects run their usual tasks
+		var notFoundErr errors.TaskfileNotFoundError
+		if experiments.ZeroConfig && errors.As(err, &notFoundErr) {
+			if e.detectedProject = detectProject(e.Dir); e.detectedProject != nil {
+				e.Entrypoint = defaultTaskfileName
+				return nil
+			}
+		}
 		return err
 	}
 	e.Dir = filepath.Dir(root)
@@ -80,13 +122,22 @@ func (e *Executor) setCurrentDir() error {
 	return nil
 }
 
-func (e *Executor) readTaskfile() error {
+func (e *Executor) readTaskfile(ctx context.Context) error {
 	uri := filepath.Join(e.Dir, e.Entrypoint)
-	node, err := read.NewNode(uri, e.Insecure)
-	if err != nil {
+	if read.IsRemoteURI(e.Entrypoint) {
+		uri = e.Entrypoint
+	}
+	var node read.Node
+	var err error
+	if e.detectedProject != nil {
+		e.Logger.Errf(logger.Yellow, "task: No Taskfile found, using the usual tasks of a %s project. Write them to a Taskfile with \"task --init\":\n", e.detectedProject.name)
+		e.Logger.Errf(logger.Default, "\n%s\n", e.detectedProject.taskfile)
+		node = read.NewMemoryNode(uri, []byte(e.detectedProject.taskfile))
+	} else if node, err = read.NewNode(uri, e.Insecure); err != nil {
 		return err
 	}
 	e.Taskfile, err = read.Taskfile(
+		read.WithTempDirTracker(ctx, e.trackTempDir),
 		node,
 		e.Insecure,
 		e.Download,
@@ -101,20 +152,21 @@ func (e *Executor) readTaskfile() error {
 }
 
 func (e *Executor) setupFuzzyModel() {
-	if e.Taskfile != nil {
+	if e.Taskfile == nil {
 		return
 	}
 
 	model := fuzzy.NewModel()
 	model.SetThreshold(1) // because we want to build grammar based on every task name
 
+	// Internal tasks can't be called directly, so they are never suggested
 	var words []string
-	for _, taskName := range e.Taskfile.Tasks.Keys() {
-		words = append(words, taskName)
-
-		for _, task := range e.Taskfile.Tasks.Values() {
-			words = append(words, task.Aliases...)
+	for _, task := range e.Taskfile.Tasks.Values() {
+		if task.Internal {
+			continue
 		}
+		words = append(words, task.Task)
+		words = append(words, task.Aliases...)
 	}
 
 	model.Train(words)
@@ -140,9 +192,72 @@ func (e *Executor) setupTempDir() error {
 		e.TempDir = filepathext.SmartJoin(e.Dir, os.Getenv("TASK_TEMP_DIR"))
 	}
 
+	// If the project directory is read-only (e.g. source mounted into a
+	// container), fall back to a writable location outside of the project so
+	// fingerprinting doesn't fail on every run.
+	if !isWritableDir(e.TempDir) {
+		fallback, err := fallbackTempDir(e.Dir)
+		if err != nil {
+			return err
+		}
+		e.Logger.VerboseErrf(logger.Yellow, "task: %q is not writable, using %q to store task state\n", e.TempDir, fallback)
+		e.TempDir = fallback
+	}
+
 	return nil
 }
 
+// isWritableDir reports whether dir, or its nearest existing parent when dir
+// does not exist yet, accepts new files. Nothing is created in place of dir.
+func isWritableDir(dir string) bool {
+	for {
+		info, err := os.Stat(dir)
+		if err == nil {
+			if !info.IsDir() {
+				return false
+			}
+			f, err := os.CreateTemp(dir, ".task-probe-*")
+			if err != nil {
+				return false
+			}
+			f.Close()
+			_ = os.Remove(f.Name())
+			return true
+		}
+		if !os.IsNotExist(err) {
+			return false
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return false
+		}
+		dir = parent
+	}
+}
+
+// fallbackTempDir returns a per-project temp dir inside the user cache
+// directory (or the system temp dir when there's no cache directory).
+func fallbackTempDir(dir string) (string, error) {
+	base, err := os.UserCacheDir()
+	if err != nil || !isWritableDir(base) {
+		base = os.TempDir()
+	}
+	projectDir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256([]byte(projectDir))
+	name := fmt.Sprintf("%s-%x", filepath.Base(projectDir), sum[:4])
+	return filepathext.SmartJoin(base, filepath.Join("task", name)), nil
+}
+
+func (e *Executor) setupEnvPolicy() {
+	e.envPolicy = e.Taskfile.EnvPolicy
+	if e.Hermetic {
+		e.envPolicy = e.envPolicy.Hermetic()
+	}
+}
+
 func (e *Executor) setupStdFiles() {
 	if e.Stdin == nil {
 		e.Stdin = os.Stdin
@@ -160,18 +275,71 @@ func (e *Executor) setupLogger() {
 		Stdout:  e.Stdout,
 		Stderr:  e.Stderr,
 		Verbose: e.Verbose,
-		Color:   e.Color,
+		Color:   logger.UseColor(e.Color),
 	}
 }
 
 func (e *Executor) setupOutput() error {
 	if !e.OutputStyle.IsSet() {
+		timestamps := e.OutputStyle.Timestamps
 		e.OutputStyle = e.Taskfile.Output
+		if timestamps != "" {
+			e.OutputStyle.Timestamps = timestamps
+		}
+	}
+
+	style := e.OutputStyle
+	// Keep the options of the style of the Taskfile when it's only chosen by
+	// the flag
+	if style.Name == e.Taskfile.Output.Name {
+		if style.Name == "prefixed" && !style.Prefixed.IsSet() {
+			style.Prefixed = e.Taskfile.Output.Prefixed
+		}
+		if style.Timestamps == "" {
+			style.Timestamps = e.Taskfile.Output.Timestamps
+		}
+	}
+	if !e.Color && style.Prefixed.Color != "" {
+		style.Prefixed.Color = taskfile.OutputColorNever
 	}
 
 	var err error
-	e.Output, err = output.BuildFor(&e.OutputStyle)
-	return err
+	e.Output, err = output.BuildFor(&style)
+	if err != nil {
+		return err
+	}
+	if group, ok := e.Output.(output.Group); ok && group.Nested {
+		e.nestedGroup = &group
+	}
+
+	// The progress dashboard needs a terminal and a run that ends, so it's
+	// replaced by the interleaved output otherwise
+	if progress, ok := e.Output.(*output.Progress); ok {
+		if e.Watch || !term.IsTerminalWriter(e.Stdout) {
+			e.Output = output.Interleaved{}
+		} else {
+			progress.Writer = e.Stdout
+			e.Logger.Stdout = progress.Printer()
+			e.Logger.Stderr = progress.Printer()
+			e.tracker = progress
+		}
+	}
+
+	if style.Timestamps != "" {
+		e.Output = output.Timestamped{
+			Output: e.Output,
+			Format: style.Timestamps,
+			Start:  time.Now(),
+		}
+	}
+	if (e.StripANSI || e.Taskfile.StripANSI) && !term.IsTerminalWriter(e.Stdout) {
+		e.Output = output.StripANSI{Output: e.Output}
+	}
+	// Outermost, so the timestamps don't make every line differ
+	if e.Watch && e.WatchDelta {
+		e.Output = &output.Delta{Output: e.Output}
+	}
+	return nil
 }
 
 func (e *Executor) setupCompiler() error {
@@ -203,19 +371,23 @@ func (e *Executor) setupCompiler() error {
 			UserWorkingDir: e.UserWorkingDir,
 			TaskfileEnv:    e.Taskfile.Env,
 			TaskfileVars:   e.Taskfile.Vars,
+			EnvPolicy:      e.envPolicy,
+			SlashPaths:     e.SlashPaths,
+			Builtins:       e.Taskfile.Builtins,
 			Logger:         e.Logger,
+			PromptVar:      e.promptVar,
 		}
 	}
 
 	return nil
 }
 
-func (e *Executor) readDotEnvFiles() error {
+func (e *Executor) readDotEnvFiles(ctx context.Context) error {
 	if e.Taskfile.Version.LessThan(taskfile.V3) {
 		return nil
 	}
 
-	env, err := read.Dotenv(e.Compiler, e.Taskfile, e.Dir)
+	env, err := read.Dotenv(ctx, e.Compiler, e.Taskfile, e.Dir)
 	if err != nil {
 		return err
 	}
@@ -229,6 +401,20 @@ func (e *Executor) readDotEnvFiles() error {
 	return err
 }
 
+// setupTheme applies the colors of the "style" of the Taskfile.
+func (e *Executor) setupTheme() error {
+	style := e.Taskfile.Style.Elements()
+	if len(style) == 0 {
+		return nil
+	}
+	theme, err := logger.DefaultTheme().WithStyle(style)
+	if err != nil {
+		return err
+	}
+	e.Logger.Theme = theme
+	return nil
+}
+
 func (e *Executor) setupDefaults() {
 	// Color available only on v3
 	if e.Taskfile.Version.LessThan(taskfile.V3) {
@@ -273,7 +459,9 @@ func (e *Executor) doVersionChecks() error {
 	}
 
 	if v.LessThan(taskfile.V3) {
-		e.Logger.Errf(logger.Yellow, "task: version 2 schemas are deprecated and will be removed in a future release\nSee https://github.com/go-task/task/issues/1197 for more details\n")
+		if err := e.deprecations.Report(deprecations.Version2Schema, "version 2 schemas are deprecated and will be removed in a future release"); err != nil {
+			return err
+		}
 	}
 
 	// consider as equal to the greater version if round
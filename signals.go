@@ -1,23 +1,54 @@
 package task
 
 import (
+	"context"
+	"fmt"
+	"io"
 	"os"
 	"os/signal"
+	"strings"
+	"sync"
 	"syscall"
 
+	"golang.org/x/exp/slices"
+
+	"github.com/nuvolaris/task/v3/internal/execext"
 	"github.com/nuvolaris/task/v3/internal/logger"
+	"github.com/nuvolaris/task/v3/internal/term"
+	"github.com/nuvolaris/task/v3/taskfile"
 )
 
+// DefaultForwardedSignals are the signals forwarded to the commands of the
+// tasks without `forward_signals:`, so servers can reload or stop gracefully.
+var DefaultForwardedSignals = []string{"SIGTERM", "SIGHUP", "SIGUSR1", "SIGUSR2"}
+
+// signalNames are the signals a task can forward. Not all of them can be
+// forwarded on every OS.
+var signalNames = []string{"SIGHUP", "SIGINT", "SIGQUIT", "SIGTERM", "SIGUSR1", "SIGUSR2"}
+
 // NOTE(@andreynering): This function intercepts SIGINT and SIGTERM signals
 // so the Task process is not killed immediately and processes running have
 // time to do cleanup work.
 func (e *Executor) InterceptInterruptSignals() {
+	e.forwarder = &signalForwarder{processes: map[*os.Process]forwardedProcess{}}
+	e.forwardSignals()
+
 	ch := make(chan os.Signal, 3)
 	signal.Notify(ch, os.Interrupt, syscall.SIGTERM)
 
 	go func() {
 		for i := 1; i <= 3; i++ {
 			sig := <-ch
+			e.interrupted.Store(true)
+
+			// The commands in the process group of Task get the interrupts of
+			// the terminal, but not the ones sent to Task alone
+			switch sig {
+			case syscall.SIGTERM:
+				e.forwarder.forward(sig)
+			case os.Interrupt:
+				e.forwarder.forwardToGroups(sig)
+			}
 
 			if i < 3 {
 				e.Logger.Outf(logger.Yellow, "task: Signal received: %q\n", sig)
@@ -29,3 +60,139 @@ func (e *Executor) InterceptInterruptSignals() {
 		}
 	}()
 }
+
+// forwardSignals forwards the forwardable signals received by Task to the
+// running commands. When no command gets one, Task reacts to it like it did
+// before, which usually means exiting.
+func (e *Executor) forwardSignals() {
+	if len(forwardableSignals) == 0 {
+		return
+	}
+	ch := make(chan os.Signal, 3)
+	for _, sig := range forwardableSignals {
+		signal.Notify(ch, sig)
+	}
+
+	go func() {
+		for sig := range ch {
+			if n := e.forwarder.forward(sig); n > 0 {
+				e.Logger.VerboseErrf(logger.Yellow, "task: Signal %q forwarded to %d command(s)\n", sig, n)
+				continue
+			}
+			signal.Reset(sig)
+			if p, err := os.FindProcess(os.Getpid()); err == nil {
+				_ = p.Signal(sig)
+			}
+		}
+	}()
+}
+
+// forwardedSignals returns the signals forwarded to the commands of the task.
+func forwardedSignals(t *taskfile.Task) ([]os.Signal, error) {
+	names := t.ForwardSignals
+	if names == nil {
+		names = DefaultForwardedSignals
+	}
+	signals := make([]os.Signal, 0, len(names))
+	for _, name := range names {
+		name = strings.ToUpper(name)
+		if !strings.HasPrefix(name, "SIG") {
+			name = "SIG" + name
+		}
+		switch name {
+		case "SIGINT":
+			signals = append(signals, os.Interrupt)
+		case "SIGTERM":
+			signals = append(signals, syscall.SIGTERM)
+		default:
+			if !isSignalName(name) {
+				return nil, fmt.Errorf("task: Task %q can't forward the unknown signal %q, it should be one of %s", t.Name(), name, strings.Join(signalNames, ", "))
+			}
+			if sig, ok := forwardableSignals[name]; ok {
+				signals = append(signals, sig)
+			}
+		}
+	}
+	return signals, nil
+}
+
+func isSignalName(name string) bool {
+	for _, n := range signalNames {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
+
+// inOwnProcessGroup tells whether the commands of the task start in their own
+// process group, so the signals forwarded to them reach the processes they
+// start too, like the server started by a script. The ones that can read from
+// a terminal stay in the one of Task, since only the foreground process group
+// can read from it.
+func inOwnProcessGroup(ctx context.Context, t *taskfile.Task, stdin io.Reader) bool {
+	if restartOnWatch(ctx, t) {
+		return true
+	}
+	return !t.Interactive && !term.IsTerminalReader(stdin)
+}
+
+// signalForwarder keeps the processes started by the commands that are still
+// running, along with the signals forwarded to each of them.
+type signalForwarder struct {
+	mutex     sync.Mutex
+	processes map[*os.Process]forwardedProcess
+}
+
+type forwardedProcess struct {
+	signals []os.Signal
+	// group is set when the process leads its own process group, which gets
+	// the signals along with it
+	group bool
+}
+
+// add starts forwarding the signals to the process, and returns the function
+// that stops it once the process exits.
+func (f *signalForwarder) add(p *os.Process, signals []os.Signal, group bool) func() {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	f.processes[p] = forwardedProcess{signals: signals, group: group}
+	return func() {
+		f.mutex.Lock()
+		defer f.mutex.Unlock()
+		delete(f.processes, p)
+	}
+}
+
+// forward sends the signal to the processes that forward it, and returns how
+// many of them got it.
+func (f *signalForwarder) forward(sig os.Signal) int {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	var n int
+	for p, fp := range f.processes {
+		if slices.Contains(fp.signals, sig) && fp.signal(p, sig) == nil {
+			n++
+		}
+	}
+	return n
+}
+
+// forwardToGroups sends the signal to the processes in their own process
+// group, which don't get the ones of the terminal.
+func (f *signalForwarder) forwardToGroups(sig os.Signal) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	for p, fp := range f.processes {
+		if fp.group {
+			_ = fp.signal(p, sig)
+		}
+	}
+}
+
+func (fp forwardedProcess) signal(p *os.Process, sig os.Signal) error {
+	if fp.group {
+		return execext.SignalProcessGroup(p, sig)
+	}
+	return p.Signal(sig)
+}
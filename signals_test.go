@@ -189,6 +189,86 @@ func TestSignalSentToProcessGroup(t *testing.T) {
 	}
 }
 
+func TestSignalForwardedToCommands(t *testing.T) {
+	task, err := getTaskPath()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// sleepit doesn't handle SIGHUP, so it's terminated by it
+	testCases := map[string]struct {
+		task     string
+		wantExit func(t *testing.T, err error)
+		want     []string
+	}{
+		"forwarded to the command by default": {
+			task: "default",
+			wantExit: func(t *testing.T, err error) {
+				var exitErr *exec.ExitError
+				if !errors.As(err, &exitErr) || exitErr.ExitCode() != 201 {
+					t.Errorf("got %v; want exit status 201", err)
+				}
+			},
+			// 129 = 128 + SIGHUP
+			want: []string{"task: Failed to run task \"default\": exit status 129\n"},
+		},
+		"forwarded to the processes started by the command": {
+			task: "script",
+			wantExit: func(t *testing.T, err error) {
+				if err != nil {
+					t.Errorf("got %v; want no error", err)
+				}
+			},
+			want: []string{"command exited with 129\n"},
+		},
+		"not forwarded with an empty forward_signals": {
+			task: "not-forwarded",
+			wantExit: func(t *testing.T, err error) {
+				var exitErr *exec.ExitError
+				if !errors.As(err, &exitErr) {
+					t.Fatalf("got %v; want Task to be terminated", err)
+				}
+				status, ok := exitErr.Sys().(syscall.WaitStatus)
+				if !ok || !status.Signaled() || status.Signal() != syscall.SIGHUP {
+					t.Errorf("got %v; want Task to be terminated by SIGHUP", err)
+				}
+			},
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			var out bytes.Buffer
+			sut := exec.Command(task, tc.task, "--", SLEEPIT, "default", "-sleep=2s")
+			sut.Stdout = &out
+			sut.Stderr = &out
+			sut.Dir = "testdata/forward_signals"
+			sut.SysProcAttr = &syscall.SysProcAttr{Setpgid: true, Pgid: 0}
+			if err := sut.Start(); err != nil {
+				t.Fatalf("starting the SUT process: %v", err)
+			}
+
+			start := time.Now()
+			for !strings.Contains(out.String(), "sleepit: ready\n") {
+				if time.Since(start) > time.Second {
+					t.Fatalf("sleepit not ready after 1s\noutput:\n%s", out.String())
+				}
+				time.Sleep(10 * time.Millisecond)
+			}
+
+			// Unlike the terminal, a process manager signals Task alone
+			if err := syscall.Kill(sut.Process.Pid, syscall.SIGHUP); err != nil {
+				t.Fatalf("sending HUP signal: %v", err)
+			}
+			tc.wantExit(t, sut.Wait())
+
+			if notFound := listDifference(tc.want, strings.SplitAfter(out.String(), "\n")); len(notFound) > 0 {
+				t.Errorf("\nwanted but not found:\n%v\noutput:\n%s", notFound, out.String())
+			}
+		})
+	}
+}
+
 func getTaskPath() (string, error) {
 	if info, err := os.Stat("./bin/task"); err == nil {
 		return info.Name(), nil
@@ -0,0 +1,147 @@
+package task
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/nuvolaris/task/v3/taskfile"
+)
+
+// sliceTasks returns the tasks that run from the From task until the Until
+// one, in the graph of the given calls. Either end may be empty, in which case
+// the graph isn't sliced on that side.
+func (e *Executor) sliceTasks(ctx context.Context, calls ...taskfile.Call) (map[string]bool, error) {
+	// before[t] are the tasks that run before t, either because t waits for
+	// them or because they run before t within the same task
+	before := make(map[string][]string)
+	visited := make(map[string]bool)
+
+	var add func(call taskfile.Call) error
+	add = func(call taskfile.Call) error {
+		t, err := e.FastCompiledTask(ctx, call)
+		if err != nil {
+			return err
+		}
+		if visited[t.Task] {
+			return nil
+		}
+		visited[t.Task] = true
+
+		var next []taskfile.Call
+		var deps []string
+		for _, d := range t.Deps {
+			deps = append(deps, d.Task)
+			next = append(next, taskfile.Call{Task: d.Task, Vars: d.Vars})
+		}
+		before[t.Task] = append(before[t.Task], deps...)
+
+		// Every stage runs after the deps and the previous stages
+		previous := deps
+		for _, stage := range t.Pipeline {
+			var current []string
+			for _, d := range stage.Tasks {
+				before[d.Task] = append(before[d.Task], previous...)
+				current = append(current, d.Task)
+				next = append(next, taskfile.Call{Task: d.Task, Vars: d.Vars})
+			}
+			before[t.Task] = append(before[t.Task], current...)
+			previous = append(previous, current...)
+		}
+
+		// Tasks called by the commands run after the deps, the pipeline and
+		// the tasks called by the previous commands
+		for _, c := range t.Cmds {
+			if c.Task == "" {
+				continue
+			}
+			before[c.Task] = append(before[c.Task], previous...)
+			before[t.Task] = append(before[t.Task], c.Task)
+			previous = append(previous, c.Task)
+			next = append(next, taskfile.Call{Task: c.Task, Vars: c.Vars})
+		}
+
+		for _, call := range next {
+			if err := add(call); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	for _, call := range calls {
+		if err := add(call); err != nil {
+			return nil, err
+		}
+	}
+
+	// runsBefore returns the tasks that run before the given one, including
+	// itself
+	runsBefore := func(name string) map[string]bool {
+		result := map[string]bool{name: true}
+		stack := []string{name}
+		for len(stack) > 0 {
+			current := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			for _, b := range before[current] {
+				if !result[b] {
+					result[b] = true
+					stack = append(stack, b)
+				}
+			}
+		}
+		return result
+	}
+
+	resolve := func(flag, name string) (string, error) {
+		task, err := e.GetTask(taskfile.Call{Task: name})
+		if err != nil {
+			return "", err
+		}
+		if !visited[task.Task] {
+			return "", fmt.Errorf("task: Task %q given to %s is not run by the given tasks", name, flag)
+		}
+		return task.Task, nil
+	}
+
+	var from, until string
+	var err error
+	if e.From != "" {
+		if from, err = resolve("--from", e.From); err != nil {
+			return nil, err
+		}
+	}
+	if e.Until != "" {
+		if until, err = resolve("--until", e.Until); err != nil {
+			return nil, err
+		}
+	}
+
+	var untilTasks map[string]bool
+	if until != "" {
+		untilTasks = runsBefore(until)
+	}
+	sliced := make(map[string]bool)
+	for name := range visited {
+		if from != "" && !runsBefore(name)[from] {
+			continue
+		}
+		if until != "" && !untilTasks[name] {
+			continue
+		}
+		sliced[name] = true
+	}
+	if len(sliced) == 0 {
+		return nil, fmt.Errorf("task: No task runs after %q and before %q", e.From, e.Until)
+	}
+	return sliced, nil
+}
+
+// hasShellCommands returns whether the task has commands other than calls to
+// other tasks.
+func hasShellCommands(t *taskfile.Task) bool {
+	for _, c := range t.Cmds {
+		if c.Task == "" {
+			return true
+		}
+	}
+	return false
+}
@@ -2,20 +2,72 @@ package task
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"strings"
 
 	"github.com/nuvolaris/task/v3/internal/fingerprint"
+	"github.com/nuvolaris/task/v3/internal/logger"
 	"github.com/nuvolaris/task/v3/taskfile"
 )
 
-// Status returns an error if any the of given tasks is not up-to-date
+// StaleReason is a reason why a task is not up-to-date.
+type StaleReason = fingerprint.StaleReason
+
+// TaskStatus tells whether a task is up-to-date and, if it isn't, why.
+type TaskStatus struct {
+	Task     string        `json:"task"`
+	UpToDate bool          `json:"up_to_date"`
+	Reasons  []StaleReason `json:"reasons,omitempty"`
+}
+
+// Status returns an error if any the of given tasks is not up-to-date. In
+// verbose mode, it also prints why.
 func (e *Executor) Status(ctx context.Context, calls ...taskfile.Call) error {
+	statuses, err := e.ExplainStatus(ctx, calls...)
+	if err != nil {
+		return err
+	}
+	if e.Verbose {
+		for _, status := range statuses {
+			for _, r := range status.Reasons {
+				e.Logger.Errf(logger.Yellow, "task: Task %q is not up-to-date, because %s\n", status.Task, formatStaleReason(r))
+			}
+		}
+	}
+	return statusError(statuses)
+}
+
+// StatusJSON prints the status of the given tasks as JSON and, like Status,
+// returns an error if any of them is not up-to-date.
+func (e *Executor) StatusJSON(ctx context.Context, calls ...taskfile.Call) error {
+	statuses, err := e.ExplainStatus(ctx, calls...)
+	if err != nil {
+		return err
+	}
+
+	encoder := json.NewEncoder(e.Stdout)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(statuses); err != nil {
+		return err
+	}
+	return statusError(statuses)
+}
+
+// ExplainStatus returns whether each of the given tasks is up-to-date and, if
+// it isn't, why. It never writes the state of the tasks, even when not in dry
+// mode.
+func (e *Executor) ExplainStatus(ctx context.Context, calls ...taskfile.Call) ([]TaskStatus, error) {
+	if err := e.setupIfNeeded(ctx); err != nil {
+		return nil, err
+	}
+	statuses := make([]TaskStatus, 0, len(calls))
 	for _, call := range calls {
 
 		// Compile the task
-		t, err := e.CompiledTask(call)
+		t, err := e.CompiledTask(ctx, call)
 		if err != nil {
-			return err
+			return nil, err
 		}
 
 		// Get the fingerprinting method to use
@@ -24,28 +76,49 @@ func (e *Executor) Status(ctx context.Context, calls ...taskfile.Call) error {
 			method = t.Method
 		}
 
-		// Check if the task is up-to-date
-		isUpToDate, err := fingerprint.IsTaskUpToDate(ctx, t,
+		// Check why the task isn't up-to-date
+		reasons, err := fingerprint.ExplainTask(ctx, t,
 			fingerprint.WithMethod(method),
 			fingerprint.WithTempDir(e.TempDir),
 			fingerprint.WithDry(e.Dry),
 			fingerprint.WithLogger(e.Logger),
 		)
 		if err != nil {
-			return err
+			return nil, err
 		}
-		if !isUpToDate {
-			return fmt.Errorf(`task: Task "%s" is not up-to-date`, t.Name())
+		statuses = append(statuses, TaskStatus{
+			Task:     t.Name(),
+			UpToDate: len(reasons) == 0,
+			Reasons:  reasons,
+		})
+	}
+	return statuses, nil
+}
+
+func statusError(statuses []TaskStatus) error {
+	for _, status := range statuses {
+		if !status.UpToDate {
+			return fmt.Errorf(`task: Task "%s" is not up-to-date`, status.Task)
 		}
 	}
 	return nil
 }
 
+func formatStaleReason(r StaleReason) string {
+	if len(r.Files) == 0 {
+		return r.Message
+	}
+	return fmt.Sprintf("%s: %s", r.Message, strings.Join(r.Files, ", "))
+}
+
 func (e *Executor) statusOnError(t *taskfile.Task) error {
 	method := t.Method
 	if method == "" {
 		method = e.Taskfile.Method
 	}
+	if err := fingerprint.ForgetGenerates(t, e.TempDir); err != nil {
+		return err
+	}
 	checker, err := fingerprint.NewSourcesChecker(method, e.TempDir, e.Dry)
 	if err != nil {
 		return err
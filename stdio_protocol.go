@@ -0,0 +1,188 @@
+package task
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/nuvolaris/task/v3/internal/jsonrpc"
+)
+
+// ServeStdioProtocol answers the JSON-RPC requests of an editor read from in,
+// and writes the responses to out, along with the events of the runs, framed
+// like the messages of the Language Server Protocol. It runs the tasks like a
+// Server, so the Taskfile is read once for all the requests:
+//
+//	listTasks  the tasks, like --list-all --json
+//	runTask    queues a run of {"task": "...", "vars": {...}} and returns it
+//	getRun     a run, given its {"id": ...}
+//	cancel     cancels a run, given its {"id": ...}
+//	getPlan    the plan of {"task": "...", "vars": {...}}, like --dry --json
+//
+// The "run" notification is sent with a run once it's queued and each time its
+// status changes, and
+// the "log" one with each line of the output of its commands. It returns when
+// in is closed, or the context is done, cancelling the runs. As in and out
+// are usually the standard input and output, the Executor must not use them.
+func (e *Executor) ServeStdioProtocol(ctx context.Context, in io.Reader, out io.Writer) error {
+	if err := e.setupIfNeeded(ctx); err != nil {
+		return err
+	}
+	p := &stdioProtocol{e: e, s: NewServer(e), w: out}
+	defer p.s.Close()
+
+	messages := make(chan []byte)
+	readErr := make(chan error, 1)
+	go func() {
+		r := bufio.NewReader(in)
+		for {
+			b, err := jsonrpc.ReadMessage(r)
+			if err != nil {
+				readErr <- err
+				return
+			}
+			select {
+			case messages <- b:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	for {
+		select {
+		case b := <-messages:
+			if err := p.handleMessage(ctx, b); err != nil {
+				return err
+			}
+		case err := <-readErr:
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
+
+type stdioProtocol struct {
+	e *Executor
+	s *Server
+	// afterReply is called once the response to the request is written
+	afterReply func()
+
+	mutex sync.Mutex
+	w     io.Writer
+}
+
+type stdioRunParams struct {
+	ID int `json:"id"`
+}
+
+type stdioLog struct {
+	Run int `json:"run"`
+	serverLog
+}
+
+func (p *stdioProtocol) handleMessage(ctx context.Context, b []byte) error {
+	var req jsonrpc.Request
+	if err := json.Unmarshal(b, &req); err != nil {
+		return p.write(jsonrpc.ErrorResponse{
+			JSONRPC: "2.0",
+			ID:      json.RawMessage("null"),
+			Error:   &jsonrpc.Error{Code: jsonrpc.CodeParseError, Message: err.Error()},
+		})
+	}
+
+	p.afterReply = nil
+	result, err := p.handle(ctx, req)
+	if p.afterReply != nil {
+		defer p.afterReply()
+	}
+	// Notifications have no ID and never get a response
+	if req.ID == nil {
+		return nil
+	}
+	if err != nil {
+		respErr, ok := err.(*jsonrpc.Error)
+		if !ok {
+			respErr = &jsonrpc.Error{
+				Code:    jsonrpc.CodeRequestFailed,
+				Message: err.Error(),
+				Data:    map[string]int{"exit_code": exitCode(err)},
+			}
+		}
+		return p.write(jsonrpc.ErrorResponse{JSONRPC: "2.0", ID: req.ID, Error: respErr})
+	}
+	return p.write(jsonrpc.Response{JSONRPC: "2.0", ID: req.ID, Result: result})
+}
+
+func (p *stdioProtocol) handle(ctx context.Context, req jsonrpc.Request) (any, error) {
+	switch req.Method {
+	case "listTasks":
+		tasks, err := p.e.GetTaskList(FilterOutInternal)
+		if err != nil {
+			return nil, err
+		}
+		return p.e.ToEditorOutput(tasks)
+
+	case "runTask":
+		var params serverCall
+		if err := jsonrpc.DecodeParams(req, &params); err != nil {
+			return nil, err
+		}
+		run, err := p.s.enqueue([]serverCall{params})
+		if err != nil {
+			return nil, err
+		}
+		// The events come after the response giving the ID of the run
+		p.afterReply = func() {
+			go p.s.follow(ctx, run, func(logs []serverLog, status json.RawMessage, _ bool) {
+				for _, l := range logs {
+					_ = p.notify("log", stdioLog{Run: run.ID, serverLog: l})
+				}
+				if status != nil {
+					_ = p.notify("run", status)
+				}
+			})
+		}
+		return p.s.snapshot(run), nil
+
+	case "getRun", "cancel":
+		var params stdioRunParams
+		if err := jsonrpc.DecodeParams(req, &params); err != nil {
+			return nil, err
+		}
+		run := p.s.runByID(params.ID)
+		if run == nil {
+			return nil, fmt.Errorf("task: Run %d not found", params.ID)
+		}
+		if req.Method == "cancel" {
+			p.s.cancelRun(run)
+		}
+		return p.s.snapshot(run), nil
+
+	case "getPlan":
+		var params serverCall
+		if err := jsonrpc.DecodeParams(req, &params); err != nil {
+			return nil, err
+		}
+		return p.e.Plan(ctx, params.call())
+	}
+
+	return nil, &jsonrpc.Error{Code: jsonrpc.CodeMethodNotFound, Message: fmt.Sprintf("task: The %q method isn't supported", req.Method)}
+}
+
+func (p *stdioProtocol) notify(method string, params any) error {
+	return p.write(jsonrpc.Notification{JSONRPC: "2.0", Method: method, Params: params})
+}
+
+func (p *stdioProtocol) write(v any) error {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	return jsonrpc.WriteMessage(p.w, v)
+}
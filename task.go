@@ -14,10 +14,12 @@ import (
 
 	"github.com/nuvolaris/task/v3/errors"
 	"github.com/nuvolaris/task/v3/internal/compiler"
+	"github.com/nuvolaris/task/v3/internal/deprecations"
 	"github.com/nuvolaris/task/v3/internal/env"
 	"github.com/nuvolaris/task/v3/internal/execext"
 	"github.com/nuvolaris/task/v3/internal/fingerprint"
 	"github.com/nuvolaris/task/v3/internal/logger"
+	"github.com/nuvolaris/task/v3/internal/otel"
 	"github.com/nuvolaris/task/v3/internal/output"
 	"github.com/nuvolaris/task/v3/internal/slicesext"
 	"github.com/nuvolaris/task/v3/internal/sort"
@@ -29,6 +31,7 @@ import (
 	"github.com/sajari/fuzzy"
 	"golang.org/x/exp/slices"
 	"golang.org/x/sync/errgroup"
+	"golang.org/x/sync/semaphore"
 )
 
 const (
@@ -59,16 +62,68 @@ type Executor struct {
 	Silent      bool
 	AssumeYes   bool
 	Dry         bool
+	DryFormat   string
 	Summary     bool
 	Parallel    bool
 	Color       bool
 	Concurrency int
 	Interval    time.Duration
+	// NoFailFast runs all the given tasks even when some of them fail, instead
+	// of stopping at the first failure.
+	NoFailFast bool
+	// WatchMaxFiles is the maximum number of files watched at once. Defaults
+	// to 10000, and a negative value disables the limit.
+	WatchMaxFiles int
+	// WatchDelta shows only the output that changed since the previous
+	// successful run of each command when watching.
+	WatchDelta bool
+	// NoWatchStatus doesn't show the status line nor read the keys when
+	// watching in a terminal.
+	NoWatchStatus bool
+	// StripANSI removes the ANSI escape sequences from the output of the
+	// commands when Stdout isn't a terminal, like the Taskfile setting.
+	StripANSI bool
+	// WatchListen is the address of an HTTP endpoint that reruns the watched
+	// tasks when it receives a POST request, e.g. "localhost:8765".
+	WatchListen string
 	AssumesTerm bool
+	// From and Until run only the part of the graph of the given tasks that
+	// starts at From and ends at Until. The commands of the other tasks are
+	// skipped, but the tasks they call still run if they are in that part.
+	From  string
+	Until string
+	// NoInput never asks for the values of variables with a prompt, using
+	// their defaults instead.
+	NoInput    bool
+	ReportFile string
+	// Hermetic inherits only the environment variables in the allowlist of
+	// the env_policy of the Taskfile, or taskfile.HermeticAllow without one.
+	Hermetic bool
+	// SlashPaths writes ROOT_DIR, TASKFILE_DIR and USER_WORKING_DIR with
+	// forward slashes, even on Windows.
+	SlashPaths bool
+	// Profile prints how long each task and command took at the end of a run.
+	Profile bool
+	// ProfileFile is where the timings of a run are written in the Chrome
+	// trace event format.
+	ProfileFile string
+	// OTelExporter emits OpenTelemetry spans for every run, task and command
+	// to the given exporter, like "otlp" or "file:trace.json". See
+	// otel.NewExporter for all of them.
+	OTelExporter string
+	// Strict turns the warnings about the deprecated things used by the
+	// Taskfiles into errors.
+	Strict bool
 
 	Stdin  io.Reader
 	Stdout io.Writer
 	Stderr io.Writer
+	// TaskWriters gives the writers of the commands of each task run, instead
+	// of Stdout and Stderr, like to stream the output of every task to its
+	// own destination. It's called once per run of a task, and the writers
+	// that are an io.Closer are closed when it finishes. A nil writer keeps
+	// the usual one.
+	TaskWriters func(taskName string) (stdout, stderr io.Writer)
 
 	Logger         *logger.Logger
 	Compiler       compiler.Compiler
@@ -76,6 +131,7 @@ type Executor struct {
 	OutputStyle    taskfile.Output
 	TaskSorter     sort.TaskSorter
 	UserWorkingDir string
+	Middlewares    []Middleware
 
 	taskvars   *taskfile.Vars
 	fuzzyModel *fuzzy.Model
@@ -85,15 +141,54 @@ type Executor struct {
 	mkdirMutexMap        map[string]*sync.Mutex
 	executionHashes      map[string]context.Context
 	executionHashesMutex sync.Mutex
+	report               *RunReport
+	callResults          Results
+	dryScript            *dryScript
+	interrupted          atomic.Bool
+	manifest             *runManifest
+	manifestMutex        sync.Mutex
+	tracker              output.Tracker
+	nestedGroup          *output.Group
+	setupDone            bool
+	watchLimitReached    bool
+	watchedFilesCount    int
+	watchStatus          *watchStatus
+	watchMutex           sync.Mutex
+	watchOwners          map[string][]int
+	promptMutex          sync.Mutex
+	promptAnswers        map[string]string
+	stdinReader          *bufio.Reader
+	detectedProject      *detectedProject
+	slicedTasks          map[string]bool
+	envPolicy            *taskfile.EnvPolicy
+	profiler             *profiler
+	tracer               *otel.Tracer
+	forwarder            *signalForwarder
+	scheduling           bool
+	deprecations         *deprecations.Reporter
 }
 
 // Run runs Task
 func (e *Executor) Run(ctx context.Context, calls ...taskfile.Call) error {
+	if err := e.setupIfNeeded(ctx); err != nil {
+		return err
+	}
+	if e.DryFormat != "" && e.DryFormat != DryFormatSh {
+		return fmt.Errorf("task: Unknown dry run format %q. Available formats: %s", e.DryFormat, DryFormatSh)
+	}
+	calls, err := e.loadPathCalls(ctx, calls)
+	if err != nil {
+		return err
+	}
+
 	// check if given tasks exist
-	for _, call := range calls {
+	for i, call := range calls {
 		task, err := e.GetTask(call)
 		if err != nil {
-			if _, ok := err.(*errors.TaskNotFoundError); ok {
+			e.failCall(i, err)
+			// The list of tasks is only printed when there's no close match
+			var taskNotFoundErr *errors.TaskNotFoundError
+			if errors.As(err, &taskNotFoundErr) && taskNotFoundErr.DidYouMean == "" {
 				if _, err := e.ListTasks(ListOptions{ListOnlyTasksWithDescriptions: true}); err != nil {
 					return err
 				}
@@ -102,18 +197,24 @@ func (e *Executor) Run(ctx context.Context, calls ...taskfile.Call) error {
 		}
 
 		if task.Internal {
-			if _, ok := err.(*errors.TaskNotFoundError); ok {
-				if _, err := e.ListTasks(ListOptions{ListOnlyTasksWithDescriptions: true}); err != nil {
-					return err
-				}
-			}
-			return &errors.TaskInternalError{TaskName: call.Task}
+			err := &errors.TaskInternalError{TaskName: call.Task}
+			e.failCall(i, err)
+			return err
 		}
 	}
 
+	e.slicedTasks = nil
+	if e.From != "" || e.Until != "" {
+		sliced, err := e.sliceTasks(ctx, calls...)
+		if err != nil {
+			return err
+		}
+		e.slicedTasks = sliced
+	}
+
 	if e.Summary {
 		for i, c := range calls {
-			compiledTask, err := e.FastCompiledTask(c)
+			compiledTask, err := e.FastCompiledTask(ctx, c)
 			if err != nil {
 				return nil
 			}
@@ -127,13 +228,65 @@ func (e *Executor) Run(ctx context.Context, calls ...taskfile.Call) error {
 		return e.watchTasks(calls...)
 	}
 
+	if e.Dry && e.DryFormat == DryFormatSh {
+		e.dryScript = &dryScript{w: e.Stdout}
+		defer func() {
+			if err := e.dryScript.close(); err != nil {
+				e.Logger.Errf(logger.Red, "task: unable to write the script: %v\n", err)
+			}
+			e.dryScript = nil
+		}()
+	}
+
+	e.resetRunState()
+	e.profiler = nil
+	if e.Profile || e.ProfileFile != "" {
+		e.profiler = newProfiler()
+	}
+	var exporter otel.Exporter
+	e.tracer = nil
+	if e.OTelExporter != "" {
+		var err error
+		if exporter, err = otel.NewExporter(e.OTelExporter, e.Stderr); err != nil {
+			return err
+		}
+	}
+	if exporter != nil {
+		e.tracer = otel.NewTracer(os.Getenv("TRACEPARENT"))
+	}
+	ctx, span := e.tracer.Start(ctx, "task run")
+	err = e.runWithAllHooks(ctx, func(ctx context.Context) error {
+		return e.runCalls(ctx, calls...)
+	})
+	span.Finish(err)
+	if exporter != nil {
+		e.exportSpans(exporter)
+	}
+	if e.tracker != nil {
+		e.tracker.Stop()
+	}
+	e.finishManifest()
+	if err2 := e.printRunReport(); err2 != nil {
+		e.Logger.Errf(logger.Red, "task: unable to write the run report: %v\n", err2)
+	}
+	if err2 := e.printProfile(); err2 != nil {
+		e.Logger.Errf(logger.Red, "task: unable to write the profile: %v\n", err2)
+	}
+	return err
+}
+
+func (e *Executor) runCalls(ctx context.Context, calls ...taskfile.Call) error {
+	if e.NoFailFast {
+		return e.runAllCalls(ctx, calls...)
+	}
+
 	g, ctx := errgroup.WithContext(ctx)
-	for _, c := range calls {
-		c := c
+	for i, c := range calls {
+		i, c := i, c
 		if e.Parallel {
-			g.Go(func() error { return e.RunTask(ctx, c) })
+			g.Go(func() error { return e.runCall(ctx, i, c) })
 		} else {
-			if err := e.RunTask(ctx, c); err != nil {
+			if err := e.runCall(ctx, i, c); err != nil {
 				return err
 			}
 		}
@@ -143,12 +296,13 @@ func (e *Executor) Run(ctx context.Context, calls ...taskfile.Call) error {
 
 // RunTask runs a task by its name
 func (e *Executor) RunTask(ctx context.Context, call taskfile.Call) error {
-	t, err := e.CompiledTask(call)
+	t, err := e.CompiledTask(ctx, call)
 	if err != nil {
 		return err
 	}
-	if !e.Watch && atomic.AddInt32(e.taskCallCount[t.Task], 1) >= MaximumTaskCall {
-		return &errors.TaskCalledTooManyTimesError{TaskName: t.Task}
+	// The watched and the scheduled tasks run for as long as Task does
+	if !e.Watch && !e.scheduling && atomic.AddInt32(e.taskCallCount[t.Task], 1) >= MaximumTaskCall {
+		return &errors.TaskCalledTooManyTimesError{TaskName: t.Task, MaximumTaskCall: MaximumTaskCall}
 	}
 
 	release := e.acquireConcurrencyLimit()
@@ -173,86 +327,156 @@ func (e *Executor) RunTask(ctx context.Context, call taskfile.Call) error {
 		}
 	}
 
-	return e.startExecution(ctx, t, func(ctx context.Context) error {
-		if !shouldRunOnCurrentPlatform(t.Platforms) {
-			e.Logger.VerboseOutf(logger.Yellow, `task: %q not for current platform - ignored\n`, call.Task)
-			return nil
+	return e.startExecution(ctx, t, func(ctx context.Context) (err error) {
+		tr := e.report.startTask(t)
+		defer func() { e.report.finishTask(ctx, tr, err, e.interrupted.Load()) }()
+		ctx = withCallReport(ctx, tr)
+		return e.taskRunner()(withTaskReport(ctx, tr), t, call)
+	})
+}
+
+// executeTask is the TaskRunner at the end of the middleware chain, which
+// actually runs the deps and commands of the task.
+func (e *Executor) executeTask(ctx context.Context, t *taskfile.Task, call taskfile.Call) (err error) {
+	if !shouldRunOnCurrentPlatform(t.Platforms) {
+		e.Logger.VerboseOutf(logger.Yellow, `task: %q not for current platform - ignored\n`, call.Task)
+		return nil
+	}
+
+	if t.Timeout != "" {
+		timeout, parseErr := time.ParseDuration(t.Timeout)
+		if parseErr != nil {
+			return fmt.Errorf("task: Task %q has an invalid timeout %q: %w", t.Task, t.Timeout, parseErr)
 		}
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+		defer func() {
+			var timeoutErr *errors.TaskTimeoutError
+			if err != nil && ctx.Err() == context.DeadlineExceeded && !errors.As(err, &timeoutErr) {
+				err = &errors.TaskTimeoutError{TaskName: t.Task, Timeout: timeout, Err: err}
+			}
+		}()
+	}
 
-		e.Logger.VerboseErrf(logger.Magenta, "task: %q started\n", call.Task)
-		if err := e.runDeps(ctx, t); err != nil {
+	e.Logger.VerboseErrf(logger.Magenta, "task: %q started\n", call.Task)
+	ctx, closeGroup := e.startOutputGroup(ctx, t, call)
+	defer func() { closeGroup(err) }()
+	if err := e.runDeps(ctx, t); err != nil {
+		return err
+	}
+	if err := e.runPipeline(ctx, t); err != nil {
+		return err
+	}
+
+	// Outside of --from/--until, only the tasks called by the commands run
+	outOfSlice := e.slicedTasks != nil && !e.slicedTasks[t.Task]
+	if outOfSlice && hasShellCommands(t) && (e.Verbose || (!call.Silent && !t.Silent && !e.Taskfile.Silent && !e.Silent)) {
+		e.Logger.Errf(logger.Magenta, "task: Task %q skipped, because it's out of --from/--until\n", t.Name())
+	}
+
+	skipFingerprinting := e.ForceAll || (call.Direct && (e.Force || forcedOnWatch(ctx))) || outOfSlice
+	if !skipFingerprinting {
+		if err := ctx.Err(); err != nil {
 			return err
 		}
 
-		skipFingerprinting := e.ForceAll || (call.Direct && e.Force)
-		if !skipFingerprinting {
-			if err := ctx.Err(); err != nil {
-				return err
-			}
+		if err := e.areTaskRequiredVarsSet(ctx, t, call); err != nil {
+			return err
+		}
 
-			if err := e.areTaskRequiredVarsSet(ctx, t, call); err != nil {
-				return err
-			}
+		preCondMet, err := e.areTaskPreconditionsMet(ctx, t)
+		if err != nil {
+			return err
+		}
+		if !preCondMet {
+			return nil
+		}
 
-			preCondMet, err := e.areTaskPreconditionsMet(ctx, t)
-			if err != nil {
-				return err
-			}
+		// Get the fingerprinting method to use
+		method := e.Taskfile.Method
+		if t.Method != "" {
+			method = t.Method
+		}
 
-			// Get the fingerprinting method to use
-			method := e.Taskfile.Method
-			if t.Method != "" {
-				method = t.Method
-			}
+		upToDate, err := fingerprint.IsTaskUpToDate(ctx, t,
+			fingerprint.WithMethod(method),
+			fingerprint.WithTempDir(e.TempDir),
+			fingerprint.WithDry(e.Dry),
+			fingerprint.WithLogger(e.Logger),
+		)
+		if err != nil {
+			return err
+		}
+		otel.SpanFromContext(ctx).SetAttribute("task.up_to_date", upToDate)
 
-			upToDate, err := fingerprint.IsTaskUpToDate(ctx, t,
-				fingerprint.WithMethod(method),
-				fingerprint.WithTempDir(e.TempDir),
-				fingerprint.WithDry(e.Dry),
-				fingerprint.WithLogger(e.Logger),
-			)
-			if err != nil {
-				return err
+		if upToDate {
+			e.report.markUpToDate(taskReportFromContext(ctx))
+			if e.Verbose || (!call.Silent && !t.Silent && !e.Taskfile.Silent && !e.Silent) {
+				e.Logger.Errf(logger.Magenta, "task: Task %q is up to date\n", t.Name())
 			}
+			return nil
+		}
+	}
 
-			if upToDate && preCondMet {
-				if e.Verbose || (!call.Silent && !t.Silent && !e.Taskfile.Silent && !e.Silent) {
-					e.Logger.Errf(logger.Magenta, "task: Task %q is up to date\n", t.Name())
-				}
-				return nil
-			}
+	if err := e.mkdir(t); err != nil {
+		e.Logger.Errf(logger.Red, "task: cannot make directory %q: %v\n", t.Dir, err)
+	}
+
+	ctx, finishOutput, err := e.routeTaskOutput(ctx, t, call)
+	if err != nil {
+		return err
+	}
+	defer func() { finishOutput(err) }()
+	ctx, closeWriters := e.startTaskWriters(ctx, t)
+	defer closeWriters()
+
+	e.report.markStarted(taskReportFromContext(ctx))
+	if e.tracker != nil && !e.Dry {
+		finish := e.tracker.TrackTask(t.Name())
+		defer func() { finish(err) }()
+	}
+	for i := 0; i < len(t.Cmds); i++ {
+		if outOfSlice && (t.Cmds[i].Task == "" || t.Cmds[i].Defer) {
+			continue
+		}
+		if t.Cmds[i].Defer {
+			defer e.runDeferred(ctx, t, call, i)
+			continue
 		}
 
-		if err := e.mkdir(t); err != nil {
-			e.Logger.Errf(logger.Red, "task: cannot make directory %q: %v\n", t.Dir, err)
+		var err error
+		if n := parallelIterations(t.Cmds, i); n > 1 {
+			err = e.runIterationsInParallel(ctx, t, call, i, n)
+			i += n - 1
+		} else {
+			err = e.runCommand(ctx, t, call, i)
 		}
+		if err != nil {
+			if err2 := e.statusOnError(t); err2 != nil {
+				e.Logger.VerboseErrf(logger.Yellow, "task: error cleaning status on error: %v\n", err2)
+			}
 
-		for i := range t.Cmds {
-			if t.Cmds[i].Defer {
-				defer e.runDeferred(t, call, i)
+			if execext.IsExitError(err) && t.IgnoreError {
+				e.Logger.VerboseErrf(logger.Yellow, "task: task error ignored: %v\n", err)
+				e.report.ignoreError(t.Name(), err)
 				continue
 			}
 
-			if err := e.runCommand(ctx, t, call, i); err != nil {
-				if err2 := e.statusOnError(t); err2 != nil {
-					e.Logger.VerboseErrf(logger.Yellow, "task: error cleaning status on error: %v\n", err2)
-				}
-
-				if execext.IsExitError(err) && t.IgnoreError {
-					e.Logger.VerboseErrf(logger.Yellow, "task: task error ignored: %v\n", err)
-					continue
-				}
-
-				if !call.Direct {
-					return err
-				}
-
-				return &errors.TaskRunError{TaskName: t.Task, Err: err}
+			if !call.Direct {
+				return err
 			}
+
+			return &errors.TaskRunError{TaskName: t.Task, Err: err}
 		}
-		e.Logger.VerboseErrf(logger.Magenta, "task: %q finished\n", call.Task)
-		return nil
-	})
+	}
+	if !outOfSlice {
+		if err := fingerprint.RecordGenerates(t, e.TempDir, e.Dry); err != nil {
+			return err
+		}
+	}
+	e.Logger.VerboseErrf(logger.Magenta, "task: %q finished\n", call.Task)
+	return nil
 }
 
 func (e *Executor) mkdir(t *taskfile.Task) error {
@@ -273,15 +497,100 @@ func (e *Executor) mkdir(t *taskfile.Task) error {
 }
 
 func (e *Executor) runDeps(ctx context.Context, t *taskfile.Task) error {
+	return e.runTasksInParallel(ctx, t, t.Deps)
+}
+
+// runPipeline runs the stages of the task pipeline one after the other. The
+// tasks of each stage run in parallel, like deps.
+func (e *Executor) runPipeline(ctx context.Context, t *taskfile.Task) error {
+	for i, stage := range t.Pipeline {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		name := stage.Name
+		if name == "" {
+			name = fmt.Sprintf("%d", i+1)
+		}
+		e.Logger.VerboseErrf(logger.Magenta, "task: %q pipeline stage %q started\n", t.Task, name)
+		if err := e.runTasksInParallel(ctx, t, stage.Tasks); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// runAllCalls is like runCalls, but it runs all the calls even when some of
+// them fail, and returns all the errors.
+func (e *Executor) runAllCalls(ctx context.Context, calls ...taskfile.Call) error {
+	errs := make([]error, len(calls))
+	var wg sync.WaitGroup
+	for i, c := range calls {
+		i, c := i, c
+		if e.Parallel {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				errs[i] = e.runCall(ctx, i, c)
+			}()
+		} else {
+			if ctx.Err() != nil {
+				break
+			}
+			errs[i] = e.runCall(ctx, i, c)
+		}
+	}
+	wg.Wait()
+
+	var failed []error
+	for _, err := range errs {
+		if err != nil {
+			failed = append(failed, err)
+		}
+	}
+	switch len(failed) {
+	case 0:
+		return nil
+	case 1:
+		return failed[0]
+	default:
+		return &errors.TasksFailedError{Errs: failed}
+	}
+}
+
+func (e *Executor) runTasksInParallel(ctx context.Context, t *taskfile.Task, deps []*taskfile.Dep) error {
 	g, ctx := errgroup.WithContext(ctx)
 
 	reacquire := e.releaseConcurrencyLimit()
 	defer reacquire()
 
-	for _, d := range t.Deps {
+	// Limit how many deps of this task run at the same time. The task setting
+	// takes precedence over the global concurrency.
+	limit := t.DepsConcurrency
+	if limit <= 0 {
+		limit = e.Concurrency
+	}
+	var sem *semaphore.Weighted
+	if limit > 0 {
+		sem = semaphore.NewWeighted(int64(limit))
+	}
+
+	for _, d := range deps {
 		d := d
 		g.Go(func() error {
+			if sem != nil {
+				if err := sem.Acquire(ctx, 1); err != nil {
+					return err
+				}
+				defer sem.Release(1)
+			}
 			err := e.RunTask(ctx, taskfile.Call{Task: d.Task, Vars: d.Vars, Silent: d.Silent})
+			// A failed dep with ignore_error doesn't stop the other ones,
+			// unless the run was cancelled
+			if err != nil && d.IgnoreError && ctx.Err() == nil {
+				e.Logger.VerboseErrf(logger.Yellow, "task: dep %q error ignored: %v\n", d.Task, err)
+				e.report.ignoreError(d.Task, err)
+				return nil
+			}
 			if err != nil {
 				return err
 			}
@@ -292,8 +601,43 @@ func (e *Executor) runDeps(ctx context.Context, t *taskfile.Task) error {
 	return g.Wait()
 }
 
-func (e *Executor) runDeferred(t *taskfile.Task, call taskfile.Call, i int) {
-	ctx, cancel := context.WithCancel(context.Background())
+// parallelIterations returns how many commands starting from the i-th one
+// are iterations of the same for loop that run in parallel, or 1.
+func parallelIterations(cmds []*taskfile.Cmd, i int) int {
+	if cmds[i].For == nil || cmds[i].Parallel <= 1 {
+		return 1
+	}
+	n := 1
+	for i+n < len(cmds) && cmds[i+n].For == cmds[i].For {
+		n++
+	}
+	return n
+}
+
+// runIterationsInParallel runs the n commands starting from the i-th one, at
+// most as many at the same time as the parallel setting of their loop.
+func (e *Executor) runIterationsInParallel(ctx context.Context, t *taskfile.Task, call taskfile.Call, i, n int) error {
+	g, ctx := errgroup.WithContext(ctx)
+	sem := semaphore.NewWeighted(int64(t.Cmds[i].Parallel))
+
+	for j := i; j < i+n; j++ {
+		j := j
+		g.Go(func() error {
+			if err := sem.Acquire(ctx, 1); err != nil {
+				return err
+			}
+			defer sem.Release(1)
+			return e.runCommand(ctx, t, call, j)
+		})
+	}
+
+	return g.Wait()
+}
+
+func (e *Executor) runDeferred(parent context.Context, t *taskfile.Task, call taskfile.Call, i int) {
+	// The deferred commands run even when the task was canceled, but still
+	// write where its other commands do
+	ctx, cancel := context.WithCancel(detachedContext{parent})
 	defer cancel()
 
 	if err := e.runCommand(ctx, t, call, i); err != nil {
@@ -301,6 +645,15 @@ func (e *Executor) runDeferred(t *taskfile.Task, call taskfile.Call, i int) {
 	}
 }
 
+// detachedContext keeps the values of a context, but is never canceled.
+type detachedContext struct {
+	context.Context
+}
+
+func (detachedContext) Deadline() (time.Time, bool) { return time.Time{}, false }
+func (detachedContext) Done() <-chan struct{}       { return nil }
+func (detachedContext) Err() error                  { return nil }
+
 func (e *Executor) runCommand(ctx context.Context, t *taskfile.Task, call taskfile.Call, i int) error {
 	cmd := t.Cmds[i]
 
@@ -310,6 +663,11 @@ func (e *Executor) runCommand(ctx context.Context, t *taskfile.Task, call taskfi
 		defer reacquire()
 
 		err := e.RunTask(ctx, taskfile.Call{Task: cmd.Task, Vars: cmd.Vars, Silent: cmd.Silent})
+		if err != nil && cmd.IgnoreError && ctx.Err() == nil {
+			e.Logger.VerboseErrf(logger.Yellow, "task: [%s] task %q error ignored: %v\n", t.Name(), cmd.Task, err)
+			e.report.ignoreError(cmd.Task, err)
+			return nil
+		}
 		if err != nil {
 			return err
 		}
@@ -320,8 +678,17 @@ func (e *Executor) runCommand(ctx context.Context, t *taskfile.Task, call taskfi
 			return nil
 		}
 
+		if e.dryScript != nil {
+			return e.dryScript.writeCmd(e, t, cmd)
+		}
+
 		if e.Verbose || (!call.Silent && !cmd.Silent && !t.Silent && !e.Taskfile.Silent && !e.Silent) {
-			e.Logger.Errf(logger.Green, "task: [%s] %s\n", t.Name(), cmd.Cmd)
+			e.Logger.Errf(logger.Command, "task: [%s] %s\n", t.Name(), cmd.Cmd)
+		}
+		environ := env.Get(t)
+		if e.Verbose {
+			set, unset := env.Diff(environ)
+			e.logCmdProvenance(t, cmd, set, unset)
 		}
 
 		if e.Dry {
@@ -332,28 +699,78 @@ func (e *Executor) runCommand(ctx context.Context, t *taskfile.Task, call taskfi
 		if t.Interactive {
 			outputWrapper = output.Interleaved{}
 		}
-		vars, err := e.Compiler.FastGetVariables(t, call)
+		if delta, ok := outputWrapper.(*output.Delta); ok {
+			outputWrapper = delta.Command(fmt.Sprintf("%s\x00%d\x00%s", t.Name(), i, cmd.Cmd))
+		}
+		vars, err := e.Compiler.FastGetVariables(ctx, t, call)
 		outputTemplater := &templater.Templater{Vars: vars, RemoveNoValue: true}
 		if err != nil {
 			return fmt.Errorf("task: failed to get variables: %w", err)
 		}
-		stdOut, stdErr, close := outputWrapper.WrapWriter(e.Stdout, e.Stderr, t.Prefix, outputTemplater)
-
-		err = execext.RunCommand(ctx, &execext.RunCommandOptions{
-			Command:   cmd.Cmd,
-			Dir:       t.Dir,
-			Env:       env.Get(t),
-			PosixOpts: slicesext.UniqueJoin(e.Taskfile.Set, t.Set, cmd.Set),
-			BashOpts:  slicesext.UniqueJoin(e.Taskfile.Shopt, t.Shopt, cmd.Shopt),
-			Stdin:     e.Stdin,
-			Stdout:    stdOut,
-			Stderr:    stdErr,
-		})
+		stdIn, stdOut, stdErr := e.Stdin, e.Stdout, e.Stderr
+		if node := outputGroupFromContext(ctx); node != nil {
+			stdOut, stdErr = node, node
+		}
+		if w := taskWritersFromContext(ctx); w != nil {
+			stdOut, stdErr = w.writers(stdOut, stdErr)
+		}
+		if out := routedOutputFromContext(ctx); out != nil && !t.Interactive {
+			var redirected bool
+			stdOut, stdErr, redirected = out.writers(stdOut, stdErr)
+			// The files get the output as is
+			if redirected {
+				outputWrapper = output.Interleaved{}
+			}
+		}
+		if t.Interactive {
+			var done func()
+			stdIn, stdOut, stdErr, done = e.startInteractive()
+			defer done()
+		}
+		stdOut, stdErr, close := outputWrapper.WrapWriter(stdOut, stdErr, t.Prefix, outputTemplater)
+
+		group := inOwnProcessGroup(ctx, t, stdIn)
+		var signals []os.Signal
+		if e.forwarder != nil {
+			if signals, err = forwardedSignals(t); err != nil {
+				return err
+			}
+		}
+		processes := e.newCommandProcesses(group, restartOnWatch(ctx, t))
+		onProcess := func(p *os.Process) func() {
+			release := processes.add(p)
+			if e.forwarder == nil {
+				return release
+			}
+			stop := e.forwarder.add(p, signals, group)
+			return func() {
+				stop()
+				release()
+			}
+		}
+
+		err = e.commandRunner(func(ctx context.Context, t *taskfile.Task, cmd *taskfile.Cmd) error {
+			return execext.RunCommand(ctx, &execext.RunCommandOptions{
+				Command:      cmd.Cmd,
+				Dir:          t.Dir,
+				Env:          environ,
+				PosixOpts:    slicesext.UniqueJoin(e.Taskfile.Set, t.Set, cmd.Set),
+				BashOpts:     slicesext.UniqueJoin(e.Taskfile.Shopt, t.Shopt, cmd.Shopt),
+				Builtins:     t.Builtins,
+				Stdin:        stdIn,
+				Stdout:       stdOut,
+				Stderr:       stdErr,
+				OnProcess:    onProcess,
+				ProcessGroup: group,
+			})
+		})(ctx, t, cmd)
+		processes.finish()
 		if closeErr := close(err); closeErr != nil {
 			e.Logger.Errf(logger.Red, "task: unable to close writer: %v\n", closeErr)
 		}
 		if execext.IsExitError(err) && cmd.IgnoreError {
 			e.Logger.VerboseErrf(logger.Yellow, "task: [%s] command error ignored: %v\n", t.Name(), err)
+			e.report.ignoreError(t.Name(), err)
 			return nil
 		}
 		return err
@@ -372,10 +789,14 @@ func (e *Executor) startExecution(ctx context.Context, t *taskfile.Task, execute
 		return execute(ctx)
 	}
 
-	e.executionHashesMutex.Lock()
+	hashes, mutex := e.executionHashes, &e.executionHashesMutex
+	if rh, ok := ctx.Value(runHashesKey{}).(*runHashes); ok {
+		hashes, mutex = rh.hashes, &rh.mutex
+	}
+	mutex.Lock()
 
-	if otherExecutionCtx, ok := e.executionHashes[h]; ok {
-		e.executionHashesMutex.Unlock()
+	if otherExecutionCtx, ok := hashes[h]; ok {
+		mutex.Unlock()
 		e.Logger.VerboseErrf(logger.Magenta, "task: skipping execution of task: %s\n", h)
 
 		// Release our execution slot to avoid blocking other tasks while we wait
@@ -389,12 +810,28 @@ func (e *Executor) startExecution(ctx context.Context, t *taskfile.Task, execute
 	ctx, cancel := context.WithCancel(ctx)
 	defer cancel()
 
-	e.executionHashes[h] = ctx
-	e.executionHashesMutex.Unlock()
+	hashes[h] = ctx
+	mutex.Unlock()
 
 	return execute(ctx)
 }
 
+type runHashesKey struct{}
+
+// runHashes are the tasks already run by a run that happens at the same time
+// as others on the same Executor, like the scheduled ones, which can't share
+// the ones of the Executor.
+type runHashes struct {
+	mutex  sync.Mutex
+	hashes map[string]context.Context
+}
+
+// withRunHashes gives the run of the context its own tasks already run, so
+// the tasks that run once run again in every such run.
+func withRunHashes(ctx context.Context) context.Context {
+	return context.WithValue(ctx, runHashesKey{}, &runHashes{hashes: make(map[string]context.Context)})
+}
+
 // GetTask will return the task with the name matching the given call from the taskfile.
 // If no task is found, it will search for tasks with a matching alias.
 // If multiple tasks contain the same alias or no matches are found an error is returned.
@@ -420,8 +857,16 @@ func (e *Executor) GetTask(call taskfile.Call) (*taskfile.Task, error) {
 			TaskNames: aliasedTasks,
 		}
 	}
-	// If we found no tasks
+	// If we found no tasks, search for a task with a wildcard name
 	if len(aliasedTasks) == 0 {
+		if wildcardTask, wildcards := e.Taskfile.Tasks.FindWildcard(call.Task); wildcardTask != nil {
+			matchingTask = wildcardTask.DeepCopy()
+			matchingTask.Wildcards = wildcards
+			if matchingTask.Label == "" {
+				matchingTask.Label = call.Task
+			}
+			return matchingTask, nil
+		}
 		didYouMean := ""
 		if e.fuzzyModel != nil {
 			didYouMean = e.fuzzyModel.SpellCheck(call.Task)
@@ -443,28 +888,16 @@ func (e *Executor) GetTaskList(filters ...FilterFunc) ([]*taskfile.Task, error)
 	// Create an error group to wait for each task to be compiled
 	var g errgroup.Group
 
-	// Filter tasks based on the given filter functions
-	for _, task := range e.Taskfile.Tasks.Values() {
-		var shouldFilter bool
-		for _, filter := range filters {
-			if filter(task) {
-				shouldFilter = true
-			}
-		}
-		if !shouldFilter {
-			tasks = append(tasks, task)
-		}
-	}
-
-	// Compile the list of tasks
-	for i := range tasks {
-		task := tasks[i]
+	// Compile the list of tasks, so their descriptions can use the variables.
+	// The tasks that can't be compiled are listed as they're written.
+	compiledTasks := e.Taskfile.Tasks.Values()
+	for i := range compiledTasks {
+		i := i
 		g.Go(func() error {
-			compiledTask, err := e.FastCompiledTask(taskfile.Call{Task: task.Task})
+			compiledTask, err := e.FastCompiledTask(context.Background(), taskfile.Call{Task: compiledTasks[i].Task})
 			if err == nil {
-				task = compiledTask
+				compiledTasks[i] = compiledTask
 			}
-			task = compiledTask
 			return nil
 		})
 	}
@@ -474,6 +907,19 @@ func (e *Executor) GetTaskList(filters ...FilterFunc) ([]*taskfile.Task, error)
 		return nil, err
 	}
 
+	// Filter tasks based on the given filter functions
+	for _, task := range compiledTasks {
+		var shouldFilter bool
+		for _, filter := range filters {
+			if filter(task) {
+				shouldFilter = true
+			}
+		}
+		if !shouldFilter {
+			tasks = append(tasks, task)
+		}
+	}
+
 	// Sort the tasks
 	if e.TaskSorter == nil {
 		e.TaskSorter = &sort.AlphaNumericWithRootTasksFirst{}
@@ -0,0 +1,135 @@
+package task
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/nuvolaris/task/v3/internal/filepathext"
+	"github.com/nuvolaris/task/v3/internal/logger"
+	"github.com/nuvolaris/task/v3/taskfile"
+)
+
+type (
+	taskOutputKey  struct{}
+	taskWritersKey struct{}
+)
+
+// routedOutput is where the commands of a task write when its output is
+// routed with "output".
+type routedOutput struct {
+	routing *taskfile.TaskOutput
+	file    *os.File
+}
+
+// routeTaskOutput opens the file the output of the commands of the task is
+// written to, if any. The returned function closes it once the task finished,
+// and prints whether it succeeded when the output went elsewhere. The tasks
+// called by the task route their own output.
+func (e *Executor) routeTaskOutput(ctx context.Context, t *taskfile.Task, call taskfile.Call) (context.Context, func(error), error) {
+	if t.Output == nil || e.Dry {
+		return context.WithValue(ctx, taskOutputKey{}, (*routedOutput)(nil)), func(error) {}, nil
+	}
+	out := &routedOutput{routing: t.Output}
+	if file := t.Output.File(); file != "" {
+		path := filepathext.SmartJoin(t.Dir, file)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			return nil, nil, err
+		}
+		f, err := os.Create(path)
+		if err != nil {
+			return nil, nil, fmt.Errorf("task: unable to write the output of task %q: %w", t.Name(), err)
+		}
+		out.file = f
+	}
+
+	return context.WithValue(ctx, taskOutputKey{}, out), func(err error) {
+		if out.file != nil {
+			if closeErr := out.file.Close(); closeErr != nil {
+				e.Logger.Errf(logger.Red, "task: unable to close writer: %v\n", closeErr)
+			}
+		}
+		if !t.Output.Redirected() {
+			return
+		}
+		where := ""
+		if file := t.Output.File(); file != "" {
+			where = fmt.Sprintf(", see its output in %s", file)
+		}
+		switch {
+		case err != nil:
+			e.Logger.Errf(logger.Red, "task: Task %q failed%s\n", t.Name(), where)
+		case e.Verbose || (!call.Silent && !t.Silent && !e.Taskfile.Silent && !e.Silent):
+			e.Logger.Errf(logger.Green, "task: Task %q succeeded%s\n", t.Name(), where)
+		}
+	}, nil
+}
+
+// writers returns the writers of a command of the task, given the ones it
+// would use otherwise, and whether the standard output was redirected.
+func (o *routedOutput) writers(stdOut, stdErr io.Writer) (io.Writer, io.Writer, bool) {
+	redirected := o.routing.Redirected()
+	switch {
+	case o.file != nil:
+		stdOut = o.file
+	case redirected:
+		stdOut = io.Discard
+	}
+	if o.routing.Stderr == taskfile.TaskOutputMerge {
+		stdErr = stdOut
+	}
+	return stdOut, stdErr, redirected
+}
+
+func routedOutputFromContext(ctx context.Context) *routedOutput {
+	out, _ := ctx.Value(taskOutputKey{}).(*routedOutput)
+	return out
+}
+
+// taskWriters are the writers given by Executor.TaskWriters for a run of a
+// task.
+type taskWriters struct {
+	stdout, stderr io.Writer
+}
+
+// startTaskWriters asks for the writers of the task, if there's a TaskWriters,
+// and returns the function closing them once the task finished. The tasks
+// called by the task get their own.
+func (e *Executor) startTaskWriters(ctx context.Context, t *taskfile.Task) (context.Context, func()) {
+	if e.TaskWriters == nil || e.Dry {
+		return ctx, func() {}
+	}
+	stdout, stderr := e.TaskWriters(t.Name())
+	w := &taskWriters{stdout: stdout, stderr: stderr}
+	return context.WithValue(ctx, taskWritersKey{}, w), func() {
+		for i, writer := range []io.Writer{stdout, stderr} {
+			closer, ok := writer.(io.Closer)
+			// The same writer is often given for both
+			if !ok || i == 1 && writer == stdout {
+				continue
+			}
+			if err := closer.Close(); err != nil {
+				e.Logger.Errf(logger.Red, "task: unable to close writer: %v\n", err)
+			}
+		}
+	}
+}
+
+// writers returns the writers of a command of the task, given the ones it
+// would use otherwise.
+func (w *taskWriters) writers(stdOut, stdErr io.Writer) (io.Writer, io.Writer) {
+	if w.stdout != nil {
+		stdOut = w.stdout
+	}
+	if w.stderr != nil {
+		stdErr = w.stderr
+	}
+	return stdOut, stdErr
+}
+
+func taskWritersFromContext(ctx context.Context) *taskWriters {
+	w, _ := ctx.Value(taskWritersKey{}).(*taskWriters)
+	return w
+}
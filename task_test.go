@@ -1,24 +1,43 @@
 package task_test
 
 import (
+	"bufio"
 	"bytes"
 	"context"
+	"encoding/json"
 	"fmt"
 	"io"
+	"net/http"
+	"net/http/httptest"
 	"os"
+	"os/exec"
 	"path/filepath"
+	"regexp"
 	"runtime"
+	"sort"
+	"strconv"
 	"strings"
+	"sync"
+	"syscall"
 	"testing"
+	"time"
 
 	"github.com/Masterminds/semver/v3"
+	"github.com/nuvolaris/sh/v3/interp"
+	"github.com/nuvolaris/sh/v3/syntax"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
 	"github.com/nuvolaris/task/v3"
+	"github.com/nuvolaris/task/v3/args"
 	"github.com/nuvolaris/task/v3/errors"
+	"github.com/nuvolaris/task/v3/internal/editors"
+	"github.com/nuvolaris/task/v3/internal/experiments"
 	"github.com/nuvolaris/task/v3/internal/filepathext"
+	"github.com/nuvolaris/task/v3/internal/fingerprint"
+	"github.com/nuvolaris/task/v3/internal/jsonrpc"
 	"github.com/nuvolaris/task/v3/taskfile"
+	"github.com/nuvolaris/task/v3/taskfile/read"
 )
 
 func init() {
@@ -91,6 +110,37 @@ func TestEnv(t *testing.T) {
 	tt.Run(t)
 }
 
+func TestEnvPolicy(t *testing.T) {
+	t.Setenv("TASK_TEST_PUBLIC", "public")
+	t.Setenv("TASK_TEST_SECRET", "secret")
+	t.Setenv("OTHER_TASK_TEST", "other")
+
+	var buff bytes.Buffer
+	e := task.Executor{
+		Dir:    "testdata/env_policy",
+		Stdout: &buff,
+		Stderr: &buff,
+		Silent: true,
+	}
+	require.NoError(t, e.Setup())
+	require.NoError(t, e.Run(context.Background(), taskfile.Call{Task: "default"}))
+	assert.Equal(t, "public from-taskfile unset\npublic \n", buff.String())
+
+	// Without an allowlist, hermetic mode keeps only the usual variables
+	t.Setenv("HOME", t.TempDir())
+	buff.Reset()
+	e = task.Executor{
+		Dir:      "testdata/env_policy/hermetic",
+		Stdout:   &buff,
+		Stderr:   &buff,
+		Silent:   true,
+		Hermetic: true,
+	}
+	require.NoError(t, e.Setup())
+	require.NoError(t, e.Run(context.Background(), taskfile.Call{Task: "default"}))
+	assert.Equal(t, "unset home\n", buff.String())
+}
+
 func TestVarsV2(t *testing.T) {
 	tt := fileContentTest{
 		Dir:       "testdata/vars/v2",
@@ -167,6 +217,62 @@ func TestMultilineVars(t *testing.T) {
 	}
 }
 
+func TestStructuredVars(t *testing.T) {
+	const dir = "testdata/vars_structured"
+
+	tests := []struct {
+		task     string
+		expected string
+	}{
+		{"range", "api web \n"},
+		{"index", "web app 3\n"},
+		{"for", "api\nweb\n"},
+		{"call", "2 a,b\n"},
+		{"env", "[80,443] {\"name\":\"app\"}\n"},
+	}
+	for _, test := range tests {
+		t.Run(test.task, func(t *testing.T) {
+			var buff bytes.Buffer
+			e := task.Executor{
+				Dir:    dir,
+				Stdout: &buff,
+				Stderr: &buff,
+			}
+			require.NoError(t, e.Setup())
+			require.NoError(t, e.Run(context.Background(), taskfile.Call{Task: test.task}))
+			assert.Equal(t, test.expected, buff.String())
+		})
+	}
+}
+
+func TestRefVars(t *testing.T) {
+	const dir = "testdata/vars_ref"
+
+	tests := []struct {
+		task     string
+		expected string
+	}{
+		{"global", "{{.NAME}}\n"},
+		{"call", "{{.NAME}}\n"},
+		// Without ref, the value is templated again by the called task
+		{"call-without-ref", "\n"},
+		{"list", "a,b\n"},
+	}
+	for _, test := range tests {
+		t.Run(test.task, func(t *testing.T) {
+			var buff bytes.Buffer
+			e := task.Executor{
+				Dir:    dir,
+				Stdout: &buff,
+				Stderr: &buff,
+			}
+			require.NoError(t, e.Setup())
+			require.NoError(t, e.Run(context.Background(), taskfile.Call{Task: test.task}))
+			assert.Equal(t, test.expected, buff.String())
+		})
+	}
+}
+
 func TestSpecialVars(t *testing.T) {
 	const dir = "testdata/special_vars"
 	const target = "default"
@@ -293,923 +399,1201 @@ func TestDeps(t *testing.T) {
 	}
 }
 
-func TestStatus(t *testing.T) {
-	const dir = "testdata/status"
-
-	files := []string{
-		"foo.txt",
-		"bar.txt",
-		"baz.txt",
-	}
-
-	for _, f := range files {
-		path := filepathext.SmartJoin(dir, f)
-		_ = os.Remove(path)
-		if _, err := os.Stat(path); err == nil {
-			t.Errorf("File should not exist: %v", err)
-		}
-	}
+func TestDepsConcurrency(t *testing.T) {
+	const limit, deps = 2, 4
 
-	var buff bytes.Buffer
+	// Every dep blocks on its "start" line until the test lets it finish,
+	// which it does only once the limit of deps is in flight.
+	w := &depsBarrier{started: make(chan struct{}), release: make(chan struct{})}
 	e := &task.Executor{
-		Dir:     dir,
-		TempDir: filepathext.SmartJoin(dir, ".task"),
-		Stdout:  &buff,
-		Stderr:  &buff,
-		Silent:  true,
+		Dir:    "testdata/deps_concurrency",
+		Stdout: w,
+		Stderr: io.Discard,
+		Silent: true,
 	}
 	require.NoError(t, e.Setup())
-	// gen-foo creates foo.txt, and will always fail it's status check.
-	require.NoError(t, e.Run(context.Background(), taskfile.Call{Task: "gen-foo"}))
-	// gen-foo creates bar.txt, and will pass its status-check the 3. time it
-	// is run. It creates bar.txt, but also lists it as its source. So, the checksum
-	// for the file won't match before after the second run as we the file
-	// only exists after the first run.
-	require.NoError(t, e.Run(context.Background(), taskfile.Call{Task: "gen-bar"}))
-	// gen-silent-baz is marked as being silent, and should only produce output
-	// if e.Verbose is set to true.
-	require.NoError(t, e.Run(context.Background(), taskfile.Call{Task: "gen-silent-baz"}))
-
-	for _, f := range files {
-		if _, err := os.Stat(filepathext.SmartJoin(dir, f)); err != nil {
-			t.Errorf("File should exist: %v", err)
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- e.Run(context.Background(), taskfile.Call{Task: "default"})
+	}()
+
+	blocked := 0
+	for i := 0; i < deps; i++ {
+		<-w.started
+		blocked++
+		if blocked == limit {
+			w.release <- struct{}{}
+			blocked--
 		}
 	}
+	for ; blocked > 0; blocked-- {
+		w.release <- struct{}{}
+	}
+	require.NoError(t, <-errCh)
+	assert.Equal(t, limit, w.maxInFlight)
+}
 
-	// Run gen-bar a second time to produce a checksum file that matches bar.txt
-	require.NoError(t, e.Run(context.Background(), taskfile.Call{Task: "gen-bar"}))
-
-	// Run gen-bar a third time, to make sure we've triggered the status check.
-	require.NoError(t, e.Run(context.Background(), taskfile.Call{Task: "gen-bar"}))
-
-	// We're silent, so no output should have been produced.
-	assert.Empty(t, buff.String())
-
-	// Now, let's remove source file, and run the task again to to prepare
-	// for the next test.
-	err := os.Remove(filepathext.SmartJoin(dir, "bar.txt"))
-	require.NoError(t, err)
-	require.NoError(t, e.Run(context.Background(), taskfile.Call{Task: "gen-bar"}))
-	buff.Reset()
+// depsBarrier counts the deps in flight, from their "start" line to their
+// "end" one, and blocks each "start" line until it's released.
+type depsBarrier struct {
+	started chan struct{}
+	release chan struct{}
 
-	// Global silence switched of, so we should see output unless the task itself
-	// is silent.
-	e.Silent = false
+	mutex       sync.Mutex
+	inFlight    int
+	maxInFlight int
+}
 
-	// all: not up-to-date
-	require.NoError(t, e.Run(context.Background(), taskfile.Call{Task: "gen-foo"}))
-	assert.Equal(t, "task: [gen-foo] touch foo.txt", strings.TrimSpace(buff.String()))
-	buff.Reset()
-	// status: not up-to-date
-	require.NoError(t, e.Run(context.Background(), taskfile.Call{Task: "gen-foo"}))
-	assert.Equal(t, "task: [gen-foo] touch foo.txt", strings.TrimSpace(buff.String()))
-	buff.Reset()
+func (b *depsBarrier) Write(p []byte) (int, error) {
+	switch string(bytes.TrimSpace(p)) {
+	case "start":
+		b.mutex.Lock()
+		b.inFlight++
+		if b.inFlight > b.maxInFlight {
+			b.maxInFlight = b.inFlight
+		}
+		b.mutex.Unlock()
+		b.started <- struct{}{}
+		<-b.release
+	case "end":
+		b.mutex.Lock()
+		b.inFlight--
+		b.mutex.Unlock()
+	}
+	return len(p), nil
+}
 
-	// sources: not up-to-date
-	require.NoError(t, e.Run(context.Background(), taskfile.Call{Task: "gen-bar"}))
-	assert.Equal(t, "task: [gen-bar] touch bar.txt", strings.TrimSpace(buff.String()))
-	buff.Reset()
-	// all: up-to-date
-	require.NoError(t, e.Run(context.Background(), taskfile.Call{Task: "gen-bar"}))
-	assert.Equal(t, `task: Task "gen-bar" is up to date`, strings.TrimSpace(buff.String()))
-	buff.Reset()
+func TestFromUntil(t *testing.T) {
+	tests := []struct {
+		from, until string
+		expected    string
+	}{
+		{"", "", "lint\ngenerate\nbuild\ntest\ndeploy\n"},
+		{"build", "", "build\ntest\ndeploy\n"},
+		{"", "build", "lint\ngenerate\nbuild\n"},
+		{"generate", "test", "generate\nbuild\ntest\n"},
+		{"deploy", "deploy", "deploy\n"},
+	}
 
-	// sources: not up-to-date, no output produced.
-	require.NoError(t, e.Run(context.Background(), taskfile.Call{Task: "gen-silent-baz"}))
-	assert.Empty(t, buff.String())
+	for _, test := range tests {
+		t.Run(test.from+"-"+test.until, func(t *testing.T) {
+			var buff bytes.Buffer
+			e := task.Executor{
+				Dir:    "testdata/from_until",
+				Stdout: &buff,
+				Stderr: &buff,
+				Silent: true,
+				From:   test.from,
+				Until:  test.until,
+			}
+			require.NoError(t, e.Setup())
+			require.NoError(t, e.Run(context.Background(), taskfile.Call{Task: "ci"}))
+			assert.Equal(t, test.expected, buff.String())
+		})
+	}
 
-	// up-to-date, no output produced
-	require.NoError(t, e.Run(context.Background(), taskfile.Call{Task: "gen-silent-baz"}))
-	assert.Empty(t, buff.String())
+	t.Run("errors", func(t *testing.T) {
+		e := task.Executor{
+			Dir:    "testdata/from_until",
+			Stdout: io.Discard,
+			Stderr: io.Discard,
+			From:   "deploy",
+			Until:  "lint",
+		}
+		require.NoError(t, e.Setup())
+		err := e.Run(context.Background(), taskfile.Call{Task: "ci"})
+		assert.EqualError(t, err, `task: No task runs after "deploy" and before "lint"`)
 
-	e.Verbose = true
-	// up-to-date, output produced due to Verbose mode.
-	require.NoError(t, e.Run(context.Background(), taskfile.Call{Task: "gen-silent-baz"}))
-	assert.Equal(t, `task: Task "gen-silent-baz" is up to date`, strings.TrimSpace(buff.String()))
-	buff.Reset()
+		err = e.Run(context.Background(), taskfile.Call{Task: "build"})
+		assert.EqualError(t, err, `task: Task "deploy" given to --from is not run by the given tasks`)
+	})
 }
 
-func TestPrecondition(t *testing.T) {
-	const dir = "testdata/precondition"
+func TestRunReport(t *testing.T) {
+	const dir = "testdata/run_report"
+	reportFile := filepathext.SmartJoin(dir, "report.json")
+	markerFile := filepathext.SmartJoin(dir, ".loop-started")
+	_ = os.Remove(reportFile)
+	_ = os.Remove(markerFile)
+	t.Cleanup(func() {
+		_ = os.Remove(reportFile)
+		_ = os.Remove(markerFile)
+	})
 
 	var buff bytes.Buffer
 	e := &task.Executor{
-		Dir:    dir,
-		Stdout: &buff,
-		Stderr: &buff,
+		Dir:        dir,
+		Stdout:     &buff,
+		Stderr:     &buff,
+		ReportFile: reportFile,
 	}
-
-	// A precondition that has been met
 	require.NoError(t, e.Setup())
-	require.NoError(t, e.Run(context.Background(), taskfile.Call{Task: "foo"}))
-	if buff.String() != "" {
-		t.Errorf("Got Output when none was expected: %s", buff.String())
-	}
 
-	// A precondition that was not met
-	require.Error(t, e.Run(context.Background(), taskfile.Call{Task: "impossible"}))
+	require.NoError(t, e.Run(context.Background(), taskfile.Call{Task: "ok"}))
+	report := e.LastRunReport()
+	assert.False(t, report.Cancelled)
+	require.Len(t, report.Tasks, 1)
+	assert.Equal(t, task.TaskStateCompleted, report.Tasks[0].State)
+	assert.NotContains(t, buff.String(), "Run cancelled")
 
-	if buff.String() != "task: 1 != 0 obviously!\n" {
-		t.Errorf("Wrong output message: %s", buff.String())
-	}
 	buff.Reset()
+	require.Error(t, e.Run(context.Background(), taskfile.Call{Task: "default"}))
+	report = e.LastRunReport()
+	assert.True(t, report.Cancelled)
+	states := make(map[string]task.TaskState)
+	for _, tr := range report.Tasks {
+		states[tr.Task] = tr.State
+	}
+	assert.Equal(t, map[string]task.TaskState{
+		"default": task.TaskStateFailed,
+		"fail":    task.TaskStateFailed,
+		"waiter":  task.TaskStateSkipped,
+		"loop":    task.TaskStateInterrupted,
+	}, states)
+	assert.Contains(t, buff.String(), "task: Run cancelled\n")
+	assert.Contains(t, buff.String(), `task: Task "loop" interrupted`)
+
+	b, err := os.ReadFile(reportFile)
+	require.NoError(t, err)
+	var written task.RunReport
+	require.NoError(t, json.Unmarshal(b, &written))
+	assert.True(t, written.Cancelled)
+	assert.Len(t, written.Tasks, 4)
+}
 
-	// Calling a task with a precondition in a dependency fails the task
-	require.Error(t, e.Run(context.Background(), taskfile.Call{Task: "depends_on_impossible"}))
+func TestProfile(t *testing.T) {
+	const dir = "testdata/profile"
+	profileFile := filepathext.SmartJoin(dir, "trace.json")
+	_ = os.Remove(profileFile)
+	t.Cleanup(func() { _ = os.Remove(profileFile) })
 
-	if buff.String() != "task: 1 != 0 obviously!\n" {
-		t.Errorf("Wrong output message: %s", buff.String())
+	var buff bytes.Buffer
+	e := &task.Executor{
+		Dir:         dir,
+		Stdout:      &buff,
+		Stderr:      &buff,
+		Silent:      true,
+		Profile:     true,
+		ProfileFile: profileFile,
 	}
-	buff.Reset()
+	require.NoError(t, e.Setup())
+	require.NoError(t, e.Run(context.Background(), taskfile.Call{Task: "default"}))
 
-	// Calling a task with a precondition in a cmd fails the task
-	require.Error(t, e.Run(context.Background(), taskfile.Call{Task: "executes_failing_task_as_cmd"}))
-	if buff.String() != "task: 1 != 0 obviously!\n" {
-		t.Errorf("Wrong output message: %s", buff.String())
+	spans := e.LastRunProfile()
+	require.Len(t, spans, 6)
+	names := make([]string, 0, len(spans))
+	for i, s := range spans {
+		names = append(names, s.Kind+" "+s.Name())
+		if i > 0 {
+			assert.GreaterOrEqual(t, spans[i-1].Duration, s.Duration)
+		}
 	}
-	buff.Reset()
-}
+	assert.ElementsMatch(t, []string{
+		"task default", "task a", "task b",
+		"cmd default: echo default", "cmd a: echo a", "cmd b: echo b",
+	}, names)
+	// The task includes its deps, so it's always the slowest
+	assert.Equal(t, "default", spans[0].Task)
+	assert.Contains(t, buff.String(), "task: Profile of the run, which took ")
 
-func TestGenerates(t *testing.T) {
-	const dir = "testdata/generates"
+	b, err := os.ReadFile(profileFile)
+	require.NoError(t, err)
+	var trace struct {
+		TraceEvents []struct {
+			Name  string `json:"name"`
+			Phase string `json:"ph"`
+		} `json:"traceEvents"`
+	}
+	require.NoError(t, json.Unmarshal(b, &trace))
+	require.Len(t, trace.TraceEvents, 6)
+	assert.Equal(t, "default", trace.TraceEvents[0].Name)
+	assert.Equal(t, "X", trace.TraceEvents[0].Phase)
+}
 
+func TestOTelExporter(t *testing.T) {
 	const (
-		srcTask        = "sub/src.txt"
-		relTask        = "rel.txt"
-		absTask        = "abs.txt"
-		fileWithSpaces = "my text file.txt"
+		dir     = "testdata/otel"
+		traceID = "4bf92f3577b34da6a3ce929d0e0e4736"
+		spanID  = "00f067aa0ba902b7"
 	)
+	t.Setenv("TRACEPARENT", "00-"+traceID+"-"+spanID+"-01")
+	traceFile := filepath.Join(t.TempDir(), "trace.json")
 
-	srcFile := filepathext.SmartJoin(dir, srcTask)
+	var buff bytes.Buffer
+	e := &task.Executor{
+		Dir:          dir,
+		TempDir:      t.TempDir(),
+		Stdout:       &buff,
+		Stderr:       &buff,
+		Silent:       true,
+		OTelExporter: "file:" + traceFile,
+	}
+	require.NoError(t, e.Setup())
 
-	for _, task := range []string{srcTask, relTask, absTask, fileWithSpaces} {
-		path := filepathext.SmartJoin(dir, task)
-		_ = os.Remove(path)
-		if _, err := os.Stat(path); err == nil {
-			t.Errorf("File should not exist: %v", err)
+	type span struct {
+		TraceID      string `json:"traceId"`
+		SpanID       string `json:"spanId"`
+		ParentSpanID string `json:"parentSpanId"`
+		Name         string `json:"name"`
+		Attributes   []struct {
+			Key   string         `json:"key"`
+			Value map[string]any `json:"value"`
+		} `json:"attributes"`
+	}
+	readSpans := func() map[string]span {
+		b, err := os.ReadFile(traceFile)
+		require.NoError(t, err)
+		var request struct {
+			ResourceSpans []struct {
+				ScopeSpans []struct {
+					Spans []span `json:"spans"`
+				} `json:"scopeSpans"`
+			} `json:"resourceSpans"`
+		}
+		require.NoError(t, json.Unmarshal(b, &request))
+		spans := make(map[string]span)
+		for _, s := range request.ResourceSpans[0].ScopeSpans[0].Spans {
+			assert.Equal(t, traceID, s.TraceID)
+			spans[s.Name] = s
+		}
+		return spans
+	}
+	attribute := func(s span, key string) any {
+		for _, a := range s.Attributes {
+			if a.Key == key {
+				for _, v := range a.Value {
+					return v
+				}
+			}
 		}
+		return nil
 	}
 
-	buff := bytes.NewBuffer(nil)
+	require.NoError(t, e.Run(context.Background(), taskfile.Call{Task: "default"}))
+	spans := readSpans()
+	require.Len(t, spans, 5)
+	assert.Equal(t, spanID, spans["task run"].ParentSpanID)
+	assert.Equal(t, spans["task run"].SpanID, spans["default"].ParentSpanID)
+	assert.Equal(t, spans["default"].SpanID, spans["greet"].ParentSpanID)
+	assert.Equal(t, spans["greet"].SpanID, spans[`echo "hello world"`].ParentSpanID)
+	assert.Equal(t, "world", attribute(spans["greet"], "task.vars.NAME"))
+	assert.Equal(t, "0", attribute(spans[`echo "hello world"`], "exit_code"))
+	assert.Equal(t, "3", attribute(spans["exit 3"], "exit_code"))
+
+	require.NoError(t, e.Run(context.Background(), taskfile.Call{Task: "built"}))
+	assert.Equal(t, false, attribute(readSpans()["built"], "task.up_to_date"))
+	require.NoError(t, e.Run(context.Background(), taskfile.Call{Task: "built"}))
+	assert.Equal(t, true, attribute(readSpans()["built"], "task.up_to_date"))
+}
+
+func TestDryFormatSh(t *testing.T) {
+	const dir = "testdata/dry_sh"
+
+	var stdout, stderr bytes.Buffer
 	e := &task.Executor{
-		Dir:    dir,
-		Stdout: buff,
-		Stderr: buff,
+		Dir:       dir,
+		Stdout:    &stdout,
+		Stderr:    &stderr,
+		Dry:       true,
+		DryFormat: task.DryFormatSh,
 	}
 	require.NoError(t, e.Setup())
+	require.NoError(t, e.Run(context.Background(), taskfile.Call{Task: "default"}))
 
-	for _, theTask := range []string{relTask, absTask, fileWithSpaces} {
-		destFile := filepathext.SmartJoin(dir, theTask)
-		upToDate := fmt.Sprintf("task: Task \"%s\" is up to date\n", srcTask) +
-			fmt.Sprintf("task: Task \"%s\" is up to date\n", theTask)
+	absDir, err := filepath.Abs(dir)
+	require.NoError(t, err)
+	quotedDir, err := syntax.Quote(absDir, syntax.LangPOSIX)
+	require.NoError(t, err)
+	expected := fmt.Sprintf(`#!/bin/sh
+set -e
+
+# task: dep
+(
+cd %[1]s
+export GREETING='hello world'
+set -o pipefail
+echo dep
+)
 
-		// Run task for the first time.
-		require.NoError(t, e.Run(context.Background(), taskfile.Call{Task: theTask}))
+# task: default
+(
+cd %[1]s
+export GREETING='hello world'
+set -o pipefail
+echo "$GREETING"
+)
 
-		if _, err := os.Stat(srcFile); err != nil {
-			t.Errorf("File should exist: %v", err)
-		}
-		if _, err := os.Stat(destFile); err != nil {
-			t.Errorf("File should exist: %v", err)
-		}
-		// Ensure task was not incorrectly found to be up-to-date on first run.
-		if buff.String() == upToDate {
-			t.Errorf("Wrong output message: %s", buff.String())
-		}
-		buff.Reset()
+# task: sub
+(
+cd %[1]s
+export GREETING='hello world'
+export NAME="it's me"
+set -o pipefail
+echo "$NAME"
+)
 
-		// Re-run task to ensure it's now found to be up-to-date.
-		require.NoError(t, e.Run(context.Background(), taskfile.Call{Task: theTask}))
-		if buff.String() != upToDate {
-			t.Errorf("Wrong output message: %s", buff.String())
-		}
-		buff.Reset()
-	}
+# task: default
+(
+cd %[1]s
+export GREETING='hello world'
+set -o pipefail
+echo done
+)
+`, quotedDir)
+	assert.Equal(t, expected, stdout.String())
+	assert.Empty(t, stderr.String())
+
+	e.DryFormat = "json"
+	assert.ErrorContains(t, e.Run(context.Background(), taskfile.Call{Task: "default"}), `Unknown dry run format "json"`)
 }
 
-func TestStatusChecksum(t *testing.T) {
-	const dir = "testdata/checksum"
+func TestMiddlewares(t *testing.T) {
+	const dir = "testdata/middleware"
 
-	tests := []struct {
-		files []string
-		task  string
-	}{
-		{[]string{"generated.txt", ".task/checksum/build"}, "build"},
-		{[]string{"generated.txt", ".task/checksum/build-with-status"}, "build-with-status"},
-	}
-
-	for _, test := range tests {
-		t.Run(test.task, func(t *testing.T) {
-			for _, f := range test.files {
-				_ = os.Remove(filepathext.SmartJoin(dir, f))
-
-				_, err := os.Stat(filepathext.SmartJoin(dir, f))
-				require.Error(t, err)
-			}
-
-			var buff bytes.Buffer
-			tempdir := filepathext.SmartJoin(dir, ".task")
-			e := task.Executor{
-				Dir:     dir,
-				TempDir: tempdir,
-				Stdout:  &buff,
-				Stderr:  &buff,
+	var buff bytes.Buffer
+	trace := func(name string) task.Middleware {
+		return func(next task.TaskRunner) task.TaskRunner {
+			return func(ctx context.Context, t *taskfile.Task, call taskfile.Call) error {
+				fmt.Fprintf(&buff, "%s: before %s\n", name, t.Task)
+				err := next(ctx, t, call)
+				fmt.Fprintf(&buff, "%s: after %s\n", name, t.Task)
+				return err
 			}
-			require.NoError(t, e.Setup())
-
-			require.NoError(t, e.Run(context.Background(), taskfile.Call{Task: test.task}))
-			for _, f := range test.files {
-				_, err := os.Stat(filepathext.SmartJoin(dir, f))
-				require.NoError(t, err)
+		}
+	}
+	skip := func(next task.TaskRunner) task.TaskRunner {
+		return func(ctx context.Context, t *taskfile.Task, call taskfile.Call) error {
+			if t.Task == "skipped" {
+				return nil
 			}
+			return next(ctx, t, call)
+		}
+	}
 
-			// Capture the modification time, so we can ensure the checksum file
-			// is not regenerated when the hash hasn't changed.
-			s, err := os.Stat(filepathext.SmartJoin(tempdir, "checksum/"+test.task))
-			require.NoError(t, err)
-			time := s.ModTime()
-
-			buff.Reset()
-			require.NoError(t, e.Run(context.Background(), taskfile.Call{Task: test.task}))
-			assert.Equal(t, `task: Task "`+test.task+`" is up to date`+"\n", buff.String())
-
-			s, err = os.Stat(filepathext.SmartJoin(tempdir, "checksum/"+test.task))
-			require.NoError(t, err)
-			assert.Equal(t, time, s.ModTime())
-		})
+	e := &task.Executor{
+		Dir:         dir,
+		Stdout:      &buff,
+		Stderr:      &buff,
+		Middlewares: []task.Middleware{trace("outer"), skip, trace("inner")},
 	}
-}
+	require.NoError(t, e.Setup())
+	require.NoError(t, e.Run(context.Background(), taskfile.Call{Task: "default"}))
 
-func TestAlias(t *testing.T) {
-	const dir = "testdata/alias"
+	assert.Equal(t, strings.Join([]string{
+		"outer: before default",
+		"inner: before default",
+		"outer: before dep",
+		"inner: before dep",
+		"dep",
+		"inner: after dep",
+		"outer: after dep",
+		"outer: before skipped",
+		"outer: after skipped",
+		"default",
+		"inner: after default",
+		"outer: after default",
+		"",
+	}, "\n"), buff.String())
+}
 
-	data, err := os.ReadFile(filepathext.SmartJoin(dir, "alias.txt"))
-	require.NoError(t, err)
+func TestForwardCLIArgs(t *testing.T) {
+	const dir = "testdata/forward_cli_args"
 
 	var buff bytes.Buffer
-	e := task.Executor{
+	e := &task.Executor{
 		Dir:    dir,
 		Stdout: &buff,
 		Stderr: &buff,
 	}
 	require.NoError(t, e.Setup())
-	require.NoError(t, e.Run(context.Background(), taskfile.Call{Task: "f"}))
-	assert.Equal(t, string(data), buff.String())
+	require.NoError(t, e.Run(context.Background(), taskfile.Call{Task: "default"}))
+
+	assert.Equal(t, strings.Join([]string{
+		"check --env 'prod eu'",
+		"<--env>",
+		"<prod eu>",
+		"<--dry-run>",
+		"not forwarded []",
+		"",
+	}, "\n"), buff.String())
 }
 
-func TestDuplicateAlias(t *testing.T) {
-	const dir = "testdata/alias"
+func TestPipeline(t *testing.T) {
+	const dir = "testdata/pipeline"
 
 	var buff bytes.Buffer
-	e := task.Executor{
+	e := &task.Executor{
 		Dir:    dir,
 		Stdout: &buff,
 		Stderr: &buff,
 	}
 	require.NoError(t, e.Setup())
-	require.Error(t, e.Run(context.Background(), taskfile.Call{Task: "x"}))
-	assert.Equal(t, "", buff.String())
-}
+	require.NoError(t, e.Run(context.Background(), taskfile.Call{Task: "release"}))
 
-func TestAliasSummary(t *testing.T) {
-	const dir = "testdata/alias"
+	// The tasks of a stage run in parallel, so their order isn't fixed
+	lines := strings.Split(strings.TrimSpace(buff.String()), "\n")
+	require.Len(t, lines, 6)
+	assert.Equal(t, "lint", lines[0])
+	assert.ElementsMatch(t, []string{"build linux", "build darwin"}, lines[1:3])
+	assert.Equal(t, []string{"docs build", "publish", "released"}, lines[3:])
 
-	data, err := os.ReadFile(filepathext.SmartJoin(dir, "alias-summary.txt"))
-	require.NoError(t, err)
+	buff.Reset()
+	require.NoError(t, e.Graph(taskfile.Call{Task: "release"}))
+	assert.Equal(t, strings.Join([]string{
+		"digraph tasks {",
+		`  "release";`,
+		`  "release" -> "lint";`,
+		`  subgraph "cluster_release_1" {`,
+		`    label = "release: build";`,
+		`    "build";`,
+		`  }`,
+		`  "release" -> "build";`,
+		`  subgraph "cluster_release_2" {`,
+		`    label = "release: stage 2";`,
+		`    "docs:build";`,
+		`  }`,
+		`  "build" -> "docs:build" [style=dashed];`,
+		`  subgraph "cluster_release_3" {`,
+		`    label = "release: stage 3";`,
+		`    "publish";`,
+		`  }`,
+		`  "docs:build" -> "publish" [style=dashed];`,
+		`  "lint";`,
+		`  "build";`,
+		`  "docs:build";`,
+		`  "publish";`,
+		"}",
+		"",
+	}, "\n"), buff.String())
+}
+
+func TestRunCleansUpTrackedResources(t *testing.T) {
+	const dir = "testdata/middleware"
 
 	var buff bytes.Buffer
-	e := task.Executor{
+	var released, leaked string
+	e := &task.Executor{
 		Dir:     dir,
-		Summary: true,
+		TempDir: t.TempDir(),
 		Stdout:  &buff,
 		Stderr:  &buff,
 	}
+	runs := filepath.Join(e.TempDir, "runs")
+	e.Middlewares = []task.Middleware{func(next task.TaskRunner) task.TaskRunner {
+		return func(ctx context.Context, tk *taskfile.Task, call taskfile.Call) error {
+			if tk.Task != "dep" {
+				return next(ctx, tk, call)
+			}
+			released, leaked = t.TempDir(), t.TempDir()
+			release, err := e.TrackResource(task.Resource{Kind: task.ResourceTempDir, ID: released})
+			require.NoError(t, err)
+			// Never released, so it's removed when the run ends
+			_, err = e.TrackResource(task.Resource{Kind: task.ResourceTempDir, ID: leaked})
+			require.NoError(t, err)
+
+			manifests, _ := filepath.Glob(filepath.Join(runs, "*.json"))
+			assert.Len(t, manifests, 1)
+			release()
+			return next(ctx, tk, call)
+		}
+	}}
 	require.NoError(t, e.Setup())
-	require.NoError(t, e.Run(context.Background(), taskfile.Call{Task: "f"}))
-	assert.Equal(t, string(data), buff.String())
+	require.NoError(t, e.Run(context.Background(), taskfile.Call{Task: "default"}))
+
+	assert.DirExists(t, released)
+	assert.NoDirExists(t, leaked)
+	manifests, err := filepath.Glob(filepath.Join(runs, "*.json"))
+	require.NoError(t, err)
+	assert.Empty(t, manifests)
 }
 
-func TestLabelUpToDate(t *testing.T) {
-	const dir = "testdata/label_uptodate"
+func TestRunTracksProcesses(t *testing.T) {
+	const dir = "testdata/background"
+	pidFile := filepathext.SmartJoin(dir, "bg.pid")
+	_ = os.Remove(pidFile)
+	t.Cleanup(func() { _ = os.Remove(pidFile) })
 
-	var buff bytes.Buffer
-	e := task.Executor{
-		Dir:    dir,
-		Stdout: &buff,
-		Stderr: &buff,
+	e := &task.Executor{
+		Dir:     dir,
+		TempDir: t.TempDir(),
+		Stdout:  io.Discard,
+		Stderr:  io.Discard,
 	}
 	require.NoError(t, e.Setup())
-	require.NoError(t, e.Run(context.Background(), taskfile.Call{Task: "foo"}))
-	assert.Contains(t, buff.String(), "foobar")
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	errs := make(chan error, 1)
+	go func() { errs <- e.Run(ctx, taskfile.Call{Task: "default"}) }()
+
+	var pid string
+	require.Eventually(t, func() bool {
+		b, err := os.ReadFile(pidFile)
+		pid = strings.TrimSpace(string(b))
+		return err == nil && pid != ""
+	}, 5*time.Second, 10*time.Millisecond)
+
+	// The process is tracked once its command returned, which may be after it
+	// wrote its PID, while the one of the next command isn't
+	runs := filepath.Join(e.TempDir, "runs")
+	var manifest struct {
+		Resources []task.Resource `json:"resources"`
+	}
+	require.Eventually(t, func() bool {
+		manifests, err := filepath.Glob(filepath.Join(runs, "*.json"))
+		if err != nil || len(manifests) != 1 {
+			return false
+		}
+		b, err := os.ReadFile(manifests[0])
+		return err == nil && json.Unmarshal(b, &manifest) == nil && len(manifest.Resources) == 1
+	}, 5*time.Second, 10*time.Millisecond)
+	assert.Equal(t, task.ResourceProcess, manifest.Resources[0].Kind)
+	assert.Equal(t, pid, manifest.Resources[0].ID)
+	assert.NotEmpty(t, manifest.Resources[0].Started)
+	cancel()
+	require.Error(t, <-errs)
+	assert.NoDirExists(t, runs)
 }
 
-func TestLabelSummary(t *testing.T) {
-	const dir = "testdata/label_summary"
+func TestCleanup(t *testing.T) {
+	const dir = "testdata/middleware"
 
 	var buff bytes.Buffer
-	e := task.Executor{
+	e := &task.Executor{
 		Dir:     dir,
-		Summary: true,
+		TempDir: t.TempDir(),
 		Stdout:  &buff,
 		Stderr:  &buff,
 	}
 	require.NoError(t, e.Setup())
-	require.NoError(t, e.Run(context.Background(), taskfile.Call{Task: "foo"}))
-	assert.Contains(t, buff.String(), "foobar")
-}
 
-func TestLabelInStatus(t *testing.T) {
-	const dir = "testdata/label_status"
-
-	e := task.Executor{
-		Dir: dir,
-	}
-	require.NoError(t, e.Setup())
-	err := e.Status(context.Background(), taskfile.Call{Task: "foo"})
-	assert.ErrorContains(t, err, "foobar")
+	runs := filepath.Join(e.TempDir, "runs")
+	require.NoError(t, os.MkdirAll(runs, 0o755))
+	writeManifest := func(name string, pid int, resources ...task.Resource) string {
+		b, err := json.Marshal(map[string]any{"pid": pid, "resources": resources})
+		require.NoError(t, err)
+		path := filepath.Join(runs, name)
+		require.NoError(t, os.WriteFile(path, b, 0o644))
+		return path
+	}
+
+	// A PID above the maximum allowed by the OS, so it can't be running
+	crashedDir := t.TempDir()
+	crashed := writeManifest("crashed.json", 1<<30, task.Resource{Kind: task.ResourceTempDir, ID: crashedDir})
+	runningDir := t.TempDir()
+	running := writeManifest("running.json", os.Getppid(), task.Resource{Kind: task.ResourceTempDir, ID: runningDir})
+
+	require.NoError(t, e.Cleanup())
+	assert.NoDirExists(t, crashedDir)
+	assert.NoFileExists(t, crashed)
+	assert.DirExists(t, runningDir)
+	assert.FileExists(t, running)
+
+	// A PID that doesn't belong to the process started by the run anymore
+	if runtime.GOOS != "windows" {
+		other := exec.Command("sleep", "30")
+		require.NoError(t, other.Start())
+		t.Cleanup(func() { _ = other.Process.Kill() })
+		reused := writeManifest("reused.json", 1<<30, task.Resource{Kind: task.ResourceProcess, ID: strconv.Itoa(other.Process.Pid), Started: "0"})
+		require.NoError(t, e.Cleanup())
+		assert.NoError(t, other.Process.Signal(syscall.Signal(0)))
+		assert.NoFileExists(t, reused)
+	}
+
+	writeManifest("invalid.json", 1<<30, task.Resource{Kind: "unknown", ID: "foo"})
+	assert.EqualError(t, e.Cleanup(), "task: Unable to clean up 1 resource(s)")
+	assert.Contains(t, buff.String(), `task: Unable to remove unknown "foo": unknown resource kind "unknown"`)
 }
 
-func TestLabelWithVariableExpansion(t *testing.T) {
-	const dir = "testdata/label_var"
+func TestClean(t *testing.T) {
+	const dir = "testdata/clean"
 
 	var buff bytes.Buffer
-	e := task.Executor{
-		Dir:    dir,
-		Stdout: &buff,
-		Stderr: &buff,
+	e := &task.Executor{
+		Dir:     dir,
+		TempDir: t.TempDir(),
+		Stdout:  &buff,
+		Stderr:  &buff,
 	}
 	require.NoError(t, e.Setup())
-	require.NoError(t, e.Run(context.Background(), taskfile.Call{Task: "foo"}))
-	assert.Contains(t, buff.String(), "foobaz")
+	require.NoError(t, e.Run(context.Background(), taskfile.Call{Task: "build"}, taskfile.Call{Task: "docs"}))
+
+	build := filepathext.SmartJoin(dir, "build.out")
+	docs := filepathext.SmartJoin(dir, "docs.txt")
+	checksum := filepath.Join(e.TempDir, "checksum", "build")
+	for _, f := range []string{build, docs, checksum} {
+		require.FileExists(t, f)
+	}
+
+	e.Dry = true
+	buff.Reset()
+	require.NoError(t, e.Clean(context.Background(), taskfile.Call{Task: "build"}))
+	assert.Contains(t, buff.String(), "task: Would remove ")
+	assert.FileExists(t, build)
+	assert.FileExists(t, checksum)
+
+	// The sources are never removed, even if they're generated too
+	e.Dry = false
+	require.NoError(t, e.Clean(context.Background(), taskfile.Call{Task: "build"}))
+	assert.NoFileExists(t, build)
+	assert.NoFileExists(t, checksum)
+	assert.FileExists(t, docs)
+	assert.FileExists(t, filepathext.SmartJoin(dir, "source.txt"))
+
+	// Without tasks, all of them are cleaned
+	require.NoError(t, e.Clean(context.Background()))
+	assert.NoFileExists(t, docs)
 }
 
-func TestLabelInSummary(t *testing.T) {
-	const dir = "testdata/label_summary"
+func TestGenEnvExample(t *testing.T) {
+	const dir = "testdata/gen_env_example"
+	exampleFile := filepathext.SmartJoin(dir, task.EnvExampleFile)
+	_ = os.Remove(exampleFile)
+	t.Cleanup(func() { _ = os.Remove(exampleFile) })
 
 	var buff bytes.Buffer
-	e := task.Executor{
+	e := &task.Executor{
 		Dir:    dir,
 		Stdout: &buff,
 		Stderr: &buff,
 	}
 	require.NoError(t, e.Setup())
-	require.NoError(t, e.Run(context.Background(), taskfile.Call{Task: "foo"}))
-	assert.Contains(t, buff.String(), "foobar")
-}
+	require.NoError(t, e.GenEnvExample())
 
-func TestPromptInSummary(t *testing.T) {
-	const dir = "testdata/prompt"
-	tests := []struct {
-		name      string
-		input     string
-		wantError bool
-	}{
-		{"test short approval", "y\n", false},
-		{"test long approval", "yes\n", false},
-		{"test uppercase approval", "Y\n", false},
-		{"test stops task", "n\n", true},
-		{"test junk value stops task", "foobar\n", true},
-		{"test Enter stops task", "\n", true},
-	}
+	b, err := os.ReadFile(exampleFile)
+	require.NoError(t, err)
+	assert.Equal(t, `# Environment variables used by the tasks of Taskfile.yml.
+# Generated by `+"`task --gen-env-example`"+`.
 
-	for _, test := range tests {
-		t.Run(test.name, func(t *testing.T) {
-			var inBuff bytes.Buffer
-			var outBuff bytes.Buffer
-			var errBuff bytes.Buffer
+# Required by: deploy
+API_TOKEN=
 
-			inBuff.Write([]byte(test.input))
+# Loaded from a dotenv file
+BUILD_CACHE=
 
-			e := task.Executor{
-				Dir:         dir,
-				Stdin:       &inBuff,
-				Stdout:      &outBuff,
-				Stderr:      &errBuff,
-				AssumesTerm: true,
-			}
-			require.NoError(t, e.Setup())
+# Used by: deploy, build
+DEPLOY_USER=
 
-			err := e.Run(context.Background(), taskfile.Call{Task: "foo"})
+# Used by the Taskfile variables
+GIT_BRANCH=
 
-			if test.wantError {
-				require.Error(t, err)
-			} else {
-				require.NoError(t, err)
-			}
-		})
-	}
-}
+# Used by: build
+GOFLAGS=
 
-func TestPromptWithIndirectTask(t *testing.T) {
-	const dir = "testdata/prompt"
-	var inBuff bytes.Buffer
-	var outBuff bytes.Buffer
-	var errBuff bytes.Buffer
+# Used by: lib:publish
+REGISTRY=
 
-	inBuff.Write([]byte("y\n"))
+# Loaded from a dotenv file
+SECRET=
+`, string(b))
+	assert.Contains(t, buff.String(), "task: Wrote 7 variable(s)")
+}
 
-	e := task.Executor{
-		Dir:         dir,
-		Stdin:       &inBuff,
-		Stdout:      &outBuff,
-		Stderr:      &errBuff,
-		AssumesTerm: true,
-	}
-	require.NoError(t, e.Setup())
+func TestLint(t *testing.T) {
+	t.Setenv("TASK_TEST_LINT_SHADOWED", "from the environment")
 
-	err := e.Run(context.Background(), taskfile.Call{Task: "bar"})
-	assert.Contains(t, outBuff.String(), "show-prompt")
+	e := &task.Executor{
+		Dir:    "testdata/lint",
+		Stdout: io.Discard,
+		Stderr: io.Discard,
+	}
+	issues, err := e.Lint()
 	require.NoError(t, err)
-}
 
-func TestPromptAssumeYes(t *testing.T) {
-	const dir = "testdata/prompt"
-	tests := []struct {
-		name      string
-		assumeYes bool
-	}{
-		{"--yes flag should skip prompt", true},
-		{"task should raise errors.TaskCancelledError", false},
+	var got []string
+	for _, issue := range issues {
+		got = append(got, issue.Rule+": "+issue.Message)
+	}
+	assert.Equal(t, []string{
+		`unused-var: var "UNUSED" of the Taskfile is never used`,
+		`shadowed-env: env var "TASK_TEST_LINT_SHADOWED" of the Taskfile is ignored, since it's already set in the environment`,
+		`unused-var: var "LEFTOVER" of task "default" is never used`,
+		`internal-dep: task "default" depends on "lib:private", which is internal to testdata/lint/lib/Taskfile.yml`,
+		`non-portable-shell: a command of task "default" isn't portable: arrays are a bash/mksh feature`,
+		`non-portable-shell: a command of task "default" isn't portable: [[ ]] tests are a bash/mksh feature`,
+		`generates-without-sources: task "build" has generates but no sources, which are needed for them to be checked`,
+		`unreachable-task: task "forgotten" is internal, but no task calls it`,
+	}, got)
+	assert.Equal(t, 21, issues[4].Location.Line)
+
+	e = &task.Executor{
+		Dir:    "testdata/lint_clean",
+		Stdout: io.Discard,
+		Stderr: io.Discard,
 	}
+	issues, err = e.Lint()
+	require.NoError(t, err)
+	assert.Empty(t, issues)
+}
 
-	for _, test := range tests {
-		t.Run(test.name, func(t *testing.T) {
-			var inBuff bytes.Buffer
-			var outBuff bytes.Buffer
-			var errBuff bytes.Buffer
-
-			// always cancel the prompt so we can require.Error
-			inBuff.Write([]byte("\n"))
-
-			e := task.Executor{
-				Dir:       dir,
-				Stdin:     &inBuff,
-				Stdout:    &outBuff,
-				Stderr:    &errBuff,
-				AssumeYes: test.assumeYes,
-			}
-			require.NoError(t, e.Setup())
+func TestStatus(t *testing.T) {
+	const dir = "testdata/status"
 
-			err := e.Run(context.Background(), taskfile.Call{Task: "foo"})
+	files := []string{
+		"foo.txt",
+		"bar.txt",
+		"baz.txt",
+	}
 
-			if !test.assumeYes {
-				require.Error(t, err)
-				return
-			}
-		})
+	for _, f := range files {
+		path := filepathext.SmartJoin(dir, f)
+		_ = os.Remove(path)
+		if _, err := os.Stat(path); err == nil {
+			t.Errorf("File should not exist: %v", err)
+		}
 	}
-}
-
-func TestNoLabelInList(t *testing.T) {
-	const dir = "testdata/label_list"
-
-	var buff bytes.Buffer
-	e := task.Executor{
-		Dir:    dir,
-		Stdout: &buff,
-		Stderr: &buff,
-	}
-	require.NoError(t, e.Setup())
-	if _, err := e.ListTasks(task.ListOptions{ListOnlyTasksWithDescriptions: true}); err != nil {
-		t.Error(err)
-	}
-	assert.Contains(t, buff.String(), "foo")
-}
-
-// task -al case 1: listAll list all tasks
-func TestListAllShowsNoDesc(t *testing.T) {
-	const dir = "testdata/list_mixed_desc"
 
 	var buff bytes.Buffer
-	e := task.Executor{
-		Dir:    dir,
-		Stdout: &buff,
-		Stderr: &buff,
+	e := &task.Executor{
+		Dir:     dir,
+		TempDir: filepathext.SmartJoin(dir, ".task"),
+		Stdout:  &buff,
+		Stderr:  &buff,
+		Silent:  true,
 	}
-
 	require.NoError(t, e.Setup())
+	// gen-foo creates foo.txt, and will always fail it's status check.
+	require.NoError(t, e.Run(context.Background(), taskfile.Call{Task: "gen-foo"}))
+	// gen-foo creates bar.txt, and will pass its status-check the 3. time it
+	// is run. It creates bar.txt, but also lists it as its source. So, the checksum
+	// for the file won't match before after the second run as we the file
+	// only exists after the first run.
+	require.NoError(t, e.Run(context.Background(), taskfile.Call{Task: "gen-bar"}))
+	// gen-silent-baz is marked as being silent, and should only produce output
+	// if e.Verbose is set to true.
+	require.NoError(t, e.Run(context.Background(), taskfile.Call{Task: "gen-silent-baz"}))
 
-	var title string
-	if _, err := e.ListTasks(task.ListOptions{ListAllTasks: true}); err != nil {
-		t.Error(err)
-	}
-	for _, title = range []string{
-		"foo",
-		"voo",
-		"doo",
-	} {
-		assert.Contains(t, buff.String(), title)
+	for _, f := range files {
+		if _, err := os.Stat(filepathext.SmartJoin(dir, f)); err != nil {
+			t.Errorf("File should exist: %v", err)
+		}
 	}
-}
 
-// task -al case 2: !listAll list some tasks (only those with desc)
-func TestListCanListDescOnly(t *testing.T) {
-	const dir = "testdata/list_mixed_desc"
+	// Run gen-bar a second time to produce a checksum file that matches bar.txt
+	require.NoError(t, e.Run(context.Background(), taskfile.Call{Task: "gen-bar"}))
 
-	var buff bytes.Buffer
-	e := task.Executor{
-		Dir:    dir,
-		Stdout: &buff,
-		Stderr: &buff,
-	}
+	// Run gen-bar a third time, to make sure we've triggered the status check.
+	require.NoError(t, e.Run(context.Background(), taskfile.Call{Task: "gen-bar"}))
 
-	require.NoError(t, e.Setup())
-	if _, err := e.ListTasks(task.ListOptions{ListOnlyTasksWithDescriptions: true}); err != nil {
-		t.Error(err)
-	}
+	// We're silent, so no output should have been produced.
+	assert.Empty(t, buff.String())
 
-	var title string
-	assert.Contains(t, buff.String(), "foo")
-	for _, title = range []string{
-		"voo",
-		"doo",
-	} {
-		assert.NotContains(t, buff.String(), title)
-	}
-}
+	// Now, let's remove source file, and run the task again to to prepare
+	// for the next test.
+	err := os.Remove(filepathext.SmartJoin(dir, "bar.txt"))
+	require.NoError(t, err)
+	require.NoError(t, e.Run(context.Background(), taskfile.Call{Task: "gen-bar"}))
+	buff.Reset()
 
-func TestStatusVariables(t *testing.T) {
-	const dir = "testdata/status_vars"
+	// Global silence switched of, so we should see output unless the task itself
+	// is silent.
+	e.Silent = false
 
-	_ = os.RemoveAll(filepathext.SmartJoin(dir, ".task"))
-	_ = os.Remove(filepathext.SmartJoin(dir, "generated.txt"))
+	// all: not up-to-date
+	require.NoError(t, e.Run(context.Background(), taskfile.Call{Task: "gen-foo"}))
+	assert.Equal(t, "task: [gen-foo] touch foo.txt", strings.TrimSpace(buff.String()))
+	buff.Reset()
+	// status: not up-to-date
+	require.NoError(t, e.Run(context.Background(), taskfile.Call{Task: "gen-foo"}))
+	assert.Equal(t, "task: [gen-foo] touch foo.txt", strings.TrimSpace(buff.String()))
+	buff.Reset()
 
-	var buff bytes.Buffer
-	e := task.Executor{
-		Dir:     dir,
-		TempDir: filepathext.SmartJoin(dir, ".task"),
-		Stdout:  &buff,
-		Stderr:  &buff,
-		Silent:  false,
-		Verbose: true,
-	}
-	require.NoError(t, e.Setup())
-	require.NoError(t, e.Run(context.Background(), taskfile.Call{Task: "build"}))
+	// sources: not up-to-date
+	require.NoError(t, e.Run(context.Background(), taskfile.Call{Task: "gen-bar"}))
+	assert.Equal(t, "task: [gen-bar] touch bar.txt", strings.TrimSpace(buff.String()))
+	buff.Reset()
+	// all: up-to-date
+	require.NoError(t, e.Run(context.Background(), taskfile.Call{Task: "gen-bar"}))
+	assert.Equal(t, `task: Task "gen-bar" is up to date`, strings.TrimSpace(buff.String()))
+	buff.Reset()
 
-	assert.Contains(t, buff.String(), "3e464c4b03f4b65d740e1e130d4d108a")
+	// sources: not up-to-date, no output produced.
+	require.NoError(t, e.Run(context.Background(), taskfile.Call{Task: "gen-silent-baz"}))
+	assert.Empty(t, buff.String())
 
-	inf, err := os.Stat(filepathext.SmartJoin(dir, "source.txt"))
-	require.NoError(t, err)
-	ts := fmt.Sprintf("%d", inf.ModTime().Unix())
-	tf := inf.ModTime().String()
+	// up-to-date, no output produced
+	require.NoError(t, e.Run(context.Background(), taskfile.Call{Task: "gen-silent-baz"}))
+	assert.Empty(t, buff.String())
 
-	assert.Contains(t, buff.String(), ts)
-	assert.Contains(t, buff.String(), tf)
+	e.Verbose = true
+	// up-to-date, output produced due to Verbose mode.
+	require.NoError(t, e.Run(context.Background(), taskfile.Call{Task: "gen-silent-baz"}))
+	assert.Equal(t, `task: Task "gen-silent-baz" is up to date`, strings.TrimSpace(buff.String()))
+	buff.Reset()
 }
 
-func TestInit(t *testing.T) {
-	const dir = "testdata/init"
-	file := filepathext.SmartJoin(dir, "Taskfile.yml")
+func TestPrecondition(t *testing.T) {
+	const dir = "testdata/precondition"
 
-	_ = os.Remove(file)
-	if _, err := os.Stat(file); err == nil {
-		t.Errorf("Taskfile.yml should not exist")
+	var buff bytes.Buffer
+	e := &task.Executor{
+		Dir:    dir,
+		Stdout: &buff,
+		Stderr: &buff,
 	}
 
-	if err := task.InitTaskfile(io.Discard, dir); err != nil {
-		t.Error(err)
+	// A precondition that has been met
+	require.NoError(t, e.Setup())
+	require.NoError(t, e.Run(context.Background(), taskfile.Call{Task: "foo"}))
+	if buff.String() != "" {
+		t.Errorf("Got Output when none was expected: %s", buff.String())
 	}
 
-	if _, err := os.Stat(file); err != nil {
-		t.Errorf("Taskfile.yml should exist")
-	}
-	_ = os.Remove(file)
-}
+	// A precondition that was not met, whose message is the one of the error
+	err := e.Run(context.Background(), taskfile.Call{Task: "impossible"})
+	assert.EqualError(t, err, "task: precondition not met: 1 != 0 obviously!")
+	assert.Empty(t, buff.String())
 
-func TestCyclicDep(t *testing.T) {
-	const dir = "testdata/cyclic"
+	// Calling a task with a precondition in a dependency fails the task
+	err = e.Run(context.Background(), taskfile.Call{Task: "depends_on_impossible"})
+	assert.ErrorContains(t, err, "task: precondition not met: 1 != 0 obviously!")
+	assert.Empty(t, buff.String())
 
-	e := task.Executor{
-		Dir:    dir,
-		Stdout: io.Discard,
-		Stderr: io.Discard,
-	}
-	require.NoError(t, e.Setup())
-	assert.IsType(t, &errors.TaskCalledTooManyTimesError{}, e.Run(context.Background(), taskfile.Call{Task: "task-1"}))
+	// Calling a task with a precondition in a cmd fails the task
+	err = e.Run(context.Background(), taskfile.Call{Task: "executes_failing_task_as_cmd"})
+	assert.ErrorContains(t, err, "task: precondition not met: 1 != 0 obviously!")
+	assert.Empty(t, buff.String())
 }
 
-func TestTaskVersion(t *testing.T) {
+func TestPreconditionChecks(t *testing.T) {
+	const dir = "testdata/precondition_checks"
+
 	tests := []struct {
-		Dir     string
-		Version *semver.Version
+		task   string
+		err    string
+		output string
 	}{
-		{"testdata/version/v2", semver.MustParse("2")},
+		{"met", "", "ran\n"},
+		{"missing_file", "file \"missing.txt\" does not exist", ""},
+		{"missing_command", "task-missing-command is not installed", ""},
+		{"skip", "", ""},
+		{"depends_on_skip", "", "ran too\n"},
+		{"warn", "", "task: environment variable \"TASK_PRECONDITION_UNSET_VAR\" is not set\nran\n"},
 	}
 
 	for _, test := range tests {
-		t.Run(test.Dir, func(t *testing.T) {
-			e := task.Executor{
-				Dir:    test.Dir,
-				Stdout: io.Discard,
-				Stderr: io.Discard,
+		t.Run(test.task, func(t *testing.T) {
+			var buff bytes.Buffer
+			e := &task.Executor{
+				Dir:    dir,
+				Stdout: &buff,
+				Stderr: &buff,
 			}
 			require.NoError(t, e.Setup())
-			assert.Equal(t, test.Version, e.Taskfile.Version)
-			assert.Equal(t, 2, e.Taskfile.Tasks.Len())
+
+			err := e.Run(context.Background(), taskfile.Call{Task: test.task})
+			if test.err != "" {
+				require.ErrorIs(t, err, task.ErrPreconditionFailed)
+				assert.EqualError(t, err, "task: precondition not met: "+test.err)
+			} else {
+				require.NoError(t, err)
+			}
+			assert.Equal(t, test.output, buff.String())
 		})
 	}
 }
 
-func TestTaskIgnoreErrors(t *testing.T) {
-	const dir = "testdata/ignore_errors"
-
-	e := task.Executor{
-		Dir:    dir,
-		Stdout: io.Discard,
-		Stderr: io.Discard,
-	}
-	require.NoError(t, e.Setup())
+func TestGenerates(t *testing.T) {
+	const dir = "testdata/generates"
 
-	require.NoError(t, e.Run(context.Background(), taskfile.Call{Task: "task-should-pass"}))
-	require.Error(t, e.Run(context.Background(), taskfile.Call{Task: "task-should-fail"}))
-	require.NoError(t, e.Run(context.Background(), taskfile.Call{Task: "cmd-should-pass"}))
-	require.Error(t, e.Run(context.Background(), taskfile.Call{Task: "cmd-should-fail"}))
-}
+	const (
+		srcTask        = "sub/src.txt"
+		relTask        = "rel.txt"
+		absTask        = "abs.txt"
+		fileWithSpaces = "my text file.txt"
+	)
 
-func TestExpand(t *testing.T) {
-	const dir = "testdata/expand"
+	srcFile := filepathext.SmartJoin(dir, srcTask)
 
-	home, err := os.UserHomeDir()
-	if err != nil {
-		t.Errorf("Couldn't get $HOME: %v", err)
+	for _, task := range []string{srcTask, relTask, absTask, fileWithSpaces} {
+		path := filepathext.SmartJoin(dir, task)
+		_ = os.Remove(path)
+		if _, err := os.Stat(path); err == nil {
+			t.Errorf("File should not exist: %v", err)
+		}
 	}
-	var buff bytes.Buffer
 
-	e := task.Executor{
+	buff := bytes.NewBuffer(nil)
+	e := &task.Executor{
 		Dir:    dir,
-		Stdout: &buff,
-		Stderr: &buff,
+		Stdout: buff,
+		Stderr: buff,
 	}
 	require.NoError(t, e.Setup())
-	require.NoError(t, e.Run(context.Background(), taskfile.Call{Task: "pwd"}))
-	assert.Equal(t, home, strings.TrimSpace(buff.String()))
-}
-
-func TestDry(t *testing.T) {
-	const dir = "testdata/dry"
 
-	file := filepathext.SmartJoin(dir, "file.txt")
-	_ = os.Remove(file)
+	for _, theTask := range []string{relTask, absTask, fileWithSpaces} {
+		destFile := filepathext.SmartJoin(dir, theTask)
+		upToDate := fmt.Sprintf("task: Task \"%s\" is up to date\n", srcTask) +
+			fmt.Sprintf("task: Task \"%s\" is up to date\n", theTask)
 
-	var buff bytes.Buffer
+		// Run task for the first time.
+		require.NoError(t, e.Run(context.Background(), taskfile.Call{Task: theTask}))
 
-	e := task.Executor{
-		Dir:    dir,
-		Stdout: &buff,
-		Stderr: &buff,
-		Dry:    true,
-	}
-	require.NoError(t, e.Setup())
-	require.NoError(t, e.Run(context.Background(), taskfile.Call{Task: "build"}))
+		if _, err := os.Stat(srcFile); err != nil {
+			t.Errorf("File should exist: %v", err)
+		}
+		if _, err := os.Stat(destFile); err != nil {
+			t.Errorf("File should exist: %v", err)
+		}
+		// Ensure task was not incorrectly found to be up-to-date on first run.
+		if buff.String() == upToDate {
+			t.Errorf("Wrong output message: %s", buff.String())
+		}
+		buff.Reset()
 
-	assert.Equal(t, "task: [build] touch file.txt", strings.TrimSpace(buff.String()))
-	if _, err := os.Stat(file); err == nil {
-		t.Errorf("File should not exist %s", file)
+		// Re-run task to ensure it's now found to be up-to-date.
+		require.NoError(t, e.Run(context.Background(), taskfile.Call{Task: theTask}))
+		if buff.String() != upToDate {
+			t.Errorf("Wrong output message: %s", buff.String())
+		}
+		buff.Reset()
 	}
 }
 
-// TestDryChecksum tests if the checksum file is not being written to disk
-// if the dry mode is enabled.
-func TestDryChecksum(t *testing.T) {
-	const dir = "testdata/dry_checksum"
-
-	checksumFile := filepathext.SmartJoin(dir, ".task/checksum/default")
-	_ = os.Remove(checksumFile)
+func TestStatusChecksum(t *testing.T) {
+	const dir = "testdata/checksum"
 
-	e := task.Executor{
-		Dir:     dir,
-		TempDir: filepathext.SmartJoin(dir, ".task"),
-		Stdout:  io.Discard,
-		Stderr:  io.Discard,
-		Dry:     true,
+	tests := []struct {
+		files []string
+		task  string
+	}{
+		{[]string{"generated.txt", ".task/checksum/build"}, "build"},
+		{[]string{"generated.txt", ".task/checksum/build-with-status"}, "build-with-status"},
 	}
-	require.NoError(t, e.Setup())
-	require.NoError(t, e.Run(context.Background(), taskfile.Call{Task: "default"}))
 
-	_, err := os.Stat(checksumFile)
-	require.Error(t, err, "checksum file should not exist")
+	for _, test := range tests {
+		t.Run(test.task, func(t *testing.T) {
+			for _, f := range test.files {
+				_ = os.Remove(filepathext.SmartJoin(dir, f))
 
-	e.Dry = false
-	require.NoError(t, e.Run(context.Background(), taskfile.Call{Task: "default"}))
-	_, err = os.Stat(checksumFile)
-	require.NoError(t, err, "checksum file should exist")
-}
+				_, err := os.Stat(filepathext.SmartJoin(dir, f))
+				require.Error(t, err)
+			}
 
-func TestIncludes(t *testing.T) {
-	tt := fileContentTest{
-		Dir:       "testdata/includes",
-		Target:    "default",
-		TrimSpace: true,
-		Files: map[string]string{
-			"main.txt":                                  "main",
-			"included_directory.txt":                    "included_directory",
-			"included_directory_without_dir.txt":        "included_directory_without_dir",
-			"included_taskfile_without_dir.txt":         "included_taskfile_without_dir",
-			"./module2/included_directory_with_dir.txt": "included_directory_with_dir",
-			"./module2/included_taskfile_with_dir.txt":  "included_taskfile_with_dir",
-			"os_include.txt":                            "os",
-		},
-	}
-	tt.Run(t)
-}
+			var buff bytes.Buffer
+			tempdir := filepathext.SmartJoin(dir, ".task")
+			e := task.Executor{
+				Dir:     dir,
+				TempDir: tempdir,
+				Stdout:  &buff,
+				Stderr:  &buff,
+			}
+			require.NoError(t, e.Setup())
 
-func TestIncludesMultiLevel(t *testing.T) {
-	tt := fileContentTest{
-		Dir:       "testdata/includes_multi_level",
-		Target:    "default",
-		TrimSpace: true,
-		Files: map[string]string{
-			"called_one.txt":   "one",
-			"called_two.txt":   "two",
-			"called_three.txt": "three",
-		},
+			require.NoError(t, e.Run(context.Background(), taskfile.Call{Task: test.task}))
+			for _, f := range test.files {
+				_, err := os.Stat(filepathext.SmartJoin(dir, f))
+				require.NoError(t, err)
+			}
+
+			// Capture the modification time, so we can ensure the checksum file
+			// is not regenerated when the hash hasn't changed.
+			s, err := os.Stat(filepathext.SmartJoin(tempdir, "checksum/"+test.task))
+			require.NoError(t, err)
+			time := s.ModTime()
+
+			buff.Reset()
+			require.NoError(t, e.Run(context.Background(), taskfile.Call{Task: test.task}))
+			assert.Equal(t, `task: Task "`+test.task+`" is up to date`+"\n", buff.String())
+
+			s, err = os.Stat(filepathext.SmartJoin(tempdir, "checksum/"+test.task))
+			require.NoError(t, err)
+			assert.Equal(t, time, s.ModTime())
+		})
 	}
-	tt.Run(t)
 }
 
-func TestIncludeCycle(t *testing.T) {
-	const dir = "testdata/includes_cycle"
+func TestGeneratesChecksum(t *testing.T) {
+	const dir = "testdata/generates_checksum"
+
+	generated := filepathext.SmartJoin(dir, "generated.txt")
+	tempdir := filepathext.SmartJoin(dir, ".task")
+	_ = os.Remove(generated)
+	_ = os.RemoveAll(tempdir)
 
 	var buff bytes.Buffer
 	e := task.Executor{
-		Dir:    dir,
-		Stdout: &buff,
-		Stderr: &buff,
-		Silent: true,
+		Dir:     dir,
+		TempDir: tempdir,
+		Stdout:  &buff,
+		Stderr:  &buff,
 	}
+	require.NoError(t, e.Setup())
 
-	err := e.Setup()
-	require.Error(t, err)
-	assert.Contains(t, err.Error(), "task: include cycle detected between")
-}
+	require.NoError(t, e.Run(context.Background(), taskfile.Call{Task: "build"}))
+	_, err := os.Stat(filepathext.SmartJoin(tempdir, "checksum/build.generates"))
+	require.NoError(t, err)
 
-func TestIncorrectVersionIncludes(t *testing.T) {
-	const dir = "testdata/incorrect_includes"
-	expectedError := "task: Import with additional parameters is only available starting on Taskfile version v3"
+	buff.Reset()
+	require.NoError(t, e.Run(context.Background(), taskfile.Call{Task: "build"}))
+	assert.Equal(t, `task: Task "build" is up to date`+"\n", buff.String())
 
-	var buff bytes.Buffer
-	e := task.Executor{
-		Dir:    dir,
-		Stdout: &buff,
-		Stderr: &buff,
-		Silent: true,
-	}
+	// Editing the generated file by hand runs the task again
+	require.NoError(t, os.WriteFile(generated, []byte("edited\n"), 0o644))
+	buff.Reset()
+	require.NoError(t, e.Run(context.Background(), taskfile.Call{Task: "build"}))
+	assert.NotContains(t, buff.String(), "is up to date")
+	b, err := os.ReadFile(generated)
+	require.NoError(t, err)
+	assert.Equal(t, "generated\n", string(b))
 
-	assert.EqualError(t, e.Setup(), expectedError)
+	buff.Reset()
+	require.NoError(t, e.Run(context.Background(), taskfile.Call{Task: "build"}))
+	assert.Equal(t, `task: Task "build" is up to date`+"\n", buff.String())
 }
 
-func TestIncludesIncorrect(t *testing.T) {
-	const dir = "testdata/includes_incorrect"
+func TestReadOnlyTempDirFallback(t *testing.T) {
+	dir := t.TempDir()
+	taskfileContent := `version: '3'
+
+tasks:
+  build:
+    sources:
+      - source.txt
+    cmds:
+      - echo "built"
+`
+	require.NoError(t, os.WriteFile(filepathext.SmartJoin(dir, "Taskfile.yml"), []byte(taskfileContent), 0o644))
+	require.NoError(t, os.WriteFile(filepathext.SmartJoin(dir, "source.txt"), []byte("source"), 0o644))
+	// A regular file in place of the .task directory can't be written into,
+	// just like a read-only mount.
+	require.NoError(t, os.WriteFile(filepathext.SmartJoin(dir, ".task"), nil, 0o444))
 
 	var buff bytes.Buffer
-	e := task.Executor{
+	e := &task.Executor{
 		Dir:    dir,
 		Stdout: &buff,
 		Stderr: &buff,
-		Silent: true,
 	}
+	require.NoError(t, e.Setup())
+	assert.NotEqual(t, filepathext.SmartJoin(dir, ".task"), e.TempDir)
+	t.Cleanup(func() { _ = os.RemoveAll(e.TempDir) })
 
-	err := e.Setup()
-	require.Error(t, err)
-	assert.Contains(t, err.Error(), "task: Failed to parse testdata/includes_incorrect/incomplete.yml:")
-}
-
-func TestIncludesEmptyMain(t *testing.T) {
-	tt := fileContentTest{
-		Dir:       "testdata/includes_empty",
-		Target:    "included:default",
-		TrimSpace: true,
-		Files: map[string]string{
-			"file.txt": "default",
-		},
-	}
-	tt.Run(t)
+	require.NoError(t, e.Run(context.Background(), taskfile.Call{Task: "build"}))
+	buff.Reset()
+	require.NoError(t, e.Run(context.Background(), taskfile.Call{Task: "build"}))
+	assert.Equal(t, `task: Task "build" is up to date`, strings.TrimSpace(buff.String()))
 }
 
-func TestIncludesDependencies(t *testing.T) {
-	tt := fileContentTest{
-		Dir:       "testdata/includes_deps",
-		Target:    "default",
-		TrimSpace: true,
-		Files: map[string]string{
-			"default.txt":     "default",
-			"called_dep.txt":  "called_dep",
-			"called_task.txt": "called_task",
-		},
+func TestRenameTask(t *testing.T) {
+	dir := t.TempDir()
+	for _, f := range []string{"Taskfile.yml", "included/Taskfile.yml"} {
+		b, err := os.ReadFile(filepathext.SmartJoin("testdata/rename", f))
+		require.NoError(t, err)
+		require.NoError(t, os.MkdirAll(filepath.Dir(filepathext.SmartJoin(dir, f)), 0o755))
+		require.NoError(t, os.WriteFile(filepathext.SmartJoin(dir, f), b, 0o644))
 	}
-	tt.Run(t)
-}
 
-func TestIncludesCallingRoot(t *testing.T) {
-	tt := fileContentTest{
-		Dir:       "testdata/includes_call_root_task",
-		Target:    "included:call-root",
-		TrimSpace: true,
-		Files: map[string]string{
-			"root_task.txt": "root task",
-		},
+	e := &task.Executor{
+		Dir:    dir,
+		Stdout: io.Discard,
+		Stderr: io.Discard,
 	}
-	tt.Run(t)
-}
+	require.NoError(t, e.Setup())
+	require.NoError(t, e.RenameTask("inc:build", "inc:compile"))
 
-func TestIncludesOptional(t *testing.T) {
-	tt := fileContentTest{
-		Dir:       "testdata/includes_optional",
-		Target:    "default",
-		TrimSpace: true,
-		Files: map[string]string{
-			"called_dep.txt": "called_dep",
-		},
-	}
-	tt.Run(t)
-}
+	root, err := os.ReadFile(filepathext.SmartJoin(dir, "Taskfile.yml"))
+	require.NoError(t, err)
+	assert.Equal(t, `version: '3'
+
+includes:
+  inc: ./included
+  other: ./included
+
+before_all: [inc:compile]
+after_each:
+  - task: other:compile
+
+tasks:
+  default:
+    # build before releasing
+    deps: [inc:compile]
+    cmds:
+      - task: inc:compile
+      - echo "inc:build"
+
+  release:
+    deps:
+      - task: 'inc:compile'
+        vars: { FOO: bar }
+      - other:compile
+
+  short:
+    - task: inc:compile
+    - echo short
+
+  ci:
+    extends: inc:compile
+
+  deploy:
+    pipeline:
+      - inc:compile
+      - [inc:test, other:compile]
+      - name: publish
+        tasks:
+          - task: inc:compile
+            vars: { FOO: bar }
+`, string(root), "the task is renamed under every namespace of the included Taskfile")
+
+	included, err := os.ReadFile(filepathext.SmartJoin(dir, "included/Taskfile.yml"))
+	require.NoError(t, err)
+	assert.Equal(t, `version: '3'
 
-func TestIncludesOptionalImplicitFalse(t *testing.T) {
-	const dir = "testdata/includes_optional_implicit_false"
-	wd, _ := os.Getwd()
+tasks:
+  compile: # the build task
+    cmds:
+      - echo build
 
-	message := "stat %s/%s/TaskfileOptional.yml: no such file or directory"
-	expected := fmt.Sprintf(message, wd, dir)
+  test:
+    deps: [compile]
+    cmds:
+      - task: :inc:compile
+      - defer: { task: compile }
+`, string(included))
 
-	e := task.Executor{
+	e = &task.Executor{
 		Dir:    dir,
 		Stdout: io.Discard,
 		Stderr: io.Discard,
 	}
-
-	err := e.Setup()
-	require.Error(t, err)
-	assert.Equal(t, expected, err.Error())
+	require.NoError(t, e.Setup())
+	assert.Error(t, e.RenameTask("inc:compile", "compile"))
+	assert.Error(t, e.RenameTask("inc:compile", "inc:test"))
 }
 
-func TestIncludesOptionalExplicitFalse(t *testing.T) {
-	const dir = "testdata/includes_optional_explicit_false"
-	wd, _ := os.Getwd()
+func TestAlias(t *testing.T) {
+	const dir = "testdata/alias"
 
-	message := "stat %s/%s/TaskfileOptional.yml: no such file or directory"
-	expected := fmt.Sprintf(message, wd, dir)
+	data, err := os.ReadFile(filepathext.SmartJoin(dir, "alias.txt"))
+	require.NoError(t, err)
 
+	var buff bytes.Buffer
 	e := task.Executor{
 		Dir:    dir,
-		Stdout: io.Discard,
-		Stderr: io.Discard,
+		Stdout: &buff,
+		Stderr: &buff,
 	}
-
-	err := e.Setup()
-	require.Error(t, err)
-	assert.Equal(t, expected, err.Error())
+	require.NoError(t, e.Setup())
+	require.NoError(t, e.Run(context.Background(), taskfile.Call{Task: "f"}))
+	assert.Equal(t, string(data), buff.String())
 }
 
-func TestIncludesFromCustomTaskfile(t *testing.T) {
-	tt := fileContentTest{
-		Dir:        "testdata/includes_yaml",
-		Entrypoint: "Custom.ext",
-		Target:     "default",
-		TrimSpace:  true,
-		Files: map[string]string{
-			"main.txt":                         "main",
-			"included_with_yaml_extension.txt": "included_with_yaml_extension",
-			"included_with_custom_file.txt":    "included_with_custom_file",
-		},
-	}
-	tt.Run(t)
-}
-
-func TestIncludesRelativePath(t *testing.T) {
-	const dir = "testdata/includes_rel_path"
-
-	var buff bytes.Buffer
-	e := task.Executor{
-		Dir:    dir,
-		Stdout: &buff,
-		Stderr: &buff,
-	}
-
-	require.NoError(t, e.Setup())
-
-	require.NoError(t, e.Run(context.Background(), taskfile.Call{Task: "common:pwd"}))
-	assert.Contains(t, buff.String(), "testdata/includes_rel_path/common")
-
-	buff.Reset()
-	require.NoError(t, e.Run(context.Background(), taskfile.Call{Task: "included:common:pwd"}))
-	assert.Contains(t, buff.String(), "testdata/includes_rel_path/common")
-}
+func TestWildcards(t *testing.T) {
+	const dir = "testdata/wildcards"
 
-func TestIncludesInternal(t *testing.T) {
-	const dir = "testdata/internal_task"
 	tests := []struct {
-		name           string
-		task           string
-		expectedErr    bool
-		expectedOutput string
+		task     string
+		expected string
 	}{
-		{"included internal task via task", "task-1", false, "Hello, World!\n"},
-		{"included internal task via dep", "task-2", false, "Hello, World!\n"},
-		{"included internal direct", "included:task-3", true, "task: No tasks with description available. Try --list-all to list all tasks\n"},
+		// An exact match is preferred to a wildcard
+		{"wildcard-foo", "Hello foo\n"},
+		{"wildcard-bar", "Hello bar\n"},
+		// The first matching task is used
+		{"wildcard-bar-baz", "Hello bar-baz\n"},
+		{"a-b-c", "Hello a, b and c\n"},
+		{"start-web", "Starting web\n"},
 	}
-
 	for _, test := range tests {
-		t.Run(test.name, func(t *testing.T) {
+		t.Run(test.task, func(t *testing.T) {
 			var buff bytes.Buffer
 			e := task.Executor{
 				Dir:    dir,
@@ -1218,1074 +1602,3644 @@ func TestIncludesInternal(t *testing.T) {
 				Silent: true,
 			}
 			require.NoError(t, e.Setup())
-
-			err := e.Run(context.Background(), taskfile.Call{Task: test.task})
-			if test.expectedErr {
-				require.Error(t, err)
-			} else {
-				require.NoError(t, err)
-			}
-			assert.Equal(t, test.expectedOutput, buff.String())
+			require.NoError(t, e.Run(context.Background(), taskfile.Call{Task: test.task}))
+			assert.Equal(t, test.expected, buff.String())
 		})
 	}
-}
-
-func TestIncludesInterpolation(t *testing.T) {
-	const dir = "testdata/includes_interpolation"
-	tests := []struct {
-		name           string
-		task           string
-		expectedErr    bool
-		expectedOutput string
-	}{
-		{"include", "include", false, "includes_interpolation\n"},
-		{"include with dir", "include-with-dir", false, "included\n"},
-	}
-
-	for _, test := range tests {
-		t.Run(test.name, func(t *testing.T) {
-			var buff bytes.Buffer
-			e := task.Executor{
-				Dir:    dir,
-				Stdout: &buff,
-				Stderr: &buff,
-				Silent: true,
-			}
-			require.NoError(t, e.Setup())
 
-			err := e.Run(context.Background(), taskfile.Call{Task: test.task})
-			if test.expectedErr {
-				require.Error(t, err)
-			} else {
-				require.NoError(t, err)
-			}
-			assert.Equal(t, test.expectedOutput, buff.String())
-		})
+	var buff bytes.Buffer
+	e := task.Executor{
+		Dir:    dir,
+		Stdout: &buff,
+		Stderr: &buff,
+		Silent: true,
 	}
+	require.NoError(t, e.Setup())
+	err := e.Run(context.Background(), taskfile.Call{Task: "wildcard-"})
+	var taskNotFoundErr *errors.TaskNotFoundError
+	assert.ErrorAs(t, err, &taskNotFoundErr)
 }
 
-func TestInternalTask(t *testing.T) {
-	const dir = "testdata/internal_task"
+func TestDidYouMean(t *testing.T) {
+	const dir = "testdata/did_you_mean"
+
 	tests := []struct {
-		name           string
-		task           string
-		expectedErr    bool
-		expectedOutput string
+		task       string
+		didYouMean string
 	}{
-		{"internal task via task", "task-1", false, "Hello, World!\n"},
-		{"internal task via dep", "task-2", false, "Hello, World!\n"},
-		{"internal direct", "task-3", true, ""},
+		{"biuld:linux", "build:linux"},
+		{"generat", "generate"},
+		{"ge", "gen"},
+		// Internal tasks are never suggested
+		{"secrte", ""},
+		{"something-else", ""},
 	}
-
 	for _, test := range tests {
-		t.Run(test.name, func(t *testing.T) {
+		t.Run(test.task, func(t *testing.T) {
 			var buff bytes.Buffer
 			e := task.Executor{
 				Dir:    dir,
 				Stdout: &buff,
 				Stderr: &buff,
-				Silent: true,
 			}
 			require.NoError(t, e.Setup())
-
 			err := e.Run(context.Background(), taskfile.Call{Task: test.task})
-			if test.expectedErr {
-				require.Error(t, err)
+			var taskNotFoundErr *errors.TaskNotFoundError
+			require.ErrorAs(t, err, &taskNotFoundErr)
+			assert.Equal(t, test.didYouMean, taskNotFoundErr.DidYouMean)
+			// The tasks are only listed without a suggestion
+			if test.didYouMean != "" {
+				assert.Empty(t, buff.String())
 			} else {
-				require.NoError(t, err)
+				assert.Contains(t, buff.String(), "Builds for Linux")
 			}
-			assert.Equal(t, test.expectedOutput, buff.String())
 		})
 	}
 }
 
-func TestIncludesShadowedDefault(t *testing.T) {
-	tt := fileContentTest{
-		Dir:       "testdata/includes_shadowed_default",
-		Target:    "included",
-		TrimSpace: true,
-		Files: map[string]string{
-			"file.txt": "shadowed",
-		},
+func TestDuplicateAlias(t *testing.T) {
+	const dir = "testdata/alias"
+
+	var buff bytes.Buffer
+	e := task.Executor{
+		Dir:    dir,
+		Stdout: &buff,
+		Stderr: &buff,
 	}
-	tt.Run(t)
+	require.NoError(t, e.Setup())
+	require.Error(t, e.Run(context.Background(), taskfile.Call{Task: "x"}))
+	assert.Equal(t, "", buff.String())
 }
 
-func TestIncludesUnshadowedDefault(t *testing.T) {
-	tt := fileContentTest{
-		Dir:       "testdata/includes_unshadowed_default",
-		Target:    "included",
-		TrimSpace: true,
-		Files: map[string]string{
-			"file.txt": "included",
-		},
+func TestAliasSummary(t *testing.T) {
+	const dir = "testdata/alias"
+
+	data, err := os.ReadFile(filepathext.SmartJoin(dir, "alias-summary.txt"))
+	require.NoError(t, err)
+
+	var buff bytes.Buffer
+	e := task.Executor{
+		Dir:     dir,
+		Summary: true,
+		Stdout:  &buff,
+		Stderr:  &buff,
 	}
-	tt.Run(t)
+	require.NoError(t, e.Setup())
+	require.NoError(t, e.Run(context.Background(), taskfile.Call{Task: "f"}))
+	assert.Equal(t, string(data), buff.String())
 }
 
-func TestSupportedFileNames(t *testing.T) {
-	fileNames := []string{
-		"Taskfile.yml",
-		"Taskfile.yaml",
-		"Taskfile.dist.yml",
-		"Taskfile.dist.yaml",
-	}
-	for _, fileName := range fileNames {
-		t.Run(fileName, func(t *testing.T) {
-			tt := fileContentTest{
-				Dir:       fmt.Sprintf("testdata/file_names/%s", fileName),
-				Target:    "default",
-				TrimSpace: true,
-				Files: map[string]string{
-					"output.txt": "hello",
-				},
-			}
-			tt.Run(t)
-		})
+func TestLabelUpToDate(t *testing.T) {
+	const dir = "testdata/label_uptodate"
+
+	var buff bytes.Buffer
+	e := task.Executor{
+		Dir:    dir,
+		Stdout: &buff,
+		Stderr: &buff,
 	}
+	require.NoError(t, e.Setup())
+	require.NoError(t, e.Run(context.Background(), taskfile.Call{Task: "foo"}))
+	assert.Contains(t, buff.String(), "foobar")
 }
 
-func TestSummary(t *testing.T) {
-	const dir = "testdata/summary"
+func TestLabelSummary(t *testing.T) {
+	const dir = "testdata/label_summary"
 
 	var buff bytes.Buffer
 	e := task.Executor{
 		Dir:     dir,
+		Summary: true,
 		Stdout:  &buff,
 		Stderr:  &buff,
-		Summary: true,
-		Silent:  true,
 	}
 	require.NoError(t, e.Setup())
-	require.NoError(t, e.Run(context.Background(), taskfile.Call{Task: "task-with-summary"}, taskfile.Call{Task: "other-task-with-summary"}))
+	require.NoError(t, e.Run(context.Background(), taskfile.Call{Task: "foo"}))
+	assert.Contains(t, buff.String(), "foobar")
+}
 
-	data, err := os.ReadFile(filepathext.SmartJoin(dir, "task-with-summary.txt"))
-	require.NoError(t, err)
+func TestLabelInStatus(t *testing.T) {
+	const dir = "testdata/label_status"
 
-	expectedOutput := string(data)
-	if runtime.GOOS == "windows" {
-		expectedOutput = strings.ReplaceAll(expectedOutput, "\r\n", "\n")
+	e := task.Executor{
+		Dir: dir,
 	}
-
-	assert.Equal(t, expectedOutput, buff.String())
+	require.NoError(t, e.Setup())
+	err := e.Status(context.Background(), taskfile.Call{Task: "foo"})
+	assert.ErrorContains(t, err, "foobar")
 }
 
-func TestWhenNoDirAttributeItRunsInSameDirAsTaskfile(t *testing.T) {
-	const expected = "dir"
-	const dir = "testdata/" + expected
-	var out bytes.Buffer
-	e := &task.Executor{
+func TestLabelWithVariableExpansion(t *testing.T) {
+	const dir = "testdata/label_var"
+
+	var buff bytes.Buffer
+	e := task.Executor{
 		Dir:    dir,
-		Stdout: &out,
-		Stderr: &out,
+		Stdout: &buff,
+		Stderr: &buff,
 	}
-
 	require.NoError(t, e.Setup())
-	require.NoError(t, e.Run(context.Background(), taskfile.Call{Task: "whereami"}))
-
-	// got should be the "dir" part of "testdata/dir"
-	got := strings.TrimSuffix(filepath.Base(out.String()), "\n")
-	assert.Equal(t, expected, got, "Mismatch in the working directory")
+	require.NoError(t, e.Run(context.Background(), taskfile.Call{Task: "foo"}))
+	assert.Contains(t, buff.String(), "foobaz")
 }
 
-func TestWhenDirAttributeAndDirExistsItRunsInThatDir(t *testing.T) {
-	const expected = "exists"
-	const dir = "testdata/dir/explicit_exists"
-	var out bytes.Buffer
-	e := &task.Executor{
+func TestLabelInSummary(t *testing.T) {
+	const dir = "testdata/label_summary"
+
+	var buff bytes.Buffer
+	e := task.Executor{
 		Dir:    dir,
-		Stdout: &out,
-		Stderr: &out,
+		Stdout: &buff,
+		Stderr: &buff,
 	}
-
 	require.NoError(t, e.Setup())
-	require.NoError(t, e.Run(context.Background(), taskfile.Call{Task: "whereami"}))
+	require.NoError(t, e.Run(context.Background(), taskfile.Call{Task: "foo"}))
+	assert.Contains(t, buff.String(), "foobar")
+}
+
+func TestPromptInSummary(t *testing.T) {
+	const dir = "testdata/prompt"
+	tests := []struct {
+		name      string
+		input     string
+		wantError bool
+	}{
+		{"test short approval", "y\n", false},
+		{"test long approval", "yes\n", false},
+		{"test uppercase approval", "Y\n", false},
+		{"test stops task", "n\n", true},
+		{"test junk value stops task", "foobar\n", true},
+		{"test Enter stops task", "\n", true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var inBuff bytes.Buffer
+			var outBuff bytes.Buffer
+			var errBuff bytes.Buffer
+
+			inBuff.Write([]byte(test.input))
+
+			e := task.Executor{
+				Dir:         dir,
+				Stdin:       &inBuff,
+				Stdout:      &outBuff,
+				Stderr:      &errBuff,
+				AssumesTerm: true,
+			}
+			require.NoError(t, e.Setup())
+
+			err := e.Run(context.Background(), taskfile.Call{Task: "foo"})
+
+			if test.wantError {
+				require.Error(t, err)
+			} else {
+				require.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestPromptVars(t *testing.T) {
+	const dir = "testdata/prompt_vars"
+	tests := []struct {
+		name     string
+		input    string
+		vars     map[string]string
+		noInput  bool
+		expected string
+	}{
+		{"answers and defaults", "\nAnn\n\n", nil, false, "Hello Ann none\n"},
+		{"answers override the defaults", "Hi\nAnn\nsecret\n", nil, false, "Hi Ann secret\n"},
+		{"set variables aren't asked", "Hi\n\n", map[string]string{"NAME": "Bob"}, false, "Hi Bob none\n"},
+		{"--no-input uses the defaults", "", map[string]string{"NAME": "Bob"}, true, "Hello Bob none\n"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var outBuff bytes.Buffer
+			var errBuff bytes.Buffer
+
+			e := task.Executor{
+				Dir:         dir,
+				Stdin:       strings.NewReader(test.input),
+				Stdout:      &outBuff,
+				Stderr:      &errBuff,
+				AssumesTerm: true,
+				NoInput:     test.noInput,
+				Silent:      true,
+			}
+			require.NoError(t, e.Setup())
+
+			call := taskfile.Call{Task: "default", Vars: &taskfile.Vars{}}
+			for k, v := range test.vars {
+				call.Vars.Set(k, taskfile.Var{Static: v})
+			}
+			require.NoError(t, e.Run(context.Background(), call))
+			assert.True(t, strings.HasSuffix(outBuff.String(), test.expected), outBuff.String())
+			if !test.noInput && test.vars == nil {
+				assert.Contains(t, outBuff.String(), "task: Your name?: ")
+				assert.Contains(t, outBuff.String(), "task: Greeting? [Hello]: ")
+				assert.Contains(t, outBuff.String(), "task: Token?: ")
+			}
+		})
+	}
+}
+
+func TestPromptVarsNoInput(t *testing.T) {
+	var buff bytes.Buffer
+	e := task.Executor{
+		Dir:     "testdata/prompt_vars",
+		Stdout:  &buff,
+		Stderr:  &buff,
+		NoInput: true,
+	}
+	require.NoError(t, e.Setup())
+
+	err := e.Run(context.Background(), taskfile.Call{Task: "default"})
+	var promptErr *errors.VarPromptUnavailableError
+	require.ErrorAs(t, err, &promptErr)
+	assert.Equal(t, "NAME", promptErr.VarName)
+	assert.Equal(t, `task: Variable "NAME" has no value or default and can't be asked for, because --no-input is set. Set it with NAME=value`, err.Error())
+}
+
+func TestPromptWithIndirectTask(t *testing.T) {
+	const dir = "testdata/prompt"
+	var inBuff bytes.Buffer
+	var outBuff bytes.Buffer
+	var errBuff bytes.Buffer
+
+	inBuff.Write([]byte("y\n"))
+
+	e := task.Executor{
+		Dir:         dir,
+		Stdin:       &inBuff,
+		Stdout:      &outBuff,
+		Stderr:      &errBuff,
+		AssumesTerm: true,
+	}
+	require.NoError(t, e.Setup())
+
+	err := e.Run(context.Background(), taskfile.Call{Task: "bar"})
+	assert.Contains(t, outBuff.String(), "show-prompt")
+	require.NoError(t, err)
+}
+
+func TestPromptAssumeYes(t *testing.T) {
+	const dir = "testdata/prompt"
+	tests := []struct {
+		name      string
+		assumeYes bool
+	}{
+		{"--yes flag should skip prompt", true},
+		{"task should raise errors.TaskCancelledError", false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var inBuff bytes.Buffer
+			var outBuff bytes.Buffer
+			var errBuff bytes.Buffer
+
+			// always cancel the prompt so we can require.Error
+			inBuff.Write([]byte("\n"))
+
+			e := task.Executor{
+				Dir:       dir,
+				Stdin:     &inBuff,
+				Stdout:    &outBuff,
+				Stderr:    &errBuff,
+				AssumeYes: test.assumeYes,
+			}
+			require.NoError(t, e.Setup())
+
+			err := e.Run(context.Background(), taskfile.Call{Task: "foo"})
+
+			if !test.assumeYes {
+				require.Error(t, err)
+				return
+			}
+		})
+	}
+}
+
+func TestNoLabelInList(t *testing.T) {
+	const dir = "testdata/label_list"
+
+	var buff bytes.Buffer
+	e := task.Executor{
+		Dir:    dir,
+		Stdout: &buff,
+		Stderr: &buff,
+	}
+	require.NoError(t, e.Setup())
+	if _, err := e.ListTasks(task.ListOptions{ListOnlyTasksWithDescriptions: true}); err != nil {
+		t.Error(err)
+	}
+	assert.Contains(t, buff.String(), "foo")
+}
+
+// task -al case 1: listAll list all tasks
+func TestListAllShowsNoDesc(t *testing.T) {
+	const dir = "testdata/list_mixed_desc"
+
+	var buff bytes.Buffer
+	e := task.Executor{
+		Dir:    dir,
+		Stdout: &buff,
+		Stderr: &buff,
+	}
+
+	require.NoError(t, e.Setup())
+
+	var title string
+	if _, err := e.ListTasks(task.ListOptions{ListAllTasks: true}); err != nil {
+		t.Error(err)
+	}
+	for _, title = range []string{
+		"foo",
+		"voo",
+		"doo",
+	} {
+		assert.Contains(t, buff.String(), title)
+	}
+}
+
+// task -al case 2: !listAll list some tasks (only those with desc)
+func TestListCanListDescOnly(t *testing.T) {
+	const dir = "testdata/list_mixed_desc"
+
+	var buff bytes.Buffer
+	e := task.Executor{
+		Dir:    dir,
+		Stdout: &buff,
+		Stderr: &buff,
+	}
+
+	require.NoError(t, e.Setup())
+	if _, err := e.ListTasks(task.ListOptions{ListOnlyTasksWithDescriptions: true}); err != nil {
+		t.Error(err)
+	}
+
+	var title string
+	assert.Contains(t, buff.String(), "foo")
+	for _, title = range []string{
+		"voo",
+		"doo",
+	} {
+		assert.NotContains(t, buff.String(), title)
+	}
+}
+
+func TestListDescTemplate(t *testing.T) {
+	const dir = "testdata/list_desc_template"
+
+	var buff bytes.Buffer
+	e := task.Executor{
+		Dir:    dir,
+		Stdout: &buff,
+		Stderr: &buff,
+	}
+	require.NoError(t, e.Setup())
+
+	_, err := e.ListTasks(task.ListOptions{ListOnlyTasksWithDescriptions: true})
+	require.NoError(t, err)
+	assert.Regexp(t, "^nuv: available subcommands:\n"+
+		"\\* build: +Builds version 1.2.3 for linux darwin\n"+
+		"\\* release: +Releases version 1.2.3\n$", buff.String())
+
+	buff.Reset()
+	e.Summary = true
+	require.NoError(t, e.Run(context.Background(), taskfile.Call{Task: "release"}))
+	assert.Contains(t, buff.String(), "Releases version 1.2.3.\n\nIt builds for linux darwin first.\n")
+}
+
+func TestListAllGroupedByTaskfile(t *testing.T) {
+	const dir = "testdata/list_grouped"
+
+	var buff bytes.Buffer
+	e := task.Executor{
+		Dir:    dir,
+		Stdout: &buff,
+		Stderr: &buff,
+	}
+	require.NoError(t, e.Setup())
+
+	_, err := e.ListTasks(task.ListOptions{ListAllTasks: true})
+	require.NoError(t, err)
+	root := filepathext.SmartJoin(dir, "Taskfile.yml")
+	docs := filepathext.SmartJoin(dir, "docs/Taskfile.yml")
+	assert.Regexp(t, "^nuv: available subcommands:\n"+
+		regexp.QuoteMeta(root)+":\n\\* build: +Build the project\n\\* lint: +\n"+
+		regexp.QuoteMeta(docs)+":\n\\* docs:serve: +Serve the docs\n$", buff.String())
+
+	// Tasks of a single Taskfile and --list aren't grouped
+	buff.Reset()
+	_, err = e.ListTasks(task.ListOptions{ListOnlyTasksWithDescriptions: true})
+	require.NoError(t, err)
+	assert.NotContains(t, buff.String(), "Taskfile.yml:")
+
+	buff.Reset()
+	_, err = e.ListTasks(task.ListOptions{ListAllTasks: true, FormatTaskListAsJSON: true})
+	require.NoError(t, err)
+	var output editors.Taskfile
+	require.NoError(t, json.Unmarshal(buff.Bytes(), &output))
+	require.Len(t, output.Tasks, 3)
+	assert.Equal(t, "docs:serve", output.Tasks[2].Name)
+	assert.Equal(t, 4, output.Tasks[2].Location.Line)
+	assert.Equal(t, docs, filepathext.TryAbsToRel(output.Tasks[2].Location.Taskfile))
+}
+
+func TestListTaskVars(t *testing.T) {
+	const dir = "testdata/list_vars"
+
+	var buff bytes.Buffer
+	e := task.Executor{
+		Dir:    dir,
+		Stdout: &buff,
+		Stderr: &buff,
+	}
+	require.NoError(t, e.Setup())
+
+	require.NoError(t, e.ListTaskVars(
+		taskfile.Call{Task: "build"},
+		taskfile.Call{Task: "deploy"},
+		taskfile.Call{Task: "clean"},
+	))
+	assert.Equal(t, "TARGET\nVERSION\nENV\nREGION\n", buff.String())
+
+	err := e.ListTaskVars(taskfile.Call{Task: "missing"})
+	assert.ErrorContains(t, err, `task: Task "missing" does not exist`)
+}
+
+func TestStatusExplain(t *testing.T) {
+	const dir = "testdata/status_explain"
+	t.Cleanup(func() {
+		_ = os.RemoveAll(filepathext.SmartJoin(dir, ".task"))
+		_ = os.Remove(filepathext.SmartJoin(dir, "src.txt"))
+		_ = os.Remove(filepathext.SmartJoin(dir, "out.txt"))
+	})
+	_ = os.RemoveAll(filepathext.SmartJoin(dir, ".task"))
+	_ = os.Remove(filepathext.SmartJoin(dir, "out.txt"))
+	require.NoError(t, os.WriteFile(filepathext.SmartJoin(dir, "src.txt"), []byte("a"), 0o644))
+
+	var buff bytes.Buffer
+	e := task.Executor{
+		Dir:     dir,
+		TempDir: filepathext.SmartJoin(dir, ".task"),
+		Stdout:  &buff,
+		Stderr:  &buff,
+		Silent:  true,
+	}
+	require.NoError(t, e.Setup())
+
+	statuses, err := e.ExplainStatus(context.Background(), taskfile.Call{Task: "build"})
+	require.NoError(t, err)
+	assert.Equal(t, []task.TaskStatus{{
+		Task: "build",
+		Reasons: []task.StaleReason{
+			{Kind: fingerprint.StaleNeverRun, Message: "there's no checksum of the sources, so the task never ran"},
+			{Kind: fingerprint.StaleGeneratesMissing, Message: "1 generated file(s) are missing", Files: []string{"out.txt"}},
+		},
+	}}, statuses)
+
+	require.NoError(t, e.Run(context.Background(), taskfile.Call{Task: "build"}))
+	statuses, err = e.ExplainStatus(context.Background(), taskfile.Call{Task: "build"})
+	require.NoError(t, err)
+	assert.Equal(t, []task.TaskStatus{{Task: "build", UpToDate: true}}, statuses)
+
+	require.NoError(t, os.WriteFile(filepathext.SmartJoin(dir, "src.txt"), []byte("b"), 0o644))
+	statuses, err = e.ExplainStatus(context.Background(),
+		taskfile.Call{Task: "build"},
+		taskfile.Call{Task: "check"},
+		taskfile.Call{Task: "always"},
+	)
+	require.NoError(t, err)
+	require.Len(t, statuses, 3)
+	assert.Equal(t, []task.StaleReason{
+		{Kind: fingerprint.StaleSourcesChanged, Message: "1 source file(s) changed", Files: []string{"src.txt"}},
+	}, statuses[0].Reasons)
+	require.Len(t, statuses[1].Reasons, 1)
+	assert.Equal(t, fingerprint.StaleStatusFailed, statuses[1].Reasons[0].Kind)
+	assert.Equal(t, "test -f missing.txt", statuses[1].Reasons[0].Command)
+	assert.Equal(t, fingerprint.StaleAlways, statuses[2].Reasons[0].Kind)
+
+	// Explaining doesn't update the checksum
+	buff.Reset()
+	e.Verbose = true
+	err = e.Status(context.Background(), taskfile.Call{Task: "build"})
+	assert.EqualError(t, err, `task: Task "build" is not up-to-date`)
+	assert.Contains(t, buff.String(), `task: Task "build" is not up-to-date, because 1 source file(s) changed: src.txt`)
+
+	buff.Reset()
+	err = e.StatusJSON(context.Background(), taskfile.Call{Task: "build"})
+	assert.EqualError(t, err, `task: Task "build" is not up-to-date`)
+	var decoded []task.TaskStatus
+	require.NoError(t, json.Unmarshal(buff.Bytes(), &decoded))
+	assert.Equal(t, statuses[:1], decoded)
+}
+
+func TestStatusVariables(t *testing.T) {
+	const dir = "testdata/status_vars"
+
+	_ = os.RemoveAll(filepathext.SmartJoin(dir, ".task"))
+	_ = os.Remove(filepathext.SmartJoin(dir, "generated.txt"))
+
+	var buff bytes.Buffer
+	e := task.Executor{
+		Dir:     dir,
+		TempDir: filepathext.SmartJoin(dir, ".task"),
+		Stdout:  &buff,
+		Stderr:  &buff,
+		Silent:  false,
+		Verbose: true,
+	}
+	require.NoError(t, e.Setup())
+	require.NoError(t, e.Run(context.Background(), taskfile.Call{Task: "build"}))
+
+	assert.Contains(t, buff.String(), "24b9ef0391754273ed6f47911cdb2b3e")
+
+	inf, err := os.Stat(filepathext.SmartJoin(dir, "source.txt"))
+	require.NoError(t, err)
+	ts := fmt.Sprintf("%d", inf.ModTime().Unix())
+	tf := inf.ModTime().String()
+
+	assert.Contains(t, buff.String(), ts)
+	assert.Contains(t, buff.String(), tf)
+}
+
+func TestInit(t *testing.T) {
+	const dir = "testdata/init"
+	file := filepathext.SmartJoin(dir, "Taskfile.yml")
+
+	_ = os.Remove(file)
+	if _, err := os.Stat(file); err == nil {
+		t.Errorf("Taskfile.yml should not exist")
+	}
+
+	if err := task.InitTaskfile(io.Discard, dir); err != nil {
+		t.Error(err)
+	}
+
+	if _, err := os.Stat(file); err != nil {
+		t.Errorf("Taskfile.yml should exist")
+	}
+	_ = os.Remove(file)
+}
+
+func TestInitTemplates(t *testing.T) {
+	var buff bytes.Buffer
+	require.NoError(t, task.ListInitTemplates(&buff))
+	for _, name := range []string{"default", "go", "node", "docker"} {
+		assert.Contains(t, buff.String(), "* "+name+":")
+	}
+
+	tests := []struct {
+		template string
+		tasks    []string
+	}{
+		{"go", []string{"default", "build", "test", "lint", "clean"}},
+		{"node", []string{"default", "install", "build", "test", "lint"}},
+		{"docker", []string{"default", "build", "run", "lint", "push"}},
+	}
+	for _, test := range tests {
+		t.Run(test.template, func(t *testing.T) {
+			dir := t.TempDir()
+			require.NoError(t, task.InitTaskfile(io.Discard, dir, task.WithInitTemplate(test.template)))
+
+			e := task.Executor{
+				Dir:    dir,
+				Stdout: io.Discard,
+				Stderr: io.Discard,
+			}
+			require.NoError(t, e.Setup())
+			assert.Equal(t, test.tasks, e.Taskfile.Tasks.Keys())
+		})
+	}
+
+	t.Run("force", func(t *testing.T) {
+		dir := t.TempDir()
+		file := filepathext.SmartJoin(dir, "Taskfile.yml")
+		require.NoError(t, task.InitTaskfile(io.Discard, dir))
+
+		err := task.InitTaskfile(io.Discard, dir, task.WithInitTemplate("go"))
+		assert.ErrorAs(t, err, &errors.TaskfileAlreadyExistsError{})
+
+		require.NoError(t, task.InitTaskfile(io.Discard, dir, task.WithInitTemplate("go"), task.WithInitForce(true)))
+		b, err := os.ReadFile(file)
+		require.NoError(t, err)
+		assert.Contains(t, string(b), "go test ./...")
+	})
+
+	t.Run("unknown", func(t *testing.T) {
+		dir := t.TempDir()
+		err := task.InitTaskfile(io.Discard, dir, task.WithInitTemplate("cobol"))
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), `task: Unknown template "cobol"`)
+		assert.NoFileExists(t, filepathext.SmartJoin(dir, "Taskfile.yml"))
+	})
+}
+
+func TestZeroConfig(t *testing.T) {
+	experiments.ZeroConfig = true
+	t.Cleanup(func() { experiments.ZeroConfig = false })
+
+	tests := []struct {
+		name     string
+		files    map[string]string
+		task     string
+		expected string
+	}{
+		{"go", map[string]string{"go.mod": "module example.com/foo\n"}, "test", "go test ./..."},
+		{"rust", map[string]string{"Cargo.toml": "[package]\n"}, "build", "cargo build"},
+		{"npm", map[string]string{"package.json": `{"scripts": {"build": "tsc"}}`}, "build", "npm run build"},
+		{"yarn", map[string]string{"package.json": `{"scripts": {"lint": "eslint ."}}`, "yarn.lock": ""}, "lint", "yarn run lint"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			dir := t.TempDir()
+			for name, content := range test.files {
+				require.NoError(t, os.WriteFile(filepathext.SmartJoin(dir, name), []byte(content), 0o644))
+			}
+
+			var buff bytes.Buffer
+			e := task.Executor{
+				Dir:    dir,
+				Stdout: &buff,
+				Stderr: &buff,
+				Dry:    true,
+			}
+			require.NoError(t, e.Setup())
+			assert.Contains(t, buff.String(), "task: No Taskfile found, using the usual tasks of a")
+			require.NoError(t, e.Run(context.Background(), taskfile.Call{Task: test.task}))
+			assert.Contains(t, buff.String(), fmt.Sprintf("task: [%s] %s\n", test.task, test.expected))
+
+			require.NoError(t, task.InitTaskfile(io.Discard, dir))
+			b, err := os.ReadFile(filepathext.SmartJoin(dir, "Taskfile.yml"))
+			require.NoError(t, err)
+			assert.Contains(t, string(b), "      - "+test.expected+"\n")
+		})
+	}
+
+	t.Run("unknown project", func(t *testing.T) {
+		e := task.Executor{
+			Dir:    t.TempDir(),
+			Stdout: io.Discard,
+			Stderr: io.Discard,
+		}
+		var notFoundErr errors.TaskfileNotFoundError
+		require.ErrorAs(t, e.Setup(), &notFoundErr)
+	})
+}
+
+func TestCyclicDep(t *testing.T) {
+	const dir = "testdata/cyclic"
+
+	e := task.Executor{
+		Dir:    dir,
+		Stdout: io.Discard,
+		Stderr: io.Discard,
+	}
+	err := e.Setup()
+	var cycleErr *errors.TaskDependencyCycleError
+	require.ErrorAs(t, err, &cycleErr)
+	assert.Equal(t, []string{"task-1", "task-2", "task-1"}, cycleErr.Cycle)
+	assert.Equal(t, `task: Task "task-1" depends on itself: task-1 -> task-2 -> task-1`, err.Error())
+	assert.Equal(t, errors.CodeTaskCalledTooManyTimes, cycleErr.Code())
+
+	// The calls from commands are only stopped when running
+	e = task.Executor{
+		Dir:    filepathext.SmartJoin(dir, "cmds"),
+		Stdout: io.Discard,
+		Stderr: io.Discard,
+	}
+	require.NoError(t, e.Setup())
+	err = e.Run(context.Background(), taskfile.Call{Task: "task-1"})
+	var tooManyErr *errors.TaskCalledTooManyTimesError
+	require.ErrorAs(t, err, &tooManyErr)
+	assert.Equal(t, task.MaximumTaskCall, tooManyErr.MaximumTaskCall)
+}
+
+func TestErrorTypes(t *testing.T) {
+	const dir = "testdata/errors"
+
+	e := task.Executor{
+		Dir:    dir,
+		Stdout: io.Discard,
+		Stderr: io.Discard,
+	}
+	require.NoError(t, e.Setup())
+
+	err := e.Run(context.Background(), taskfile.Call{Task: "precondition", Direct: true})
+	var preconditionErr *errors.TaskPreconditionError
+	require.ErrorAs(t, err, &preconditionErr)
+	assert.Equal(t, "precondition", preconditionErr.TaskName)
+	assert.Equal(t, "one is not zero", preconditionErr.Msg)
+	assert.ErrorIs(t, err, task.ErrPreconditionFailed)
+	assert.EqualError(t, err, "task: precondition not met: one is not zero")
+
+	err = e.Run(context.Background(), taskfile.Call{Task: "timeout", Direct: true})
+	var timeoutErr *errors.TaskTimeoutError
+	require.ErrorAs(t, err, &timeoutErr)
+	assert.Equal(t, "timeout", timeoutErr.TaskName)
+	assert.Equal(t, 50*time.Millisecond, timeoutErr.Timeout)
+	assert.EqualError(t, err, `task: Task "timeout" timed out after 50ms`)
+	// Without a task, it's the whole run that timed out
+	assert.EqualError(t, &errors.TaskTimeoutError{Timeout: time.Second, Err: context.DeadlineExceeded}, "task: Timed out after 1s: context deadline exceeded")
+
+	err = e.Run(context.Background(), taskfile.Call{Task: "invalid-timeout", Direct: true})
+	assert.ErrorContains(t, err, `task: Task "invalid-timeout" has an invalid timeout "soon"`)
+
+	err = e.Run(context.Background(), taskfile.Call{Task: "exit", Direct: true})
+	var runErr *errors.TaskRunError
+	require.ErrorAs(t, err, &runErr)
+	assert.Equal(t, 3, runErr.TaskExitCode())
+	var taskErr errors.TaskError
+	require.ErrorAs(t, err, &taskErr)
+	assert.Equal(t, errors.CodeTaskRunError, taskErr.Code())
+
+	err = e.Run(context.Background(), taskfile.Call{Task: "missing"})
+	var notFoundErr *errors.TaskNotFoundError
+	require.ErrorAs(t, err, &notFoundErr)
+	assert.Equal(t, "missing", notFoundErr.TaskName)
+}
+
+func TestNewExecutor(t *testing.T) {
+	var buff bytes.Buffer
+	e := task.NewExecutor(
+		task.WithDir("testdata/executor"),
+		task.WithStdout(&buff),
+		task.WithStderr(&buff),
+		task.WithSilent(true),
+	)
+
+	// Setup is called on the first run, and the Executor can be reused
+	require.NoError(t, e.Run(context.Background(), taskfile.Call{Task: "default"}))
+	require.NoError(t, e.Run(context.Background(), taskfile.Call{Task: "default"}))
+	assert.Equal(t, "hello\nhello\n", buff.String())
+
+	// Nothing of a run carries over to the next one
+	buff.Reset()
+	require.NoError(t, e.Run(context.Background(), taskfile.Call{Task: "once"}, taskfile.Call{Task: "once"}))
+	require.NoError(t, e.Run(context.Background(), taskfile.Call{Task: "once"}))
+	assert.Equal(t, "once\nonce\n", buff.String(), "the tasks that run once run once per run")
+
+	e = task.NewExecutor(
+		task.WithDir("testdata/executor"),
+		task.WithConcurrency(-1),
+	)
+	err := e.Run(context.Background(), taskfile.Call{Task: "default"})
+	assert.EqualError(t, err, "task: The concurrency can't be negative, got -1")
+}
+
+func TestCompiledTask(t *testing.T) {
+	e := task.NewExecutor(task.WithDir("testdata/compiled_task"))
+
+	// The Taskfile is read on first use, like with Run
+	compiled, err := e.CompiledTask(context.Background(), taskfile.Call{Task: "greet"})
+	require.NoError(t, err)
+	var cmds []string
+	for _, cmd := range compiled.Cmds {
+		cmds = append(cmds, cmd.Cmd)
+	}
+	assert.Equal(t, []string{`echo "Hello, World!"`, "echo a", "echo b"}, cmds)
+	assert.Equal(t, "World", compiled.Vars.Get("NAME").Static)
+	assert.Equal(t, "root", compiled.Env.Get("ROOT_ENV").Static)
+	assert.Equal(t, "World", compiled.Env.Get("TASK_ENV").Static)
+
+	compiled, err = e.CompiledTask(context.Background(), taskfile.Call{Task: "included:greet"})
+	require.NoError(t, err)
+	require.Len(t, compiled.Cmds, 1)
+	assert.Equal(t, `echo "Hi, World!"`, compiled.Cmds[0].Cmd)
+	assert.Equal(t, "included", filepath.Base(compiled.Dir))
+}
+
+func TestSetupWithContext(t *testing.T) {
+	const dir = "testdata/setup_context"
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	e := task.Executor{
+		Dir:    dir,
+		Stdout: io.Discard,
+		Stderr: io.Discard,
+	}
+	assert.ErrorIs(t, e.SetupWithContext(ctx), context.Canceled)
+
+	// The commands of the dynamic variables are cancelled too
+	var buff bytes.Buffer
+	e = task.Executor{
+		Dir:    dir,
+		Stdout: &buff,
+		Stderr: &buff,
+		Silent: true,
+	}
+	require.NoError(t, e.SetupWithContext(context.Background()))
+	assert.ErrorIs(t, e.Run(ctx, taskfile.Call{Task: "default"}), context.Canceled)
+	require.NoError(t, e.Run(context.Background(), taskfile.Call{Task: "default"}))
+	assert.Equal(t, "hello\n", buff.String())
+}
+
+func TestTaskVersion(t *testing.T) {
+	tests := []struct {
+		Dir     string
+		Version *semver.Version
+	}{
+		{"testdata/version/v2", semver.MustParse("2")},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Dir, func(t *testing.T) {
+			e := task.Executor{
+				Dir:    test.Dir,
+				Stdout: io.Discard,
+				Stderr: io.Discard,
+			}
+			require.NoError(t, e.Setup())
+			assert.Equal(t, test.Version, e.Taskfile.Version)
+			assert.Equal(t, 2, e.Taskfile.Tasks.Len())
+		})
+	}
+}
+
+func TestTaskIgnoreErrors(t *testing.T) {
+	const dir = "testdata/ignore_errors"
+
+	e := task.Executor{
+		Dir:    dir,
+		Stdout: io.Discard,
+		Stderr: io.Discard,
+	}
+	require.NoError(t, e.Setup())
+
+	require.NoError(t, e.Run(context.Background(), taskfile.Call{Task: "task-should-pass"}))
+	require.Error(t, e.Run(context.Background(), taskfile.Call{Task: "task-should-fail"}))
+	require.NoError(t, e.Run(context.Background(), taskfile.Call{Task: "cmd-should-pass"}))
+	require.Error(t, e.Run(context.Background(), taskfile.Call{Task: "cmd-should-fail"}))
+}
+
+func TestDepIgnoreErrors(t *testing.T) {
+	const dir = "testdata/ignore_errors"
+
+	var buff bytes.Buffer
+	e := task.Executor{
+		Dir:    dir,
+		Stdout: &buff,
+		Stderr: &buff,
+		Silent: true,
+	}
+	require.NoError(t, e.Setup())
+
+	require.NoError(t, e.Run(context.Background(), taskfile.Call{Task: "dep-should-pass"}))
+	assert.Contains(t, buff.String(), "passing\n")
+	assert.Contains(t, buff.String(), "after deps\n")
+	assert.Contains(t, buff.String(), "task: Ignored 1 error(s):\ntask: - \"failing\": exit status 1\n")
+	assert.Equal(t, []*task.IgnoredError{{Task: "failing", Error: "exit status 1"}}, e.LastRunReport().IgnoredErrors)
+
+	buff.Reset()
+	require.Error(t, e.Run(context.Background(), taskfile.Call{Task: "dep-should-fail"}))
+	assert.NotContains(t, buff.String(), "after deps")
+	assert.Empty(t, e.LastRunReport().IgnoredErrors)
+
+	buff.Reset()
+	require.NoError(t, e.Run(context.Background(), taskfile.Call{Task: "call-should-pass"}))
+	assert.Contains(t, buff.String(), "after call\n")
+	assert.Len(t, e.LastRunReport().IgnoredErrors, 1)
+}
+
+func TestNoFailFast(t *testing.T) {
+	const dir = "testdata/fail_fast"
+
+	calls := []taskfile.Call{
+		{Task: "fail-1", Direct: true},
+		{Task: "ok", Direct: true},
+		{Task: "fail-2", Direct: true},
+	}
+
+	var buff bytes.Buffer
+	e := task.Executor{
+		Dir:    dir,
+		Stdout: &buff,
+		Stderr: &buff,
+		Silent: true,
+	}
+	require.NoError(t, e.Setup())
+	err := e.Run(context.Background(), calls...)
+	var runErr *errors.TaskRunError
+	require.ErrorAs(t, err, &runErr)
+	assert.Equal(t, "fail-1", runErr.TaskName)
+	assert.Empty(t, buff.String())
+
+	for _, parallel := range []bool{false, true} {
+		buff.Reset()
+		e := task.Executor{
+			Dir:        dir,
+			Stdout:     &buff,
+			Stderr:     &buff,
+			Silent:     true,
+			Parallel:   parallel,
+			NoFailFast: true,
+		}
+		require.NoError(t, e.Setup())
+		err := e.Run(context.Background(), calls...)
+		var failedErr *errors.TasksFailedError
+		require.ErrorAs(t, err, &failedErr)
+		assert.Equal(t, "ok\n", buff.String())
+		assert.EqualError(t, err, "task: 2 tasks failed:\n"+
+			`task: Failed to run task "fail-1": exit status 1`+"\n"+
+			`task: Failed to run task "fail-2": exit status 2`)
+		require.ErrorAs(t, err, &runErr)
+		assert.Equal(t, 1, runErr.TaskExitCode())
+	}
+}
+
+func TestRunInDirs(t *testing.T) {
+	const dir = "testdata/dirs"
+
+	globals := &taskfile.Vars{}
+	globals.Set("NAME", taskfile.Var{Static: "x"})
+
+	var buff bytes.Buffer
+	e := task.Executor{
+		Dir:         dir,
+		Stdout:      &buff,
+		Stderr:      &buff,
+		Silent:      true,
+		Concurrency: 1,
+	}
+	err := e.RunInDirs(context.Background(), []string{"services/*"}, globals, taskfile.Call{Task: "default", Direct: true})
+	require.NoError(t, err)
+	assert.Equal(t, "[services/a] a x\n[services/b] b x\n", buff.String())
+
+	buff.Reset()
+	e.NoFailFast = true
+	err = e.RunInDirs(context.Background(), []string{"services/*"}, nil, taskfile.Call{Task: "fail", Direct: true})
+	assert.EqualError(t, err, `services/a: task: Failed to run task "fail": exit status 1`)
+	assert.Equal(t, "[services/b] b ok\n", buff.String())
+
+	err = e.RunInDirs(context.Background(), []string{"services/docs", "missing/*"}, nil, taskfile.Call{Task: "default", Direct: true})
+	assert.EqualError(t, err, "task: No directory matching services/docs, missing/* has a Taskfile")
+}
+
+func TestRunInDirsOptions(t *testing.T) {
+	// Every option applies to the directories, like Strict
+	e := task.Executor{
+		Dir:    "testdata",
+		Stdout: io.Discard,
+		Stderr: io.Discard,
+		Strict: true,
+	}
+	err := e.RunInDirs(context.Background(), []string{"deprecations"}, nil, taskfile.Call{Task: "default", Direct: true})
+	var deprecatedErr *errors.TaskfileDeprecatedError
+	assert.ErrorAs(t, err, &deprecatedErr)
+}
+
+func TestExpand(t *testing.T) {
+	const dir = "testdata/expand"
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		t.Errorf("Couldn't get $HOME: %v", err)
+	}
+	var buff bytes.Buffer
+
+	e := task.Executor{
+		Dir:    dir,
+		Stdout: &buff,
+		Stderr: &buff,
+	}
+	require.NoError(t, e.Setup())
+	require.NoError(t, e.Run(context.Background(), taskfile.Call{Task: "pwd"}))
+	assert.Equal(t, home, strings.TrimSpace(buff.String()))
+}
+
+func TestDry(t *testing.T) {
+	const dir = "testdata/dry"
+
+	file := filepathext.SmartJoin(dir, "file.txt")
+	_ = os.Remove(file)
+
+	var buff bytes.Buffer
+
+	e := task.Executor{
+		Dir:    dir,
+		Stdout: &buff,
+		Stderr: &buff,
+		Dry:    true,
+	}
+	require.NoError(t, e.Setup())
+	require.NoError(t, e.Run(context.Background(), taskfile.Call{Task: "build"}))
+
+	assert.Equal(t, "task: [build] touch file.txt", strings.TrimSpace(buff.String()))
+	if _, err := os.Stat(file); err == nil {
+		t.Errorf("File should not exist %s", file)
+	}
+}
+
+// TestDryChecksum tests if the checksum file is not being written to disk
+// if the dry mode is enabled.
+func TestDryChecksum(t *testing.T) {
+	const dir = "testdata/dry_checksum"
+
+	checksumFile := filepathext.SmartJoin(dir, ".task/checksum/default")
+	_ = os.Remove(checksumFile)
+
+	e := task.Executor{
+		Dir:     dir,
+		TempDir: filepathext.SmartJoin(dir, ".task"),
+		Stdout:  io.Discard,
+		Stderr:  io.Discard,
+		Dry:     true,
+	}
+	require.NoError(t, e.Setup())
+	require.NoError(t, e.Run(context.Background(), taskfile.Call{Task: "default"}))
+
+	_, err := os.Stat(checksumFile)
+	require.Error(t, err, "checksum file should not exist")
+
+	e.Dry = false
+	require.NoError(t, e.Run(context.Background(), taskfile.Call{Task: "default"}))
+	_, err = os.Stat(checksumFile)
+	require.NoError(t, err, "checksum file should exist")
+}
+
+func TestIncludes(t *testing.T) {
+	tt := fileContentTest{
+		Dir:       "testdata/includes",
+		Target:    "default",
+		TrimSpace: true,
+		Files: map[string]string{
+			"main.txt":                                  "main",
+			"included_directory.txt":                    "included_directory",
+			"included_directory_without_dir.txt":        "included_directory_without_dir",
+			"included_taskfile_without_dir.txt":         "included_taskfile_without_dir",
+			"./module2/included_directory_with_dir.txt": "included_directory_with_dir",
+			"./module2/included_taskfile_with_dir.txt":  "included_taskfile_with_dir",
+			"os_include.txt":                            "os",
+		},
+	}
+	tt.Run(t)
+}
+
+func TestIncludesMultiLevel(t *testing.T) {
+	tt := fileContentTest{
+		Dir:       "testdata/includes_multi_level",
+		Target:    "default",
+		TrimSpace: true,
+		Files: map[string]string{
+			"called_one.txt":   "one",
+			"called_two.txt":   "two",
+			"called_three.txt": "three",
+		},
+	}
+	tt.Run(t)
+}
+
+func TestIncludeCycle(t *testing.T) {
+	const dir = "testdata/includes_cycle"
+
+	var buff bytes.Buffer
+	e := task.Executor{
+		Dir:    dir,
+		Stdout: &buff,
+		Stderr: &buff,
+		Silent: true,
+	}
+
+	err := e.Setup()
+	require.Error(t, err)
+	assert.Equal(t, "task: include cycle detected: "+
+		"testdata/includes_cycle/Taskfile.yml -> "+
+		"testdata/includes_cycle/one/Taskfile.yml -> "+
+		"testdata/includes_cycle/one/two/Taskfile.yml -> "+
+		"testdata/includes_cycle/Taskfile.yml", filepath.ToSlash(err.Error()))
+}
+
+func TestIncorrectVersionIncludes(t *testing.T) {
+	const dir = "testdata/incorrect_includes"
+	expectedError := "task: Import with additional parameters is only available starting on Taskfile version v3"
+
+	var buff bytes.Buffer
+	e := task.Executor{
+		Dir:    dir,
+		Stdout: &buff,
+		Stderr: &buff,
+		Silent: true,
+	}
+
+	assert.EqualError(t, e.Setup(), expectedError)
+}
+
+func TestIncludesIncorrect(t *testing.T) {
+	const dir = "testdata/includes_incorrect"
+
+	var buff bytes.Buffer
+	e := task.Executor{
+		Dir:    dir,
+		Stdout: &buff,
+		Stderr: &buff,
+		Silent: true,
+	}
+
+	err := e.Setup()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "task: Failed to parse testdata/includes_incorrect/incomplete.yml:")
+}
+
+func TestIncludesEmptyMain(t *testing.T) {
+	tt := fileContentTest{
+		Dir:       "testdata/includes_empty",
+		Target:    "included:default",
+		TrimSpace: true,
+		Files: map[string]string{
+			"file.txt": "default",
+		},
+	}
+	tt.Run(t)
+}
+
+func TestIncludesDependencies(t *testing.T) {
+	tt := fileContentTest{
+		Dir:       "testdata/includes_deps",
+		Target:    "default",
+		TrimSpace: true,
+		Files: map[string]string{
+			"default.txt":     "default",
+			"called_dep.txt":  "called_dep",
+			"called_task.txt": "called_task",
+		},
+	}
+	tt.Run(t)
+}
+
+func TestIncludesCallingRoot(t *testing.T) {
+	tt := fileContentTest{
+		Dir:       "testdata/includes_call_root_task",
+		Target:    "included:call-root",
+		TrimSpace: true,
+		Files: map[string]string{
+			"root_task.txt": "root task",
+		},
+	}
+	tt.Run(t)
+}
+
+func TestIncludesOptional(t *testing.T) {
+	tt := fileContentTest{
+		Dir:       "testdata/includes_optional",
+		Target:    "default",
+		TrimSpace: true,
+		Files: map[string]string{
+			"called_dep.txt": "called_dep",
+		},
+	}
+	tt.Run(t)
+}
+
+func TestIncludesOptionalImplicitFalse(t *testing.T) {
+	const dir = "testdata/includes_optional_implicit_false"
+	wd, _ := os.Getwd()
+
+	message := "stat %s/%s/TaskfileOptional.yml: no such file or directory"
+	expected := fmt.Sprintf(message, wd, dir)
+
+	e := task.Executor{
+		Dir:    dir,
+		Stdout: io.Discard,
+		Stderr: io.Discard,
+	}
+
+	err := e.Setup()
+	require.Error(t, err)
+	assert.Equal(t, expected, err.Error())
+}
+
+func TestIncludesOptionalExplicitFalse(t *testing.T) {
+	const dir = "testdata/includes_optional_explicit_false"
+	wd, _ := os.Getwd()
+
+	message := "stat %s/%s/TaskfileOptional.yml: no such file or directory"
+	expected := fmt.Sprintf(message, wd, dir)
+
+	e := task.Executor{
+		Dir:    dir,
+		Stdout: io.Discard,
+		Stderr: io.Discard,
+	}
+
+	err := e.Setup()
+	require.Error(t, err)
+	assert.Equal(t, expected, err.Error())
+}
+
+func TestIncludesIf(t *testing.T) {
+	const dir = "testdata/includes_if"
+	var buff bytes.Buffer
+	e := task.Executor{
+		Dir:    dir,
+		Stdout: &buff,
+		Stderr: &buff,
+		Silent: true,
+	}
+	require.NoError(t, e.Setup())
+
+	require.NoError(t, e.Run(context.Background(), taskfile.Call{Task: "enabled:default"}))
+	assert.Equal(t, "enabled\n", buff.String())
+	assert.Error(t, e.Run(context.Background(), taskfile.Call{Task: "disabled:default"}))
+	assert.Error(t, e.Run(context.Background(), taskfile.Call{Task: "plan9:default"}))
+
+	e = task.Executor{
+		Dir:    filepathext.SmartJoin(dir, "invalid"),
+		Stdout: io.Discard,
+		Stderr: io.Discard,
+	}
+	err := e.Setup()
+	require.Error(t, err)
+	assert.Equal(t, `task: The "if" of the include "included" must be true or false, got "yes please"`, err.Error())
+}
+
+func TestIncludesFromCustomTaskfile(t *testing.T) {
+	tt := fileContentTest{
+		Dir:        "testdata/includes_yaml",
+		Entrypoint: "Custom.ext",
+		Target:     "default",
+		TrimSpace:  true,
+		Files: map[string]string{
+			"main.txt":                         "main",
+			"included_with_yaml_extension.txt": "included_with_yaml_extension",
+			"included_with_custom_file.txt":    "included_with_custom_file",
+		},
+	}
+	tt.Run(t)
+}
+
+func TestIncludesRelativePath(t *testing.T) {
+	const dir = "testdata/includes_rel_path"
+
+	var buff bytes.Buffer
+	e := task.Executor{
+		Dir:    dir,
+		Stdout: &buff,
+		Stderr: &buff,
+	}
+
+	require.NoError(t, e.Setup())
+
+	require.NoError(t, e.Run(context.Background(), taskfile.Call{Task: "common:pwd"}))
+	assert.Contains(t, buff.String(), "testdata/includes_rel_path/common")
+
+	buff.Reset()
+	require.NoError(t, e.Run(context.Background(), taskfile.Call{Task: "included:common:pwd"}))
+	assert.Contains(t, buff.String(), "testdata/includes_rel_path/common")
+}
+
+func TestIncludesInternal(t *testing.T) {
+	const dir = "testdata/internal_task"
+	tests := []struct {
+		name           string
+		task           string
+		expectedErr    bool
+		expectedOutput string
+	}{
+		{"included internal task via task", "task-1", false, "Hello, World!\n"},
+		{"included internal task via dep", "task-2", false, "Hello, World!\n"},
+		{"included internal direct", "included:task-3", true, "task: No tasks with description available. Try --list-all to list all tasks\n"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var buff bytes.Buffer
+			e := task.Executor{
+				Dir:    dir,
+				Stdout: &buff,
+				Stderr: &buff,
+				Silent: true,
+			}
+			require.NoError(t, e.Setup())
+
+			err := e.Run(context.Background(), taskfile.Call{Task: test.task})
+			if test.expectedErr {
+				require.Error(t, err)
+			} else {
+				require.NoError(t, err)
+			}
+			assert.Equal(t, test.expectedOutput, buff.String())
+		})
+	}
+}
+
+func TestIncludesInterpolation(t *testing.T) {
+	const dir = "testdata/includes_interpolation"
+	tests := []struct {
+		name           string
+		task           string
+		expectedErr    bool
+		expectedOutput string
+	}{
+		{"include", "include", false, "includes_interpolation\n"},
+		{"include with dir", "include-with-dir", false, "included\n"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var buff bytes.Buffer
+			e := task.Executor{
+				Dir:    dir,
+				Stdout: &buff,
+				Stderr: &buff,
+				Silent: true,
+			}
+			require.NoError(t, e.Setup())
+
+			err := e.Run(context.Background(), taskfile.Call{Task: test.task})
+			if test.expectedErr {
+				require.Error(t, err)
+			} else {
+				require.NoError(t, err)
+			}
+			assert.Equal(t, test.expectedOutput, buff.String())
+		})
+	}
+}
+
+func TestInternalTask(t *testing.T) {
+	const dir = "testdata/internal_task"
+	tests := []struct {
+		name           string
+		task           string
+		expectedErr    bool
+		expectedOutput string
+	}{
+		{"internal task via task", "task-1", false, "Hello, World!\n"},
+		{"internal task via dep", "task-2", false, "Hello, World!\n"},
+		{"internal direct", "task-3", true, ""},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var buff bytes.Buffer
+			e := task.Executor{
+				Dir:    dir,
+				Stdout: &buff,
+				Stderr: &buff,
+				Silent: true,
+			}
+			require.NoError(t, e.Setup())
+
+			err := e.Run(context.Background(), taskfile.Call{Task: test.task})
+			if test.expectedErr {
+				var taskInternalErr *errors.TaskInternalError
+				require.ErrorAs(t, err, &taskInternalErr)
+			} else {
+				require.NoError(t, err)
+			}
+			assert.Equal(t, test.expectedOutput, buff.String())
+		})
+	}
+}
+
+func TestExtends(t *testing.T) {
+	const dir = "testdata/extends"
+	tests := []struct {
+		task           string
+		expectedOutput string
+	}{
+		{"build-api", "go build -trimpath -o api (0)\n"},
+		{"build-cli", "go build -trimpath -o cli (1)\n"},
+		{"build-static", "static app\n"},
+		{"lint", "golangci-lint run\n"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.task, func(t *testing.T) {
+			var buff bytes.Buffer
+			e := task.Executor{
+				Dir:    dir,
+				Stdout: &buff,
+				Stderr: &buff,
+				Silent: true,
+			}
+			require.NoError(t, e.Setup())
+			require.NoError(t, e.Run(context.Background(), taskfile.Call{Task: test.task}))
+			assert.Equal(t, test.expectedOutput, buff.String())
+		})
+	}
+
+	// Only the fields the task leaves unset are inherited
+	e := task.Executor{Dir: dir}
+	require.NoError(t, e.Setup())
+	api, err := e.GetTask(taskfile.Call{Task: "build-api"})
+	require.NoError(t, err)
+	assert.Equal(t, "Builds a Go binary", api.Desc)
+	assert.False(t, api.Internal)
+	cli, err := e.GetTask(taskfile.Call{Task: "build-cli"})
+	require.NoError(t, err)
+	assert.Equal(t, "Builds the CLI", cli.Desc)
+
+	e = task.Executor{Dir: "testdata/extends_cycle"}
+	assert.EqualError(t, e.Setup(), `task: Task "a" extends itself: a -> b -> a`)
+}
+
+func TestIncludesShadowedDefault(t *testing.T) {
+	tt := fileContentTest{
+		Dir:       "testdata/includes_shadowed_default",
+		Target:    "included",
+		TrimSpace: true,
+		Files: map[string]string{
+			"file.txt": "shadowed",
+		},
+	}
+	tt.Run(t)
+}
+
+func TestIncludesUnshadowedDefault(t *testing.T) {
+	tt := fileContentTest{
+		Dir:       "testdata/includes_unshadowed_default",
+		Target:    "included",
+		TrimSpace: true,
+		Files: map[string]string{
+			"file.txt": "included",
+		},
+	}
+	tt.Run(t)
+}
+
+func TestSupportedFileNames(t *testing.T) {
+	fileNames := []string{
+		"Taskfile.yml",
+		"Taskfile.yaml",
+		"Taskfile.dist.yml",
+		"Taskfile.dist.yaml",
+	}
+	for _, fileName := range fileNames {
+		t.Run(fileName, func(t *testing.T) {
+			tt := fileContentTest{
+				Dir:       fmt.Sprintf("testdata/file_names/%s", fileName),
+				Target:    "default",
+				TrimSpace: true,
+				Files: map[string]string{
+					"output.txt": "hello",
+				},
+			}
+			tt.Run(t)
+		})
+	}
+}
+
+func TestSummary(t *testing.T) {
+	const dir = "testdata/summary"
+
+	var buff bytes.Buffer
+	e := task.Executor{
+		Dir:     dir,
+		Stdout:  &buff,
+		Stderr:  &buff,
+		Summary: true,
+		Silent:  true,
+	}
+	require.NoError(t, e.Setup())
+	require.NoError(t, e.Run(context.Background(), taskfile.Call{Task: "task-with-summary"}, taskfile.Call{Task: "other-task-with-summary"}))
+
+	data, err := os.ReadFile(filepathext.SmartJoin(dir, "task-with-summary.txt"))
+	require.NoError(t, err)
+
+	expectedOutput := string(data)
+	if runtime.GOOS == "windows" {
+		expectedOutput = strings.ReplaceAll(expectedOutput, "\r\n", "\n")
+	}
+
+	assert.Equal(t, expectedOutput, buff.String())
+}
+
+func TestWhenNoDirAttributeItRunsInSameDirAsTaskfile(t *testing.T) {
+	const expected = "dir"
+	const dir = "testdata/" + expected
+	var out bytes.Buffer
+	e := &task.Executor{
+		Dir:    dir,
+		Stdout: &out,
+		Stderr: &out,
+	}
+
+	require.NoError(t, e.Setup())
+	require.NoError(t, e.Run(context.Background(), taskfile.Call{Task: "whereami"}))
+
+	// got should be the "dir" part of "testdata/dir"
+	got := strings.TrimSuffix(filepath.Base(out.String()), "\n")
+	assert.Equal(t, expected, got, "Mismatch in the working directory")
+}
+
+func TestWhenDirAttributeAndDirExistsItRunsInThatDir(t *testing.T) {
+	const expected = "exists"
+	const dir = "testdata/dir/explicit_exists"
+	var out bytes.Buffer
+	e := &task.Executor{
+		Dir:    dir,
+		Stdout: &out,
+		Stderr: &out,
+	}
+
+	require.NoError(t, e.Setup())
+	require.NoError(t, e.Run(context.Background(), taskfile.Call{Task: "whereami"}))
+
+	got := strings.TrimSuffix(filepath.Base(out.String()), "\n")
+	assert.Equal(t, expected, got, "Mismatch in the working directory")
+}
+
+func TestWhenDirAttributeItCreatesMissingAndRunsInThatDir(t *testing.T) {
+	const expected = "createme"
+	const dir = "testdata/dir/explicit_doesnt_exist/"
+	const toBeCreated = dir + expected
+	const target = "whereami"
+	var out bytes.Buffer
+	e := &task.Executor{
+		Dir:    dir,
+		Stdout: &out,
+		Stderr: &out,
+	}
+
+	// Ensure that the directory to be created doesn't actually exist.
+	_ = os.RemoveAll(toBeCreated)
+	if _, err := os.Stat(toBeCreated); err == nil {
+		t.Errorf("Directory should not exist: %v", err)
+	}
+	require.NoError(t, e.Setup())
+	require.NoError(t, e.Run(context.Background(), taskfile.Call{Task: target}))
+
+	got := strings.TrimSuffix(filepath.Base(out.String()), "\n")
+	assert.Equal(t, expected, got, "Mismatch in the working directory")
+
+	// Clean-up after ourselves only if no error.
+	_ = os.RemoveAll(toBeCreated)
+}
+
+func TestDynamicVariablesRunOnTheNewCreatedDir(t *testing.T) {
+	const expected = "created"
+	const dir = "testdata/dir/dynamic_var_on_created_dir/"
+	const toBeCreated = dir + expected
+	const target = "default"
+	var out bytes.Buffer
+	e := &task.Executor{
+		Dir:    dir,
+		Stdout: &out,
+		Stderr: &out,
+	}
+
+	// Ensure that the directory to be created doesn't actually exist.
+	_ = os.RemoveAll(toBeCreated)
+	if _, err := os.Stat(toBeCreated); err == nil {
+		t.Errorf("Directory should not exist: %v", err)
+	}
+	require.NoError(t, e.Setup())
+	require.NoError(t, e.Run(context.Background(), taskfile.Call{Task: target}))
 
 	got := strings.TrimSuffix(filepath.Base(out.String()), "\n")
 	assert.Equal(t, expected, got, "Mismatch in the working directory")
+
+	// Clean-up after ourselves only if no error.
+	_ = os.RemoveAll(toBeCreated)
+}
+
+func TestDynamicVariablesShouldRunOnTheTaskDir(t *testing.T) {
+	tt := fileContentTest{
+		Dir:       "testdata/dir/dynamic_var",
+		Target:    "default",
+		TrimSpace: false,
+		Files: map[string]string{
+			"subdirectory/from_root_taskfile.txt":          "subdirectory\n",
+			"subdirectory/from_included_taskfile.txt":      "subdirectory\n",
+			"subdirectory/from_included_taskfile_task.txt": "subdirectory\n",
+			"subdirectory/from_interpolated_dir.txt":       "subdirectory\n",
+		},
+	}
+	tt.Run(t)
+}
+
+func TestDisplaysErrorOnVersion1Schema(t *testing.T) {
+	e := task.Executor{
+		Dir:    "testdata/version/v1",
+		Stdout: io.Discard,
+		Stderr: io.Discard,
+	}
+	err := e.Setup()
+	require.Error(t, err)
+	assert.Equal(t, "task: version 1 schemas are no longer supported", err.Error())
+}
+
+func TestDisplaysWarningOnVersion2Schema(t *testing.T) {
+	var buff bytes.Buffer
+	e := task.Executor{
+		Dir:    "testdata/version/v2",
+		Stdout: io.Discard,
+		Stderr: &buff,
+	}
+	err := e.Setup()
+	require.NoError(t, err)
+	assert.Equal(t, "task: version 2 schemas are deprecated and will be removed in a future release\nSee https://github.com/go-task/task/issues/1197 for more details\n", buff.String())
+}
+
+func TestDeprecations(t *testing.T) {
+	var buff bytes.Buffer
+	e := task.Executor{
+		Dir:    "testdata/deprecations",
+		Stdout: io.Discard,
+		Stderr: &buff,
+	}
+	require.NoError(t, e.Setup())
+	assert.Equal(t, strings.Join([]string{
+		`task: the Taskfile uses the "FromSlash" template function, which is deprecated, use "fromSlash" instead`,
+		`See https://taskfile.dev/deprecations/template-functions/ for more details`,
+		`task: task "default" uses the "IsSH" template function, which is deprecated`,
+		`See https://taskfile.dev/deprecations/template-functions/ for more details`,
+		`task: task "again" uses the "FromSlash" template function, which is deprecated, use "fromSlash" instead`,
+		`See https://taskfile.dev/deprecations/template-functions/ for more details`,
+		``,
+	}, "\n"), buff.String())
+
+	// The deprecations found by the CLI are reported once too
+	buff.Reset()
+	require.NoError(t, e.ReportForceAll())
+	require.NoError(t, e.ReportForceAll())
+	assert.Equal(t, 1, strings.Count(buff.String(), "--force will only force the called tasks"))
+
+	e = task.Executor{
+		Dir:    "testdata/deprecations",
+		Stdout: io.Discard,
+		Stderr: io.Discard,
+		Strict: true,
+	}
+	err := e.Setup()
+	var deprecatedErr *errors.TaskfileDeprecatedError
+	require.ErrorAs(t, err, &deprecatedErr)
+	assert.Equal(t, errors.CodeTaskfileDeprecated, deprecatedErr.Code())
+
+	e = task.Executor{
+		Dir:    "testdata/executor",
+		Stdout: io.Discard,
+		Stderr: io.Discard,
+		Strict: true,
+	}
+	require.ErrorAs(t, e.ReportForceAll(), &deprecatedErr)
+}
+
+func TestShortTaskNotation(t *testing.T) {
+	const dir = "testdata/short_task_notation"
+
+	var buff bytes.Buffer
+	e := task.Executor{
+		Dir:    dir,
+		Stdout: &buff,
+		Stderr: &buff,
+		Silent: true,
+	}
+	require.NoError(t, e.Setup())
+	require.NoError(t, e.Run(context.Background(), taskfile.Call{Task: "default"}))
+	assert.Equal(t, "string-slice-1\nstring-slice-2\nstring\ncmd\n", buff.String())
+}
+
+func TestDotenvShouldIncludeAllEnvFiles(t *testing.T) {
+	tt := fileContentTest{
+		Dir:       "testdata/dotenv/default",
+		Target:    "default",
+		TrimSpace: false,
+		Files: map[string]string{
+			"include.txt": "INCLUDE1='from_include1' INCLUDE2='from_include2'\n",
+		},
+	}
+	tt.Run(t)
+}
+
+func TestDotenvShouldErrorWhenIncludingDependantDotenvs(t *testing.T) {
+	const dir = "testdata/dotenv/error_included_envs"
+	const entry = "Taskfile.yml"
+
+	var buff bytes.Buffer
+	e := task.Executor{
+		Dir:        dir,
+		Entrypoint: entry,
+		Summary:    true,
+		Stdout:     &buff,
+		Stderr:     &buff,
+	}
+
+	err := e.Setup()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "move the dotenv")
+}
+
+func TestDotenvShouldAllowMissingEnv(t *testing.T) {
+	tt := fileContentTest{
+		Dir:       "testdata/dotenv/missing_env",
+		Target:    "default",
+		TrimSpace: false,
+		Files: map[string]string{
+			"include.txt": "INCLUDE1='' INCLUDE2=''\n",
+		},
+	}
+	tt.Run(t)
+}
+
+func TestDotenvHasLocalEnvInPath(t *testing.T) {
+	tt := fileContentTest{
+		Dir:       "testdata/dotenv/local_env_in_path",
+		Target:    "default",
+		TrimSpace: false,
+		Files: map[string]string{
+			"var.txt": "VAR='var_in_dot_env_1'\n",
+		},
+	}
+	tt.Run(t)
+}
+
+func TestDotenvHasLocalVarInPath(t *testing.T) {
+	tt := fileContentTest{
+		Dir:       "testdata/dotenv/local_var_in_path",
+		Target:    "default",
+		TrimSpace: false,
+		Files: map[string]string{
+			"var.txt": "VAR='var_in_dot_env_3'\n",
+		},
+	}
+	tt.Run(t)
+}
+
+func TestDotenvHasEnvVarInPath(t *testing.T) {
+	os.Setenv("ENV_VAR", "testing")
+
+	tt := fileContentTest{
+		Dir:       "testdata/dotenv/env_var_in_path",
+		Target:    "default",
+		TrimSpace: false,
+		Files: map[string]string{
+			"var.txt": "VAR='var_in_dot_env_2'\n",
+		},
+	}
+	tt.Run(t)
+}
+
+func TestTaskDotenv(t *testing.T) {
+	tt := fileContentTest{
+		Dir:       "testdata/dotenv_task/default",
+		Target:    "dotenv",
+		TrimSpace: true,
+		Files: map[string]string{
+			"dotenv.txt": "foo",
+		},
+	}
+	tt.Run(t)
+}
+
+func TestTaskDotenvFail(t *testing.T) {
+	tt := fileContentTest{
+		Dir:       "testdata/dotenv_task/default",
+		Target:    "no-dotenv",
+		TrimSpace: true,
+		Files: map[string]string{
+			"no-dotenv.txt": "global",
+		},
+	}
+	tt.Run(t)
+}
+
+func TestTaskDotenvOverriddenByEnv(t *testing.T) {
+	tt := fileContentTest{
+		Dir:       "testdata/dotenv_task/default",
+		Target:    "dotenv-overridden-by-env",
+		TrimSpace: true,
+		Files: map[string]string{
+			"dotenv-overridden-by-env.txt": "overridden",
+		},
+	}
+	tt.Run(t)
+}
+
+func TestTaskDotenvWithVarName(t *testing.T) {
+	tt := fileContentTest{
+		Dir:       "testdata/dotenv_task/default",
+		Target:    "dotenv-with-var-name",
+		TrimSpace: true,
+		Files: map[string]string{
+			"dotenv-with-var-name.txt": "foo",
+		},
+	}
+	tt.Run(t)
+}
+
+func TestExitImmediately(t *testing.T) {
+	const dir = "testdata/exit_immediately"
+
+	var buff bytes.Buffer
+	e := task.Executor{
+		Dir:    dir,
+		Stdout: &buff,
+		Stderr: &buff,
+		Silent: true,
+	}
+	require.NoError(t, e.Setup())
+
+	require.Error(t, e.Run(context.Background(), taskfile.Call{Task: "default"}))
+	assert.Contains(t, buff.String(), `"this_should_fail": executable file not found in $PATH`)
+}
+
+func TestRunOnlyRunsJobsHashOnce(t *testing.T) {
+	tt := fileContentTest{
+		Dir:    "testdata/run",
+		Target: "generate-hash",
+		Files: map[string]string{
+			"hash.txt": "starting 1\n1\n2\n",
+		},
+	}
+	tt.Run(t)
+}
+
+func TestDeferredCmds(t *testing.T) {
+	const dir = "testdata/deferred"
+	var buff bytes.Buffer
+	e := task.Executor{
+		Dir:    dir,
+		Stdout: &buff,
+		Stderr: &buff,
+	}
+	require.NoError(t, e.Setup())
+
+	expectedOutputOrder := strings.TrimSpace(`
+task: [task-2] echo 'cmd ran'
+cmd ran
+task: [task-2] exit 1
+task: [task-2] echo 'failing' && exit 2
+failing
+task: [task-2] echo 'echo ran'
+echo ran
+task: [task-1] echo 'task-1 ran successfully'
+task-1 ran successfully
+`)
+	require.Error(t, e.Run(context.Background(), taskfile.Call{Task: "task-2"}))
+	assert.Contains(t, buff.String(), expectedOutputOrder)
+}
+
+func TestHooks(t *testing.T) {
+	const dir = "testdata/hooks"
+	var buff bytes.Buffer
+	e := task.Executor{
+		Dir:    dir,
+		Stdout: &buff,
+		Stderr: &buff,
+		Silent: true,
+	}
+	require.NoError(t, e.Setup())
+	require.NoError(t, e.Run(context.Background(), taskfile.Call{Task: "foo"}, taskfile.Call{Task: "bar"}))
+	assert.Equal(t, "setup\n> foo\ndep\nfoo\nfoo 0\n> bar\nbar\nbar 0\nteardown 0\n", buff.String())
+
+	// The after hooks run even when the task failed
+	buff.Reset()
+	require.Error(t, e.Run(context.Background(), taskfile.Call{Task: "fail"}))
+	assert.Equal(t, "setup\n> fail\nfail 3\nteardown 3\n", buff.String())
+
+	// Only the root Taskfile can have hooks
+	e = task.Executor{
+		Dir:    filepathext.SmartJoin(dir, "included"),
+		Stdout: io.Discard,
+		Stderr: io.Discard,
+	}
+	require.ErrorIs(t, e.Setup(), read.ErrIncludedTaskfilesCantHaveHooks)
+}
+
+func TestCmdProvenance(t *testing.T) {
+	const dir = "testdata/provenance"
+	var buff bytes.Buffer
+	e := task.Executor{
+		Dir:     dir,
+		Stdout:  &buff,
+		Stderr:  &buff,
+		Verbose: true,
+	}
+	require.NoError(t, e.Setup())
+	require.NoError(t, e.Run(context.Background(), taskfile.Call{Task: "default"}, taskfile.Call{Task: "sub:default"}))
+
+	abs, err := filepath.Abs(dir)
+	require.NoError(t, err)
+	// The commands of the tasks extended keep where they're written
+	assert.Contains(t, buff.String(), "task: [default] echo base\n"+
+		"task: [default]   from "+filepath.Join(dir, "Taskfile.yml")+":9:9\n"+
+		"task: [default]   dir: "+filepathext.SmartJoin(abs, "sub")+"\n"+
+		"task: [default]   env: +TASK_PROVENANCE=value\n")
+	assert.Contains(t, buff.String(), "task: [sub:default] echo sub\n"+
+		"task: [sub:default]   from "+filepath.Join(dir, "sub", "Taskfile.yml")+":4:12\n")
+}
+
+func TestPathCall(t *testing.T) {
+	const dir = "testdata/path_call"
+	abs, err := filepath.Abs(dir)
+	require.NoError(t, err)
+
+	var buff bytes.Buffer
+	e := task.Executor{
+		Dir:            dir,
+		UserWorkingDir: abs,
+		Stdout:         &buff,
+		Stderr:         &buff,
+		Silent:         true,
+	}
+	require.NoError(t, e.Setup())
+	require.NoError(t, e.Run(context.Background(), taskfile.Call{Task: "./services/api:deploy"}))
+	assert.Equal(t, "build\ndeploy api from api\n", buff.String())
+
+	// The paths are cleaned, so they all name the Taskfile loaded
+	buff.Reset()
+	require.NoError(t, e.Run(context.Background(), taskfile.Call{Task: "./services/../services/api"}, taskfile.Call{Task: "./services/api/"}))
+	assert.Equal(t, "default api\ndefault api\n", buff.String())
+
+	err = e.Run(context.Background(), taskfile.Call{Task: "./services/api:build"})
+	var internalErr *errors.TaskInternalError
+	assert.ErrorAs(t, err, &internalErr)
+	assert.Error(t, e.Run(context.Background(), taskfile.Call{Task: "./services/missing:deploy"}))
+	assert.Error(t, e.Run(context.Background(), taskfile.Call{Task: "./:default"}))
+}
+
+func TestIgnoreNilElements(t *testing.T) {
+	tests := []struct {
+		name string
+		dir  string
+	}{
+		{"nil cmd", "testdata/ignore_nil_elements/cmds"},
+		{"nil dep", "testdata/ignore_nil_elements/deps"},
+		{"nil include", "testdata/ignore_nil_elements/includes"},
+		{"nil precondition", "testdata/ignore_nil_elements/preconditions"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var buff bytes.Buffer
+			e := task.Executor{
+				Dir:    test.dir,
+				Stdout: &buff,
+				Stderr: &buff,
+				Silent: true,
+			}
+			require.NoError(t, e.Setup())
+			require.NoError(t, e.Run(context.Background(), taskfile.Call{Task: "default"}))
+			assert.Equal(t, "string-slice-1\n", buff.String())
+		})
+	}
+}
+
+func TestOutputGroup(t *testing.T) {
+	const dir = "testdata/output_group"
+	var buff bytes.Buffer
+	e := task.Executor{
+		Dir:    dir,
+		Stdout: &buff,
+		Stderr: &buff,
+	}
+	require.NoError(t, e.Setup())
+
+	expectedOutputOrder := strings.TrimSpace(`
+task: [hello] echo 'Hello!'
+::group::hello
+Hello!
+::endgroup::
+task: [bye] echo 'Bye!'
+::group::bye
+Bye!
+::endgroup::
+`)
+	require.NoError(t, e.Run(context.Background(), taskfile.Call{Task: "bye"}))
+	t.Log(buff.String())
+	assert.Equal(t, strings.TrimSpace(buff.String()), expectedOutputOrder)
 }
 
-func TestWhenDirAttributeItCreatesMissingAndRunsInThatDir(t *testing.T) {
-	const expected = "createme"
-	const dir = "testdata/dir/explicit_doesnt_exist/"
-	const toBeCreated = dir + expected
-	const target = "whereami"
-	var out bytes.Buffer
-	e := &task.Executor{
+func TestTaskOutputRouting(t *testing.T) {
+	const dir = "testdata/task_output"
+	t.Cleanup(func() { _ = os.RemoveAll(filepathext.SmartJoin(dir, "logs")) })
+	var stdout, stderr bytes.Buffer
+	e := task.Executor{
 		Dir:    dir,
-		Stdout: &out,
-		Stderr: &out,
+		Stdout: &stdout,
+		Stderr: &stderr,
 	}
+	require.NoError(t, e.Setup())
 
-	// Ensure that the directory to be created doesn't actually exist.
-	_ = os.RemoveAll(toBeCreated)
-	if _, err := os.Stat(toBeCreated); err == nil {
-		t.Errorf("Directory should not exist: %v", err)
+	// Only whether the task succeeded is printed
+	require.NoError(t, e.Run(context.Background(), taskfile.Call{Task: "to-file"}))
+	assert.Empty(t, stdout.String())
+	assert.NotContains(t, stderr.String(), "err\n")
+	assert.Contains(t, stderr.String(), `task: Task "to-file" succeeded, see its output in logs/to-file.log`)
+	b, err := os.ReadFile(filepathext.SmartJoin(dir, "logs/to-file.log"))
+	require.NoError(t, err)
+	assert.Equal(t, "out\nerr\n", string(b))
+
+	stdout.Reset()
+	stderr.Reset()
+	require.NoError(t, e.Run(context.Background(), taskfile.Call{Task: "discard"}))
+	assert.Empty(t, stdout.String())
+	assert.Contains(t, stderr.String(), "err\n")
+	assert.Contains(t, stderr.String(), `task: Task "discard" succeeded`)
+
+	stdout.Reset()
+	stderr.Reset()
+	require.NoError(t, e.Run(context.Background(), taskfile.Call{Task: "merge"}))
+	assert.Equal(t, "err\n", stdout.String())
+	assert.NotContains(t, stderr.String(), "succeeded")
+
+	stdout.Reset()
+	stderr.Reset()
+	require.Error(t, e.Run(context.Background(), taskfile.Call{Task: "failing"}))
+	assert.Contains(t, stderr.String(), `task: Task "failing" failed, see its output in logs/failing.log`)
+	b, err = os.ReadFile(filepathext.SmartJoin(dir, "logs/failing.log"))
+	require.NoError(t, err)
+	assert.Equal(t, "before\n", string(b))
+}
+
+func TestStripANSI(t *testing.T) {
+	const dir = "testdata/strip_ansi"
+	var buff bytes.Buffer
+	e := task.Executor{
+		Dir:    dir,
+		Stdout: &buff,
+		Stderr: &buff,
+		Silent: true,
 	}
 	require.NoError(t, e.Setup())
-	require.NoError(t, e.Run(context.Background(), taskfile.Call{Task: target}))
 
-	got := strings.TrimSuffix(filepath.Base(out.String()), "\n")
-	assert.Equal(t, expected, got, "Mismatch in the working directory")
+	require.NoError(t, e.Run(context.Background(), taskfile.Call{Task: "default"}))
+	assert.Equal(t, "red\n", buff.String())
+}
 
-	// Clean-up after ourselves only if no error.
-	_ = os.RemoveAll(toBeCreated)
+func TestOutputGroupNested(t *testing.T) {
+	const dir = "testdata/output_group_nested"
+	var buff bytes.Buffer
+	e := task.Executor{
+		Dir:    dir,
+		Stdout: &buff,
+		Stderr: &buff,
+		Silent: true,
+	}
+	require.NoError(t, e.Setup())
+
+	expected := `>> release
+  >> build (from release)
+    >> generate (from build)
+    generating
+    << generate
+  building
+  << build
+releasing
+<< release
+`
+	require.NoError(t, e.Run(context.Background(), taskfile.Call{Task: "release"}))
+	assert.Equal(t, expected, buff.String())
 }
 
-func TestDynamicVariablesRunOnTheNewCreatedDir(t *testing.T) {
-	const expected = "created"
-	const dir = "testdata/dir/dynamic_var_on_created_dir/"
-	const toBeCreated = dir + expected
-	const target = "default"
-	var out bytes.Buffer
-	e := &task.Executor{
+func TestOutputGroupInteractive(t *testing.T) {
+	const dir = "testdata/output_group"
+	var buff bytes.Buffer
+	e := task.Executor{
 		Dir:    dir,
-		Stdout: &out,
-		Stderr: &out,
+		Stdout: &buff,
+		Stderr: &buff,
+		Silent: true,
 	}
+	require.NoError(t, e.Setup())
 
-	// Ensure that the directory to be created doesn't actually exist.
-	_ = os.RemoveAll(toBeCreated)
-	if _, err := os.Stat(toBeCreated); err == nil {
-		t.Errorf("Directory should not exist: %v", err)
+	// Interactive commands are never grouped, so they can use the terminal
+	require.NoError(t, e.Run(context.Background(), taskfile.Call{Task: "interactive"}))
+	assert.Equal(t, "Interactive!\n", buff.String())
+}
+
+func TestOutputGroupErrorOnlySwallowsOutputOnSuccess(t *testing.T) {
+	const dir = "testdata/output_group_error_only"
+	var buff bytes.Buffer
+	e := task.Executor{
+		Dir:    dir,
+		Stdout: &buff,
+		Stderr: &buff,
 	}
 	require.NoError(t, e.Setup())
-	require.NoError(t, e.Run(context.Background(), taskfile.Call{Task: target}))
 
-	got := strings.TrimSuffix(filepath.Base(out.String()), "\n")
-	assert.Equal(t, expected, got, "Mismatch in the working directory")
+	require.NoError(t, e.Run(context.Background(), taskfile.Call{Task: "passing"}))
+	t.Log(buff.String())
+	assert.Empty(t, buff.String())
+}
 
-	// Clean-up after ourselves only if no error.
-	_ = os.RemoveAll(toBeCreated)
+func TestOutputGroupErrorOnlyShowsOutputOnFailure(t *testing.T) {
+	const dir = "testdata/output_group_error_only"
+	var buff bytes.Buffer
+	e := task.Executor{
+		Dir:    dir,
+		Stdout: &buff,
+		Stderr: &buff,
+	}
+	require.NoError(t, e.Setup())
+
+	require.Error(t, e.Run(context.Background(), taskfile.Call{Task: "failing"}))
+	t.Log(buff.String())
+	assert.Contains(t, buff.String(), "failing-output")
+	assert.NotContains(t, buff.String(), "passing-output")
 }
 
-func TestDynamicVariablesShouldRunOnTheTaskDir(t *testing.T) {
-	tt := fileContentTest{
-		Dir:       "testdata/dir/dynamic_var",
-		Target:    "default",
-		TrimSpace: false,
-		Files: map[string]string{
-			"subdirectory/from_root_taskfile.txt":          "subdirectory\n",
-			"subdirectory/from_included_taskfile.txt":      "subdirectory\n",
-			"subdirectory/from_included_taskfile_task.txt": "subdirectory\n",
-			"subdirectory/from_interpolated_dir.txt":       "subdirectory\n",
-		},
+func TestIncludedVars(t *testing.T) {
+	const dir = "testdata/include_with_vars"
+	var buff bytes.Buffer
+	e := task.Executor{
+		Dir:    dir,
+		Stdout: &buff,
+		Stderr: &buff,
 	}
-	tt.Run(t)
+	require.NoError(t, e.Setup())
+
+	expectedOutputOrder := strings.TrimSpace(`
+task: [included1:task1] echo "VAR_1 is included1-var1"
+VAR_1 is included1-var1
+task: [included1:task1] echo "VAR_2 is included-default-var2"
+VAR_2 is included-default-var2
+task: [included2:task1] echo "VAR_1 is included2-var1"
+VAR_1 is included2-var1
+task: [included2:task1] echo "VAR_2 is included-default-var2"
+VAR_2 is included-default-var2
+task: [included3:task1] echo "VAR_1 is included-default-var1"
+VAR_1 is included-default-var1
+task: [included3:task1] echo "VAR_2 is included-default-var2"
+VAR_2 is included-default-var2
+`)
+	require.NoError(t, e.Run(context.Background(), taskfile.Call{Task: "task1"}))
+	t.Log(buff.String())
+	assert.Equal(t, strings.TrimSpace(buff.String()), expectedOutputOrder)
 }
 
-func TestDisplaysErrorOnVersion1Schema(t *testing.T) {
+func TestIncludedVarsMultiLevel(t *testing.T) {
+	const dir = "testdata/include_with_vars_multi_level"
+	var buff bytes.Buffer
 	e := task.Executor{
-		Dir:    "testdata/version/v1",
-		Stdout: io.Discard,
-		Stderr: io.Discard,
+		Dir:    dir,
+		Stdout: &buff,
+		Stderr: &buff,
 	}
-	err := e.Setup()
-	require.Error(t, err)
-	assert.Equal(t, "task: version 1 schemas are no longer supported", err.Error())
+	require.NoError(t, e.Setup())
+
+	expectedOutputOrder := strings.TrimSpace(`
+task: [lib:greet] echo 'Hello world'
+Hello world
+task: [foo:lib:greet] echo 'Hello foo'
+Hello foo
+task: [bar:lib:greet] echo 'Hello bar'
+Hello bar
+`)
+	require.NoError(t, e.Run(context.Background(), taskfile.Call{Task: "default"}))
+	t.Log(buff.String())
+	assert.Equal(t, strings.TrimSpace(buff.String()), expectedOutputOrder)
+}
+
+func TestIncludedVarsAndEnv(t *testing.T) {
+	const dir = "testdata/include_with_env"
+	var buff bytes.Buffer
+	e := task.Executor{
+		Dir:    dir,
+		Stdout: &buff,
+		Stderr: &buff,
+		Silent: true,
+	}
+	require.NoError(t, e.Setup())
+
+	// The vars of the include override the ones of the included Taskfile,
+	// which can use them, and so does its env
+	require.NoError(t, e.Run(context.Background(), taskfile.Call{Task: "default"}))
+	assert.Equal(t, "en site/en 1\nit site/it 0\nfr site/fr 0\n", buff.String())
+}
+
+func TestErrorCode(t *testing.T) {
+	const dir = "testdata/error_code"
+	tests := []struct {
+		name     string
+		task     string
+		expected int
+	}{
+		{
+			name:     "direct task",
+			task:     "direct",
+			expected: 42,
+		}, {
+			name:     "indirect task",
+			task:     "indirect",
+			expected: 42,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var buff bytes.Buffer
+			e := &task.Executor{
+				Dir:    dir,
+				Stdout: &buff,
+				Stderr: &buff,
+				Silent: true,
+			}
+			require.NoError(t, e.Setup())
+
+			err := e.Run(context.Background(), taskfile.Call{Task: test.task, Direct: true})
+			require.Error(t, err)
+			taskRunErr, ok := err.(*errors.TaskRunError)
+			assert.True(t, ok, "cannot cast returned error to *task.TaskRunError")
+			assert.Equal(t, test.expected, taskRunErr.TaskExitCode(), "unexpected exit code from task")
+		})
+	}
+}
+
+func TestEvaluateSymlinksInPaths(t *testing.T) {
+	const dir = "testdata/evaluate_symlinks_in_paths"
+	var buff bytes.Buffer
+	e := &task.Executor{
+		Dir:    dir,
+		Stdout: &buff,
+		Stderr: &buff,
+		Silent: false,
+	}
+	require.NoError(t, e.Setup())
+	err := e.Run(context.Background(), taskfile.Call{Task: "default"})
+	require.NoError(t, err)
+	assert.NotEqual(t, `task: Task "default" is up to date`, strings.TrimSpace(buff.String()))
+	buff.Reset()
+	err = e.Run(context.Background(), taskfile.Call{Task: "test-sym"})
+	require.NoError(t, err)
+	assert.NotEqual(t, `task: Task "test-sym" is up to date`, strings.TrimSpace(buff.String()))
+	buff.Reset()
+	err = e.Run(context.Background(), taskfile.Call{Task: "default"})
+	require.NoError(t, err)
+	assert.NotEqual(t, `task: Task "default" is up to date`, strings.TrimSpace(buff.String()))
+	buff.Reset()
+	err = e.Run(context.Background(), taskfile.Call{Task: "default"})
+	require.NoError(t, err)
+	assert.Equal(t, `task: Task "default" is up to date`, strings.TrimSpace(buff.String()))
+	buff.Reset()
+	err = e.Run(context.Background(), taskfile.Call{Task: "reset"})
+	require.NoError(t, err)
+	buff.Reset()
+	err = os.RemoveAll(dir + "/.task")
+	require.NoError(t, err)
 }
 
-func TestDisplaysWarningOnVersion2Schema(t *testing.T) {
-	var buff bytes.Buffer
-	e := task.Executor{
-		Dir:    "testdata/version/v2",
-		Stdout: io.Discard,
-		Stderr: &buff,
+func TestTaskfileWalk(t *testing.T) {
+	tests := []struct {
+		name     string
+		dir      string
+		expected string
+	}{
+		{
+			name:     "walk from root directory",
+			dir:      "testdata/taskfile_walk",
+			expected: "foo\n",
+		}, {
+			name:     "walk from sub directory",
+			dir:      "testdata/taskfile_walk/foo",
+			expected: "foo\n",
+		}, {
+			name:     "walk from sub sub directory",
+			dir:      "testdata/taskfile_walk/foo/bar",
+			expected: "foo\n",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var buff bytes.Buffer
+			e := task.Executor{
+				Dir:    test.dir,
+				Stdout: &buff,
+				Stderr: &buff,
+			}
+			require.NoError(t, e.Setup())
+			require.NoError(t, e.Run(context.Background(), taskfile.Call{Task: "default"}))
+			assert.Equal(t, test.expected, buff.String())
+		})
 	}
-	err := e.Setup()
-	require.NoError(t, err)
-	assert.Equal(t, "task: version 2 schemas are deprecated and will be removed in a future release\nSee https://github.com/go-task/task/issues/1197 for more details\n", buff.String())
 }
 
-func TestShortTaskNotation(t *testing.T) {
-	const dir = "testdata/short_task_notation"
-
+func TestUserWorkingDirectory(t *testing.T) {
 	var buff bytes.Buffer
 	e := task.Executor{
-		Dir:    dir,
+		Dir:    "testdata/user_working_dir",
 		Stdout: &buff,
 		Stderr: &buff,
-		Silent: true,
 	}
+	wd, err := os.Getwd()
+	require.NoError(t, err)
 	require.NoError(t, e.Setup())
 	require.NoError(t, e.Run(context.Background(), taskfile.Call{Task: "default"}))
-	assert.Equal(t, "string-slice-1\nstring-slice-2\nstring\n", buff.String())
+	assert.Equal(t, fmt.Sprintf("%s\n", wd), buff.String())
 }
 
-func TestDotenvShouldIncludeAllEnvFiles(t *testing.T) {
-	tt := fileContentTest{
-		Dir:       "testdata/dotenv/default",
-		Target:    "default",
-		TrimSpace: false,
-		Files: map[string]string{
-			"include.txt": "INCLUDE1='from_include1' INCLUDE2='from_include2'\n",
-		},
-	}
-	tt.Run(t)
-}
+func TestUserWorkingDirectoryWithIncluded(t *testing.T) {
+	wd, err := os.Getwd()
+	require.NoError(t, err)
 
-func TestDotenvShouldErrorWhenIncludingDependantDotenvs(t *testing.T) {
-	const dir = "testdata/dotenv/error_included_envs"
-	const entry = "Taskfile.yml"
+	wd = filepathext.SmartJoin(wd, "testdata/user_working_dir_with_includes/somedir")
 
 	var buff bytes.Buffer
 	e := task.Executor{
-		Dir:        dir,
-		Entrypoint: entry,
-		Summary:    true,
-		Stdout:     &buff,
-		Stderr:     &buff,
-	}
-
-	err := e.Setup()
-	require.Error(t, err)
-	assert.Contains(t, err.Error(), "move the dotenv")
-}
-
-func TestDotenvShouldAllowMissingEnv(t *testing.T) {
-	tt := fileContentTest{
-		Dir:       "testdata/dotenv/missing_env",
-		Target:    "default",
-		TrimSpace: false,
-		Files: map[string]string{
-			"include.txt": "INCLUDE1='' INCLUDE2=''\n",
-		},
-	}
-	tt.Run(t)
-}
-
-func TestDotenvHasLocalEnvInPath(t *testing.T) {
-	tt := fileContentTest{
-		Dir:       "testdata/dotenv/local_env_in_path",
-		Target:    "default",
-		TrimSpace: false,
-		Files: map[string]string{
-			"var.txt": "VAR='var_in_dot_env_1'\n",
-		},
-	}
-	tt.Run(t)
-}
-
-func TestDotenvHasLocalVarInPath(t *testing.T) {
-	tt := fileContentTest{
-		Dir:       "testdata/dotenv/local_var_in_path",
-		Target:    "default",
-		TrimSpace: false,
-		Files: map[string]string{
-			"var.txt": "VAR='var_in_dot_env_3'\n",
-		},
-	}
-	tt.Run(t)
-}
-
-func TestDotenvHasEnvVarInPath(t *testing.T) {
-	os.Setenv("ENV_VAR", "testing")
-
-	tt := fileContentTest{
-		Dir:       "testdata/dotenv/env_var_in_path",
-		Target:    "default",
-		TrimSpace: false,
-		Files: map[string]string{
-			"var.txt": "VAR='var_in_dot_env_2'\n",
-		},
+		UserWorkingDir: wd,
+		Dir:            "testdata/user_working_dir_with_includes",
+		Stdout:         &buff,
+		Stderr:         &buff,
 	}
-	tt.Run(t)
-}
 
-func TestTaskDotenv(t *testing.T) {
-	tt := fileContentTest{
-		Dir:       "testdata/dotenv_task/default",
-		Target:    "dotenv",
-		TrimSpace: true,
-		Files: map[string]string{
-			"dotenv.txt": "foo",
-		},
-	}
-	tt.Run(t)
+	require.NoError(t, err)
+	require.NoError(t, e.Setup())
+	require.NoError(t, e.Run(context.Background(), taskfile.Call{Task: "included:echo"}))
+	assert.Equal(t, fmt.Sprintf("%s\n", wd), buff.String())
 }
 
-func TestTaskDotenvFail(t *testing.T) {
-	tt := fileContentTest{
-		Dir:       "testdata/dotenv_task/default",
-		Target:    "no-dotenv",
-		TrimSpace: true,
-		Files: map[string]string{
-			"no-dotenv.txt": "global",
-		},
+func TestPlatforms(t *testing.T) {
+	var buff bytes.Buffer
+	e := task.Executor{
+		Dir:    "testdata/platforms",
+		Stdout: &buff,
+		Stderr: &buff,
 	}
-	tt.Run(t)
+	require.NoError(t, e.Setup())
+	require.NoError(t, e.Run(context.Background(), taskfile.Call{Task: "build-" + runtime.GOOS}))
+	assert.Equal(t, fmt.Sprintf("task: [build-%s] echo 'Running task on %s'\nRunning task on %s\n", runtime.GOOS, runtime.GOOS, runtime.GOOS), buff.String())
 }
 
-func TestTaskDotenvOverriddenByEnv(t *testing.T) {
-	tt := fileContentTest{
-		Dir:       "testdata/dotenv_task/default",
-		Target:    "dotenv-overridden-by-env",
-		TrimSpace: true,
-		Files: map[string]string{
-			"dotenv-overridden-by-env.txt": "overridden",
-		},
+func TestPOSIXShellOptsGlobalLevel(t *testing.T) {
+	var buff bytes.Buffer
+	e := task.Executor{
+		Dir:    "testdata/shopts/global_level",
+		Stdout: &buff,
+		Stderr: &buff,
 	}
-	tt.Run(t)
-}
+	require.NoError(t, e.Setup())
 
-func TestTaskDotenvWithVarName(t *testing.T) {
-	tt := fileContentTest{
-		Dir:       "testdata/dotenv_task/default",
-		Target:    "dotenv-with-var-name",
-		TrimSpace: true,
-		Files: map[string]string{
-			"dotenv-with-var-name.txt": "foo",
-		},
-	}
-	tt.Run(t)
+	err := e.Run(context.Background(), taskfile.Call{Task: "pipefail"})
+	require.NoError(t, err)
+	assert.Equal(t, "pipefail\ton\n", buff.String())
 }
 
-func TestExitImmediately(t *testing.T) {
-	const dir = "testdata/exit_immediately"
-
+func TestPOSIXShellOptsTaskLevel(t *testing.T) {
 	var buff bytes.Buffer
 	e := task.Executor{
-		Dir:    dir,
+		Dir:    "testdata/shopts/task_level",
 		Stdout: &buff,
 		Stderr: &buff,
-		Silent: true,
 	}
 	require.NoError(t, e.Setup())
 
-	require.Error(t, e.Run(context.Background(), taskfile.Call{Task: "default"}))
-	assert.Contains(t, buff.String(), `"this_should_fail": executable file not found in $PATH`)
-}
-
-func TestRunOnlyRunsJobsHashOnce(t *testing.T) {
-	tt := fileContentTest{
-		Dir:    "testdata/run",
-		Target: "generate-hash",
-		Files: map[string]string{
-			"hash.txt": "starting 1\n1\n2\n",
-		},
-	}
-	tt.Run(t)
+	err := e.Run(context.Background(), taskfile.Call{Task: "pipefail"})
+	require.NoError(t, err)
+	assert.Equal(t, "pipefail\ton\n", buff.String())
 }
 
-func TestDeferredCmds(t *testing.T) {
-	const dir = "testdata/deferred"
+func TestPOSIXShellOptsCommandLevel(t *testing.T) {
 	var buff bytes.Buffer
 	e := task.Executor{
-		Dir:    dir,
+		Dir:    "testdata/shopts/command_level",
 		Stdout: &buff,
 		Stderr: &buff,
 	}
 	require.NoError(t, e.Setup())
 
-	expectedOutputOrder := strings.TrimSpace(`
-task: [task-2] echo 'cmd ran'
-cmd ran
-task: [task-2] exit 1
-task: [task-2] echo 'failing' && exit 2
-failing
-task: [task-2] echo 'echo ran'
-echo ran
-task: [task-1] echo 'task-1 ran successfully'
-task-1 ran successfully
-`)
-	require.Error(t, e.Run(context.Background(), taskfile.Call{Task: "task-2"}))
-	assert.Contains(t, buff.String(), expectedOutputOrder)
+	err := e.Run(context.Background(), taskfile.Call{Task: "pipefail"})
+	require.NoError(t, err)
+	assert.Equal(t, "pipefail\ton\n", buff.String())
 }
 
-func TestIgnoreNilElements(t *testing.T) {
-	tests := []struct {
-		name string
-		dir  string
-	}{
-		{"nil cmd", "testdata/ignore_nil_elements/cmds"},
-		{"nil dep", "testdata/ignore_nil_elements/deps"},
-		{"nil include", "testdata/ignore_nil_elements/includes"},
-		{"nil precondition", "testdata/ignore_nil_elements/preconditions"},
+func TestBashShellOptsGlobalLevel(t *testing.T) {
+	var buff bytes.Buffer
+	e := task.Executor{
+		Dir:    "testdata/shopts/global_level",
+		Stdout: &buff,
+		Stderr: &buff,
 	}
+	require.NoError(t, e.Setup())
 
-	for _, test := range tests {
-		t.Run(test.name, func(t *testing.T) {
-			var buff bytes.Buffer
-			e := task.Executor{
-				Dir:    test.dir,
-				Stdout: &buff,
-				Stderr: &buff,
-				Silent: true,
-			}
-			require.NoError(t, e.Setup())
-			require.NoError(t, e.Run(context.Background(), taskfile.Call{Task: "default"}))
-			assert.Equal(t, "string-slice-1\n", buff.String())
-		})
+	err := e.Run(context.Background(), taskfile.Call{Task: "globstar"})
+	require.NoError(t, err)
+	assert.Equal(t, "globstar\ton\n", buff.String())
+}
+
+func TestBashShellOptsTaskLevel(t *testing.T) {
+	var buff bytes.Buffer
+	e := task.Executor{
+		Dir:    "testdata/shopts/task_level",
+		Stdout: &buff,
+		Stderr: &buff,
 	}
+	require.NoError(t, e.Setup())
+
+	err := e.Run(context.Background(), taskfile.Call{Task: "globstar"})
+	require.NoError(t, err)
+	assert.Equal(t, "globstar\ton\n", buff.String())
 }
 
-func TestOutputGroup(t *testing.T) {
-	const dir = "testdata/output_group"
+func TestBashShellOptsCommandLevel(t *testing.T) {
 	var buff bytes.Buffer
 	e := task.Executor{
-		Dir:    dir,
+		Dir:    "testdata/shopts/command_level",
 		Stdout: &buff,
 		Stderr: &buff,
 	}
 	require.NoError(t, e.Setup())
 
-	expectedOutputOrder := strings.TrimSpace(`
-task: [hello] echo 'Hello!'
-::group::hello
-Hello!
-::endgroup::
-task: [bye] echo 'Bye!'
-::group::bye
-Bye!
-::endgroup::
-`)
-	require.NoError(t, e.Run(context.Background(), taskfile.Call{Task: "bye"}))
-	t.Log(buff.String())
-	assert.Equal(t, strings.TrimSpace(buff.String()), expectedOutputOrder)
+	err := e.Run(context.Background(), taskfile.Call{Task: "globstar"})
+	require.NoError(t, err)
+	assert.Equal(t, "globstar\ton\n", buff.String())
 }
 
-func TestOutputGroupErrorOnlySwallowsOutputOnSuccess(t *testing.T) {
-	const dir = "testdata/output_group_error_only"
+func TestSplitArgs(t *testing.T) {
 	var buff bytes.Buffer
 	e := task.Executor{
-		Dir:    dir,
+		Dir:    "testdata/split_args",
 		Stdout: &buff,
 		Stderr: &buff,
+		Silent: true,
 	}
 	require.NoError(t, e.Setup())
 
-	require.NoError(t, e.Run(context.Background(), taskfile.Call{Task: "passing"}))
-	t.Log(buff.String())
-	assert.Empty(t, buff.String())
+	vars := &taskfile.Vars{}
+	vars.Set("CLI_ARGS", taskfile.Var{Static: "foo bar 'foo bar baz'"})
+
+	err := e.Run(context.Background(), taskfile.Call{Task: "default", Vars: vars})
+	require.NoError(t, err)
+	assert.Equal(t, "3\n", buff.String())
 }
 
-func TestOutputGroupErrorOnlyShowsOutputOnFailure(t *testing.T) {
-	const dir = "testdata/output_group_error_only"
+func TestCLIArgsList(t *testing.T) {
 	var buff bytes.Buffer
 	e := task.Executor{
-		Dir:    dir,
+		Dir:    "testdata/cli_args_list",
 		Stdout: &buff,
 		Stderr: &buff,
+		Silent: true,
 	}
 	require.NoError(t, e.Setup())
 
-	require.Error(t, e.Run(context.Background(), taskfile.Call{Task: "failing"}))
-	t.Log(buff.String())
-	assert.Contains(t, "failing-output", strings.TrimSpace(buff.String()))
-	assert.NotContains(t, "passing", strings.TrimSpace(buff.String()))
+	vars := &taskfile.Vars{}
+	require.NoError(t, args.SetCLIArgs(vars, []string{"-run", "Test X"}))
+	assert.Equal(t, "-run 'Test X'", vars.Get("CLI_ARGS").Static)
+
+	// Like the CLI does
+	e.Taskfile.Vars.Merge(vars)
+	err := e.Run(context.Background(), taskfile.Call{Task: "default"})
+	require.NoError(t, err)
+	assert.Equal(t, "[-run]\n[Test X]\n2\n", buff.String())
 }
 
-func TestIncludedVars(t *testing.T) {
-	const dir = "testdata/include_with_vars"
+func TestArgs(t *testing.T) {
 	var buff bytes.Buffer
 	e := task.Executor{
-		Dir:    dir,
+		Dir:    "testdata/args",
 		Stdout: &buff,
 		Stderr: &buff,
+		Silent: true,
 	}
 	require.NoError(t, e.Setup())
 
-	expectedOutputOrder := strings.TrimSpace(`
-task: [included1:task1] echo "VAR_1 is included1-var1"
-VAR_1 is included1-var1
-task: [included1:task1] echo "VAR_2 is included-default-var2"
-VAR_2 is included-default-var2
-task: [included2:task1] echo "VAR_1 is included2-var1"
-VAR_1 is included2-var1
-task: [included2:task1] echo "VAR_2 is included-default-var2"
-VAR_2 is included-default-var2
-task: [included3:task1] echo "VAR_1 is included-default-var1"
-VAR_1 is included-default-var1
-task: [included3:task1] echo "VAR_2 is included-default-var2"
-VAR_2 is included-default-var2
-`)
-	require.NoError(t, e.Run(context.Background(), taskfile.Call{Task: "task1"}))
-	t.Log(buff.String())
-	assert.Equal(t, strings.TrimSpace(buff.String()), expectedOutputOrder)
+	calls, err := e.BindArgs([]taskfile.Call{{Task: "lint"}, {Task: "deploy"}, {Task: "prod"}, {Task: "api"}, {Task: "web"}})
+	require.NoError(t, err)
+	require.Len(t, calls, 2)
+	require.NoError(t, e.Run(context.Background(), calls...))
+	assert.Equal(t, "lint\nprod: api web\n", buff.String())
+
+	// The args can be set as vars by other tasks
+	buff.Reset()
+	require.NoError(t, e.Run(context.Background(), taskfile.Call{Task: "staging"}))
+	assert.Equal(t, "staging:\n", buff.String())
+
+	calls, err = e.BindArgs([]taskfile.Call{{Task: "deploy"}})
+	require.NoError(t, err)
+	err = e.Run(context.Background(), calls...)
+	var argsErr *errors.TaskMissingRequiredArgs
+	require.ErrorAs(t, err, &argsErr)
+	assert.Equal(t, `task: Task "deploy" cancelled because it is missing required arguments: target. Usage: task deploy <target> [services...]`, err.Error())
 }
 
-func TestIncludedVarsMultiLevel(t *testing.T) {
-	const dir = "testdata/include_with_vars_multi_level"
+func TestSingleCmdDep(t *testing.T) {
+	tt := fileContentTest{
+		Dir:    "testdata/single_cmd_dep",
+		Target: "foo",
+		Files: map[string]string{
+			"foo.txt": "foo\n",
+			"bar.txt": "bar\n",
+		},
+	}
+	tt.Run(t)
+}
+
+func TestSilence(t *testing.T) {
 	var buff bytes.Buffer
 	e := task.Executor{
-		Dir:    dir,
+		Dir:    "testdata/silent",
 		Stdout: &buff,
 		Stderr: &buff,
+		Silent: false,
 	}
 	require.NoError(t, e.Setup())
 
-	expectedOutputOrder := strings.TrimSpace(`
-task: [lib:greet] echo 'Hello world'
-Hello world
-task: [foo:lib:greet] echo 'Hello foo'
-Hello foo
-task: [bar:lib:greet] echo 'Hello bar'
-Hello bar
-`)
-	require.NoError(t, e.Run(context.Background(), taskfile.Call{Task: "default"}))
-	t.Log(buff.String())
-	assert.Equal(t, strings.TrimSpace(buff.String()), expectedOutputOrder)
+	// First verify that the silent flag is in place.
+	task, err := e.GetTask(taskfile.Call{Task: "task-test-silent-calls-chatty-silenced"})
+	require.NoError(t, err, "Unable to look up task task-test-silent-calls-chatty-silenced")
+	require.True(t, task.Cmds[0].Silent, "The task task-test-silent-calls-chatty-silenced should have a silent call to chatty")
+
+	// Then test the two basic cases where the task is silent or not.
+	// A silenced task.
+	err = e.Run(context.Background(), taskfile.Call{Task: "silent"})
+	require.NoError(t, err)
+	require.Empty(t, buff.String(), "siWhile running lent: Expected not see output, because the task is silent")
+
+	buff.Reset()
+
+	// A chatty (not silent) task.
+	err = e.Run(context.Background(), taskfile.Call{Task: "chatty"})
+	require.NoError(t, err)
+	require.NotEmpty(t, buff.String(), "chWhile running atty: Expected to see output, because the task is not silent")
+
+	buff.Reset()
+
+	// Then test invoking the two task from other tasks.
+	// A silenced task that calls a chatty task.
+	err = e.Run(context.Background(), taskfile.Call{Task: "task-test-silent-calls-chatty-non-silenced"})
+	require.NoError(t, err)
+	require.NotEmpty(t, buff.String(), "While running task-test-silent-calls-chatty-non-silenced: Expected to see output. The task is silenced, but the called task is not. Silence does not propagate to called tasks.")
+
+	buff.Reset()
+
+	// A silent task that does a silent call to a chatty task.
+	err = e.Run(context.Background(), taskfile.Call{Task: "task-test-silent-calls-chatty-silenced"})
+	require.NoError(t, err)
+	require.Empty(t, buff.String(), "While running task-test-silent-calls-chatty-silenced: Expected not to see output. The task calls chatty task, but the call is silenced.")
+
+	buff.Reset()
+
+	// A chatty task that does a call to a chatty task.
+	err = e.Run(context.Background(), taskfile.Call{Task: "task-test-chatty-calls-chatty-non-silenced"})
+	require.NoError(t, err)
+	require.NotEmpty(t, buff.String(), "While running task-test-chatty-calls-chatty-non-silenced: Expected to see output. Both caller and callee are chatty and not silenced.")
+
+	buff.Reset()
+
+	// A chatty task that does a silenced call to a chatty task.
+	err = e.Run(context.Background(), taskfile.Call{Task: "task-test-chatty-calls-chatty-silenced"})
+	require.NoError(t, err)
+	require.NotEmpty(t, buff.String(), "While running task-test-chatty-calls-chatty-silenced: Expected to see output. Call to a chatty task is silenced, but the parent task is not.")
+
+	buff.Reset()
+
+	// A chatty task with no cmd's of its own that does a silenced call to a chatty task.
+	err = e.Run(context.Background(), taskfile.Call{Task: "task-test-no-cmds-calls-chatty-silenced"})
+	require.NoError(t, err)
+	require.Empty(t, buff.String(), "While running task-test-no-cmds-calls-chatty-silenced: Expected not to see output. While the task itself is not silenced, it does not have any cmds and only does an invocation of a silenced task.")
+
+	buff.Reset()
+
+	// A chatty task that does a silenced invocation of a task.
+	err = e.Run(context.Background(), taskfile.Call{Task: "task-test-chatty-calls-silenced-cmd"})
+	require.NoError(t, err)
+	require.Empty(t, buff.String(), "While running task-test-chatty-calls-silenced-cmd: Expected not to see output. While the task itself is not silenced, its call to the chatty task is silent.")
+
+	buff.Reset()
+
+	// Then test calls via dependencies.
+	// A silent task that depends on a chatty task.
+	err = e.Run(context.Background(), taskfile.Call{Task: "task-test-is-silent-depends-on-chatty-non-silenced"})
+	require.NoError(t, err)
+	require.NotEmpty(t, buff.String(), "While running task-test-is-silent-depends-on-chatty-non-silenced: Expected to see output. The task is silent and depends on a chatty task. Dependencies does not inherit silence.")
+
+	buff.Reset()
+
+	// A silent task that depends on a silenced chatty task.
+	err = e.Run(context.Background(), taskfile.Call{Task: "task-test-is-silent-depends-on-chatty-silenced"})
+	require.NoError(t, err)
+	require.Empty(t, buff.String(), "While running task-test-is-silent-depends-on-chatty-silenced: Expected not to see output. The task is silent and has a silenced dependency on a chatty task.")
+
+	buff.Reset()
+
+	// A chatty task that, depends on a silenced chatty task.
+	err = e.Run(context.Background(), taskfile.Call{Task: "task-test-is-chatty-depends-on-chatty-silenced"})
+	require.NoError(t, err)
+	require.Empty(t, buff.String(), "While running task-test-is-chatty-depends-on-chatty-silenced: Expected not to see output. The task is chatty but does not have commands and has a silenced dependency on a chatty task.")
+
+	buff.Reset()
+}
+
+func TestForce(t *testing.T) {
+	tests := []struct {
+		name     string
+		env      map[string]string
+		force    bool
+		forceAll bool
+	}{
+		{
+			name:  "force",
+			force: true,
+		},
+		{
+			name:     "force-all",
+			forceAll: true,
+		},
+		{
+			name:  "force with gentle force experiment",
+			force: true,
+			env: map[string]string{
+				"TASK_X_GENTLE_FORCE": "1",
+			},
+		},
+		{
+			name:     "force-all with gentle force experiment",
+			forceAll: true,
+			env: map[string]string{
+				"TASK_X_GENTLE_FORCE": "1",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buff bytes.Buffer
+			e := task.Executor{
+				Dir:      "testdata/force",
+				Stdout:   &buff,
+				Stderr:   &buff,
+				Force:    tt.force,
+				ForceAll: tt.forceAll,
+			}
+			require.NoError(t, e.Setup())
+			require.NoError(t, e.Run(context.Background(), taskfile.Call{Task: "task-with-dep", Direct: true}))
+		})
+	}
 }
 
-func TestErrorCode(t *testing.T) {
-	const dir = "testdata/error_code"
+func TestFor(t *testing.T) {
 	tests := []struct {
-		name     string
-		task     string
-		expected int
+		name           string
+		expectedOutput string
 	}{
 		{
-			name:     "direct task",
-			task:     "direct",
-			expected: 42,
-		}, {
-			name:     "indirect task",
-			task:     "indirect",
-			expected: 42,
+			name:           "loop-explicit",
+			expectedOutput: "a\nb\nc\n",
+		},
+		{
+			name:           "loop-sources",
+			expectedOutput: "bar\nfoo\n",
+		},
+		{
+			name:           "loop-sources-glob",
+			expectedOutput: "bar\nfoo\n",
+		},
+		{
+			name:           "loop-vars",
+			expectedOutput: "foo\nbar\n",
+		},
+		{
+			name:           "loop-vars-sh",
+			expectedOutput: "bar\nfoo\n",
+		},
+		{
+			name:           "loop-task",
+			expectedOutput: "foo\nbar\n",
+		},
+		{
+			name:           "loop-task-as",
+			expectedOutput: "foo\nbar\n",
+		},
+		{
+			name:           "loop-different-tasks",
+			expectedOutput: "1\n2\n3\n",
 		},
 	}
+
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
 			var buff bytes.Buffer
-			e := &task.Executor{
-				Dir:    dir,
+			e := task.Executor{
+				Dir:    "testdata/for",
 				Stdout: &buff,
 				Stderr: &buff,
 				Silent: true,
+				Force:  true,
 			}
 			require.NoError(t, e.Setup())
-
-			err := e.Run(context.Background(), taskfile.Call{Task: test.task, Direct: true})
-			require.Error(t, err)
-			taskRunErr, ok := err.(*errors.TaskRunError)
-			assert.True(t, ok, "cannot cast returned error to *task.TaskRunError")
-			assert.Equal(t, test.expected, taskRunErr.TaskExitCode(), "unexpected exit code from task")
+			require.NoError(t, e.Run(context.Background(), taskfile.Call{Task: test.name, Direct: true}))
+			assert.Equal(t, test.expectedOutput, buff.String())
 		})
 	}
 }
 
-func TestEvaluateSymlinksInPaths(t *testing.T) {
-	const dir = "testdata/evaluate_symlinks_in_paths"
-	var buff bytes.Buffer
-	e := &task.Executor{
-		Dir:    dir,
-		Stdout: &buff,
-		Stderr: &buff,
-		Silent: false,
+func TestForParallel(t *testing.T) {
+	// Each iteration only finishes once the other one started, so running
+	// them one after the other would never end
+	for _, name := range []string{"loop-parallel", "loop-parallel-task"} {
+		t.Run(name, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+
+			vars := &taskfile.Vars{}
+			vars.Set("DIR", taskfile.Var{Static: filepath.ToSlash(t.TempDir())})
+
+			var buff bytes.Buffer
+			e := task.Executor{
+				Dir:    "testdata/for",
+				Stdout: &buff,
+				Stderr: &buff,
+				Silent: true,
+			}
+			require.NoError(t, e.Setup())
+			require.NoError(t, e.Run(ctx, taskfile.Call{Task: name, Vars: vars}))
+			assert.Equal(t, "done\n", buff.String())
+		})
 	}
-	require.NoError(t, e.Setup())
-	err := e.Run(context.Background(), taskfile.Call{Task: "default"})
-	require.NoError(t, err)
-	assert.NotEqual(t, `task: Task "default" is up to date`, strings.TrimSpace(buff.String()))
-	buff.Reset()
-	err = e.Run(context.Background(), taskfile.Call{Task: "test-sym"})
-	require.NoError(t, err)
-	assert.NotEqual(t, `task: Task "test-sym" is up to date`, strings.TrimSpace(buff.String()))
-	buff.Reset()
-	err = e.Run(context.Background(), taskfile.Call{Task: "default"})
-	require.NoError(t, err)
-	assert.NotEqual(t, `task: Task "default" is up to date`, strings.TrimSpace(buff.String()))
-	buff.Reset()
-	err = e.Run(context.Background(), taskfile.Call{Task: "default"})
-	require.NoError(t, err)
-	assert.Equal(t, `task: Task "default" is up to date`, strings.TrimSpace(buff.String()))
-	buff.Reset()
-	err = e.Run(context.Background(), taskfile.Call{Task: "reset"})
-	require.NoError(t, err)
-	buff.Reset()
-	err = os.RemoveAll(dir + "/.task")
-	require.NoError(t, err)
 }
 
-func TestTaskfileWalk(t *testing.T) {
+func TestBuiltins(t *testing.T) {
+	// The nuv integration handles some of these commands itself when enabled
+	nuvIntegrationEnabled := interp.NuvIntegrationEnabled
+	interp.NuvIntegrationEnabled = false
+	t.Cleanup(func() { interp.NuvIntegrationEnabled = nuvIntegrationEnabled })
+	// Make sure the host commands aren't found
+	t.Setenv("PATH", "")
+
 	tests := []struct {
-		name     string
-		dir      string
-		expected string
+		task   string
+		output string
 	}{
-		{
-			name:     "walk from root directory",
-			dir:      "testdata/taskfile_walk",
-			expected: "foo\n",
-		}, {
-			name:     "walk from sub directory",
-			dir:      "testdata/taskfile_walk/foo",
-			expected: "foo\n",
-		}, {
-			name:     "walk from sub sub directory",
-			dir:      "testdata/taskfile_walk/foo/bar",
-			expected: "foo\n",
-		},
+		{"status", "ran\n"},
+		{"lib:status", ""},
+		{"lib:dynamic", "content\n"},
+		{"lib:precondition", "ran\n"},
 	}
 	for _, test := range tests {
-		t.Run(test.name, func(t *testing.T) {
+		t.Run(test.task, func(t *testing.T) {
 			var buff bytes.Buffer
 			e := task.Executor{
-				Dir:    test.dir,
+				Dir:    "testdata/builtins",
 				Stdout: &buff,
 				Stderr: &buff,
+				Silent: true,
 			}
 			require.NoError(t, e.Setup())
-			require.NoError(t, e.Run(context.Background(), taskfile.Call{Task: "default"}))
-			assert.Equal(t, test.expected, buff.String())
+			require.NoError(t, e.Run(context.Background(), taskfile.Call{Task: test.task}))
+			assert.Equal(t, test.output, buff.String())
 		})
 	}
 }
 
-func TestUserWorkingDirectory(t *testing.T) {
+func TestForParallelIgnoreError(t *testing.T) {
 	var buff bytes.Buffer
 	e := task.Executor{
-		Dir:    "testdata/user_working_dir",
+		Dir:    "testdata/for",
 		Stdout: &buff,
 		Stderr: &buff,
+		Silent: true,
 	}
-	wd, err := os.Getwd()
-	require.NoError(t, err)
 	require.NoError(t, e.Setup())
-	require.NoError(t, e.Run(context.Background(), taskfile.Call{Task: "default"}))
-	assert.Equal(t, fmt.Sprintf("%s\n", wd), buff.String())
+	require.NoError(t, e.Run(context.Background(), taskfile.Call{Task: "loop-parallel-task-ignore-error"}))
+	assert.Equal(t, "done\ntask: Ignored 1 error(s):\ntask: - \"fail-on-b\": exit status 1\n", buff.String())
 }
 
-func TestUserWorkingDirectoryWithIncluded(t *testing.T) {
-	wd, err := os.Getwd()
-	require.NoError(t, err)
-
-	wd = filepathext.SmartJoin(wd, "testdata/user_working_dir_with_includes/somedir")
+func TestDynamicVarsCache(t *testing.T) {
+	const dir = "testdata/dynamic_vars_cache"
+	for _, file := range []string{"runs.txt", "here.txt", "sub.txt"} {
+		_ = os.Remove(filepathext.SmartJoin(dir, file))
+	}
 
 	var buff bytes.Buffer
 	e := task.Executor{
-		UserWorkingDir: wd,
-		Dir:            "testdata/user_working_dir_with_includes",
-		Stdout:         &buff,
-		Stderr:         &buff,
+		Dir:    dir,
+		Stdout: &buff,
+		Stderr: &buff,
+		Silent: true,
 	}
+	require.NoError(t, e.Setup())
+	require.NoError(t, e.Run(context.Background(), taskfile.Call{Task: "default"}))
+	assert.Contains(t, buff.String(), "12\n")
 
+	// Every task uses the global vars, but they ran once
+	b, err := os.ReadFile(filepathext.SmartJoin(dir, "runs.txt"))
 	require.NoError(t, err)
-	require.NoError(t, e.Setup())
-	require.NoError(t, e.Run(context.Background(), taskfile.Call{Task: "included:echo"}))
-	assert.Equal(t, fmt.Sprintf("%s\n", wd), buff.String())
+	runs := strings.Fields(string(b))
+	sort.Strings(runs)
+	assert.Equal(t, []string{"one", "two"}, runs)
+
+	// The same command runs again in another directory
+	here, err := os.ReadFile(filepathext.SmartJoin(dir, "here.txt"))
+	require.NoError(t, err)
+	sub, err := os.ReadFile(filepathext.SmartJoin(dir, "sub.txt"))
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(strings.TrimSpace(string(here)), "sub"), strings.TrimSpace(string(sub)))
 }
 
-func TestPlatforms(t *testing.T) {
+func TestLazyVars(t *testing.T) {
+	const dir = "testdata/lazy_vars"
+	runs := filepathext.SmartJoin(dir, "runs.txt")
+	_ = os.Remove(runs)
+
 	var buff bytes.Buffer
 	e := task.Executor{
-		Dir:    "testdata/platforms",
+		Dir:    dir,
 		Stdout: &buff,
 		Stderr: &buff,
+		Silent: true,
 	}
 	require.NoError(t, e.Setup())
-	require.NoError(t, e.Run(context.Background(), taskfile.Call{Task: "build-" + runtime.GOOS}))
-	assert.Equal(t, fmt.Sprintf("task: [build-%s] echo 'Running task on %s'\nRunning task on %s\n", runtime.GOOS, runtime.GOOS, runtime.GOOS), buff.String())
+
+	require.NoError(t, e.Run(context.Background(), taskfile.Call{Task: "unused"}))
+	assert.Equal(t, "unused\n", buff.String())
+	assert.NoFileExists(t, runs)
+
+	buff.Reset()
+	require.NoError(t, e.Run(context.Background(), taskfile.Call{Task: "used"}))
+	assert.Equal(t, "got value\nvalue\n", buff.String())
+	b, err := os.ReadFile(runs)
+	require.NoError(t, err)
+	assert.Equal(t, "lazy\n", string(b))
 }
 
-func TestPOSIXShellOptsGlobalLevel(t *testing.T) {
+func TestIncludesVarsStrategy(t *testing.T) {
 	var buff bytes.Buffer
 	e := task.Executor{
-		Dir:    "testdata/shopts/global_level",
-		Stdout: &buff,
-		Stderr: &buff,
+		Dir:     "testdata/includes_vars_strategy",
+		Stdout:  &buff,
+		Stderr:  &buff,
+		Silent:  true,
+		Verbose: true,
 	}
 	require.NoError(t, e.Setup())
 
-	err := e.Run(context.Background(), taskfile.Call{Task: "pipefail"})
-	require.NoError(t, err)
-	assert.Equal(t, "pipefail\ton\n", buff.String())
+	require.NoError(t, e.Run(context.Background(), taskfile.Call{Task: "override"}))
+	assert.Contains(t, buff.String(), "include-\n")
+	assert.Contains(t, buff.String(), `task: "override:default" var "NAME" set by the include`)
+
+	buff.Reset()
+	require.NoError(t, e.Run(context.Background(), taskfile.Call{Task: "inherit"}))
+	assert.Contains(t, buff.String(), "included-include\n")
+	assert.Contains(t, buff.String(), `task: "inherit:default" var "NAME" set by the included Taskfile`)
+
+	e = task.Executor{
+		Dir:    "testdata/includes_vars_strategy/error",
+		Stdout: io.Discard,
+		Stderr: io.Discard,
+	}
+	err := e.Setup()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `The var "NAME" of the include "included" is set by the included Taskfile too`)
 }
 
-func TestPOSIXShellOptsTaskLevel(t *testing.T) {
+func TestWhich(t *testing.T) {
 	var buff bytes.Buffer
 	e := task.Executor{
-		Dir:    "testdata/shopts/task_level",
+		Dir:    "testdata/which",
 		Stdout: &buff,
 		Stderr: &buff,
 	}
 	require.NoError(t, e.Setup())
+	require.NoError(t, e.Which(taskfile.Call{Task: "default"}, taskfile.Call{Task: "docs:site:b"}))
+
+	root := filepathext.TryAbsToRel(filepathext.SmartJoin(e.Dir, "Taskfile.yml"))
+	docs := filepathext.TryAbsToRel(filepathext.SmartJoin(e.Dir, "docs/Taskfile.yml"))
+	site := filepathext.TryAbsToRel(filepathext.SmartJoin(e.Dir, "docs/Site.yml"))
+	assert.Equal(t, fmt.Sprintf(`%s:7:3
+%s:4:3
+  included as "docs" by %s:4:3
+  included as "site" by %s:4:3
+`, root, site, root, docs), buff.String())
+
+	assert.Error(t, e.Which(taskfile.Call{Task: "nope"}))
+}
 
-	err := e.Run(context.Background(), taskfile.Call{Task: "pipefail"})
-	require.NoError(t, err)
-	assert.Equal(t, "pipefail\ton\n", buff.String())
+func TestIncludesParallel(t *testing.T) {
+	for i := 0; i < 10; i++ {
+		var buff bytes.Buffer
+		e := task.Executor{
+			Dir:    "testdata/includes_parallel",
+			Stdout: &buff,
+			Stderr: &buff,
+			Silent: true,
+		}
+		require.NoError(t, e.Setup())
+		// The tasks and vars are merged in the order of the includes, so the
+		// NAME of Two.yml is the global one
+		assert.Equal(t, []string{"default", "one:greet", "two:greet", "three:greet", "four:greet"}, e.Taskfile.Tasks.Keys())
+
+		require.NoError(t, e.Run(context.Background(), taskfile.Call{Task: "default"}))
+		assert.Equal(t, "One Two\nTwo Two\nOne three\nTwo four\n", buff.String())
+	}
 }
 
-func TestPOSIXShellOptsCommandLevel(t *testing.T) {
+func TestOverrideTaskfile(t *testing.T) {
 	var buff bytes.Buffer
 	e := task.Executor{
-		Dir:    "testdata/shopts/command_level",
+		Dir:    "testdata/override",
 		Stdout: &buff,
 		Stderr: &buff,
+		Silent: true,
 	}
 	require.NoError(t, e.Setup())
+	require.NoError(t, e.Run(context.Background(), taskfile.Call{Task: "default"}, taskfile.Call{Task: "build"}, taskfile.Call{Task: "mine"}))
+	assert.Equal(t, "default local\nlocal build\nmine\n", buff.String())
 
-	err := e.Run(context.Background(), taskfile.Call{Task: "pipefail"})
-	require.NoError(t, err)
-	assert.Equal(t, "pipefail\ton\n", buff.String())
+	// Another Taskfile given with --taskfile isn't overridden
+	buff.Reset()
+	e = task.Executor{
+		Dir:        "testdata/override",
+		Entrypoint: "Taskfile.override.yml",
+		Stdout:     &buff,
+		Stderr:     &buff,
+		Silent:     true,
+	}
+	require.NoError(t, e.Setup())
+	assert.Nil(t, e.Taskfile.Tasks.Get("default"))
+
+	e = task.Executor{
+		Dir:    "testdata/override/dotenv",
+		Stdout: io.Discard,
+		Stderr: io.Discard,
+	}
+	assert.ErrorContains(t, e.Setup(), "can't have dotenv declarations")
 }
 
-func TestBashShellOptsGlobalLevel(t *testing.T) {
+func TestJSONAndTOMLTaskfiles(t *testing.T) {
 	var buff bytes.Buffer
 	e := task.Executor{
-		Dir:    "testdata/shopts/global_level",
+		Dir:    "testdata/json_toml",
 		Stdout: &buff,
 		Stderr: &buff,
+		Silent: true,
 	}
 	require.NoError(t, e.Setup())
-
-	err := e.Run(context.Background(), taskfile.Call{Task: "globstar"})
-	require.NoError(t, err)
-	assert.Equal(t, "globstar\ton\n", buff.String())
+	assert.Equal(t, []string{"default", "after", "lib:zeta", "lib:greet", "lib:alpha"}, e.Taskfile.Tasks.Keys())
+	assert.Equal(t, "Greets from TOML", e.Taskfile.Tasks.Get("lib:greet").Desc)
+	require.NoError(t, e.Run(context.Background(), taskfile.Call{Task: "default"}))
+	assert.Equal(t, "hello json\ngreet 3\nzeta\n", buff.String())
 }
 
-func TestBashShellOptsTaskLevel(t *testing.T) {
+func TestPlan(t *testing.T) {
 	var buff bytes.Buffer
 	e := task.Executor{
-		Dir:    "testdata/shopts/task_level",
+		Dir:    "testdata/plan",
 		Stdout: &buff,
 		Stderr: &buff,
 	}
 	require.NoError(t, e.Setup())
+	plan, err := e.Plan(context.Background(), taskfile.Call{Task: "default", Direct: true}, taskfile.Call{Task: "generated", Direct: true})
+	require.NoError(t, err)
+	assert.Empty(t, buff.String())
 
-	err := e.Run(context.Background(), taskfile.Call{Task: "globstar"})
+	dir, err := filepath.Abs("testdata/plan")
 	require.NoError(t, err)
-	assert.Equal(t, "globstar\ton\n", buff.String())
+	assert.Equal(t, &task.Plan{Tasks: []*task.PlannedTask{
+		{Task: "setup", Dir: filepath.Join(dir, "sub"), Env: map[string]string{"MODE": "dev"}, Cmds: []*task.PlannedCmd{{Cmd: "echo setup"}}},
+		{Task: "setup", Dir: filepath.Join(dir, "sub"), Skipped: task.PlanSkippedRunOnce},
+		{
+			Task:  "default",
+			Label: "main",
+			Dir:   dir,
+			Env:   map[string]string{"MODE": "dev"},
+			Deps:  []string{"setup", "setup"},
+			Cmds: []*task.PlannedCmd{
+				{Cmd: "echo build"},
+				{Task: "skipped"},
+				{Cmd: "echo never", Skipped: task.PlanSkippedPlatform},
+				{Cmd: "echo cleanup", Deferred: true},
+			},
+		},
+		{Task: "skipped", Dir: dir, Env: map[string]string{"MODE": "dev"}, Skipped: task.PlanSkippedPrecondition, Message: "not now"},
+		{Task: "generated", Dir: dir, Env: map[string]string{"MODE": "dev"}, Skipped: task.PlanSkippedUpToDate},
+	}}, plan)
+	_, err = os.Stat("testdata/plan/sub")
+	assert.True(t, os.IsNotExist(err), "planning must not create the dir of the tasks")
 }
 
-func TestBashShellOptsCommandLevel(t *testing.T) {
+func TestStatusWithSources(t *testing.T) {
+	const dir = "testdata/status_sources"
+	for _, f := range []string{"out.txt", "marker.txt", ".task"} {
+		require.NoError(t, os.RemoveAll(filepathext.SmartJoin(dir, f)))
+	}
+	t.Cleanup(func() {
+		for _, f := range []string{"out.txt", "marker.txt"} {
+			_ = os.Remove(filepathext.SmartJoin(dir, f))
+		}
+	})
+
 	var buff bytes.Buffer
-	e := task.Executor{
-		Dir:    "testdata/shopts/command_level",
+	e := &task.Executor{
+		Dir:    dir,
 		Stdout: &buff,
 		Stderr: &buff,
+		Silent: true,
 	}
 	require.NoError(t, e.Setup())
+	run := func() string {
+		buff.Reset()
+		require.NoError(t, e.Run(context.Background(), taskfile.Call{Task: "build"}))
+		return buff.String()
+	}
 
-	err := e.Run(context.Background(), taskfile.Call{Task: "globstar"})
-	require.NoError(t, err)
-	assert.Equal(t, "globstar\ton\n", buff.String())
+	assert.Equal(t, "built\n", run())
+	assert.Equal(t, "", run(), "both the sources and the status are up-to-date")
+
+	// The checksum of the sources still matches, but the status fails
+	require.NoError(t, os.Remove(filepathext.SmartJoin(dir, "marker.txt")))
+	assert.Equal(t, "built\n", run())
+	assert.Equal(t, "", run())
+
+	// The status passes, but the sources changed
+	require.NoError(t, os.WriteFile(filepathext.SmartJoin(dir, "src.txt"), []byte("changed\n"), 0o644))
+	t.Cleanup(func() { _ = os.WriteFile(filepathext.SmartJoin(dir, "src.txt"), []byte("source\n"), 0o644) })
+	assert.Equal(t, "built\n", run())
+	assert.Equal(t, "", run())
 }
 
-func TestSplitArgs(t *testing.T) {
+func TestChecksumPerVars(t *testing.T) {
+	const dir = "testdata/checksum_vars"
+	require.NoError(t, os.RemoveAll(filepathext.SmartJoin(dir, ".task")))
+
 	var buff bytes.Buffer
-	e := task.Executor{
-		Dir:    "testdata/split_args",
+	e := &task.Executor{
+		Dir:    dir,
 		Stdout: &buff,
 		Stderr: &buff,
 		Silent: true,
 	}
-	require.NoError(t, e.Setup())
+	require.NoError(t, e.Setup())
+	run := func(image string) string {
+		buff.Reset()
+		call := taskfile.Call{Task: "build"}
+		if image != "" {
+			call.Vars = &taskfile.Vars{}
+			call.Vars.Set("IMAGE", taskfile.Var{Static: image})
+		}
+		require.NoError(t, e.Run(context.Background(), call))
+		return buff.String()
+	}
 
-	vars := &taskfile.Vars{}
-	vars.Set("CLI_ARGS", taskfile.Var{Static: "foo bar 'foo bar baz'"})
+	assert.Equal(t, "build foo\n", run(""))
+	assert.Equal(t, "build bar\n", run("bar"))
+	assert.Equal(t, "", run("foo"), "the default value is the same as foo")
+	assert.Equal(t, "", run("bar"))
 
-	err := e.Run(context.Background(), taskfile.Call{Task: "default", Vars: vars})
+	files, err := os.ReadDir(filepathext.SmartJoin(dir, ".task/checksum"))
 	require.NoError(t, err)
-	assert.Equal(t, "3\n", buff.String())
+	var checksums int
+	for _, f := range files {
+		if !strings.HasSuffix(f.Name(), ".sources") {
+			checksums++
+		}
+	}
+	assert.Equal(t, 2, checksums)
+
+	// The state of both values is removed, whatever the value cleaned with
+	require.NoError(t, e.Clean(context.Background(), taskfile.Call{Task: "build"}))
+	files, err = os.ReadDir(filepathext.SmartJoin(dir, ".task/checksum"))
+	require.NoError(t, err)
+	assert.Empty(t, files)
 }
 
-func TestSingleCmdDep(t *testing.T) {
-	tt := fileContentTest{
-		Dir:    "testdata/single_cmd_dep",
-		Target: "foo",
-		Files: map[string]string{
-			"foo.txt": "foo\n",
-			"bar.txt": "bar\n",
-		},
-	}
-	tt.Run(t)
+func TestVersion(t *testing.T) {
+	v := task.Version()
+	assert.NotEmpty(t, v.Version)
+	assert.Equal(t, runtime.Version(), v.GoVersion)
+	assert.Equal(t, experiments.Enabled() != nil, len(v.Experiments) > 0)
+
+	b, err := json.Marshal(v)
+	require.NoError(t, err)
+	var fields map[string]any
+	require.NoError(t, json.Unmarshal(b, &fields))
+	assert.Contains(t, fields, "version")
+	assert.Contains(t, fields, "go_version")
+	assert.IsType(t, []any{}, fields["experiments"], "the experiments are always a list")
 }
 
-func TestSilence(t *testing.T) {
+func TestRunWithResults(t *testing.T) {
 	var buff bytes.Buffer
-	e := task.Executor{
-		Dir:    "testdata/silent",
-		Stdout: &buff,
-		Stderr: &buff,
-		Silent: false,
+	e := &task.Executor{
+		Dir:        "testdata/run_results",
+		Stdout:     &buff,
+		Stderr:     &buff,
+		Silent:     true,
+		Parallel:   true,
+		NoFailFast: true,
 	}
 	require.NoError(t, e.Setup())
 
-	// First verify that the silent flag is in place.
-	task, err := e.GetTask(taskfile.Call{Task: "task-test-silent-calls-chatty-silenced"})
-	require.NoError(t, err, "Unable to look up task task-test-silent-calls-chatty-silenced")
-	require.True(t, task.Cmds[0].Silent, "The task task-test-silent-calls-chatty-silenced should have a silent call to chatty")
+	results, err := e.RunWithResults(context.Background(),
+		taskfile.Call{Task: "ok"},
+		taskfile.Call{Task: "fail"},
+		taskfile.Call{Task: "up-to-date"},
+	)
+	require.Error(t, err)
+	require.Len(t, results, 3)
 
-	// Then test the two basic cases where the task is silent or not.
-	// A silenced task.
-	err = e.Run(context.Background(), taskfile.Call{Task: "silent"})
-	require.NoError(t, err)
-	require.Empty(t, buff.String(), "siWhile running lent: Expected not see output, because the task is silent")
+	assert.Equal(t, "ok", results[0].Task)
+	assert.Equal(t, task.TaskStateCompleted, results[0].State)
+	assert.Zero(t, results[0].ExitCode)
+	assert.NoError(t, results[0].Err)
+	assert.Positive(t, results[0].Duration)
 
-	buff.Reset()
+	assert.Equal(t, task.TaskStateFailed, results[1].State)
+	assert.Equal(t, 3, results[1].ExitCode)
+	assert.Error(t, results[1].Err)
 
-	// A chatty (not silent) task.
-	err = e.Run(context.Background(), taskfile.Call{Task: "chatty"})
-	require.NoError(t, err)
-	require.NotEmpty(t, buff.String(), "chWhile running atty: Expected to see output, because the task is not silent")
+	assert.Equal(t, task.TaskStateSkipped, results[2].State)
+	assert.True(t, results[2].UpToDate)
 
-	buff.Reset()
+	assert.Equal(t, task.Results{results[1]}, results.Failed())
+	assert.Len(t, e.LastRunReport().Tasks, 4, "the deps are only in the report")
 
-	// Then test invoking the two task from other tasks.
-	// A silenced task that calls a chatty task.
-	err = e.Run(context.Background(), taskfile.Call{Task: "task-test-silent-calls-chatty-non-silenced"})
-	require.NoError(t, err)
-	require.NotEmpty(t, buff.String(), "While running task-test-silent-calls-chatty-non-silenced: Expected to see output. The task is silenced, but the called task is not. Silence does not propagate to called tasks.")
+	// Nothing runs when a task doesn't exist
+	results, err = e.RunWithResults(context.Background(), taskfile.Call{Task: "ok"}, taskfile.Call{Task: "missing"})
+	require.Error(t, err)
+	assert.Equal(t, task.TaskStateSkipped, results[0].State)
+	assert.Equal(t, task.TaskStateFailed, results[1].State)
+	assert.Equal(t, errors.CodeTaskNotFound, results[1].ExitCode)
+}
 
-	buff.Reset()
+type closingBuffer struct {
+	bytes.Buffer
+	closed int
+}
 
-	// A silent task that does a silent call to a chatty task.
-	err = e.Run(context.Background(), taskfile.Call{Task: "task-test-silent-calls-chatty-silenced"})
-	require.NoError(t, err)
-	require.Empty(t, buff.String(), "While running task-test-silent-calls-chatty-silenced: Expected not to see output. The task calls chatty task, but the call is silenced.")
+func (b *closingBuffer) Close() error {
+	b.closed++
+	return nil
+}
 
-	buff.Reset()
+func TestTaskWriters(t *testing.T) {
+	var buff bytes.Buffer
+	var mutex sync.Mutex
+	writers := map[string]*closingBuffer{}
+	stderr := &closingBuffer{}
+	e := task.NewExecutor(
+		task.WithDir("testdata/task_writers"),
+		task.WithStdout(&buff),
+		task.WithStderr(&buff),
+		task.WithSilent(true),
+		task.WithTaskWriters(func(taskName string) (io.Writer, io.Writer) {
+			mutex.Lock()
+			defer mutex.Unlock()
+			w := &closingBuffer{}
+			writers[taskName] = w
+			if taskName == "default" {
+				return w, stderr
+			}
+			return w, nil
+		}),
+	)
+	require.NoError(t, e.Run(context.Background(), taskfile.Call{Task: "default"}))
 
-	// A chatty task that does a call to a chatty task.
-	err = e.Run(context.Background(), taskfile.Call{Task: "task-test-chatty-calls-chatty-non-silenced"})
-	require.NoError(t, err)
-	require.NotEmpty(t, buff.String(), "While running task-test-chatty-calls-chatty-non-silenced: Expected to see output. Both caller and callee are chatty and not silenced.")
+	assert.Empty(t, buff.String())
+	require.Len(t, writers, 3)
+	assert.Equal(t, "default\n", writers["default"].String())
+	assert.Equal(t, "default error\n", stderr.String())
+	assert.Equal(t, "dep\n", writers["dep"].String())
+	assert.Equal(t, "called\n", writers["called"].String())
+	for name, w := range writers {
+		assert.Equal(t, 1, w.closed, name)
+	}
+	assert.Equal(t, 1, stderr.closed)
+}
 
-	buff.Reset()
+func TestServer(t *testing.T) {
+	var buff bytes.Buffer
+	e := task.NewExecutor(
+		task.WithDir("testdata/serve"),
+		task.WithStdout(&buff),
+		task.WithStderr(&buff),
+		task.WithSilent(true),
+	)
+	s := task.NewServer(e)
+	defer s.Close()
+	server := httptest.NewServer(s)
+	defer server.Close()
 
-	// A chatty task that does a silenced call to a chatty task.
-	err = e.Run(context.Background(), taskfile.Call{Task: "task-test-chatty-calls-chatty-silenced"})
+	resp, err := http.Get(server.URL + "/tasks")
 	require.NoError(t, err)
-	require.NotEmpty(t, buff.String(), "While running task-test-chatty-calls-chatty-silenced: Expected to see output. Call to a chatty task is silenced, but the parent task is not.")
+	var tasks editors.Taskfile
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&tasks))
+	resp.Body.Close()
+	require.Len(t, tasks.Tasks, 3)
+	assert.Equal(t, "fail", tasks.Tasks[0].Name)
+	assert.Equal(t, "hello", tasks.Tasks[1].Name)
+	assert.Equal(t, "Says hello", tasks.Tasks[1].Desc)
+
+	type run struct {
+		ID      int    `json:"id"`
+		Status  string `json:"status"`
+		Error   string `json:"error"`
+		Results []struct {
+			Task     string `json:"task"`
+			State    string `json:"state"`
+			ExitCode int    `json:"exit_code"`
+		} `json:"results"`
+	}
+	start := func(body string) run {
+		resp, err := http.Post(server.URL+"/runs", "application/json", strings.NewReader(body))
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		require.Equal(t, http.StatusAccepted, resp.StatusCode)
+		var r run
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&r))
+		return r
+	}
+	// The logs are streamed until the run finished
+	logs := func(id int) string {
+		resp, err := http.Get(fmt.Sprintf("%s/runs/%d/logs", server.URL, id))
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+		b, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		return string(b)
+	}
+	get := func(id int) run {
+		resp, err := http.Get(fmt.Sprintf("%s/runs/%d", server.URL, id))
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		var r run
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&r))
+		return r
+	}
+
+	hello := start(`{"calls": [{"task": "hello", "vars": {"NAME": "world"}}]}`)
+	assert.Equal(t, 1, hello.ID)
+	out := logs(hello.ID)
+	assert.Contains(t, out, "event: log\ndata: {\"task\":\"hello\",\"stream\":\"stdout\",\"line\":\"hello world\"}\n\n")
+	assert.Contains(t, out, "event: log\ndata: {\"task\":\"hello\",\"stream\":\"stderr\",\"line\":\"bye\"}\n\n")
+	assert.Contains(t, out, "event: end\n")
+	hello = get(hello.ID)
+	assert.Equal(t, "completed", hello.Status)
+	require.Len(t, hello.Results, 1)
+	assert.Equal(t, "completed", hello.Results[0].State)
+
+	fail := start(`{"calls": [{"task": "fail"}]}`)
+	logs(fail.ID)
+	fail = get(fail.ID)
+	assert.Equal(t, "failed", fail.Status)
+	assert.NotEmpty(t, fail.Error)
+	require.Len(t, fail.Results, 1)
+	assert.Equal(t, 3, fail.Results[0].ExitCode)
+
+	resp, err = http.Post(server.URL+"/runs", "application/json", strings.NewReader(`{"calls": []}`))
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
 
-	buff.Reset()
+	resp, err = http.Get(server.URL + "/runs/42")
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
 
-	// A chatty task with no cmd's of its own that does a silenced call to a chatty task.
-	err = e.Run(context.Background(), taskfile.Call{Task: "task-test-no-cmds-calls-chatty-silenced"})
+	resp, err = http.Get(server.URL + "/runs")
 	require.NoError(t, err)
-	require.Empty(t, buff.String(), "While running task-test-no-cmds-calls-chatty-silenced: Expected not to see output. While the task itself is not silenced, it does not have any cmds and only does an invocation of a silenced task.")
+	var runs []run
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&runs))
+	resp.Body.Close()
+	assert.Len(t, runs, 2)
+	assert.Empty(t, buff.String())
+}
 
-	buff.Reset()
+func TestServerHistory(t *testing.T) {
+	e := task.NewExecutor(
+		task.WithDir("testdata/serve"),
+		task.WithStdout(io.Discard),
+		task.WithStderr(io.Discard),
+		task.WithSilent(true),
+	)
+	s := task.NewServer(e)
+	s.MaxRuns = 2
+	s.MaxRunLogs = 1
+	defer s.Close()
+	server := httptest.NewServer(s)
+	defer server.Close()
+
+	type run struct {
+		ID          int    `json:"id"`
+		Status      string `json:"status"`
+		DroppedLogs int    `json:"dropped_logs"`
+	}
+	logs := func(id int) string {
+		resp, err := http.Get(fmt.Sprintf("%s/runs/%d/logs", server.URL, id))
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		b, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		return string(b)
+	}
+	for i := 1; i <= 3; i++ {
+		resp, err := http.Post(server.URL+"/runs", "application/json", strings.NewReader(`{"calls": [{"task": "hello"}]}`))
+		require.NoError(t, err)
+		resp.Body.Close()
+		require.Equal(t, http.StatusAccepted, resp.StatusCode)
+		logs(i)
+	}
 
-	// A chatty task that does a silenced invocation of a task.
-	err = e.Run(context.Background(), taskfile.Call{Task: "task-test-chatty-calls-silenced-cmd"})
+	resp, err := http.Get(server.URL + "/runs")
 	require.NoError(t, err)
-	require.Empty(t, buff.String(), "While running task-test-chatty-calls-silenced-cmd: Expected not to see output. While the task itself is not silenced, its call to the chatty task is silent.")
+	var runs []run
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&runs))
+	resp.Body.Close()
+	require.Len(t, runs, 2)
+	assert.Equal(t, 2, runs[0].ID)
+	assert.Equal(t, 3, runs[1].ID)
+	assert.Equal(t, 1, runs[1].DroppedLogs)
+
+	resp, err = http.Get(server.URL + "/runs/1")
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
 
-	buff.Reset()
+	out := logs(3)
+	assert.NotContains(t, out, `"line":"hello`)
+	assert.Contains(t, out, "event: log\ndata: {\"task\":\"hello\",\"stream\":\"stderr\",\"line\":\"bye\"}\n\n")
+}
 
-	// Then test calls via dependencies.
-	// A silent task that depends on a chatty task.
-	err = e.Run(context.Background(), taskfile.Call{Task: "task-test-is-silent-depends-on-chatty-non-silenced"})
-	require.NoError(t, err)
-	require.NotEmpty(t, buff.String(), "While running task-test-is-silent-depends-on-chatty-non-silenced: Expected to see output. The task is silent and depends on a chatty task. Dependencies does not inherit silence.")
+func TestServerRefusedRequests(t *testing.T) {
+	e := task.NewExecutor(
+		task.WithDir("testdata/serve"),
+		task.WithStdout(io.Discard),
+		task.WithStderr(io.Discard),
+		task.WithSilent(true),
+	)
+	s := task.NewServer(e)
+	defer s.Close()
+	server := httptest.NewServer(s)
+	defer server.Close()
 
-	buff.Reset()
+	do := func(modify func(r *http.Request)) int {
+		req, err := http.NewRequest(http.MethodPost, server.URL+"/runs", strings.NewReader(`{"calls": [{"task": "hello"}]}`))
+		require.NoError(t, err)
+		req.Header.Set("Content-Type", "application/json")
+		modify(req)
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	assert.Equal(t, http.StatusUnsupportedMediaType, do(func(r *http.Request) {
+		r.Header.Set("Content-Type", "text/plain")
+	}))
+	assert.Equal(t, http.StatusForbidden, do(func(r *http.Request) {
+		r.Header.Set("Origin", "https://example.com")
+	}))
+	assert.Equal(t, http.StatusForbidden, do(func(r *http.Request) {
+		r.Host = "example.com"
+	}))
+	assert.Equal(t, http.StatusAccepted, do(func(r *http.Request) {
+		r.Header.Set("Origin", server.URL)
+	}))
+
+	s.Token = "secret"
+	assert.Equal(t, http.StatusUnauthorized, do(func(r *http.Request) {}))
+	assert.Equal(t, http.StatusUnauthorized, do(func(r *http.Request) {
+		r.Header.Set("Authorization", "Bearer wrong")
+	}))
+	assert.Equal(t, http.StatusAccepted, do(func(r *http.Request) {
+		r.Header.Set("Authorization", "Bearer secret")
+		r.Host = "example.com"
+	}))
+
+	err := e.Serve(context.Background(), ":0", "")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "without a token")
+}
 
-	// A silent task that depends on a silenced chatty task.
-	err = e.Run(context.Background(), taskfile.Call{Task: "task-test-is-silent-depends-on-chatty-silenced"})
-	require.NoError(t, err)
-	require.Empty(t, buff.String(), "While running task-test-is-silent-depends-on-chatty-silenced: Expected not to see output. The task is silent and has a silenced dependency on a chatty task.")
+func TestStdioProtocol(t *testing.T) {
+	var buff bytes.Buffer
+	e := task.NewExecutor(
+		task.WithDir("testdata/serve"),
+		task.WithStdout(&buff),
+		task.WithStderr(&buff),
+		task.WithSilent(true),
+	)
+	inR, inW := io.Pipe()
+	outR, outW := io.Pipe()
+	done := make(chan error, 1)
+	go func() {
+		done <- e.ServeStdioProtocol(context.Background(), inR, outW)
+		outW.Close()
+	}()
+
+	type message struct {
+		ID     int             `json:"id"`
+		Method string          `json:"method"`
+		Params json.RawMessage `json:"params"`
+		Result json.RawMessage `json:"result"`
+		Error  *jsonrpc.Error  `json:"error"`
+	}
+	out := bufio.NewReader(outR)
+	read := func() message {
+		b, err := jsonrpc.ReadMessage(out)
+		require.NoError(t, err)
+		var m message
+		require.NoError(t, json.Unmarshal(b, &m))
+		return m
+	}
+	call := func(id int, method string, params any) message {
+		b, err := json.Marshal(params)
+		require.NoError(t, err)
+		req := jsonrpc.Request{ID: json.RawMessage(strconv.Itoa(id)), Method: method, Params: b}
+		require.NoError(t, jsonrpc.WriteMessage(inW, req))
+		m := read()
+		require.Equal(t, id, m.ID)
+		return m
+	}
+
+	m := call(1, "listTasks", nil)
+	require.Nil(t, m.Error)
+	var tasks editors.Taskfile
+	require.NoError(t, json.Unmarshal(m.Result, &tasks))
+	assert.Len(t, tasks.Tasks, 3)
+
+	m = call(2, "runTask", map[string]any{"task": "hello", "vars": map[string]string{"NAME": "editor"}})
+	require.Nil(t, m.Error)
+	var run struct {
+		ID     int    `json:"id"`
+		Status string `json:"status"`
+	}
+	require.NoError(t, json.Unmarshal(m.Result, &run))
+	assert.Equal(t, 1, run.ID)
+
+	var logs []string
+	for run.Status != "completed" && run.Status != "failed" {
+		m = read()
+		switch m.Method {
+		case "log":
+			var l struct {
+				Run    int    `json:"run"`
+				Stream string `json:"stream"`
+				Line   string `json:"line"`
+			}
+			require.NoError(t, json.Unmarshal(m.Params, &l))
+			assert.Equal(t, 1, l.Run)
+			logs = append(logs, l.Stream+": "+l.Line)
+		case "run":
+			require.NoError(t, json.Unmarshal(m.Params, &run))
+		default:
+			t.Fatalf("unexpected message %+v", m)
+		}
+	}
+	assert.Equal(t, "completed", run.Status)
+	assert.Equal(t, []string{"stdout: hello editor", "stderr: bye"}, logs)
 
-	buff.Reset()
+	m = call(3, "getPlan", map[string]any{"task": "hello"})
+	require.Nil(t, m.Error)
+	assert.Contains(t, string(m.Result), "echo hello")
 
-	// A chatty task that, depends on a silenced chatty task.
-	err = e.Run(context.Background(), taskfile.Call{Task: "task-test-is-chatty-depends-on-chatty-silenced"})
-	require.NoError(t, err)
-	require.Empty(t, buff.String(), "While running task-test-is-chatty-depends-on-chatty-silenced: Expected not to see output. The task is chatty but does not have commands and has a silenced dependency on a chatty task.")
+	m = call(4, "getPlan", map[string]any{"task": "missing"})
+	require.NotNil(t, m.Error)
+	assert.Equal(t, jsonrpc.CodeRequestFailed, m.Error.Code)
 
-	buff.Reset()
+	m = call(5, "getRun", map[string]any{"id": 42})
+	require.NotNil(t, m.Error)
+
+	m = call(6, "unknown", nil)
+	require.NotNil(t, m.Error)
+	assert.Equal(t, jsonrpc.CodeMethodNotFound, m.Error.Code)
+
+	inW.Close()
+	require.NoError(t, <-done)
+	assert.Empty(t, buff.String())
 }
 
-func TestForce(t *testing.T) {
-	tests := []struct {
-		name     string
-		env      map[string]string
-		force    bool
-		forceAll bool
-	}{
-		{
-			name:  "force",
-			force: true,
-		},
-		{
-			name:     "force-all",
-			forceAll: true,
-		},
-		{
-			name:  "force with gentle force experiment",
-			force: true,
-			env: map[string]string{
-				"TASK_X_GENTLE_FORCE": "1",
-			},
-		},
-		{
-			name:     "force-all with gentle force experiment",
-			forceAll: true,
-			env: map[string]string{
-				"TASK_X_GENTLE_FORCE": "1",
-			},
-		},
+// TestStdioProtocolDuringRun lists and plans the tasks while a run executes,
+// as they're compiled by the same Executor. Run it with -race.
+func TestStdioProtocolDuringRun(t *testing.T) {
+	e := task.NewExecutor(
+		task.WithDir("testdata/serve"),
+		task.WithStdout(io.Discard),
+		task.WithStderr(io.Discard),
+		task.WithSilent(true),
+	)
+	inR, inW := io.Pipe()
+	outR, outW := io.Pipe()
+	done := make(chan error, 1)
+	go func() {
+		done <- e.ServeStdioProtocol(context.Background(), inR, outW)
+		outW.Close()
+	}()
+
+	type message struct {
+		ID     int             `json:"id"`
+		Method string          `json:"method"`
+		Params json.RawMessage `json:"params"`
+		Result json.RawMessage `json:"result"`
+		Error  *jsonrpc.Error  `json:"error"`
+	}
+	out := bufio.NewReader(outR)
+	read := func() message {
+		b, err := jsonrpc.ReadMessage(out)
+		require.NoError(t, err)
+		var m message
+		require.NoError(t, json.Unmarshal(b, &m))
+		return m
 	}
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			var buff bytes.Buffer
-			e := task.Executor{
-				Dir:      "testdata/force",
-				Stdout:   &buff,
-				Stderr:   &buff,
-				Force:    tt.force,
-				ForceAll: tt.forceAll,
+	// The notifications of the run may come before the response
+	call := func(id int, method string, params any) message {
+		b, err := json.Marshal(params)
+		require.NoError(t, err)
+		req := jsonrpc.Request{ID: json.RawMessage(strconv.Itoa(id)), Method: method, Params: b}
+		require.NoError(t, jsonrpc.WriteMessage(inW, req))
+		for {
+			if m := read(); m.Method == "" {
+				require.Equal(t, id, m.ID)
+				return m
 			}
-			require.NoError(t, e.Setup())
-			require.NoError(t, e.Run(context.Background(), taskfile.Call{Task: "task-with-dep", Direct: true}))
-		})
+		}
+	}
+
+	m := call(1, "runTask", map[string]any{"task": "wait"})
+	require.Nil(t, m.Error)
+	for {
+		m = read()
+		if m.Method == "log" && strings.Contains(string(m.Params), `"line":"started"`) {
+			break
+		}
+	}
+
+	for id := 2; id < 12; id += 2 {
+		m = call(id, "listTasks", nil)
+		require.Nil(t, m.Error)
+		var tasks editors.Taskfile
+		require.NoError(t, json.Unmarshal(m.Result, &tasks))
+		assert.Len(t, tasks.Tasks, 3)
+
+		m = call(id+1, "getPlan", map[string]any{"task": "hello", "vars": map[string]string{"NAME": "plan"}})
+		require.Nil(t, m.Error)
+		assert.Contains(t, string(m.Result), "echo hello plan")
 	}
+
+	m = call(12, "getRun", map[string]any{"id": 1})
+	require.Nil(t, m.Error)
+	assert.Contains(t, string(m.Result), `"status":"running"`)
+
+	m = call(13, "cancel", map[string]any{"id": 1})
+	require.Nil(t, m.Error)
+	inW.Close()
+	require.NoError(t, <-done)
 }
 
-func TestFor(t *testing.T) {
-	tests := []struct {
-		name           string
-		expectedOutput string
-	}{
-		{
-			name:           "loop-explicit",
-			expectedOutput: "a\nb\nc\n",
-		},
-		{
-			name:           "loop-sources",
-			expectedOutput: "bar\nfoo\n",
-		},
-		{
-			name:           "loop-sources-glob",
-			expectedOutput: "bar\nfoo\n",
-		},
-		{
-			name:           "loop-vars",
-			expectedOutput: "foo\nbar\n",
-		},
-		{
-			name:           "loop-vars-sh",
-			expectedOutput: "bar\nfoo\n",
-		},
-		{
-			name:           "loop-task",
-			expectedOutput: "foo\nbar\n",
-		},
-		{
-			name:           "loop-task-as",
-			expectedOutput: "foo\nbar\n",
-		},
-		{
-			name:           "loop-different-tasks",
-			expectedOutput: "1\n2\n3\n",
-		},
+func TestScheduler(t *testing.T) {
+	var buff syncBuffer
+	e := task.NewExecutor(
+		task.WithDir("testdata/scheduler"),
+		task.WithStdout(&buff),
+		task.WithStderr(&buff),
+		task.WithSilent(true),
+	)
+	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
+
+	events := map[string]map[string]int{}
+	var exitCodes []int
+	err := e.RunScheduler(ctx, func(event task.SchedulerEvent) {
+		if events[event.Task] == nil {
+			events[event.Task] = map[string]int{}
+		}
+		events[event.Task][event.Event]++
+		if event.Event == task.SchedulerEventFailed {
+			exitCodes = append(exitCodes, event.ExitCode)
+		}
+	})
+	require.NoError(t, err)
+
+	assert.NotContains(t, events, "unscheduled")
+	assert.GreaterOrEqual(t, events["tick"][task.SchedulerEventFinished], 2)
+	assert.Contains(t, buff.String(), "tick\n")
+	assert.NotContains(t, buff.String(), "never")
+	// Every run has its own tasks that run once
+	assert.GreaterOrEqual(t, strings.Count(buff.String(), "once\n"), events["tick"][task.SchedulerEventFinished])
+
+	// The runs due while the previous one is running are skipped or queued
+	assert.Positive(t, events["skipped"][task.SchedulerEventSkipped])
+	assert.Zero(t, events["skipped"][task.SchedulerEventQueued])
+	assert.Positive(t, events["queued"][task.SchedulerEventQueued])
+	assert.Zero(t, events["queued"][task.SchedulerEventSkipped])
+
+	assert.GreaterOrEqual(t, events["fail"][task.SchedulerEventFailed], 2)
+	for _, code := range exitCodes {
+		assert.Equal(t, 2, code)
 	}
 
-	for _, test := range tests {
-		t.Run(test.name, func(t *testing.T) {
-			var buff bytes.Buffer
-			e := task.Executor{
-				Dir:    "testdata/for",
-				Stdout: &buff,
-				Stderr: &buff,
-				Silent: true,
-				Force:  true,
-			}
-			require.NoError(t, e.Setup())
-			require.NoError(t, e.Run(context.Background(), taskfile.Call{Task: test.name, Direct: true}))
-			assert.Equal(t, test.expectedOutput, buff.String())
-		})
+	// Every run started finished once the scheduler stopped
+	for name, e := range events {
+		assert.Equal(t, e[task.SchedulerEventStarted], e[task.SchedulerEventFinished]+e[task.SchedulerEventFailed]+e[task.SchedulerEventInterrupted], name)
 	}
+
+	e = task.NewExecutor(task.WithDir("testdata/serve"), task.WithStdout(io.Discard), task.WithStderr(io.Discard))
+	assert.EqualError(t, e.RunScheduler(context.Background(), nil), "task: No task has a schedule")
+}
+
+// syncBuffer is a bytes.Buffer that can be written by the running tasks while
+// the test reads it.
+type syncBuffer struct {
+	mutex sync.Mutex
+	buff  bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	return b.buff.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	return b.buff.String()
 }
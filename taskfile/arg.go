@@ -0,0 +1,103 @@
+package taskfile
+
+import (
+	"fmt"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Arg is a positional argument of a task. The words given after the name of
+// the task on the command line are set to the variables named after its args,
+// in order. A variadic arg takes all the remaining words, as a list.
+type Arg struct {
+	Name     string `schema:",required"`
+	Desc     string
+	Required bool
+	Variadic bool
+}
+
+func (a *Arg) DeepCopy() *Arg {
+	if a == nil {
+		return nil
+	}
+	return &Arg{
+		Name:     a.Name,
+		Desc:     a.Desc,
+		Required: a.Required,
+		Variadic: a.Variadic,
+	}
+}
+
+func (a *Arg) UnmarshalYAML(node *yaml.Node) error {
+	switch node.Kind {
+
+	// Just the name of an optional arg
+	case yaml.ScalarNode:
+		var name string
+		if err := node.Decode(&name); err != nil {
+			return err
+		}
+		a.Name = name
+		return nil
+
+	case yaml.MappingNode:
+		var arg struct {
+			Name     string
+			Desc     string
+			Required bool
+			Variadic bool
+		}
+		if err := node.Decode(&arg); err != nil {
+			return err
+		}
+		if arg.Name == "" {
+			return fmt.Errorf("yaml: line %d: task argument must have a name", node.Line)
+		}
+		a.Name = arg.Name
+		a.Desc = arg.Desc
+		a.Required = arg.Required
+		a.Variadic = arg.Variadic
+		return nil
+	}
+
+	return fmt.Errorf("yaml: line %d: cannot unmarshal %s into task argument", node.Line, node.ShortTag())
+}
+
+// validateArgs checks that only the last arg of a task is variadic and that
+// no required arg comes after an optional one, since the words couldn't be
+// told apart otherwise.
+func validateArgs(node *yaml.Node, args []*Arg) error {
+	var optional string
+	for i, arg := range args {
+		if arg.Variadic && i != len(args)-1 {
+			return fmt.Errorf("yaml: line %d: only the last argument of a task can be variadic, not %q", node.Line, arg.Name)
+		}
+		if arg.Required && optional != "" {
+			return fmt.Errorf("yaml: line %d: required argument %q can't come after the optional %q", node.Line, arg.Name, optional)
+		}
+		if !arg.Required {
+			optional = arg.Name
+		}
+	}
+	return nil
+}
+
+// ArgsUsage returns the usage of the args of a task, like
+// "<target> [flags...]".
+func ArgsUsage(args []*Arg) string {
+	words := make([]string, 0, len(args))
+	for _, arg := range args {
+		word := arg.Name
+		if arg.Variadic {
+			word += "..."
+		}
+		if arg.Required {
+			word = "<" + word + ">"
+		} else {
+			word = "[" + word + "]"
+		}
+		words = append(words, word)
+	}
+	return strings.Join(words, " ")
+}
@@ -0,0 +1,43 @@
+package taskfile_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+
+	"github.com/nuvolaris/task/v3/taskfile"
+)
+
+func TestArgsParse(t *testing.T) {
+	const content = `
+args:
+  - target
+  - name: services
+    desc: The services to deploy
+    variadic: true
+`
+	var task taskfile.Task
+	require.NoError(t, yaml.Unmarshal([]byte(content), &task))
+	assert.Equal(t, []*taskfile.Arg{
+		{Name: "target"},
+		{Name: "services", Desc: "The services to deploy", Variadic: true},
+	}, task.Args)
+	assert.Equal(t, "[target] [services...]", taskfile.ArgsUsage(task.Args))
+
+	tests := []struct {
+		content string
+		err     string
+	}{
+		{"args: [{name: a, variadic: true}, b]", `only the last argument of a task can be variadic, not "a"`},
+		{"args: [a, {name: b, required: true}]", `required argument "b" can't come after the optional "a"`},
+		{"args: [{desc: nameless}]", "task argument must have a name"},
+	}
+	for _, test := range tests {
+		var task taskfile.Task
+		err := yaml.Unmarshal([]byte(test.content), &task)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), test.err)
+	}
+}
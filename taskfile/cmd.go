@@ -10,16 +10,20 @@ import (
 
 // Cmd is a task command
 type Cmd struct {
-	Cmd         string
-	Task        string
-	For         *For
-	Silent      bool
-	Set         []string
-	Shopt       []string
-	Vars        *Vars
-	IgnoreError bool
-	Defer       bool
-	Platforms   []*Platform
+	Cmd            string
+	Task           string
+	For            *For
+	Parallel       int
+	Silent         bool
+	Set            []string
+	Shopt          []string
+	Vars           *Vars
+	ForwardCLIArgs bool
+	IgnoreError    bool
+	Defer          bool
+	Platforms      []*Platform
+	// Location is where the command is written, set when its task is read
+	Location *Location `schema:"-"`
 }
 
 func (c *Cmd) DeepCopy() *Cmd {
@@ -27,16 +31,19 @@ func (c *Cmd) DeepCopy() *Cmd {
 		return nil
 	}
 	return &Cmd{
-		Cmd:         c.Cmd,
-		Task:        c.Task,
-		For:         c.For.DeepCopy(),
-		Silent:      c.Silent,
-		Set:         deepcopy.Slice(c.Set),
-		Shopt:       deepcopy.Slice(c.Shopt),
-		Vars:        c.Vars.DeepCopy(),
-		IgnoreError: c.IgnoreError,
-		Defer:       c.Defer,
-		Platforms:   deepcopy.Slice(c.Platforms),
+		Cmd:            c.Cmd,
+		Task:           c.Task,
+		For:            c.For.DeepCopy(),
+		Parallel:       c.Parallel,
+		Silent:         c.Silent,
+		Set:            deepcopy.Slice(c.Set),
+		Shopt:          deepcopy.Slice(c.Shopt),
+		Vars:           c.Vars.DeepCopy(),
+		ForwardCLIArgs: c.ForwardCLIArgs,
+		IgnoreError:    c.IgnoreError,
+		Defer:          c.Defer,
+		Platforms:      deepcopy.Slice(c.Platforms),
+		Location:       c.Location.DeepCopy(),
 	}
 }
 
@@ -57,6 +64,7 @@ func (c *Cmd) UnmarshalYAML(node *yaml.Node) error {
 		var cmdStruct struct {
 			Cmd         string
 			For         *For
+			Parallel    int
 			Silent      bool
 			Set         []string
 			Shopt       []string
@@ -64,8 +72,12 @@ func (c *Cmd) UnmarshalYAML(node *yaml.Node) error {
 			Platforms   []*Platform
 		}
 		if err := node.Decode(&cmdStruct); err == nil && cmdStruct.Cmd != "" {
+			if err := checkParallel(node, cmdStruct.For, cmdStruct.Parallel); err != nil {
+				return err
+			}
 			c.Cmd = cmdStruct.Cmd
 			c.For = cmdStruct.For
+			c.Parallel = cmdStruct.Parallel
 			c.Silent = cmdStruct.Silent
 			c.Set = cmdStruct.Set
 			c.Shopt = cmdStruct.Shopt
@@ -97,16 +109,25 @@ func (c *Cmd) UnmarshalYAML(node *yaml.Node) error {
 
 		// A task call
 		var taskCall struct {
-			Task   string
-			Vars   *Vars
-			For    *For
-			Silent bool
+			Task           string
+			Vars           *Vars
+			For            *For
+			Parallel       int
+			Silent         bool
+			ForwardCLIArgs bool `yaml:"forward_cli_args"`
+			IgnoreError    bool `yaml:"ignore_error"`
 		}
 		if err := node.Decode(&taskCall); err == nil && taskCall.Task != "" {
+			if err := checkParallel(node, taskCall.For, taskCall.Parallel); err != nil {
+				return err
+			}
 			c.Task = taskCall.Task
 			c.Vars = taskCall.Vars
 			c.For = taskCall.For
+			c.Parallel = taskCall.Parallel
 			c.Silent = taskCall.Silent
+			c.ForwardCLIArgs = taskCall.ForwardCLIArgs
+			c.IgnoreError = taskCall.IgnoreError
 			return nil
 		}
 
@@ -115,3 +136,13 @@ func (c *Cmd) UnmarshalYAML(node *yaml.Node) error {
 
 	return fmt.Errorf("yaml: line %d: cannot unmarshal %s into command", node.Line, node.ShortTag())
 }
+
+func checkParallel(node *yaml.Node, f *For, parallel int) error {
+	if parallel < 0 {
+		return fmt.Errorf("yaml: line %d: parallel can't be negative, got %d", node.Line, parallel)
+	}
+	if parallel > 0 && f == nil {
+		return fmt.Errorf("yaml: line %d: parallel only applies to commands with for", node.Line)
+	}
+	return nil
+}
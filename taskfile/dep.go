@@ -8,9 +8,11 @@ import (
 
 // Dep is a task dependency
 type Dep struct {
-	Task   string
-	Vars   *Vars
-	Silent bool
+	Task           string
+	Vars           *Vars
+	Silent         bool
+	ForwardCLIArgs bool
+	IgnoreError    bool
 }
 
 func (d *Dep) DeepCopy() *Dep {
@@ -18,8 +20,11 @@ func (d *Dep) DeepCopy() *Dep {
 		return nil
 	}
 	return &Dep{
-		Task: d.Task,
-		Vars: d.Vars.DeepCopy(),
+		Task:           d.Task,
+		Vars:           d.Vars.DeepCopy(),
+		Silent:         d.Silent,
+		ForwardCLIArgs: d.ForwardCLIArgs,
+		IgnoreError:    d.IgnoreError,
 	}
 }
 
@@ -36,9 +41,11 @@ func (d *Dep) UnmarshalYAML(node *yaml.Node) error {
 
 	case yaml.MappingNode:
 		var taskCall struct {
-			Task   string
-			Vars   *Vars
-			Silent bool
+			Task           string
+			Vars           *Vars
+			Silent         bool
+			ForwardCLIArgs bool `yaml:"forward_cli_args"`
+			IgnoreError    bool `yaml:"ignore_error"`
 		}
 		if err := node.Decode(&taskCall); err != nil {
 			return err
@@ -46,6 +53,8 @@ func (d *Dep) UnmarshalYAML(node *yaml.Node) error {
 		d.Task = taskCall.Task
 		d.Vars = taskCall.Vars
 		d.Silent = taskCall.Silent
+		d.ForwardCLIArgs = taskCall.ForwardCLIArgs
+		d.IgnoreError = taskCall.IgnoreError
 		return nil
 	}
 
@@ -0,0 +1,158 @@
+package taskfile
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"runtime"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/nuvolaris/task/v3/internal/deepcopy"
+)
+
+// HermeticAllow are the environment variables inherited in hermetic mode
+// when the env_policy of the Taskfile has no allowlist. They're the ones most
+// tools can't work without.
+var HermeticAllow = []string{
+	"PATH",
+	"HOME",
+	"USER",
+	"LOGNAME",
+	"SHELL",
+	"TERM",
+	"TMPDIR",
+	"TMP",
+	"TEMP",
+	"LANG",
+	"LC_*",
+	"TZ",
+	// Windows
+	"SYSTEMROOT",
+	"SYSTEMDRIVE",
+	"WINDIR",
+	"COMSPEC",
+	"PATHEXT",
+	"USERPROFILE",
+	"APPDATA",
+	"LOCALAPPDATA",
+	"PROGRAMDATA",
+	"PROGRAMFILES",
+}
+
+// EnvPolicy limits the environment inherited by the commands, so they run the
+// same on every machine. The names of the variables can have "*" wildcards.
+type EnvPolicy struct {
+	// Allow, when not nil, is the only variables inherited.
+	Allow []string
+	// Deny are never inherited, even if allowed.
+	Deny []string
+}
+
+func (p *EnvPolicy) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind != yaml.MappingNode {
+		return fmt.Errorf("yaml: line %d: cannot unmarshal %s into env policy", node.Line, node.ShortTag())
+	}
+	var policy struct {
+		Allow []string
+		Deny  []string
+	}
+	if err := node.Decode(&policy); err != nil {
+		return err
+	}
+	// "allow: []" allows nothing, which is different from no allowlist
+	if allow := mappingValue(node, "allow"); allow != nil && policy.Allow == nil {
+		policy.Allow = []string{}
+	}
+	p.Allow = policy.Allow
+	p.Deny = policy.Deny
+	return nil
+}
+
+// Hermetic returns a copy of the policy that inherits only HermeticAllow,
+// unless it has an allowlist already.
+func (p *EnvPolicy) Hermetic() *EnvPolicy {
+	hermetic := &EnvPolicy{Allow: HermeticAllow}
+	if p != nil {
+		hermetic.Deny = p.Deny
+		if p.Allow != nil {
+			hermetic.Allow = p.Allow
+		}
+	}
+	return hermetic
+}
+
+// Allowed tells whether the variable with the given name is inherited. A nil
+// policy allows everything.
+func (p *EnvPolicy) Allowed(name string) bool {
+	if p == nil {
+		return true
+	}
+	if matchEnvName(p.Deny, name) {
+		return false
+	}
+	return p.Allow == nil || matchEnvName(p.Allow, name)
+}
+
+// Filter returns the variables of the given environment, as "key=value"
+// pairs, that are inherited.
+func (p *EnvPolicy) Filter(environ []string) []string {
+	if p == nil {
+		return environ
+	}
+	filtered := make([]string, 0, len(environ))
+	for _, kv := range environ {
+		name, _, _ := strings.Cut(kv, "=")
+		if p.Allowed(name) {
+			filtered = append(filtered, kv)
+		}
+	}
+	return filtered
+}
+
+// Environ returns the inherited environment of the process. It's nil for a
+// nil policy, which means the whole environment.
+func (p *EnvPolicy) Environ() []string {
+	if p == nil {
+		return nil
+	}
+	return p.Filter(os.Environ())
+}
+
+// DeepCopy creates a new instance of EnvPolicy and copies data by value from
+// the source struct.
+func (p *EnvPolicy) DeepCopy() *EnvPolicy {
+	if p == nil {
+		return nil
+	}
+	return &EnvPolicy{
+		Allow: deepcopy.Slice(p.Allow),
+		Deny:  deepcopy.Slice(p.Deny),
+	}
+}
+
+func matchEnvName(patterns []string, name string) bool {
+	// The environment isn't case sensitive on Windows
+	if runtime.GOOS == "windows" {
+		name = strings.ToUpper(name)
+	}
+	for _, pattern := range patterns {
+		if runtime.GOOS == "windows" {
+			pattern = strings.ToUpper(pattern)
+		}
+		if ok, _ := path.Match(pattern, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+func mappingValue(node *yaml.Node, key string) *yaml.Node {
+	for i := 0; i < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i+1]
+		}
+	}
+	return nil
+}
@@ -0,0 +1,122 @@
+package taskfile
+
+import (
+	"fmt"
+	"strings"
+
+	"golang.org/x/exp/slices"
+
+	"github.com/nuvolaris/task/v3/internal/deepcopy"
+)
+
+// ResolveExtends makes every task with "extends" inherit from the task it
+// names, which can extend another task too. The template is resolved first,
+// then the fields set by the task override the inherited ones:
+//
+//   - vars and env are merged, and the ones of the task win;
+//   - lists, like cmds, deps, args, sources or status, are inherited only when
+//     the task has none;
+//   - strings, like desc, dir or method, are inherited only when the task
+//     leaves them empty;
+//   - silent, interactive, ignore_error and ignore_gitignored are inherited
+//     when the template sets them.
+//
+// The aliases and internal of the template are never inherited, so a template
+// can be internal and its tasks still be called.
+func (t *Tasks) ResolveExtends() error {
+	resolved := make(map[string]bool, t.Len())
+	var resolve func(name string, chain []string) error
+	resolve = func(name string, chain []string) error {
+		task := t.Get(name)
+		if resolved[name] || task == nil || task.Extends == "" {
+			return nil
+		}
+		if slices.Contains(chain, name) {
+			return fmt.Errorf("task: Task %q extends itself: %s", name, strings.Join(append(chain, name), " -> "))
+		}
+		base := t.Get(task.Extends)
+		if base == nil {
+			return fmt.Errorf("task: Task %q extends %q, which does not exist", name, task.Extends)
+		}
+		if err := resolve(base.Task, append(chain, name)); err != nil {
+			return err
+		}
+		task.inherit(base)
+		resolved[name] = true
+		return nil
+	}
+	for _, name := range t.Keys() {
+		if err := resolve(name, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// inherit copies the fields of the template that the task doesn't set.
+func (t *Task) inherit(template *Task) {
+	base := template.DeepCopy()
+
+	vars := &Vars{}
+	vars.Merge(base.Vars)
+	vars.Merge(t.Vars)
+	t.Vars = vars
+	env := &Vars{}
+	env.Merge(base.Env)
+	env.Merge(t.Env)
+	t.Env = env
+
+	inheritSlice(&t.Cmds, base.Cmds)
+	inheritSlice(&t.Deps, base.Deps)
+	inheritSlice(&t.Pipeline, base.Pipeline)
+	inheritSlice(&t.Sources, base.Sources)
+	inheritSlice(&t.Generates, base.Generates)
+	inheritSlice(&t.Status, base.Status)
+	inheritSlice(&t.Preconditions, base.Preconditions)
+	inheritSlice(&t.Set, base.Set)
+	inheritSlice(&t.Shopt, base.Shopt)
+	inheritSlice(&t.Dotenv, base.Dotenv)
+	inheritSlice(&t.Platforms, base.Platforms)
+	inheritSlice(&t.Args, base.Args)
+	if t.ForwardSignals == nil {
+		t.ForwardSignals = deepcopy.Slice(base.ForwardSignals)
+	}
+	if t.Requires == nil {
+		t.Requires = base.Requires
+	}
+	if t.Output == nil {
+		t.Output = base.Output.DeepCopy()
+	}
+
+	inheritString(&t.Label, base.Label)
+	inheritString(&t.Desc, base.Desc)
+	inheritString(&t.Prompt, base.Prompt)
+	inheritString(&t.Summary, base.Summary)
+	inheritString(&t.GeneratesMethod, base.GeneratesMethod)
+	inheritString(&t.Dir, base.Dir)
+	inheritString(&t.Method, base.Method)
+	inheritString(&t.Prefix, base.Prefix)
+	inheritString(&t.Run, base.Run)
+	inheritString(&t.Watch, base.Watch)
+	inheritString(&t.Timeout, base.Timeout)
+	if t.DepsConcurrency == 0 {
+		t.DepsConcurrency = base.DepsConcurrency
+	}
+
+	t.Silent = t.Silent || base.Silent
+	t.Interactive = t.Interactive || base.Interactive
+	t.IgnoreError = t.IgnoreError || base.IgnoreError
+	t.IgnoreGitignored = t.IgnoreGitignored || base.IgnoreGitignored
+}
+
+func inheritSlice[T any](s *[]T, base []T) {
+	if len(*s) == 0 {
+		*s = base
+	}
+}
+
+func inheritString(s *string, base string) {
+	if *s == "" {
+		*s = base
+	}
+}
@@ -9,9 +9,9 @@ import (
 )
 
 type For struct {
-	From  string
-	List  []string
-	Var   string
+	From  string   `schema:"-"`
+	List  []string `schema:"-"`
+	Var   string   `schema:",required"`
 	Split string
 	As    string
 }
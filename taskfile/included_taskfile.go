@@ -14,14 +14,60 @@ import (
 
 // IncludedTaskfile represents information about included taskfiles
 type IncludedTaskfile struct {
-	Taskfile       string
+	Taskfile       string `schema:",required"`
 	Dir            string
 	Optional       bool
+	If             string
 	Internal       bool
 	Aliases        []string
-	AdvancedImport bool
+	AdvancedImport bool `schema:"-"`
 	Vars           *Vars
-	BaseDir        string // The directory from which the including taskfile was loaded; used to resolve relative paths
+	Env            *Vars
+	VarsStrategy   string `schema:",enum=override|inherit|error"`
+	Checksum       string
+	Cosign         *IncludeCosign
+	Location       *Location `schema:"-"`
+	BaseDir        string    `schema:"-"` // The directory from which the including taskfile was loaded; used to resolve relative paths
+}
+
+// The vars strategies tell what happens when the vars or env of an include
+// are set by the included Taskfile too.
+const (
+	// VarsStrategyOverride uses the value of the include. It's the default.
+	VarsStrategyOverride = "override"
+	// VarsStrategyInherit keeps the value of the included Taskfile, so the
+	// include only sets the ones it leaves unset.
+	VarsStrategyInherit = "inherit"
+	// VarsStrategyError makes the include fail.
+	VarsStrategyError = "error"
+)
+
+// IncludeCosign verifies the signature of an included Taskfile with cosign,
+// either against a key or, for the keyless signatures, against the identity
+// of their certificate. The paths are relative to the including Taskfile.
+type IncludeCosign struct {
+	// Key is the public key, as a path or anything cosign accepts, like the
+	// URI of a KMS
+	Key string
+	// Signature is the signature of the Taskfile, as a path or URL. The OCI
+	// artifacts are verified with the signatures of their registry without it
+	Signature string
+	// Certificate is the certificate of a keyless signature, as a path or URL
+	Certificate string
+	// Bundle holds the signature and the certificate, as a path or URL
+	Bundle   string
+	Identity string
+	Issuer   string
+}
+
+// DeepCopy creates a new instance of IncludeCosign and copies data by value
+// from the source struct.
+func (c *IncludeCosign) DeepCopy() *IncludeCosign {
+	if c == nil {
+		return nil
+	}
+	v := *c
+	return &v
 }
 
 // IncludedTaskfiles represents information about included tasksfiles
@@ -45,6 +91,7 @@ func (tfs *IncludedTaskfiles) UnmarshalYAML(node *yaml.Node) error {
 			if err := valueNode.Decode(&v); err != nil {
 				return err
 			}
+			v.Location = &Location{Line: keyNode.Line, Column: keyNode.Column}
 			tfs.Set(keyNode.Value, v)
 		}
 		return nil
@@ -98,23 +145,44 @@ func (it *IncludedTaskfile) UnmarshalYAML(node *yaml.Node) error {
 
 	case yaml.MappingNode:
 		var includedTaskfile struct {
-			Taskfile string
-			Dir      string
-			Optional bool
-			Internal bool
-			Aliases  []string
-			Vars     *Vars
+			Taskfile     string
+			Dir          string
+			Optional     bool
+			If           string
+			Internal     bool
+			Aliases      []string
+			Vars         *Vars
+			Env          *Vars
+			VarsStrategy string `yaml:"vars_strategy"`
+			Checksum     string
+			Cosign       *IncludeCosign
 		}
 		if err := node.Decode(&includedTaskfile); err != nil {
 			return err
 		}
+		if err := checkIncludeChecksum(includedTaskfile.Checksum); err != nil {
+			return fmt.Errorf("yaml: line %d: %w", node.Line, err)
+		}
+		if c := includedTaskfile.Cosign; c != nil && c.Key == "" && (c.Identity == "" || c.Issuer == "") {
+			return fmt.Errorf("yaml: line %d: the cosign of an include needs a key, or the identity and the issuer of a keyless signature", node.Line)
+		}
+		switch includedTaskfile.VarsStrategy {
+		case "", VarsStrategyOverride, VarsStrategyInherit, VarsStrategyError:
+		default:
+			return fmt.Errorf("yaml: line %d: the vars_strategy of an include must be %q, %q or %q, got %q", node.Line, VarsStrategyOverride, VarsStrategyInherit, VarsStrategyError, includedTaskfile.VarsStrategy)
+		}
 		it.Taskfile = includedTaskfile.Taskfile
 		it.Dir = includedTaskfile.Dir
 		it.Optional = includedTaskfile.Optional
+		it.If = includedTaskfile.If
 		it.Internal = includedTaskfile.Internal
 		it.Aliases = includedTaskfile.Aliases
 		it.AdvancedImport = true
 		it.Vars = includedTaskfile.Vars
+		it.Env = includedTaskfile.Env
+		it.VarsStrategy = includedTaskfile.VarsStrategy
+		it.Checksum = includedTaskfile.Checksum
+		it.Cosign = includedTaskfile.Cosign
 		return nil
 	}
 
@@ -131,13 +199,32 @@ func (it *IncludedTaskfile) DeepCopy() *IncludedTaskfile {
 		Taskfile:       it.Taskfile,
 		Dir:            it.Dir,
 		Optional:       it.Optional,
+		If:             it.If,
 		Internal:       it.Internal,
 		AdvancedImport: it.AdvancedImport,
 		Vars:           it.Vars.DeepCopy(),
+		Env:            it.Env.DeepCopy(),
+		VarsStrategy:   it.VarsStrategy,
+		Checksum:       it.Checksum,
+		Cosign:         it.Cosign.DeepCopy(),
+		Location:       it.Location.DeepCopy(),
 		BaseDir:        it.BaseDir,
 	}
 }
 
+// checkIncludeChecksum checks that the checksum of an include is a SHA-256 one,
+// with or without the "sha256:" prefix.
+func checkIncludeChecksum(checksum string) error {
+	if checksum == "" {
+		return nil
+	}
+	hex := strings.TrimPrefix(checksum, "sha256:")
+	if len(hex) != 64 || strings.Trim(strings.ToLower(hex), "0123456789abcdef") != "" {
+		return fmt.Errorf("the checksum of an include must be a SHA-256 one, like sha256:<64 hex digits>, got %q", checksum)
+	}
+	return nil
+}
+
 // FullTaskfilePath returns the fully qualified path to the included taskfile
 func (it *IncludedTaskfile) FullTaskfilePath() (string, error) {
 	return it.resolvePath(it.Taskfile)
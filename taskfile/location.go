@@ -1,9 +1,23 @@
 package taskfile
 
+import "github.com/nuvolaris/task/v3/internal/deepcopy"
+
 type Location struct {
 	Line     int
 	Column   int
 	Taskfile string
+	// Includes are the includes that brought the task into the root Taskfile,
+	// starting from the root one
+	Includes []IncludeLocation
+}
+
+// An IncludeLocation is where a Taskfile includes another one under a
+// namespace.
+type IncludeLocation struct {
+	Namespace string
+	Line      int
+	Column    int
+	Taskfile  string
 }
 
 func (l *Location) DeepCopy() *Location {
@@ -14,5 +28,6 @@ func (l *Location) DeepCopy() *Location {
 		Line:     l.Line,
 		Column:   l.Column,
 		Taskfile: l.Taskfile,
+		Includes: deepcopy.Slice(l.Includes),
 	}
 }
@@ -39,12 +39,22 @@ func Merge(t1, t2 *Taskfile, includedTaskfile *IncludedTaskfile, namespaces ...s
 		// taskfile are marked as internal
 		task.Internal = task.Internal || (includedTaskfile != nil && includedTaskfile.Internal)
 
-		// Add namespaces to dependencies, commands and aliases
+		// Add namespaces to the extended task, dependencies, commands and aliases
+		if task.Extends != "" {
+			task.Extends = taskNameWithNamespace(task.Extends, namespaces...)
+		}
 		for _, dep := range task.Deps {
 			if dep != nil && dep.Task != "" {
 				dep.Task = taskNameWithNamespace(dep.Task, namespaces...)
 			}
 		}
+		for _, stage := range task.Pipeline {
+			for _, dep := range stage.Tasks {
+				if dep != nil && dep.Task != "" {
+					dep.Task = taskNameWithNamespace(dep.Task, namespaces...)
+				}
+			}
+		}
 		for _, cmd := range task.Cmds {
 			if cmd != nil && cmd.Task != "" {
 				cmd.Task = taskNameWithNamespace(cmd.Task, namespaces...)
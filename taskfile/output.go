@@ -12,8 +12,23 @@ type Output struct {
 	Name string `yaml:"-"`
 	// Group specific style
 	Group OutputGroup
+	// Prefixed specific style
+	Prefixed OutputPrefixed
+	// Timestamps prefixes every line with the time it was written at. It's
+	// either empty, OutputTimestampsRFC3339 or OutputTimestampsRelative.
+	Timestamps string `yaml:"-"`
 }
 
+// Formats of the timestamps of Output.
+const (
+	// OutputTimestampsRFC3339 prints the time of the day, like
+	// "2006-01-02T15:04:05Z07:00".
+	OutputTimestampsRFC3339 = "rfc3339"
+	// OutputTimestampsRelative prints the time since the run started, like
+	// "+1.234s".
+	OutputTimestampsRelative = "relative"
+)
+
 // IsSet returns true if and only if a custom output style is set.
 func (s *Output) IsSet() bool {
 	return s.Name != ""
@@ -32,28 +47,86 @@ func (s *Output) UnmarshalYAML(node *yaml.Node) error {
 
 	case yaml.MappingNode:
 		var tmp struct {
-			Group *OutputGroup
+			Interleaved *struct{}
+			Group       *OutputGroup
+			Prefixed    *OutputPrefixed
 		}
 		if err := node.Decode(&tmp); err != nil {
-			return fmt.Errorf("task: output style must be a string or mapping with a \"group\" key: %w", err)
+			return fmt.Errorf("task: output style must be a string or mapping with an \"interleaved\", \"group\" or \"prefixed\" key: %w", err)
+		}
+		// Every style accepts the timestamps option
+		var options map[string]struct {
+			Timestamps outputTimestamps
+		}
+		if err := node.Decode(&options); err != nil {
+			return err
 		}
-		if tmp.Group == nil {
-			return fmt.Errorf("task: output style must have the \"group\" key when in mapping form")
+		if (tmp.Interleaved != nil && tmp.Group != nil) || (tmp.Interleaved != nil && tmp.Prefixed != nil) || (tmp.Group != nil && tmp.Prefixed != nil) {
+			return fmt.Errorf("task: output style can only have one of the \"interleaved\", \"group\" and \"prefixed\" keys")
 		}
-		*s = Output{
-			Name:  "group",
-			Group: *tmp.Group,
+		switch {
+		case tmp.Interleaved != nil:
+			*s = Output{Name: "interleaved"}
+		case tmp.Group != nil:
+			*s = Output{
+				Name:  "group",
+				Group: *tmp.Group,
+			}
+		case tmp.Prefixed != nil:
+			if err := tmp.Prefixed.validate(); err != nil {
+				return err
+			}
+			*s = Output{
+				Name:     "prefixed",
+				Prefixed: *tmp.Prefixed,
+			}
+		default:
+			return fmt.Errorf("task: output style must have the \"interleaved\", \"group\" or \"prefixed\" key when in mapping form")
 		}
+		s.Timestamps = string(options[s.Name].Timestamps)
 		return nil
 	}
 
 	return fmt.Errorf("yaml: line %d: cannot unmarshal %s into output", node.Line, node.ShortTag())
 }
 
+// ValidateTimestamps returns an error if the format of the timestamps is
+// unknown.
+func (s *Output) ValidateTimestamps() error {
+	switch s.Timestamps {
+	case "", OutputTimestampsRFC3339, OutputTimestampsRelative:
+		return nil
+	}
+	return fmt.Errorf("task: output timestamps must be %q or %q, got %q", OutputTimestampsRFC3339, OutputTimestampsRelative, s.Timestamps)
+}
+
+// outputTimestamps is the format of the timestamps, where true is a shortcut
+// for RFC3339 ones.
+type outputTimestamps string
+
+func (t *outputTimestamps) UnmarshalYAML(node *yaml.Node) error {
+	var enabled bool
+	if err := node.Decode(&enabled); err == nil {
+		if enabled {
+			*t = OutputTimestampsRFC3339
+		}
+		return nil
+	}
+	var format string
+	if err := node.Decode(&format); err != nil {
+		return err
+	}
+	*t = outputTimestamps(format)
+	return (&Output{Timestamps: format}).ValidateTimestamps()
+}
+
 // OutputGroup is the style options specific to the Group style.
 type OutputGroup struct {
 	Begin, End string
 	ErrorOnly  bool `yaml:"error_only"`
+	// Nested groups the output of each task, with the groups of its
+	// dependencies and of the tasks its commands call indented inside it.
+	Nested bool
 }
 
 // IsSet returns true if and only if a custom output style is set.
@@ -63,3 +136,38 @@ func (g *OutputGroup) IsSet() bool {
 	}
 	return g.Begin != "" || g.End != ""
 }
+
+// Colors of the prefixes of the Prefixed style.
+const (
+	// OutputColorNever disables colors. This is the default.
+	OutputColorNever = "never"
+	// OutputColorAuto colors the prefixes unless colors are disabled or the
+	// output isn't a terminal.
+	OutputColorAuto = "auto"
+	// OutputColorAlways always colors the prefixes.
+	OutputColorAlways = "always"
+)
+
+// OutputPrefixed is the style options specific to the Prefixed style.
+type OutputPrefixed struct {
+	// Template of the prefix. PREFIX and TIME are available in addition to
+	// the variables of the task.
+	Template string
+	Color    string `schema:",enum=never|auto|always"`
+}
+
+// IsSet returns true if and only if a custom output style is set.
+func (p *OutputPrefixed) IsSet() bool {
+	if p == nil {
+		return false
+	}
+	return p.Template != "" || p.Color != ""
+}
+
+func (p *OutputPrefixed) validate() error {
+	switch p.Color {
+	case "", OutputColorNever, OutputColorAuto, OutputColorAlways:
+		return nil
+	}
+	return fmt.Errorf("task: output prefixed color must be one of %q, %q or %q, got %q", OutputColorNever, OutputColorAuto, OutputColorAlways, p.Color)
+}
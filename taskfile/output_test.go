@@ -0,0 +1,101 @@
+package taskfile_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+
+	"github.com/nuvolaris/task/v3/taskfile"
+)
+
+func TestOutputParse(t *testing.T) {
+	tests := []struct {
+		content  string
+		expected taskfile.Output
+	}{
+		{
+			"prefixed",
+			taskfile.Output{Name: "prefixed"},
+		},
+		{
+			`
+group:
+  begin: "::group::{{.TASK}}"
+  error_only: true
+`,
+			taskfile.Output{Name: "group", Group: taskfile.OutputGroup{Begin: "::group::{{.TASK}}", ErrorOnly: true}},
+		},
+		{
+			`
+prefixed:
+  template: "[{{.TASK}} {{.TIME}}]"
+  color: auto
+`,
+			taskfile.Output{Name: "prefixed", Prefixed: taskfile.OutputPrefixed{Template: "[{{.TASK}} {{.TIME}}]", Color: taskfile.OutputColorAuto}},
+		},
+		{
+			"interleaved: {timestamps: true}",
+			taskfile.Output{Name: "interleaved", Timestamps: taskfile.OutputTimestampsRFC3339},
+		},
+		{
+			"group: {timestamps: relative}",
+			taskfile.Output{Name: "group", Timestamps: taskfile.OutputTimestampsRelative},
+		},
+		{
+			"prefixed: {timestamps: false}",
+			taskfile.Output{Name: "prefixed"},
+		},
+	}
+	for _, test := range tests {
+		var output taskfile.Output
+		require.NoError(t, yaml.Unmarshal([]byte(test.content), &output))
+		assert.Equal(t, test.expected, output)
+	}
+}
+
+func TestOutputParseErrors(t *testing.T) {
+	tests := []struct {
+		content string
+		err     string
+	}{
+		{
+			"prefixed: {color: sometimes}",
+			`task: output prefixed color must be one of "never", "auto" or "always", got "sometimes"`,
+		},
+		{
+			"{group: {begin: foo}, prefixed: {color: auto}}",
+			`task: output style can only have one of the "interleaved", "group" and "prefixed" keys`,
+		},
+		{
+			"{}",
+			`task: output style must have the "interleaved", "group" or "prefixed" key when in mapping form`,
+		},
+		{
+			"interleaved: {timestamps: yesterday}",
+			`task: output timestamps must be "rfc3339" or "relative", got "yesterday"`,
+		},
+	}
+	for _, test := range tests {
+		var output taskfile.Output
+		err := yaml.Unmarshal([]byte(test.content), &output)
+		assert.EqualError(t, err, test.err, test.content)
+	}
+}
+
+func TestTaskOutputParse(t *testing.T) {
+	var output taskfile.TaskOutput
+	require.NoError(t, yaml.Unmarshal([]byte("{stdout: 'logs/{{.TASK}}.log', stderr: merge}"), &output))
+	assert.Equal(t, taskfile.TaskOutput{Stdout: "logs/{{.TASK}}.log", Stderr: taskfile.TaskOutputMerge}, output)
+	assert.True(t, output.Redirected())
+	assert.Equal(t, "logs/{{.TASK}}.log", output.File())
+
+	output = taskfile.TaskOutput{}
+	require.NoError(t, yaml.Unmarshal([]byte("stdout: discard"), &output))
+	assert.True(t, output.Redirected())
+	assert.Empty(t, output.File())
+
+	err := yaml.Unmarshal([]byte("stderr: file"), &output)
+	assert.EqualError(t, err, `yaml: line 1: invalid stderr "file", must be inherit or merge`)
+}
@@ -0,0 +1,87 @@
+package taskfile
+
+import (
+	"fmt"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/nuvolaris/task/v3/internal/deepcopy"
+)
+
+// Stage is a step of a task pipeline. The tasks of a stage run in parallel
+// and the next stage only starts once all of them finish.
+type Stage struct {
+	Name  string
+	Tasks []*Dep
+}
+
+func (s *Stage) DeepCopy() *Stage {
+	if s == nil {
+		return nil
+	}
+	return &Stage{
+		Name:  s.Name,
+		Tasks: deepcopy.Slice(s.Tasks),
+	}
+}
+
+func (s *Stage) UnmarshalYAML(node *yaml.Node) error {
+	switch node.Kind {
+
+	// A single task, in any of the forms of a dependency
+	case yaml.ScalarNode:
+		var dep Dep
+		if err := node.Decode(&dep); err != nil {
+			return err
+		}
+		s.Tasks = []*Dep{&dep}
+		return nil
+
+	// A list of tasks that run in parallel
+	case yaml.SequenceNode:
+		var deps []*Dep
+		if err := node.Decode(&deps); err != nil {
+			return err
+		}
+		if len(deps) == 0 {
+			return fmt.Errorf("yaml: line %d: pipeline stage must have at least one task", node.Line)
+		}
+		s.Tasks = deps
+		return nil
+
+	case yaml.MappingNode:
+		var stage struct {
+			Name  string
+			Tasks []*Dep
+		}
+		// Without a name or a list of tasks, it's a single task with vars
+		if !hasKey(node, "name") && !hasKey(node, "tasks") {
+			var dep Dep
+			if err := node.Decode(&dep); err != nil {
+				return err
+			}
+			s.Tasks = []*Dep{&dep}
+			return nil
+		}
+		if err := node.Decode(&stage); err != nil {
+			return err
+		}
+		if len(stage.Tasks) == 0 {
+			return fmt.Errorf("yaml: line %d: pipeline stage must have at least one task", node.Line)
+		}
+		s.Name = stage.Name
+		s.Tasks = stage.Tasks
+		return nil
+	}
+
+	return fmt.Errorf("yaml: line %d: cannot unmarshal %s into pipeline stage", node.Line, node.ShortTag())
+}
+
+func hasKey(node *yaml.Node, key string) bool {
+	for i := 0; i < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return true
+		}
+	}
+	return false
+}
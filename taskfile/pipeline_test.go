@@ -0,0 +1,69 @@
+package taskfile_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+
+	"github.com/nuvolaris/task/v3/internal/orderedmap"
+	"github.com/nuvolaris/task/v3/taskfile"
+)
+
+func TestStageParse(t *testing.T) {
+	tests := []struct {
+		content  string
+		expected *taskfile.Stage
+	}{
+		{
+			"build",
+			&taskfile.Stage{Tasks: []*taskfile.Dep{{Task: "build"}}},
+		},
+		{
+			"[build:linux, build:darwin]",
+			&taskfile.Stage{Tasks: []*taskfile.Dep{{Task: "build:linux"}, {Task: "build:darwin"}}},
+		},
+		{
+			`
+task: build
+vars: {OS: linux}
+`,
+			&taskfile.Stage{Tasks: []*taskfile.Dep{{Task: "build", Vars: &taskfile.Vars{
+				OrderedMap: orderedmap.FromMapWithOrder(
+					map[string]taskfile.Var{"OS": {Static: "linux"}},
+					[]string{"OS"},
+				),
+			}}}},
+		},
+		{
+			`
+name: test
+tasks: [unit, {task: e2e, silent: true}]
+`,
+			&taskfile.Stage{Name: "test", Tasks: []*taskfile.Dep{{Task: "unit"}, {Task: "e2e", Silent: true}}},
+		},
+	}
+	for _, test := range tests {
+		var stage taskfile.Stage
+		err := yaml.Unmarshal([]byte(test.content), &stage)
+		require.NoError(t, err)
+		assert.Equal(t, test.expected, &stage)
+	}
+}
+
+func TestStageParseErrors(t *testing.T) {
+	tests := []struct {
+		content string
+		err     string
+	}{
+		{"[]", "yaml: line 1: pipeline stage must have at least one task"},
+		{"name: empty", "yaml: line 1: pipeline stage must have at least one task"},
+		{"name: empty\ntasks: []", "yaml: line 1: pipeline stage must have at least one task"},
+	}
+	for _, test := range tests {
+		var stage taskfile.Stage
+		err := yaml.Unmarshal([]byte(test.content), &stage)
+		assert.EqualError(t, err, test.err, test.content)
+	}
+}
@@ -10,10 +10,21 @@ import (
 // ErrCantUnmarshalPrecondition is returned for invalid precond YAML.
 var ErrCantUnmarshalPrecondition = errors.New("task: Can't unmarshal precondition value")
 
+// Possible values of Precondition.OnFailure
+const (
+	PreconditionFail = "fail"
+	PreconditionSkip = "skip"
+	PreconditionWarn = "warn"
+)
+
 // Precondition represents a precondition necessary for a task to run
 type Precondition struct {
-	Sh  string
-	Msg string
+	Sh            string
+	FileExists    string
+	EnvSet        string
+	CommandExists string
+	Msg           string
+	OnFailure     string `schema:",enum=fail|skip|warn"`
 }
 
 func (p *Precondition) DeepCopy() *Precondition {
@@ -21,8 +32,12 @@ func (p *Precondition) DeepCopy() *Precondition {
 		return nil
 	}
 	return &Precondition{
-		Sh:  p.Sh,
-		Msg: p.Msg,
+		Sh:            p.Sh,
+		FileExists:    p.FileExists,
+		EnvSet:        p.EnvSet,
+		CommandExists: p.CommandExists,
+		Msg:           p.Msg,
+		OnFailure:     p.OnFailure,
 	}
 }
 
@@ -40,17 +55,51 @@ func (p *Precondition) UnmarshalYAML(node *yaml.Node) error {
 		return nil
 
 	case yaml.MappingNode:
-		var sh struct {
-			Sh  string
-			Msg string
+		var precondition struct {
+			Sh            string
+			FileExists    string `yaml:"file_exists"`
+			EnvSet        string `yaml:"env_set"`
+			CommandExists string `yaml:"command_exists"`
+			Msg           string
+			OnFailure     string `yaml:"on_failure"`
 		}
-		if err := node.Decode(&sh); err != nil {
+		if err := node.Decode(&precondition); err != nil {
 			return err
 		}
-		p.Sh = sh.Sh
-		p.Msg = sh.Msg
+
+		var checks int
+		for _, check := range []string{precondition.Sh, precondition.FileExists, precondition.EnvSet, precondition.CommandExists} {
+			if check != "" {
+				checks++
+			}
+		}
+		if checks != 1 {
+			return fmt.Errorf("yaml: line %d: precondition must have exactly one of sh, file_exists, env_set or command_exists", node.Line)
+		}
+
+		switch precondition.OnFailure {
+		case "", PreconditionFail, PreconditionSkip, PreconditionWarn:
+		default:
+			return fmt.Errorf("yaml: line %d: invalid on_failure %q, must be one of fail, skip or warn", node.Line, precondition.OnFailure)
+		}
+
+		p.Sh = precondition.Sh
+		p.FileExists = precondition.FileExists
+		p.EnvSet = precondition.EnvSet
+		p.CommandExists = precondition.CommandExists
+		p.Msg = precondition.Msg
+		p.OnFailure = precondition.OnFailure
 		if p.Msg == "" {
-			p.Msg = fmt.Sprintf("%s failed", sh.Sh)
+			switch {
+			case p.FileExists != "":
+				p.Msg = fmt.Sprintf("file %q does not exist", p.FileExists)
+			case p.EnvSet != "":
+				p.Msg = fmt.Sprintf("environment variable %q is not set", p.EnvSet)
+			case p.CommandExists != "":
+				p.Msg = fmt.Sprintf("command %q not found", p.CommandExists)
+			default:
+				p.Msg = fmt.Sprintf("%s failed", p.Sh)
+			}
 		}
 		return nil
 	}
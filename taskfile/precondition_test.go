@@ -42,6 +42,28 @@ msg: "1 is not 2"
 			&taskfile.Precondition{},
 			&taskfile.Precondition{Sh: "[ 1 = 2 ]", Msg: "1 is not 2"},
 		},
+		{
+			"file_exists: foo.txt",
+			&taskfile.Precondition{},
+			&taskfile.Precondition{FileExists: "foo.txt", Msg: `file "foo.txt" does not exist`},
+		},
+		{
+			`
+env_set: FOO
+on_failure: skip
+`,
+			&taskfile.Precondition{},
+			&taskfile.Precondition{EnvSet: "FOO", Msg: `environment variable "FOO" is not set`, OnFailure: taskfile.PreconditionSkip},
+		},
+		{
+			`
+command_exists: docker
+msg: "docker is required"
+on_failure: warn
+`,
+			&taskfile.Precondition{},
+			&taskfile.Precondition{CommandExists: "docker", Msg: "docker is required", OnFailure: taskfile.PreconditionWarn},
+		},
 	}
 	for _, test := range tests {
 		err := yaml.Unmarshal([]byte(test.content), test.v)
@@ -49,3 +71,34 @@ msg: "1 is not 2"
 		assert.Equal(t, test.expected, test.v)
 	}
 }
+
+func TestPreconditionParseErrors(t *testing.T) {
+	tests := []struct {
+		content string
+		err     string
+	}{
+		{
+			"msg: no check",
+			"precondition must have exactly one of sh, file_exists, env_set or command_exists",
+		},
+		{
+			`
+sh: "true"
+file_exists: foo.txt
+`,
+			"precondition must have exactly one of sh, file_exists, env_set or command_exists",
+		},
+		{
+			`
+sh: "true"
+on_failure: ignore
+`,
+			`invalid on_failure "ignore", must be one of fail, skip or warn`,
+		},
+	}
+	for _, test := range tests {
+		var p taskfile.Precondition
+		err := yaml.Unmarshal([]byte(test.content), &p)
+		assert.ErrorContains(t, err, test.err)
+	}
+}
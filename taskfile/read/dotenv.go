@@ -1,6 +1,7 @@
 package read
 
 import (
+	"context"
 	"os"
 
 	"github.com/joho/godotenv"
@@ -11,12 +12,12 @@ import (
 	"github.com/nuvolaris/task/v3/taskfile"
 )
 
-func Dotenv(c compiler.Compiler, tf *taskfile.Taskfile, dir string) (*taskfile.Vars, error) {
+func Dotenv(ctx context.Context, c compiler.Compiler, tf *taskfile.Taskfile, dir string) (*taskfile.Vars, error) {
 	if len(tf.Dotenv) == 0 {
 		return nil, nil
 	}
 
-	vars, err := c.GetTaskfileVariables()
+	vars, err := c.GetTaskfileVariables(ctx)
 	if err != nil {
 		return nil, err
 	}
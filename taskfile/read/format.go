@@ -0,0 +1,85 @@
+package read
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pelletier/go-toml"
+	"gopkg.in/yaml.v3"
+
+	"github.com/nuvolaris/task/v3/taskfile"
+)
+
+// decodeTaskfile decodes the content of a Taskfile, which is YAML unless the
+// extension of its location is .json or .toml. JSON is a subset of YAML, so
+// it's only checked to be valid, and TOML is converted to the same YAML nodes,
+// with its lines, so the errors point to the right place.
+func decodeTaskfile(location string, b []byte, t *taskfile.Taskfile) error {
+	switch strings.ToLower(filepath.Ext(location)) {
+	case ".json":
+		if !json.Valid(b) {
+			var v any
+			return json.Unmarshal(b, &v)
+		}
+	case ".toml":
+		tree, err := toml.LoadBytes(b)
+		if err != nil {
+			return err
+		}
+		return tomlNode(tree, tree.Position()).Decode(t)
+	}
+	return yaml.Unmarshal(b, t)
+}
+
+func tomlNode(v any, pos toml.Position) *yaml.Node {
+	node := &yaml.Node{Line: pos.Line, Column: pos.Col}
+	switch v := v.(type) {
+	case *toml.Tree:
+		node.Kind = yaml.MappingNode
+		node.Tag = "!!map"
+		keys := v.Keys()
+		// The keys are in the order of the file, like in YAML
+		sort.SliceStable(keys, func(i, j int) bool {
+			pi, pj := v.GetPositionPath([]string{keys[i]}), v.GetPositionPath([]string{keys[j]})
+			if pi.Line != pj.Line {
+				return pi.Line < pj.Line
+			}
+			return pi.Col < pj.Col
+		})
+		for _, k := range keys {
+			keyPos := v.GetPositionPath([]string{k})
+			node.Content = append(node.Content,
+				&yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: k, Line: keyPos.Line, Column: keyPos.Col},
+				tomlNode(v.GetPath([]string{k}), keyPos),
+			)
+		}
+	case []*toml.Tree:
+		node.Kind = yaml.SequenceNode
+		node.Tag = "!!seq"
+		for _, item := range v {
+			node.Content = append(node.Content, tomlNode(item, item.Position()))
+		}
+	case []any:
+		node.Kind = yaml.SequenceNode
+		node.Tag = "!!seq"
+		for _, item := range v {
+			node.Content = append(node.Content, tomlNode(item, pos))
+		}
+	case bool:
+		node.Kind, node.Tag, node.Value = yaml.ScalarNode, "!!bool", strconv.FormatBool(v)
+	case int64:
+		node.Kind, node.Tag, node.Value = yaml.ScalarNode, "!!int", strconv.FormatInt(v, 10)
+	case float64:
+		node.Kind, node.Tag, node.Value = yaml.ScalarNode, "!!float", strconv.FormatFloat(v, 'g', -1, 64)
+	case time.Time:
+		node.Kind, node.Tag, node.Value = yaml.ScalarNode, "!!timestamp", v.Format(time.RFC3339Nano)
+	default:
+		node.Kind, node.Tag, node.Value = yaml.ScalarNode, "!!str", fmt.Sprint(v)
+	}
+	return node
+}
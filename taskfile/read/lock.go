@@ -0,0 +1,145 @@
+package read
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/nuvolaris/task/v3/errors"
+	"github.com/nuvolaris/task/v3/internal/filepathext"
+)
+
+// LockFileName is the name of the lock file, next to the root Taskfile.
+const LockFileName = "Taskfile.lock"
+
+const lockHeader = "# Generated by Task, keep it in version control. Run \"task --update-includes\" to update the pinned Taskfiles.\n"
+
+// A Lock keeps the checksums of the pinned remote Taskfiles, like the
+// versioned ones (see VersionedURI) or the ones of a Git repository at a ref,
+// so they can be verified every time they're read. A nil Lock verifies
+// nothing.
+type Lock struct {
+	path     string
+	mutex    sync.Mutex
+	changed  bool
+	Includes map[string]LockedTaskfile
+}
+
+// LockedTaskfile is an entry of the lock file.
+type LockedTaskfile struct {
+	Checksum string `yaml:"checksum"`
+}
+
+type lockFile struct {
+	Version  int                       `yaml:"version"`
+	Includes map[string]LockedTaskfile `yaml:"includes"`
+}
+
+// ReadLock reads the lock file in the given directory, if there's one.
+func ReadLock(dir string) (*Lock, error) {
+	lock := &Lock{
+		path:     filepath.Join(dir, LockFileName),
+		Includes: map[string]LockedTaskfile{},
+	}
+	b, err := os.ReadFile(lock.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return lock, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var f lockFile
+	if err := yaml.Unmarshal(b, &f); err != nil {
+		return nil, &errors.TaskfileInvalidError{URI: filepathext.TryAbsToRel(lock.path), Err: err}
+	}
+	for uri, locked := range f.Includes {
+		lock.Includes[uri] = locked
+	}
+	return lock, nil
+}
+
+// readLockOf reads the lock file of the given root node. Only local Taskfiles
+// have one.
+func readLockOf(node Node) (*Lock, error) {
+	fileNode, ok := node.(*FileNode)
+	if !ok {
+		return nil, nil
+	}
+	return ReadLock(fileNode.Dir)
+}
+
+// Set locks the URI to the checksum of the given content.
+func (l *Lock) Set(uri string, b []byte) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	l.Includes[uri] = LockedTaskfile{Checksum: checksum(b)}
+	l.changed = true
+}
+
+// Remove unlocks the URI.
+func (l *Lock) Remove(uri string) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	if _, ok := l.Includes[uri]; ok {
+		delete(l.Includes, uri)
+		l.changed = true
+	}
+}
+
+// locked tells whether the node is in the lock file.
+func (l *Lock) locked(node Node) bool {
+	if l == nil {
+		return false
+	}
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	_, ok := l.Includes[node.Location()]
+	return ok
+}
+
+// verify checks the content of a node against the lock file, if it's there.
+func (l *Lock) verify(node Node, b []byte) error {
+	if l == nil {
+		return nil
+	}
+	l.mutex.Lock()
+	locked, ok := l.Includes[node.Location()]
+	l.mutex.Unlock()
+	if ok && locked.Checksum != checksum(b) {
+		return &errors.TaskfileLockMismatchError{URI: node.Location(), LockFile: filepathext.TryAbsToRel(l.path)}
+	}
+	return nil
+}
+
+// pin adds the trusted content of a pinned node, like a versioned one, to the
+// lock file, unless it's there already.
+func (l *Lock) pin(node Node, b []byte) {
+	pinnedNode, ok := node.(interface{ Pinned() bool })
+	if l == nil || !ok || !pinnedNode.Pinned() || l.locked(node) {
+		return
+	}
+	l.Set(node.Location(), b)
+}
+
+// Save writes the lock file, if it changed since it was read.
+func (l *Lock) Save() error {
+	if l == nil {
+		return nil
+	}
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	if !l.changed {
+		return nil
+	}
+	b, err := yaml.Marshal(lockFile{Version: 1, Includes: l.Includes})
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(l.path, append([]byte(lockHeader), b...), 0o644); err != nil {
+		return err
+	}
+	l.changed = false
+	return nil
+}
@@ -23,17 +23,32 @@ func NewNode(
 ) (Node, error) {
 	var node Node
 	var err error
-	switch getScheme(uri) {
-	case "http", "https":
+	switch scheme := getScheme(uri); {
+	case IsFetcherURI(uri):
+		node, err = NewFetcherNode(uri, insecure, opts...)
+	case scheme == "http", scheme == "https":
 		node, err = NewHTTPNode(uri, insecure, opts...)
 	default:
+		if IsVersionedURI(uri) {
+			node, err = NewVersionedNode(uri, opts...)
+			break
+		}
 		// If no other scheme matches, we assume it's a file
 		node, err = NewFileNode(uri, opts...)
 	}
+	if err != nil {
+		return nil, err
+	}
 	if node.Remote() && !experiments.RemoteTaskfiles {
 		return nil, errors.New("task: Remote taskfiles are not enabled. You can read more about this experiment and how to enable it at https://taskfile.dev/experiments/remote-taskfiles")
 	}
-	return node, err
+	return node, nil
+}
+
+// IsRemoteURI tells whether the URI points to a remote Taskfile, rather than
+// to a path.
+func IsRemoteURI(uri string) bool {
+	return strings.Contains(uri, "://") || IsVersionedURI(uri) || IsFetcherURI(uri)
 }
 
 func getScheme(uri string) string {
@@ -1,5 +1,7 @@
 package read
 
+import "github.com/nuvolaris/task/v3/taskfile"
+
 type (
 	NodeOption func(*BaseNode)
 	// BaseNode is a generic node that implements the Parent() and Optional()
@@ -9,6 +11,9 @@ type (
 	BaseNode struct {
 		parent   Node
 		optional bool
+		// checksum and cosign verify the Taskfile once read
+		checksum string
+		cosign   *taskfile.IncludeCosign
 	}
 )
 
@@ -45,3 +50,16 @@ func WithOptional(optional bool) NodeOption {
 func (node *BaseNode) Optional() bool {
 	return node.optional
 }
+
+// WithVerification makes the node verify the Taskfile it reads, against a
+// SHA-256 checksum or a cosign signature, before it's used.
+func WithVerification(checksum string, cosign *taskfile.IncludeCosign) NodeOption {
+	return func(node *BaseNode) {
+		node.checksum = checksum
+		node.cosign = cosign
+	}
+}
+
+func (node *BaseNode) verification() (string, *taskfile.IncludeCosign) {
+	return node.checksum, node.cosign
+}
@@ -0,0 +1,132 @@
+package read
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// A Fetcher gets the remote Taskfiles of one kind of source, like a Git
+// repository. The fetchers are chosen by the prefix of the include, in the
+// style of go-getter: git::https://github.com/org/repo.git//ci.yml uses the
+// one registered as "git". A fetcher registered under a URL scheme, like
+// "s3", is also used for the includes with that scheme.
+type Fetcher interface {
+	// Parse checks the source, without the prefix, when the include is read,
+	// so the mistakes are reported before anything is downloaded. The sources
+	// that aren't encrypted must be refused unless insecure is set.
+	Parse(src string, insecure bool) (Source, error)
+}
+
+// A Source is a remote Taskfile parsed by a Fetcher.
+type Source interface {
+	// Fetch downloads the Taskfile.
+	Fetch(ctx context.Context) ([]byte, error)
+	// Pinned tells whether the source points to a fixed version of the
+	// Taskfile, in which case its checksum is kept in the lock file.
+	Pinned() bool
+	// Join returns the source of a Taskfile at a path relative to this one,
+	// like the ones it includes, without the prefix.
+	Join(path string) string
+}
+
+var (
+	fetchersMutex sync.RWMutex
+	fetchers      = map[string]Fetcher{}
+)
+
+func init() {
+	RegisterFetcher("git", gitFetcher{})
+}
+
+// RegisterFetcher makes the includes with the given prefix or scheme use the
+// fetcher, replacing the one registered before, if any. Programs embedding
+// Task can register fetchers for the sources it doesn't know about.
+func RegisterFetcher(name string, fetcher Fetcher) {
+	fetchersMutex.Lock()
+	defer fetchersMutex.Unlock()
+	fetchers[name] = fetcher
+}
+
+// fetcherFor returns the fetcher of the URI, along with the name it's
+// registered as and the source without the prefix. The HTTP URLs have no
+// fetcher unless one is registered for them, as they're read by HTTPNode.
+func fetcherFor(uri string) (string, string, Fetcher, bool) {
+	fetchersMutex.RLock()
+	defer fetchersMutex.RUnlock()
+	if name, src, ok := strings.Cut(uri, "::"); ok && !strings.Contains(name, "/") {
+		fetcher, ok := fetchers[name]
+		return name, src, fetcher, ok
+	}
+	if scheme := getScheme(uri); scheme != "" {
+		fetcher, ok := fetchers[scheme]
+		return scheme, uri, fetcher, ok
+	}
+	return "", "", nil, false
+}
+
+// IsFetcherURI tells whether the URI is read by a Fetcher, either from its
+// prefix or its scheme.
+func IsFetcherURI(uri string) bool {
+	_, _, _, ok := fetcherFor(uri)
+	return ok
+}
+
+// A FetcherNode is a node that reads a Taskfile with a Fetcher.
+type FetcherNode struct {
+	*BaseNode
+	// Prefix is the name of the fetcher given before "::", if any
+	Prefix string
+	Source Source
+	src    string
+}
+
+func NewFetcherNode(uri string, insecure bool, opts ...NodeOption) (*FetcherNode, error) {
+	name, src, fetcher, ok := fetcherFor(uri)
+	if !ok {
+		return nil, fmt.Errorf("task: %q has no fetcher for %q", uri, name)
+	}
+	source, err := fetcher.Parse(src, insecure)
+	if err != nil {
+		return nil, err
+	}
+	node := &FetcherNode{
+		BaseNode: NewBaseNode(opts...),
+		Source:   source,
+		src:      src,
+	}
+	if src != uri {
+		node.Prefix = name
+	}
+	return node, nil
+}
+
+func (node *FetcherNode) Location() string {
+	if node.Prefix != "" {
+		return node.Prefix + "::" + node.src
+	}
+	return node.src
+}
+
+func (node *FetcherNode) Remote() bool {
+	return true
+}
+
+func (node *FetcherNode) Read(ctx context.Context) ([]byte, error) {
+	return node.Source.Fetch(ctx)
+}
+
+// Pinned tells whether the node points to a fixed version of the Taskfile.
+func (node *FetcherNode) Pinned() bool {
+	return node.Source.Pinned()
+}
+
+// Join returns the URI of a Taskfile at a path relative to this one, fetched
+// the same way.
+func (node *FetcherNode) Join(path string) string {
+	if node.Prefix != "" {
+		return node.Prefix + "::" + node.Source.Join(path)
+	}
+	return node.Source.Join(path)
+}
@@ -0,0 +1,144 @@
+package read
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/url"
+	"os"
+	"os/exec"
+	"path"
+	"strings"
+
+	"github.com/nuvolaris/task/v3/errors"
+)
+
+// gitFetcher gets the Taskfiles of any Git repository with the git command,
+// so the repositories of any host can be used, with the credentials of the
+// user: git::https://github.com/org/repo.git//tasks/ci.yml?ref=main. The
+// double slash separates the repository from the path of the Taskfile in it.
+// Without a ref, the default branch is used.
+type gitFetcher struct{}
+
+func (gitFetcher) Parse(src string, insecure bool) (Source, error) {
+	rest, query, _ := strings.Cut(src, "?")
+	// The double slash of the scheme isn't the one before the path
+	start := 0
+	if i := strings.Index(rest, "://"); i != -1 {
+		start = i + len("://")
+	}
+	i := strings.Index(rest[start:], "//")
+	if i == -1 || rest[start+i+2:] == "" {
+		return nil, fmt.Errorf("task: %q has no path to a Taskfile after the repository, like git::https://github.com/org/repo.git//Taskfile.yml", src)
+	}
+	repo, file := rest[:start+i], path.Clean(rest[start+i+2:])
+	if strings.HasPrefix(repo, "-") {
+		return nil, fmt.Errorf("task: %q has a repository starting with \"-\", which git would take as an option", src)
+	}
+	if file == ".." || strings.HasPrefix(file, "../") {
+		return nil, fmt.Errorf("task: %q points to a Taskfile outside of the repository", src)
+	}
+	switch getScheme(repo) {
+	case "http", "git":
+		if !insecure {
+			return nil, &errors.TaskfileNotSecureError{URI: src}
+		}
+	}
+	values, err := url.ParseQuery(query)
+	if err != nil {
+		return nil, fmt.Errorf("task: %q has an invalid query: %w", src, err)
+	}
+	for key := range values {
+		if key != "ref" {
+			return nil, fmt.Errorf("task: %q has an unsupported %q parameter, only \"ref\" is supported", src, key)
+		}
+	}
+	ref := values.Get("ref")
+	if strings.HasPrefix(ref, "-") {
+		return nil, fmt.Errorf("task: %q has a ref starting with \"-\", which git would take as an option", src)
+	}
+	return &gitSource{repo: repo, path: file, ref: ref, insecure: insecure}, nil
+}
+
+// A gitSource is a Taskfile in a Git repository at a given ref.
+type gitSource struct {
+	repo     string
+	path     string
+	ref      string
+	insecure bool
+}
+
+func (s *gitSource) String() string {
+	src := s.repo + "//" + s.path
+	if s.ref != "" {
+		src += "?ref=" + s.ref
+	}
+	return src
+}
+
+func (s *gitSource) Pinned() bool {
+	return s.ref != ""
+}
+
+func (s *gitSource) Join(p string) string {
+	joined := *s
+	joined.path = path.Join(path.Dir(s.path), p)
+	return joined.String()
+}
+
+// Fetch fetches only the commit of the ref, without its history, in an empty
+// repository, and reads the Taskfile from it without checking it out.
+func (s *gitSource) Fetch(ctx context.Context) ([]byte, error) {
+	dir, remove, err := mkdirTemp(ctx, "task-git-")
+	if err != nil {
+		return nil, err
+	}
+	defer remove()
+
+	ref := s.ref
+	if ref == "" {
+		ref = "HEAD"
+	}
+	if _, err := s.git(ctx, dir, "init", "--quiet"); err != nil {
+		return nil, err
+	}
+	if _, err := s.git(ctx, dir, "fetch", "--quiet", "--depth=1", "--", s.repo, ref); err != nil {
+		return nil, err
+	}
+	return s.git(ctx, dir, "show", "FETCH_HEAD:"+s.path)
+}
+
+func (s *gitSource) git(ctx context.Context, dir string, args ...string) ([]byte, error) {
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Dir = dir
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	// The credentials can't be asked for, as the includes are read at the
+	// same time
+	cmd.Env = append(os.Environ(), "GIT_TERMINAL_PROMPT=0")
+	// Transports like ext:: run commands, and file:// reads the repositories
+	// of the machine, so a Taskfile can only use the ones of the network,
+	// unless the user allows others
+	if _, ok := os.LookupEnv("GIT_ALLOW_PROTOCOL"); !ok {
+		protocols := "https:ssh"
+		if s.insecure {
+			protocols += ":http:git"
+		}
+		cmd.Env = append(cmd.Env, "GIT_ALLOW_PROTOCOL="+protocols)
+	}
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		reason := strings.TrimSpace(stderr.String())
+		if reason == "" {
+			reason = err.Error()
+		}
+		uri := s.String()
+		if getScheme(s.repo) != "git" {
+			uri = "git::" + uri
+		}
+		return nil, errors.TaskfileFetchFailedError{URI: uri, Reason: reason}
+	}
+	return stdout.Bytes(), nil
+}
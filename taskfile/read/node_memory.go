@@ -0,0 +1,33 @@
+package read
+
+import (
+	"context"
+)
+
+// A MemoryNode is a node that reads a taskfile from memory, like one that is
+// generated instead of written by the user.
+type MemoryNode struct {
+	*BaseNode
+	location string
+	content  []byte
+}
+
+func NewMemoryNode(location string, content []byte, opts ...NodeOption) *MemoryNode {
+	return &MemoryNode{
+		BaseNode: NewBaseNode(opts...),
+		location: location,
+		content:  content,
+	}
+}
+
+func (node *MemoryNode) Location() string {
+	return node.location
+}
+
+func (node *MemoryNode) Remote() bool {
+	return false
+}
+
+func (node *MemoryNode) Read(ctx context.Context) ([]byte, error) {
+	return node.content, nil
+}
@@ -0,0 +1,352 @@
+package read
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/nuvolaris/task/v3/errors"
+)
+
+const (
+	ociManifestMediaType = "application/vnd.oci.image.manifest.v1+json"
+	// ociTitleAnnotation is the name of the file of a layer, as set by the
+	// tools pushing files as OCI artifacts, like ORAS
+	ociTitleAnnotation = "org.opencontainers.image.title"
+	// ociMaxSize is the limit of the manifests and the Taskfiles read, as
+	// they're read to memory before their digest is verified
+	ociMaxSize = 4 << 20
+)
+
+func init() {
+	RegisterFetcher("oci", ociFetcher{})
+}
+
+// ociFetcher gets the Taskfiles of a bundle published as an OCI artifact in a
+// container registry: oci://ghcr.io/org/tasks:v1. Each layer of the artifact
+// is a file, named by its title annotation, and the Taskfile read is the
+// default one unless a path is given after a double slash, like
+// oci://ghcr.io/org/tasks:v1//ci.yml. The digests of the manifest and of the
+// layer are verified.
+type ociFetcher struct{}
+
+func (ociFetcher) Parse(src string, insecure bool) (Source, error) {
+	rest := strings.TrimPrefix(src, "oci://")
+	ref, file, _ := strings.Cut(rest, "//")
+	host, repo, _ := strings.Cut(ref, "/")
+	if host == "" || repo == "" {
+		return nil, fmt.Errorf("task: %q has no repository, like oci://ghcr.io/org/tasks:v1", src)
+	}
+	s := &ociSource{host: host, repo: repo, insecure: insecure}
+	if i := strings.LastIndex(s.repo, "@"); i != -1 {
+		s.repo, s.digest = s.repo[:i], s.repo[i+1:]
+		if !strings.HasPrefix(s.digest, "sha256:") {
+			return nil, fmt.Errorf("task: %q has an unsupported digest, only sha256 is supported", src)
+		}
+	} else if i := strings.LastIndex(s.repo, ":"); i > strings.LastIndex(s.repo, "/") {
+		s.repo, s.tag = s.repo[:i], s.repo[i+1:]
+		if s.tag == "" {
+			return nil, fmt.Errorf("task: %q has an empty tag", src)
+		}
+	}
+	if s.repo == "" {
+		return nil, fmt.Errorf("task: %q has no repository, like oci://ghcr.io/org/tasks:v1", src)
+	}
+	if file != "" {
+		s.path = path.Clean(file)
+		if s.path == ".." || strings.HasPrefix(s.path, "../") {
+			return nil, fmt.Errorf("task: %q points to a Taskfile outside of the artifact", src)
+		}
+	}
+	return s, nil
+}
+
+// An ociSource is a Taskfile of an OCI artifact, at a tag or a digest.
+type ociSource struct {
+	host   string
+	repo   string
+	tag    string
+	digest string
+	// path is the file of the artifact, or empty for the default Taskfile
+	path     string
+	insecure bool
+	// fetched is the digest of the manifest once fetched
+	fetched string
+}
+
+func (s *ociSource) String() string {
+	src := "oci://" + s.host + "/" + s.repo
+	switch {
+	case s.digest != "":
+		src += "@" + s.digest
+	case s.tag != "":
+		src += ":" + s.tag
+	}
+	if s.path != "" {
+		src += "//" + s.path
+	}
+	return src
+}
+
+// image returns the reference of the artifact for tools like cosign, at the
+// digest it was fetched at, if it was.
+func (s *ociSource) image() string {
+	switch {
+	case s.fetched != "":
+		return s.host + "/" + s.repo + "@" + s.fetched
+	case s.digest != "":
+		return s.host + "/" + s.repo + "@" + s.digest
+	case s.tag != "":
+		return s.host + "/" + s.repo + ":" + s.tag
+	}
+	return s.host + "/" + s.repo
+}
+
+func (s *ociSource) Pinned() bool {
+	return s.tag != "" || s.digest != ""
+}
+
+func (s *ociSource) Join(p string) string {
+	joined := *s
+	joined.path = path.Join(path.Dir(s.path), p)
+	return joined.String()
+}
+
+type ociDescriptor struct {
+	MediaType   string            `json:"mediaType"`
+	Digest      string            `json:"digest"`
+	Size        int64             `json:"size"`
+	Annotations map[string]string `json:"annotations"`
+}
+
+type ociManifest struct {
+	MediaType string          `json:"mediaType"`
+	Layers    []ociDescriptor `json:"layers"`
+}
+
+func (s *ociSource) Fetch(ctx context.Context) ([]byte, error) {
+	ref := s.digest
+	if ref == "" {
+		ref = s.tag
+	}
+	if ref == "" {
+		ref = "latest"
+	}
+	b, err := s.get(ctx, "manifests/"+ref, ociManifestMediaType)
+	if err != nil {
+		return nil, err
+	}
+	if s.digest != "" {
+		if err := s.verify(s.digest, b); err != nil {
+			return nil, err
+		}
+	}
+	s.fetched = fmt.Sprintf("sha256:%x", sha256.Sum256(b))
+	var manifest ociManifest
+	if err := json.Unmarshal(b, &manifest); err != nil {
+		return nil, s.failed(fmt.Sprintf("invalid manifest: %v", err))
+	}
+
+	layer, err := s.layer(manifest.Layers)
+	if err != nil {
+		return nil, err
+	}
+	if b, err = s.get(ctx, "blobs/"+layer.Digest, ""); err != nil {
+		return nil, err
+	}
+	if err := s.verify(layer.Digest, b); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// layer returns the layer of the Taskfile: the one titled with its path, or
+// with one of the default names. An artifact with a single untitled layer is
+// the Taskfile itself.
+func (s *ociSource) layer(layers []ociDescriptor) (ociDescriptor, error) {
+	names := defaultTaskfiles
+	if s.path != "" {
+		names = []string{s.path}
+	}
+	for _, name := range names {
+		for _, layer := range layers {
+			if layer.Annotations[ociTitleAnnotation] == name {
+				return layer, nil
+			}
+		}
+	}
+	if s.path == "" && len(layers) == 1 && layers[0].Annotations[ociTitleAnnotation] == "" {
+		return layers[0], nil
+	}
+	if s.path != "" {
+		return ociDescriptor{}, s.failed(fmt.Sprintf("the artifact has no %q file", s.path))
+	}
+	return ociDescriptor{}, s.failed("the artifact has no Taskfile")
+}
+
+func (s *ociSource) verify(digest string, b []byte) error {
+	if got := fmt.Sprintf("sha256:%x", sha256.Sum256(b)); got != digest {
+		return s.failed(fmt.Sprintf("the digest is %s instead of %s", got, digest))
+	}
+	return nil
+}
+
+func (s *ociSource) failed(reason string) error {
+	return errors.TaskfileFetchFailedError{URI: s.String(), Reason: reason}
+}
+
+// get reads a manifest or a blob of the repository, getting a token for the
+// registry first if asked to.
+func (s *ociSource) get(ctx context.Context, endpoint, accept string) ([]byte, error) {
+	scheme := "https"
+	if s.insecure {
+		scheme = "http"
+	}
+	host := s.host
+	if host == "docker.io" {
+		host = "registry-1.docker.io"
+	}
+	u := fmt.Sprintf("%s://%s/v2/%s/%s", scheme, host, s.repo, endpoint)
+
+	resp, err := s.do(ctx, u, accept, "")
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode == http.StatusUnauthorized {
+		challenge := resp.Header.Get("WWW-Authenticate")
+		resp.Body.Close()
+		token, err := s.token(ctx, challenge)
+		if err != nil {
+			return nil, err
+		}
+		if resp, err = s.do(ctx, u, accept, token); err != nil {
+			return nil, err
+		}
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, errors.TaskfileFetchFailedError{URI: s.String(), HTTPStatusCode: resp.StatusCode}
+	}
+	return io.ReadAll(io.LimitReader(resp.Body, ociMaxSize))
+}
+
+func (s *ociSource) do(ctx context.Context, u, accept, token string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", u, nil)
+	if err != nil {
+		return nil, err
+	}
+	if accept != "" {
+		req.Header.Set("Accept", accept)
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return nil, s.failed(err.Error())
+	}
+	return resp, nil
+}
+
+// token gets a token from the service the registry points to, with the
+// credentials of the Docker configuration if there are some for the registry,
+// or anonymously otherwise.
+func (s *ociSource) token(ctx context.Context, challenge string) (string, error) {
+	scheme, params, _ := strings.Cut(challenge, " ")
+	if !strings.EqualFold(scheme, "Bearer") {
+		return "", errors.TaskfileFetchFailedError{URI: s.String(), HTTPStatusCode: http.StatusUnauthorized}
+	}
+	values := url.Values{}
+	var realm string
+	for _, param := range strings.Split(params, ",") {
+		key, value, _ := strings.Cut(strings.TrimSpace(param), "=")
+		value = strings.Trim(value, `"`)
+		if key == "realm" {
+			realm = value
+		} else {
+			values.Set(key, value)
+		}
+	}
+	if realm == "" {
+		return "", s.failed("the registry asks for a token without telling where to get it")
+	}
+	req, err := http.NewRequestWithContext(ctx, "GET", realm+"?"+values.Encode(), nil)
+	if err != nil {
+		return "", err
+	}
+	if auth := dockerAuth(s.host); auth != "" {
+		req.Header.Set("Authorization", "Basic "+auth)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		if ctx.Err() != nil {
+			return "", ctx.Err()
+		}
+		return "", s.failed(err.Error())
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", errors.TaskfileFetchFailedError{URI: s.String(), HTTPStatusCode: resp.StatusCode}
+	}
+	var token struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&token); err != nil {
+		return "", s.failed(fmt.Sprintf("invalid token: %v", err))
+	}
+	if token.Token != "" {
+		return token.Token, nil
+	}
+	return token.AccessToken, nil
+}
+
+// dockerAuth returns the credentials of the registry saved by "docker login",
+// encoded for basic authentication, if any. The credential helpers aren't
+// used.
+func dockerAuth(host string) string {
+	dir := os.Getenv("DOCKER_CONFIG")
+	if dir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return ""
+		}
+		dir = filepath.Join(home, ".docker")
+	}
+	b, err := os.ReadFile(filepath.Join(dir, "config.json"))
+	if err != nil {
+		return ""
+	}
+	var config struct {
+		Auths map[string]struct {
+			Auth string `json:"auth"`
+		} `json:"auths"`
+	}
+	if err := json.Unmarshal(b, &config); err != nil {
+		return ""
+	}
+	keys := []string{host, "https://" + host}
+	if host == "docker.io" {
+		keys = append(keys, "https://index.docker.io/v1/")
+	}
+	for _, key := range keys {
+		if auth := config.Auths[key].Auth; auth != "" {
+			if _, err := base64.StdEncoding.DecodeString(auth); err == nil {
+				return auth
+			}
+		}
+	}
+	return ""
+}
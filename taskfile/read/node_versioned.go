@@ -0,0 +1,191 @@
+package read
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/Masterminds/semver/v3"
+
+	"github.com/nuvolaris/task/v3/errors"
+)
+
+// versionedHost knows where a Git hosting service serves the raw files and
+// the tags of a repository.
+type versionedHost struct {
+	rawURL  func(repo, ref, path string) string
+	tagsURL func(repo string) string
+}
+
+var versionedHosts = map[string]versionedHost{
+	"github.com": {
+		rawURL: func(repo, ref, path string) string {
+			return fmt.Sprintf("https://raw.githubusercontent.com/%s/%s/%s", repo, ref, path)
+		},
+		tagsURL: func(repo string) string {
+			return fmt.Sprintf("https://api.github.com/repos/%s/tags?per_page=100", repo)
+		},
+	},
+	"gitlab.com": {
+		rawURL: func(repo, ref, path string) string {
+			return fmt.Sprintf("https://gitlab.com/%s/-/raw/%s/%s", repo, ref, path)
+		},
+		tagsURL: func(repo string) string {
+			return fmt.Sprintf("https://gitlab.com/api/v4/projects/%s/repository/tags?per_page=100", url.PathEscape(repo))
+		},
+	},
+}
+
+// A VersionedURI points to a Taskfile in a Git repository at a given ref,
+// in the style of go-getter: github.com/org/tasks//build.yml?ref=v1.2.0. The
+// double slash separates the repository from the path of the Taskfile in it.
+// Without a ref, the default branch is used.
+type VersionedURI struct {
+	Host string
+	Repo string
+	Path string
+	Ref  string
+}
+
+// IsVersionedURI tells whether the given URI is a VersionedURI of a supported
+// host.
+func IsVersionedURI(uri string) bool {
+	host, _, _ := strings.Cut(uri, "/")
+	_, ok := versionedHosts[host]
+	return ok && strings.Contains(uri, "//")
+}
+
+// ParseVersionedURI parses a VersionedURI.
+func ParseVersionedURI(uri string) (*VersionedURI, error) {
+	rest, query, _ := strings.Cut(uri, "?")
+	repo, path, found := strings.Cut(rest, "//")
+	if !found || path == "" {
+		return nil, fmt.Errorf("task: %q has no path to a Taskfile after the repository, like github.com/org/repo//Taskfile.yml", uri)
+	}
+	host, repo, _ := strings.Cut(repo, "/")
+	if _, ok := versionedHosts[host]; !ok {
+		return nil, fmt.Errorf("task: %q is not a supported host for versioned includes", host)
+	}
+	if strings.Count(repo, "/") < 1 || strings.HasSuffix(repo, "/") {
+		return nil, fmt.Errorf("task: %q has no repository, like github.com/org/repo//Taskfile.yml", uri)
+	}
+	values, err := url.ParseQuery(query)
+	if err != nil {
+		return nil, fmt.Errorf("task: %q has an invalid query: %w", uri, err)
+	}
+	for key := range values {
+		if key != "ref" {
+			return nil, fmt.Errorf("task: %q has an unsupported %q parameter, only \"ref\" is supported", uri, key)
+		}
+	}
+	return &VersionedURI{
+		Host: host,
+		Repo: repo,
+		Path: path,
+		Ref:  values.Get("ref"),
+	}, nil
+}
+
+func (u *VersionedURI) String() string {
+	s := fmt.Sprintf("%s/%s//%s", u.Host, u.Repo, u.Path)
+	if u.Ref != "" {
+		s += "?ref=" + u.Ref
+	}
+	return s
+}
+
+// Pinned tells whether the URI points to a given ref, instead of the default
+// branch. Only pinned Taskfiles are kept in the lock file.
+func (u *VersionedURI) Pinned() bool {
+	return u.Ref != ""
+}
+
+// WithRef returns a copy of the URI pointing to the given ref.
+func (u *VersionedURI) WithRef(ref string) *VersionedURI {
+	v := *u
+	v.Ref = ref
+	return &v
+}
+
+// RawURL returns the URL the Taskfile is downloaded from.
+func (u *VersionedURI) RawURL() string {
+	ref := u.Ref
+	if ref == "" {
+		ref = "HEAD"
+	}
+	return versionedHosts[u.Host].rawURL(u.Repo, ref, u.Path)
+}
+
+// LatestTag returns the newest semantic version tag of the repository, or an
+// empty string if it has none.
+func (u *VersionedURI) LatestTag(ctx context.Context) (string, error) {
+	tagsURL := versionedHosts[u.Host].tagsURL(u.Repo)
+	req, err := http.NewRequestWithContext(ctx, "GET", tagsURL, nil)
+	if err != nil {
+		return "", err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		if ctx.Err() != nil {
+			return "", ctx.Err()
+		}
+		return "", errors.TaskfileFetchFailedError{URI: tagsURL}
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", errors.TaskfileFetchFailedError{URI: tagsURL, HTTPStatusCode: resp.StatusCode}
+	}
+
+	var tags []struct {
+		Name string `json:"name"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&tags); err != nil {
+		return "", fmt.Errorf("task: Failed to read the tags of %s: %w", u.Repo, err)
+	}
+	var latest string
+	var latestVersion *semver.Version
+	for _, tag := range tags {
+		v, err := semver.NewVersion(tag.Name)
+		if err != nil || v.Prerelease() != "" {
+			continue
+		}
+		if latestVersion == nil || v.GreaterThan(latestVersion) {
+			latest, latestVersion = tag.Name, v
+		}
+	}
+	return latest, nil
+}
+
+// A VersionedNode is a node that reads a Taskfile from a Git repository at a
+// given ref. It's downloaded over HTTPS from the raw files of the host.
+type VersionedNode struct {
+	*HTTPNode
+	URI *VersionedURI
+}
+
+func NewVersionedNode(uri string, opts ...NodeOption) (*VersionedNode, error) {
+	versionedURI, err := ParseVersionedURI(uri)
+	if err != nil {
+		return nil, err
+	}
+	httpNode, err := NewHTTPNode(versionedURI.RawURL(), false, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return &VersionedNode{
+		HTTPNode: httpNode,
+		URI:      versionedURI,
+	}, nil
+}
+
+func (node *VersionedNode) Location() string {
+	return node.URI.String()
+}
+
+// Pinned tells whether the node points to a given ref.
+func (node *VersionedNode) Pinned() bool {
+	return node.URI.Pinned()
+}
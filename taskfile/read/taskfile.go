@@ -6,7 +6,11 @@ import (
 	"os"
 	"path/filepath"
 	"runtime"
+	"strconv"
+	"strings"
+	"sync"
 
+	"golang.org/x/exp/slices"
 	"gopkg.in/yaml.v3"
 
 	"github.com/nuvolaris/task/v3/errors"
@@ -20,6 +24,14 @@ import (
 var (
 	// ErrIncludedTaskfilesCantHaveDotenvs is returned when a included Taskfile contains dotenvs
 	ErrIncludedTaskfilesCantHaveDotenvs = errors.New("task: Included Taskfiles can't have dotenv declarations. Please, move the dotenv declaration to the main Taskfile")
+	// ErrIncludedTaskfilesCantHaveEnvPolicies is returned when an included Taskfile contains an env_policy
+	ErrIncludedTaskfilesCantHaveEnvPolicies = errors.New("task: Included Taskfiles can't have an env_policy. Please, move it to the main Taskfile")
+	// ErrIncludedTaskfilesCantHaveHooks is returned when an included Taskfile contains before_all, after_all, before_each or after_each
+	ErrIncludedTaskfilesCantHaveHooks = errors.New("task: Included Taskfiles can't have before_all, after_all, before_each nor after_each. Please, move them to the main Taskfile")
+
+	// promptMutex makes the Taskfiles read at the same time ask the user
+	// whether to trust them one after the other
+	promptMutex sync.Mutex
 
 	defaultTaskfiles = []string{
 		"Taskfile.yml",
@@ -30,16 +42,33 @@ var (
 		"taskfile.dist.yml",
 		"Taskfile.dist.yaml",
 		"taskfile.dist.yaml",
+		"Taskfile.json",
+		"taskfile.json",
+		"Taskfile.toml",
+		"taskfile.toml",
+	}
+
+	// overrideTaskfiles are the names of the local Taskfile merged into the
+	// root one, usually in .gitignore. Only the first one found is used.
+	overrideTaskfiles = []string{
+		"Taskfile.override.yml",
+		"taskfile.override.yml",
+		"Taskfile.override.yaml",
+		"taskfile.override.yaml",
 	}
 )
 
-func readTaskfile(
+// readTaskfileBytes returns the content of the Taskfile of the node, from the
+// cache for the remote ones when possible.
+func readTaskfileBytes(
+	ctx context.Context,
 	node Node,
 	download,
 	offline bool,
 	tempDir string,
+	lock *Lock,
 	l *logger.Logger,
-) (*taskfile.Taskfile, error) {
+) ([]byte, error) {
 	var b []byte
 	var err error
 	var cache *Cache
@@ -60,6 +89,9 @@ func readTaskfile(
 
 		if b != nil {
 			l.VerboseOutf(logger.Magenta, "task: [%s] Fetched cached copy\n", node.Location())
+			if err := lock.verify(node, b); err != nil {
+				return nil, err
+			}
 		}
 	}
 
@@ -73,10 +105,13 @@ func readTaskfile(
 
 	// If we still don't have a copy, get the file in the usual way
 	if b == nil {
-		b, err = node.Read(context.Background())
+		b, err = node.Read(ctx)
 		if err != nil {
 			return nil, err
 		}
+		if err := lock.verify(node, b); err != nil {
+			return nil, err
+		}
 
 		// If the node was remote, we need to check the checksum
 		if node.Remote() {
@@ -86,14 +121,21 @@ func readTaskfile(
 			checksum := checksum(b)
 			cachedChecksum := cache.readChecksum(node)
 
-			// If the checksum doesn't exist, prompt the user to continue
-			if cachedChecksum == "" {
+			// The Taskfiles are read at the same time, but the user is asked
+			// about one at a time
+			promptMutex.Lock()
+			defer promptMutex.Unlock()
+			switch {
+			case lock.locked(node):
+				// The Taskfiles in the lock file are trusted, as they match it
+			case cachedChecksum == "":
+				// If the checksum doesn't exist, prompt the user to continue
 				if cont, err := l.Prompt(logger.Yellow, fmt.Sprintf("The task you are attempting to run depends on the remote Taskfile at %q.\n--- Make sure you trust the source of this Taskfile before continuing ---\nContinue?", node.Location()), "n", "y", "yes"); err != nil {
 					return nil, err
 				} else if !cont {
 					return nil, &errors.TaskfileNotTrustedError{URI: node.Location()}
 				}
-			} else if checksum != cachedChecksum {
+			case checksum != cachedChecksum:
 				// If there is a cached hash, but it doesn't match the expected hash, prompt the user to continue
 				if cont, err := l.Prompt(logger.Yellow, fmt.Sprintf("The Taskfile at %q has changed since you last used it!\n--- Make sure you trust the source of this Taskfile before continuing ---\nContinue?", node.Location()), "n", "y", "yes"); err != nil {
 					return nil, err
@@ -120,19 +162,17 @@ func readTaskfile(
 		}
 	}
 
-	var t taskfile.Taskfile
-	if err := yaml.Unmarshal(b, &t); err != nil {
-		return nil, &errors.TaskfileInvalidError{URI: filepathext.TryAbsToRel(node.Location()), Err: err}
-	}
-	t.Location = node.Location()
+	lock.pin(node, b)
 
-	return &t, nil
+	return b, nil
 }
 
 // Taskfile reads a Taskfile for a given directory
 // Uses current dir when dir is left empty. Uses Taskfile.yml
-// or Taskfile.yaml when entrypoint is left empty
+// or Taskfile.yaml when entrypoint is left empty. The context cancels reading
+// the included Taskfiles, like downloading the remote ones.
 func Taskfile(
+	ctx context.Context,
 	node Node,
 	insecure bool,
 	download bool,
@@ -140,9 +180,45 @@ func Taskfile(
 	tempDir string,
 	l *logger.Logger,
 ) (*taskfile.Taskfile, error) {
+	lock, err := readLockOf(node)
+	if err != nil {
+		return nil, err
+	}
+
+	// The Taskfiles included many times are read once, and decoded again
+	// every time, as merging them changes them. A limited number of them is
+	// read at the same time.
+	var reads sync.Map
+	sem := make(chan struct{}, runtime.NumCPU())
+	readTaskfile := func(node Node) (*taskfile.Taskfile, error) {
+		v, _ := reads.LoadOrStore(node.Location(), &readResult{})
+		r := v.(*readResult)
+		r.once.Do(func() {
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			r.b, r.err = readTaskfileBytes(ctx, node, download, offline, tempDir, lock, l)
+		})
+		if r.err != nil {
+			return nil, r.err
+		}
+		// The includes of the same Taskfile can verify it differently
+		if err := verifyTaskfile(ctx, node, r.b, insecure, l); err != nil {
+			return nil, err
+		}
+		var t taskfile.Taskfile
+		if err := decodeTaskfile(node.Location(), r.b, &t); err != nil {
+			return nil, &errors.TaskfileInvalidError{URI: filepathext.TryAbsToRel(node.Location()), Err: err}
+		}
+		t.Location = node.Location()
+		return &t, nil
+	}
+
 	var _taskfile func(Node) (*taskfile.Taskfile, error)
 	_taskfile = func(node Node) (*taskfile.Taskfile, error) {
-		t, err := readTaskfile(node, download, offline, tempDir, l)
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		t, err := readTaskfile(node)
 		if err != nil {
 			return nil, err
 		}
@@ -159,17 +235,36 @@ func Taskfile(
 			})
 		}
 
+		var includes []*include
 		err = t.Includes.Range(func(namespace string, includedTask taskfile.IncludedTaskfile) error {
 			if t.Version.Compare(taskfile.V3) >= 0 {
 				tr := templater.Templater{Vars: t.Vars, RemoveNoValue: true}
+				if includedTask.If != "" {
+					include, err := isIncludeConditionMet(namespace, tr.Replace(includedTask.If))
+					if err := tr.Err(); err != nil {
+						return err
+					}
+					if err != nil {
+						return err
+					}
+					if !include {
+						return nil
+					}
+				}
 				includedTask = taskfile.IncludedTaskfile{
 					Taskfile:       tr.Replace(includedTask.Taskfile),
 					Dir:            tr.Replace(includedTask.Dir),
 					Optional:       includedTask.Optional,
+					If:             includedTask.If,
 					Internal:       includedTask.Internal,
 					Aliases:        includedTask.Aliases,
 					AdvancedImport: includedTask.AdvancedImport,
 					Vars:           includedTask.Vars,
+					Env:            includedTask.Env,
+					VarsStrategy:   includedTask.VarsStrategy,
+					Checksum:       tr.Replace(includedTask.Checksum),
+					Cosign:         includedTask.Cosign,
+					Location:       includedTask.Location,
 					BaseDir:        includedTask.BaseDir,
 				}
 				if err := tr.Err(); err != nil {
@@ -177,14 +272,24 @@ func Taskfile(
 				}
 			}
 
-			uri, err := includedTask.FullTaskfilePath()
-			if err != nil {
-				return err
+			// Versioned and fetched Taskfiles aren't paths, even if they look
+			// like ones, and the relative paths included by a fetched Taskfile
+			// are fetched from the same place
+			uri := includedTask.Taskfile
+			if fetcherNode, ok := node.(*FetcherNode); ok && isRelativeInclude(uri) {
+				uri = fetcherNode.Join(uri)
+			}
+			if !IsVersionedURI(uri) && !IsFetcherURI(uri) {
+				var err error
+				if uri, err = includedTask.FullTaskfilePath(); err != nil {
+					return err
+				}
 			}
 
 			includeReaderNode, err := NewNode(uri, insecure,
 				WithParent(node),
 				WithOptional(includedTask.Optional),
+				WithVerification(includedTask.Checksum, resolveCosign(includedTask.Cosign, includedTask.BaseDir)),
 			)
 			if err != nil {
 				if includedTask.Optional {
@@ -196,69 +301,32 @@ func Taskfile(
 			if err := checkCircularIncludes(includeReaderNode); err != nil {
 				return err
 			}
-
-			includedTaskfile, err := _taskfile(includeReaderNode)
-			if err != nil {
-				if includedTask.Optional {
-					return nil
-				}
-				return err
-			}
-
-			if t.Version.Compare(taskfile.V3) >= 0 && len(includedTaskfile.Dotenv) > 0 {
-				return ErrIncludedTaskfilesCantHaveDotenvs
-			}
-
-			if includedTask.AdvancedImport {
-				dir, err := includedTask.FullDirPath()
-				if err != nil {
-					return err
-				}
-
-				// nolint: errcheck
-				includedTaskfile.Vars.Range(func(k string, v taskfile.Var) error {
-					o := v
-					o.Dir = dir
-					includedTaskfile.Vars.Set(k, o)
-					return nil
-				})
-				// nolint: errcheck
-				includedTaskfile.Env.Range(func(k string, v taskfile.Var) error {
-					o := v
-					o.Dir = dir
-					includedTaskfile.Env.Set(k, o)
-					return nil
-				})
-
-				for _, task := range includedTaskfile.Tasks.Values() {
-					task.Dir = filepathext.SmartJoin(dir, task.Dir)
-					if task.IncludeVars == nil {
-						task.IncludeVars = &taskfile.Vars{}
-					}
-					task.IncludeVars.Merge(includedTask.Vars)
-					task.IncludedTaskfileVars = includedTaskfile.Vars
-					task.IncludedTaskfile = &includedTask
-				}
-			}
-
-			if err = taskfile.Merge(t, includedTaskfile, &includedTask, namespace); err != nil {
-				return err
-			}
-
-			if includedTaskfile.Tasks.Get("default") != nil && t.Tasks.Get(namespace) == nil {
-				defaultTaskName := fmt.Sprintf("%s:default", namespace)
-				task := t.Tasks.Get(defaultTaskName)
-				task.Aliases = append(task.Aliases, namespace)
-				task.Aliases = append(task.Aliases, includedTask.Aliases...)
-				t.Tasks.Set(defaultTaskName, task)
-			}
-
+			includes = append(includes, &include{namespace: namespace, includedTask: includedTask, node: includeReaderNode})
 			return nil
 		})
 		if err != nil {
 			return nil, err
 		}
 
+		// The included Taskfiles are read at the same time, then merged in
+		// the order of the includes, so the result is always the same
+		var wg sync.WaitGroup
+		for _, inc := range includes {
+			inc := inc
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				inc.taskfile, inc.err = _taskfile(inc.node)
+			}()
+		}
+		wg.Wait()
+
+		for _, inc := range includes {
+			if err := mergeInclude(t, node.Location(), inc); err != nil {
+				return nil, err
+			}
+		}
+
 		if t.Version.Compare(taskfile.V3) < 0 {
 			if node, isFileNode := node.(*FileNode); isFileNode {
 				path := filepathext.SmartJoin(node.Dir, fmt.Sprintf("Taskfile_%s.yml", runtime.GOOS))
@@ -268,7 +336,7 @@ func Taskfile(
 						Entrypoint: path,
 						Dir:        node.Dir,
 					}
-					b, err := osNode.Read(context.Background())
+					b, err := osNode.Read(ctx)
 					if err != nil {
 						return nil, err
 					}
@@ -289,15 +357,264 @@ func Taskfile(
 			if task == nil {
 				task = &taskfile.Task{}
 			}
-			// Set the location of the taskfile for each task
+			// Set the location of the taskfile for each task, and its
+			// builtins setting, which the included ones already got from
+			// their own Taskfile
 			if task.Location.Taskfile == "" {
 				task.Location.Taskfile = t.Location
+				task.Builtins = t.Builtins
+			}
+			// and for its commands, which keep it once the tasks extending it
+			// copy them
+			for _, cmd := range task.Cmds {
+				if cmd != nil && cmd.Location != nil && cmd.Location.Taskfile == "" {
+					cmd.Location.Taskfile = t.Location
+				}
 			}
 		}
 
 		return t, nil
 	}
-	return _taskfile(node)
+	t, err := _taskfile(node)
+	if err != nil {
+		return nil, err
+	}
+	if err := mergeOverride(t, node, _taskfile, l); err != nil {
+		return nil, err
+	}
+	// The tasks can extend the ones of any Taskfile, so this is done once
+	// all of them are merged
+	if err := t.Tasks.ResolveExtends(); err != nil {
+		return nil, err
+	}
+	if err := lock.Save(); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// Include reads the Taskfile of a directory, or the Taskfile itself, and
+// merges its tasks into t under the namespace, like an include of it would,
+// running them in its directory. It's how the tasks called by the path of
+// their Taskfile, like "./services/api:deploy", are loaded when called.
+func Include(
+	ctx context.Context,
+	t *taskfile.Taskfile,
+	namespace string,
+	path string,
+	insecure bool,
+	download bool,
+	offline bool,
+	tempDir string,
+	l *logger.Logger,
+) error {
+	node, err := NewFileNode(path)
+	if err != nil {
+		return err
+	}
+	if node.Location() == t.Location {
+		return fmt.Errorf("task: %q is the root Taskfile, so its tasks are called by their names", filepathext.TryAbsToRel(path))
+	}
+	included, err := Taskfile(ctx, node, insecure, download, offline, tempDir, l)
+	if err != nil {
+		return err
+	}
+	return mergeInclude(t, t.Location, &include{
+		namespace: namespace,
+		includedTask: taskfile.IncludedTaskfile{
+			Taskfile:       node.Location(),
+			Dir:            node.Dir,
+			AdvancedImport: true,
+		},
+		node:     node,
+		taskfile: included,
+	})
+}
+
+// isIncludeConditionMet tells whether the templated "if" of an include is
+// true. An empty condition is false, like a template printing nothing.
+func isIncludeConditionMet(namespace, condition string) (bool, error) {
+	condition = strings.TrimSpace(condition)
+	if condition == "" {
+		return false, nil
+	}
+	met, err := strconv.ParseBool(condition)
+	if err != nil {
+		return false, fmt.Errorf(`task: The "if" of the include %q must be true or false, got %q`, namespace, condition)
+	}
+	return met, nil
+}
+
+// isRelativeInclude tells whether the included Taskfile is at a path relative
+// to the including one, rather than remote or absolute.
+func isRelativeInclude(uri string) bool {
+	return !strings.Contains(uri, "://") && !IsVersionedURI(uri) && !IsFetcherURI(uri) &&
+		!filepathext.IsAbs(uri) && !strings.HasPrefix(uri, "~") && !strings.HasPrefix(uri, "$")
+}
+
+// An include is an included Taskfile being read.
+type include struct {
+	namespace    string
+	includedTask taskfile.IncludedTaskfile
+	node         Node
+	taskfile     *taskfile.Taskfile
+	err          error
+}
+
+type readResult struct {
+	once sync.Once
+	b    []byte
+	err  error
+}
+
+// mergeInclude merges an included Taskfile, once read, into the including one.
+func mergeInclude(t *taskfile.Taskfile, location string, inc *include) error {
+	namespace, includedTask, includedTaskfile := inc.namespace, inc.includedTask, inc.taskfile
+	if inc.err != nil {
+		if includedTask.Optional {
+			return nil
+		}
+		return inc.err
+	}
+
+	if t.Version.Compare(taskfile.V3) >= 0 && len(includedTaskfile.Dotenv) > 0 {
+		return ErrIncludedTaskfilesCantHaveDotenvs
+	}
+
+	if includedTaskfile.EnvPolicy != nil {
+		return ErrIncludedTaskfilesCantHaveEnvPolicies
+	}
+
+	if includedTaskfile.HasHooks() {
+		return ErrIncludedTaskfilesCantHaveHooks
+	}
+
+	if includedTask.AdvancedImport {
+		dir, err := includedTask.FullDirPath()
+		if err != nil {
+			return err
+		}
+
+		includeVars, err := applyVarsStrategy(namespace, includedTask, "var", includedTask.Vars, includedTaskfile.Vars)
+		if err != nil {
+			return err
+		}
+		includeEnv, err := applyVarsStrategy(namespace, includedTask, "env", includedTask.Env, includedTaskfile.Env)
+		if err != nil {
+			return err
+		}
+
+		// nolint: errcheck
+		includedTaskfile.Vars.Range(func(k string, v taskfile.Var) error {
+			o := v
+			o.Dir = dir
+			includedTaskfile.Vars.Set(k, o)
+			return nil
+		})
+		// nolint: errcheck
+		includedTaskfile.Env.Range(func(k string, v taskfile.Var) error {
+			o := v
+			o.Dir = dir
+			includedTaskfile.Env.Set(k, o)
+			return nil
+		})
+
+		for _, task := range includedTaskfile.Tasks.Values() {
+			task.Dir = filepathext.SmartJoin(dir, task.Dir)
+			if task.IncludeVars == nil {
+				task.IncludeVars = &taskfile.Vars{}
+			}
+			task.IncludeVars.Merge(includeVars)
+			if task.IncludeEnv == nil {
+				task.IncludeEnv = &taskfile.Vars{}
+			}
+			task.IncludeEnv.Merge(includeEnv)
+			task.IncludedTaskfileVars = includedTaskfile.Vars
+			task.IncludedTaskfile = &includedTask
+		}
+	}
+
+	include := taskfile.IncludeLocation{Namespace: namespace, Taskfile: location}
+	if includedTask.Location != nil {
+		include.Line = includedTask.Location.Line
+		include.Column = includedTask.Location.Column
+	}
+	for _, task := range includedTaskfile.Tasks.Values() {
+		if task != nil && task.Location != nil {
+			task.Location.Includes = append([]taskfile.IncludeLocation{include}, task.Location.Includes...)
+		}
+	}
+
+	if err := taskfile.Merge(t, includedTaskfile, &includedTask, namespace); err != nil {
+		return err
+	}
+
+	if includedTaskfile.Tasks.Get("default") != nil && t.Tasks.Get(namespace) == nil {
+		defaultTaskName := fmt.Sprintf("%s:default", namespace)
+		task := t.Tasks.Get(defaultTaskName)
+		task.Aliases = append(task.Aliases, namespace)
+		task.Aliases = append(task.Aliases, includedTask.Aliases...)
+		t.Tasks.Set(defaultTaskName, task)
+	}
+
+	return nil
+}
+
+// mergeOverride merges the override Taskfile next to the root Taskfile, if
+// any, like an included Taskfile without a namespace: its tasks replace the
+// ones with the same name and its vars and env win. It's only looked for when
+// the root Taskfile has one of the default names.
+func mergeOverride(t *taskfile.Taskfile, node Node, read func(Node) (*taskfile.Taskfile, error), l *logger.Logger) error {
+	fileNode, ok := node.(*FileNode)
+	if !ok || !slices.Contains(defaultTaskfiles, fileNode.Entrypoint) {
+		return nil
+	}
+	for _, name := range overrideTaskfiles {
+		path := filepathext.SmartJoin(fileNode.Dir, name)
+		if _, err := os.Stat(path); err != nil {
+			continue
+		}
+		l.VerboseErrf(logger.Magenta, "task: Merging %s\n", filepathext.TryAbsToRel(path))
+		overrideNode, err := NewFileNode(path, WithParent(node))
+		if err != nil {
+			return err
+		}
+		override, err := read(overrideNode)
+		if err != nil {
+			return err
+		}
+		if len(override.Dotenv) > 0 {
+			return ErrIncludedTaskfilesCantHaveDotenvs
+		}
+		if override.EnvPolicy != nil {
+			return ErrIncludedTaskfilesCantHaveEnvPolicies
+		}
+		if override.HasHooks() {
+			return ErrIncludedTaskfilesCantHaveHooks
+		}
+		return taskfile.Merge(t, override, nil)
+	}
+	return nil
+}
+
+// applyVarsStrategy returns the vars or env that the include gives to the
+// tasks of the included Taskfile, following its vars_strategy when the
+// included Taskfile sets them too.
+func applyVarsStrategy(namespace string, include taskfile.IncludedTaskfile, kind string, includeVars, taskfileVars *taskfile.Vars) (*taskfile.Vars, error) {
+	vars := &taskfile.Vars{}
+	err := includeVars.Range(func(k string, v taskfile.Var) error {
+		if taskfileVars.Exists(k) {
+			switch include.VarsStrategy {
+			case taskfile.VarsStrategyInherit:
+				return nil
+			case taskfile.VarsStrategyError:
+				return fmt.Errorf(`task: The %s %q of the include %q is set by the included Taskfile too, which its "error" vars_strategy forbids`, kind, k, namespace)
+			}
+		}
+		vars.Set(k, v)
+		return nil
+	})
+	return vars, err
 }
 
 // Exists will check if a file at the given path Exists. If it does, it will
@@ -324,6 +641,30 @@ func Exists(path string) (string, error) {
 	return "", errors.TaskfileNotFoundError{URI: path, Walk: false}
 }
 
+// GlobalDir returns the directory of the global Taskfile. It's the user home
+// directory, unless it has no Taskfile and $XDG_CONFIG_HOME/task (or
+// ~/.config/task when $XDG_CONFIG_HOME isn't set) has one.
+func GlobalDir() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("task: Failed to get user home directory: %w", err)
+	}
+	if _, err := Exists(home); err == nil {
+		return home, nil
+	}
+
+	configDir := os.Getenv("XDG_CONFIG_HOME")
+	if configDir == "" {
+		configDir = filepath.Join(home, ".config")
+	}
+	configDir = filepath.Join(configDir, "task")
+	if _, err := Exists(configDir); err == nil {
+		return configDir, nil
+	}
+
+	return home, nil
+}
+
 // ExistsWalk will check if a file at the given path exists by calling the
 // exists function. If a file is not found, it will walk up the directory tree
 // calling the exists function until it finds a file or reaches the root
@@ -366,16 +707,14 @@ func checkCircularIncludes(node Node) error {
 	if node.Parent() == nil {
 		return errors.New("task: failed to check for include cycle: node.Parent was nil")
 	}
-	curNode := node
 	location := node.Location()
-	for curNode.Parent() != nil {
-		curNode = curNode.Parent()
-		curLocation := curNode.Location()
-		if curLocation == location {
-			return fmt.Errorf("task: include cycle detected between %s <--> %s",
-				curLocation,
-				node.Parent().Location(),
-			)
+	cycle := []string{filepathext.TryAbsToRel(location)}
+	for curNode := node.Parent(); curNode != nil; curNode = curNode.Parent() {
+		cycle = append(cycle, filepathext.TryAbsToRel(curNode.Location()))
+		if curNode.Location() == location {
+			// The cycle was walked from the end, so it's printed reversed
+			slices.Reverse(cycle)
+			return fmt.Errorf("task: include cycle detected: %s", strings.Join(cycle, " -> "))
 		}
 	}
 	return nil
@@ -0,0 +1,352 @@
+package read_test
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nuvolaris/task/v3/errors"
+	"github.com/nuvolaris/task/v3/internal/experiments"
+	"github.com/nuvolaris/task/v3/internal/logger"
+	"github.com/nuvolaris/task/v3/taskfile/read"
+)
+
+func TestGlobalDir(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the home directory isn't read from $HOME on Windows")
+	}
+
+	home := t.TempDir()
+	configHome := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_CONFIG_HOME", configHome)
+
+	writeTaskfile := func(dir string) {
+		require.NoError(t, os.MkdirAll(dir, 0o755))
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "Taskfile.yml"), []byte("version: '3'\n"), 0o644))
+	}
+
+	// No global Taskfile at all
+	dir, err := read.GlobalDir()
+	require.NoError(t, err)
+	assert.Equal(t, home, dir)
+
+	// Only in $XDG_CONFIG_HOME
+	writeTaskfile(filepath.Join(configHome, "task"))
+	dir, err = read.GlobalDir()
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(configHome, "task"), dir)
+
+	// $HOME takes precedence
+	writeTaskfile(home)
+	dir, err = read.GlobalDir()
+	require.NoError(t, err)
+	assert.Equal(t, home, dir)
+
+	// ~/.config is used when $XDG_CONFIG_HOME isn't set
+	home = t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_CONFIG_HOME", "")
+	writeTaskfile(filepath.Join(home, ".config", "task"))
+	dir, err = read.GlobalDir()
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(home, ".config", "task"), dir)
+}
+
+func TestParseVersionedURI(t *testing.T) {
+	assert.True(t, read.IsVersionedURI("github.com/org/tasks//build.yml?ref=v1.2.0"))
+	assert.False(t, read.IsVersionedURI("./tasks//build.yml"))
+	assert.False(t, read.IsVersionedURI("https://github.com/org/tasks/build.yml"))
+
+	uri, err := read.ParseVersionedURI("github.com/org/tasks//ci/build.yml?ref=v1.2.0")
+	require.NoError(t, err)
+	assert.Equal(t, &read.VersionedURI{Host: "github.com", Repo: "org/tasks", Path: "ci/build.yml", Ref: "v1.2.0"}, uri)
+	assert.True(t, uri.Pinned())
+	assert.Equal(t, "https://raw.githubusercontent.com/org/tasks/v1.2.0/ci/build.yml", uri.RawURL())
+	assert.Equal(t, "github.com/org/tasks//ci/build.yml?ref=v1.3.0", uri.WithRef("v1.3.0").String())
+
+	uri, err = read.ParseVersionedURI("gitlab.com/group/sub/tasks//Taskfile.yml")
+	require.NoError(t, err)
+	assert.False(t, uri.Pinned())
+	assert.Equal(t, "https://gitlab.com/group/sub/tasks/-/raw/HEAD/Taskfile.yml", uri.RawURL())
+
+	_, err = read.ParseVersionedURI("github.com/org/tasks")
+	assert.Error(t, err)
+	_, err = read.ParseVersionedURI("github.com/org//Taskfile.yml")
+	assert.Error(t, err)
+	_, err = read.ParseVersionedURI("github.com/org/tasks//Taskfile.yml?depth=1")
+	assert.Error(t, err)
+}
+
+func TestLock(t *testing.T) {
+	dir := t.TempDir()
+	const uri = "github.com/org/tasks//build.yml?ref=v1.2.0"
+
+	lock, err := read.ReadLock(dir)
+	require.NoError(t, err)
+	assert.Empty(t, lock.Includes)
+
+	// Nothing is written until something is locked
+	require.NoError(t, lock.Save())
+	assert.NoFileExists(t, filepath.Join(dir, read.LockFileName))
+
+	lock.Set(uri, []byte("version: '3'\n"))
+	require.NoError(t, lock.Save())
+
+	lock, err = read.ReadLock(dir)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]read.LockedTaskfile{
+		uri: {Checksum: "f46c302190aef1002a93cf600bba671567efbd4b5a248e8d672ad5d4bae6b04e"},
+	}, lock.Includes)
+
+	lock.Remove(uri)
+	require.NoError(t, lock.Save())
+	lock, err = read.ReadLock(dir)
+	require.NoError(t, err)
+	assert.Empty(t, lock.Includes)
+}
+
+func TestGitFetcher(t *testing.T) {
+	node, err := read.NewFetcherNode("git::https://github.com/org/repo.git//tasks/ci.yml?ref=main", false)
+	require.NoError(t, err)
+	assert.Equal(t, "git::https://github.com/org/repo.git//tasks/ci.yml?ref=main", node.Location())
+	assert.True(t, node.Pinned())
+	assert.Equal(t, "git::https://github.com/org/repo.git//common/lint.yml?ref=main", node.Join("../common/lint.yml"))
+
+	node, err = read.NewFetcherNode("git::git@github.com:org/repo.git//Taskfile.yml", false)
+	require.NoError(t, err)
+	assert.False(t, node.Pinned())
+
+	assert.True(t, read.IsFetcherURI("git::https://github.com/org/repo.git//ci.yml"))
+	assert.True(t, read.IsFetcherURI("git://example.com/repo.git//ci.yml"))
+	assert.False(t, read.IsFetcherURI("https://example.com/Taskfile.yml"))
+	assert.False(t, read.IsFetcherURI("./tasks/ci.yml"))
+
+	_, err = read.NewFetcherNode("git::https://github.com/org/repo.git", false)
+	assert.Error(t, err)
+	_, err = read.NewFetcherNode("git::https://github.com/org/repo.git//../ci.yml", false)
+	assert.Error(t, err)
+	_, err = read.NewFetcherNode("git::https://github.com/org/repo.git//ci.yml?depth=1", false)
+	assert.Error(t, err)
+	_, err = read.NewFetcherNode("git::http://example.com/repo.git//ci.yml", false)
+	assert.ErrorAs(t, err, new(*errors.TaskfileNotSecureError))
+	_, err = read.NewFetcherNode("git::--upload-pack=touch /tmp/pwned//ci.yml", false)
+	assert.Error(t, err, "a repository can't be an option")
+	_, err = read.NewFetcherNode("git::https://github.com/org/repo.git//ci.yml?ref=--upload-pack=touch", false)
+	assert.Error(t, err, "a ref can't be an option")
+	_, err = read.NewFetcherNode("s3::bucket/ci.yml", false)
+	assert.Error(t, err, "no fetcher is registered for s3")
+}
+
+func TestGitInclude(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git isn't installed")
+	}
+	remoteTaskfiles := experiments.RemoteTaskfiles
+	experiments.RemoteTaskfiles = true
+	t.Cleanup(func() { experiments.RemoteTaskfiles = remoteTaskfiles })
+	// The local repositories must be allowed
+	t.Setenv("GIT_ALLOW_PROTOCOL", "file")
+
+	repo := t.TempDir()
+	git := func(args ...string) {
+		cmd := exec.Command("git", append([]string{"-c", "user.name=Task", "-c", "user.email=task@example.com"}, args...)...)
+		cmd.Dir = repo
+		out, err := cmd.CombinedOutput()
+		require.NoError(t, err, string(out))
+	}
+	ci := "version: '3'\nincludes:\n  common: ../common/Taskfile.yml\ntasks:\n  build: echo build\n"
+	common := "version: '3'\ntasks:\n  lint: echo lint\n"
+	require.NoError(t, os.MkdirAll(filepath.Join(repo, "tasks"), 0o755))
+	require.NoError(t, os.MkdirAll(filepath.Join(repo, "common"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(repo, "tasks", "ci.yml"), []byte(ci), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(repo, "common", "Taskfile.yml"), []byte(common), 0o644))
+	git("init", "--quiet", "--initial-branch=main")
+	git("add", ".")
+	git("commit", "--quiet", "-m", "Add the Taskfiles")
+
+	// The pinned Taskfiles in the lock file are trusted without asking
+	dir := t.TempDir()
+	uri := "git::file://" + filepath.ToSlash(repo) + "//tasks/ci.yml?ref=main"
+	lock, err := read.ReadLock(dir)
+	require.NoError(t, err)
+	lock.Set(uri, []byte(ci))
+	lock.Set("git::file://"+filepath.ToSlash(repo)+"//common/Taskfile.yml?ref=main", []byte(common))
+	require.NoError(t, lock.Save())
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "Taskfile.yml"), []byte("version: '3'\nincludes:\n  ci: "+uri+"\n"), 0o644))
+
+	node, err := read.NewFileNode(dir)
+	require.NoError(t, err)
+	l := &logger.Logger{Stdout: io.Discard, Stderr: io.Discard}
+	var tracked []string
+	released := 0
+	ctx := read.WithTempDirTracker(context.Background(), func(dir string) func() {
+		tracked = append(tracked, dir)
+		return func() { released++ }
+	})
+	tf, err := read.Taskfile(ctx, node, false, false, false, t.TempDir(), l)
+	require.NoError(t, err)
+	assert.NotEmpty(t, tracked, "the temp dirs of the fetches are tracked")
+	assert.Equal(t, len(tracked), released)
+	for _, dir := range tracked {
+		assert.NoDirExists(t, dir)
+	}
+	assert.NotNil(t, tf.Tasks.Get("ci:build"))
+	assert.NotNil(t, tf.Tasks.Get("ci:common:lint"), "the relative includes are fetched from the same repository")
+
+	os.Unsetenv("GIT_ALLOW_PROTOCOL")
+	_, err = read.Taskfile(context.Background(), node, false, false, false, t.TempDir(), l)
+	assert.ErrorContains(t, err, "transport 'file' not allowed", "the local repositories aren't allowed by default")
+}
+
+func TestOCIFetcher(t *testing.T) {
+	ci := []byte("version: '3'\nincludes:\n  lint: lint.yml\ntasks:\n  build: echo build\n")
+	lint := []byte("version: '3'\ntasks:\n  lint: echo lint\n")
+	digest := func(b []byte) string { return fmt.Sprintf("sha256:%x", sha256.Sum256(b)) }
+	layer := func(name string, b []byte) map[string]any {
+		return map[string]any{
+			"mediaType":   "application/vnd.nuvolaris.task.taskfile.v1+yaml",
+			"digest":      digest(b),
+			"size":        len(b),
+			"annotations": map[string]string{"org.opencontainers.image.title": name},
+		}
+	}
+	manifest, err := json.Marshal(map[string]any{
+		"schemaVersion": 2,
+		"mediaType":     "application/vnd.oci.image.manifest.v1+json",
+		"layers":        []any{layer("Taskfile.yml", ci), layer("lint.yml", lint)},
+	})
+	require.NoError(t, err)
+	blobs := map[string][]byte{digest(ci): ci, digest(lint): lint, digest([]byte("other")): []byte("tampered")}
+
+	var srv *httptest.Server
+	srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/token" {
+			assert.Equal(t, "repository:org/tasks:pull", r.URL.Query().Get("scope"))
+			_, _ = w.Write([]byte(`{"token":"secret"}`))
+			return
+		}
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			w.Header().Set("WWW-Authenticate", fmt.Sprintf(`Bearer realm="%s/token",service="registry",scope="repository:org/tasks:pull"`, srv.URL))
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch {
+		// The manifest is served for any digest, like by a tampered registry
+		case r.URL.Path == "/v2/org/tasks/manifests/v1", strings.HasPrefix(r.URL.Path, "/v2/org/tasks/manifests/sha256:"):
+			_, _ = w.Write(manifest)
+		case strings.HasPrefix(r.URL.Path, "/v2/org/tasks/blobs/"):
+			b, ok := blobs[strings.TrimPrefix(r.URL.Path, "/v2/org/tasks/blobs/")]
+			if !ok {
+				w.WriteHeader(http.StatusNotFound)
+			}
+			_, _ = w.Write(b)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+	registry := strings.TrimPrefix(srv.URL, "http://")
+
+	_, err = read.NewFetcherNode("oci://"+registry+"/org/tasks:v1", false)
+	require.NoError(t, err, "the connection is only made when reading")
+
+	node, err := read.NewFetcherNode("oci://"+registry+"/org/tasks:v1", true)
+	require.NoError(t, err)
+	assert.True(t, node.Pinned())
+	assert.Equal(t, "oci://"+registry+"/org/tasks:v1//lint.yml", node.Join("lint.yml"))
+	b, err := node.Read(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, ci, b, "the default Taskfile is read")
+
+	node, err = read.NewFetcherNode("oci://"+registry+"/org/tasks@"+digest(manifest)+"//lint.yml", true)
+	require.NoError(t, err)
+	b, err = node.Read(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, lint, b)
+
+	node, err = read.NewFetcherNode("oci://"+registry+"/org/tasks@"+digest([]byte("other")), true)
+	require.NoError(t, err)
+	_, err = node.Read(context.Background())
+	assert.ErrorContains(t, err, "the digest is "+digest(manifest))
+
+	node, err = read.NewFetcherNode("oci://"+registry+"/org/tasks:v1//missing.yml", true)
+	require.NoError(t, err)
+	_, err = node.Read(context.Background())
+	assert.ErrorContains(t, err, `the artifact has no "missing.yml" file`)
+
+	// A tampered layer doesn't match the digest of the manifest
+	manifest, err = json.Marshal(map[string]any{"layers": []any{layer("Taskfile.yml", []byte("other"))}})
+	require.NoError(t, err)
+	node, err = read.NewFetcherNode("oci://"+registry+"/org/tasks:v1", true)
+	require.NoError(t, err)
+	_, err = node.Read(context.Background())
+	assert.ErrorContains(t, err, "the digest is "+digest([]byte("tampered")))
+
+	node, err = read.NewFetcherNode("oci://ghcr.io/org/tasks", false)
+	require.NoError(t, err)
+	assert.False(t, node.Pinned())
+	_, err = read.NewFetcherNode("oci://ghcr.io", false)
+	assert.Error(t, err)
+	_, err = read.NewFetcherNode("oci://ghcr.io/org/tasks@md5:abc", false)
+	assert.Error(t, err)
+	_, err = read.NewFetcherNode("oci://ghcr.io/org/tasks:v1//../ci.yml", false)
+	assert.Error(t, err)
+}
+
+func TestIncludeVerification(t *testing.T) {
+	const included = "version: '3'\ntasks:\n  build: echo build\n"
+	sum := fmt.Sprintf("sha256:%x", sha256.Sum256([]byte(included)))
+	readWith := func(t *testing.T, include string, insecure bool) error {
+		dir := t.TempDir()
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "included.yml"), []byte(included), 0o644))
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "Taskfile.yml"), []byte("version: '3'\nincludes:\n  inc:\n    taskfile: included.yml\n"+include), 0o644))
+		node, err := read.NewFileNode(dir)
+		require.NoError(t, err)
+		l := &logger.Logger{Stdout: io.Discard, Stderr: io.Discard}
+		_, err = read.Taskfile(context.Background(), node, insecure, false, false, t.TempDir(), l)
+		return err
+	}
+
+	assert.NoError(t, readWith(t, "    checksum: "+sum+"\n", false))
+	err := readWith(t, "    checksum: sha256:"+strings.Repeat("0", 64)+"\n", false)
+	var verificationErr *errors.TaskfileVerificationFailedError
+	require.ErrorAs(t, err, &verificationErr)
+	assert.Contains(t, verificationErr.Reason, "its checksum is "+sum)
+	assert.NoError(t, readWith(t, "    checksum: sha256:"+strings.Repeat("0", 64)+"\n", true), "--insecure doesn't verify")
+
+	if runtime.GOOS == "windows" {
+		t.Skip("cosign is faked with a shell script")
+	}
+	// cosign is faked with a script accepting only the right key
+	bin := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(bin, "cosign"), []byte("#!/bin/sh\necho \"$@\" > \"$COSIGN_ARGS\"\ncase \"$*\" in *--key*good.pub*) exit 0;; esac\necho 'invalid signature' >&2\nexit 1\n"), 0o755))
+	t.Setenv("PATH", bin+string(os.PathListSeparator)+os.Getenv("PATH"))
+	argsFile := filepath.Join(t.TempDir(), "args")
+	t.Setenv("COSIGN_ARGS", argsFile)
+
+	require.NoError(t, readWith(t, "    cosign: { key: good.pub, signature: included.yml.sig }\n", false))
+	args, err := os.ReadFile(argsFile)
+	require.NoError(t, err)
+	assert.Regexp(t, `^verify-blob \S+ --signature /\S+/included.yml.sig --key /\S+/good.pub\n$`, string(args), "the paths are relative to the Taskfile")
+
+	err = readWith(t, "    cosign: { key: bad.pub, signature: included.yml.sig }\n", false)
+	require.ErrorAs(t, err, &verificationErr)
+	assert.Equal(t, "invalid signature", verificationErr.Reason)
+
+	err = readWith(t, "    cosign: { key: good.pub }\n", false)
+	require.ErrorAs(t, err, &verificationErr, "only the OCI artifacts can be verified without a signature")
+}
@@ -0,0 +1,33 @@
+package read
+
+import (
+	"context"
+	"os"
+)
+
+type tempDirTrackerKey struct{}
+
+// WithTempDirTracker returns a context in which track is called with every
+// temp dir created while reading the Taskfiles, like the ones of the Git
+// fetches, so the ones left by a crash can be removed later. The function it
+// returns is called once the dir is removed.
+func WithTempDirTracker(ctx context.Context, track func(dir string) func()) context.Context {
+	return context.WithValue(ctx, tempDirTrackerKey{}, track)
+}
+
+// mkdirTemp creates a temp dir, tracked when the context has a tracker, and
+// returns the function that removes it.
+func mkdirTemp(ctx context.Context, pattern string) (string, func(), error) {
+	dir, err := os.MkdirTemp("", pattern)
+	if err != nil {
+		return "", nil, err
+	}
+	release := func() {}
+	if track, ok := ctx.Value(tempDirTrackerKey{}).(func(dir string) func()); ok {
+		release = track(dir)
+	}
+	return dir, func() {
+		_ = os.RemoveAll(dir)
+		release()
+	}, nil
+}
@@ -0,0 +1,144 @@
+package read
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/nuvolaris/task/v3/errors"
+	"github.com/nuvolaris/task/v3/internal/filepathext"
+	"github.com/nuvolaris/task/v3/internal/logger"
+	"github.com/nuvolaris/task/v3/taskfile"
+)
+
+// verifyTaskfile checks the content of a Taskfile against the checksum and
+// the cosign signature of its include, if any, before anything of it is used.
+// With --insecure, nothing is verified.
+func verifyTaskfile(ctx context.Context, node Node, b []byte, insecure bool, l *logger.Logger) error {
+	verified, ok := node.(interface {
+		verification() (string, *taskfile.IncludeCosign)
+	})
+	if !ok {
+		return nil
+	}
+	want, cosign := verified.verification()
+	if want == "" && cosign == nil {
+		return nil
+	}
+	if insecure {
+		l.Errf(logger.Yellow, "task: [%s] Not verified because of --insecure\n", node.Location())
+		return nil
+	}
+
+	if want != "" {
+		if got := checksum(b); got != strings.ToLower(strings.TrimPrefix(want, "sha256:")) {
+			return &errors.TaskfileVerificationFailedError{
+				URI:    node.Location(),
+				Reason: fmt.Sprintf("its checksum is sha256:%s instead of %s", got, want),
+			}
+		}
+	}
+	if cosign != nil {
+		if err := verifyCosign(ctx, node, b, cosign); err != nil {
+			return err
+		}
+		l.VerboseErrf(logger.Magenta, "task: [%s] Verified the signature\n", node.Location())
+	}
+	return nil
+}
+
+// verifyCosign verifies the signature of the Taskfile with the cosign command.
+// Without a signature, the OCI artifacts are verified with the ones of their
+// registry.
+func verifyCosign(ctx context.Context, node Node, b []byte, c *taskfile.IncludeCosign) error {
+	var args []string
+	if c.Signature == "" && c.Bundle == "" {
+		image, ok := ociImage(node)
+		if !ok {
+			return &errors.TaskfileVerificationFailedError{
+				URI:    node.Location(),
+				Reason: "its cosign has no signature nor bundle, which only the OCI artifacts can do without",
+			}
+		}
+		args = append(args, "verify", image)
+	} else {
+		dir, remove, err := mkdirTemp(ctx, "task-verify-")
+		if err != nil {
+			return err
+		}
+		defer remove()
+		blob := filepath.Join(dir, "Taskfile")
+		if err := os.WriteFile(blob, b, 0o600); err != nil {
+			return err
+		}
+		args = append(args, "verify-blob", blob)
+		if c.Signature != "" {
+			args = append(args, "--signature", c.Signature)
+		}
+		if c.Certificate != "" {
+			args = append(args, "--certificate", c.Certificate)
+		}
+		if c.Bundle != "" {
+			args = append(args, "--bundle", c.Bundle)
+		}
+	}
+	if c.Key != "" {
+		args = append(args, "--key", c.Key)
+	} else {
+		args = append(args, "--certificate-identity", c.Identity, "--certificate-oidc-issuer", c.Issuer)
+	}
+
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, "cosign", args...)
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		reason := strings.TrimSpace(stderr.String())
+		if reason == "" {
+			reason = err.Error()
+		}
+		return &errors.TaskfileVerificationFailedError{URI: node.Location(), Reason: reason}
+	}
+	return nil
+}
+
+// ociImage returns the image of the OCI artifact the node reads, at the
+// digest it was read at when known.
+func ociImage(node Node) (string, bool) {
+	fetcherNode, ok := node.(*FetcherNode)
+	if !ok {
+		return "", false
+	}
+	source, ok := fetcherNode.Source.(*ociSource)
+	if !ok {
+		return "", false
+	}
+	return source.image(), true
+}
+
+// resolveCosign resolves the paths of the cosign of an include, relative to
+// the including Taskfile. The URLs and the other references cosign accepts,
+// like the URIs of a KMS, are left as they are.
+func resolveCosign(c *taskfile.IncludeCosign, dir string) *taskfile.IncludeCosign {
+	if c == nil {
+		return nil
+	}
+	resolve := func(path string) string {
+		if path == "" || strings.Contains(path, "://") || filepathext.IsAbs(path) {
+			return path
+		}
+		return filepathext.SmartJoin(dir, path)
+	}
+	c = c.DeepCopy()
+	c.Key = resolve(c.Key)
+	c.Signature = resolve(c.Signature)
+	c.Certificate = resolve(c.Certificate)
+	c.Bundle = resolve(c.Bundle)
+	return c
+}
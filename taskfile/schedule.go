@@ -0,0 +1,76 @@
+package taskfile
+
+import (
+	"fmt"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/nuvolaris/task/v3/internal/cron"
+)
+
+// Possible values of Schedule.Overlap
+const (
+	// OverlapSkip doesn't run the task when it's due while the previous run
+	// of its schedule is still running. It's the default.
+	OverlapSkip = "skip"
+	// OverlapQueue runs the task once the previous runs of its schedule
+	// finished, so none is lost.
+	OverlapQueue = "queue"
+	// OverlapConcurrent runs the task even when the previous runs of its
+	// schedule are still running.
+	OverlapConcurrent = "concurrent"
+)
+
+// Schedule is when `task --scheduler` runs a task.
+type Schedule struct {
+	Cron    string `schema:",required"`
+	Overlap string `schema:",enum=skip|queue|concurrent"`
+}
+
+func (s *Schedule) DeepCopy() *Schedule {
+	if s == nil {
+		return nil
+	}
+	return &Schedule{
+		Cron:    s.Cron,
+		Overlap: s.Overlap,
+	}
+}
+
+func (s *Schedule) UnmarshalYAML(node *yaml.Node) error {
+	switch node.Kind {
+
+	case yaml.ScalarNode:
+		var expr string
+		if err := node.Decode(&expr); err != nil {
+			return err
+		}
+		if _, err := cron.Parse(expr); err != nil {
+			return fmt.Errorf("yaml: line %d: %w", node.Line, err)
+		}
+		s.Cron = expr
+		return nil
+
+	case yaml.MappingNode:
+		var schedule struct {
+			Cron    string
+			Overlap string
+		}
+		if err := node.Decode(&schedule); err != nil {
+			return err
+		}
+		if _, err := cron.Parse(schedule.Cron); err != nil {
+			return fmt.Errorf("yaml: line %d: %w", node.Line, err)
+		}
+		switch schedule.Overlap {
+		case "", OverlapSkip, OverlapQueue, OverlapConcurrent:
+		default:
+			return fmt.Errorf("yaml: line %d: invalid overlap %q, must be one of skip, queue or concurrent", node.Line, schedule.Overlap)
+		}
+		s.Cron = schedule.Cron
+		s.Overlap = schedule.Overlap
+		return nil
+	}
+
+	return fmt.Errorf("yaml: line %d: cannot unmarshal %s into schedule", node.Line, node.ShortTag())
+}
@@ -0,0 +1,259 @@
+package taskfile
+
+import (
+	"reflect"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/Masterminds/semver/v3"
+)
+
+// Schema returns the JSON Schema of the Taskfile format, derived from the
+// types of this package so it can't get out of sync with them.
+//
+// A field is a property named after its "schema" or "yaml" tag, or after the
+// field itself in snake case. The "schema" tag can also make the property
+// required or list the values it accepts, like in `schema:"run,enum=a|b"`,
+// and `schema:"-"` leaves out the fields set by Task itself. The types with
+// shorthand forms, like the commands given as a string, describe themselves by
+// implementing schemaType.
+func Schema() map[string]any {
+	g := &schemaGenerator{definitions: map[string]any{}}
+	root := g.object(reflect.TypeOf(Taskfile{}))
+	root["$schema"] = "http://json-schema.org/draft-07/schema#"
+	root["title"] = "Taskfile"
+	root["definitions"] = g.definitions
+	return root
+}
+
+// A schemaType describes its own JSON Schema.
+type schemaType interface {
+	jsonSchema(g *schemaGenerator) map[string]any
+}
+
+var schemaTypeOf = reflect.TypeOf((*schemaType)(nil)).Elem()
+
+type schemaGenerator struct {
+	definitions map[string]any
+}
+
+// schema returns the schema of a type, which is a reference to the
+// definitions for the types of this package.
+func (g *schemaGenerator) schema(t reflect.Type) map[string]any {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	switch t {
+	case reflect.TypeOf(semver.Version{}):
+		return map[string]any{"type": []string{"string", "number"}}
+	case reflect.TypeOf(time.Duration(0)):
+		return map[string]any{"type": []string{"string", "integer"}}
+	}
+	if reflect.PointerTo(t).Implements(schemaTypeOf) {
+		return g.ref(t, func() map[string]any {
+			return reflect.New(t).Interface().(schemaType).jsonSchema(g)
+		})
+	}
+	switch t.Kind() {
+	case reflect.String:
+		return map[string]any{"type": "string"}
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]any{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]any{"type": "number"}
+	case reflect.Slice, reflect.Array:
+		return map[string]any{"type": "array", "items": g.schema(t.Elem())}
+	case reflect.Map:
+		return map[string]any{"type": "object", "additionalProperties": g.schema(t.Elem())}
+	case reflect.Struct:
+		return g.ref(t, func() map[string]any { return g.object(t) })
+	}
+	return map[string]any{}
+}
+
+// ref adds the schema of a type to the definitions, once, and returns a
+// reference to it.
+func (g *schemaGenerator) ref(t reflect.Type, build func() map[string]any) map[string]any {
+	name := snakeCase(t.Name())
+	if _, ok := g.definitions[name]; !ok {
+		// Set before building, in case the type refers to itself
+		g.definitions[name] = map[string]any{}
+		g.definitions[name] = build()
+	}
+	return map[string]any{"$ref": "#/definitions/" + name}
+}
+
+// object returns the schema of the fields of a struct.
+func (g *schemaGenerator) object(t reflect.Type) map[string]any {
+	properties := map[string]any{}
+	var required []string
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag, hasTag := f.Tag.Lookup("schema")
+		if !f.IsExported() || tag == "-" || (!hasTag && f.Tag.Get("yaml") == "-") {
+			continue
+		}
+		name, options, _ := strings.Cut(tag, ",")
+		if name == "" {
+			name, _, _ = strings.Cut(f.Tag.Get("yaml"), ",")
+		}
+		if name == "" {
+			name = snakeCase(f.Name)
+		}
+		property := g.schema(f.Type)
+		for _, option := range strings.Split(options, ",") {
+			switch {
+			case option == "required":
+				required = append(required, name)
+			case strings.HasPrefix(option, "enum="):
+				property["enum"] = strings.Split(strings.TrimPrefix(option, "enum="), "|")
+			}
+		}
+		properties[name] = property
+	}
+	object := map[string]any{
+		"type":                 "object",
+		"properties":           properties,
+		"additionalProperties": false,
+	}
+	if len(required) > 0 {
+		object["required"] = required
+	}
+	return object
+}
+
+// snakeCase converts a Go name, like ForwardCLIArgs, to the name of its key,
+// like forward_cli_args.
+func snakeCase(name string) string {
+	runes := []rune(name)
+	var b strings.Builder
+	for i, r := range runes {
+		if i > 0 && unicode.IsUpper(r) {
+			prevLower := unicode.IsLower(runes[i-1])
+			nextLower := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+			if prevLower || (unicode.IsUpper(runes[i-1]) && nextLower) {
+				b.WriteByte('_')
+			}
+		}
+		b.WriteRune(unicode.ToLower(r))
+	}
+	return b.String()
+}
+
+func anyOf(schemas ...map[string]any) map[string]any {
+	return map[string]any{"anyOf": schemas}
+}
+
+func stringSchema() map[string]any {
+	return map[string]any{"type": "string"}
+}
+
+func (*Tasks) jsonSchema(g *schemaGenerator) map[string]any {
+	return map[string]any{"type": "object", "additionalProperties": g.schema(reflect.TypeOf(Task{}))}
+}
+
+func (*Task) jsonSchema(g *schemaGenerator) map[string]any {
+	cmd := g.schema(reflect.TypeOf(Cmd{}))
+	object := g.object(reflect.TypeOf(Task{}))
+	object["properties"].(map[string]any)["cmd"] = cmd
+	return anyOf(stringSchema(), map[string]any{"type": "array", "items": cmd}, object)
+}
+
+func (*Cmd) jsonSchema(g *schemaGenerator) map[string]any {
+	object := g.object(reflect.TypeOf(Cmd{}))
+	object["properties"].(map[string]any)["defer"] = anyOf(stringSchema(), g.schema(reflect.TypeOf(Call{})))
+	return anyOf(stringSchema(), object)
+}
+
+func (*Dep) jsonSchema(g *schemaGenerator) map[string]any {
+	return anyOf(stringSchema(), g.object(reflect.TypeOf(Dep{})))
+}
+
+func (*Stage) jsonSchema(g *schemaGenerator) map[string]any {
+	dep := g.schema(reflect.TypeOf(Dep{}))
+	return anyOf(dep, map[string]any{"type": "array", "items": dep}, g.object(reflect.TypeOf(Stage{})))
+}
+
+func (*For) jsonSchema(g *schemaGenerator) map[string]any {
+	return anyOf(stringSchema(), map[string]any{"type": "array", "items": stringSchema()}, g.object(reflect.TypeOf(For{})))
+}
+
+func (*Arg) jsonSchema(g *schemaGenerator) map[string]any {
+	return anyOf(stringSchema(), g.object(reflect.TypeOf(Arg{})))
+}
+
+func (*Precondition) jsonSchema(g *schemaGenerator) map[string]any {
+	return anyOf(stringSchema(), g.object(reflect.TypeOf(Precondition{})))
+}
+
+func (*Schedule) jsonSchema(g *schemaGenerator) map[string]any {
+	return anyOf(stringSchema(), g.object(reflect.TypeOf(Schedule{})))
+}
+
+func (*Platform) jsonSchema(g *schemaGenerator) map[string]any {
+	return stringSchema()
+}
+
+func (*IncludedTaskfiles) jsonSchema(g *schemaGenerator) map[string]any {
+	return map[string]any{"type": "object", "additionalProperties": g.schema(reflect.TypeOf(IncludedTaskfile{}))}
+}
+
+func (*IncludedTaskfile) jsonSchema(g *schemaGenerator) map[string]any {
+	return anyOf(stringSchema(), g.object(reflect.TypeOf(IncludedTaskfile{})))
+}
+
+func (*Vars) jsonSchema(g *schemaGenerator) map[string]any {
+	return map[string]any{"type": "object", "additionalProperties": g.schema(reflect.TypeOf(Var{}))}
+}
+
+func (*Var) jsonSchema(g *schemaGenerator) map[string]any {
+	object := func(properties map[string]any, required string) map[string]any {
+		return map[string]any{
+			"type":                 "object",
+			"properties":           properties,
+			"required":             []string{required},
+			"additionalProperties": false,
+		}
+	}
+	boolean := map[string]any{"type": "boolean"}
+	return anyOf(
+		map[string]any{"type": []string{"string", "number", "boolean", "null", "array"}},
+		object(map[string]any{"sh": stringSchema(), "lazy": boolean}, "sh"),
+		object(map[string]any{"ref": stringSchema()}, "ref"),
+		object(map[string]any{"prompt": stringSchema(), "default": stringSchema(), "secret": boolean}, "prompt"),
+		map[string]any{"type": "object"},
+	)
+}
+
+func (*Output) jsonSchema(g *schemaGenerator) map[string]any {
+	timestamps := anyOf(
+		map[string]any{"type": "boolean"},
+		map[string]any{"type": "string", "enum": []string{OutputTimestampsRFC3339, OutputTimestampsRelative}},
+	)
+	style := func(t reflect.Type) map[string]any {
+		object := map[string]any{"type": "object", "properties": map[string]any{}, "additionalProperties": false}
+		if t != nil {
+			object = g.object(t)
+		}
+		object["properties"].(map[string]any)["timestamps"] = timestamps
+		return object
+	}
+	return anyOf(
+		map[string]any{"type": "string", "enum": []string{"interleaved", "group", "prefixed", "progress"}},
+		map[string]any{
+			"type": "object",
+			"properties": map[string]any{
+				"interleaved": style(nil),
+				"group":       style(reflect.TypeOf(OutputGroup{})),
+				"prefixed":    style(reflect.TypeOf(OutputPrefixed{})),
+			},
+			"minProperties":        1,
+			"maxProperties":        1,
+			"additionalProperties": false,
+		},
+	)
+}
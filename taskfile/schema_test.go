@@ -0,0 +1,53 @@
+package taskfile_test
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nuvolaris/task/v3/taskfile"
+)
+
+func TestSchema(t *testing.T) {
+	schema := taskfile.Schema()
+	_, err := json.Marshal(schema)
+	require.NoError(t, err)
+
+	definitions := schema["definitions"].(map[string]any)
+	var checkRefs func(v any)
+	checkRefs = func(v any) {
+		switch v := v.(type) {
+		case map[string]any:
+			if ref, ok := v["$ref"].(string); ok {
+				assert.Contains(t, definitions, strings.TrimPrefix(ref, "#/definitions/"))
+			}
+			for _, value := range v {
+				checkRefs(value)
+			}
+		case []map[string]any:
+			for _, value := range v {
+				checkRefs(value)
+			}
+		}
+	}
+	checkRefs(schema)
+
+	properties := schema["properties"].(map[string]any)
+	assert.Contains(t, properties, "env_policy")
+	assert.NotContains(t, properties, "location")
+	assert.Equal(t, []string{"version"}, schema["required"])
+
+	task := definitions["task"].(map[string]any)["anyOf"].([]map[string]any)[2]
+	taskProperties := task["properties"].(map[string]any)
+	assert.Contains(t, taskProperties, "cmd")
+	assert.Contains(t, taskProperties, "deps_concurrency")
+	assert.NotContains(t, taskProperties, "task")
+	assert.NotContains(t, taskProperties, "wildcards")
+	assert.Equal(t, []string{"always", "once", "when_changed"}, taskProperties["run"].(map[string]any)["enum"])
+
+	cmd := definitions["cmd"].(map[string]any)["anyOf"].([]map[string]any)[1]
+	assert.Contains(t, cmd["properties"], "forward_cli_args")
+}
@@ -0,0 +1,42 @@
+package taskfile
+
+// Style is the colors of the elements of the output of Task, like "bold red"
+// or "bright-cyan". See logger.ParseColor for all of them.
+type Style struct {
+	// Text is the plain text.
+	Text string
+	// Command is the echo of a command before it runs.
+	Command string
+	// TaskName is the name of a task in the lists and summaries.
+	TaskName string `yaml:"task_name"`
+	Success  string
+	// Info is for the details, like the verbose messages.
+	Info string
+	// Highlight sets some parts apart, like the aliases in the lists.
+	Highlight string
+	Warning   string
+	Error     string
+}
+
+// Elements returns the colors of the elements that are set, by their name.
+func (s *Style) Elements() map[string]string {
+	if s == nil {
+		return nil
+	}
+	elements := map[string]string{}
+	for name, spec := range map[string]string{
+		"text":      s.Text,
+		"command":   s.Command,
+		"task_name": s.TaskName,
+		"success":   s.Success,
+		"info":      s.Info,
+		"highlight": s.Highlight,
+		"warning":   s.Warning,
+		"error":     s.Error,
+	} {
+		if spec != "" {
+			elements[name] = spec
+		}
+	}
+	return elements
+}
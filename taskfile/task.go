@@ -8,39 +8,68 @@ import (
 	"github.com/nuvolaris/task/v3/internal/deepcopy"
 )
 
+// WatchRestart is the value of Task.Watch that stops the commands of the task,
+// along with the processes they started, when its sources change while
+// watching, and waits for them to exit before running the task again. It's
+// meant for long-running commands, like dev servers.
+const WatchRestart = "restart"
+
 // Task represents a task
 type Task struct {
-	Task                 string
+	Task                 string `schema:"-"`
+	Extends              string
 	Cmds                 []*Cmd
 	Deps                 []*Dep
+	DepsConcurrency      int
+	Pipeline             []*Stage
 	Label                string
 	Desc                 string
 	Prompt               string
 	Summary              string
 	Requires             *Requires
+	Args                 []*Arg
 	Aliases              []string
 	Sources              []string
+	IgnoreGitignored     bool
 	Generates            []string
+	GeneratesMethod      string `schema:",enum=exists|checksum"`
 	Status               []string
 	Preconditions        []*Precondition
 	Dir                  string
 	Set                  []string
 	Shopt                []string
-	Vars                 *Vars
+	Vars                 *Vars `hash:"ignore"`
 	Env                  *Vars
 	Dotenv               []string
 	Silent               bool
 	Interactive          bool
 	Internal             bool
-	Method               string
+	Method               string `schema:",enum=checksum|timestamp|none"`
 	Prefix               string
+	Output               *TaskOutput
 	IgnoreError          bool
-	Run                  string
-	IncludeVars          *Vars
-	IncludedTaskfileVars *Vars
-	IncludedTaskfile     *IncludedTaskfile
+	Run                  string `schema:",enum=always|once|when_changed"`
+	Watch                string `schema:",enum=restart"`
+	Timeout              string
+	Schedule             *Schedule
+	ForwardSignals       []string
+	IncludeVars          *Vars             `schema:"-"`
+	IncludeEnv           *Vars             `schema:"-"`
+	IncludedTaskfileVars *Vars             `schema:"-"`
+	IncludedTaskfile     *IncludedTaskfile `schema:"-"`
 	Platforms            []*Platform
-	Location             *Location
+	Location             *Location `schema:"-"`
+	// Wildcards are the parts of the called name matched by the "*" of a
+	// wildcard task name
+	Wildcards []string `schema:"-"`
+	// EnvPolicy is the env_policy of the root Taskfile, set when compiled
+	EnvPolicy *EnvPolicy `schema:"-"`
+	// Builtins is the builtins setting of the Taskfile the task is defined in
+	Builtins bool `schema:"-"`
+	// VarsHash is the hash of the values of the variables used by the task,
+	// set when compiled, so the state of its sources is kept apart for each
+	// of them
+	VarsHash string `schema:"-" hash:"ignore"`
 }
 
 func (t *Task) Name() string {
@@ -60,6 +89,7 @@ func (t *Task) UnmarshalYAML(node *yaml.Node) error {
 			return err
 		}
 		t.Cmds = append(t.Cmds, &cmd)
+		setCmdLocations(t.Cmds, []*yaml.Node{node})
 		return nil
 
 	// Shortcut syntax for a simple task with a list of commands
@@ -69,58 +99,79 @@ func (t *Task) UnmarshalYAML(node *yaml.Node) error {
 			return err
 		}
 		t.Cmds = cmds
+		setCmdLocations(t.Cmds, node.Content)
 		return nil
 
 	// Full task object
 	case yaml.MappingNode:
 		var task struct {
-			Cmds          []*Cmd
-			Cmd           *Cmd
-			Deps          []*Dep
-			Label         string
-			Desc          string
-			Prompt        string
-			Summary       string
-			Aliases       []string
-			Sources       []string
-			Generates     []string
-			Status        []string
-			Preconditions []*Precondition
-			Dir           string
-			Set           []string
-			Shopt         []string
-			Vars          *Vars
-			Env           *Vars
-			Dotenv        []string
-			Silent        bool
-			Interactive   bool
-			Internal      bool
-			Method        string
-			Prefix        string
-			IgnoreError   bool `yaml:"ignore_error"`
-			Run           string
-			Platforms     []*Platform
-			Requires      *Requires
+			Extends          string
+			Cmds             []*Cmd
+			Cmd              *Cmd
+			Deps             []*Dep
+			DepsConcurrency  int `yaml:"deps_concurrency"`
+			Pipeline         []*Stage
+			Label            string
+			Desc             string
+			Prompt           string
+			Summary          string
+			Aliases          []string
+			Sources          []string
+			IgnoreGitignored bool `yaml:"ignore_gitignored"`
+			Generates        []string
+			GeneratesMethod  string `yaml:"generates_method"`
+			Status           []string
+			Preconditions    []*Precondition
+			Dir              string
+			Set              []string
+			Shopt            []string
+			Vars             *Vars
+			Env              *Vars
+			Dotenv           []string
+			Silent           bool
+			Interactive      bool
+			Internal         bool
+			Method           string
+			Prefix           string
+			Output           *TaskOutput
+			IgnoreError      bool `yaml:"ignore_error"`
+			Run              string
+			Watch            string
+			Timeout          string
+			Schedule         *Schedule
+			ForwardSignals   []string `yaml:"forward_signals"`
+			Platforms        []*Platform
+			Requires         *Requires
+			Args             []*Arg
 		}
 		if err := node.Decode(&task); err != nil {
 			return err
 		}
+		t.Extends = task.Extends
 		if task.Cmd != nil {
 			if task.Cmds != nil {
 				return fmt.Errorf("yaml: line %d: task cannot have both cmd and cmds", node.Line)
 			}
 			t.Cmds = []*Cmd{task.Cmd}
+			setCmdLocations(t.Cmds, []*yaml.Node{mappingValue(node, "cmd")})
 		} else {
 			t.Cmds = task.Cmds
+			if cmds := mappingValue(node, "cmds"); cmds != nil {
+				setCmdLocations(t.Cmds, cmds.Content)
+			}
 		}
 		t.Deps = task.Deps
+		t.DepsConcurrency = task.DepsConcurrency
+		t.Pipeline = task.Pipeline
 		t.Label = task.Label
 		t.Desc = task.Desc
 		t.Prompt = task.Prompt
 		t.Summary = task.Summary
 		t.Aliases = task.Aliases
 		t.Sources = task.Sources
+		t.IgnoreGitignored = task.IgnoreGitignored
 		t.Generates = task.Generates
+		t.GeneratesMethod = task.GeneratesMethod
 		t.Status = task.Status
 		t.Preconditions = task.Preconditions
 		t.Dir = task.Dir
@@ -134,10 +185,28 @@ func (t *Task) UnmarshalYAML(node *yaml.Node) error {
 		t.Internal = task.Internal
 		t.Method = task.Method
 		t.Prefix = task.Prefix
+		t.Output = task.Output
 		t.IgnoreError = task.IgnoreError
 		t.Run = task.Run
+		switch task.Watch {
+		case "", WatchRestart:
+		default:
+			return fmt.Errorf("yaml: line %d: invalid watch %q, must be restart", node.Line, task.Watch)
+		}
+		t.Watch = task.Watch
+		t.Timeout = task.Timeout
+		t.Schedule = task.Schedule
+		t.ForwardSignals = task.ForwardSignals
+		// "forward_signals: []" forwards nothing, instead of the defaults
+		if mappingValue(node, "forward_signals") != nil && t.ForwardSignals == nil {
+			t.ForwardSignals = []string{}
+		}
 		t.Platforms = task.Platforms
 		t.Requires = task.Requires
+		if err := validateArgs(node, task.Args); err != nil {
+			return err
+		}
+		t.Args = task.Args
 		return nil
 	}
 
@@ -146,21 +215,36 @@ func (t *Task) UnmarshalYAML(node *yaml.Node) error {
 
 // DeepCopy creates a new instance of Task and copies
 // data by value from the source struct.
+// setCmdLocations sets the location of each command to the one of the node it
+// was read from.
+func setCmdLocations(cmds []*Cmd, nodes []*yaml.Node) {
+	for i, cmd := range cmds {
+		if cmd != nil && i < len(nodes) {
+			cmd.Location = &Location{Line: nodes[i].Line, Column: nodes[i].Column}
+		}
+	}
+}
+
 func (t *Task) DeepCopy() *Task {
 	if t == nil {
 		return nil
 	}
 	c := &Task{
 		Task:                 t.Task,
+		Extends:              t.Extends,
 		Cmds:                 deepcopy.Slice(t.Cmds),
 		Deps:                 deepcopy.Slice(t.Deps),
+		DepsConcurrency:      t.DepsConcurrency,
+		Pipeline:             deepcopy.Slice(t.Pipeline),
 		Label:                t.Label,
 		Desc:                 t.Desc,
 		Prompt:               t.Prompt,
 		Summary:              t.Summary,
 		Aliases:              deepcopy.Slice(t.Aliases),
 		Sources:              deepcopy.Slice(t.Sources),
+		IgnoreGitignored:     t.IgnoreGitignored,
 		Generates:            deepcopy.Slice(t.Generates),
+		GeneratesMethod:      t.GeneratesMethod,
 		Status:               deepcopy.Slice(t.Status),
 		Preconditions:        deepcopy.Slice(t.Preconditions),
 		Dir:                  t.Dir,
@@ -174,14 +258,25 @@ func (t *Task) DeepCopy() *Task {
 		Internal:             t.Internal,
 		Method:               t.Method,
 		Prefix:               t.Prefix,
+		Output:               t.Output.DeepCopy(),
 		IgnoreError:          t.IgnoreError,
 		Run:                  t.Run,
+		Watch:                t.Watch,
+		Timeout:              t.Timeout,
+		Schedule:             t.Schedule.DeepCopy(),
+		ForwardSignals:       deepcopy.Slice(t.ForwardSignals),
 		IncludeVars:          t.IncludeVars.DeepCopy(),
+		IncludeEnv:           t.IncludeEnv.DeepCopy(),
 		IncludedTaskfileVars: t.IncludedTaskfileVars.DeepCopy(),
 		IncludedTaskfile:     t.IncludedTaskfile.DeepCopy(),
 		Platforms:            deepcopy.Slice(t.Platforms),
 		Location:             t.Location.DeepCopy(),
+		Wildcards:            deepcopy.Slice(t.Wildcards),
+		EnvPolicy:            t.EnvPolicy.DeepCopy(),
+		Builtins:             t.Builtins,
+		VarsHash:             t.VarsHash,
 		Requires:             t.Requires.DeepCopy(),
+		Args:                 deepcopy.Slice(t.Args),
 	}
 	return c
 }
@@ -0,0 +1,71 @@
+package taskfile
+
+import (
+	"fmt"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Destinations of the output of the commands of a task, besides the files
+// TaskOutput.Stdout can be.
+const (
+	// TaskOutputInherit writes the output where Task writes its own. This is
+	// the default.
+	TaskOutputInherit = "inherit"
+	// TaskOutputDiscard drops the standard output.
+	TaskOutputDiscard = "discard"
+	// TaskOutputMerge writes the standard error wherever the standard output
+	// goes.
+	TaskOutputMerge = "merge"
+)
+
+// TaskOutput tells where the output of the commands of a task goes, so the
+// noisy ones can be logged to a file instead.
+type TaskOutput struct {
+	// Stdout is TaskOutputInherit, TaskOutputDiscard or the path of the file
+	// the standard output is written to, relative to the directory of the
+	// task.
+	Stdout string
+	// Stderr is TaskOutputInherit or TaskOutputMerge.
+	Stderr string `schema:",enum=inherit|merge"`
+}
+
+func (o *TaskOutput) UnmarshalYAML(node *yaml.Node) error {
+	var output struct {
+		Stdout string
+		Stderr string
+	}
+	if err := node.Decode(&output); err != nil {
+		return err
+	}
+	switch output.Stderr {
+	case "", TaskOutputInherit, TaskOutputMerge:
+	default:
+		return fmt.Errorf("yaml: line %d: invalid stderr %q, must be %s or %s", node.Line, output.Stderr, TaskOutputInherit, TaskOutputMerge)
+	}
+	o.Stdout = output.Stdout
+	o.Stderr = output.Stderr
+	return nil
+}
+
+// Redirected tells whether the standard output goes somewhere else than where
+// Task writes its own.
+func (o *TaskOutput) Redirected() bool {
+	return o != nil && o.Stdout != "" && o.Stdout != TaskOutputInherit
+}
+
+// File returns the file the standard output is written to, if any.
+func (o *TaskOutput) File() string {
+	if !o.Redirected() || o.Stdout == TaskOutputDiscard {
+		return ""
+	}
+	return o.Stdout
+}
+
+func (o *TaskOutput) DeepCopy() *TaskOutput {
+	if o == nil {
+		return nil
+	}
+	c := *o
+	return &c
+}
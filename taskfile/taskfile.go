@@ -17,21 +17,36 @@ var (
 
 // Taskfile represents a Taskfile.yml
 type Taskfile struct {
-	Location   string
-	Version    *semver.Version
+	Location   string          `schema:"-"`
+	Version    *semver.Version `schema:",required"`
 	Expansions int
 	Output     Output
-	Method     string
+	Method     string `schema:",enum=checksum|timestamp|none"`
 	Includes   *IncludedTaskfiles
 	Set        []string
 	Shopt      []string
+	Builtins   bool
 	Vars       *Vars
 	Env        *Vars
+	EnvPolicy  *EnvPolicy
 	Tasks      Tasks
 	Silent     bool
+	StripANSI  bool
+	Style      *Style
 	Dotenv     []string
-	Run        string
+	Run        string `schema:",enum=always|once|when_changed"`
 	Interval   time.Duration
+	Seed       *int64
+	BeforeAll  []*Dep
+	AfterAll   []*Dep
+	BeforeEach []*Dep
+	AfterEach  []*Dep
+}
+
+// HasHooks tells whether the Taskfile has tasks to run before or after the
+// ones called.
+func (tf *Taskfile) HasHooks() bool {
+	return len(tf.BeforeAll) > 0 || len(tf.AfterAll) > 0 || len(tf.BeforeEach) > 0 || len(tf.AfterEach) > 0
 }
 
 func (tf *Taskfile) UnmarshalYAML(node *yaml.Node) error {
@@ -45,13 +60,22 @@ func (tf *Taskfile) UnmarshalYAML(node *yaml.Node) error {
 			Includes   *IncludedTaskfiles
 			Set        []string
 			Shopt      []string
+			Builtins   bool
 			Vars       *Vars
 			Env        *Vars
+			EnvPolicy  *EnvPolicy `yaml:"env_policy"`
 			Tasks      Tasks
 			Silent     bool
+			StripANSI  bool `yaml:"strip_ansi"`
+			Style      *Style
 			Dotenv     []string
 			Run        string
 			Interval   time.Duration
+			Seed       *int64
+			BeforeAll  []*Dep `yaml:"before_all"`
+			AfterAll   []*Dep `yaml:"after_all"`
+			BeforeEach []*Dep `yaml:"before_each"`
+			AfterEach  []*Dep `yaml:"after_each"`
 		}
 		if err := node.Decode(&taskfile); err != nil {
 			return err
@@ -63,13 +87,22 @@ func (tf *Taskfile) UnmarshalYAML(node *yaml.Node) error {
 		tf.Includes = taskfile.Includes
 		tf.Set = taskfile.Set
 		tf.Shopt = taskfile.Shopt
+		tf.Builtins = taskfile.Builtins
 		tf.Vars = taskfile.Vars
 		tf.Env = taskfile.Env
+		tf.EnvPolicy = taskfile.EnvPolicy
 		tf.Tasks = taskfile.Tasks
 		tf.Silent = taskfile.Silent
+		tf.StripANSI = taskfile.StripANSI
+		tf.Style = taskfile.Style
 		tf.Dotenv = taskfile.Dotenv
 		tf.Run = taskfile.Run
 		tf.Interval = taskfile.Interval
+		tf.Seed = taskfile.Seed
+		tf.BeforeAll = taskfile.BeforeAll
+		tf.AfterAll = taskfile.AfterAll
+		tf.BeforeEach = taskfile.BeforeEach
+		tf.AfterEach = taskfile.AfterEach
 		if tf.Expansions <= 0 {
 			tf.Expansions = 2
 		}
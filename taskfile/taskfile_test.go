@@ -96,3 +96,40 @@ vars:
 		assert.Equal(t, test.expected, test.v)
 	}
 }
+
+func TestIncludeVerificationParse(t *testing.T) {
+	const checksum = "sha256:f46c302190aef1002a93cf600bba671567efbd4b5a248e8d672ad5d4bae6b04e"
+	var it taskfile.IncludedTaskfile
+	require.NoError(t, yaml.Unmarshal([]byte("taskfile: oci://ghcr.io/org/tasks:v1\nchecksum: "+checksum+"\ncosign: { key: cosign.pub }\n"), &it))
+	assert.Equal(t, checksum, it.Checksum)
+	assert.Equal(t, &taskfile.IncludeCosign{Key: "cosign.pub"}, it.Cosign)
+	assert.Equal(t, it.Cosign, it.DeepCopy().Cosign)
+
+	require.NoError(t, yaml.Unmarshal([]byte("taskfile: x.yml\ncosign: { identity: me@example.com, issuer: https://accounts.google.com, bundle: x.bundle }\n"), &it))
+
+	for _, content := range []string{
+		"taskfile: x.yml\nchecksum: md5:abc\n",
+		"taskfile: x.yml\nchecksum: sha256:abc\n",
+		"taskfile: x.yml\ncosign: { identity: me@example.com }\n",
+	} {
+		assert.Error(t, yaml.Unmarshal([]byte(content), &taskfile.IncludedTaskfile{}), content)
+	}
+}
+
+func TestScheduleParse(t *testing.T) {
+	var task taskfile.Task
+	require.NoError(t, yaml.Unmarshal([]byte("cmds: [echo]\nschedule: '0 3 * * *'\n"), &task))
+	assert.Equal(t, &taskfile.Schedule{Cron: "0 3 * * *"}, task.Schedule)
+
+	require.NoError(t, yaml.Unmarshal([]byte("cmds: [echo]\nschedule: { cron: '@every 10m', overlap: queue }\n"), &task))
+	assert.Equal(t, &taskfile.Schedule{Cron: "@every 10m", Overlap: taskfile.OverlapQueue}, task.Schedule)
+	assert.Equal(t, task.Schedule, task.DeepCopy().Schedule)
+
+	for _, content := range []string{
+		"schedule: '0 3 * *'\n",
+		"schedule: { cron: '61 * * * *' }\n",
+		"schedule: { cron: '@daily', overlap: never }\n",
+	} {
+		assert.Error(t, yaml.Unmarshal([]byte(content), &taskfile.Task{}), content)
+	}
+}
@@ -2,6 +2,8 @@ package taskfile
 
 import (
 	"fmt"
+	"regexp"
+	"strings"
 
 	"gopkg.in/yaml.v3"
 
@@ -52,3 +54,37 @@ func (t *Tasks) UnmarshalYAML(node *yaml.Node) error {
 
 	return fmt.Errorf("yaml: line %d: cannot unmarshal %s into tasks", node.Line, node.ShortTag())
 }
+
+// FindWildcard returns the first task whose name has wildcards matching the
+// given name, like "build-*" for "build-frontend", along with the parts of the
+// name matched by every "*".
+func (t *Tasks) FindWildcard(name string) (*Task, []string) {
+	if t == nil {
+		return nil, nil
+	}
+	for _, task := range t.Values() {
+		if !strings.Contains(task.Task, "*") {
+			continue
+		}
+		if wildcards := matchWildcards(task.Task, name); wildcards != nil {
+			return task, wildcards
+		}
+	}
+	return nil, nil
+}
+
+// matchWildcards returns the parts of name matched by the "*" of the pattern,
+// or nil if it doesn't match. Each "*" matches at least one character, and
+// the ones on the left match as little as possible.
+func matchWildcards(pattern, name string) []string {
+	parts := strings.Split(pattern, "*")
+	for i, part := range parts {
+		parts[i] = regexp.QuoteMeta(part)
+	}
+	re := regexp.MustCompile("^" + strings.Join(parts, "(.+?)") + "$")
+	match := re.FindStringSubmatch(name)
+	if match == nil {
+		return nil
+	}
+	return match[1:]
+}
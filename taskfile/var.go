@@ -5,6 +5,7 @@ import (
 
 	"gopkg.in/yaml.v3"
 
+	"github.com/nuvolaris/task/v3/internal/experiments"
 	"github.com/nuvolaris/task/v3/internal/orderedmap"
 )
 
@@ -18,8 +19,8 @@ type Vars struct {
 func (vs *Vars) ToCacheMap() (m map[string]any) {
 	m = make(map[string]any, vs.Len())
 	_ = vs.Range(func(k string, v Var) error {
-		if v.Sh != "" {
-			// Dynamic variable is not yet resolved; trigger
+		if v.Sh != "" || v.Ref != "" || v.Prompt != "" {
+			// Dynamic variable, reference or prompt is not yet resolved; trigger
 			// <no value> to be used in templates.
 			return nil
 		}
@@ -50,6 +51,14 @@ func (vs *Vars) Merge(other *Vars) {
 	vs.OrderedMap.Merge(other.OrderedMap)
 }
 
+// Wrapper around OrderedMap.Exists to ensure we don't get nil pointer errors
+func (vs *Vars) Exists(key string) bool {
+	if vs == nil {
+		return false
+	}
+	return vs.OrderedMap.Exists(key)
+}
+
 // Wrapper around OrderedMap.Len to ensure we don't get nil pointer errors
 func (vs *Vars) Len() int {
 	if vs == nil {
@@ -74,7 +83,15 @@ type Var struct {
 	Static string
 	Live   any
 	Sh     string
+	Ref    string
 	Dir    string
+	// Prompt is asked to the user when the variable isn't set otherwise.
+	// Default is used when it can't be asked, and Secret hides the answer.
+	Prompt  string
+	Default string
+	Secret  bool
+	// Lazy dynamic variables run only when a template uses them.
+	Lazy bool
 }
 
 func (v *Var) UnmarshalYAML(node *yaml.Node) error {
@@ -86,18 +103,117 @@ func (v *Var) UnmarshalYAML(node *yaml.Node) error {
 			return err
 		}
 		v.Static = str
+		if experiments.AnyVariables {
+			var value any
+			if err := node.Decode(&value); err != nil {
+				return err
+			}
+			if _, isString := value.(string); !isString && value != nil {
+				v.Live = value
+			}
+		}
+		return nil
+
+	case yaml.SequenceNode:
+		var list []any
+		if err := node.Decode(&list); err != nil {
+			return err
+		}
+		v.Live = list
 		return nil
 
 	case yaml.MappingNode:
-		var sh struct {
-			Sh string
+		// A map with a "sh" key (and optionally "lazy") is a dynamic variable
+		isDynamic, err := isDynamicVar(node)
+		if err != nil {
+			return err
 		}
-		if err := node.Decode(&sh); err != nil {
+		if isDynamic {
+			var sh struct {
+				Sh   string
+				Lazy bool
+			}
+			if err := node.Decode(&sh); err != nil {
+				return err
+			}
+			v.Sh = sh.Sh
+			v.Lazy = sh.Lazy
+			return nil
+		}
+		// A map with a single "ref" key references another variable
+		if len(node.Content) == 2 && node.Content[0].Value == "ref" {
+			var ref struct {
+				Ref string
+			}
+			if err := node.Decode(&ref); err != nil {
+				return err
+			}
+			v.Ref = ref.Ref
+			return nil
+		}
+		// A map with a "prompt" key (and optionally "default" and "secret")
+		// asks the user for the value
+		if isPromptVar(node) {
+			var prompt struct {
+				Prompt  string
+				Default string
+				Secret  bool
+			}
+			if err := node.Decode(&prompt); err != nil {
+				return err
+			}
+			if prompt.Prompt == "" {
+				return fmt.Errorf("yaml: line %d: the prompt of a variable can't be empty", node.Line)
+			}
+			v.Prompt = prompt.Prompt
+			v.Default = prompt.Default
+			v.Secret = prompt.Secret
+			return nil
+		}
+		var m map[string]any
+		if err := node.Decode(&m); err != nil {
 			return err
 		}
-		v.Sh = sh.Sh
+		v.Live = m
 		return nil
 	}
 
 	return fmt.Errorf("yaml: line %d: cannot unmarshal %s into variable", node.Line, node.ShortTag())
 }
+
+// isDynamicVar returns whether the map is a dynamic variable. Other keys next
+// to "sh" are an error, rather than making it a map, since they're most likely
+// a typo or an option dynamic variables don't have.
+func isDynamicVar(node *yaml.Node) (bool, error) {
+	hasSh := false
+	var unknown *yaml.Node
+	for i := 0; i < len(node.Content); i += 2 {
+		switch node.Content[i].Value {
+		case "sh":
+			hasSh = true
+		case "lazy":
+		default:
+			if unknown == nil {
+				unknown = node.Content[i]
+			}
+		}
+	}
+	if hasSh && unknown != nil {
+		return false, fmt.Errorf("yaml: line %d: unknown key %q of a dynamic variable, which only takes \"sh\" and \"lazy\"", unknown.Line, unknown.Value)
+	}
+	return hasSh, nil
+}
+
+func isPromptVar(node *yaml.Node) bool {
+	hasPrompt := false
+	for i := 0; i < len(node.Content); i += 2 {
+		switch node.Content[i].Value {
+		case "prompt":
+			hasPrompt = true
+		case "default", "secret":
+		default:
+			return false
+		}
+	}
+	return hasPrompt
+}
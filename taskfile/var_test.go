@@ -0,0 +1,63 @@
+package taskfile_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+
+	"github.com/nuvolaris/task/v3/taskfile"
+)
+
+func TestVarParse(t *testing.T) {
+	tests := []struct {
+		content  string
+		expected taskfile.Var
+	}{
+		{
+			`foo`,
+			taskfile.Var{Static: "foo"},
+		},
+		{
+			`sh: echo foo`,
+			taskfile.Var{Sh: "echo foo"},
+		},
+		{
+			`ref: FOO`,
+			taskfile.Var{Ref: "FOO"},
+		},
+		{
+			`[a, 1, true]`,
+			taskfile.Var{Live: []any{"a", 1, true}},
+		},
+		{
+			`{name: foo, ports: [80, 443]}`,
+			taskfile.Var{Live: map[string]any{"name": "foo", "ports": []any{80, 443}}},
+		},
+		{
+			`prompt: Your name?`,
+			taskfile.Var{Prompt: "Your name?"},
+		},
+		{
+			`{prompt: "Token?", default: none, secret: true}`,
+			taskfile.Var{Prompt: "Token?", Default: "none", Secret: true},
+		},
+		{
+			`{prompt: "Your name?", color: blue}`,
+			taskfile.Var{Live: map[string]any{"prompt": "Your name?", "color": "blue"}},
+		},
+	}
+	for _, test := range tests {
+		var v taskfile.Var
+		require.NoError(t, yaml.Unmarshal([]byte(test.content), &v))
+		assert.Equal(t, test.expected, v)
+	}
+}
+
+func TestVarParseUnknownKeyOfDynamicVar(t *testing.T) {
+	var v taskfile.Var
+	err := yaml.Unmarshal([]byte(`{sh: echo foo, dir: bar}`), &v)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `unknown key "dir" of a dynamic variable`)
+}
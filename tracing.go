@@ -0,0 +1,91 @@
+package task
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/nuvolaris/sh/v3/interp"
+
+	"github.com/nuvolaris/task/v3/internal/logger"
+	"github.com/nuvolaris/task/v3/internal/otel"
+	"github.com/nuvolaris/task/v3/taskfile"
+)
+
+// exportTimeout is how long the spans of a run can take to be exported, so a
+// collector that's down never blocks Task.
+const exportTimeout = 10 * time.Second
+
+// traceTask is the middleware starting the span of every task, around its
+// deps and commands.
+func (e *Executor) traceTask(next TaskRunner) TaskRunner {
+	return func(ctx context.Context, t *taskfile.Task, call taskfile.Call) (err error) {
+		ctx, span := e.startTaskSpan(ctx, t, call)
+		defer func() { span.Finish(err) }()
+		return next(ctx, t, call)
+	}
+}
+
+// traceCommand is the middleware starting the span of every command.
+func (e *Executor) traceCommand(next commandRunner) commandRunner {
+	return func(ctx context.Context, t *taskfile.Task, cmd *taskfile.Cmd) error {
+		ctx, span := e.startCommandSpan(ctx, t, cmd)
+		err := next(ctx, t, cmd)
+		finishCommandSpan(span, err)
+		return err
+	}
+}
+
+// startTaskSpan starts the span of a task. The variables given to the call
+// are attributes of the span, except for the secret ones.
+func (e *Executor) startTaskSpan(ctx context.Context, t *taskfile.Task, call taskfile.Call) (context.Context, *otel.Span) {
+	ctx, span := e.tracer.Start(ctx, t.Name())
+	if span == nil {
+		return ctx, nil
+	}
+	span.SetAttribute("task.name", t.Task)
+	if t.Location != nil {
+		span.SetAttribute("task.taskfile", t.Location.Taskfile)
+	}
+	_ = call.Vars.Range(func(k string, v taskfile.Var) error {
+		if v.Secret {
+			return nil
+		}
+		if v.Live != nil {
+			span.SetAttribute("task.vars."+k, v.Live)
+		} else {
+			span.SetAttribute("task.vars."+k, v.Static)
+		}
+		return nil
+	})
+	return ctx, span
+}
+
+// startCommandSpan starts the span of a command, named after its first line.
+func (e *Executor) startCommandSpan(ctx context.Context, t *taskfile.Task, cmd *taskfile.Cmd) (context.Context, *otel.Span) {
+	name, _, _ := strings.Cut(cmd.Cmd, "\n")
+	ctx, span := e.tracer.Start(ctx, name)
+	span.SetAttribute("task.name", t.Task)
+	span.SetAttribute("command", cmd.Cmd)
+	return ctx, span
+}
+
+func finishCommandSpan(span *otel.Span, err error) {
+	switch status, ok := interp.IsExitStatus(err); {
+	case err == nil:
+		span.SetAttribute("exit_code", 0)
+	case ok:
+		span.SetAttribute("exit_code", int(status))
+	}
+	span.Finish(err)
+}
+
+// exportSpans sends the spans of the last run. Failing to do so never fails
+// the run.
+func (e *Executor) exportSpans(exporter otel.Exporter) {
+	ctx, cancel := context.WithTimeout(context.Background(), exportTimeout)
+	defer cancel()
+	if err := exporter.Export(ctx, e.tracer.Spans()); err != nil {
+		e.Logger.Errf(logger.Red, "task: unable to export the OpenTelemetry spans: %v\n", err)
+	}
+}
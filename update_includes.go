@@ -0,0 +1,149 @@
+package task
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+
+	"github.com/Masterminds/semver/v3"
+	"gopkg.in/yaml.v3"
+
+	"github.com/nuvolaris/task/v3/internal/filepathext"
+	"github.com/nuvolaris/task/v3/internal/logger"
+	"github.com/nuvolaris/task/v3/taskfile/read"
+)
+
+// UpdateIncludes bumps every pinned versioned include (like
+// github.com/org/tasks//build.yml?ref=v1.2.0) of the root Taskfile and its
+// local includes to the newest semantic version tag of its repository, and
+// locks the new versions in Taskfile.lock. The Taskfiles are edited in place,
+// like RenameTask does.
+func (e *Executor) UpdateIncludes(ctx context.Context) error {
+	e.setupStdFiles()
+	e.setupLogger()
+	if err := e.validate(); err != nil {
+		return err
+	}
+	if err := e.setCurrentDir(); err != nil {
+		return err
+	}
+
+	root := filepathext.SmartJoin(e.Dir, e.Entrypoint)
+	files, err := loadRenameFiles(root, "", map[[2]string]bool{})
+	if err != nil {
+		return err
+	}
+	// The namespaces don't matter here, only the files
+	files = mergeRenameFiles(files)
+	lock, err := read.ReadLock(filepath.Dir(root))
+	if err != nil {
+		return err
+	}
+
+	var updated int
+	for _, f := range files {
+		for _, node := range includedTaskfileNodes(f.root) {
+			if !read.IsVersionedURI(node.Value) {
+				continue
+			}
+			uri, err := read.ParseVersionedURI(node.Value)
+			if err != nil {
+				return err
+			}
+			if !uri.Pinned() {
+				continue
+			}
+			latest, err := uri.LatestTag(ctx)
+			if err != nil {
+				return err
+			}
+			if !isNewerTag(latest, uri.Ref) {
+				e.Logger.VerboseOutf(logger.Magenta, "task: %s is up to date\n", uri)
+				continue
+			}
+			newURI := uri.WithRef(latest)
+			if e.Dry {
+				e.Logger.Outf(logger.Green, "task: %s would be updated to %s\n", uri, latest)
+				continue
+			}
+
+			// The user asked for the new version, so it's locked right away
+			// instead of asking to trust it on the next run
+			includeNode, err := read.NewNode(newURI.String(), e.Insecure)
+			if err != nil {
+				return err
+			}
+			b, err := includeNode.Read(ctx)
+			if err != nil {
+				return err
+			}
+			lock.Remove(uri.String())
+			lock.Set(newURI.String(), b)
+			f.addEdit(node, newURI.String())
+			e.Logger.Outf(logger.Green, "task: %s updated to %s\n", uri, latest)
+			updated++
+		}
+	}
+
+	for _, f := range files {
+		if len(f.edits) == 0 {
+			continue
+		}
+		content, err := f.apply()
+		if err != nil {
+			return err
+		}
+		info, err := os.Stat(f.path)
+		if err != nil {
+			return err
+		}
+		if err := os.WriteFile(f.path, content, info.Mode()); err != nil {
+			return err
+		}
+	}
+	if err := lock.Save(); err != nil {
+		return err
+	}
+
+	if updated == 0 && !e.Dry {
+		e.Logger.Outf(logger.Green, "task: The pinned includes are up to date\n")
+	}
+	return nil
+}
+
+// includedTaskfileNodes returns the nodes with the Taskfile of every include
+// of a Taskfile, in both the short and the long form.
+func includedTaskfileNodes(root *yaml.Node) []*yaml.Node {
+	includes := mappingValue(root, "includes")
+	if includes == nil || includes.Kind != yaml.MappingNode {
+		return nil
+	}
+	var nodes []*yaml.Node
+	for i := 1; i < len(includes.Content); i += 2 {
+		node := includes.Content[i]
+		if node.Kind == yaml.MappingNode {
+			node = mappingValue(node, "taskfile")
+		}
+		if node != nil && node.Kind == yaml.ScalarNode {
+			nodes = append(nodes, node)
+		}
+	}
+	return nodes
+}
+
+// isNewerTag tells whether the latest tag is newer than the current ref. Refs
+// that aren't semantic versions, like branches, are never updated.
+func isNewerTag(latest, current string) bool {
+	if latest == "" {
+		return false
+	}
+	latestVersion, err := semver.NewVersion(latest)
+	if err != nil {
+		return false
+	}
+	currentVersion, err := semver.NewVersion(current)
+	if err != nil {
+		return false
+	}
+	return latestVersion.GreaterThan(currentVersion)
+}
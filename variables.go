@@ -1,11 +1,17 @@
 package task
 
 import (
+	"context"
+	"encoding/json"
+	"fmt"
 	"os"
 	"path/filepath"
+	"reflect"
 	"strings"
 
 	"github.com/joho/godotenv"
+	"github.com/zeebo/xxh3"
+	"golang.org/x/exp/slices"
 
 	"github.com/nuvolaris/task/v3/internal/execext"
 	"github.com/nuvolaris/task/v3/internal/filepathext"
@@ -15,17 +21,27 @@ import (
 )
 
 // CompiledTask returns a copy of a task, but replacing variables in almost all
-// properties using the Go template package.
-func (e *Executor) CompiledTask(call taskfile.Call) (*taskfile.Task, error) {
-	return e.compiledTask(call, true)
+// properties using the Go template package. It's the task exactly as it would
+// run for the given call: its Vars are the variables it was compiled with, its
+// Env has the environment of the Taskfile, its dotenv files and its own, and
+// the commands of its "for" loops are expanded. The Taskfile is read first, if
+// it wasn't already.
+func (e *Executor) CompiledTask(ctx context.Context, call taskfile.Call) (*taskfile.Task, error) {
+	if err := e.setupIfNeeded(ctx); err != nil {
+		return nil, err
+	}
+	return e.compiledTask(ctx, call, true)
 }
 
 // FastCompiledTask is like CompiledTask, but it skippes dynamic variables.
-func (e *Executor) FastCompiledTask(call taskfile.Call) (*taskfile.Task, error) {
-	return e.compiledTask(call, false)
+func (e *Executor) FastCompiledTask(ctx context.Context, call taskfile.Call) (*taskfile.Task, error) {
+	if err := e.setupIfNeeded(ctx); err != nil {
+		return nil, err
+	}
+	return e.compiledTask(ctx, call, false)
 }
 
-func (e *Executor) compiledTask(call taskfile.Call, evaluateShVars bool) (*taskfile.Task, error) {
+func (e *Executor) compiledTask(ctx context.Context, call taskfile.Call, evaluateShVars bool) (*taskfile.Task, error) {
 	origTask, err := e.GetTask(call)
 	if err != nil {
 		return nil, err
@@ -33,29 +49,39 @@ func (e *Executor) compiledTask(call taskfile.Call, evaluateShVars bool) (*taskf
 
 	var vars *taskfile.Vars
 	if evaluateShVars {
-		vars, err = e.Compiler.GetVariables(origTask, call)
+		vars, err = e.Compiler.GetVariables(ctx, origTask, call)
 	} else {
-		vars, err = e.Compiler.FastGetVariables(origTask, call)
+		vars, err = e.Compiler.FastGetVariables(ctx, origTask, call)
 	}
 	if err != nil {
 		return nil, err
 	}
 
-	r := templater.Templater{Vars: vars, RemoveNoValue: e.Taskfile.Version.Compare(taskfile.V3) >= 0}
+	r := templater.Templater{
+		Vars:          vars,
+		RemoveNoValue: e.Taskfile.Version.Compare(taskfile.V3) >= 0,
+		Dir:           e.Dir,
+		ResolveLazy: func(v taskfile.Var) (string, error) {
+			return e.Compiler.HandleDynamicVar(ctx, v, e.Dir)
+		},
+	}
 
 	new := taskfile.Task{
 		Task:                 origTask.Task,
+		Extends:              origTask.Extends,
 		Label:                r.Replace(origTask.Label),
 		Desc:                 r.Replace(origTask.Desc),
 		Prompt:               r.Replace(origTask.Prompt),
 		Summary:              r.Replace(origTask.Summary),
 		Aliases:              origTask.Aliases,
 		Sources:              r.ReplaceSlice(origTask.Sources),
+		IgnoreGitignored:     origTask.IgnoreGitignored,
 		Generates:            r.ReplaceSlice(origTask.Generates),
+		GeneratesMethod:      r.Replace(origTask.GeneratesMethod),
 		Dir:                  r.Replace(origTask.Dir),
 		Set:                  origTask.Set,
 		Shopt:                origTask.Shopt,
-		Vars:                 nil,
+		Vars:                 vars,
 		Env:                  nil,
 		Dotenv:               r.ReplaceSlice(origTask.Dotenv),
 		Silent:               origTask.Silent,
@@ -63,13 +89,25 @@ func (e *Executor) compiledTask(call taskfile.Call, evaluateShVars bool) (*taskf
 		Internal:             origTask.Internal,
 		Method:               r.Replace(origTask.Method),
 		Prefix:               r.Replace(origTask.Prefix),
+		Output:               replaceTaskOutput(&r, origTask.Output),
 		IgnoreError:          origTask.IgnoreError,
+		DepsConcurrency:      origTask.DepsConcurrency,
 		Run:                  r.Replace(origTask.Run),
+		Watch:                origTask.Watch,
+		Timeout:              r.Replace(origTask.Timeout),
+		Schedule:             origTask.Schedule,
+		ForwardSignals:       origTask.ForwardSignals,
 		IncludeVars:          origTask.IncludeVars,
+		IncludeEnv:           origTask.IncludeEnv,
 		IncludedTaskfileVars: origTask.IncludedTaskfileVars,
 		Platforms:            origTask.Platforms,
 		Location:             origTask.Location,
+		Wildcards:            origTask.Wildcards,
 		Requires:             origTask.Requires,
+		Args:                 origTask.Args,
+		EnvPolicy:            e.envPolicy,
+		Builtins:             origTask.Builtins,
+		VarsHash:             varsHash(origTask, vars),
 	}
 	new.Dir, err = execext.Expand(new.Dir)
 	if err != nil {
@@ -104,10 +142,20 @@ func (e *Executor) compiledTask(call taskfile.Call, evaluateShVars bool) (*taskf
 	new.Env = &taskfile.Vars{}
 	new.Env.Merge(r.ReplaceVars(e.Taskfile.Env))
 	new.Env.Merge(r.ReplaceVars(dotenvEnvs))
+	new.Env.Merge(r.ReplaceVars(origTask.IncludeEnv))
 	new.Env.Merge(r.ReplaceVars(origTask.Env))
 	if evaluateShVars {
 		err = new.Env.Range(func(k string, v taskfile.Var) error {
-			static, err := e.Compiler.HandleDynamicVar(v, new.Dir)
+			// Environment variables are always strings
+			if v.Live != nil {
+				value, err := envValue(v.Live)
+				if err != nil {
+					return fmt.Errorf("task: Unable to set the env var %q: %w", k, err)
+				}
+				new.Env.Set(k, taskfile.Var{Static: value})
+				return nil
+			}
+			static, err := e.Compiler.HandleDynamicVar(ctx, v, new.Dir)
 			if err != nil {
 				return err
 			}
@@ -126,32 +174,40 @@ func (e *Executor) compiledTask(call taskfile.Call, evaluateShVars bool) (*taskf
 				continue
 			}
 			if cmd.For != nil {
-				var list []string
+				var list []any
 				// Get the list from the explicit for list
 				if cmd.For.List != nil && len(cmd.For.List) > 0 {
-					list = cmd.For.List
+					list = toAnySlice(cmd.For.List)
 				}
 				// Get the list from the task sources
 				if cmd.For.From == "sources" {
-					list, err = fingerprint.Globs(new.Dir, new.Sources)
+					sources, err := fingerprint.Sources(&new)
 					if err != nil {
 						return nil, err
 					}
 					// Make the paths relative to the task dir
-					for i, v := range list {
-						if list[i], err = filepath.Rel(new.Dir, v); err != nil {
+					for i, v := range sources {
+						if sources[i], err = filepath.Rel(new.Dir, v); err != nil {
 							return nil, err
 						}
 					}
+					list = toAnySlice(sources)
 				}
-				// Get the list from a variable and split it up
+				// Get the list from a variable and split it up, unless the
+				// variable already is a list
 				if cmd.For.Var != "" {
 					if vars != nil {
 						v := vars.Get(cmd.For.Var)
-						if cmd.For.Split != "" {
-							list = strings.Split(v.Static, cmd.For.Split)
+						value := v.Static
+						if v.Live != nil {
+							value = fmt.Sprint(v.Live)
+						}
+						if items, ok := v.Live.([]any); ok {
+							list = items
+						} else if cmd.For.Split != "" {
+							list = toAnySlice(strings.Split(value, cmd.For.Split))
 						} else {
-							list = strings.Fields(v.Static)
+							list = toAnySlice(strings.Fields(value))
 						}
 					}
 				}
@@ -162,35 +218,42 @@ func (e *Executor) compiledTask(call taskfile.Call, evaluateShVars bool) (*taskf
 				} else {
 					as = "ITEM"
 				}
-				// Create a new command for each item in the list
+				// Create a new command for each item in the list. They share
+				// the for of the loop, so they can run in parallel.
 				for _, loopValue := range list {
 					extra := map[string]any{
 						as: loopValue,
 					}
 					new.Cmds = append(new.Cmds, &taskfile.Cmd{
-						Cmd:         r.ReplaceWithExtra(cmd.Cmd, extra),
-						Task:        r.ReplaceWithExtra(cmd.Task, extra),
-						Silent:      cmd.Silent,
-						Set:         cmd.Set,
-						Shopt:       cmd.Shopt,
-						Vars:        r.ReplaceVarsWithExtra(cmd.Vars, extra),
-						IgnoreError: cmd.IgnoreError,
-						Defer:       cmd.Defer,
-						Platforms:   cmd.Platforms,
+						Cmd:            r.ReplaceWithExtra(cmd.Cmd, extra),
+						Task:           r.ReplaceWithExtra(cmd.Task, extra),
+						For:            cmd.For,
+						Parallel:       cmd.Parallel,
+						Silent:         cmd.Silent,
+						Set:            cmd.Set,
+						Shopt:          cmd.Shopt,
+						Vars:           forwardCLIArgs(cmd.ForwardCLIArgs, r.ReplaceVarsWithExtra(cmd.Vars, extra), vars),
+						ForwardCLIArgs: cmd.ForwardCLIArgs,
+						IgnoreError:    cmd.IgnoreError,
+						Defer:          cmd.Defer,
+						Platforms:      cmd.Platforms,
+						Location:       cmd.Location,
 					})
 				}
 				continue
 			}
 			new.Cmds = append(new.Cmds, &taskfile.Cmd{
-				Cmd:         r.Replace(cmd.Cmd),
-				Task:        r.Replace(cmd.Task),
-				Silent:      cmd.Silent,
-				Set:         cmd.Set,
-				Shopt:       cmd.Shopt,
-				Vars:        r.ReplaceVars(cmd.Vars),
-				IgnoreError: cmd.IgnoreError,
-				Defer:       cmd.Defer,
-				Platforms:   cmd.Platforms,
+				Cmd:            r.Replace(cmd.Cmd),
+				Task:           r.Replace(cmd.Task),
+				Silent:         cmd.Silent,
+				Set:            cmd.Set,
+				Shopt:          cmd.Shopt,
+				Vars:           forwardCLIArgs(cmd.ForwardCLIArgs, r.ReplaceVars(cmd.Vars), vars),
+				ForwardCLIArgs: cmd.ForwardCLIArgs,
+				IgnoreError:    cmd.IgnoreError,
+				Defer:          cmd.Defer,
+				Platforms:      cmd.Platforms,
+				Location:       cmd.Location,
 			})
 		}
 	}
@@ -201,12 +264,39 @@ func (e *Executor) compiledTask(call taskfile.Call, evaluateShVars bool) (*taskf
 				continue
 			}
 			new.Deps = append(new.Deps, &taskfile.Dep{
-				Task:   r.Replace(dep.Task),
-				Vars:   r.ReplaceVars(dep.Vars),
-				Silent: dep.Silent,
+				Task:           r.Replace(dep.Task),
+				Vars:           forwardCLIArgs(dep.ForwardCLIArgs, r.ReplaceVars(dep.Vars), vars),
+				Silent:         dep.Silent,
+				ForwardCLIArgs: dep.ForwardCLIArgs,
+				IgnoreError:    dep.IgnoreError,
 			})
 		}
 	}
+	if len(origTask.Pipeline) > 0 {
+		new.Pipeline = make([]*taskfile.Stage, 0, len(origTask.Pipeline))
+		for _, stage := range origTask.Pipeline {
+			if stage == nil {
+				continue
+			}
+			newStage := &taskfile.Stage{
+				Name:  r.Replace(stage.Name),
+				Tasks: make([]*taskfile.Dep, 0, len(stage.Tasks)),
+			}
+			for _, dep := range stage.Tasks {
+				if dep == nil {
+					continue
+				}
+				newStage.Tasks = append(newStage.Tasks, &taskfile.Dep{
+					Task:           r.Replace(dep.Task),
+					Vars:           forwardCLIArgs(dep.ForwardCLIArgs, r.ReplaceVars(dep.Vars), vars),
+					Silent:         dep.Silent,
+					ForwardCLIArgs: dep.ForwardCLIArgs,
+					IgnoreError:    dep.IgnoreError,
+				})
+			}
+			new.Pipeline = append(new.Pipeline, newStage)
+		}
+	}
 
 	if len(origTask.Preconditions) > 0 {
 		new.Preconditions = make([]*taskfile.Precondition, 0, len(origTask.Preconditions))
@@ -215,8 +305,12 @@ func (e *Executor) compiledTask(call taskfile.Call, evaluateShVars bool) (*taskf
 				continue
 			}
 			new.Preconditions = append(new.Preconditions, &taskfile.Precondition{
-				Sh:  r.Replace(precond.Sh),
-				Msg: r.Replace(precond.Msg),
+				Sh:            r.Replace(precond.Sh),
+				FileExists:    r.Replace(precond.FileExists),
+				EnvSet:        r.Replace(precond.EnvSet),
+				CommandExists: r.Replace(precond.CommandExists),
+				Msg:           r.Replace(precond.Msg),
+				OnFailure:     precond.OnFailure,
 			})
 		}
 	}
@@ -242,3 +336,117 @@ func (e *Executor) compiledTask(call taskfile.Call, evaluateShVars bool) (*taskf
 
 	return &new, r.Err()
 }
+
+// replaceTaskOutput templates the file the output of the task is written to.
+func replaceTaskOutput(r *templater.Templater, o *taskfile.TaskOutput) *taskfile.TaskOutput {
+	if o == nil {
+		return nil
+	}
+	return &taskfile.TaskOutput{Stdout: r.Replace(o.Stdout), Stderr: o.Stderr}
+}
+
+func toAnySlice(s []string) []any {
+	items := make([]any, len(s))
+	for i, v := range s {
+		items[i] = v
+	}
+	return items
+}
+
+// forwardCLIArgs adds the CLI_ARGS and CLI_ARGS_LIST of the calling task to
+// the vars of a nested call, unless the call sets them itself. The values are
+// passed as they are, so the quoting of each argument is kept.
+func forwardCLIArgs(forward bool, callVars, vars *taskfile.Vars) *taskfile.Vars {
+	if !forward || vars == nil || !vars.Exists("CLI_ARGS") {
+		return callVars
+	}
+	if callVars == nil {
+		callVars = &taskfile.Vars{}
+	}
+	for _, name := range []string{"CLI_ARGS", "CLI_ARGS_LIST"} {
+		if vars.Exists(name) && !callVars.Exists(name) {
+			callVars.Set(name, vars.Get(name))
+		}
+	}
+	return callVars
+}
+
+// fingerprintIgnoredVars are the special variables left out of the VarsHash
+// of a task, since they don't change what it does or, like CHECKSUM, are the
+// state itself.
+var fingerprintIgnoredVars = map[string]bool{
+	"TASK":             true,
+	"ROOT_DIR":         true,
+	"TASKFILE_DIR":     true,
+	"USER_WORKING_DIR": true,
+	"TASK_VERSION":     true,
+	"CHECKSUM":         true,
+	"TIMESTAMP":        true,
+}
+
+// varsHash returns the hash of the values of the variables used by the
+// commands, sources, generates, status, dir, dotenv files and env of a task,
+// or an empty string when it uses none. So "task build IMAGE=foo" and
+// "task build IMAGE=bar" don't share the checksum of the sources.
+func varsHash(t *taskfile.Task, vars *taskfile.Vars) string {
+	templates := []string{t.Dir}
+	for _, list := range [][]string{t.Sources, t.Generates, t.Status, t.Dotenv} {
+		templates = append(templates, list...)
+	}
+	var names []string
+	for _, cmd := range t.Cmds {
+		if cmd == nil || cmd.Task != "" {
+			continue
+		}
+		templates = append(templates, cmd.Cmd)
+		if cmd.For != nil && cmd.For.Var != "" {
+			names = append(names, cmd.For.Var)
+		}
+	}
+	_ = t.Env.Range(func(_ string, v taskfile.Var) error {
+		templates = append(templates, v.Static, v.Sh)
+		return nil
+	})
+	for _, s := range templates {
+		if !strings.Contains(s, "{{") {
+			continue
+		}
+		if tmpl, err := templater.Parse(s); err == nil {
+			names = append(names, templater.VarNames(tmpl)...)
+		}
+	}
+
+	slices.Sort(names)
+	names = slices.Compact(names)
+	h := xxh3.New()
+	empty := true
+	for _, name := range names {
+		if fingerprintIgnoredVars[name] || !vars.Exists(name) {
+			continue
+		}
+		v := vars.Get(name)
+		value := v.Static
+		if v.Live != nil {
+			value = fmt.Sprint(v.Live)
+		}
+		fmt.Fprintf(h, "%s=%q\n", name, value)
+		empty = false
+	}
+	if empty {
+		return ""
+	}
+	return fmt.Sprintf("%016x", h.Sum64())
+}
+
+// envValue returns the string of a value set to an environment variable: the
+// JSON of lists and maps, which a command can parse, and the value itself for
+// the rest.
+func envValue(value any) (string, error) {
+	switch reflect.ValueOf(value).Kind() {
+	case reflect.Slice, reflect.Array, reflect.Map:
+		b, err := json.Marshal(value)
+		return string(b), err
+	default:
+		return fmt.Sprint(value), nil
+	}
+}
@@ -0,0 +1,35 @@
+package task
+
+import (
+	"runtime"
+
+	"github.com/nuvolaris/task/v3/internal/experiments"
+	"github.com/nuvolaris/task/v3/internal/version"
+)
+
+// VersionInfo describes the build of Task, as printed by --version --json.
+type VersionInfo struct {
+	Version string `json:"version"`
+	// Commit is the commit Task was built from, if known
+	Commit string `json:"commit,omitempty"`
+	// Date is when Task was built, if known
+	Date        string   `json:"date,omitempty"`
+	GoVersion   string   `json:"go_version"`
+	Experiments []string `json:"experiments"`
+}
+
+// Version returns the build of Task, so the programs embedding it can report
+// which one they use.
+func Version() VersionInfo {
+	enabled := experiments.Enabled()
+	if enabled == nil {
+		enabled = []string{}
+	}
+	return VersionInfo{
+		Version:     version.GetVersion(),
+		Commit:      version.GetCommit(),
+		Date:        version.GetDate(),
+		GoVersion:   runtime.Version(),
+		Experiments: enabled,
+	}
+}
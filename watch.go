@@ -7,18 +7,36 @@ import (
 	"os/signal"
 	"path/filepath"
 	"strings"
+	"sync/atomic"
 	"syscall"
 	"time"
 
-	"github.com/radovskyb/watcher"
+	"golang.org/x/exp/slices"
 
 	"github.com/nuvolaris/task/v3/errors"
+	"github.com/nuvolaris/task/v3/internal/filepathext"
 	"github.com/nuvolaris/task/v3/internal/fingerprint"
 	"github.com/nuvolaris/task/v3/internal/logger"
 	"github.com/nuvolaris/task/v3/taskfile"
 )
 
-const defaultWatchInterval = 5 * time.Second
+const (
+	// defaultWatchInterval is how often the files are polled when the events
+	// of the file system can't be watched.
+	defaultWatchInterval = 5 * time.Second
+	// defaultWatchMaxFiles is the default limit of watched files. When
+	// polling, every watched file is checked on each interval, so watching
+	// huge trees uses a lot of CPU.
+	defaultWatchMaxFiles = 10000
+)
+
+// ignoredWatchDirs are directories that usually hold VCS data, dependencies
+// or caches, with thousands of files. Their files are never watched unless a
+// source explicitly points into them.
+var ignoredWatchDirs = []string{
+	".git", ".hg", ".svn", ".task", ".terraform", ".venv", "__pycache__",
+	"bower_components", "node_modules", "vendor", "venv",
+}
 
 // watchTasks start watching the given tasks
 func (e *Executor) watchTasks(calls ...taskfile.Call) error {
@@ -29,77 +47,179 @@ func (e *Executor) watchTasks(calls ...taskfile.Call) error {
 
 	e.Logger.Errf(logger.Green, "task: Started watching for tasks: %s\n", strings.Join(tasks, ", "))
 
-	ctx, cancel := context.WithCancel(context.Background())
-	for _, c := range calls {
-		c := c
+	keys, stopStatus := e.startWatchStatus(calls)
+	defer stopStatus()
+
+	// Each call has its own context, so a trigger can rerun a single task
+	runs := make([]*watchRun, len(calls))
+	run := func(i int, force bool) {
+		prev := runs[i]
+		ctx, cancel := context.WithCancel(context.Background())
+		r := &watchRun{cancel: cancel, done: make(chan struct{}), force: force}
+		runs[i] = r
+		ctx = withWatchRun(ctx, r)
+		if prev != nil {
+			prev.cancel()
+			// The next runs wait too, until the stopped commands exit
+			r.restart.Store(prev.restart.Load())
+		}
+		c := calls[i]
 		go func() {
-			if err := e.RunTask(ctx, c); err != nil && !isContextError(err) {
+			defer close(r.done)
+			if prev != nil && prev.restart.Load() {
+				<-prev.done
+			}
+			if ctx.Err() != nil {
+				return
+			}
+			e.watchStatus.started(i)
+			start := time.Now()
+			err := e.RunTask(ctx, c)
+			if isContextError(err) {
+				return
+			}
+			e.watchStatus.finished(i, err, time.Since(start))
+			if err != nil {
 				e.Logger.Errf(logger.Red, "%v\n", err)
 			}
 		}()
 	}
+	for i := range calls {
+		run(i, false)
+	}
+
+	triggers := make(chan []int)
+	if e.WatchListen != "" {
+		stop, err := e.listenForWatchTriggers(calls, triggers)
+		if err != nil {
+			return err
+		}
+		defer stop()
+	}
 
+	// The files are polled when an interval is set, since the events of the
+	// file system aren't always delivered, like on NFS or inside containers
 	var watchInterval time.Duration
 	switch {
 	case e.Interval != 0:
 		watchInterval = e.Interval
 	case e.Taskfile.Interval != 0:
 		watchInterval = e.Taskfile.Interval
-	default:
-		watchInterval = defaultWatchInterval
 	}
 
-	e.Logger.VerboseOutf(logger.Green, "task: Watching for changes every %v\n", watchInterval)
-
-	w := watcher.New()
+	var w sourceWatcher
+	if watchInterval == 0 {
+		events, err := newEventsWatcher(nil)
+		if err == nil {
+			events.register = func() error { return e.registerWatchedFiles(events, calls...) }
+			err = events.register()
+			if err == nil || !isWatchEventsError(err) {
+				w = events
+			} else {
+				events.Close()
+			}
+		}
+		if isWatchEventsError(err) {
+			e.Logger.Errf(logger.Yellow, "task: Can't watch the events of the file system (%v), checking for changes every %v instead\n", err, defaultWatchInterval)
+			watchInterval = defaultWatchInterval
+		} else if err != nil {
+			e.Logger.Errf(logger.Red, "%v\n", err)
+		}
+	}
+	if w == nil {
+		var poll *pollWatcher
+		poll = newPollWatcher(watchInterval, func() error { return e.registerWatchedFiles(poll, calls...) })
+		w = poll
+		e.Logger.VerboseOutf(logger.Green, "task: Watching for changes every %v\n", watchInterval)
+	} else {
+		e.Logger.VerboseOutf(logger.Green, "task: Watching for changes with the events of the file system\n")
+	}
 	defer w.Close()
-	w.SetMaxEvents(1)
 
 	closeOnInterrupt(w)
 
 	go func() {
 		for {
 			select {
-			case event := <-w.Event:
-				e.Logger.VerboseErrf(logger.Magenta, "task: received watch event: %v\n", event)
+			case paths := <-w.Changes():
+				e.Logger.VerboseErrf(logger.Magenta, "task: received watch event: %v\n", strings.Join(paths, ", "))
 
-				cancel()
-				ctx, cancel = context.WithCancel(context.Background())
+				e.Compiler.ResetCache()
 
+				for _, i := range e.watchedBy(paths, len(calls)) {
+					run(i, false)
+				}
+			case indexes := <-triggers:
 				e.Compiler.ResetCache()
 
-				for _, c := range calls {
-					c := c
-					go func() {
-						if err := e.RunTask(ctx, c); err != nil && !isContextError(err) {
-							e.Logger.Errf(logger.Red, "%v\n", err)
-						}
-					}()
-				}
-			case err := <-w.Error:
-				switch err {
-				case watcher.ErrWatchedFileDeleted:
-				default:
-					e.Logger.Errf(logger.Red, "%v\n", err)
-				}
-			case <-w.Closed:
-				cancel()
-				return
-			}
-		}
-	}()
+				for _, i := range indexes {
+					run(i, false)
+				}
+			case key, ok := <-keys:
+				if !ok {
+					keys = nil
+					continue
+				}
+				switch key {
+				case watchKeyRerun:
+					e.Compiler.ResetCache()
 
-	go func() {
-		// re-register every 5 seconds because we can have new files, but this process is expensive to run
-		for {
-			if err := e.registerWatchedFiles(w, calls...); err != nil {
+					for i := range calls {
+						run(i, true)
+					}
+				case watchKeyClear:
+					e.watchStatus.clearNext()
+				case watchKeyQuit:
+					w.Close()
+				}
+			case err := <-w.Errors():
 				e.Logger.Errf(logger.Red, "%v\n", err)
+			case <-w.Closed():
+				for _, r := range runs {
+					r.cancel()
+				}
+				return
 			}
-			time.Sleep(watchInterval)
 		}
 	}()
 
-	return w.Start(watchInterval)
+	return w.Start()
+}
+
+// A watchRun is a run of a watched task.
+type watchRun struct {
+	cancel context.CancelFunc
+	done   chan struct{}
+	// force runs the watched task even if it's up to date, like --force
+	force bool
+	// restart is set once a command of a task with "watch: restart" started,
+	// so the next run waits for it to be stopped
+	restart atomic.Bool
+}
+
+type watchRunKey struct{}
+
+func withWatchRun(ctx context.Context, r *watchRun) context.Context {
+	return context.WithValue(ctx, watchRunKey{}, r)
+}
+
+// restartOnWatch tells whether the commands of the task are stopped along
+// with their processes when its sources change, and makes the next run wait
+// for them to exit if so.
+func restartOnWatch(ctx context.Context, t *taskfile.Task) bool {
+	r, _ := ctx.Value(watchRunKey{}).(*watchRun)
+	if r == nil || t.Watch != taskfile.WatchRestart {
+		return false
+	}
+	r.restart.Store(true)
+	return true
+}
+
+// forcedOnWatch tells whether the run was asked for while watching, so the
+// watched tasks run even if they're up to date.
+func forcedOnWatch(ctx context.Context) bool {
+	r, _ := ctx.Value(watchRunKey{}).(*watchRun)
+	return r != nil && r.force
 }
 
 func isContextError(err error) bool {
@@ -110,7 +230,7 @@ func isContextError(err error) bool {
 	return err == context.Canceled || err == context.DeadlineExceeded
 }
 
-func closeOnInterrupt(w *watcher.Watcher) {
+func closeOnInterrupt(w sourceWatcher) {
 	ch := make(chan os.Signal, 1)
 	signal.Notify(ch, os.Interrupt, syscall.SIGTERM)
 	go func() {
@@ -119,12 +239,22 @@ func closeOnInterrupt(w *watcher.Watcher) {
 	}()
 }
 
-func (e *Executor) registerWatchedFiles(w *watcher.Watcher, calls ...taskfile.Call) error {
+func (e *Executor) registerWatchedFiles(w sourceWatcher, calls ...taskfile.Call) error {
 	watchedFiles := w.WatchedFiles()
+	count := len(watchedFiles)
+	maxFiles := e.WatchMaxFiles
+	if maxFiles == 0 {
+		maxFiles = defaultWatchMaxFiles
+	}
+
+	// owners are the watched tasks each file is a source of, so a change only
+	// reruns these
+	owners := map[string][]int{}
+	var owner int
 
 	var registerTaskFiles func(taskfile.Call) error
 	registerTaskFiles = func(c taskfile.Call) error {
-		task, err := e.CompiledTask(c)
+		task, err := e.CompiledTask(context.Background(), c)
 		if err != nil {
 			return err
 		}
@@ -134,6 +264,13 @@ func (e *Executor) registerWatchedFiles(w *watcher.Watcher, calls ...taskfile.Ca
 				return err
 			}
 		}
+		for _, stage := range task.Pipeline {
+			for _, d := range stage.Tasks {
+				if err := registerTaskFiles(taskfile.Call{Task: d.Task, Vars: d.Vars}); err != nil {
+					return err
+				}
+			}
+		}
 		for _, c := range task.Cmds {
 			if c.Task != "" {
 				if err := registerTaskFiles(taskfile.Call{Task: c.Task, Vars: c.Vars}); err != nil {
@@ -142,40 +279,119 @@ func (e *Executor) registerWatchedFiles(w *watcher.Watcher, calls ...taskfile.Ca
 			}
 		}
 
+		// The files excluded by the sources aren't watched
+		sources, err := fingerprint.Sources(task)
+		if err != nil {
+			return err
+		}
+		included := make(map[string]bool, len(sources))
+		for _, f := range sources {
+			included[filepathext.Key(f)] = true
+		}
+
 		for _, s := range task.Sources {
+			if fingerprint.IsExclusion(s) {
+				continue
+			}
+			// New sources can appear in the directories the globs match
+			// files in
+			if root, recursive := globRoot(task.Dir, s); root != "" {
+				absRoot, err := filepath.Abs(root)
+				if err != nil {
+					return err
+				}
+				if err := w.AddDir(absRoot, recursive, s); err != nil {
+					return err
+				}
+			}
 			files, err := fingerprint.Glob(task.Dir, s)
 			if err != nil {
 				return fmt.Errorf("task: %s: %w", s, err)
 			}
 			for _, f := range files {
+				if !included[filepathext.Key(f)] {
+					continue
+				}
 				absFile, err := filepath.Abs(f)
 				if err != nil {
 					return err
 				}
-				if shouldIgnoreFile(absFile) {
+				if shouldIgnoreFile(absFile, s) {
 					continue
 				}
+				if !slices.Contains(owners[absFile], owner) {
+					owners[absFile] = append(owners[absFile], owner)
+				}
 				if _, ok := watchedFiles[absFile]; ok {
 					continue
 				}
+				if maxFiles > 0 && count >= maxFiles {
+					if !e.watchLimitReached {
+						e.Logger.Errf(logger.Yellow, "task: Watching only the first %d files, because the sources of the tasks match more. Narrow down the sources or raise the limit with --watch-max-files\n", maxFiles)
+						e.watchLimitReached = true
+					}
+					return nil
+				}
 				if err := w.Add(absFile); err != nil {
 					return err
 				}
+				watchedFiles[absFile] = nil
+				count++
 				e.Logger.VerboseOutf(logger.Green, "task: watching new file: %v\n", absFile)
 			}
 		}
 		return nil
 	}
 
-	for _, c := range calls {
+	defer func() {
+		e.watchMutex.Lock()
+		defer e.watchMutex.Unlock()
+		e.watchOwners = owners
+	}()
+	for i, c := range calls {
+		owner = i
 		if err := registerTaskFiles(c); err != nil {
 			return err
 		}
 	}
+	if count != e.watchedFilesCount {
+		e.Logger.VerboseOutf(logger.Green, "task: Watching %d file(s)\n", count)
+		e.watchedFilesCount = count
+		e.watchStatus.setFiles(count)
+	}
 	return nil
 }
 
-func shouldIgnoreFile(path string) bool {
-	return strings.Contains(path, "/.git") || strings.Contains(path, "/.hg") ||
-		strings.Contains(path, "/.task") || strings.Contains(path, "/node_modules")
+// watchedBy returns the indexes of the watched tasks the files are sources
+// of, in order. All of them are returned when a file isn't known, like one
+// that was removed.
+func (e *Executor) watchedBy(paths []string, calls int) []int {
+	e.watchMutex.Lock()
+	defer e.watchMutex.Unlock()
+	var indexes []int
+	for _, path := range paths {
+		owners, ok := e.watchOwners[path]
+		if !ok {
+			indexes = make([]int, calls)
+			for i := range indexes {
+				indexes[i] = i
+			}
+			return indexes
+		}
+		indexes = append(indexes, owners...)
+	}
+	slices.Sort(indexes)
+	return slices.Compact(indexes)
+}
+
+// shouldIgnoreFile returns true if the file is inside one of the ignored
+// directories, unless the source it was matched by mentions that directory.
+func shouldIgnoreFile(path, source string) bool {
+	dirs := strings.Split(filepath.ToSlash(filepath.Dir(path)), "/")
+	for _, dir := range dirs {
+		if isIgnoredWatchDir(dir, source) {
+			return true
+		}
+	}
+	return false
 }
@@ -0,0 +1,360 @@
+package task
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/radovskyb/watcher"
+	"golang.org/x/exp/slices"
+
+	"github.com/nuvolaris/task/v3/internal/filepathext"
+)
+
+// watchDebounce is how long the watchers wait for more changes after one, so
+// saving many files at once reruns the tasks only once.
+const watchDebounce = 100 * time.Millisecond
+
+// A sourceWatcher reports the changes to the sources of the watched tasks.
+type sourceWatcher interface {
+	// WatchedFiles returns the watched files, by their absolute path.
+	WatchedFiles() map[string]os.FileInfo
+	// Add watches a file.
+	Add(name string) error
+	// AddDir watches a directory where new sources can appear, along with
+	// its subdirectories if recursive. The subdirectories ignored when
+	// watching are left out, unless the source mentions them.
+	AddDir(dir string, recursive bool, source string) error
+	// Start reports the changes until the watcher is closed.
+	Start() error
+	Close()
+	// Changes receives the changed files, gathered for a short while so
+	// saving many files at once reruns the tasks only once.
+	Changes() <-chan []string
+	Errors() <-chan error
+	Closed() <-chan struct{}
+}
+
+// pollWatcher is the sourceWatcher checking the watched files on each
+// interval. It's used when the interval is set, since the events of the
+// file system aren't always delivered (e.g. on NFS or inside containers),
+// or when they aren't available.
+type pollWatcher struct {
+	*watcher.Watcher
+	interval time.Duration
+	// register finds the new sources, which is done on each interval too
+	register func() error
+	changes  chan []string
+	errors   chan error
+}
+
+func newPollWatcher(interval time.Duration, register func() error) *pollWatcher {
+	return &pollWatcher{
+		Watcher:  watcher.New(),
+		interval: interval,
+		register: register,
+		changes:  make(chan []string),
+		errors:   make(chan error),
+	}
+}
+
+func (w *pollWatcher) AddDir(string, bool, string) error {
+	return nil
+}
+
+func (w *pollWatcher) Start() error {
+	go func() {
+		var (
+			timer   <-chan time.Time
+			changed []string
+		)
+		for {
+			select {
+			case event := <-w.Event:
+				changed = appendChanged(changed, event.Path)
+				timer = time.After(watchDebounce)
+			case <-timer:
+				timer = nil
+				if !send(w.changes, changed, w.Watcher.Closed) {
+					return
+				}
+				changed = nil
+			case err := <-w.Error:
+				if err != watcher.ErrWatchedFileDeleted {
+					send(w.errors, err, w.Watcher.Closed)
+				}
+			case <-w.Watcher.Closed:
+				return
+			}
+		}
+	}()
+	go func() {
+		// re-register on each interval because we can have new files, but this process is expensive to run
+		for {
+			if err := w.register(); err != nil && !send(w.errors, err, w.Watcher.Closed) {
+				return
+			}
+			time.Sleep(w.interval)
+		}
+	}()
+	return w.Watcher.Start(w.interval)
+}
+
+func (w *pollWatcher) Changes() <-chan []string { return w.changes }
+func (w *pollWatcher) Errors() <-chan error     { return w.errors }
+func (w *pollWatcher) Closed() <-chan struct{}  { return w.Watcher.Closed }
+
+// eventsWatcher is the sourceWatcher using the events of the file system. It
+// watches the directories of the sources, so files replaced by editors are
+// still watched, and the directories created inside them, so the new sources
+// are found without polling.
+type eventsWatcher struct {
+	w        *fsnotify.Watcher
+	register func() error
+	changes  chan []string
+	errors   chan error
+	closed   chan struct{}
+	close    sync.Once
+
+	mutex sync.Mutex
+	files map[string]bool
+	dirs  map[string]bool
+	// recursive are the directories watched with their subdirectories, and
+	// the sources they're watched for
+	recursive map[string]string
+}
+
+// errWatchEvents is returned when the events of the file system can't be
+// watched, e.g. because the limit of watches of the OS is reached.
+type errWatchEvents struct {
+	err error
+}
+
+func (err *errWatchEvents) Error() string { return err.err.Error() }
+func (err *errWatchEvents) Unwrap() error { return err.err }
+
+func newEventsWatcher(register func() error) (*eventsWatcher, error) {
+	w, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, &errWatchEvents{err}
+	}
+	return &eventsWatcher{
+		w:         w,
+		register:  register,
+		changes:   make(chan []string),
+		errors:    make(chan error),
+		closed:    make(chan struct{}),
+		files:     map[string]bool{},
+		dirs:      map[string]bool{},
+		recursive: map[string]string{},
+	}, nil
+}
+
+func (w *eventsWatcher) WatchedFiles() map[string]os.FileInfo {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	files := make(map[string]os.FileInfo, len(w.files))
+	for f := range w.files {
+		files[f] = nil
+	}
+	return files
+}
+
+func (w *eventsWatcher) Add(name string) error {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	if err := w.watchDir(filepath.Dir(name)); err != nil {
+		return err
+	}
+	w.files[name] = true
+	return nil
+}
+
+func (w *eventsWatcher) AddDir(dir string, recursive bool, source string) error {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	if !recursive {
+		return w.watchDir(dir)
+	}
+	return w.watchTree(dir, source)
+}
+
+// watchTree watches the directory and its subdirectories.
+func (w *eventsWatcher) watchTree(dir, source string) error {
+	if _, ok := w.recursive[dir]; ok {
+		return nil
+	}
+	return filepath.WalkDir(dir, func(path string, d os.DirEntry, err error) error {
+		if err != nil || !d.IsDir() {
+			return nil
+		}
+		if path != dir && isIgnoredWatchDir(d.Name(), source) {
+			return filepath.SkipDir
+		}
+		w.recursive[path] = source
+		return w.watchDir(path)
+	})
+}
+
+func (w *eventsWatcher) watchDir(dir string) error {
+	if w.dirs[dir] {
+		return nil
+	}
+	if err := w.w.Add(dir); err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return &errWatchEvents{err}
+	}
+	w.dirs[dir] = true
+	return nil
+}
+
+func (w *eventsWatcher) Start() error {
+	var (
+		timer   <-chan time.Time
+		changed []string
+		created bool
+	)
+	for {
+		select {
+		case event, ok := <-w.w.Events:
+			if !ok {
+				return nil
+			}
+			if event.Op == fsnotify.Chmod {
+				continue
+			}
+			path := filepath.Clean(event.Name)
+			w.mutex.Lock()
+			if event.Op&fsnotify.Create != 0 {
+				created = true
+				// The directories created inside the ones watched
+				// recursively are watched too, with the files they already
+				// have
+				if source, ok := w.recursive[filepath.Dir(path)]; ok {
+					if info, err := os.Stat(path); err == nil && info.IsDir() && !isIgnoredWatchDir(info.Name(), source) {
+						if err := w.watchTree(path, source); err != nil {
+							w.mutex.Unlock()
+							if !send(w.errors, err, w.closed) {
+								return nil
+							}
+							continue
+						}
+					}
+				}
+			}
+			if event.Op&(fsnotify.Remove|fsnotify.Rename) != 0 {
+				created = true
+				delete(w.dirs, path)
+				delete(w.recursive, path)
+			}
+			if w.files[path] {
+				changed = appendChanged(changed, path)
+			}
+			w.mutex.Unlock()
+			timer = time.After(watchDebounce)
+		case <-timer:
+			timer = nil
+			// New files can match the sources, and the removed ones don't
+			if created {
+				created = false
+				before := w.WatchedFiles()
+				if err := w.register(); err != nil && !send(w.errors, err, w.closed) {
+					return nil
+				}
+				w.forgetRemoved()
+				for f := range w.WatchedFiles() {
+					if _, ok := before[f]; !ok {
+						changed = appendChanged(changed, f)
+					}
+				}
+			}
+			if len(changed) > 0 && !send(w.changes, changed, w.closed) {
+				return nil
+			}
+			changed = nil
+		case err, ok := <-w.w.Errors:
+			if !ok {
+				return nil
+			}
+			if !send(w.errors, err, w.closed) {
+				return nil
+			}
+		case <-w.closed:
+			return nil
+		}
+	}
+}
+
+// forgetRemoved stops watching the files that don't exist anymore.
+func (w *eventsWatcher) forgetRemoved() {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	for f := range w.files {
+		if _, err := os.Stat(f); err != nil {
+			delete(w.files, f)
+		}
+	}
+}
+
+// appendChanged adds the path to the changed files, unless it's already there.
+func appendChanged(changed []string, path string) []string {
+	if slices.Contains(changed, path) {
+		return changed
+	}
+	return append(changed, path)
+}
+
+func (w *eventsWatcher) Close() {
+	w.close.Do(func() {
+		close(w.closed)
+		_ = w.w.Close()
+	})
+}
+
+func (w *eventsWatcher) Changes() <-chan []string { return w.changes }
+func (w *eventsWatcher) Errors() <-chan error     { return w.errors }
+func (w *eventsWatcher) Closed() <-chan struct{}  { return w.closed }
+
+// globRoot returns the directory a source glob matches files in, and whether
+// it matches files in its subdirectories too. It returns nothing for the
+// sources that are plain files, since their directories are watched anyway.
+func globRoot(dir, source string) (string, bool) {
+	parts := strings.Split(filepath.ToSlash(source), "/")
+	for i, part := range parts {
+		if strings.ContainsAny(part, "*?[{") {
+			root := filepathext.SmartJoin(dir, filepath.FromSlash(strings.Join(parts[:i], "/")))
+			return root, i < len(parts)-1 || strings.Contains(part, "**")
+		}
+	}
+	return "", false
+}
+
+// send sends the value unless the watcher is closed first, and tells whether
+// it was sent.
+func send[T any](ch chan<- T, v T, closed <-chan struct{}) bool {
+	select {
+	case ch <- v:
+		return true
+	case <-closed:
+		return false
+	}
+}
+
+// isWatchEventsError tells whether the error comes from watching the events
+// of the file system, in which case polling is used instead.
+func isWatchEventsError(err error) bool {
+	var eventsErr *errWatchEvents
+	return errors.As(err, &eventsErr)
+}
+
+// isIgnoredWatchDir tells whether the directory is one of the ignored ones
+// that the source doesn't mention.
+func isIgnoredWatchDir(name, source string) bool {
+	return slices.Contains(ignoredWatchDirs, name) && !strings.Contains(filepath.ToSlash(source), name)
+}
@@ -0,0 +1,59 @@
+package task
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/nuvolaris/task/v3/internal/logger"
+	"github.com/nuvolaris/task/v3/taskfile"
+)
+
+// listenForWatchTriggers serves the HTTP endpoint of WatchListen. A POST
+// request to "/" reruns all the watched tasks, and one to "/<task>" reruns
+// only that task. The indexes of the calls to rerun are sent to triggers.
+func (e *Executor) listenForWatchTriggers(calls []taskfile.Call, triggers chan<- []int) (stop func(), err error) {
+	listener, err := net.Listen("tcp", e.WatchListen)
+	if err != nil {
+		return nil, fmt.Errorf("task: Failed to listen for watch triggers: %w", err)
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", http.MethodPost)
+			http.Error(w, "task: Only POST requests trigger the watched tasks", http.StatusMethodNotAllowed)
+			return
+		}
+
+		name := strings.TrimPrefix(r.URL.Path, "/")
+		var indexes []int
+		for i, c := range calls {
+			if name == "" || c.Task == name {
+				indexes = append(indexes, i)
+			}
+		}
+		if len(indexes) == 0 {
+			http.Error(w, fmt.Sprintf("task: Task %q is not being watched", name), http.StatusNotFound)
+			return
+		}
+
+		e.Logger.VerboseErrf(logger.Magenta, "task: received watch trigger: %s %s\n", r.Method, r.URL.Path)
+		select {
+		case triggers <- indexes:
+			w.WriteHeader(http.StatusAccepted)
+		case <-r.Context().Done():
+		}
+	})
+
+	server := &http.Server{Handler: mux}
+	go func() {
+		if err := server.Serve(listener); err != nil && err != http.ErrServerClosed {
+			e.Logger.Errf(logger.Red, "task: Failed to serve watch triggers: %v\n", err)
+		}
+	}()
+
+	e.Logger.Errf(logger.Green, "task: Listening for watch triggers on http://%s\n", listener.Addr())
+	return func() { _ = server.Close() }, nil
+}
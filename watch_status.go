@@ -0,0 +1,175 @@
+package task
+
+import (
+	"bufio"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fatih/color"
+
+	"github.com/nuvolaris/task/v3/internal/output"
+	"github.com/nuvolaris/task/v3/internal/term"
+	"github.com/nuvolaris/task/v3/taskfile"
+)
+
+// Keys read while watching in a terminal.
+const (
+	watchKeyRerun = 'r'
+	watchKeyClear = 'c'
+	watchKeyQuit  = 'q'
+)
+
+// watchStatus shows the state of the watched tasks on a line at the bottom of
+// the terminal: how many files are watched and how the last run of each task
+// went.
+type watchStatus struct {
+	line  *output.StatusLine
+	tasks []string
+	// keys is set when the keys are read
+	keys bool
+
+	mutex   sync.Mutex
+	files   int
+	results []string
+	// clear is set by the key that clears the screen before the next run
+	clear bool
+}
+
+// startWatchStatus shows the status line when watching in a terminal, and
+// returns the keys pressed meanwhile, along with the function that gives the
+// terminal back. The keys aren't read when a task needs the terminal itself.
+func (e *Executor) startWatchStatus(calls []taskfile.Call) (<-chan byte, func()) {
+	if e.NoWatchStatus || !term.IsTerminalReader(e.Stdin) || !term.IsTerminalWriter(e.Stderr) {
+		return nil, func() {}
+	}
+
+	s := &watchStatus{line: &output.StatusLine{Writer: e.Stderr}, results: make([]string, len(calls))}
+	for _, c := range calls {
+		s.tasks = append(s.tasks, c.Task)
+	}
+	e.Stdout, e.Stderr = s.line.Wrap(e.Stdout), s.line.Wrap(e.Stderr)
+	e.Logger.Stdout, e.Logger.Stderr = s.line.Wrap(e.Logger.Stdout), s.line.Wrap(e.Logger.Stderr)
+	e.watchStatus = s
+
+	if e.needsTerminal() {
+		s.setFiles(e.watchedFilesCount)
+		return nil, s.line.Stop
+	}
+
+	// The commands don't get the input of the terminal, since it's for Task
+	restore, err := term.Cbreak(e.Stdin)
+	if err != nil {
+		restore = func() {}
+	}
+	keys := make(chan byte)
+	stdin := bufio.NewReader(e.Stdin)
+	e.Stdin = strings.NewReader("")
+	s.keys = true
+	go func() {
+		for {
+			key, err := stdin.ReadByte()
+			if err != nil {
+				close(keys)
+				return
+			}
+			keys <- key
+		}
+	}()
+	s.setFiles(e.watchedFilesCount)
+	return keys, func() {
+		s.line.Stop()
+		restore()
+	}
+}
+
+// needsTerminal tells whether some task is interactive or asks for a
+// confirmation or for the value of a variable, in which case the terminal is
+// read by the task itself.
+func (e *Executor) needsTerminal() bool {
+	prompts := func(vars *taskfile.Vars) bool {
+		found := false
+		_ = vars.Range(func(_ string, v taskfile.Var) error {
+			found = found || v.Prompt != ""
+			return nil
+		})
+		return found
+	}
+	if prompts(e.Taskfile.Vars) {
+		return true
+	}
+	for _, t := range e.Taskfile.Tasks.Values() {
+		if t.Interactive || t.Prompt != "" || prompts(t.Vars) {
+			return true
+		}
+	}
+	return false
+}
+
+// setFiles records how many files are watched.
+func (s *watchStatus) setFiles(count int) {
+	if s == nil {
+		return
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.files = count
+	s.draw()
+}
+
+// started records that the i-th watched task runs again, and clears the
+// screen first if asked to.
+func (s *watchStatus) started(i int) {
+	if s == nil {
+		return
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.clear {
+		s.clear = false
+		s.line.ClearScreen()
+	}
+	s.results[i] = color.CyanString("…") + " " + s.tasks[i]
+	s.draw()
+}
+
+// finished records how the run of the i-th watched task went.
+func (s *watchStatus) finished(i int, err error, d time.Duration) {
+	if s == nil {
+		return
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	mark := color.GreenString("✓")
+	if err != nil {
+		mark = color.RedString("✗")
+	}
+	s.results[i] = fmt.Sprintf("%s %s %.1fs", mark, s.tasks[i], d.Seconds())
+	s.draw()
+}
+
+// clearNext clears the screen before the next run.
+func (s *watchStatus) clearNext() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.clear = true
+}
+
+// draw updates the line. It must be called with the mutex held.
+func (s *watchStatus) draw() {
+	var results []string
+	for _, r := range s.results {
+		if r != "" {
+			results = append(results, r)
+		}
+	}
+	parts := []string{fmt.Sprintf("Watching %d file(s)", s.files)}
+	if len(results) > 0 {
+		parts = append(parts, strings.Join(results, ", "))
+	}
+	if s.keys {
+		parts = append(parts, color.New(color.Faint).Sprint("r: rerun, c: clear, q: quit"))
+	}
+	s.line.Set(strings.Join(parts, " │ "))
+}
@@ -6,7 +6,10 @@ package task_test
 import (
 	"bytes"
 	"context"
+	"net/http"
 	"os"
+	"path/filepath"
+	"regexp"
 	"strings"
 	"testing"
 	"time"
@@ -79,3 +82,244 @@ Hello, World!
 	err = os.RemoveAll(filepathext.SmartJoin(dir, "src"))
 	require.NoError(t, err)
 }
+
+func TestFileWatcherMaxFiles(t *testing.T) {
+	const dir = "testdata/watcher_max_files"
+	t.Cleanup(func() {
+		_ = os.RemoveAll(filepathext.SmartJoin(dir, ".task"))
+		_ = os.RemoveAll(filepathext.SmartJoin(dir, "src"))
+		_ = os.RemoveAll(filepathext.SmartJoin(dir, "node_modules"))
+	})
+	for _, file := range []string{"src/a", "src/b", "src/c", "node_modules/lib/index.js"} {
+		path := filepathext.SmartJoin(dir, file)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+		require.NoError(t, os.WriteFile(path, []byte("test"), 0o644))
+	}
+
+	var buff syncBuffer
+	e := &task.Executor{
+		Dir:           dir,
+		Stdout:        &buff,
+		Stderr:        &buff,
+		Watch:         true,
+		Verbose:       true,
+		WatchMaxFiles: 2,
+	}
+	require.NoError(t, e.Setup())
+
+	go func() {
+		_ = e.Run(context.Background(), taskfile.Call{Task: "default"})
+	}()
+	time.Sleep(300 * time.Millisecond)
+
+	output := buff.String()
+	assert.Contains(t, output, "task: Watching only the first 2 files")
+	assert.Contains(t, output, "task: Watching 2 file(s)\n")
+	assert.NotContains(t, output, "node_modules")
+}
+
+func TestFileWatcherEvents(t *testing.T) {
+	const dir = "testdata/watcher_events"
+	t.Cleanup(func() {
+		_ = os.RemoveAll(filepathext.SmartJoin(dir, ".task"))
+		_ = os.RemoveAll(filepathext.SmartJoin(dir, "src"))
+	})
+	write := func(file string) {
+		path := filepathext.SmartJoin(dir, file)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+		require.NoError(t, os.WriteFile(path, []byte(time.Now().String()), 0o644))
+	}
+	write("src/a.txt")
+
+	var buff syncBuffer
+	e := &task.Executor{
+		Dir:     dir,
+		Stdout:  &buff,
+		Stderr:  &buff,
+		Watch:   true,
+		Silent:  true,
+		Verbose: true,
+	}
+	require.NoError(t, e.Setup())
+
+	go func() {
+		_ = e.Run(context.Background(), taskfile.Call{Task: "default"})
+	}()
+	require.Eventually(t, func() bool {
+		return countLines(buff.String(), "built") == 1 && strings.Contains(buff.String(), "task: Watching for changes")
+	}, time.Second, 10*time.Millisecond)
+
+	// No interval is set, so the changes are seen right away
+	write("src/a.txt")
+	require.Eventually(t, func() bool {
+		return countLines(buff.String(), "built") == 2
+	}, time.Second, 10*time.Millisecond)
+
+	// The new directories are watched too, without restarting
+	write("src/new/deeper/b.txt")
+	require.Eventually(t, func() bool {
+		return countLines(buff.String(), "built") == 3
+	}, time.Second, 10*time.Millisecond)
+	write("src/new/deeper/b.txt")
+	require.Eventually(t, func() bool {
+		return countLines(buff.String(), "built") == 4
+	}, time.Second, 10*time.Millisecond)
+
+	// Files that aren't sources don't rerun the task
+	write("src/other.log")
+	time.Sleep(300 * time.Millisecond)
+	assert.Equal(t, 4, countLines(buff.String(), "built"))
+}
+
+func TestFileWatcherRestart(t *testing.T) {
+	const dir = "testdata/watcher_restart"
+	t.Cleanup(func() {
+		_ = os.RemoveAll(filepathext.SmartJoin(dir, ".task"))
+		_ = os.RemoveAll(filepathext.SmartJoin(dir, "src"))
+	})
+	write := func() {
+		require.NoError(t, os.MkdirAll(filepathext.SmartJoin(dir, "src"), 0o755))
+		require.NoError(t, os.WriteFile(filepathext.SmartJoin(dir, "src/a.txt"), []byte(time.Now().String()), 0o644))
+	}
+	write()
+
+	var buff syncBuffer
+	e := &task.Executor{
+		Dir:     dir,
+		Stdout:  &buff,
+		Stderr:  &buff,
+		Watch:   true,
+		Silent:  true,
+		Verbose: true,
+	}
+	require.NoError(t, e.Setup())
+
+	go func() {
+		_ = e.Run(context.Background(), taskfile.Call{Task: "serve"})
+	}()
+	require.Eventually(t, func() bool {
+		return countLines(buff.String(), "started") == 1 && strings.Contains(buff.String(), "task: Watching for changes")
+	}, time.Second, 10*time.Millisecond)
+
+	// The server never exits, so it's stopped and started again
+	write()
+	require.Eventually(t, func() bool {
+		return countLines(buff.String(), "started") == 2
+	}, 2*time.Second, 10*time.Millisecond)
+	output := buff.String()
+	assert.Equal(t, 1, countLines(output, "stopped"))
+	assert.Less(t, strings.Index(output, "stopped"), strings.LastIndex(output, "started"), "the task ran again before the server stopped")
+}
+
+func TestFileWatcherIndependentTasks(t *testing.T) {
+	const dir = "testdata/watcher_independent"
+	t.Cleanup(func() {
+		_ = os.RemoveAll(filepathext.SmartJoin(dir, ".task"))
+		_ = os.RemoveAll(filepathext.SmartJoin(dir, "src"))
+	})
+	write := func(file string) {
+		path := filepathext.SmartJoin(dir, file)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+		require.NoError(t, os.WriteFile(path, []byte(time.Now().String()), 0o644))
+	}
+	for _, file := range []string{"src/build/a.txt", "src/test/a.txt", "src/shared.txt"} {
+		write(file)
+	}
+
+	var buff syncBuffer
+	e := &task.Executor{
+		Dir:     dir,
+		Stdout:  &buff,
+		Stderr:  &buff,
+		Watch:   true,
+		Silent:  true,
+		Verbose: true,
+	}
+	require.NoError(t, e.Setup())
+
+	go func() {
+		_ = e.Run(context.Background(), taskfile.Call{Task: "build"}, taskfile.Call{Task: "test"})
+	}()
+	runs := func(build, test int) func() bool {
+		return func() bool {
+			output := buff.String()
+			return countLines(output, "build") == build && countLines(output, "test") == test
+		}
+	}
+	require.Eventually(t, func() bool {
+		return runs(1, 1)() && strings.Contains(buff.String(), "task: Watching for changes")
+	}, time.Second, 10*time.Millisecond)
+
+	// Only the task whose sources changed runs again
+	write("src/test/a.txt")
+	require.Eventually(t, runs(1, 2), time.Second, 10*time.Millisecond)
+	write("src/build/b.txt")
+	require.Eventually(t, runs(2, 2), time.Second, 10*time.Millisecond)
+	write("src/shared.txt")
+	require.Eventually(t, runs(3, 3), time.Second, 10*time.Millisecond)
+}
+
+func TestFileWatcherListen(t *testing.T) {
+	var buff syncBuffer
+	e := &task.Executor{
+		Dir:         "testdata/watcher_listen",
+		Stdout:      &buff,
+		Stderr:      &buff,
+		Watch:       true,
+		Silent:      true,
+		WatchListen: "127.0.0.1:0",
+	}
+	require.NoError(t, e.Setup())
+
+	go func() {
+		_ = e.Run(context.Background(), taskfile.Call{Task: "a"}, taskfile.Call{Task: "b"})
+	}()
+
+	listening := regexp.MustCompile(`Listening for watch triggers on (http://\S+)`)
+	var url string
+	require.Eventually(t, func() bool {
+		if m := listening.FindStringSubmatch(buff.String()); m != nil {
+			url = m[1]
+			return true
+		}
+		return false
+	}, time.Second, 10*time.Millisecond)
+	require.Eventually(t, func() bool {
+		return countLines(buff.String(), "a") == 1 && countLines(buff.String(), "b") == 1
+	}, time.Second, 10*time.Millisecond)
+
+	post := func(path string) int {
+		resp, err := http.Post(url+path, "", nil)
+		require.NoError(t, err)
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	assert.Equal(t, http.StatusAccepted, post("/b"))
+	require.Eventually(t, func() bool {
+		return countLines(buff.String(), "b") == 2
+	}, time.Second, 10*time.Millisecond)
+	assert.Equal(t, 1, countLines(buff.String(), "a"))
+
+	assert.Equal(t, http.StatusAccepted, post("/"))
+	require.Eventually(t, func() bool {
+		return countLines(buff.String(), "a") == 2 && countLines(buff.String(), "b") == 3
+	}, time.Second, 10*time.Millisecond)
+
+	assert.Equal(t, http.StatusNotFound, post("/c"))
+	resp, err := http.Get(url + "/a")
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusMethodNotAllowed, resp.StatusCode)
+}
+
+// countLines returns how many lines of s are equal to line.
+func countLines(s, line string) int {
+	count := 0
+	for _, l := range strings.Split(s, "\n") {
+		if l == line {
+			count++
+		}
+	}
+	return count
+}
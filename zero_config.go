@@ -0,0 +1,116 @@
+package task
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// projectType is a kind of project that is detected from its files, so its
+// usual tasks can run without a Taskfile (with the ZERO_CONFIG experiment).
+type projectType struct {
+	name string
+	// marker is the file that identifies the project
+	marker string
+	tasks  func(dir string) []projectTask
+}
+
+type projectTask struct {
+	name string
+	desc string
+	cmd  string
+}
+
+var projectTypes = []projectType{
+	{
+		name:   "Go",
+		marker: "go.mod",
+		tasks: func(string) []projectTask {
+			return []projectTask{
+				{"build", "Build the packages", "go build ./..."},
+				{"test", "Run the tests", "go test ./..."},
+				{"lint", "Check the code", "go vet ./..."},
+			}
+		},
+	},
+	{
+		name:   "Node.js",
+		marker: "package.json",
+		tasks:  nodeTasks,
+	},
+	{
+		name:   "Rust",
+		marker: "Cargo.toml",
+		tasks: func(string) []projectTask {
+			return []projectTask{
+				{"build", "Build the crate", "cargo build"},
+				{"test", "Run the tests", "cargo test"},
+				{"lint", "Check the code", "cargo clippy"},
+			}
+		},
+	},
+}
+
+// nodeTasks runs the build, test and lint scripts of package.json, with the
+// package manager of the lock file.
+func nodeTasks(dir string) []projectTask {
+	manager := "npm"
+	for _, lock := range []struct{ file, manager string }{
+		{"pnpm-lock.yaml", "pnpm"},
+		{"yarn.lock", "yarn"},
+		{"bun.lockb", "bun"},
+	} {
+		if _, err := os.Stat(filepath.Join(dir, lock.file)); err == nil {
+			manager = lock.manager
+			break
+		}
+	}
+
+	var pkg struct {
+		Scripts map[string]string `json:"scripts"`
+	}
+	if b, err := os.ReadFile(filepath.Join(dir, "package.json")); err == nil {
+		_ = json.Unmarshal(b, &pkg)
+	}
+
+	var tasks []projectTask
+	for _, script := range []struct{ name, desc string }{
+		{"build", "Build the project"},
+		{"test", "Run the tests"},
+		{"lint", "Check the code"},
+	} {
+		if _, ok := pkg.Scripts[script.name]; ok {
+			tasks = append(tasks, projectTask{script.name, script.desc, fmt.Sprintf("%s run %s", manager, script.name)})
+		}
+	}
+	return tasks
+}
+
+// detectedProject is a project found in a directory without a Taskfile.
+type detectedProject struct {
+	name     string
+	taskfile string
+}
+
+// detectProject returns the project in dir, with a Taskfile of its usual
+// tasks, or nil if it's not a known kind of project.
+func detectProject(dir string) *detectedProject {
+	for _, p := range projectTypes {
+		if _, err := os.Stat(filepath.Join(dir, p.marker)); err != nil {
+			continue
+		}
+		var b strings.Builder
+		b.WriteString("# https://taskfile.dev\n\nversion: '3'\n\ntasks:")
+		tasks := p.tasks(dir)
+		for _, t := range tasks {
+			fmt.Fprintf(&b, "\n  %s:\n    desc: %s\n    cmds:\n      - %s\n", t.name, t.desc, t.cmd)
+		}
+		if len(tasks) == 0 {
+			b.WriteString(" {}\n")
+		}
+		return &detectedProject{name: p.name, taskfile: b.String()}
+	}
+	return nil
+}